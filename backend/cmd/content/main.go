@@ -0,0 +1,217 @@
+// Команда content проверяет, импортирует и экспортирует файлы контента
+// (курсы, уроки, упражнения) в формате YAML/JSON.
+//
+//	go run ./cmd/content validate content/guitar.yaml
+//	go run ./cmd/content import [-prune] [-dry-run] content/guitar.yaml
+//	go run ./cmd/content export [-course slug] [-lesson slug] [-format yaml|json] [-o file]
+package main
+
+import (
+	"context"
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"log"
+	"os"
+	"strings"
+
+	"sonara-space/backend/config"
+	"sonara-space/backend/internal/content"
+	"sonara-space/backend/internal/db"
+)
+
+// errDryRun откатывает транзакцию пробного импорта
+var errDryRun = errors.New("dry run")
+
+func usage() {
+	fmt.Fprintln(os.Stderr, `usage:
+  content validate <file>...
+  content import [-prune] [-dry-run] <file>...
+  content export [-course slug]... [-lesson slug]... [-format yaml|json] [-o file]`)
+	os.Exit(2)
+}
+
+// list — флаг, который можно указать несколько раз
+type list []string
+
+func (l *list) String() string     { return strings.Join(*l, ",") }
+func (l *list) Set(v string) error { *l = append(*l, v); return nil }
+
+func main() {
+	if len(os.Args) < 2 {
+		usage()
+	}
+	cmd, args := os.Args[1], os.Args[2:]
+	var err error
+	switch cmd {
+	case "validate":
+		err = validateCmd(args)
+	case "import":
+		err = importCmd(args)
+	case "export":
+		err = exportCmd(args)
+	default:
+		usage()
+	}
+	if err != nil {
+		log.Fatal(err)
+	}
+}
+
+func readBundle(path string) (*content.Bundle, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+	return content.Decode(f, content.IsYAML(path))
+}
+
+// validateCmd проверяет файлы без подключения к базе: ссылки на уроки
+// должны разрешаться внутри переданных файлов
+func validateCmd(args []string) error {
+	if len(args) == 0 {
+		usage()
+	}
+	failed := false
+	for _, path := range args {
+		b, err := readBundle(path)
+		if err == nil {
+			err = b.Validate(nil)
+		}
+		if err != nil {
+			failed = true
+			fmt.Fprintf(os.Stderr, "%s: %v\n", path, err)
+			continue
+		}
+		fmt.Printf("%s: ok (%d courses, %d lessons)\n", path, len(b.Courses), len(b.Lessons))
+	}
+	if failed {
+		os.Exit(1)
+	}
+	return nil
+}
+
+func connect() {
+	if _, err := config.LoadConfig(); err != nil {
+		log.Println("Warning: .env file not found, using system environment variables")
+	}
+	pool, err := db.Connect()
+	if err != nil {
+		log.Fatalf("db connect error: %v", err)
+	}
+	db.Pool = pool
+}
+
+func importCmd(args []string) error {
+	fs := flag.NewFlagSet("import", flag.ExitOnError)
+	prune := fs.Bool("prune", false, "delete exercises missing from the file (with their progress)")
+	dryRun := fs.Bool("dry-run", false, "print the report and roll back")
+	fs.Parse(args)
+	if fs.NArg() == 0 {
+		usage()
+	}
+
+	connect()
+	defer db.Pool.Close()
+	ctx := context.Background()
+
+	for _, path := range fs.Args() {
+		b, err := readBundle(path)
+		if err != nil {
+			return fmt.Errorf("%s: %w", path, err)
+		}
+
+		tx, err := db.Pool.Begin(ctx)
+		if err != nil {
+			return err
+		}
+		known, err := content.KnownLessons(ctx, tx)
+		if err == nil {
+			err = b.Validate(known)
+		}
+		var report *content.Report
+		if err == nil {
+			report, err = content.Import(ctx, tx, b, content.ImportOptions{Prune: *prune})
+		}
+		if err == nil && *dryRun {
+			err = errDryRun
+		}
+		if err != nil {
+			tx.Rollback(ctx)
+		} else {
+			err = tx.Commit(ctx)
+		}
+		if err != nil && !errors.Is(err, errDryRun) {
+			return fmt.Errorf("%s: %w", path, err)
+		}
+
+		fmt.Printf("%s:\n", path)
+		printReport(os.Stdout, report)
+		if *dryRun {
+			fmt.Println("  dry run: nothing saved")
+		}
+	}
+	return nil
+}
+
+func printReport(w io.Writer, r *content.Report) {
+	for _, name := range r.Created {
+		fmt.Fprintf(w, "  + %s\n", name)
+	}
+	for _, name := range r.Updated {
+		fmt.Fprintf(w, "  ~ %s\n", name)
+	}
+	for _, name := range r.Deleted {
+		fmt.Fprintf(w, "  - %s\n", name)
+	}
+	for _, name := range r.Published {
+		fmt.Fprintf(w, "  * %s published\n", name)
+	}
+	for _, name := range r.Stale {
+		fmt.Fprintf(w, "  ! %s is not in the file (use -prune to delete)\n", name)
+	}
+	fmt.Fprintf(w, "  created %d, updated %d, unchanged %d, deleted %d\n",
+		len(r.Created), len(r.Updated), r.Unchanged, len(r.Deleted))
+}
+
+func exportCmd(args []string) error {
+	fs := flag.NewFlagSet("export", flag.ExitOnError)
+	var courses, lessons list
+	fs.Var(&courses, "course", "course slug (repeatable)")
+	fs.Var(&lessons, "lesson", "lesson slug (repeatable)")
+	format := fs.String("format", "", "yaml or json (default: by -o extension, yaml for stdout)")
+	out := fs.String("o", "", "output file (default: stdout)")
+	fs.Parse(args)
+
+	isYAML := true
+	switch {
+	case *format == "json":
+		isYAML = false
+	case *format == "yaml":
+	case *format != "":
+		return fmt.Errorf("unknown format %q", *format)
+	case *out != "":
+		isYAML = content.IsYAML(*out)
+	}
+
+	connect()
+	defer db.Pool.Close()
+
+	b, err := content.Export(context.Background(), db.Pool, content.ExportFilter{Courses: courses, Lessons: lessons})
+	if err != nil {
+		return err
+	}
+
+	w := io.Writer(os.Stdout)
+	if *out != "" {
+		f, err := os.Create(*out)
+		if err != nil {
+			return err
+		}
+		defer f.Close()
+		w = f
+	}
+	return content.Encode(w, b, isYAML)
+}
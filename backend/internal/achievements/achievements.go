@@ -0,0 +1,311 @@
+// Package achievements выдаёт значки за достижения. Значки и их условия
+// (Rule) — данные в таблице achievements, а не код: новый значок добавляется
+// строкой в таблицу.
+//
+// Условия проверяются по всей истории ученика (Facts), а не по одному
+// событию, поэтому проверка ретроактивна: новый значок получают и те, кто
+// выполнил условие до его появления (см. Backfill). Время выдачи — момент,
+// когда условие выполнилось, если его можно восстановить по истории.
+package achievements
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log"
+	"time"
+
+	"sonara-space/backend/internal/models"
+	"sonara-space/backend/internal/music"
+	"sonara-space/backend/internal/stats"
+
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgconn"
+	"github.com/jackc/pgx/v5/pgxpool"
+)
+
+// Виды условий
+const (
+	KindExercises  = "exercises"   // пройдено Count упражнений (типа ExerciseType, если задан)
+	KindScaleNotes = "scale_notes" // пройдены упражнения на все ноты гаммы Scale
+	KindLesson     = "lesson"      // пройдены все упражнения урока Lesson (slug)
+	KindStreak     = "streak"      // серия занятий достигла Days дней
+	KindXP         = "xp"          // набрано XP опыта
+)
+
+// ErrRule — условие значка заполнено неверно
+var ErrRule = errors.New("invalid achievement rule")
+
+// DB — методы pgx, нужные пакету (подходят pgxpool.Pool и pgx.Tx)
+type DB interface {
+	Exec(ctx context.Context, sql string, args ...interface{}) (pgconn.CommandTag, error)
+	Query(ctx context.Context, sql string, args ...interface{}) (pgx.Rows, error)
+	QueryRow(ctx context.Context, sql string, args ...interface{}) pgx.Row
+}
+
+// Rule — условие значка, JSON в колонке achievements.rule:
+// {"kind": "exercises", "exercise_type": "chord", "count": 1}
+type Rule struct {
+	Kind         string `json:"kind"`
+	Count        int    `json:"count,omitempty"`
+	ExerciseType string `json:"exercise_type,omitempty"`
+	Scale        string `json:"scale,omitempty"` // тональность: "C", "Am"
+	Lesson       string `json:"lesson,omitempty"`
+	Days         int    `json:"days,omitempty"`
+	XP           int    `json:"xp,omitempty"`
+}
+
+// ParseRule разбирает и проверяет условие
+func ParseRule(raw []byte) (Rule, error) {
+	var r Rule
+	if err := json.Unmarshal(raw, &r); err != nil {
+		return Rule{}, fmt.Errorf("%w: %v", ErrRule, err)
+	}
+	return r, r.Validate()
+}
+
+// Validate проверяет, что у условия заданы нужные параметры
+func (r Rule) Validate() error {
+	ok := false
+	switch r.Kind {
+	case KindExercises:
+		ok = r.Count > 0 && (r.ExerciseType == "" || r.ExerciseType == models.ExerciseTypeChord ||
+			r.ExerciseType == models.ExerciseTypeNote || r.ExerciseType == models.ExerciseTypeSequence ||
+			r.ExerciseType == models.ExerciseTypeRhythm)
+	case KindScaleNotes:
+		_, err := music.ParseKey(r.Scale)
+		ok = err == nil
+	case KindLesson:
+		ok = r.Lesson != ""
+	case KindStreak:
+		ok = r.Days > 0
+	case KindXP:
+		ok = r.XP > 0
+	}
+	if !ok {
+		return fmt.Errorf("%w: %+v", ErrRule, r)
+	}
+	return nil
+}
+
+// Passed — упражнение, пройденное учеником
+type Passed struct {
+	Type     string
+	Expected string
+	At       time.Time
+}
+
+// Facts — история ученика, по которой проверяются условия
+type Facts struct {
+	Passed  []Passed             // по возрастанию At
+	Lessons map[string]time.Time // slug пройденного урока — когда пройдено последнее упражнение
+	Stats   stats.Stats
+	Now     time.Time // время выдачи, если момент выполнения условия не восстановить
+}
+
+// Evaluate проверяет условие и возвращает момент, когда оно выполнилось
+func Evaluate(r Rule, f Facts) (bool, time.Time) {
+	switch r.Kind {
+	case KindExercises:
+		n := 0
+		for _, p := range f.Passed {
+			if r.ExerciseType == "" || p.Type == r.ExerciseType {
+				if n++; n == r.Count {
+					return true, p.At
+				}
+			}
+		}
+	case KindScaleNotes:
+		key, err := music.ParseKey(r.Scale)
+		if err != nil {
+			return false, time.Time{}
+		}
+		scale := key.PitchClasses()
+		var covered music.PitchClassSet
+		for _, p := range f.Passed {
+			if p.Type != models.ExerciseTypeNote {
+				continue
+			}
+			note, err := music.ParseNote(p.Expected)
+			if err != nil || !scale.Has(note.PitchClass()) {
+				continue
+			}
+			if covered = covered.Add(note.PitchClass()); covered == scale {
+				return true, p.At
+			}
+		}
+	case KindLesson:
+		if at, ok := f.Lessons[r.Lesson]; ok {
+			return true, at
+		}
+	case KindStreak:
+		return f.Stats.Streak.Longest >= r.Days, f.Now
+	case KindXP:
+		return f.Stats.Level.XP >= r.XP, f.Now
+	}
+	return false, time.Time{}
+}
+
+// LoadFacts собирает историю ученика из progress. Упражнения, пройденные
+// только перенесённой при переходе на новую версию попыткой, не считаются:
+// их исходное упражнение уже учтено.
+func LoadFacts(ctx context.Context, db DB, userID int64, st stats.Stats) (Facts, error) {
+	f := Facts{Lessons: make(map[string]time.Time), Stats: st}
+	if err := db.QueryRow(ctx, `SELECT LOCALTIMESTAMP`).Scan(&f.Now); err != nil {
+		return Facts{}, err
+	}
+
+	rows, err := db.Query(ctx, `
+		SELECT x.type, x.expected, COALESCE(p.completed_at, p.updated_at)
+		FROM progress p
+		JOIN exercises x ON x.id = p.exercise_id
+		WHERE p.user_id = $1 AND p.completed
+		  AND NOT COALESCE((
+			SELECT a.carried FROM exercise_attempts a
+			WHERE a.user_id = p.user_id AND a.exercise_id = p.exercise_id AND a.passed
+			ORDER BY a.created_at, a.id LIMIT 1), FALSE)
+		ORDER BY 3, p.exercise_id
+	`, userID)
+	if err != nil {
+		return Facts{}, err
+	}
+	for rows.Next() {
+		var p Passed
+		if err := rows.Scan(&p.Type, &p.Expected, &p.At); err != nil {
+			rows.Close()
+			return Facts{}, err
+		}
+		f.Passed = append(f.Passed, p)
+	}
+	rows.Close()
+	if err := rows.Err(); err != nil {
+		return Facts{}, err
+	}
+
+	// Урок пройден, когда пройдены все упражнения версии, которую проходит ученик
+	rows, err = db.Query(ctx, `
+		SELECT l.slug, MAX(COALESCE(p.completed_at, p.updated_at))
+		FROM user_lesson_exercises($1) e
+		JOIN lessons l ON l.id = e.lesson_id
+		LEFT JOIN progress p ON p.user_id = $1 AND p.exercise_id = e.exercise_id AND p.completed
+		WHERE l.slug IS NOT NULL
+		GROUP BY l.slug
+		HAVING COUNT(*) = COUNT(p.id)
+	`, userID)
+	if err != nil {
+		return Facts{}, err
+	}
+	defer rows.Close()
+	for rows.Next() {
+		var slug string
+		var at time.Time
+		if err := rows.Scan(&slug, &at); err != nil {
+			return Facts{}, err
+		}
+		f.Lessons[slug] = at
+	}
+	return f, rows.Err()
+}
+
+// Award проверяет все условия и выдаёт ещё не полученные значки. Вызывается
+// после stats.Recompute в той же транзакции; повторный вызов ничего не
+// меняет. Возвращает ID выданных сейчас значков.
+func Award(ctx context.Context, db DB, userID int64, st stats.Stats) ([]int64, error) {
+	rows, err := db.Query(ctx, `
+		SELECT a.id, a.slug, a.rule FROM achievements a
+		WHERE NOT EXISTS (SELECT 1 FROM user_achievements ua WHERE ua.achievement_id = a.id AND ua.user_id = $1)
+		ORDER BY a.order_index, a.id
+	`, userID)
+	if err != nil {
+		return nil, err
+	}
+	type pending struct {
+		id   int64
+		rule Rule
+	}
+	var list []pending
+	for rows.Next() {
+		var p pending
+		var slug string
+		var raw []byte
+		if err := rows.Scan(&p.id, &slug, &raw); err != nil {
+			rows.Close()
+			return nil, err
+		}
+		if p.rule, err = ParseRule(raw); err != nil {
+			// Ошибка в данных одного значка не мешает остальным
+			log.Printf("achievements: %s: %v", slug, err)
+			continue
+		}
+		list = append(list, p)
+	}
+	rows.Close()
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	if len(list) == 0 {
+		return nil, nil
+	}
+
+	facts, err := LoadFacts(ctx, db, userID, st)
+	if err != nil {
+		return nil, err
+	}
+	var awarded []int64
+	for _, p := range list {
+		ok, at := Evaluate(p.rule, facts)
+		if !ok {
+			continue
+		}
+		tag, err := db.Exec(ctx, `
+			INSERT INTO user_achievements (user_id, achievement_id, awarded_at)
+			VALUES ($1, $2, $3) ON CONFLICT DO NOTHING
+		`, userID, p.id, at)
+		if err != nil {
+			return nil, err
+		}
+		if tag.RowsAffected() > 0 {
+			awarded = append(awarded, p.id)
+		}
+	}
+	return awarded, nil
+}
+
+// Backfill выдаёт значки, добавленные после прошлого запуска (backfilled_at
+// IS NULL), всем ученикам с прогрессом. Вызывается при старте сервера.
+func Backfill(ctx context.Context, pool *pgxpool.Pool) error {
+	var ids []int64
+	rows, err := pool.Query(ctx, `SELECT id FROM achievements WHERE backfilled_at IS NULL`)
+	if err != nil {
+		return err
+	}
+	ids, err = pgx.CollectRows(rows, pgx.RowTo[int64])
+	if err != nil || len(ids) == 0 {
+		return err
+	}
+
+	rows, err = pool.Query(ctx, `SELECT DISTINCT user_id FROM progress ORDER BY user_id`)
+	if err != nil {
+		return err
+	}
+	users, err := pgx.CollectRows(rows, pgx.RowTo[int64])
+	if err != nil {
+		return err
+	}
+	for _, userID := range users {
+		if err := pgx.BeginFunc(ctx, pool, func(tx pgx.Tx) error {
+			st, err := stats.Recompute(ctx, tx, userID)
+			if err != nil {
+				return err
+			}
+			_, err = Award(ctx, tx, userID, st)
+			return err
+		}); err != nil {
+			return fmt.Errorf("user %d: %w", userID, err)
+		}
+	}
+
+	_, err = pool.Exec(ctx, `UPDATE achievements SET backfilled_at = NOW() WHERE id = ANY($1)`, ids)
+	return err
+}
@@ -0,0 +1,110 @@
+package audio
+
+import (
+	"math"
+
+	"sonara-space/backend/internal/music"
+)
+
+// analysisRate — частота, до которой понижается запись перед анализом
+const analysisRate = 16000
+
+// toneThreshold — доля максимума хромы, начиная с которой звук считается сыгранным
+const toneThreshold = 0.5
+
+// NoteResult — результат анализа попытки сыграть ноту
+type NoteResult struct {
+	DetectedFrequency float64 `json:"detected_frequency"`
+	DetectedNote      string  `json:"detected_note,omitempty"`
+	CentsOff          float64 `json:"cents_off"`
+	Voiced            float64 `json:"voiced"`
+	Match             bool    `json:"match"`
+}
+
+// ChordResult — результат анализа попытки сыграть аккорд
+type ChordResult struct {
+	DetectedChord string   `json:"detected_chord,omitempty"`
+	Similarity    float64  `json:"similarity"`
+	TonesFound    []string `json:"tones_found"`
+	TonesMissing  []string `json:"tones_missing"`
+	Completeness  float64  `json:"completeness"`
+	Match         bool     `json:"match"`
+	Chroma        Chroma   `json:"chroma"`
+}
+
+// Band — полоса частот инструмента, Гц: от нижней до верхней ноты его
+// диапазона. Нулевая полоса ничего не ограничивает.
+type Band struct {
+	Low, High float64
+}
+
+// semitone — отношение частот соседних полутонов: запас на расстройку по краям полосы
+var semitone = math.Pow(2, 1.0/12)
+
+// AnalyzeNote определяет высоту тона записи и сравнивает её с ожидаемой нотой.
+// Нота засчитывается, если звук попал в тот же полутон (±50 центов).
+func AnalyzeNote(b *Buffer, expected music.Note, a4 float64) NoteResult {
+	return AnalyzeNoteInBand(b, expected, a4, Band{})
+}
+
+// AnalyzeNoteInBand — как AnalyzeNote, но тон ищется только в полосе
+// инструмента: звуки, которые на нём не сыграть, не путают анализ
+func AnalyzeNoteInBand(b *Buffer, expected music.Note, a4 float64, band Band) NoteResult {
+	target := expected.Frequency(a4)
+	// Ищем тон чуть шире октавы в обе стороны, чтобы заметить ошибку на октаву
+	minFreq, maxFreq := target/2.5, target*2.5
+	if band.Low > 0 && band.Low/semitone < target {
+		minFreq = math.Max(minFreq, band.Low/semitone)
+	}
+	if band.High > 0 && band.High*semitone > target {
+		maxFreq = math.Min(maxFreq, band.High*semitone)
+	}
+	frames := PitchTrack(b.Resample(analysisRate), minFreq, maxFreq)
+	freq, voiced := DominantPitch(frames)
+
+	res := NoteResult{DetectedFrequency: freq, Voiced: voiced}
+	if freq == 0 {
+		return res
+	}
+	detected := music.NoteFromMIDI(int(math.Round(music.FrequencyToMIDI(freq, a4))), expected.Accidental < 0)
+	res.DetectedNote = detected.String()
+	res.CentsOff = expected.CentsOff(freq, a4)
+	res.Match = math.Abs(res.CentsOff) < 50
+	return res
+}
+
+// AnalyzeChord строит хрому записи и сравнивает её с ожидаемым аккордом
+func AnalyzeChord(b *Buffer, expected music.Chord, a4 float64) ChordResult {
+	return AnalyzeChordInBand(b, expected, a4, Band{})
+}
+
+// AnalyzeChordInBand — как AnalyzeChord, но хрома строится от нижней ноты
+// инструмента: гул и шум ниже неё не учитываются
+func AnalyzeChordInBand(b *Buffer, expected music.Chord, a4 float64, band Band) ChordResult {
+	chroma := ComputeChromaInBand(b.Resample(analysisRate), a4, band)
+	res := ChordResult{Chroma: chroma, TonesFound: []string{}, TonesMissing: []string{}}
+
+	present := chroma.Present(toneThreshold)
+	if present == 0 {
+		return res
+	}
+
+	tones := expected.Tones()
+	for _, tone := range tones {
+		if present.Has(tone.PitchClass()) {
+			res.TonesFound = append(res.TonesFound, tone.String())
+		} else {
+			res.TonesMissing = append(res.TonesMissing, tone.String())
+		}
+	}
+	if len(tones) > 0 {
+		res.Completeness = float64(len(res.TonesFound)) / float64(len(tones))
+	}
+
+	detected, similarity := RecognizeChord(chroma, expected)
+	res.DetectedChord = detected.String()
+	res.Similarity = similarity
+	res.Match = detected.Equivalent(expected) ||
+		detected.PitchClasses() == expected.PitchClasses()
+	return res
+}
@@ -0,0 +1,181 @@
+package audio
+
+import (
+	"math"
+	"math/cmplx"
+
+	"sonara-space/backend/internal/music"
+)
+
+// Chroma — энергия по 12 звуковысотным классам (0 = C), нормированная к максимуму
+type Chroma [12]float64
+
+const (
+	chromaFrameSize = 4096
+	chromaMinFreq   = 60.0
+	chromaMaxFreq   = 2000.0
+)
+
+// fft — итеративное БПФ по основанию 2; длина входа должна быть степенью двойки
+func fft(x []complex128) {
+	n := len(x)
+	for i, j := 1, 0; i < n; i++ {
+		bit := n >> 1
+		for ; j&bit != 0; bit >>= 1 {
+			j ^= bit
+		}
+		j ^= bit
+		if i < j {
+			x[i], x[j] = x[j], x[i]
+		}
+	}
+	for size := 2; size <= n; size <<= 1 {
+		step := cmplx.Exp(complex(0, -2*math.Pi/float64(size)))
+		for start := 0; start < n; start += size {
+			w := complex(1, 0)
+			for k := 0; k < size/2; k++ {
+				a := x[start+k]
+				b := x[start+k+size/2] * w
+				x[start+k] = a + b
+				x[start+k+size/2] = a - b
+				w *= step
+			}
+		}
+	}
+}
+
+// ComputeChroma усредняет хрому по всем не-тихим кадрам записи
+func ComputeChroma(b *Buffer, a4 float64) Chroma {
+	return ComputeChromaInBand(b, a4, Band{})
+}
+
+// ComputeChromaInBand — как ComputeChroma, но нижняя граница спектра берётся
+// по нижней ноте инструмента (для баса она ниже обычной, для укулеле — выше).
+// Верхняя граница не сужается: обертоны помогают узнать аккорд.
+func ComputeChromaInBand(b *Buffer, a4 float64, band Band) Chroma {
+	minFreq := chromaMinFreq
+	if band.Low > 0 {
+		minFreq = band.Low / semitone
+	}
+	var total Chroma
+	if b.SampleRate == 0 {
+		return total
+	}
+
+	hann := make([]float64, chromaFrameSize)
+	for i := range hann {
+		hann[i] = 0.5 - 0.5*math.Cos(2*math.Pi*float64(i)/float64(chromaFrameSize-1))
+	}
+
+	// Номер класса для каждого бина спектра считаем один раз
+	binClass := make([]int, chromaFrameSize/2)
+	for k := range binClass {
+		freq := float64(k) * float64(b.SampleRate) / chromaFrameSize
+		if freq < minFreq || freq > chromaMaxFreq {
+			binClass[k] = -1
+			continue
+		}
+		midi := int(math.Round(music.FrequencyToMIDI(freq, a4)))
+		binClass[k] = ((midi % 12) + 12) % 12
+	}
+
+	buf := make([]complex128, chromaFrameSize)
+	for start := 0; start+chromaFrameSize <= len(b.Samples); start += chromaFrameSize / 2 {
+		frame := b.Samples[start : start+chromaFrameSize]
+		if RMS(frame) < silenceRMS {
+			continue
+		}
+		for i, s := range frame {
+			buf[i] = complex(s*hann[i], 0)
+		}
+		fft(buf)
+		for k, pc := range binClass {
+			if pc < 0 {
+				continue
+			}
+			mag := cmplx.Abs(buf[k])
+			// Логарифмическое сжатие, чтобы громкий бас не забивал остальные звуки
+			total[pc] += math.Log1p(mag)
+		}
+	}
+	return total.normalized()
+}
+
+func (c Chroma) normalized() Chroma {
+	var max float64
+	for _, v := range c {
+		max = math.Max(max, v)
+	}
+	if max == 0 {
+		return c
+	}
+	for i := range c {
+		c[i] /= max
+	}
+	return c
+}
+
+// Similarity — насколько хрома похожа на шаблон набора классов: средняя
+// энергия звуков шаблона минус средняя энергия остальных (от -1 до 1).
+// В отличие от косинусной близости не поощряет лишние звуки шаблона,
+// которые попадают на обертоны (B в трезвучии C от третьей гармоники E).
+func (c Chroma) Similarity(set music.PitchClassSet) float64 {
+	var in, out float64
+	var nIn, nOut int
+	for pc := 0; pc < 12; pc++ {
+		if set.Has(music.PitchClass(pc)) {
+			in += c[pc]
+			nIn++
+		} else {
+			out += c[pc]
+			nOut++
+		}
+	}
+	if nIn == 0 {
+		return 0
+	}
+	score := in / float64(nIn)
+	if nOut > 0 {
+		score -= out / float64(nOut)
+	}
+	return score
+}
+
+// Present возвращает классы, энергия которых не ниже threshold (0..1)
+func (c Chroma) Present(threshold float64) music.PitchClassSet {
+	var set music.PitchClassSet
+	for pc, v := range c {
+		if v >= threshold {
+			set = set.Add(music.PitchClass(pc))
+		}
+	}
+	return set
+}
+
+// chordCandidates — типы аккордов, среди которых ищется лучшее совпадение
+var chordCandidates = []string{"", "m", "7", "maj7", "m7", "dim", "aug", "sus2", "sus4"}
+
+// RecognizeChord подбирает аккорд, шаблон которого лучше всего совпадает
+// с хромой. extra — дополнительные кандидаты (например, ожидаемый аккорд).
+func RecognizeChord(c Chroma, extra ...music.Chord) (music.Chord, float64) {
+	var best music.Chord
+	bestScore := math.Inf(-1)
+	consider := func(chord music.Chord) {
+		score := c.Similarity(chord.PitchClasses())
+		if score > bestScore {
+			best, bestScore = chord, score
+		}
+	}
+	for _, chord := range extra {
+		consider(chord)
+	}
+	for pc := 0; pc < 12; pc++ {
+		for _, q := range chordCandidates {
+			chord, err := music.ParseChord(music.PitchClass(pc).Name(false) + q)
+			if err == nil {
+				consider(chord)
+			}
+		}
+	}
+	return best, bestScore
+}
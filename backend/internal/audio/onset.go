@@ -0,0 +1,72 @@
+package audio
+
+import "math"
+
+const (
+	// onsetFrame — шаг огибающей громкости, с
+	onsetFrame = 0.005
+	// onsetRise — во сколько раз громкость должна вырасти относительно
+	// недавнего уровня, чтобы считаться новым ударом (~6 дБ)
+	onsetRise = 2.0
+	// onsetGap — удары ближе этого интервала сливаются в один, с
+	onsetGap = 0.06
+	// onsetHistory — сколько кадров назад смотрим на "недавний уровень"
+	onsetHistory = 4
+)
+
+// Onsets находит начала ударов (атаки) в записи и возвращает их моменты в
+// секундах от начала. Удар — резкий рост громкости над тишиной: огибающая
+// считается кадрами по 5 мс и сравнивается с минимумом предыдущих кадров.
+func Onsets(b *Buffer) []float64 {
+	step := int(onsetFrame * float64(b.SampleRate))
+	if step == 0 {
+		return nil
+	}
+	n := len(b.Samples) / step
+	env := make([]float64, n)
+	for i := range env {
+		env[i] = RMS(b.Samples[i*step : (i+1)*step])
+	}
+
+	var onsets []float64
+	last := math.Inf(-1)
+	for i := range env {
+		if env[i] < silenceRMS {
+			continue
+		}
+		floor := math.Inf(1)
+		for j := max(0, i-onsetHistory); j < i; j++ {
+			floor = math.Min(floor, env[j])
+		}
+		if i == 0 {
+			floor = 0
+		}
+		// Запись может начинаться сразу со звука: рост "от тишины"
+		if env[i] < onsetRise*math.Max(floor, silenceRMS/onsetRise) {
+			continue
+		}
+		t := float64(i) * onsetFrame
+		if t-last < onsetGap {
+			continue
+		}
+		onsets = append(onsets, attackTime(b, i*step, step))
+		last = t
+	}
+	return onsets
+}
+
+// attackTime уточняет момент атаки внутри кадра: первый отсчёт, который
+// достигает половины пика кадра
+func attackTime(b *Buffer, start, step int) float64 {
+	end := min(len(b.Samples), start+step)
+	var peak float64
+	for _, s := range b.Samples[start:end] {
+		peak = math.Max(peak, math.Abs(s))
+	}
+	for i := start; i < end; i++ {
+		if math.Abs(b.Samples[i]) >= peak/2 {
+			return float64(i) / float64(b.SampleRate)
+		}
+	}
+	return float64(start) / float64(b.SampleRate)
+}
@@ -0,0 +1,116 @@
+package audio
+
+import (
+	"math"
+	"sort"
+)
+
+// silenceRMS — ниже этого уровня кадр считается тишиной
+const silenceRMS = 0.01
+
+// yinThreshold — порог нормированной разностной функции алгоритма YIN
+const yinThreshold = 0.15
+
+// PitchFrame — оценка высоты тона в одном кадре
+type PitchFrame struct {
+	Time       float64 // начало кадра, секунды
+	Frequency  float64 // 0, если тон не найден
+	Confidence float64 // 0..1
+}
+
+// PitchTrack разбивает запись на кадры и оценивает высоту тона в каждом
+// алгоритмом YIN (de Cheveigné, Kawahara, 2002) в диапазоне [minFreq, maxFreq].
+func PitchTrack(b *Buffer, minFreq, maxFreq float64) []PitchFrame {
+	if b.SampleRate == 0 || minFreq <= 0 || maxFreq <= minFreq {
+		return nil
+	}
+	rate := float64(b.SampleRate)
+	maxTau := int(rate / minFreq)
+	minTau := int(rate / maxFreq)
+	if minTau < 2 {
+		minTau = 2
+	}
+	window := maxTau
+	hop := window / 2
+	if hop == 0 {
+		return nil
+	}
+
+	var frames []PitchFrame
+	diff := make([]float64, maxTau+1)
+	for start := 0; start+window+maxTau <= len(b.Samples); start += hop {
+		frame := b.Samples[start : start+window+maxTau]
+		pf := PitchFrame{Time: float64(start) / rate}
+		if RMS(frame[:window]) >= silenceRMS {
+			pf.Frequency, pf.Confidence = yin(frame, window, minTau, maxTau, rate, diff)
+		}
+		frames = append(frames, pf)
+	}
+	return frames
+}
+
+func yin(frame []float64, window, minTau, maxTau int, rate float64, d []float64) (float64, float64) {
+	// Разностная функция
+	for tau := 1; tau <= maxTau; tau++ {
+		var sum float64
+		for j := 0; j < window; j++ {
+			delta := frame[j] - frame[j+tau]
+			sum += delta * delta
+		}
+		d[tau] = sum
+	}
+
+	// Кумулятивная нормировка
+	d[0] = 1
+	var running float64
+	for tau := 1; tau <= maxTau; tau++ {
+		running += d[tau]
+		if running == 0 {
+			d[tau] = 1
+		} else {
+			d[tau] = d[tau] * float64(tau) / running
+		}
+	}
+
+	// Первый локальный минимум ниже порога
+	tau := -1
+	for t := minTau; t <= maxTau; t++ {
+		if d[t] < yinThreshold {
+			for t+1 <= maxTau && d[t+1] < d[t] {
+				t++
+			}
+			tau = t
+			break
+		}
+	}
+	if tau < 0 {
+		return 0, 0
+	}
+
+	// Параболическая интерполяция вокруг минимума
+	better := float64(tau)
+	if tau > 1 && tau < maxTau {
+		s0, s1, s2 := d[tau-1], d[tau], d[tau+1]
+		denom := 2 * (2*s1 - s2 - s0)
+		if denom != 0 {
+			better = float64(tau) + (s2-s0)/denom
+		}
+	}
+	return rate / better, math.Max(0, 1-d[tau])
+}
+
+// DominantPitch возвращает медианную частоту по кадрам с тоном
+// и долю таких кадров среди всех кадров записи.
+func DominantPitch(frames []PitchFrame) (freq float64, voiced float64) {
+	var freqs []float64
+	for _, f := range frames {
+		if f.Frequency > 0 {
+			freqs = append(freqs, f.Frequency)
+		}
+	}
+	if len(freqs) == 0 || len(frames) == 0 {
+		return 0, 0
+	}
+	sort.Float64s(freqs)
+	return freqs[len(freqs)/2], float64(len(freqs)) / float64(len(frames))
+}
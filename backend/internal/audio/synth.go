@@ -0,0 +1,87 @@
+package audio
+
+import (
+	"math"
+
+	"sonara-space/backend/internal/music"
+)
+
+const (
+	// synthAttack и synthRelease — нарастание и отпускание звука, с: без них
+	// на краях нот слышны щелчки
+	synthAttack  = 0.01
+	synthRelease = 0.08
+	// synthDecay — скорость затухания звука, 1/с (похоже на электропиано)
+	synthDecay = 1.5
+	// synthPeak — максимальная амплитуда готовой записи
+	synthPeak = 0.8
+)
+
+// synthHarmonics — громкость обертонов относительно основного тона
+var synthHarmonics = []float64{1, 0.4, 0.15, 0.05}
+
+// Tone — звук синтезатора: ноты (MIDI), которые начинаются одновременно в
+// момент Start и звучат Duration секунд. Пустой MIDI — пауза.
+type Tone struct {
+	MIDI     []int
+	Start    float64
+	Duration float64
+}
+
+// Synthesize озвучивает звуки простым аддитивным синтезатором: основной тон
+// с несколькими обертонами, короткая атака, экспоненциальное затухание и
+// отпускание в конце ноты. Результат нормируется, чтобы аккорды не перегружали запись.
+func Synthesize(tones []Tone, sampleRate int, a4 float64) *Buffer {
+	var end float64
+	for _, t := range tones {
+		end = math.Max(end, t.Start+t.Duration)
+	}
+	b := &Buffer{SampleRate: sampleRate, Samples: make([]float64, int((end+synthRelease)*float64(sampleRate)))}
+
+	rate := float64(sampleRate)
+	for _, t := range tones {
+		if len(t.MIDI) == 0 || t.Duration <= 0 {
+			continue
+		}
+		from := int(t.Start * rate)
+		length := int((t.Duration + synthRelease) * rate)
+		gain := 1 / float64(len(t.MIDI))
+		for _, midi := range t.MIDI {
+			freq := music.MIDIToFrequency(float64(midi), a4)
+			for i := 0; i < length && from+i < len(b.Samples); i++ {
+				sec := float64(i) / rate
+				var v float64
+				for h, amp := range synthHarmonics {
+					// Обертоны выше половины частоты дискретизации дают искажения
+					if f := freq * float64(h+1); f < rate/2 {
+						v += amp * math.Sin(2*math.Pi*f*sec)
+					}
+				}
+				b.Samples[from+i] += gain * v * envelope(sec, t.Duration)
+			}
+		}
+	}
+
+	var peak float64
+	for _, s := range b.Samples {
+		peak = math.Max(peak, math.Abs(s))
+	}
+	if peak > 0 {
+		for i := range b.Samples {
+			b.Samples[i] *= synthPeak / peak
+		}
+	}
+	return b
+}
+
+// envelope — громкость ноты длительностью d в момент sec от её начала
+func envelope(sec, d float64) float64 {
+	level := math.Exp(-synthDecay * sec)
+	switch {
+	case sec < synthAttack:
+		level *= sec / synthAttack
+	case sec > d:
+		level *= math.Max(0, 1-(sec-d)/synthRelease)
+	}
+	return level
+}
@@ -0,0 +1,262 @@
+// Package audio содержит разбор записей (WAV/PCM) и анализ звука на чистом
+// Go: определение высоты тона (YIN) и распознавание аккордов по хроме.
+package audio
+
+import (
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"io"
+	"math"
+)
+
+// Buffer — моно-запись с отсчётами в диапазоне [-1, 1]
+type Buffer struct {
+	SampleRate int
+	Samples    []float64
+}
+
+// Duration возвращает длительность записи в секундах
+func (b *Buffer) Duration() float64 {
+	if b.SampleRate == 0 {
+		return 0
+	}
+	return float64(len(b.Samples)) / float64(b.SampleRate)
+}
+
+// Slice возвращает часть записи между from и to (в секундах)
+func (b *Buffer) Slice(from, to float64) *Buffer {
+	start := int(from * float64(b.SampleRate))
+	end := int(to * float64(b.SampleRate))
+	if start < 0 {
+		start = 0
+	}
+	if end > len(b.Samples) {
+		end = len(b.Samples)
+	}
+	if start > end {
+		start = end
+	}
+	return &Buffer{SampleRate: b.SampleRate, Samples: b.Samples[start:end]}
+}
+
+var ErrUnsupportedFormat = errors.New("audio: unsupported WAV format")
+
+// maxFmtChunk ограничивает размер блока fmt: в WAVE_FORMAT_EXTENSIBLE он 40 байт,
+// а больший размер в заголовке — признак испорченного или подделанного файла
+const maxFmtChunk = 64
+
+const (
+	wavFormatPCM   = 1
+	wavFormatFloat = 3
+	wavFormatExt   = 0xFFFE
+)
+
+// DecodeWAV читает WAV-файл (PCM 8/16/24/32 бит или float32) и сводит каналы в моно
+func DecodeWAV(r io.Reader) (*Buffer, error) {
+	var header [12]byte
+	if _, err := io.ReadFull(r, header[:]); err != nil {
+		return nil, fmt.Errorf("audio: read RIFF header: %w", err)
+	}
+	if string(header[0:4]) != "RIFF" || string(header[8:12]) != "WAVE" {
+		return nil, errors.New("audio: not a RIFF/WAVE file")
+	}
+
+	var (
+		format        uint16
+		channels      int
+		sampleRate    int
+		bitsPerSample int
+		haveFmt       bool
+	)
+
+	for {
+		var chunk [8]byte
+		if _, err := io.ReadFull(r, chunk[:]); err != nil {
+			return nil, fmt.Errorf("audio: missing data chunk: %w", err)
+		}
+		id := string(chunk[0:4])
+		size := int64(binary.LittleEndian.Uint32(chunk[4:8]))
+
+		switch id {
+		case "fmt ":
+			if size < 16 || size > maxFmtChunk {
+				return nil, ErrUnsupportedFormat
+			}
+			body := make([]byte, size)
+			if _, err := io.ReadFull(r, body); err != nil {
+				return nil, fmt.Errorf("audio: read fmt chunk: %w", err)
+			}
+			format = binary.LittleEndian.Uint16(body[0:2])
+			channels = int(binary.LittleEndian.Uint16(body[2:4]))
+			sampleRate = int(binary.LittleEndian.Uint32(body[4:8]))
+			bitsPerSample = int(binary.LittleEndian.Uint16(body[14:16]))
+			if format == wavFormatExt && size >= 26 {
+				format = binary.LittleEndian.Uint16(body[24:26])
+			}
+			haveFmt = true
+			if size%2 == 1 {
+				io.CopyN(io.Discard, r, 1)
+			}
+
+		case "data":
+			if !haveFmt {
+				return nil, errors.New("audio: data chunk before fmt chunk")
+			}
+			if channels <= 0 || sampleRate <= 0 {
+				return nil, ErrUnsupportedFormat
+			}
+			data, err := io.ReadAll(io.LimitReader(r, size))
+			if err != nil {
+				return nil, fmt.Errorf("audio: read data chunk: %w", err)
+			}
+			samples, err := decodeSamples(data, format, bitsPerSample, channels)
+			if err != nil {
+				return nil, err
+			}
+			return &Buffer{SampleRate: sampleRate, Samples: samples}, nil
+
+		default:
+			if _, err := io.CopyN(io.Discard, r, size+size%2); err != nil {
+				return nil, fmt.Errorf("audio: skip %q chunk: %w", id, err)
+			}
+		}
+	}
+}
+
+// DecodePCM16 разбирает "сырые" 16-битные little-endian отсчёты
+func DecodePCM16(data []byte, sampleRate, channels int) (*Buffer, error) {
+	if sampleRate <= 0 || channels <= 0 {
+		return nil, errors.New("audio: sample rate and channels must be positive")
+	}
+	samples, err := decodeSamples(data, wavFormatPCM, 16, channels)
+	if err != nil {
+		return nil, err
+	}
+	return &Buffer{SampleRate: sampleRate, Samples: samples}, nil
+}
+
+func decodeSamples(data []byte, format uint16, bits, channels int) ([]float64, error) {
+	bytesPerSample := bits / 8
+	if bits%8 != 0 || bytesPerSample == 0 {
+		return nil, ErrUnsupportedFormat
+	}
+	switch {
+	case format == wavFormatPCM && bits <= 32:
+	case format == wavFormatFloat && bits == 32:
+	default:
+		return nil, ErrUnsupportedFormat
+	}
+
+	frameSize := bytesPerSample * channels
+	frames := len(data) / frameSize
+	out := make([]float64, frames)
+	for i := 0; i < frames; i++ {
+		var sum float64
+		for ch := 0; ch < channels; ch++ {
+			off := i*frameSize + ch*bytesPerSample
+			sum += sampleAt(data[off:off+bytesPerSample], format, bits)
+		}
+		out[i] = sum / float64(channels)
+	}
+	return out, nil
+}
+
+func sampleAt(b []byte, format uint16, bits int) float64 {
+	if format == wavFormatFloat {
+		return float64(math.Float32frombits(binary.LittleEndian.Uint32(b)))
+	}
+	switch bits {
+	case 8:
+		// 8-битный WAV беззнаковый
+		return (float64(b[0]) - 128) / 128
+	case 16:
+		return float64(int16(binary.LittleEndian.Uint16(b))) / 32768
+	case 24:
+		v := int32(b[0]) | int32(b[1])<<8 | int32(b[2])<<16
+		if v&0x800000 != 0 {
+			v |= ^0xFFFFFF
+		}
+		return float64(v) / 8388608
+	default:
+		return float64(int32(binary.LittleEndian.Uint32(b))) / 2147483648
+	}
+}
+
+// EncodeWAV записывает буфер как 16-битный моно WAV
+func EncodeWAV(w io.Writer, b *Buffer) error {
+	dataSize := len(b.Samples) * 2
+	header := make([]byte, 44)
+	copy(header[0:4], "RIFF")
+	binary.LittleEndian.PutUint32(header[4:8], uint32(36+dataSize))
+	copy(header[8:12], "WAVE")
+	copy(header[12:16], "fmt ")
+	binary.LittleEndian.PutUint32(header[16:20], 16)
+	binary.LittleEndian.PutUint16(header[20:22], wavFormatPCM)
+	binary.LittleEndian.PutUint16(header[22:24], 1)
+	binary.LittleEndian.PutUint32(header[24:28], uint32(b.SampleRate))
+	binary.LittleEndian.PutUint32(header[28:32], uint32(b.SampleRate*2))
+	binary.LittleEndian.PutUint16(header[32:34], 2)
+	binary.LittleEndian.PutUint16(header[34:36], 16)
+	copy(header[36:40], "data")
+	binary.LittleEndian.PutUint32(header[40:44], uint32(dataSize))
+	if _, err := w.Write(header); err != nil {
+		return err
+	}
+
+	data := make([]byte, dataSize)
+	for i, s := range b.Samples {
+		if s > 1 {
+			s = 1
+		} else if s < -1 {
+			s = -1
+		}
+		binary.LittleEndian.PutUint16(data[i*2:], uint16(int16(math.Round(s*32767))))
+	}
+	_, err := w.Write(data)
+	return err
+}
+
+// Resample понижает частоту дискретизации в целое число раз, усредняя
+// соседние отсчёты (грубый ФНЧ, которого достаточно для анализа высоты тона).
+func (b *Buffer) Resample(maxRate int) *Buffer {
+	factor := b.SampleRate / maxRate
+	if factor <= 1 {
+		return b
+	}
+	out := make([]float64, len(b.Samples)/factor)
+	for i := range out {
+		var sum float64
+		for j := 0; j < factor; j++ {
+			sum += b.Samples[i*factor+j]
+		}
+		out[i] = sum / float64(factor)
+	}
+	return &Buffer{SampleRate: b.SampleRate / factor, Samples: out}
+}
+
+// TrimLeadingSilence отбрасывает тишину в начале записи (с точностью до 10 мс)
+func TrimLeadingSilence(b *Buffer) *Buffer {
+	step := b.SampleRate / 100
+	if step == 0 {
+		return b
+	}
+	for start := 0; start+step <= len(b.Samples); start += step {
+		if RMS(b.Samples[start:start+step]) >= silenceRMS {
+			return &Buffer{SampleRate: b.SampleRate, Samples: b.Samples[start:]}
+		}
+	}
+	return b
+}
+
+// RMS возвращает среднеквадратичную громкость отсчётов
+func RMS(samples []float64) float64 {
+	if len(samples) == 0 {
+		return 0
+	}
+	var sum float64
+	for _, s := range samples {
+		sum += s * s
+	}
+	return math.Sqrt(sum / float64(len(samples)))
+}
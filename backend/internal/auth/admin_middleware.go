@@ -0,0 +1,36 @@
+package auth
+
+import (
+	"log"
+	"net/http"
+
+	"sonara-space/backend/internal/db"
+)
+
+// RequireAdmin пропускает дальше только пользователей с флагом is_admin.
+// Должен стоять после JWTMiddleware.
+func RequireAdmin(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		userID, ok := r.Context().Value(UserIDKey).(int64)
+		if !ok || userID == 0 {
+			http.Error(w, "Unauthorized", http.StatusUnauthorized)
+			return
+		}
+
+		var isAdmin bool
+		err := db.Pool.QueryRow(r.Context(),
+			"SELECT is_admin FROM users WHERE id=$1", userID,
+		).Scan(&isAdmin)
+		if err != nil {
+			log.Printf("RequireAdmin: user %d lookup failed: %v", userID, err)
+			http.Error(w, "admin access required", http.StatusForbidden)
+			return
+		}
+		if !isAdmin {
+			http.Error(w, "admin access required", http.StatusForbidden)
+			return
+		}
+
+		next.ServeHTTP(w, r)
+	})
+}
@@ -0,0 +1,517 @@
+// Package content описывает декларативный формат контента (курсы, уроки,
+// упражнения, песни, медиа) в YAML/JSON и его импорт/экспорт в базу.
+// Записи связываются по стабильным slug, а не по ID, поэтому файлы можно
+// хранить в git и импортировать повторно: импорт идемпотентен.
+package content
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"path/filepath"
+	"strings"
+
+	"sonara-space/backend/internal/i18n"
+	"sonara-space/backend/internal/instruments"
+	"sonara-space/backend/internal/models"
+
+	"gopkg.in/yaml.v3"
+)
+
+// FormatVersion — текущая версия формата файлов
+const FormatVersion = 1
+
+// Bundle — файл контента
+type Bundle struct {
+	Version int      `yaml:"version" json:"version"`
+	Skills  []Skill  `yaml:"skills,omitempty" json:"skills,omitempty"`
+	Courses []Course `yaml:"courses,omitempty" json:"courses,omitempty"`
+	Lessons []Lesson `yaml:"lessons,omitempty" json:"lessons,omitempty"`
+}
+
+// Skill — навык таксономии. Упражнения ссылаются на навыки по slug; навыки,
+// которых нет в файле, должны уже быть в базе.
+type Skill struct {
+	Slug        string `yaml:"slug" json:"slug"`
+	Name        string `yaml:"name" json:"name"`
+	Instrument  string `yaml:"instrument,omitempty" json:"instrument,omitempty"`
+	Description string `yaml:"description,omitempty" json:"description,omitempty"`
+}
+
+// Course — курс; модули ссылаются на уроки по slug
+type Course struct {
+	Slug        string   `yaml:"slug" json:"slug"`
+	Title       string   `yaml:"title" json:"title"`
+	Instrument  string   `yaml:"instrument" json:"instrument"`
+	Description string   `yaml:"description,omitempty" json:"description,omitempty"`
+	Status      string   `yaml:"status,omitempty" json:"status,omitempty"`
+	Modules     []Module `yaml:"modules" json:"modules"`
+}
+
+// Module — модуль курса
+type Module struct {
+	Title   string   `yaml:"title" json:"title"`
+	Lessons []string `yaml:"lessons" json:"lessons"`
+}
+
+// Lesson — урок с упражнениями. Порядок упражнений — порядок в файле.
+type Lesson struct {
+	Slug          string                       `yaml:"slug" json:"slug"`
+	Title         string                       `yaml:"title" json:"title"`
+	Instrument    string                       `yaml:"instrument" json:"instrument"`
+	Tuning        string                       `yaml:"tuning,omitempty" json:"tuning,omitempty"`
+	Capo          int                          `yaml:"capo,omitempty" json:"capo,omitempty"`
+	Description   string                       `yaml:"description,omitempty" json:"description,omitempty"`
+	Status        string                       `yaml:"status,omitempty" json:"status,omitempty"`
+	Difficulty    string                       `yaml:"difficulty,omitempty" json:"difficulty,omitempty"`
+	Tier          string                       `yaml:"tier,omitempty" json:"tier,omitempty"`
+	Locale        string                       `yaml:"locale,omitempty" json:"locale,omitempty"`
+	Tags          []string                     `yaml:"tags,omitempty" json:"tags,omitempty"`
+	Prerequisites []Prerequisite               `yaml:"prerequisites,omitempty" json:"prerequisites,omitempty"`
+	Media         []Media                      `yaml:"media,omitempty" json:"media,omitempty"`
+	Translations  map[string]LessonTranslation `yaml:"translations,omitempty" json:"translations,omitempty"`
+	Exercises     []Exercise                   `yaml:"exercises" json:"exercises"`
+}
+
+// Prerequisite — урок открывается после MinPercent% урока Lesson
+type Prerequisite struct {
+	Lesson     string `yaml:"lesson" json:"lesson"`
+	MinPercent int    `yaml:"min_percent,omitempty" json:"min_percent,omitempty"`
+}
+
+// Media — ссылка на аудио, видео, ноты или картинку урока
+type Media struct {
+	Kind  string `yaml:"kind" json:"kind"`
+	URI   string `yaml:"uri" json:"uri"`
+	Title string `yaml:"title,omitempty" json:"title,omitempty"`
+}
+
+// LessonTranslation — перевод урока на другой язык
+type LessonTranslation struct {
+	Title       string `yaml:"title,omitempty" json:"title,omitempty"`
+	Description string `yaml:"description,omitempty" json:"description,omitempty"`
+}
+
+// Exercise — упражнение; для type: sequence вместо expected задаётся song,
+// для type: rhythm — rhythm
+type Exercise struct {
+	Slug         string            `yaml:"slug" json:"slug"`
+	Title        string            `yaml:"title" json:"title"`
+	Type         string            `yaml:"type" json:"type"`
+	Expected     string            `yaml:"expected,omitempty" json:"expected,omitempty"`
+	Song         *Song             `yaml:"song,omitempty" json:"song,omitempty"`
+	Rhythm       *RhythmSpec       `yaml:"rhythm,omitempty" json:"rhythm,omitempty"`
+	Skills       []string          `yaml:"skills,omitempty" json:"skills,omitempty"`
+	Translations map[string]string `yaml:"translations,omitempty" json:"translations,omitempty"`
+}
+
+// Song — песня или мелодия. Размер записывается как "3/4".
+type Song struct {
+	Title         string `yaml:"title" json:"title"`
+	StepType      string `yaml:"step_type" json:"step_type"`
+	Tempo         int    `yaml:"tempo" json:"tempo"`
+	TimeSignature string `yaml:"time_signature" json:"time_signature"`
+	Steps         []Step `yaml:"steps" json:"steps"`
+}
+
+// Step — шаг песни
+type Step struct {
+	Symbol string  `yaml:"symbol" json:"symbol"`
+	Beats  float64 `yaml:"beats" json:"beats"`
+	Lyric  string  `yaml:"lyric,omitempty" json:"lyric,omitempty"`
+}
+
+// Sequence переводит песню в модель exercise_sequences
+func (s *Song) Sequence() (models.Sequence, error) {
+	seq := models.Sequence{SongTitle: s.Title, StepType: s.StepType, Tempo: s.Tempo}
+	if _, err := fmt.Sscanf(s.TimeSignature, "%d/%d", &seq.BeatsPerBar, &seq.BeatUnit); err != nil {
+		return seq, fmt.Errorf("invalid time_signature %q, expected like 3/4", s.TimeSignature)
+	}
+	for _, st := range s.Steps {
+		step := models.SequenceStep{Symbol: st.Symbol, Beats: st.Beats}
+		if st.Lyric != "" {
+			lyric := st.Lyric
+			step.Lyric = &lyric
+		}
+		seq.Steps = append(seq.Steps, step)
+	}
+	return seq, nil
+}
+
+// songFromSequence — обратное преобразование для экспорта
+func songFromSequence(seq *models.Sequence) *Song {
+	song := &Song{
+		Title:         seq.SongTitle,
+		StepType:      seq.StepType,
+		Tempo:         seq.Tempo,
+		TimeSignature: fmt.Sprintf("%d/%d", seq.BeatsPerBar, seq.BeatUnit),
+	}
+	for _, st := range seq.Steps {
+		step := Step{Symbol: st.Symbol, Beats: st.Beats}
+		if st.Lyric != nil {
+			step.Lyric = *st.Lyric
+		}
+		song.Steps = append(song.Steps, step)
+	}
+	return song
+}
+
+// RhythmSpec — ритмический рисунок. Рисунок записывается строкой длительностей
+// в долях: "!1 1 0.5 0.5 r1" (r — пауза, ! — акцент). Без count_in отсчёт —
+// один такт, окна допуска без значений — по умолчанию.
+type RhythmSpec struct {
+	Tempo         int    `yaml:"tempo" json:"tempo"`
+	TimeSignature string `yaml:"time_signature" json:"time_signature"`
+	CountIn       *int   `yaml:"count_in,omitempty" json:"count_in,omitempty"`
+	Pattern       string `yaml:"pattern" json:"pattern"`
+	PerfectMs     int    `yaml:"perfect_ms,omitempty" json:"perfect_ms,omitempty"`
+	GoodMs        int    `yaml:"good_ms,omitempty" json:"good_ms,omitempty"`
+	MissMs        int    `yaml:"miss_ms,omitempty" json:"miss_ms,omitempty"`
+}
+
+// Rhythm переводит рисунок в модель exercise_rhythms
+func (s *RhythmSpec) Rhythm() (models.Rhythm, error) {
+	r := models.Rhythm{Tempo: s.Tempo, CountIn: 1, PerfectMs: s.PerfectMs, GoodMs: s.GoodMs, MissMs: s.MissMs}
+	if s.CountIn != nil {
+		r.CountIn = *s.CountIn
+	}
+	if _, err := fmt.Sscanf(s.TimeSignature, "%d/%d", &r.BeatsPerBar, &r.BeatUnit); err != nil {
+		return r, fmt.Errorf("invalid time_signature %q, expected like 3/4", s.TimeSignature)
+	}
+	pattern, err := models.ParseRhythmPattern(s.Pattern)
+	if err != nil {
+		return r, err
+	}
+	r.Pattern = pattern
+	return r, nil
+}
+
+// rhythmFromModel — обратное преобразование для экспорта
+func rhythmFromModel(r *models.Rhythm) *RhythmSpec {
+	countIn := r.CountIn
+	return &RhythmSpec{
+		Tempo:         r.Tempo,
+		TimeSignature: fmt.Sprintf("%d/%d", r.BeatsPerBar, r.BeatUnit),
+		CountIn:       &countIn,
+		Pattern:       r.Expected(),
+		PerfectMs:     r.PerfectMs,
+		GoodMs:        r.GoodMs,
+		MissMs:        r.MissMs,
+	}
+}
+
+// IsYAML определяет формат по расширению файла (по умолчанию YAML)
+func IsYAML(path string) bool {
+	ext := strings.ToLower(filepath.Ext(path))
+	return ext != ".json"
+}
+
+// Decode читает файл контента в YAML или JSON. Неизвестные поля — ошибка:
+// опечатка в имени поля не должна молча терять данные.
+func Decode(r io.Reader, isYAML bool) (*Bundle, error) {
+	var b Bundle
+	if isYAML {
+		dec := yaml.NewDecoder(r)
+		dec.KnownFields(true)
+		if err := dec.Decode(&b); err != nil && !errors.Is(err, io.EOF) {
+			return nil, fmt.Errorf("content: invalid YAML: %w", err)
+		}
+	} else {
+		dec := json.NewDecoder(r)
+		dec.DisallowUnknownFields()
+		if err := dec.Decode(&b); err != nil {
+			return nil, fmt.Errorf("content: invalid JSON: %w", err)
+		}
+	}
+	return &b, nil
+}
+
+// Encode записывает файл контента в YAML или JSON
+func Encode(w io.Writer, b *Bundle, isYAML bool) error {
+	if isYAML {
+		enc := yaml.NewEncoder(w)
+		enc.SetIndent(2)
+		if err := enc.Encode(b); err != nil {
+			return err
+		}
+		return enc.Close()
+	}
+	enc := json.NewEncoder(w)
+	enc.SetIndent("", "  ")
+	return enc.Encode(b)
+}
+
+// ValidationError собирает все ошибки файла, чтобы их можно было исправить за раз
+type ValidationError []string
+
+func (e ValidationError) Error() string {
+	return "content: invalid bundle:\n  " + strings.Join(e, "\n  ")
+}
+
+// Validate проверяет файл и заполняет значения по умолчанию. known — slug
+// уроков, которые уже есть в базе: на них можно ссылаться из курсов и
+// пререквизитов, не включая сами уроки в файл.
+func (b *Bundle) Validate(known map[string]bool) error {
+	var errs ValidationError
+	fail := func(format string, args ...interface{}) {
+		errs = append(errs, fmt.Sprintf(format, args...))
+	}
+
+	if b.Version == 0 {
+		b.Version = FormatVersion
+	}
+	if b.Version != FormatVersion {
+		fail("unsupported version %d", b.Version)
+	}
+
+	skills := make(map[string]bool)
+	for i := range b.Skills {
+		sk := &b.Skills[i]
+		where := fmt.Sprintf("skills[%d] (%s)", i, sk.Slug)
+		if !ValidSlug(sk.Slug) {
+			fail("%s: invalid slug", where)
+		} else if skills[sk.Slug] {
+			fail("%s: duplicate slug", where)
+		}
+		skills[sk.Slug] = true
+		sk.Name = strings.TrimSpace(sk.Name)
+		if sk.Name == "" {
+			fail("%s: name is required", where)
+		}
+		if sk.Instrument != "" && !instruments.Valid(sk.Instrument) {
+			fail("%s: invalid instrument %q", where, sk.Instrument)
+		}
+	}
+
+	lessons := make(map[string]bool)
+	for i := range b.Lessons {
+		l := &b.Lessons[i]
+		where := fmt.Sprintf("lessons[%d] (%s)", i, l.Slug)
+		if !ValidSlug(l.Slug) {
+			fail("%s: invalid slug", where)
+		} else if lessons[l.Slug] {
+			fail("%s: duplicate slug", where)
+		}
+		lessons[l.Slug] = true
+	}
+	exists := func(slug string) bool { return lessons[slug] || known[slug] }
+
+	for i := range b.Lessons {
+		l := &b.Lessons[i]
+		where := fmt.Sprintf("lessons[%d] (%s)", i, l.Slug)
+		l.Title = strings.TrimSpace(l.Title)
+		if l.Title == "" {
+			fail("%s: title is required", where)
+		}
+		setup := instruments.Setup{Instrument: l.Instrument, Tuning: l.Tuning, Capo: l.Capo}
+		rng, setupErr := setup.Range()
+		if errors.Is(setupErr, instruments.ErrUnknownInstrument) {
+			fail("%s: invalid instrument %q", where, l.Instrument)
+		} else if setupErr != nil {
+			fail("%s: %v", where, setupErr)
+		}
+		if l.Status == "" {
+			l.Status = models.LessonStatusPublished
+		}
+		if l.Status != models.LessonStatusPublished && l.Status != models.LessonStatusDraft {
+			fail("%s: invalid status %q", where, l.Status)
+		}
+		if l.Difficulty == "" {
+			l.Difficulty = models.DifficultyBeginner
+		}
+		switch l.Difficulty {
+		case models.DifficultyBeginner, models.DifficultyIntermediate, models.DifficultyAdvanced:
+		default:
+			fail("%s: invalid difficulty %q", where, l.Difficulty)
+		}
+		if l.Tier == "" {
+			l.Tier = models.TierFree
+		}
+		if l.Tier != models.TierFree && l.Tier != models.TierPremium {
+			fail("%s: invalid tier %q", where, l.Tier)
+		}
+		if l.Locale == "" {
+			l.Locale = i18n.DefaultLocale
+		}
+		if l.Tags == nil {
+			l.Tags = []string{}
+		}
+		if l.Status == models.LessonStatusPublished && len(l.Exercises) == 0 {
+			fail("%s: published lesson must have exercises", where)
+		}
+
+		for j := range l.Prerequisites {
+			p := &l.Prerequisites[j]
+			if p.MinPercent == 0 {
+				p.MinPercent = 80
+			}
+			if p.Lesson == l.Slug {
+				fail("%s: lesson cannot require itself", where)
+			} else if !exists(p.Lesson) {
+				fail("%s: prerequisite %q not found", where, p.Lesson)
+			}
+			if p.MinPercent < 1 || p.MinPercent > 100 {
+				fail("%s: prerequisite %q: min_percent must be 1..100", where, p.Lesson)
+			}
+		}
+
+		for j, m := range l.Media {
+			switch m.Kind {
+			case "audio", "video", "image", "score", "other":
+			default:
+				fail("%s: media[%d]: invalid kind %q", where, j, m.Kind)
+			}
+			if strings.TrimSpace(m.URI) == "" {
+				fail("%s: media[%d]: uri is required", where, j)
+			}
+		}
+
+		for locale := range l.Translations {
+			if !i18n.IsSupported(locale) || locale == l.Locale {
+				fail("%s: invalid translation locale %q", where, locale)
+			}
+		}
+
+		slugs := make(map[string]bool)
+		for j := range l.Exercises {
+			e := &l.Exercises[j]
+			e.Title = strings.TrimSpace(e.Title)
+			e.Expected = strings.TrimSpace(e.Expected)
+			if e.Slug == "" {
+				e.Slug = ExerciseSlug(e.Type, e.Expected)
+				// Повторяющиеся аккорды в уроке получают суффиксы: chord-am, chord-am-2
+				for n := 2; slugs[e.Slug]; n++ {
+					e.Slug = fmt.Sprintf("%s-%d", ExerciseSlug(e.Type, e.Expected), n)
+				}
+			}
+			ew := fmt.Sprintf("%s: exercises[%d] (%s)", where, j, e.Slug)
+			if !ValidSlug(e.Slug) {
+				fail("%s: invalid slug", ew)
+			} else if slugs[e.Slug] {
+				fail("%s: duplicate slug", ew)
+			}
+			slugs[e.Slug] = true
+			if e.Title == "" {
+				fail("%s: title is required", ew)
+			}
+			if err := e.validate(); err != nil {
+				fail("%s: %v", ew, err)
+			} else if notes := e.notes(); setupErr == nil {
+				if bad := rng.Outside(notes); len(bad) > 0 {
+					fail("%s: %s is outside the %s range %s", ew, notes[bad[0]], setup, rng)
+				}
+			}
+			seen := make(map[string]bool)
+			for _, skill := range e.Skills {
+				if !ValidSlug(skill) {
+					fail("%s: invalid skill %q", ew, skill)
+				} else if seen[skill] {
+					fail("%s: duplicate skill %q", ew, skill)
+				}
+				seen[skill] = true
+			}
+			for locale := range e.Translations {
+				if !i18n.IsSupported(locale) || locale == l.Locale {
+					fail("%s: invalid translation locale %q", ew, locale)
+				}
+			}
+		}
+	}
+
+	courses := make(map[string]bool)
+	for i := range b.Courses {
+		c := &b.Courses[i]
+		where := fmt.Sprintf("courses[%d] (%s)", i, c.Slug)
+		if !ValidSlug(c.Slug) {
+			fail("%s: invalid slug", where)
+		} else if courses[c.Slug] {
+			fail("%s: duplicate slug", where)
+		}
+		courses[c.Slug] = true
+		if strings.TrimSpace(c.Title) == "" {
+			fail("%s: title is required", where)
+		}
+		if !instruments.Valid(c.Instrument) {
+			fail("%s: invalid instrument %q", where, c.Instrument)
+		}
+		if c.Status == "" {
+			c.Status = models.LessonStatusPublished
+		}
+		if c.Status != models.LessonStatusPublished && c.Status != models.LessonStatusDraft {
+			fail("%s: invalid status %q", where, c.Status)
+		}
+		for j, m := range c.Modules {
+			if strings.TrimSpace(m.Title) == "" {
+				fail("%s: modules[%d]: title is required", where, j)
+			}
+			for _, slug := range m.Lessons {
+				if !exists(slug) {
+					fail("%s: modules[%d]: lesson %q not found", where, j, slug)
+				}
+			}
+		}
+	}
+
+	if len(errs) > 0 {
+		return errs
+	}
+	return nil
+}
+
+// validate проверяет ожидаемое значение или песню и заполняет Expected для песен
+// notes возвращает ноты упражнения: ожидаемую ноту или шаги мелодии
+func (e *Exercise) notes() []string {
+	switch {
+	case e.Type == models.ExerciseTypeNote:
+		return []string{e.Expected}
+	case e.Song != nil && e.Song.StepType == models.ExerciseTypeNote:
+		notes := make([]string, len(e.Song.Steps))
+		for i, st := range e.Song.Steps {
+			notes[i] = st.Symbol
+		}
+		return notes
+	}
+	return nil
+}
+
+func (e *Exercise) validate() error {
+	if e.Type == models.ExerciseTypeSequence {
+		if e.Song == nil {
+			return errors.New("song is required for sequence exercises")
+		}
+		seq, err := e.Song.Sequence()
+		if err != nil {
+			return err
+		}
+		expected, err := seq.Validate()
+		if err != nil {
+			return err
+		}
+		e.Expected = expected
+		return nil
+	}
+	if e.Song != nil {
+		return errors.New("song is only allowed for sequence exercises")
+	}
+	if e.Type == models.ExerciseTypeRhythm {
+		if e.Rhythm == nil {
+			return errors.New("rhythm is required for rhythm exercises")
+		}
+		r, err := e.Rhythm.Rhythm()
+		if err != nil {
+			return err
+		}
+		expected, err := r.Validate()
+		if err != nil {
+			return err
+		}
+		// Рисунок приводится к той же записи, что и при экспорте
+		e.Rhythm, e.Expected = rhythmFromModel(&r), expected
+		return nil
+	}
+	if e.Rhythm != nil {
+		return errors.New("rhythm is only allowed for rhythm exercises")
+	}
+	return models.ValidateExpected(e.Type, e.Expected)
+}
@@ -0,0 +1,74 @@
+package content
+
+import (
+	"regexp"
+	"strings"
+	"unicode"
+)
+
+var slugPattern = regexp.MustCompile(`^[a-z0-9]+(-[a-z0-9]+)*$`)
+
+// ValidSlug проверяет slug: латиница в нижнем регистре, цифры и одиночные дефисы
+func ValidSlug(s string) bool {
+	return len(s) <= 100 && slugPattern.MatchString(s)
+}
+
+// translit — транслитерация русского и казахского алфавитов
+var translit = map[rune]string{
+	'а': "a", 'б': "b", 'в': "v", 'г': "g", 'д': "d", 'е': "e", 'ё': "e",
+	'ж': "zh", 'з': "z", 'и': "i", 'й': "y", 'к': "k", 'л': "l", 'м': "m",
+	'н': "n", 'о': "o", 'п': "p", 'р': "r", 'с': "s", 'т': "t", 'у': "u",
+	'ф': "f", 'х': "kh", 'ц': "ts", 'ч': "ch", 'ш': "sh", 'щ': "shch",
+	'ъ': "", 'ы': "y", 'ь': "", 'э': "e", 'ю': "yu", 'я': "ya",
+	'ә': "a", 'ғ': "gh", 'қ': "q", 'ң': "ng", 'ө': "o", 'ұ': "u",
+	'ү': "u", 'һ': "h", 'і': "i",
+	'#': "sharp",
+}
+
+// Slugify строит slug из произвольного текста: "Основы гитары" → "osnovy-gitary"
+func Slugify(s string) string {
+	var b strings.Builder
+	dash := false
+	for _, r := range strings.ToLower(s) {
+		var part string
+		switch t, ok := translit[r]; {
+		case ok:
+			part = t
+		case r < unicode.MaxASCII && (unicode.IsLetter(r) || unicode.IsDigit(r)):
+			part = string(r)
+		}
+		if part == "" {
+			// Мягкий и твёрдый знаки просто пропускаем, остальное — разделитель
+			if r != 'ъ' && r != 'ь' {
+				dash = b.Len() > 0
+			}
+			continue
+		}
+		if dash {
+			b.WriteByte('-')
+			dash = false
+		}
+		b.WriteString(part)
+	}
+	slug := b.String()
+	if len(slug) > 80 {
+		slug = strings.TrimRight(slug[:80], "-")
+	}
+	return slug
+}
+
+// ExerciseSlug строит slug упражнения: "chord-am", "note-csharp4", песни — "song",
+// ритмические рисунки — "rhythm"
+func ExerciseSlug(exerciseType, expected string) string {
+	switch exerciseType {
+	case "sequence":
+		return "song"
+	case "rhythm":
+		return "rhythm"
+	}
+	base := Slugify(expected)
+	if base == "" {
+		base = "x"
+	}
+	return exerciseType + "-" + base
+}
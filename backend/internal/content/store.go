@@ -0,0 +1,837 @@
+package content
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"reflect"
+
+	"sonara-space/backend/internal/models"
+	"sonara-space/backend/internal/versions"
+
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgconn"
+)
+
+// DB — методы pgx, нужные импорту и экспорту (подходят pgxpool.Pool и pgx.Tx)
+type DB interface {
+	Exec(ctx context.Context, sql string, args ...interface{}) (pgconn.CommandTag, error)
+	Query(ctx context.Context, sql string, args ...interface{}) (pgx.Rows, error)
+	QueryRow(ctx context.Context, sql string, args ...interface{}) pgx.Row
+}
+
+// ImportOptions — параметры импорта
+type ImportOptions struct {
+	// Prune удаляет упражнения урока, которых нет в файле. Вместе с ними
+	// удаляется прогресс учеников, поэтому по умолчанию они только перечисляются.
+	Prune bool
+}
+
+// Report — что изменил импорт. Записи вида "lesson:guitar-basics",
+// "exercise:guitar-basics/chord-am", "course:guitar-from-scratch".
+type Report struct {
+	Created   []string `json:"created"`
+	Updated   []string `json:"updated"`
+	Unchanged int      `json:"unchanged"`
+	Stale     []string `json:"stale"`
+	Deleted   []string `json:"deleted"`
+	// Published — новые версии уроков: "lesson:guitar-basics@2"
+	Published []string `json:"published"`
+}
+
+func (r *Report) track(name string, inserted, changed bool) {
+	switch {
+	case inserted:
+		r.Created = append(r.Created, name)
+	case changed:
+		r.Updated = append(r.Updated, name)
+	default:
+		r.Unchanged++
+	}
+}
+
+// KnownLessons возвращает slug всех уроков в базе — для Bundle.Validate
+func KnownLessons(ctx context.Context, db DB) (map[string]bool, error) {
+	rows, err := db.Query(ctx, `SELECT slug FROM lessons`)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	known := make(map[string]bool)
+	for rows.Next() {
+		var slug string
+		if err := rows.Scan(&slug); err != nil {
+			return nil, err
+		}
+		known[slug] = true
+	}
+	return known, rows.Err()
+}
+
+func nullable(s string) *string {
+	if s == "" {
+		return nil
+	}
+	return &s
+}
+
+// upsert выполняет INSERT ... ON CONFLICT DO UPDATE ... WHERE <есть изменения>
+// RETURNING id, (xmax = 0). Если строка не изменилась, RETURNING ничего не
+// вернёт — тогда ID берётся запросом lookup.
+func upsert(ctx context.Context, db DB, query string, args []interface{}, lookup string, lookupArgs ...interface{}) (id int64, inserted, changed bool, err error) {
+	err = db.QueryRow(ctx, query, args...).Scan(&id, &inserted)
+	if err == nil {
+		return id, inserted, true, nil
+	}
+	if !errors.Is(err, pgx.ErrNoRows) {
+		return 0, false, false, err
+	}
+	err = db.QueryRow(ctx, lookup, lookupArgs...).Scan(&id)
+	return id, false, false, err
+}
+
+// Import сохраняет проверенный (Validate) файл в базу. Запускать в транзакции:
+// при ошибке частично импортированный контент не должен остаться в базе.
+// Переводы только добавляются и обновляются — те, что загружены через XLIFF
+// и отсутствуют в файле, не удаляются.
+func Import(ctx context.Context, db DB, b *Bundle, opts ImportOptions) (*Report, error) {
+	report := &Report{Created: []string{}, Updated: []string{}, Stale: []string{}, Deleted: []string{}, Published: []string{}}
+	lessonIDs := make(map[string]int64)
+
+	// Навыки — до уроков: упражнения ссылаются на них
+	for _, sk := range b.Skills {
+		if err := importSkill(ctx, db, sk, report); err != nil {
+			return nil, fmt.Errorf("skill %s: %w", sk.Slug, err)
+		}
+	}
+
+	for _, l := range b.Lessons {
+		id, inserted, changed, err := upsert(ctx, db, `
+			INSERT INTO lessons (slug, title, instrument, description, status, difficulty, tier, locale, tags, tuning, capo)
+			VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10, $11)
+			ON CONFLICT (slug) DO UPDATE SET
+				title = EXCLUDED.title, instrument = EXCLUDED.instrument, description = EXCLUDED.description,
+				status = EXCLUDED.status, difficulty = EXCLUDED.difficulty, tier = EXCLUDED.tier,
+				locale = EXCLUDED.locale, tags = EXCLUDED.tags, tuning = EXCLUDED.tuning, capo = EXCLUDED.capo,
+				updated_at = NOW()
+			WHERE (lessons.title, lessons.instrument, lessons.description, lessons.status,
+				lessons.difficulty, lessons.tier, lessons.locale, lessons.tags, lessons.tuning, lessons.capo)
+				IS DISTINCT FROM (EXCLUDED.title, EXCLUDED.instrument, EXCLUDED.description, EXCLUDED.status,
+				EXCLUDED.difficulty, EXCLUDED.tier, EXCLUDED.locale, EXCLUDED.tags, EXCLUDED.tuning, EXCLUDED.capo)
+			RETURNING id, (xmax = 0)
+		`, []interface{}{l.Slug, l.Title, l.Instrument, nullable(l.Description), l.Status,
+			l.Difficulty, l.Tier, l.Locale, l.Tags, nullable(l.Tuning), l.Capo},
+			`SELECT id FROM lessons WHERE slug = $1`, l.Slug)
+		if err != nil {
+			return nil, fmt.Errorf("lesson %s: %w", l.Slug, err)
+		}
+		report.track("lesson:"+l.Slug, inserted, changed)
+		lessonIDs[l.Slug] = id
+
+		if err := importExercises(ctx, db, id, l, opts, report); err != nil {
+			return nil, fmt.Errorf("lesson %s: %w", l.Slug, err)
+		}
+		if err := importLessonExtras(ctx, db, id, l); err != nil {
+			return nil, fmt.Errorf("lesson %s: %w", l.Slug, err)
+		}
+		if l.Status == models.LessonStatusPublished {
+			v, created, err := versions.Publish(ctx, db, id, nil, "Импорт контента", nil)
+			if err != nil {
+				return nil, fmt.Errorf("lesson %s: publish: %w", l.Slug, err)
+			}
+			if created {
+				report.Published = append(report.Published, fmt.Sprintf("lesson:%s@%d", l.Slug, v.Version))
+			}
+		}
+	}
+
+	// Пререквизиты — после всех уроков: они могут ссылаться на уроки ниже по файлу
+	for _, l := range b.Lessons {
+		required := make([]string, 0, len(l.Prerequisites))
+		for _, p := range l.Prerequisites {
+			required = append(required, p.Lesson)
+			_, err := db.Exec(ctx, `
+				INSERT INTO lesson_prerequisites (lesson_id, required_lesson_id, min_percent)
+				SELECT $1, id, $3 FROM lessons WHERE slug = $2
+				ON CONFLICT (lesson_id, required_lesson_id) DO UPDATE SET min_percent = EXCLUDED.min_percent
+			`, lessonIDs[l.Slug], p.Lesson, p.MinPercent)
+			if err != nil {
+				return nil, fmt.Errorf("lesson %s: prerequisite %s: %w", l.Slug, p.Lesson, err)
+			}
+		}
+		_, err := db.Exec(ctx, `
+			DELETE FROM lesson_prerequisites lp USING lessons r
+			WHERE lp.lesson_id = $1 AND r.id = lp.required_lesson_id AND NOT (r.slug = ANY($2))
+		`, lessonIDs[l.Slug], required)
+		if err != nil {
+			return nil, fmt.Errorf("lesson %s: prerequisites: %w", l.Slug, err)
+		}
+	}
+
+	for _, c := range b.Courses {
+		if err := importCourse(ctx, db, c, report); err != nil {
+			return nil, fmt.Errorf("course %s: %w", c.Slug, err)
+		}
+	}
+	return report, nil
+}
+
+func importSkill(ctx context.Context, db DB, sk Skill, report *Report) error {
+	_, inserted, changed, err := upsert(ctx, db, `
+		INSERT INTO skills (slug, name, instrument, description) VALUES ($1, $2, $3, $4)
+		ON CONFLICT (slug) DO UPDATE SET
+			name = EXCLUDED.name, instrument = EXCLUDED.instrument,
+			description = EXCLUDED.description, updated_at = NOW()
+		WHERE (skills.name, skills.instrument, skills.description)
+			IS DISTINCT FROM (EXCLUDED.name, EXCLUDED.instrument, EXCLUDED.description)
+		RETURNING id, (xmax = 0)
+	`, []interface{}{sk.Slug, sk.Name, nullable(sk.Instrument), nullable(sk.Description)},
+		`SELECT id FROM skills WHERE slug = $1`, sk.Slug)
+	if err != nil {
+		return err
+	}
+	report.track("skill:"+sk.Slug, inserted, changed)
+	return nil
+}
+
+// syncExerciseSkills приводит навыки упражнения к списку из файла.
+// changed == true, если связи изменились.
+func syncExerciseSkills(ctx context.Context, db DB, exerciseID int64, slugs []string) (bool, error) {
+	if slugs == nil {
+		slugs = []string{}
+	}
+	rows, err := db.Query(ctx, `
+		SELECT u.slug FROM unnest($1::text[]) AS u(slug)
+		WHERE NOT EXISTS (SELECT 1 FROM skills s WHERE s.slug = u.slug)
+	`, slugs)
+	if err != nil {
+		return false, err
+	}
+	missing, err := pgx.CollectRows(rows, pgx.RowTo[string])
+	if err != nil {
+		return false, err
+	}
+	if len(missing) > 0 {
+		return false, fmt.Errorf("skill %q not found", missing[0])
+	}
+
+	added, err := db.Exec(ctx, `
+		INSERT INTO exercise_skills (exercise_id, skill_id)
+		SELECT $1, id FROM skills WHERE slug = ANY($2)
+		ON CONFLICT DO NOTHING
+	`, exerciseID, slugs)
+	if err != nil {
+		return false, err
+	}
+	removed, err := db.Exec(ctx, `
+		DELETE FROM exercise_skills es USING skills s
+		WHERE es.exercise_id = $1 AND s.id = es.skill_id AND NOT (s.slug = ANY($2))
+	`, exerciseID, slugs)
+	if err != nil {
+		return false, err
+	}
+	return added.RowsAffected() > 0 || removed.RowsAffected() > 0, nil
+}
+
+// rhythmColumns — колонки exercise_rhythms r при LEFT JOIN, читаются в nullRhythm
+const rhythmColumns = `r.tempo, r.beats_per_bar, r.beat_unit, r.count_in, r.pattern, r.perfect_ms, r.good_ms, r.miss_ms`
+
+// nullRhythm — ритмический рисунок из LEFT JOIN: без строки все поля nil
+type nullRhythm struct {
+	tempo, beatsPerBar, beatUnit, countIn *int
+	pattern                               []models.RhythmHit
+	perfectMs, goodMs, missMs             *int
+}
+
+func (n *nullRhythm) dest() []interface{} {
+	return []interface{}{&n.tempo, &n.beatsPerBar, &n.beatUnit, &n.countIn, &n.pattern, &n.perfectMs, &n.goodMs, &n.missMs}
+}
+
+func (n *nullRhythm) spec() *RhythmSpec {
+	if n.tempo == nil {
+		return nil
+	}
+	return rhythmFromModel(&models.Rhythm{
+		Tempo: *n.tempo, BeatsPerBar: *n.beatsPerBar, BeatUnit: *n.beatUnit, CountIn: *n.countIn,
+		Pattern: n.pattern, PerfectMs: *n.perfectMs, GoodMs: *n.goodMs, MissMs: *n.missMs,
+	})
+}
+
+// syncExerciseRhythm записывает ритмический рисунок упражнения (nil — удаляет)
+// и сообщает, изменился ли он
+func syncExerciseRhythm(ctx context.Context, db DB, exerciseID int64, spec *RhythmSpec) (bool, error) {
+	if spec == nil {
+		tag, err := db.Exec(ctx, `DELETE FROM exercise_rhythms WHERE exercise_id = $1`, exerciseID)
+		if err != nil {
+			return false, err
+		}
+		return tag.RowsAffected() > 0, nil
+	}
+	r, err := spec.Rhythm()
+	if err != nil {
+		return false, err
+	}
+	tag, err := db.Exec(ctx, `
+		INSERT INTO exercise_rhythms (exercise_id, tempo, beats_per_bar, beat_unit, count_in, pattern,
+			perfect_ms, good_ms, miss_ms)
+		VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9)
+		ON CONFLICT (exercise_id) DO UPDATE SET
+			tempo = EXCLUDED.tempo, beats_per_bar = EXCLUDED.beats_per_bar, beat_unit = EXCLUDED.beat_unit,
+			count_in = EXCLUDED.count_in, pattern = EXCLUDED.pattern,
+			perfect_ms = EXCLUDED.perfect_ms, good_ms = EXCLUDED.good_ms, miss_ms = EXCLUDED.miss_ms
+		WHERE (exercise_rhythms.tempo, exercise_rhythms.beats_per_bar, exercise_rhythms.beat_unit,
+			exercise_rhythms.count_in, exercise_rhythms.pattern, exercise_rhythms.perfect_ms,
+			exercise_rhythms.good_ms, exercise_rhythms.miss_ms)
+			IS DISTINCT FROM (EXCLUDED.tempo, EXCLUDED.beats_per_bar, EXCLUDED.beat_unit, EXCLUDED.count_in,
+			EXCLUDED.pattern, EXCLUDED.perfect_ms, EXCLUDED.good_ms, EXCLUDED.miss_ms)
+	`, exerciseID, r.Tempo, r.BeatsPerBar, r.BeatUnit, r.CountIn, r.Pattern, r.PerfectMs, r.GoodMs, r.MissMs)
+	if err != nil {
+		return false, err
+	}
+	return tag.RowsAffected() > 0, nil
+}
+
+// forkPublished заменяет копией упражнение черновика, если оно входит в
+// опубликованную версию, а файл меняет его содержание: опубликованные
+// упражнения не меняются (см. пакет versions). Порядок и переводы — не содержание.
+func forkPublished(ctx context.Context, db DB, lessonID int64, e Exercise) (bool, error) {
+	var id int64
+	var cur Exercise
+	var published bool
+	var songTitle, stepType *string
+	var tempo, beatsPerBar, beatUnit *int
+	var steps []models.SequenceStep
+	var rh nullRhythm
+	err := db.QueryRow(ctx, `
+		SELECT e.id, e.title, e.type, e.expected,
+			EXISTS (SELECT 1 FROM lesson_version_exercises WHERE exercise_id = e.id),
+			s.song_title, s.step_type, s.tempo, s.beats_per_bar, s.beat_unit, s.steps,
+			`+rhythmColumns+`
+		FROM exercises e
+		LEFT JOIN exercise_sequences s ON s.exercise_id = e.id
+		LEFT JOIN exercise_rhythms r ON r.exercise_id = e.id
+		WHERE e.lesson_id = $1 AND e.slug = $2 AND e.retired_at IS NULL
+	`, lessonID, e.Slug).Scan(append([]interface{}{&id, &cur.Title, &cur.Type, &cur.Expected, &published,
+		&songTitle, &stepType, &tempo, &beatsPerBar, &beatUnit, &steps}, rh.dest()...)...)
+	if errors.Is(err, pgx.ErrNoRows) || (err == nil && !published) {
+		return false, nil
+	}
+	if err != nil {
+		return false, err
+	}
+
+	if songTitle != nil {
+		cur.Song = songFromSequence(&models.Sequence{
+			SongTitle: *songTitle, StepType: *stepType, Tempo: *tempo,
+			BeatsPerBar: *beatsPerBar, BeatUnit: *beatUnit, Steps: steps,
+		})
+	}
+	cur.Rhythm = rh.spec()
+	if cur.Title == e.Title && cur.Type == e.Type && cur.Expected == e.Expected &&
+		reflect.DeepEqual(cur.Song, e.Song) && reflect.DeepEqual(cur.Rhythm, e.Rhythm) {
+		return false, nil
+	}
+	if _, err := versions.Fork(ctx, db, id); err != nil {
+		return false, err
+	}
+	return true, nil
+}
+
+func importExercises(ctx context.Context, db DB, lessonID int64, l Lesson, opts ImportOptions, report *Report) error {
+	slugs := make([]string, 0, len(l.Exercises))
+	for i, e := range l.Exercises {
+		name := "exercise:" + l.Slug + "/" + e.Slug
+		slugs = append(slugs, e.Slug)
+		forked, err := forkPublished(ctx, db, lessonID, e)
+		if err != nil {
+			return fmt.Errorf("exercise %s: %w", e.Slug, err)
+		}
+		id, inserted, changed, err := upsert(ctx, db, `
+			INSERT INTO exercises (lesson_id, slug, title, expected, type, order_index)
+			VALUES ($1, $2, $3, $4, $5, $6)
+			ON CONFLICT (lesson_id, slug) WHERE retired_at IS NULL DO UPDATE SET
+				title = EXCLUDED.title, expected = EXCLUDED.expected, type = EXCLUDED.type,
+				order_index = EXCLUDED.order_index, updated_at = NOW()
+			WHERE (exercises.title, exercises.expected, exercises.type, exercises.order_index)
+				IS DISTINCT FROM (EXCLUDED.title, EXCLUDED.expected, EXCLUDED.type, EXCLUDED.order_index)
+			RETURNING id, (xmax = 0)
+		`, []interface{}{lessonID, e.Slug, e.Title, e.Expected, e.Type, i + 1},
+			`SELECT id FROM exercises WHERE lesson_id = $1 AND slug = $2 AND retired_at IS NULL`, lessonID, e.Slug)
+		if err != nil {
+			return fmt.Errorf("exercise %s: %w", e.Slug, err)
+		}
+		changed = changed || forked
+
+		// Изменения песни тоже считаются изменением упражнения
+		if e.Song != nil {
+			seq, _ := e.Song.Sequence()
+			tag, err := db.Exec(ctx, `
+				INSERT INTO exercise_sequences (exercise_id, song_title, step_type, tempo, beats_per_bar, beat_unit, steps)
+				VALUES ($1, $2, $3, $4, $5, $6, $7)
+				ON CONFLICT (exercise_id) DO UPDATE SET
+					song_title = EXCLUDED.song_title, step_type = EXCLUDED.step_type, tempo = EXCLUDED.tempo,
+					beats_per_bar = EXCLUDED.beats_per_bar, beat_unit = EXCLUDED.beat_unit, steps = EXCLUDED.steps
+				WHERE (exercise_sequences.song_title, exercise_sequences.step_type, exercise_sequences.tempo,
+					exercise_sequences.beats_per_bar, exercise_sequences.beat_unit, exercise_sequences.steps)
+					IS DISTINCT FROM (EXCLUDED.song_title, EXCLUDED.step_type, EXCLUDED.tempo,
+					EXCLUDED.beats_per_bar, EXCLUDED.beat_unit, EXCLUDED.steps)
+			`, id, seq.SongTitle, seq.StepType, seq.Tempo, seq.BeatsPerBar, seq.BeatUnit, seq.Steps)
+			if err != nil {
+				return fmt.Errorf("exercise %s: song: %w", e.Slug, err)
+			}
+			changed = changed || tag.RowsAffected() > 0
+		} else {
+			tag, err := db.Exec(ctx, `DELETE FROM exercise_sequences WHERE exercise_id = $1`, id)
+			if err != nil {
+				return fmt.Errorf("exercise %s: song: %w", e.Slug, err)
+			}
+			changed = changed || tag.RowsAffected() > 0
+		}
+		rhythmChanged, err := syncExerciseRhythm(ctx, db, id, e.Rhythm)
+		if err != nil {
+			return fmt.Errorf("exercise %s: rhythm: %w", e.Slug, err)
+		}
+		changed = changed || rhythmChanged
+		skillsChanged, err := syncExerciseSkills(ctx, db, id, e.Skills)
+		if err != nil {
+			return fmt.Errorf("exercise %s: %w", e.Slug, err)
+		}
+		report.track(name, inserted, changed || skillsChanged)
+
+		for locale, title := range e.Translations {
+			_, err := db.Exec(ctx, `
+				INSERT INTO exercise_translations (exercise_id, locale, title) VALUES ($1, $2, $3)
+				ON CONFLICT (exercise_id, locale) DO UPDATE SET title = EXCLUDED.title, updated_at = NOW()
+				WHERE exercise_translations.title IS DISTINCT FROM EXCLUDED.title
+			`, id, locale, title)
+			if err != nil {
+				return fmt.Errorf("exercise %s: translation %s: %w", e.Slug, locale, err)
+			}
+		}
+	}
+
+	rows, err := db.Query(ctx, `
+		SELECT slug FROM exercises WHERE lesson_id = $1 AND retired_at IS NULL AND NOT (slug = ANY($2))
+		ORDER BY order_index, id
+	`, lessonID, slugs)
+	if err != nil {
+		return err
+	}
+	stale, err := pgx.CollectRows(rows, pgx.RowTo[string])
+	if err != nil {
+		return err
+	}
+	for _, slug := range stale {
+		name := "exercise:" + l.Slug + "/" + slug
+		if !opts.Prune {
+			report.Stale = append(report.Stale, name)
+			continue
+		}
+		var id int64
+		err := db.QueryRow(ctx, `SELECT id FROM exercises WHERE lesson_id = $1 AND slug = $2 AND retired_at IS NULL`,
+			lessonID, slug).Scan(&id)
+		if err == nil {
+			_, err = versions.Remove(ctx, db, id)
+		}
+		if err != nil {
+			return fmt.Errorf("delete exercise %s: %w", slug, err)
+		}
+		report.Deleted = append(report.Deleted, name)
+	}
+	return nil
+}
+
+// importLessonExtras синхронизирует медиа и добавляет переводы урока
+func importLessonExtras(ctx context.Context, db DB, lessonID int64, l Lesson) error {
+	uris := make([]string, 0, len(l.Media))
+	for i, m := range l.Media {
+		uris = append(uris, m.URI)
+		_, err := db.Exec(ctx, `
+			INSERT INTO lesson_media (lesson_id, kind, uri, title, order_index) VALUES ($1, $2, $3, $4, $5)
+			ON CONFLICT (lesson_id, uri) DO UPDATE SET
+				kind = EXCLUDED.kind, title = EXCLUDED.title, order_index = EXCLUDED.order_index
+		`, lessonID, m.Kind, m.URI, nullable(m.Title), i+1)
+		if err != nil {
+			return fmt.Errorf("media %s: %w", m.URI, err)
+		}
+	}
+	if _, err := db.Exec(ctx, `DELETE FROM lesson_media WHERE lesson_id = $1 AND NOT (uri = ANY($2))`, lessonID, uris); err != nil {
+		return fmt.Errorf("media: %w", err)
+	}
+
+	for locale, t := range l.Translations {
+		_, err := db.Exec(ctx, `
+			INSERT INTO lesson_translations (lesson_id, locale, title, description) VALUES ($1, $2, $3, $4)
+			ON CONFLICT (lesson_id, locale) DO UPDATE SET
+				title = EXCLUDED.title, description = EXCLUDED.description, updated_at = NOW()
+			WHERE (lesson_translations.title, lesson_translations.description)
+				IS DISTINCT FROM (EXCLUDED.title, EXCLUDED.description)
+		`, lessonID, locale, nullable(t.Title), nullable(t.Description))
+		if err != nil {
+			return fmt.Errorf("translation %s: %w", locale, err)
+		}
+	}
+	return nil
+}
+
+func importCourse(ctx context.Context, db DB, c Course, report *Report) error {
+	courseID, inserted, changed, err := upsert(ctx, db, `
+		INSERT INTO courses (slug, title, instrument, description, status)
+		VALUES ($1, $2, $3, $4, $5)
+		ON CONFLICT (slug) DO UPDATE SET
+			title = EXCLUDED.title, instrument = EXCLUDED.instrument,
+			description = EXCLUDED.description, status = EXCLUDED.status, updated_at = NOW()
+		WHERE (courses.title, courses.instrument, courses.description, courses.status)
+			IS DISTINCT FROM (EXCLUDED.title, EXCLUDED.instrument, EXCLUDED.description, EXCLUDED.status)
+		RETURNING id, (xmax = 0)
+	`, []interface{}{c.Slug, c.Title, c.Instrument, nullable(c.Description), c.Status},
+		`SELECT id FROM courses WHERE slug = $1`, c.Slug)
+	if err != nil {
+		return err
+	}
+
+	// Модули сопоставляются по позиции, уроки модуля — по slug
+	for i, m := range c.Modules {
+		var moduleID int64
+		err := db.QueryRow(ctx, `
+			INSERT INTO course_modules (course_id, title, order_index) VALUES ($1, $2, $3)
+			ON CONFLICT (course_id, order_index) DO UPDATE SET title = EXCLUDED.title
+			RETURNING id
+		`, courseID, m.Title, i+1).Scan(&moduleID)
+		if err != nil {
+			return fmt.Errorf("module %d: %w", i+1, err)
+		}
+		for j, slug := range m.Lessons {
+			_, err := db.Exec(ctx, `
+				INSERT INTO module_lessons (module_id, lesson_id, order_index)
+				SELECT $1, id, $3 FROM lessons WHERE slug = $2
+				ON CONFLICT (module_id, lesson_id) DO UPDATE SET order_index = EXCLUDED.order_index
+			`, moduleID, slug, j+1)
+			if err != nil {
+				return fmt.Errorf("module %d: lesson %s: %w", i+1, slug, err)
+			}
+		}
+		_, err = db.Exec(ctx, `
+			DELETE FROM module_lessons ml USING lessons l
+			WHERE ml.module_id = $1 AND l.id = ml.lesson_id AND NOT (l.slug = ANY($2))
+		`, moduleID, m.Lessons)
+		if err != nil {
+			return fmt.Errorf("module %d: %w", i+1, err)
+		}
+	}
+	tag, err := db.Exec(ctx, `DELETE FROM course_modules WHERE course_id = $1 AND order_index > $2`,
+		courseID, len(c.Modules))
+	if err != nil {
+		return err
+	}
+	report.track("course:"+c.Slug, inserted, changed || tag.RowsAffected() > 0)
+	return nil
+}
+
+// ExportFilter ограничивает экспорт. Пустой фильтр — весь контент.
+// При выборе курсов экспортируются и все их уроки.
+type ExportFilter struct {
+	Courses []string
+	Lessons []string
+}
+
+// Export собирает файл контента из базы. Повторный импорт результата
+// не меняет базу.
+func Export(ctx context.Context, db DB, f ExportFilter) (*Bundle, error) {
+	b := &Bundle{Version: FormatVersion}
+	all := len(f.Courses) == 0 && len(f.Lessons) == 0
+
+	courses, err := exportCourses(ctx, db, f.Courses, all)
+	if err != nil {
+		return nil, err
+	}
+	b.Courses = courses
+
+	wanted := append([]string{}, f.Lessons...)
+	for _, c := range courses {
+		for _, m := range c.Modules {
+			wanted = append(wanted, m.Lessons...)
+		}
+	}
+
+	rows, err := db.Query(ctx, `
+		SELECT id, slug, title, instrument, COALESCE(tuning, ''), capo, COALESCE(description, ''),
+			status, difficulty, tier, locale, tags
+		FROM lessons WHERE $1 OR slug = ANY($2)
+		ORDER BY created_at, id
+	`, all, wanted)
+	if err != nil {
+		return nil, err
+	}
+	var ids []int64
+	index := make(map[int64]int)
+	for rows.Next() {
+		var id int64
+		var l Lesson
+		if err := rows.Scan(&id, &l.Slug, &l.Title, &l.Instrument, &l.Tuning, &l.Capo, &l.Description, &l.Status,
+			&l.Difficulty, &l.Tier, &l.Locale, &l.Tags); err != nil {
+			rows.Close()
+			return nil, err
+		}
+		l.Exercises = []Exercise{}
+		index[id] = len(b.Lessons)
+		ids = append(ids, id)
+		b.Lessons = append(b.Lessons, l)
+	}
+	rows.Close()
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+
+	if err := exportExercises(ctx, db, b, ids, index); err != nil {
+		return nil, err
+	}
+	if err := exportLessonExtras(ctx, db, b, ids, index); err != nil {
+		return nil, err
+	}
+	skills, err := exportSkills(ctx, db, ids)
+	if err != nil {
+		return nil, err
+	}
+	b.Skills = skills
+	return b, nil
+}
+
+// exportSkills выгружает навыки, на которые ссылаются упражнения уроков,
+// чтобы файл можно было импортировать в пустую базу
+func exportSkills(ctx context.Context, db DB, lessonIDs []int64) ([]Skill, error) {
+	rows, err := db.Query(ctx, `
+		SELECT s.slug, s.name, COALESCE(s.instrument, ''), COALESCE(s.description, '')
+		FROM skills s
+		WHERE EXISTS (
+			SELECT 1 FROM exercise_skills es JOIN exercises e ON e.id = es.exercise_id
+			WHERE es.skill_id = s.id AND e.lesson_id = ANY($1) AND e.retired_at IS NULL
+		)
+		ORDER BY s.id
+	`, lessonIDs)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var skills []Skill
+	for rows.Next() {
+		var sk Skill
+		if err := rows.Scan(&sk.Slug, &sk.Name, &sk.Instrument, &sk.Description); err != nil {
+			return nil, err
+		}
+		skills = append(skills, sk)
+	}
+	return skills, rows.Err()
+}
+
+func exportCourses(ctx context.Context, db DB, slugs []string, all bool) ([]Course, error) {
+	rows, err := db.Query(ctx, `
+		SELECT c.slug, c.title, c.instrument, COALESCE(c.description, ''), c.status,
+			m.title, COALESCE(array_agg(l.slug ORDER BY ml.order_index, l.id) FILTER (WHERE l.id IS NOT NULL), '{}')
+		FROM courses c
+		LEFT JOIN course_modules m ON m.course_id = c.id
+		LEFT JOIN module_lessons ml ON ml.module_id = m.id
+		LEFT JOIN lessons l ON l.id = ml.lesson_id
+		WHERE $1 OR c.slug = ANY($2)
+		GROUP BY c.id, m.id
+		ORDER BY c.created_at, c.id, m.order_index
+	`, all, slugs)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var courses []Course
+	for rows.Next() {
+		var c Course
+		var moduleTitle *string
+		var lessons []string
+		if err := rows.Scan(&c.Slug, &c.Title, &c.Instrument, &c.Description, &c.Status, &moduleTitle, &lessons); err != nil {
+			return nil, err
+		}
+		if n := len(courses); n == 0 || courses[n-1].Slug != c.Slug {
+			c.Modules = []Module{}
+			courses = append(courses, c)
+		}
+		if moduleTitle != nil {
+			cur := &courses[len(courses)-1]
+			cur.Modules = append(cur.Modules, Module{Title: *moduleTitle, Lessons: lessons})
+		}
+	}
+	return courses, rows.Err()
+}
+
+func exportExercises(ctx context.Context, db DB, b *Bundle, lessonIDs []int64, index map[int64]int) error {
+	rows, err := db.Query(ctx, `
+		SELECT e.id, e.lesson_id, e.slug, e.title, e.type, e.expected,
+			s.song_title, s.step_type, s.tempo, s.beats_per_bar, s.beat_unit, s.steps,
+			`+rhythmColumns+`
+		FROM exercises e
+		LEFT JOIN exercise_sequences s ON s.exercise_id = e.id
+		LEFT JOIN exercise_rhythms r ON r.exercise_id = e.id
+		WHERE e.lesson_id = ANY($1) AND e.retired_at IS NULL
+		ORDER BY e.lesson_id, e.order_index, e.id
+	`, lessonIDs)
+	if err != nil {
+		return err
+	}
+	defer rows.Close()
+
+	type ref struct{ lesson, exercise int }
+	refs := make(map[int64]ref)
+	for rows.Next() {
+		var id, lessonID int64
+		var e Exercise
+		var songTitle, stepType *string
+		var tempo, beatsPerBar, beatUnit *int
+		var steps []models.SequenceStep
+		var rh nullRhythm
+		if err := rows.Scan(append([]interface{}{&id, &lessonID, &e.Slug, &e.Title, &e.Type, &e.Expected,
+			&songTitle, &stepType, &tempo, &beatsPerBar, &beatUnit, &steps}, rh.dest()...)...); err != nil {
+			return err
+		}
+		if e.Rhythm = rh.spec(); e.Rhythm != nil {
+			// Для ритма expected вычисляется из рисунка
+			e.Expected = ""
+		}
+		if songTitle != nil {
+			e.Song = songFromSequence(&models.Sequence{
+				SongTitle: *songTitle, StepType: *stepType, Tempo: *tempo,
+				BeatsPerBar: *beatsPerBar, BeatUnit: *beatUnit, Steps: steps,
+			})
+			// Для песен expected вычисляется из шагов
+			e.Expected = ""
+		}
+		l := &b.Lessons[index[lessonID]]
+		refs[id] = ref{index[lessonID], len(l.Exercises)}
+		l.Exercises = append(l.Exercises, e)
+	}
+	if err := rows.Err(); err != nil {
+		return err
+	}
+	rows.Close()
+
+	srows, err := db.Query(ctx, `
+		SELECT es.exercise_id, s.slug
+		FROM exercise_skills es
+		JOIN skills s ON s.id = es.skill_id
+		JOIN exercises e ON e.id = es.exercise_id
+		WHERE e.lesson_id = ANY($1) AND e.retired_at IS NULL
+		ORDER BY es.exercise_id, s.slug
+	`, lessonIDs)
+	if err != nil {
+		return err
+	}
+	for srows.Next() {
+		var id int64
+		var slug string
+		if err := srows.Scan(&id, &slug); err != nil {
+			srows.Close()
+			return err
+		}
+		r := refs[id]
+		e := &b.Lessons[r.lesson].Exercises[r.exercise]
+		e.Skills = append(e.Skills, slug)
+	}
+	srows.Close()
+	if err := srows.Err(); err != nil {
+		return err
+	}
+
+	trows, err := db.Query(ctx, `
+		SELECT t.exercise_id, t.locale, t.title
+		FROM exercise_translations t JOIN exercises e ON e.id = t.exercise_id
+		WHERE e.lesson_id = ANY($1) AND e.retired_at IS NULL
+	`, lessonIDs)
+	if err != nil {
+		return err
+	}
+	defer trows.Close()
+	for trows.Next() {
+		var id int64
+		var locale, title string
+		if err := trows.Scan(&id, &locale, &title); err != nil {
+			return err
+		}
+		r := refs[id]
+		e := &b.Lessons[r.lesson].Exercises[r.exercise]
+		if e.Translations == nil {
+			e.Translations = make(map[string]string)
+		}
+		e.Translations[locale] = title
+	}
+	return trows.Err()
+}
+
+func exportLessonExtras(ctx context.Context, db DB, b *Bundle, lessonIDs []int64, index map[int64]int) error {
+	rows, err := db.Query(ctx, `
+		SELECT lp.lesson_id, r.slug, lp.min_percent
+		FROM lesson_prerequisites lp JOIN lessons r ON r.id = lp.required_lesson_id
+		WHERE lp.lesson_id = ANY($1)
+		ORDER BY lp.lesson_id, r.slug
+	`, lessonIDs)
+	if err != nil {
+		return err
+	}
+	for rows.Next() {
+		var id int64
+		var p Prerequisite
+		if err := rows.Scan(&id, &p.Lesson, &p.MinPercent); err != nil {
+			rows.Close()
+			return err
+		}
+		l := &b.Lessons[index[id]]
+		l.Prerequisites = append(l.Prerequisites, p)
+	}
+	rows.Close()
+	if err := rows.Err(); err != nil {
+		return err
+	}
+
+	rows, err = db.Query(ctx, `
+		SELECT lesson_id, kind, uri, COALESCE(title, '')
+		FROM lesson_media WHERE lesson_id = ANY($1)
+		ORDER BY lesson_id, order_index, id
+	`, lessonIDs)
+	if err != nil {
+		return err
+	}
+	for rows.Next() {
+		var id int64
+		var m Media
+		if err := rows.Scan(&id, &m.Kind, &m.URI, &m.Title); err != nil {
+			rows.Close()
+			return err
+		}
+		l := &b.Lessons[index[id]]
+		l.Media = append(l.Media, m)
+	}
+	rows.Close()
+	if err := rows.Err(); err != nil {
+		return err
+	}
+
+	rows, err = db.Query(ctx, `
+		SELECT lesson_id, locale, COALESCE(title, ''), COALESCE(description, '')
+		FROM lesson_translations WHERE lesson_id = ANY($1)
+	`, lessonIDs)
+	if err != nil {
+		return err
+	}
+	defer rows.Close()
+	for rows.Next() {
+		var id int64
+		var locale string
+		var t LessonTranslation
+		if err := rows.Scan(&id, &locale, &t.Title, &t.Description); err != nil {
+			return err
+		}
+		l := &b.Lessons[index[id]]
+		if l.Translations == nil {
+			l.Translations = make(map[string]LessonTranslation)
+		}
+		l.Translations[locale] = t
+	}
+	return rows.Err()
+}
@@ -0,0 +1,353 @@
+// Package eartraining генерирует задания на слух: интервалы, вид аккорда,
+// ступень в тональности и мелодический диктант. Задание однозначно задаётся
+// видом, сложностью, seed и номером, поэтому сессию можно хранить без самих
+// заданий и восстановить их при проверке ответа.
+package eartraining
+
+import (
+	"errors"
+	"math"
+	"math/rand/v2"
+	"strings"
+
+	"sonara-space/backend/internal/audio"
+	"sonara-space/backend/internal/models"
+	"sonara-space/backend/internal/music"
+)
+
+// Version — версия генератора. Её нужно увеличить, если меняются правила
+// генерации: иначе сохранённые сессии восстановятся с другими заданиями.
+const Version = 1
+
+// Виды заданий
+const (
+	KindInterval     = "interval"
+	KindChordQuality = "chord_quality"
+	KindScaleDegree  = "scale_degree"
+	KindDictation    = "dictation"
+)
+
+// Kinds — все виды заданий
+var Kinds = []string{KindInterval, KindChordQuality, KindScaleDegree, KindDictation}
+
+// Как звучит интервал
+const (
+	Ascending  = "ascending"
+	Descending = "descending"
+	Harmonic   = "harmonic"
+)
+
+var (
+	ErrKind       = errors.New("eartraining: unknown kind")
+	ErrDifficulty = errors.New("eartraining: unknown difficulty")
+)
+
+// Event — звуки задания: ноты (MIDI), взятые одновременно, длительностью
+// Beats долей. Пустой MIDI — пауза.
+type Event struct {
+	MIDI  []int
+	Beats float64
+}
+
+// Question — задание. Events и Answer не отдаются клиенту: по ним виден ответ.
+type Question struct {
+	Index     int      `json:"index"`
+	Kind      string   `json:"kind"`
+	Direction string   `json:"direction,omitempty"` // интервал: ascending, descending, harmonic
+	Key       string   `json:"key,omitempty"`       // ступень и диктант: тональность
+	Start     string   `json:"start,omitempty"`     // диктант: первая нота (подсказка)
+	Length    int      `json:"length,omitempty"`    // диктант: число нот
+	Choices   []string `json:"choices,omitempty"`   // варианты ответа (у диктанта нет)
+	Tempo     int      `json:"tempo"`
+	Events    []Event  `json:"-"`
+	Answer    string   `json:"-"`
+}
+
+// Verdict — проверка ответа. Score — доля верного (для диктанта — по нотам).
+type Verdict struct {
+	Correct  bool    `json:"correct"`
+	Score    float64 `json:"score"`
+	Expected string  `json:"expected"`
+}
+
+// intervalNames — названия интервалов по числу полутонов
+var intervalNames = [13]string{"P1", "m2", "M2", "m3", "M3", "P4", "TT", "P5", "m6", "M6", "m7", "M7", "P8"}
+
+// chordQualities — виды аккордов и их состав в полутонах от основного тона
+var chordQualities = map[string][]int{
+	"major":      {0, 4, 7},
+	"minor":      {0, 3, 7},
+	"diminished": {0, 3, 6},
+	"augmented":  {0, 4, 8},
+	"dominant7":  {0, 4, 7, 10},
+	"major7":     {0, 4, 7, 11},
+	"minor7":     {0, 3, 7, 10},
+}
+
+// level — набор заданий для сложности
+type level struct {
+	intervals  []int    // интервалы в полутонах
+	directions []string // как звучат интервалы
+	qualities  []string // виды аккордов
+	inversions bool     // аккорды в обращениях
+	keys       []string // тональности для ступеней и диктанта
+	degrees    []int    // ступени (1..7)
+	notes      int      // длина диктанта
+	leap       int      // наибольший скачок диктанта в ступенях
+}
+
+var levels = map[string]level{
+	models.DifficultyBeginner: {
+		intervals:  []int{3, 4, 5, 7, 12},
+		directions: []string{Ascending},
+		qualities:  []string{"major", "minor"},
+		keys:       []string{"C", "G", "F"},
+		degrees:    []int{1, 3, 5},
+		notes:      3,
+		leap:       1,
+	},
+	models.DifficultyIntermediate: {
+		intervals:  []int{1, 2, 3, 4, 5, 7, 8, 9, 10, 11, 12},
+		directions: []string{Ascending, Descending},
+		qualities:  []string{"major", "minor", "diminished", "augmented"},
+		keys:       []string{"C", "G", "D", "A", "F", "Bb", "Eb"},
+		degrees:    []int{1, 2, 3, 4, 5, 6, 7},
+		notes:      4,
+		leap:       2,
+	},
+	models.DifficultyAdvanced: {
+		intervals:  []int{1, 2, 3, 4, 5, 6, 7, 8, 9, 10, 11, 12},
+		directions: []string{Ascending, Descending, Harmonic},
+		qualities:  []string{"major", "minor", "diminished", "augmented", "dominant7", "major7", "minor7"},
+		inversions: true,
+		keys:       []string{"C", "G", "D", "A", "E", "F", "Bb", "Eb", "Am", "Em", "Bm", "Dm", "Gm", "Cm"},
+		degrees:    []int{1, 2, 3, 4, 5, 6, 7},
+		notes:      6,
+		leap:       4,
+	},
+}
+
+// Generate создаёт задание номер index из сессии с данным seed
+func Generate(kind, difficulty string, seed int64, index int) (Question, error) {
+	lv, ok := levels[difficulty]
+	if !ok {
+		return Question{}, ErrDifficulty
+	}
+	rng := rand.New(rand.NewPCG(uint64(seed), uint64(index)))
+	q := Question{Index: index, Kind: kind, Tempo: 90}
+
+	switch kind {
+	case KindInterval:
+		interval(&q, lv, rng)
+	case KindChordQuality:
+		chordQuality(&q, lv, rng)
+	case KindScaleDegree:
+		scaleDegree(&q, lv, rng)
+	case KindDictation:
+		dictation(&q, lv, rng)
+	default:
+		return Question{}, ErrKind
+	}
+	return q, nil
+}
+
+// Session создаёт count заданий сессии
+func Session(kind, difficulty string, seed int64, count int) ([]Question, error) {
+	questions := make([]Question, count)
+	for i := range questions {
+		q, err := Generate(kind, difficulty, seed, i)
+		if err != nil {
+			return nil, err
+		}
+		questions[i] = q
+	}
+	return questions, nil
+}
+
+// Check проверяет ответ. Интервалы записываются как P5, m3, TT; виды
+// аккордов — major, minor7; ступени — цифрой; диктант — нотами через пробел
+// (энгармонизмы засчитываются).
+func Check(q Question, answer string) Verdict {
+	answer = strings.TrimSpace(answer)
+	v := Verdict{Expected: q.Answer}
+	switch q.Kind {
+	case KindDictation:
+		expected := strings.Fields(q.Answer)
+		given := strings.Fields(answer)
+		hits := 0
+		for i, s := range expected {
+			if i >= len(given) {
+				break
+			}
+			want, _ := music.ParseNote(s)
+			if got, err := music.ParseNote(given[i]); err == nil && got.MIDI() == want.MIDI() {
+				hits++
+			}
+		}
+		v.Correct = hits == len(expected) && len(given) == len(expected)
+		v.Score = round1(100 * float64(hits) / float64(max(len(expected), len(given))))
+		return v
+	case KindChordQuality:
+		v.Correct = strings.EqualFold(answer, q.Answer)
+	default:
+		v.Correct = answer == q.Answer
+	}
+	if v.Correct {
+		v.Score = 100
+	}
+	return v
+}
+
+// Render озвучивает задание встроенным синтезатором
+func Render(q Question, sampleRate int) *audio.Buffer {
+	secondsPerBeat := 60 / float64(q.Tempo)
+	var tones []audio.Tone
+	var position float64
+	for _, e := range q.Events {
+		tones = append(tones, audio.Tone{MIDI: e.MIDI, Start: position * secondsPerBeat, Duration: e.Beats * secondsPerBeat})
+		position += e.Beats
+	}
+	return audio.Synthesize(tones, sampleRate, music.StandardA4)
+}
+
+func interval(q *Question, lv level, rng *rand.Rand) {
+	size := pick(rng, lv.intervals)
+	q.Direction = pick(rng, lv.directions)
+	q.Answer = intervalNames[size]
+	for _, iv := range lv.intervals {
+		q.Choices = append(q.Choices, intervalNames[iv])
+	}
+
+	low := 55 + rng.IntN(12) // от G3 до F#4
+	high := low + size
+	switch q.Direction {
+	case Ascending:
+		q.Events = []Event{{MIDI: []int{low}, Beats: 1}, {MIDI: []int{high}, Beats: 2}}
+	case Descending:
+		q.Events = []Event{{MIDI: []int{high}, Beats: 1}, {MIDI: []int{low}, Beats: 2}}
+	default:
+		q.Events = []Event{{MIDI: []int{low, high}, Beats: 3}}
+	}
+}
+
+func chordQuality(q *Question, lv level, rng *rand.Rand) {
+	name := pick(rng, lv.qualities)
+	q.Answer = name
+	q.Choices = append([]string(nil), lv.qualities...)
+
+	root := 48 + rng.IntN(12) // от C3 до B3
+	var notes []int
+	for _, iv := range chordQualities[name] {
+		notes = append(notes, root+iv)
+	}
+	if lv.inversions {
+		// Обращение: нижние звуки переносятся на октаву вверх
+		for n := rng.IntN(len(notes)); n > 0; n-- {
+			notes = append(notes[1:], notes[0]+12)
+		}
+	}
+	// Сначала арпеджио, потом аккорд целиком
+	for _, m := range notes {
+		q.Events = append(q.Events, Event{MIDI: []int{m}, Beats: 0.5})
+	}
+	q.Events = append(q.Events, Event{MIDI: notes, Beats: 3})
+}
+
+func scaleDegree(q *Question, lv level, rng *rand.Rand) {
+	key, tonic := pickKey(rng, lv)
+	degree := pick(rng, lv.degrees)
+	q.Key = key.String()
+	q.Answer = string(rune('0' + degree))
+	for _, d := range lv.degrees {
+		q.Choices = append(q.Choices, string(rune('0'+d)))
+	}
+
+	// Каденция I–IV–V–I задаёт тональность, после паузы звучит ступень.
+	// В миноре V — мажорная, с повышенной VII ступенью.
+	scale := scaleOf(key)
+	for _, d := range []int{0, 3, 4, 0} {
+		chord := triad(tonic, scale, d)
+		if key.Minor && d == 4 {
+			chord[1]++
+		}
+		q.Events = append(q.Events, Event{MIDI: chord, Beats: 1})
+	}
+	q.Events = append(q.Events,
+		Event{Beats: 1},
+		Event{MIDI: []int{tonic + 12 + scale[degree-1]}, Beats: 2},
+	)
+}
+
+func dictation(q *Question, lv level, rng *rand.Rand) {
+	key, tonic := pickKey(rng, lv)
+	scale := scaleOf(key)
+	q.Key = key.String()
+	q.Length = lv.notes
+
+	// Мелодия — шаги по ступеням от тоники в пределах от V ступени снизу до
+	// III ступени через октаву
+	position := 0
+	var notes []string
+	q.Events = append(q.Events, Event{MIDI: triad(tonic, scale, 0), Beats: 2}, Event{Beats: 1})
+	for i := 0; i < lv.notes; i++ {
+		if i > 0 {
+			for {
+				step := rng.IntN(2*lv.leap+1) - lv.leap
+				if step != 0 && position+step >= -3 && position+step <= 9 {
+					position += step
+					break
+				}
+			}
+		}
+		octave, degree := floorDiv(position, 7)
+		midi := tonic + 12 + 12*octave + scale[degree]
+		notes = append(notes, key.SpellNote(midi).String())
+		beats := 1.0
+		if i == lv.notes-1 {
+			beats = 2
+		}
+		q.Events = append(q.Events, Event{MIDI: []int{midi}, Beats: beats})
+	}
+	q.Start = notes[0]
+	q.Answer = strings.Join(notes, " ")
+}
+
+// pickKey выбирает тональность и MIDI тоники в малой октаве (от C3 до B3)
+func pickKey(rng *rand.Rand, lv level) (music.Key, int) {
+	key, _ := music.ParseKey(pick(rng, lv.keys))
+	return key, 48 + int(key.Tonic.PitchClass())
+}
+
+// scaleOf возвращает ступени гаммы в полутонах от тоники
+func scaleOf(k music.Key) [7]int {
+	if k.Minor {
+		return [7]int{0, 2, 3, 5, 7, 8, 10}
+	}
+	return [7]int{0, 2, 4, 5, 7, 9, 11}
+}
+
+// triad строит трезвучие на ступени degree (0 — тоника) в тесном расположении
+func triad(tonic int, scale [7]int, degree int) []int {
+	notes := make([]int, 3)
+	for i := range notes {
+		octave, d := floorDiv(degree+2*i, 7)
+		notes[i] = tonic + 12*octave + scale[d]
+	}
+	return notes
+}
+
+func floorDiv(n, d int) (int, int) {
+	q, r := n/d, n%d
+	if r < 0 {
+		q, r = q-1, r+d
+	}
+	return q, r
+}
+
+func pick[T any](rng *rand.Rand, items []T) T {
+	return items[rng.IntN(len(items))]
+}
+
+func round1(v float64) float64 {
+	return math.Round(v*10) / 10
+}
@@ -0,0 +1,98 @@
+package handlers
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"net/http"
+	"time"
+
+	"sonara-space/backend/internal/achievements"
+	"sonara-space/backend/internal/auth"
+	"sonara-space/backend/internal/db"
+	"sonara-space/backend/internal/stats"
+
+	"github.com/jackc/pgx/v5"
+)
+
+// AchievementView — значок в ответе API; AwardedAt == nil — ещё не получен
+type AchievementView struct {
+	ID          int64      `json:"id"`
+	Slug        string     `json:"slug"`
+	Title       string     `json:"title"`
+	Description string     `json:"description"`
+	Icon        *string    `json:"icon,omitempty"`
+	Earned      bool       `json:"earned"`
+	AwardedAt   *time.Time `json:"awarded_at"`
+}
+
+// awardAchievements пересчитывает серию и опыт ученика и выдаёт заслуженные значки
+func awardAchievements(ctx context.Context, tx pgx.Tx, userID int64) error {
+	st, err := stats.Recompute(ctx, tx, userID)
+	if err != nil {
+		return fmt.Errorf("recompute stats: %w", err)
+	}
+	if _, err := achievements.Award(ctx, tx, userID, st); err != nil {
+		return fmt.Errorf("award achievements: %w", err)
+	}
+	return nil
+}
+
+// GetMyAchievementsHandler возвращает все значки: полученные — с временем
+// получения. Перед ответом условия проверяются заново по всей истории.
+func GetMyAchievementsHandler(w http.ResponseWriter, r *http.Request) {
+	userID := r.Context().Value(auth.UserIDKey).(int64)
+	ctx := r.Context()
+
+	tx, err := db.Pool.Begin(ctx)
+	if err != nil {
+		log.Printf("GetMyAchievementsHandler: %v", err)
+		http.Error(w, "Database error", http.StatusInternalServerError)
+		return
+	}
+	defer tx.Rollback(ctx)
+
+	if err := awardAchievements(ctx, tx, userID); err != nil {
+		log.Printf("GetMyAchievementsHandler: %v", err)
+		http.Error(w, "Database error", http.StatusInternalServerError)
+		return
+	}
+
+	rows, err := tx.Query(ctx, `
+		SELECT a.id, a.slug, a.title, a.description, a.icon, ua.awarded_at
+		FROM achievements a
+		LEFT JOIN user_achievements ua ON ua.achievement_id = a.id AND ua.user_id = $1
+		ORDER BY ua.awarded_at IS NULL, a.order_index, a.id
+	`, userID)
+	if err != nil {
+		log.Printf("GetMyAchievementsHandler: %v", err)
+		http.Error(w, "Database error", http.StatusInternalServerError)
+		return
+	}
+	defer rows.Close()
+
+	list := []AchievementView{}
+	for rows.Next() {
+		var v AchievementView
+		if err := rows.Scan(&v.ID, &v.Slug, &v.Title, &v.Description, &v.Icon, &v.AwardedAt); err != nil {
+			log.Printf("GetMyAchievementsHandler: %v", err)
+			http.Error(w, "Database error", http.StatusInternalServerError)
+			return
+		}
+		v.Earned = v.AwardedAt != nil
+		list = append(list, v)
+	}
+	if err := rows.Err(); err != nil {
+		log.Printf("GetMyAchievementsHandler: %v", err)
+		http.Error(w, "Database error", http.StatusInternalServerError)
+		return
+	}
+	rows.Close()
+
+	if err := tx.Commit(ctx); err != nil {
+		log.Printf("GetMyAchievementsHandler: commit: %v", err)
+		http.Error(w, "Database error", http.StatusInternalServerError)
+		return
+	}
+	writeJSON(w, http.StatusOK, list)
+}
@@ -0,0 +1,836 @@
+package handlers
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log"
+	"net/http"
+	"strconv"
+	"strings"
+
+	"sonara-space/backend/internal/content"
+	"sonara-space/backend/internal/db"
+	"sonara-space/backend/internal/instruments"
+	"sonara-space/backend/internal/models"
+	"sonara-space/backend/internal/versions"
+
+	"github.com/go-chi/chi/v5"
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgconn"
+)
+
+// LessonInput представляет тело запроса на создание/изменение урока
+type LessonInput struct {
+	Slug        string   `json:"slug"`
+	Title       string   `json:"title"`
+	Instrument  string   `json:"instrument"`
+	Tuning      *string  `json:"tuning"`
+	Capo        int      `json:"capo"`
+	Description *string  `json:"description"`
+	Difficulty  string   `json:"difficulty"`
+	Tier        string   `json:"tier"`
+	Locale      string   `json:"locale"`
+	Tags        []string `json:"tags"`
+}
+
+// ExerciseInput представляет тело запроса на создание/изменение упражнения.
+// Для type = "sequence" вместо expected передаётся sequence, для
+// type = "rhythm" — rhythm.
+type ExerciseInput struct {
+	Slug       string         `json:"slug"`
+	Title      string         `json:"title"`
+	Expected   string         `json:"expected"`
+	Type       string         `json:"type"`
+	OrderIndex int            `json:"order_index"`
+	Sequence   *SequenceInput `json:"sequence"`
+	Rhythm     *RhythmInput   `json:"rhythm"`
+}
+
+// ReorderRequest задаёт новый порядок упражнений урока
+type ReorderRequest struct {
+	ExerciseIDs []int64 `json:"exercise_ids"`
+}
+
+// Validate проверяет урок и нормализует поля: обрезает пробелы, подставляет
+// сложность, тариф и язык по умолчанию, убирает повторы тегов
+func (in *LessonInput) Validate() error {
+	in.Slug = strings.TrimSpace(in.Slug)
+	in.Title = strings.TrimSpace(in.Title)
+	in.Instrument = strings.ToLower(strings.TrimSpace(in.Instrument))
+	if in.Slug != "" && !content.ValidSlug(in.Slug) {
+		return errors.New("invalid slug")
+	}
+	if in.Title == "" {
+		return errors.New("title is required")
+	}
+	if !instruments.Valid(in.Instrument) {
+		return errors.New("invalid instrument")
+	}
+	if in.Tuning != nil {
+		if *in.Tuning = strings.TrimSpace(*in.Tuning); *in.Tuning == "" {
+			in.Tuning = nil
+		}
+	}
+	if err := in.setup().Validate(); err != nil {
+		return err
+	}
+
+	if in.Difficulty == "" {
+		in.Difficulty = models.DifficultyBeginner
+	}
+	switch in.Difficulty {
+	case models.DifficultyBeginner, models.DifficultyIntermediate, models.DifficultyAdvanced:
+	default:
+		return errors.New("invalid difficulty")
+	}
+	if in.Tier == "" {
+		in.Tier = models.TierFree
+	}
+	if in.Tier != models.TierFree && in.Tier != models.TierPremium {
+		return errors.New("invalid tier")
+	}
+	in.Locale = strings.ToLower(strings.TrimSpace(in.Locale))
+	if in.Locale == "" {
+		in.Locale = "ru"
+	}
+	if len(in.Locale) > 10 {
+		return errors.New("invalid locale")
+	}
+
+	// Теги храним в нижнем регистре и без повторов
+	tags := make([]string, 0, len(in.Tags))
+	seen := make(map[string]bool)
+	for _, tag := range in.Tags {
+		tag = strings.ToLower(strings.TrimSpace(tag))
+		if tag == "" || seen[tag] {
+			continue
+		}
+		seen[tag] = true
+		tags = append(tags, tag)
+	}
+	in.Tags = tags
+	return nil
+}
+
+// setup — инструмент урока со строем и каподастром
+func (in *LessonInput) setup() instruments.Setup {
+	s := instruments.Setup{Instrument: in.Instrument, Capo: in.Capo}
+	if in.Tuning != nil {
+		s.Tuning = *in.Tuning
+	}
+	return s
+}
+
+// Validate проверяет упражнение; для песни и ритма expected строится из
+// последовательности или рисунка
+func (in *ExerciseInput) Validate() error {
+	in.Slug = strings.TrimSpace(in.Slug)
+	in.Title = strings.TrimSpace(in.Title)
+	in.Expected = strings.TrimSpace(in.Expected)
+	if in.Slug != "" && !content.ValidSlug(in.Slug) {
+		return errors.New("invalid slug")
+	}
+	if in.Title == "" {
+		return errors.New("title is required")
+	}
+	if in.OrderIndex < 0 {
+		return errors.New("order_index must not be negative")
+	}
+	if in.Type == models.ExerciseTypeSequence {
+		if in.Sequence == nil {
+			return errors.New("sequence is required for sequence exercises")
+		}
+		expected, err := in.Sequence.validate()
+		if err != nil {
+			return err
+		}
+		in.Expected = expected
+		return nil
+	}
+	if in.Sequence != nil {
+		return errors.New("sequence is only allowed for sequence exercises")
+	}
+	if in.Type == models.ExerciseTypeRhythm {
+		if in.Rhythm == nil {
+			return errors.New("rhythm is required for rhythm exercises")
+		}
+		expected, err := in.Rhythm.validate()
+		if err != nil {
+			return err
+		}
+		in.Expected = expected
+		return nil
+	}
+	if in.Rhythm != nil {
+		return errors.New("rhythm is only allowed for rhythm exercises")
+	}
+	return models.ValidateExpected(in.Type, in.Expected)
+}
+
+func writeJSON(w http.ResponseWriter, status int, v interface{}) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	json.NewEncoder(w).Encode(v)
+}
+
+func urlParamID(r *http.Request, name string) (int64, error) {
+	return strconv.ParseInt(chi.URLParam(r, name), 10, 64)
+}
+
+// errSlugTaken — явно указанный slug уже занят
+var errSlugTaken = errors.New("slug already exists")
+
+// pickSlug возвращает slug для новой записи. Явно указанный slug должен быть
+// свободен; иначе slug строится из base с суффиксами -2, -3... до свободного.
+// takenQuery получает slug первым параметром, затем args, и возвращает bool.
+func pickSlug(ctx context.Context, q content.DB, explicit, base, takenQuery string, args ...interface{}) (string, error) {
+	taken := func(slug string) (bool, error) {
+		var ok bool
+		err := q.QueryRow(ctx, takenQuery, append([]interface{}{slug}, args...)...).Scan(&ok)
+		return ok, err
+	}
+	if explicit != "" {
+		ok, err := taken(explicit)
+		if err == nil && ok {
+			err = errSlugTaken
+		}
+		return explicit, err
+	}
+	for n := 1; ; n++ {
+		slug := base
+		if n > 1 {
+			slug = fmt.Sprintf("%s-%d", base, n)
+		}
+		ok, err := taken(slug)
+		if err != nil || !ok {
+			return slug, err
+		}
+	}
+}
+
+// maxSlugAttempts — сколько раз повторять вставку, если выбранный slug
+// успел занять параллельный запрос
+const maxSlugAttempts = 5
+
+// slugDB — пул или транзакция: в транзакции Begin открывает точку сохранения
+type slugDB interface {
+	content.DB
+	Begin(ctx context.Context) (pgx.Tx, error)
+}
+
+// slugConflict — запрос нарушил уникальность slug
+func slugConflict(err error) bool {
+	var pgErr *pgconn.PgError
+	return errors.As(err, &pgErr) && pgErr.Code == "23505" &&
+		strings.Contains(pgErr.ConstraintName, "slug")
+}
+
+// insertWithSlug выбирает slug (см. pickSlug) и вставляет запись функцией
+// insert. Проверка и вставка не атомарны: если параллельный запрос занял тот
+// же slug, вставка откатывается до точки сохранения и повторяется со
+// следующим свободным slug. Занятый явный slug — errSlugTaken.
+func insertWithSlug(ctx context.Context, q slugDB, explicit, base, takenQuery string, takenArgs []interface{},
+	insert func(tx pgx.Tx, slug string) error) (string, error) {
+	for attempt := 1; ; attempt++ {
+		tx, err := q.Begin(ctx)
+		if err != nil {
+			return "", err
+		}
+		slug, err := pickSlug(ctx, tx, explicit, base, takenQuery, takenArgs...)
+		if err == nil {
+			err = insert(tx, slug)
+		}
+		if err == nil {
+			return slug, tx.Commit(ctx)
+		}
+		tx.Rollback(ctx)
+		if !slugConflict(err) {
+			return "", err
+		}
+		if explicit != "" {
+			return "", errSlugTaken
+		}
+		if attempt == maxSlugAttempts {
+			return "", err
+		}
+	}
+}
+
+// AdminListLessonsHandler возвращает все уроки, включая черновики
+func AdminListLessonsHandler(w http.ResponseWriter, r *http.Request) {
+	rows, err := db.Pool.Query(r.Context(),
+		`SELECT `+lessonColumns+` FROM lessons ORDER BY created_at, id`)
+	if err != nil {
+		log.Printf("AdminListLessonsHandler: %v", err)
+		http.Error(w, "Database error", http.StatusInternalServerError)
+		return
+	}
+	defer rows.Close()
+
+	lessons := []models.Lesson{}
+	for rows.Next() {
+		var lesson models.Lesson
+		if err := scanLesson(rows, &lesson); err != nil {
+			http.Error(w, "Database scan error", http.StatusInternalServerError)
+			return
+		}
+		lessons = append(lessons, lesson)
+	}
+	if err := rows.Err(); err != nil {
+		http.Error(w, "Database rows error", http.StatusInternalServerError)
+		return
+	}
+
+	writeJSON(w, http.StatusOK, lessons)
+}
+
+// AdminCreateLessonHandler создаёт урок в статусе черновика
+func AdminCreateLessonHandler(w http.ResponseWriter, r *http.Request) {
+	var in LessonInput
+	if err := json.NewDecoder(r.Body).Decode(&in); err != nil {
+		http.Error(w, "Invalid JSON", http.StatusBadRequest)
+		return
+	}
+	if err := in.Validate(); err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	base := content.Slugify(in.Title)
+	if base == "" {
+		base = "lesson"
+	}
+	var lesson models.Lesson
+	_, err := insertWithSlug(r.Context(), db.Pool, in.Slug, base,
+		`SELECT EXISTS (SELECT 1 FROM lessons WHERE slug = $1)`, nil,
+		func(tx pgx.Tx, slug string) error {
+			return scanLesson(tx.QueryRow(r.Context(), `
+				INSERT INTO lessons (slug, title, instrument, tuning, capo, description, difficulty, tier, locale, tags, status)
+				VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10, 'draft')
+				RETURNING `+lessonColumns,
+				slug, in.Title, in.Instrument, in.Tuning, in.Capo, in.Description, in.Difficulty, in.Tier, in.Locale, in.Tags), &lesson)
+		})
+	if errors.Is(err, errSlugTaken) {
+		http.Error(w, err.Error(), http.StatusConflict)
+		return
+	}
+	if err != nil {
+		log.Printf("AdminCreateLessonHandler: %v", err)
+		http.Error(w, "Database error", http.StatusInternalServerError)
+		return
+	}
+
+	writeJSON(w, http.StatusCreated, lesson)
+}
+
+// AdminUpdateLessonHandler изменяет свойства урока (кроме статуса публикации)
+func AdminUpdateLessonHandler(w http.ResponseWriter, r *http.Request) {
+	lessonID, err := urlParamID(r, "id")
+	if err != nil {
+		http.Error(w, "Invalid lesson ID", http.StatusBadRequest)
+		return
+	}
+
+	var in LessonInput
+	if err := json.NewDecoder(r.Body).Decode(&in); err != nil {
+		http.Error(w, "Invalid JSON", http.StatusBadRequest)
+		return
+	}
+	if err := in.Validate(); err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	// slug меняется только если передан явно: на него ссылаются файлы контента
+	if in.Slug != "" {
+		_, err = pickSlug(r.Context(), db.Pool, in.Slug, "",
+			`SELECT EXISTS (SELECT 1 FROM lessons WHERE slug = $1 AND id <> $2)`, lessonID)
+		if errors.Is(err, errSlugTaken) {
+			http.Error(w, err.Error(), http.StatusConflict)
+			return
+		}
+		if err != nil {
+			log.Printf("AdminUpdateLessonHandler: slug: %v", err)
+			http.Error(w, "Database error", http.StatusInternalServerError)
+			return
+		}
+	}
+
+	// Смена инструмента или строя не должна сделать упражнения черновика неиграбельными
+	notes, err := draftNotes(r.Context(), lessonID)
+	if err != nil {
+		log.Printf("AdminUpdateLessonHandler: %v", err)
+		http.Error(w, "Database error", http.StatusInternalServerError)
+		return
+	}
+	rng, _ := in.setup().Range()
+	if bad := rng.Outside(notes); len(bad) > 0 {
+		http.Error(w, fmt.Sprintf("exercise note %s is outside the %s range %s", notes[bad[0]], in.setup(), rng),
+			http.StatusUnprocessableEntity)
+		return
+	}
+
+	var lesson models.Lesson
+	err = scanLesson(db.Pool.QueryRow(r.Context(), `
+		UPDATE lessons SET title = $2, instrument = $3, description = $4,
+			difficulty = $5, tier = $6, locale = $7, tags = $8,
+			slug = COALESCE(NULLIF($9, ''), slug), tuning = $10, capo = $11, updated_at = NOW()
+		WHERE id = $1
+		RETURNING `+lessonColumns,
+		lessonID, in.Title, in.Instrument, in.Description, in.Difficulty, in.Tier, in.Locale, in.Tags, in.Slug,
+		in.Tuning, in.Capo), &lesson)
+	if errors.Is(err, pgx.ErrNoRows) {
+		http.Error(w, "Lesson not found", http.StatusNotFound)
+		return
+	}
+	if slugConflict(err) {
+		http.Error(w, errSlugTaken.Error(), http.StatusConflict)
+		return
+	}
+	if err != nil {
+		log.Printf("AdminUpdateLessonHandler: %v", err)
+		http.Error(w, "Database error", http.StatusInternalServerError)
+		return
+	}
+
+	writeJSON(w, http.StatusOK, lesson)
+}
+
+// AdminDeleteLessonHandler удаляет урок вместе с упражнениями. Урок, который
+// публиковался или по которому есть прогресс, только архивируется:
+// история учеников не удаляется.
+func AdminDeleteLessonHandler(w http.ResponseWriter, r *http.Request) {
+	lessonID, err := urlParamID(r, "id")
+	if err != nil {
+		http.Error(w, "Invalid lesson ID", http.StatusBadRequest)
+		return
+	}
+
+	tag, err := db.Pool.Exec(r.Context(), `
+		DELETE FROM lessons l WHERE l.id = $1
+			AND NOT EXISTS (SELECT 1 FROM lesson_versions v WHERE v.lesson_id = l.id)
+			AND NOT EXISTS (SELECT 1 FROM exercises e JOIN exercise_attempts a ON a.exercise_id = e.id WHERE e.lesson_id = l.id)
+			AND NOT EXISTS (SELECT 1 FROM exercises e JOIN progress p ON p.exercise_id = e.id WHERE e.lesson_id = l.id)
+	`, lessonID)
+	if err == nil && tag.RowsAffected() == 0 {
+		tag, err = db.Pool.Exec(r.Context(),
+			`UPDATE lessons SET status = 'archived', updated_at = NOW() WHERE id = $1`, lessonID)
+	}
+	if err != nil {
+		log.Printf("AdminDeleteLessonHandler: %v", err)
+		http.Error(w, "Database error", http.StatusInternalServerError)
+		return
+	}
+	if tag.RowsAffected() == 0 {
+		http.Error(w, "Lesson not found", http.StatusNotFound)
+		return
+	}
+
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// AdminUnpublishLessonHandler снимает урок с публикации
+func AdminUnpublishLessonHandler(w http.ResponseWriter, r *http.Request) {
+	setLessonStatus(w, r, models.LessonStatusDraft)
+}
+
+func setLessonStatus(w http.ResponseWriter, r *http.Request, status string) {
+	lessonID, err := urlParamID(r, "id")
+	if err != nil {
+		http.Error(w, "Invalid lesson ID", http.StatusBadRequest)
+		return
+	}
+
+	tag, err := db.Pool.Exec(r.Context(),
+		`UPDATE lessons SET status = $2, updated_at = NOW() WHERE id = $1`, lessonID, status)
+	if err != nil {
+		log.Printf("setLessonStatus: %v", err)
+		http.Error(w, "Database error", http.StatusInternalServerError)
+		return
+	}
+	if tag.RowsAffected() == 0 {
+		http.Error(w, "Lesson not found", http.StatusNotFound)
+		return
+	}
+
+	writeJSON(w, http.StatusOK, map[string]interface{}{
+		"id":     lessonID,
+		"status": status,
+	})
+}
+
+// AdminCreateExerciseHandler добавляет упражнение в урок.
+// Если order_index не указан, упражнение ставится в конец.
+func AdminCreateExerciseHandler(w http.ResponseWriter, r *http.Request) {
+	lessonID, err := urlParamID(r, "id")
+	if err != nil {
+		http.Error(w, "Invalid lesson ID", http.StatusBadRequest)
+		return
+	}
+
+	var in ExerciseInput
+	if err := json.NewDecoder(r.Body).Decode(&in); err != nil {
+		http.Error(w, "Invalid JSON", http.StatusBadRequest)
+		return
+	}
+	if err := in.Validate(); err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	ctx := r.Context()
+	tx, err := db.Pool.Begin(ctx)
+	if err != nil {
+		log.Printf("AdminCreateExerciseHandler: %v", err)
+		http.Error(w, "Database error", http.StatusInternalServerError)
+		return
+	}
+	defer tx.Rollback(ctx)
+
+	setup, err := loadInstrumentSetup(ctx, tx, lessonID)
+	if errors.Is(err, pgx.ErrNoRows) {
+		http.Error(w, "Lesson not found", http.StatusNotFound)
+		return
+	}
+	if err != nil {
+		log.Printf("AdminCreateExerciseHandler: %v", err)
+		http.Error(w, "Database error", http.StatusInternalServerError)
+		return
+	}
+	if err := checkExerciseRange(setup, &in); err != nil {
+		http.Error(w, err.Error(), http.StatusUnprocessableEntity)
+		return
+	}
+
+	exercise := models.Exercise{LessonID: lessonID, Title: in.Title, Expected: in.Expected, Type: in.Type}
+	exercise.Slug, err = insertWithSlug(ctx, tx, in.Slug, content.ExerciseSlug(in.Type, in.Expected),
+		`SELECT EXISTS (SELECT 1 FROM exercises WHERE slug = $1 AND lesson_id = $2 AND retired_at IS NULL)`,
+		[]interface{}{lessonID},
+		func(tx pgx.Tx, slug string) error {
+			return tx.QueryRow(ctx, `
+				INSERT INTO exercises (lesson_id, slug, title, expected, type, order_index)
+				SELECT l.id, $6, $2, $3, $4,
+					CASE WHEN $5 > 0 THEN $5
+					     ELSE COALESCE((SELECT MAX(order_index) FROM exercises
+					                    WHERE lesson_id = l.id AND retired_at IS NULL), 0) + 1 END
+				FROM lessons l WHERE l.id = $1
+				RETURNING id, order_index, created_at
+			`, lessonID, in.Title, in.Expected, in.Type, in.OrderIndex, slug).Scan(
+				&exercise.ID, &exercise.OrderIndex, &exercise.CreatedAt)
+		})
+	if errors.Is(err, errSlugTaken) {
+		http.Error(w, err.Error(), http.StatusConflict)
+		return
+	}
+	if errors.Is(err, pgx.ErrNoRows) {
+		http.Error(w, "Lesson not found", http.StatusNotFound)
+		return
+	}
+	if err != nil {
+		log.Printf("AdminCreateExerciseHandler: %v", err)
+		http.Error(w, "Database error", http.StatusInternalServerError)
+		return
+	}
+
+	if in.Sequence != nil {
+		if err := saveSequence(ctx, tx, exercise.ID, in.Sequence); err != nil {
+			log.Printf("AdminCreateExerciseHandler: save sequence: %v", err)
+			http.Error(w, "Database error", http.StatusInternalServerError)
+			return
+		}
+	}
+	if in.Rhythm != nil {
+		if err := saveRhythm(ctx, tx, exercise.ID, in.Rhythm); err != nil {
+			log.Printf("AdminCreateExerciseHandler: save rhythm: %v", err)
+			http.Error(w, "Database error", http.StatusInternalServerError)
+			return
+		}
+	}
+
+	if err := tx.Commit(ctx); err != nil {
+		log.Printf("AdminCreateExerciseHandler: commit: %v", err)
+		http.Error(w, "Database error", http.StatusInternalServerError)
+		return
+	}
+
+	views, err := withSequences(ctx, []models.Exercise{exercise})
+	if err != nil {
+		log.Printf("AdminCreateExerciseHandler: load sequence: %v", err)
+		http.Error(w, "Database error", http.StatusInternalServerError)
+		return
+	}
+
+	writeJSON(w, http.StatusCreated, views[0])
+}
+
+// AdminUpdateExerciseHandler изменяет упражнение черновика. Упражнение из
+// опубликованной версии не меняется: правка применяется к его копии, и в
+// ответе приходит ID копии.
+func AdminUpdateExerciseHandler(w http.ResponseWriter, r *http.Request) {
+	exerciseID, err := urlParamID(r, "id")
+	if err != nil {
+		http.Error(w, "Invalid exercise ID", http.StatusBadRequest)
+		return
+	}
+
+	var in ExerciseInput
+	if err := json.NewDecoder(r.Body).Decode(&in); err != nil {
+		http.Error(w, "Invalid JSON", http.StatusBadRequest)
+		return
+	}
+	if err := in.Validate(); err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	ctx := r.Context()
+	tx, err := db.Pool.Begin(ctx)
+	if err != nil {
+		log.Printf("AdminUpdateExerciseHandler: %v", err)
+		http.Error(w, "Database error", http.StatusInternalServerError)
+		return
+	}
+	defer tx.Rollback(ctx)
+
+	var lessonID int64
+	var setup instruments.Setup
+	err = tx.QueryRow(ctx, `SELECT lesson_id FROM exercises WHERE id = $1`, exerciseID).Scan(&lessonID)
+	if err == nil {
+		setup, err = loadInstrumentSetup(ctx, tx, lessonID)
+	}
+	if errors.Is(err, pgx.ErrNoRows) {
+		http.Error(w, "Exercise not found", http.StatusNotFound)
+		return
+	}
+	if err != nil {
+		log.Printf("AdminUpdateExerciseHandler: %v", err)
+		http.Error(w, "Database error", http.StatusInternalServerError)
+		return
+	}
+	if err := checkExerciseRange(setup, &in); err != nil {
+		http.Error(w, err.Error(), http.StatusUnprocessableEntity)
+		return
+	}
+
+	published, err := versions.Published(ctx, tx, exerciseID)
+	if err == nil && published {
+		exerciseID, err = versions.Fork(ctx, tx, exerciseID)
+	}
+	if errors.Is(err, versions.ErrNotFound) {
+		http.Error(w, "Exercise not found", http.StatusNotFound)
+		return
+	}
+	if err != nil {
+		log.Printf("AdminUpdateExerciseHandler: fork: %v", err)
+		http.Error(w, "Database error", http.StatusInternalServerError)
+		return
+	}
+
+	if in.Slug != "" {
+		_, err = pickSlug(ctx, tx, in.Slug, "", `SELECT EXISTS (SELECT 1 FROM exercises o
+			JOIN exercises e ON e.lesson_id = o.lesson_id AND e.id = $2
+			WHERE o.slug = $1 AND o.id <> $2 AND o.retired_at IS NULL)`, exerciseID)
+		if errors.Is(err, errSlugTaken) {
+			http.Error(w, err.Error(), http.StatusConflict)
+			return
+		}
+		if err != nil {
+			log.Printf("AdminUpdateExerciseHandler: slug: %v", err)
+			http.Error(w, "Database error", http.StatusInternalServerError)
+			return
+		}
+	}
+
+	exercise := models.Exercise{ID: exerciseID, Title: in.Title, Expected: in.Expected, Type: in.Type}
+	err = tx.QueryRow(ctx, `
+		UPDATE exercises SET title = $2, expected = $3, type = $4,
+			order_index = CASE WHEN $5 > 0 THEN $5 ELSE order_index END,
+			slug = COALESCE(NULLIF($6, ''), slug), updated_at = NOW()
+		WHERE id = $1 AND retired_at IS NULL
+		RETURNING lesson_id, slug, order_index, created_at
+	`, exerciseID, in.Title, in.Expected, in.Type, in.OrderIndex, in.Slug).Scan(
+		&exercise.LessonID, &exercise.Slug, &exercise.OrderIndex, &exercise.CreatedAt)
+	if errors.Is(err, pgx.ErrNoRows) {
+		http.Error(w, "Exercise not found", http.StatusNotFound)
+		return
+	}
+	// slug мог занять параллельный запрос после pickSlug
+	if slugConflict(err) {
+		http.Error(w, errSlugTaken.Error(), http.StatusConflict)
+		return
+	}
+	if err != nil {
+		log.Printf("AdminUpdateExerciseHandler: %v", err)
+		http.Error(w, "Database error", http.StatusInternalServerError)
+		return
+	}
+
+	if in.Sequence != nil {
+		err = saveSequence(ctx, tx, exerciseID, in.Sequence)
+	} else {
+		// Упражнение перестало быть песней
+		_, err = tx.Exec(ctx, `DELETE FROM exercise_sequences WHERE exercise_id = $1`, exerciseID)
+	}
+	if err != nil {
+		log.Printf("AdminUpdateExerciseHandler: sequence: %v", err)
+		http.Error(w, "Database error", http.StatusInternalServerError)
+		return
+	}
+	if in.Rhythm != nil {
+		err = saveRhythm(ctx, tx, exerciseID, in.Rhythm)
+	} else {
+		_, err = tx.Exec(ctx, `DELETE FROM exercise_rhythms WHERE exercise_id = $1`, exerciseID)
+	}
+	if err != nil {
+		log.Printf("AdminUpdateExerciseHandler: rhythm: %v", err)
+		http.Error(w, "Database error", http.StatusInternalServerError)
+		return
+	}
+
+	if err := tx.Commit(ctx); err != nil {
+		log.Printf("AdminUpdateExerciseHandler: commit: %v", err)
+		http.Error(w, "Database error", http.StatusInternalServerError)
+		return
+	}
+
+	views, err := withSequences(ctx, []models.Exercise{exercise})
+	if err != nil {
+		log.Printf("AdminUpdateExerciseHandler: load sequence: %v", err)
+		http.Error(w, "Database error", http.StatusInternalServerError)
+		return
+	}
+
+	writeJSON(w, http.StatusOK, views[0])
+}
+
+// AdminDeleteExerciseHandler убирает упражнение из черновика. Упражнения
+// опубликованных версий и с прогрессом учеников остаются в истории.
+func AdminDeleteExerciseHandler(w http.ResponseWriter, r *http.Request) {
+	exerciseID, err := urlParamID(r, "id")
+	if err != nil {
+		http.Error(w, "Invalid exercise ID", http.StatusBadRequest)
+		return
+	}
+
+	ctx := r.Context()
+	tx, err := db.Pool.Begin(ctx)
+	if err != nil {
+		log.Printf("AdminDeleteExerciseHandler: %v", err)
+		http.Error(w, "Database error", http.StatusInternalServerError)
+		return
+	}
+	defer tx.Rollback(ctx)
+
+	_, err = versions.Remove(ctx, tx, exerciseID)
+	if errors.Is(err, versions.ErrNotFound) {
+		http.Error(w, "Exercise not found", http.StatusNotFound)
+		return
+	}
+	if err == nil {
+		err = tx.Commit(ctx)
+	}
+	if err != nil {
+		log.Printf("AdminDeleteExerciseHandler: %v", err)
+		http.Error(w, "Database error", http.StatusInternalServerError)
+		return
+	}
+
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// errReorder — перестановка не перечисляет упражнения урока ровно по одному разу
+var errReorder = errors.New("exercise_ids must list every exercise of the lesson")
+
+// ReorderIndexes проверяет новый порядок упражнений (existing — упражнения
+// черновика) и возвращает order_index каждого: 1, 2, 3... по порядку запроса
+func ReorderIndexes(existing map[int64]bool, ids []int64) (map[int64]int, error) {
+	if len(ids) != len(existing) {
+		return nil, errReorder
+	}
+	order := make(map[int64]int, len(ids))
+	for i, id := range ids {
+		if _, dup := order[id]; !existing[id] || dup {
+			return nil, errReorder
+		}
+		order[id] = i + 1
+	}
+	return order, nil
+}
+
+// AdminReorderExercisesHandler атомарно переставляет упражнения черновика урока.
+// В запросе должны быть перечислены все упражнения черновика ровно по одному разу.
+// Порядок в опубликованных версиях хранится отдельно и не меняется.
+func AdminReorderExercisesHandler(w http.ResponseWriter, r *http.Request) {
+	lessonID, err := urlParamID(r, "id")
+	if err != nil {
+		http.Error(w, "Invalid lesson ID", http.StatusBadRequest)
+		return
+	}
+
+	var req ReorderRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		http.Error(w, "Invalid JSON", http.StatusBadRequest)
+		return
+	}
+
+	ctx := r.Context()
+	tx, err := db.Pool.Begin(ctx)
+	if err != nil {
+		log.Printf("AdminReorderExercisesHandler: %v", err)
+		http.Error(w, "Database error", http.StatusInternalServerError)
+		return
+	}
+	defer tx.Rollback(ctx)
+
+	// Блокируем упражнения урока, чтобы параллельные перестановки не перемешались
+	rows, err := tx.Query(ctx,
+		`SELECT id FROM exercises WHERE lesson_id = $1 AND retired_at IS NULL FOR UPDATE`, lessonID)
+	if err != nil {
+		log.Printf("AdminReorderExercisesHandler: %v", err)
+		http.Error(w, "Database error", http.StatusInternalServerError)
+		return
+	}
+	existing := map[int64]bool{}
+	for rows.Next() {
+		var id int64
+		if err := rows.Scan(&id); err != nil {
+			rows.Close()
+			http.Error(w, "Database scan error", http.StatusInternalServerError)
+			return
+		}
+		existing[id] = true
+	}
+	rows.Close()
+	if err := rows.Err(); err != nil {
+		http.Error(w, "Database rows error", http.StatusInternalServerError)
+		return
+	}
+
+	order, err := ReorderIndexes(existing, req.ExerciseIDs)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	for _, id := range req.ExerciseIDs {
+		_, err := tx.Exec(ctx,
+			`UPDATE exercises SET order_index = $2, updated_at = NOW() WHERE id = $1`, id, order[id])
+		if err != nil {
+			log.Printf("AdminReorderExercisesHandler: %v", err)
+			http.Error(w, "Database error", http.StatusInternalServerError)
+			return
+		}
+	}
+
+	if err := tx.Commit(ctx); err != nil {
+		log.Printf("AdminReorderExercisesHandler: commit: %v", err)
+		http.Error(w, "Database error", http.StatusInternalServerError)
+		return
+	}
+
+	writeJSON(w, http.StatusOK, map[string]interface{}{
+		"lesson_id":    lessonID,
+		"exercise_ids": req.ExerciseIDs,
+	})
+}
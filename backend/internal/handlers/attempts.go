@@ -0,0 +1,565 @@
+package handlers
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"log"
+	"math"
+	"mime"
+	"net/http"
+	"strconv"
+	"time"
+
+	"sonara-space/backend/internal/audio"
+	"sonara-space/backend/internal/auth"
+	"sonara-space/backend/internal/db"
+	"sonara-space/backend/internal/models"
+	"sonara-space/backend/internal/music"
+	"sonara-space/backend/internal/practice"
+	"sonara-space/backend/internal/progress"
+	"sonara-space/backend/internal/reviews"
+	"sonara-space/backend/internal/rhythm"
+	"sonara-space/backend/internal/scoring"
+	"sonara-space/backend/internal/transpose"
+	"sonara-space/backend/internal/versions"
+
+	"github.com/jackc/pgx/v5"
+)
+
+const (
+	// maxAttemptBytes — ограничение размера записи попытки (~1 минута 16-бит моно 44.1 кГц)
+	maxAttemptBytes = 6 << 20
+	// maxAttemptSeconds — максимальная длительность анализируемой записи
+	maxAttemptSeconds = 60
+)
+
+// AttemptResponse — результат проверки попытки на сервере
+type AttemptResponse struct {
+	AttemptID  int64          `json:"attempt_id"`
+	ExerciseID int64          `json:"exercise_id"`
+	Score      float64        `json:"score"`
+	Passed     bool           `json:"passed"`
+	Status     string         `json:"status"`
+	Scoring    scoring.Result `json:"scoring"`
+	Analysis   interface{}    `json:"analysis"`
+}
+
+// StepAnalysis — результат по одному шагу песни/мелодии
+type StepAnalysis struct {
+	Symbol string      `json:"symbol"`
+	Score  float64     `json:"score"`
+	Result interface{} `json:"result"`
+}
+
+// SubmitAttemptHandler принимает запись попытки (WAV или 16-битный PCM),
+// сам определяет сыгранную ноту/аккорд, сравнивает с ожидаемым и сохраняет оценку.
+//
+// Content-Type: audio/wav — WAV-файл; audio/L16 или application/octet-stream —
+// "сырые" отсчёты, частота и число каналов передаются параметрами
+// ?sample_rate=44100&channels=1.
+//
+// Если ученик играет песню в другой тональности, сдвиг передаётся так же,
+// как при получении урока: ?transpose=N.
+//
+// Попытку ритмического упражнения можно прислать без записи —
+// application/json {"onsets_ms": [...]} с моментами ударов от первой доли.
+func SubmitAttemptHandler(w http.ResponseWriter, r *http.Request) {
+	userID := r.Context().Value(auth.UserIDKey).(int64)
+	ctx := r.Context()
+
+	exerciseID, err := urlParamID(r, "id")
+	if err != nil {
+		http.Error(w, "Invalid exercise ID", http.StatusBadRequest)
+		return
+	}
+
+	exercise, err := loadExercise(ctx, userID, exerciseID)
+	if err != nil {
+		http.Error(w, "Exercise not found", http.StatusNotFound)
+		return
+	}
+	semitones, err := queryTranspose(r)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	var (
+		metrics    scoring.Metrics
+		analysis   interface{}
+		durationMs *int
+	)
+	if exercise.Type == models.ExerciseTypeRhythm && isJSONRequest(r) {
+		onsets, readErr := readRhythmOnsets(w, r)
+		if readErr != nil {
+			http.Error(w, readErr.Error(), http.StatusBadRequest)
+			return
+		}
+		metrics, analysis, err = analyzeRhythm(ctx, exercise.ID, onsets, false)
+	} else {
+		buf, readErr := readAttemptAudio(w, r)
+		if readErr != nil {
+			http.Error(w, readErr.Error(), http.StatusBadRequest)
+			return
+		}
+		if buf.Duration() > maxAttemptSeconds {
+			http.Error(w, "Recording is too long", http.StatusRequestEntityTooLarge)
+			return
+		}
+		d := int(buf.Duration() * 1000)
+		durationMs = &d
+		metrics, analysis, err = analyzeAttempt(ctx, exercise, buf, semitones)
+	}
+	if err != nil {
+		log.Printf("SubmitAttemptHandler: exercise %d: %v", exerciseID, err)
+		http.Error(w, "Cannot analyze exercise", http.StatusUnprocessableEntity)
+		return
+	}
+
+	detected, err := json.Marshal(analysis)
+	if err != nil {
+		log.Printf("SubmitAttemptHandler: encode analysis: %v", err)
+		http.Error(w, "Server error", http.StatusInternalServerError)
+		return
+	}
+	attempt := models.Attempt{
+		UserID:     userID,
+		ExerciseID: exerciseID,
+		Source:     models.AttemptSourceServer,
+		Detected:   detected,
+		DurationMs: durationMs,
+	}
+	// Для ритма в истории сохраняем среднее отклонение ударов
+	if res, ok := analysis.(rhythm.Result); ok {
+		deviation := int(math.Round(res.MeanAbsDeviationMs))
+		attempt.TimingDeviationMs = &deviation
+	}
+	result, err := recordAttempt(ctx, &attempt, func(retries int) scoring.Result {
+		metrics.Retries = retries
+		return scoring.Evaluate(exercise.Type, metrics)
+	})
+	if err != nil {
+		log.Printf("SubmitAttemptHandler: record attempt: %v", err)
+		http.Error(w, "Database error", http.StatusInternalServerError)
+		return
+	}
+
+	writeJSON(w, http.StatusOK, AttemptResponse{
+		AttemptID:  attempt.ID,
+		ExerciseID: exerciseID,
+		Score:      result.Score,
+		Passed:     result.Passed,
+		Status:     attempt.Status,
+		Scoring:    result,
+		Analysis:   analysis,
+	})
+}
+
+// AttemptView — попытка в истории
+type AttemptView struct {
+	ID                int64           `json:"id"`
+	Source            string          `json:"source"`
+	Status            string          `json:"status"`
+	Passed            bool            `json:"passed"`
+	Score             float64         `json:"score"`
+	Detected          json.RawMessage `json:"detected,omitempty"`
+	TimingDeviationMs *int            `json:"timing_deviation_ms,omitempty"`
+	DurationMs        *int            `json:"duration_ms,omitempty"`
+	CreatedAt         time.Time       `json:"created_at"`
+}
+
+// AttemptHistoryResponse — страница истории попыток
+type AttemptHistoryResponse struct {
+	ExerciseID int64         `json:"exercise_id"`
+	Total      int           `json:"total"`
+	Limit      int           `json:"limit"`
+	Offset     int           `json:"offset"`
+	Items      []AttemptView `json:"items"`
+}
+
+// GetAttemptsHandler возвращает историю попыток текущего пользователя по
+// упражнению, новые сверху. Параметры: ?limit=20&offset=0 (limit не больше 100).
+func GetAttemptsHandler(w http.ResponseWriter, r *http.Request) {
+	userID := r.Context().Value(auth.UserIDKey).(int64)
+	ctx := r.Context()
+
+	exerciseID, err := urlParamID(r, "id")
+	if err != nil {
+		http.Error(w, "Invalid exercise ID", http.StatusBadRequest)
+		return
+	}
+	limit, offset, err := pageParams(r, 20, 100)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	resp := AttemptHistoryResponse{ExerciseID: exerciseID, Limit: limit, Offset: offset, Items: []AttemptView{}}
+	err = db.Pool.QueryRow(ctx,
+		`SELECT COUNT(*) FROM exercise_attempts WHERE user_id = $1 AND exercise_id = $2`,
+		userID, exerciseID).Scan(&resp.Total)
+	if err != nil {
+		log.Printf("GetAttemptsHandler: count: %v", err)
+		http.Error(w, "Database error", http.StatusInternalServerError)
+		return
+	}
+
+	rows, err := db.Pool.Query(ctx, `
+		SELECT id, source, status, passed, score, detected, timing_deviation_ms, duration_ms, created_at
+		FROM exercise_attempts
+		WHERE user_id = $1 AND exercise_id = $2
+		ORDER BY created_at DESC, id DESC
+		LIMIT $3 OFFSET $4
+	`, userID, exerciseID, limit, offset)
+	if err != nil {
+		log.Printf("GetAttemptsHandler: %v", err)
+		http.Error(w, "Database error", http.StatusInternalServerError)
+		return
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		var a AttemptView
+		if err := rows.Scan(&a.ID, &a.Source, &a.Status, &a.Passed, &a.Score, &a.Detected,
+			&a.TimingDeviationMs, &a.DurationMs, &a.CreatedAt); err != nil {
+			http.Error(w, "Database scan error", http.StatusInternalServerError)
+			return
+		}
+		resp.Items = append(resp.Items, a)
+	}
+	if err := rows.Err(); err != nil {
+		http.Error(w, "Database rows error", http.StatusInternalServerError)
+		return
+	}
+
+	writeJSON(w, http.StatusOK, resp)
+}
+
+// queryLimit разбирает ?limit= с ограничением сверху
+func queryLimit(r *http.Request, defaultLimit, maxLimit int) (int, error) {
+	limit := defaultLimit
+	if v := r.URL.Query().Get("limit"); v != "" {
+		n, err := strconv.Atoi(v)
+		if err != nil || n <= 0 {
+			return 0, errors.New("invalid limit")
+		}
+		limit = n
+	}
+	if limit > maxLimit {
+		limit = maxLimit
+	}
+	return limit, nil
+}
+
+// pageParams разбирает ?limit=&offset= с ограничением сверху
+func pageParams(r *http.Request, defaultLimit, maxLimit int) (int, int, error) {
+	limit, err := queryLimit(r, defaultLimit, maxLimit)
+	if err != nil {
+		return 0, 0, err
+	}
+	offset := 0
+	if v := r.URL.Query().Get("offset"); v != "" {
+		n, err := strconv.Atoi(v)
+		if err != nil || n < 0 {
+			return 0, 0, errors.New("invalid offset")
+		}
+		offset = n
+	}
+	return limit, offset, nil
+}
+
+// readAttemptAudio читает тело запроса как WAV или PCM в зависимости от Content-Type
+func readAttemptAudio(w http.ResponseWriter, r *http.Request) (*audio.Buffer, error) {
+	body, err := io.ReadAll(http.MaxBytesReader(w, r.Body, maxAttemptBytes))
+	if err != nil {
+		return nil, errors.New("recording is too large or unreadable")
+	}
+	if len(body) == 0 {
+		return nil, errors.New("empty recording")
+	}
+
+	mediaType, params, _ := mime.ParseMediaType(r.Header.Get("Content-Type"))
+	switch mediaType {
+	case "audio/wav", "audio/x-wav", "audio/wave", "audio/vnd.wave":
+		buf, err := audio.DecodeWAV(bytes.NewReader(body))
+		if err != nil {
+			return nil, fmt.Errorf("invalid WAV: %v", err)
+		}
+		return buf, nil
+
+	case "audio/l16", "application/octet-stream":
+		rate, channels := params["rate"], params["channels"]
+		if q := r.URL.Query().Get("sample_rate"); q != "" {
+			rate = q
+		}
+		if q := r.URL.Query().Get("channels"); q != "" {
+			channels = q
+		}
+		if channels == "" {
+			channels = "1"
+		}
+		sampleRate, err1 := strconv.Atoi(rate)
+		numChannels, err2 := strconv.Atoi(channels)
+		if err1 != nil || err2 != nil {
+			return nil, errors.New("sample_rate and channels are required for raw PCM")
+		}
+		buf, err := audio.DecodePCM16(body, sampleRate, numChannels)
+		if err != nil {
+			return nil, fmt.Errorf("invalid PCM: %v", err)
+		}
+		return buf, nil
+	}
+	return nil, errors.New("unsupported Content-Type, use audio/wav or audio/L16")
+}
+
+// analyzeAttempt анализирует запись в зависимости от типа упражнения и
+// возвращает измерения для scoring вместе с подробностями анализа. Песню,
+// сдвинутую учеником на semitones полутонов, сверяют с новой тональностью.
+func analyzeAttempt(ctx context.Context, exercise models.Exercise, buf *audio.Buffer, semitones int) (scoring.Metrics, interface{}, error) {
+	setup, err := loadInstrumentSetup(ctx, db.Pool, exercise.LessonID)
+	if err != nil {
+		return scoring.Metrics{}, nil, err
+	}
+	band := instrumentBand(setup)
+
+	switch exercise.Type {
+	case models.ExerciseTypeNote:
+		note, err := music.ParseNote(exercise.Expected)
+		if err != nil {
+			return scoring.Metrics{}, nil, err
+		}
+		res := audio.AnalyzeNoteInBand(buf, note, music.StandardA4, band)
+		return scoring.Metrics{Pitch: notePitch(res)}, res, nil
+
+	case models.ExerciseTypeChord:
+		chord, err := music.ParseChord(exercise.Expected)
+		if err != nil {
+			return scoring.Metrics{}, nil, err
+		}
+		res := audio.AnalyzeChordInBand(buf, chord, music.StandardA4, band)
+		return scoring.Metrics{Chord: chordMetric(res)}, res, nil
+
+	case models.ExerciseTypeSequence:
+		sequences, err := loadSequences(ctx, []int64{exercise.ID})
+		if err != nil {
+			return scoring.Metrics{}, nil, err
+		}
+		seq, ok := sequences[exercise.ID]
+		if !ok {
+			return scoring.Metrics{}, nil, errors.New("sequence data is missing")
+		}
+		if semitones != 0 {
+			res, err := transpose.Sequence(seq, semitones)
+			if err != nil {
+				return scoring.Metrics{}, nil, err
+			}
+			seq = &res.Sequence
+		}
+		return analyzeSequence(seq, buf, band)
+
+	case models.ExerciseTypeRhythm:
+		return analyzeRhythm(ctx, exercise.ID, recordedOnsets(buf), true)
+	}
+	return scoring.Metrics{}, nil, fmt.Errorf("unsupported exercise type %q", exercise.Type)
+}
+
+// analyzeSequence делит запись на отрезки по темпу и оценивает каждый шаг отдельно.
+// Отсчёт начинается с первого звука в записи.
+func analyzeSequence(seq *models.Sequence, buf *audio.Buffer, band audio.Band) (scoring.Metrics, interface{}, error) {
+	buf = audio.TrimLeadingSilence(buf)
+	secondsPerBeat := 60 / float64(seq.Tempo)
+
+	var metrics scoring.Metrics
+	steps := make([]StepAnalysis, 0, len(seq.Steps))
+	var position float64
+	for _, step := range seq.Steps {
+		start := position * secondsPerBeat
+		end := (position + step.Beats) * secondsPerBeat
+		position += step.Beats
+
+		// Края отрезка отбрасываем: там обычно смена аппликатуры
+		margin := (end - start) * 0.1
+		segment := buf.Slice(start+margin, end-margin)
+
+		sa := StepAnalysis{Symbol: step.Symbol}
+		switch seq.StepType {
+		case models.ExerciseTypeNote:
+			note, err := music.ParseNote(step.Symbol)
+			if err != nil {
+				return scoring.Metrics{}, nil, err
+			}
+			res := audio.AnalyzeNoteInBand(segment, note, music.StandardA4, band)
+			sa.Score, sa.Result = scoring.PitchAccuracy(*notePitch(res)), res
+		default:
+			chord, err := music.ParseChord(step.Symbol)
+			if err != nil {
+				return scoring.Metrics{}, nil, err
+			}
+			res := audio.AnalyzeChordInBand(segment, chord, music.StandardA4, band)
+			sa.Score, sa.Result = scoring.ChordAccuracy(*chordMetric(res)), res
+		}
+		metrics.Steps = append(metrics.Steps, sa.Score)
+		steps = append(steps, sa)
+	}
+	return metrics, steps, nil
+}
+
+func notePitch(res audio.NoteResult) *scoring.Pitch {
+	return &scoring.Pitch{Correct: res.Match, CentsOff: res.CentsOff}
+}
+
+func chordMetric(res audio.ChordResult) *scoring.Chord {
+	return &scoring.Chord{Recognized: res.Match, Completeness: res.Completeness}
+}
+
+// loadExercise загружает упражнение опубликованного урока в той версии,
+// которую видит пользователь (см. user_lesson_exercises). Черновики, снятые
+// с публикации уроки и упражнения других версий — pgx.ErrNoRows.
+func loadExercise(ctx context.Context, userID, exerciseID int64) (models.Exercise, error) {
+	var exercise models.Exercise
+	err := db.Pool.QueryRow(ctx, `
+		SELECT e.id, e.lesson_id, e.title, e.expected, e.type, e.order_index, e.created_at
+		FROM user_lesson_exercises($1) ue
+		JOIN exercises e ON e.id = ue.exercise_id
+		JOIN lessons l ON l.id = ue.lesson_id AND l.status = 'published'
+		WHERE e.id = $2
+	`, userID, exerciseID).Scan(
+		&exercise.ID, &exercise.LessonID, &exercise.Title,
+		&exercise.Expected, &exercise.Type, &exercise.OrderIndex, &exercise.CreatedAt)
+	return exercise, err
+}
+
+// recordAttempt оценивает и сохраняет попытку, а в той же транзакции
+// пересчитывает сводку progress по всей истории попыток пользователя.
+// evaluate получает число предыдущих неудачных попыток — их считаем под
+// блокировкой, чтобы параллельные попытки не получили одинаковый штраф.
+func recordAttempt(ctx context.Context, attempt *models.Attempt, evaluate func(retries int) scoring.Result) (scoring.Result, error) {
+	tx, err := db.Pool.Begin(ctx)
+	if err != nil {
+		return scoring.Result{}, err
+	}
+	defer tx.Rollback(ctx)
+
+	// Сериализуем попытки одного пользователя по одному упражнению,
+	// иначе параллельный пересчёт может не увидеть соседнюю попытку.
+	if _, err := tx.Exec(ctx, `SELECT pg_advisory_xact_lock($1::int, $2::int)`,
+		attempt.UserID, attempt.ExerciseID); err != nil {
+		return scoring.Result{}, err
+	}
+
+	history, err := loadAttemptHistory(ctx, tx, attempt.UserID, attempt.ExerciseID)
+	if err != nil {
+		return scoring.Result{}, fmt.Errorf("load attempts: %w", err)
+	}
+	retries := progress.Retries(history)
+
+	result := evaluate(retries)
+	attempt.Score = result.Score
+	attempt.Passed = result.Passed
+	if attempt.Status == "" {
+		attempt.Status = models.StatusFailed
+		if result.Passed {
+			attempt.Status = models.StatusDone
+		}
+	}
+
+	err = tx.QueryRow(ctx, `
+		INSERT INTO exercise_attempts (user_id, exercise_id, source, status, passed, score,
+			detected, timing_deviation_ms, duration_ms)
+		VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9)
+		RETURNING id, created_at
+	`, attempt.UserID, attempt.ExerciseID, attempt.Source, attempt.Status, attempt.Passed,
+		attempt.Score, attempt.Detected, attempt.TimingDeviationMs, attempt.DurationMs,
+	).Scan(&attempt.ID, &attempt.CreatedAt)
+	if err != nil {
+		return scoring.Result{}, fmt.Errorf("insert attempt: %w", err)
+	}
+
+	if err := refreshProgress(ctx, tx, attempt.UserID, attempt.ExerciseID); err != nil {
+		return scoring.Result{}, fmt.Errorf("refresh progress: %w", err)
+	}
+	// Отметка "начал" — не повторение; остальные попытки двигают расписание,
+	// отметки клиента — с наименьшей оценкой
+	if attempt.Status != models.StatusInProgress {
+		quality := reviews.Quality(result.Score, result.Passed)
+		if attempt.Source == models.AttemptSourceClient {
+			quality = reviews.ReportedQuality(result.Passed)
+		}
+		if err := reviews.Record(ctx, tx, attempt.UserID, attempt.ExerciseID, quality); err != nil {
+			return scoring.Result{}, fmt.Errorf("schedule review: %w", err)
+		}
+	}
+	// Первая попытка закрепляет за пользователем версию урока
+	if err := versions.Pin(ctx, tx, attempt.UserID, attempt.ExerciseID); err != nil {
+		return scoring.Result{}, fmt.Errorf("pin lesson version: %w", err)
+	}
+	// Попытка — тоже признак занятия: продлевает открытую сессию
+	if err := practice.Touch(ctx, tx, attempt.UserID, attempt.ExerciseID); err != nil {
+		return scoring.Result{}, fmt.Errorf("touch practice session: %w", err)
+	}
+	if err := awardAchievements(ctx, tx, attempt.UserID); err != nil {
+		return scoring.Result{}, err
+	}
+	if err := tx.Commit(ctx); err != nil {
+		return scoring.Result{}, err
+	}
+	syncLeaderboard(ctx, attempt.UserID)
+	return result, nil
+}
+
+// loadAttemptHistory возвращает все попытки пользователя по упражнению по порядку
+func loadAttemptHistory(ctx context.Context, tx pgx.Tx, userID, exerciseID int64) ([]models.Attempt, error) {
+	rows, err := tx.Query(ctx, `
+		SELECT id, source, status, passed, score, created_at
+		FROM exercise_attempts
+		WHERE user_id = $1 AND exercise_id = $2
+		ORDER BY created_at, id
+	`, userID, exerciseID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var history []models.Attempt
+	for rows.Next() {
+		a := models.Attempt{UserID: userID, ExerciseID: exerciseID}
+		if err := rows.Scan(&a.ID, &a.Source, &a.Status, &a.Passed, &a.Score, &a.CreatedAt); err != nil {
+			return nil, err
+		}
+		history = append(history, a)
+	}
+	return history, rows.Err()
+}
+
+// refreshProgress пересчитывает строку progress из exercise_attempts
+// (правила — progress.FromAttempts)
+func refreshProgress(ctx context.Context, tx pgx.Tx, userID, exerciseID int64) error {
+	history, err := loadAttemptHistory(ctx, tx, userID, exerciseID)
+	if err != nil {
+		return err
+	}
+	p, ok := progress.FromAttempts(history)
+	if !ok {
+		return nil
+	}
+	_, err = tx.Exec(ctx, `
+		INSERT INTO progress (user_id, exercise_id, status, completed, attempts, best_score, last_score,
+			scored_by_server, completed_at, created_at, updated_at)
+		VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10, $11)
+		ON CONFLICT (user_id, exercise_id) DO UPDATE SET
+			status = EXCLUDED.status,
+			completed = EXCLUDED.completed,
+			attempts = EXCLUDED.attempts,
+			best_score = EXCLUDED.best_score,
+			last_score = EXCLUDED.last_score,
+			scored_by_server = EXCLUDED.scored_by_server,
+			completed_at = EXCLUDED.completed_at,
+			updated_at = EXCLUDED.updated_at
+	`, userID, exerciseID, p.Status, p.Completed, p.Attempts, p.BestScore, p.LastScore,
+		p.ScoredByServer, p.CompletedAt, p.FirstAt, p.LastAt)
+	return err
+}
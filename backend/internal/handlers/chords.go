@@ -0,0 +1,100 @@
+package handlers
+
+import (
+	"net/http"
+	"net/url"
+	"strconv"
+
+	"sonara-space/backend/internal/instruments"
+	"sonara-space/backend/internal/music"
+	"sonara-space/backend/internal/voicings"
+
+	"github.com/go-chi/chi/v5"
+)
+
+const maxVoicings = 20
+
+// ChordVoicingsResponse — расположения аккорда на инструменте
+type ChordVoicingsResponse struct {
+	Chord      string   `json:"chord"`
+	Tones      []string `json:"tones"`
+	Instrument string   `json:"instrument"`
+	Tuning     string   `json:"tuning,omitempty"`
+	Capo       int      `json:"capo"`
+	Strings    []string `json:"strings,omitempty"` // открытые струны с учётом каподастра, от нижней
+	// Voicings — []voicings.Fretting для струнных или []voicings.PianoVoicing для клавишных
+	Voicings any `json:"voicings"`
+}
+
+// GetChordVoicingsHandler строит аппликатуры аккорда для инструмента:
+// GET /chords/{symbol}/voicings?instrument=guitar&tuning=drop-d&capo=2&limit=6.
+// Символ принимается в той же записи, что и Expected упражнений; косая черта
+// и диез в пути кодируются: C%2FG, F%23m.
+func GetChordVoicingsHandler(w http.ResponseWriter, r *http.Request) {
+	symbol, err := url.PathUnescape(chi.URLParam(r, "symbol"))
+	if err != nil {
+		http.Error(w, "Invalid chord symbol", http.StatusBadRequest)
+		return
+	}
+	chord, err := music.ParseChord(symbol)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	q := r.URL.Query()
+	setup := instruments.Setup{Instrument: q.Get("instrument"), Tuning: q.Get("tuning")}
+	if setup.Instrument == "" {
+		setup.Instrument = instruments.Guitar
+	}
+	if v := q.Get("capo"); v != "" {
+		if setup.Capo, err = strconv.Atoi(v); err != nil {
+			http.Error(w, "Invalid capo", http.StatusBadRequest)
+			return
+		}
+	}
+	limit, err := queryLimit(r, voicings.DefaultLimit, maxVoicings)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	inst, tuning, err := setup.Resolve()
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	resp := ChordVoicingsResponse{
+		Chord:      chord.String(),
+		Tones:      []string{},
+		Instrument: inst.ID,
+		Tuning:     tuning.ID,
+		Capo:       setup.Capo,
+	}
+	for _, p := range chord.Tones() {
+		resp.Tones = append(resp.Tones, p.String())
+	}
+	opts := voicings.Options{Limit: limit}
+
+	if inst.Kind == instruments.KindKeys {
+		list := voicings.Piano(chord, opts)
+		if list == nil {
+			list = []voicings.PianoVoicing{}
+		}
+		resp.Voicings = list
+		writeJSON(w, http.StatusOK, resp)
+		return
+	}
+
+	open := tuning.OpenStrings()
+	for i := range open {
+		open[i] += setup.Capo
+		resp.Strings = append(resp.Strings, music.NoteFromMIDI(open[i], false).String())
+	}
+	list := voicings.Fretted(chord, open, inst.Frets-setup.Capo, opts)
+	if list == nil {
+		list = []voicings.Fretting{}
+	}
+	resp.Voicings = list
+	writeJSON(w, http.StatusOK, resp)
+}
@@ -0,0 +1,122 @@
+package handlers
+
+import (
+	"bytes"
+	"errors"
+	"log"
+	"mime"
+	"net/http"
+	"strings"
+
+	"sonara-space/backend/internal/content"
+	"sonara-space/backend/internal/db"
+)
+
+const maxBundleBytes = 20 << 20
+
+// ContentImportResponse — результат импорта файла контента
+type ContentImportResponse struct {
+	DryRun bool            `json:"dry_run"`
+	Report *content.Report `json:"report,omitempty"`
+	Errors []string        `json:"errors,omitempty"`
+}
+
+// bundleIsYAML выбирает формат по ?format= или Content-Type (по умолчанию YAML)
+func bundleIsYAML(r *http.Request) bool {
+	switch r.URL.Query().Get("format") {
+	case "json":
+		return false
+	case "yaml":
+		return true
+	}
+	mediaType, _, _ := mime.ParseMediaType(r.Header.Get("Content-Type"))
+	return !strings.Contains(mediaType, "json")
+}
+
+// AdminImportContentHandler импортирует файл контента (YAML или JSON) — то же,
+// что `go run ./cmd/content import`. ?prune=true удаляет упражнения, которых
+// нет в файле; ?dry_run=true показывает отчёт без сохранения.
+func AdminImportContentHandler(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+	r.Body = http.MaxBytesReader(w, r.Body, maxBundleBytes)
+	query := r.URL.Query()
+	dryRun := query.Get("dry_run") == "true"
+
+	bundle, err := content.Decode(r.Body, bundleIsYAML(r))
+	if err != nil {
+		writeJSON(w, http.StatusBadRequest, ContentImportResponse{DryRun: dryRun, Errors: []string{err.Error()}})
+		return
+	}
+
+	tx, err := db.Pool.Begin(ctx)
+	if err != nil {
+		http.Error(w, "Database error", http.StatusInternalServerError)
+		return
+	}
+	defer tx.Rollback(ctx)
+
+	known, err := content.KnownLessons(ctx, tx)
+	if err != nil {
+		log.Printf("AdminImportContentHandler: %v", err)
+		http.Error(w, "Database error", http.StatusInternalServerError)
+		return
+	}
+	var invalid content.ValidationError
+	if err := bundle.Validate(known); errors.As(err, &invalid) {
+		writeJSON(w, http.StatusBadRequest, ContentImportResponse{DryRun: dryRun, Errors: invalid})
+		return
+	}
+
+	report, err := content.Import(ctx, tx, bundle, content.ImportOptions{Prune: query.Get("prune") == "true"})
+	if err != nil {
+		log.Printf("AdminImportContentHandler: %v", err)
+		http.Error(w, "Database error", http.StatusInternalServerError)
+		return
+	}
+	if !dryRun {
+		if err := tx.Commit(ctx); err != nil {
+			http.Error(w, "Database error", http.StatusInternalServerError)
+			return
+		}
+	}
+	writeJSON(w, http.StatusOK, ContentImportResponse{DryRun: dryRun, Report: report})
+}
+
+// AdminExportContentHandler выгружает контент в YAML (по умолчанию) или
+// ?format=json. Фильтры ?course= и ?lesson= можно повторять.
+func AdminExportContentHandler(w http.ResponseWriter, r *http.Request) {
+	query := r.URL.Query()
+	format := query.Get("format")
+	if format == "" {
+		format = "yaml"
+	}
+	if format != "yaml" && format != "json" {
+		http.Error(w, "Unsupported format, use yaml or json", http.StatusBadRequest)
+		return
+	}
+
+	bundle, err := content.Export(r.Context(), db.Pool, content.ExportFilter{
+		Courses: query["course"],
+		Lessons: query["lesson"],
+	})
+	if err != nil {
+		log.Printf("AdminExportContentHandler: %v", err)
+		http.Error(w, "Database error", http.StatusInternalServerError)
+		return
+	}
+
+	var buf bytes.Buffer
+	if err := content.Encode(&buf, bundle, format == "yaml"); err != nil {
+		log.Printf("AdminExportContentHandler: encode: %v", err)
+		http.Error(w, "Server error", http.StatusInternalServerError)
+		return
+	}
+
+	contentType := "application/json"
+	if format == "yaml" {
+		contentType = "application/yaml"
+	}
+	w.Header().Set("Content-Type", contentType)
+	w.Header().Set("Content-Disposition", `attachment; filename="content.`+format+`"`)
+	w.Write(buf.Bytes())
+}
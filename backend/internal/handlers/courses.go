@@ -0,0 +1,384 @@
+package handlers
+
+import (
+	"context"
+	"errors"
+	"log"
+	"net/http"
+
+	"sonara-space/backend/internal/auth"
+	"sonara-space/backend/internal/db"
+	"sonara-space/backend/internal/models"
+	"sonara-space/backend/internal/paths"
+
+	"github.com/jackc/pgx/v5"
+)
+
+// CourseSummary — курс в списке курсов
+type CourseSummary struct {
+	ID          int64   `json:"id"`
+	Title       string  `json:"title"`
+	Instrument  string  `json:"instrument"`
+	Description *string `json:"description,omitempty"`
+	Lessons     int     `json:"lessons"`
+	Percent     float64 `json:"percent"`
+}
+
+// CourseView — курс с модулями и состоянием уроков для текущего пользователя
+type CourseView struct {
+	ID          int64        `json:"id"`
+	Title       string       `json:"title"`
+	Instrument  string       `json:"instrument"`
+	Description *string      `json:"description,omitempty"`
+	Percent     float64      `json:"percent"`
+	Modules     []ModuleView `json:"modules"`
+}
+
+// ModuleView — модуль курса
+type ModuleView struct {
+	ID      int64              `json:"id"`
+	Title   string             `json:"title"`
+	Lessons []CourseLessonView `json:"lessons"`
+}
+
+// CourseLessonView — урок курса: открыт ли он и что нужно для открытия
+type CourseLessonView struct {
+	ID           int64             `json:"id"`
+	Title        string            `json:"title"`
+	Instrument   string            `json:"instrument"`
+	Difficulty   string            `json:"difficulty"`
+	Percent      float64           `json:"percent"`
+	Completed    bool              `json:"completed"`
+	Locked       bool              `json:"locked"`
+	Requirements []RequirementView `json:"requirements"`
+}
+
+// RequirementView — пререквизит урока с прогрессом пользователя
+type RequirementView struct {
+	paths.Requirement
+	Title string `json:"title"`
+}
+
+// NextLessonResponse — рекомендованный следующий урок курса.
+// Lesson == null, если все доступные уроки курса пройдены.
+type NextLessonResponse struct {
+	CourseID    int64             `json:"course_id"`
+	CourseTitle string            `json:"course_title"`
+	Lesson      *CourseLessonView `json:"lesson"`
+}
+
+// GetCoursesHandler возвращает опубликованные курсы с общим прогрессом пользователя
+func GetCoursesHandler(w http.ResponseWriter, r *http.Request) {
+	userID := r.Context().Value(auth.UserIDKey).(int64)
+
+	rows, err := db.Pool.Query(r.Context(), `
+		SELECT c.id, c.title, c.instrument, c.description,
+			COUNT(DISTINCT l.id),
+			COUNT(e.exercise_id),
+			COUNT(*) FILTER (WHERE p.completed)
+		FROM courses c
+		LEFT JOIN course_modules m ON m.course_id = c.id
+		LEFT JOIN module_lessons ml ON ml.module_id = m.id
+		LEFT JOIN lessons l ON l.id = ml.lesson_id AND l.status = 'published'
+		LEFT JOIN user_lesson_exercises($1) e ON e.lesson_id = l.id
+		LEFT JOIN progress p ON p.exercise_id = e.exercise_id AND p.user_id = $1
+		WHERE c.status = 'published'
+		GROUP BY c.id
+		ORDER BY c.created_at, c.id
+	`, userID)
+	if err != nil {
+		log.Printf("GetCoursesHandler: %v", err)
+		http.Error(w, "Database error", http.StatusInternalServerError)
+		return
+	}
+	defer rows.Close()
+
+	courses := []CourseSummary{}
+	for rows.Next() {
+		var c CourseSummary
+		var total, completed int
+		if err := rows.Scan(&c.ID, &c.Title, &c.Instrument, &c.Description, &c.Lessons, &total, &completed); err != nil {
+			http.Error(w, "Database scan error", http.StatusInternalServerError)
+			return
+		}
+		if total > 0 {
+			c.Percent = float64(completed) / float64(total) * 100
+		}
+		courses = append(courses, c)
+	}
+	if err := rows.Err(); err != nil {
+		http.Error(w, "Database rows error", http.StatusInternalServerError)
+		return
+	}
+
+	writeJSON(w, http.StatusOK, courses)
+}
+
+// GetCourseHandler возвращает курс с модулями; у каждого урока — прогресс,
+// признак блокировки и список пререквизитов
+func GetCourseHandler(w http.ResponseWriter, r *http.Request) {
+	userID := r.Context().Value(auth.UserIDKey).(int64)
+
+	courseID, err := urlParamID(r, "id")
+	if err != nil {
+		http.Error(w, "Invalid course ID", http.StatusBadRequest)
+		return
+	}
+
+	course, _, err := loadCourse(r.Context(), userID, courseID)
+	if errors.Is(err, pgx.ErrNoRows) {
+		http.Error(w, "Course not found", http.StatusNotFound)
+		return
+	}
+	if err != nil {
+		log.Printf("GetCourseHandler: %v", err)
+		http.Error(w, "Database error", http.StatusInternalServerError)
+		return
+	}
+
+	writeJSON(w, http.StatusOK, course)
+}
+
+// GetCourseNextLessonHandler рекомендует следующий урок курса
+func GetCourseNextLessonHandler(w http.ResponseWriter, r *http.Request) {
+	userID := r.Context().Value(auth.UserIDKey).(int64)
+
+	courseID, err := urlParamID(r, "id")
+	if err != nil {
+		http.Error(w, "Invalid course ID", http.StatusBadRequest)
+		return
+	}
+	writeNextLesson(r.Context(), w, userID, courseID)
+}
+
+// GetNextLessonHandler рекомендует следующий урок в курсе, которым
+// пользователь занимался последним (или в первом курсе, если он ещё не начинал)
+func GetNextLessonHandler(w http.ResponseWriter, r *http.Request) {
+	userID := r.Context().Value(auth.UserIDKey).(int64)
+
+	var courseID int64
+	err := db.Pool.QueryRow(r.Context(), `
+		SELECT c.id
+		FROM courses c
+		LEFT JOIN course_modules m ON m.course_id = c.id
+		LEFT JOIN module_lessons ml ON ml.module_id = m.id
+		LEFT JOIN exercises e ON e.lesson_id = ml.lesson_id
+		LEFT JOIN progress p ON p.exercise_id = e.id AND p.user_id = $1
+		WHERE c.status = 'published'
+		GROUP BY c.id
+		ORDER BY MAX(p.updated_at) DESC NULLS LAST, c.created_at, c.id
+		LIMIT 1
+	`, userID).Scan(&courseID)
+	if errors.Is(err, pgx.ErrNoRows) {
+		http.Error(w, "No courses available", http.StatusNotFound)
+		return
+	}
+	if err != nil {
+		log.Printf("GetNextLessonHandler: %v", err)
+		http.Error(w, "Database error", http.StatusInternalServerError)
+		return
+	}
+	writeNextLesson(r.Context(), w, userID, courseID)
+}
+
+func writeNextLesson(ctx context.Context, w http.ResponseWriter, userID, courseID int64) {
+	course, states, err := loadCourse(ctx, userID, courseID)
+	if errors.Is(err, pgx.ErrNoRows) {
+		http.Error(w, "Course not found", http.StatusNotFound)
+		return
+	}
+	if err != nil {
+		log.Printf("writeNextLesson: course %d: %v", courseID, err)
+		http.Error(w, "Database error", http.StatusInternalServerError)
+		return
+	}
+
+	resp := NextLessonResponse{CourseID: course.ID, CourseTitle: course.Title}
+	if next, ok := paths.Next(states); ok {
+		for _, m := range course.Modules {
+			for i := range m.Lessons {
+				if m.Lessons[i].ID == next.LessonID {
+					resp.Lesson = &m.Lessons[i]
+				}
+			}
+		}
+	}
+	writeJSON(w, http.StatusOK, resp)
+}
+
+// loadCourse собирает курс и вычисляет состояние уроков по таблице progress.
+// Возвращает pgx.ErrNoRows, если курса нет или он не опубликован.
+func loadCourse(ctx context.Context, userID, courseID int64) (*CourseView, []paths.State, error) {
+	var course models.Course
+	err := db.Pool.QueryRow(ctx, `
+		SELECT id, title, instrument, description, status, created_at
+		FROM courses WHERE id = $1 AND status = 'published'
+	`, courseID).Scan(&course.ID, &course.Title, &course.Instrument, &course.Description,
+		&course.Status, &course.CreatedAt)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	view := &CourseView{
+		ID:          course.ID,
+		Title:       course.Title,
+		Instrument:  course.Instrument,
+		Description: course.Description,
+		Modules:     []ModuleView{},
+	}
+
+	// Модули и уроки в порядке прохождения; модули без опубликованных уроков тоже показываем
+	rows, err := db.Pool.Query(ctx, `
+		SELECT m.id, m.title, l.id, l.title, l.instrument, l.difficulty
+		FROM course_modules m
+		LEFT JOIN module_lessons ml ON ml.module_id = m.id
+		LEFT JOIN lessons l ON l.id = ml.lesson_id AND l.status = 'published'
+		WHERE m.course_id = $1
+		ORDER BY m.order_index, m.id, ml.order_index, l.id
+	`, courseID)
+	if err != nil {
+		return nil, nil, err
+	}
+	defer rows.Close()
+
+	var lessonIDs []int64
+	for rows.Next() {
+		var moduleID int64
+		var moduleTitle string
+		var lessonID *int64
+		var title, instrument, difficulty *string
+		if err := rows.Scan(&moduleID, &moduleTitle, &lessonID, &title, &instrument, &difficulty); err != nil {
+			return nil, nil, err
+		}
+		if n := len(view.Modules); n == 0 || view.Modules[n-1].ID != moduleID {
+			view.Modules = append(view.Modules, ModuleView{ID: moduleID, Title: moduleTitle, Lessons: []CourseLessonView{}})
+		}
+		if lessonID == nil {
+			continue
+		}
+		m := &view.Modules[len(view.Modules)-1]
+		m.Lessons = append(m.Lessons, CourseLessonView{
+			ID:         *lessonID,
+			Title:      *title,
+			Instrument: *instrument,
+			Difficulty: *difficulty,
+		})
+		lessonIDs = append(lessonIDs, *lessonID)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, nil, err
+	}
+
+	prereqs, titles, err := loadPrerequisites(ctx, lessonIDs)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	// Прогресс нужен и по урокам курса, и по пререквизитам вне курса
+	ids := append([]int64{}, lessonIDs...)
+	for id := range titles {
+		ids = append(ids, id)
+	}
+	percents, totals, err := lessonPercents(ctx, userID, ids)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	ordered := make([]paths.Lesson, 0, len(lessonIDs))
+	for _, id := range lessonIDs {
+		ordered = append(ordered, paths.Lesson{ID: id, Percent: percents[id], Prerequisites: prereqs[id]})
+	}
+	states := paths.Evaluate(ordered, percents)
+
+	var total, completed float64
+	i := 0
+	for mi := range view.Modules {
+		for li := range view.Modules[mi].Lessons {
+			st := states[i]
+			i++
+			l := &view.Modules[mi].Lessons[li]
+			l.Percent, l.Completed, l.Locked = st.Percent, st.Completed, st.Locked
+			l.Requirements = make([]RequirementView, 0, len(st.Requirements))
+			for _, req := range st.Requirements {
+				l.Requirements = append(l.Requirements, RequirementView{Requirement: req, Title: titles[req.LessonID]})
+			}
+			total += float64(totals[l.ID])
+			completed += float64(totals[l.ID]) * st.Percent / 100
+		}
+	}
+	if total > 0 {
+		view.Percent = completed / total * 100
+	}
+	return view, states, nil
+}
+
+// loadPrerequisites возвращает пререквизиты уроков и названия требуемых уроков.
+// Неопубликованные уроки-пререквизиты не учитываются: их нельзя пройти.
+func loadPrerequisites(ctx context.Context, lessonIDs []int64) (map[int64][]paths.Prerequisite, map[int64]string, error) {
+	prereqs := make(map[int64][]paths.Prerequisite)
+	titles := make(map[int64]string)
+	if len(lessonIDs) == 0 {
+		return prereqs, titles, nil
+	}
+
+	rows, err := db.Pool.Query(ctx, `
+		SELECT lp.lesson_id, lp.required_lesson_id, lp.min_percent, l.title
+		FROM lesson_prerequisites lp
+		JOIN lessons l ON l.id = lp.required_lesson_id AND l.status = 'published'
+		WHERE lp.lesson_id = ANY($1)
+		ORDER BY lp.lesson_id, lp.required_lesson_id
+	`, lessonIDs)
+	if err != nil {
+		return nil, nil, err
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		var p models.LessonPrerequisite
+		var title string
+		if err := rows.Scan(&p.LessonID, &p.RequiredLessonID, &p.MinPercent, &title); err != nil {
+			return nil, nil, err
+		}
+		prereqs[p.LessonID] = append(prereqs[p.LessonID], paths.Prerequisite{
+			LessonID:   p.RequiredLessonID,
+			MinPercent: p.MinPercent,
+		})
+		titles[p.RequiredLessonID] = title
+	}
+	return prereqs, titles, rows.Err()
+}
+
+// lessonPercents считает по таблице progress, какая доля упражнений каждого
+// урока пройдена пользователем. Второй результат — число упражнений в уроке.
+func lessonPercents(ctx context.Context, userID int64, lessonIDs []int64) (map[int64]float64, map[int64]int, error) {
+	percents := make(map[int64]float64)
+	totals := make(map[int64]int)
+	if len(lessonIDs) == 0 {
+		return percents, totals, nil
+	}
+
+	rows, err := db.Pool.Query(ctx, `
+		SELECT e.lesson_id, COUNT(e.exercise_id), COUNT(*) FILTER (WHERE p.completed)
+		FROM user_lesson_exercises($1) e
+		LEFT JOIN progress p ON p.exercise_id = e.exercise_id AND p.user_id = $1
+		WHERE e.lesson_id = ANY($2)
+		GROUP BY e.lesson_id
+	`, userID, lessonIDs)
+	if err != nil {
+		return nil, nil, err
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		var lessonID int64
+		var total, completed int
+		if err := rows.Scan(&lessonID, &total, &completed); err != nil {
+			return nil, nil, err
+		}
+		totals[lessonID] = total
+		if total > 0 {
+			percents[lessonID] = float64(completed) / float64(total) * 100
+		}
+	}
+	return percents, totals, rows.Err()
+}
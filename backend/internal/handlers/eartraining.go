@@ -0,0 +1,366 @@
+package handlers
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log"
+	"math/rand/v2"
+	"net/http"
+	"strconv"
+	"time"
+
+	"sonara-space/backend/internal/audio"
+	"sonara-space/backend/internal/auth"
+	"sonara-space/backend/internal/db"
+	"sonara-space/backend/internal/eartraining"
+	"sonara-space/backend/internal/models"
+
+	"github.com/go-chi/chi/v5"
+	"github.com/jackc/pgx/v5"
+)
+
+const (
+	// defaultEarTrainingCount и maxEarTrainingCount — число заданий в сессии
+	defaultEarTrainingCount = 10
+	maxEarTrainingCount     = 50
+	// earTrainingSampleRate — частота дискретизации аудио заданий
+	earTrainingSampleRate = 22050
+	// maxEarTrainingSeed — seed не больше 2^53, чтобы JavaScript-клиенты не теряли точность
+	maxEarTrainingSeed = 1 << 53
+)
+
+// EarTrainingSessionRequest — запрос на новую сессию. Без seed он выбирается
+// случайно; с тем же seed задания повторяются.
+type EarTrainingSessionRequest struct {
+	Kind       string `json:"kind"`
+	Difficulty string `json:"difficulty"`
+	Count      int    `json:"count"`
+	Seed       *int64 `json:"seed"`
+}
+
+// EarTrainingSessionView — сессия с заданиями и уже данными ответами
+type EarTrainingSessionView struct {
+	ID          int64                     `json:"id"`
+	Kind        string                    `json:"kind"`
+	Difficulty  string                    `json:"difficulty"`
+	Seed        int64                     `json:"seed"`
+	Answered    int                       `json:"answered"`
+	Correct     int                       `json:"correct"`
+	Score       *float64                  `json:"score"`
+	CreatedAt   time.Time                 `json:"created_at"`
+	CompletedAt *time.Time                `json:"completed_at,omitempty"`
+	Questions   []EarTrainingQuestionView `json:"questions"`
+}
+
+// EarTrainingQuestionView — задание; Result появляется после ответа
+type EarTrainingQuestionView struct {
+	eartraining.Question
+	AudioURL string                 `json:"audio_url"`
+	Result   *EarTrainingAnswerView `json:"result,omitempty"`
+}
+
+// EarTrainingAnswerView — ответ на задание
+type EarTrainingAnswerView struct {
+	Answer   string  `json:"answer"`
+	Expected string  `json:"expected"`
+	Correct  bool    `json:"correct"`
+	Score    float64 `json:"score"`
+}
+
+// EarTrainingAnswerRequest — ответ ученика
+type EarTrainingAnswerRequest struct {
+	Answer string `json:"answer"`
+}
+
+// EarTrainingAnswerResponse — проверка ответа и состояние сессии
+type EarTrainingAnswerResponse struct {
+	EarTrainingAnswerView
+	Session EarTrainingSessionView `json:"session"`
+}
+
+var errSessionOutdated = errors.New("session was created by an older question generator")
+
+// CreateEarTrainingSessionHandler создаёт сессию заданий на слух:
+// {"kind": "interval", "difficulty": "beginner", "count": 10, "seed": 42}
+func CreateEarTrainingSessionHandler(w http.ResponseWriter, r *http.Request) {
+	userID := r.Context().Value(auth.UserIDKey).(int64)
+	ctx := r.Context()
+
+	var req EarTrainingSessionRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		http.Error(w, "Invalid JSON", http.StatusBadRequest)
+		return
+	}
+	if req.Difficulty == "" {
+		req.Difficulty = models.DifficultyBeginner
+	}
+	if req.Count == 0 {
+		req.Count = defaultEarTrainingCount
+	}
+	if req.Count < 0 || req.Count > maxEarTrainingCount {
+		http.Error(w, fmt.Sprintf("count must be between 1 and %d", maxEarTrainingCount), http.StatusBadRequest)
+		return
+	}
+	seed := rand.Int64N(maxEarTrainingSeed)
+	if req.Seed != nil {
+		if *req.Seed < 0 || *req.Seed >= maxEarTrainingSeed {
+			http.Error(w, "seed must be between 0 and 2^53", http.StatusBadRequest)
+			return
+		}
+		seed = *req.Seed
+	}
+	// Проверяем вид и сложность до записи в базу
+	if _, err := eartraining.Generate(req.Kind, req.Difficulty, seed, 0); err != nil {
+		msg := "invalid difficulty"
+		if errors.Is(err, eartraining.ErrKind) {
+			msg = "invalid kind"
+		}
+		http.Error(w, msg, http.StatusBadRequest)
+		return
+	}
+
+	s := models.EarTrainingSession{UserID: userID, Kind: req.Kind, Difficulty: req.Difficulty,
+		Seed: seed, GeneratorVersion: eartraining.Version, QuestionCount: req.Count}
+	err := db.Pool.QueryRow(ctx, `
+		INSERT INTO ear_training_sessions (user_id, kind, difficulty, seed, generator_version, question_count)
+		VALUES ($1, $2, $3, $4, $5, $6)
+		RETURNING id, created_at
+	`, s.UserID, s.Kind, s.Difficulty, s.Seed, s.GeneratorVersion, s.QuestionCount).Scan(&s.ID, &s.CreatedAt)
+	if err != nil {
+		log.Printf("CreateEarTrainingSessionHandler: %v", err)
+		http.Error(w, "Database error", http.StatusInternalServerError)
+		return
+	}
+
+	view, err := earTrainingView(ctx, s)
+	if err != nil {
+		log.Printf("CreateEarTrainingSessionHandler: %v", err)
+		http.Error(w, "Server error", http.StatusInternalServerError)
+		return
+	}
+	writeJSON(w, http.StatusCreated, view)
+}
+
+// GetEarTrainingSessionHandler возвращает сессию текущего пользователя
+func GetEarTrainingSessionHandler(w http.ResponseWriter, r *http.Request) {
+	s, ok := earTrainingSessionParam(w, r, "GetEarTrainingSessionHandler")
+	if !ok {
+		return
+	}
+	view, err := earTrainingView(r.Context(), s)
+	if errors.Is(err, errSessionOutdated) {
+		http.Error(w, err.Error(), http.StatusGone)
+		return
+	}
+	if err != nil {
+		log.Printf("GetEarTrainingSessionHandler: %v", err)
+		http.Error(w, "Database error", http.StatusInternalServerError)
+		return
+	}
+	writeJSON(w, http.StatusOK, view)
+}
+
+// GetEarTrainingAudioHandler отдаёт звук задания в WAV
+func GetEarTrainingAudioHandler(w http.ResponseWriter, r *http.Request) {
+	s, ok := earTrainingSessionParam(w, r, "GetEarTrainingAudioHandler")
+	if !ok {
+		return
+	}
+	q, ok := earTrainingQuestionParam(w, r, s)
+	if !ok {
+		return
+	}
+
+	var buf bytes.Buffer
+	if err := audio.EncodeWAV(&buf, eartraining.Render(q, earTrainingSampleRate)); err != nil {
+		log.Printf("GetEarTrainingAudioHandler: %v", err)
+		http.Error(w, "Server error", http.StatusInternalServerError)
+		return
+	}
+	w.Header().Set("Content-Type", "audio/wav")
+	w.Header().Set("Content-Length", strconv.Itoa(buf.Len()))
+	// Звук задания не меняется: его можно кэшировать
+	w.Header().Set("Cache-Control", "private, max-age=86400")
+	w.Write(buf.Bytes())
+}
+
+// AnswerEarTrainingHandler проверяет ответ на задание. На каждое задание
+// отвечают один раз; после последнего ответа сессия завершается со средним баллом.
+func AnswerEarTrainingHandler(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+	s, ok := earTrainingSessionParam(w, r, "AnswerEarTrainingHandler")
+	if !ok {
+		return
+	}
+	q, ok := earTrainingQuestionParam(w, r, s)
+	if !ok {
+		return
+	}
+	var req EarTrainingAnswerRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		http.Error(w, "Invalid JSON", http.StatusBadRequest)
+		return
+	}
+	verdict := eartraining.Check(q, req.Answer)
+
+	tx, err := db.Pool.Begin(ctx)
+	if err != nil {
+		log.Printf("AnswerEarTrainingHandler: %v", err)
+		http.Error(w, "Database error", http.StatusInternalServerError)
+		return
+	}
+	defer tx.Rollback(ctx)
+
+	// Блокировка сессии: параллельные последние ответы должны увидеть друг друга
+	if _, err := tx.Exec(ctx, `SELECT 1 FROM ear_training_sessions WHERE id = $1 FOR UPDATE`, s.ID); err != nil {
+		log.Printf("AnswerEarTrainingHandler: lock: %v", err)
+		http.Error(w, "Database error", http.StatusInternalServerError)
+		return
+	}
+	tag, err := tx.Exec(ctx, `
+		INSERT INTO ear_training_answers (session_id, question_index, answer, expected, correct, score)
+		VALUES ($1, $2, $3, $4, $5, $6)
+		ON CONFLICT (session_id, question_index) DO NOTHING
+	`, s.ID, q.Index, req.Answer, verdict.Expected, verdict.Correct, verdict.Score)
+	if err != nil {
+		log.Printf("AnswerEarTrainingHandler: %v", err)
+		http.Error(w, "Database error", http.StatusInternalServerError)
+		return
+	}
+	if tag.RowsAffected() == 0 {
+		http.Error(w, "Question already answered", http.StatusConflict)
+		return
+	}
+
+	err = tx.QueryRow(ctx, `
+		UPDATE ear_training_sessions s SET score = a.score, completed_at = NOW()
+		FROM (SELECT COUNT(*) AS answered, AVG(score) AS score
+		      FROM ear_training_answers WHERE session_id = $1) a
+		WHERE s.id = $1 AND a.answered = s.question_count
+		RETURNING s.score, s.completed_at
+	`, s.ID).Scan(&s.Score, &s.CompletedAt)
+	if err != nil && !errors.Is(err, pgx.ErrNoRows) {
+		log.Printf("AnswerEarTrainingHandler: complete: %v", err)
+		http.Error(w, "Database error", http.StatusInternalServerError)
+		return
+	}
+	if err := tx.Commit(ctx); err != nil {
+		log.Printf("AnswerEarTrainingHandler: commit: %v", err)
+		http.Error(w, "Database error", http.StatusInternalServerError)
+		return
+	}
+
+	view, err := earTrainingView(ctx, s)
+	if err != nil {
+		log.Printf("AnswerEarTrainingHandler: %v", err)
+		http.Error(w, "Database error", http.StatusInternalServerError)
+		return
+	}
+	writeJSON(w, http.StatusOK, EarTrainingAnswerResponse{
+		EarTrainingAnswerView: EarTrainingAnswerView{
+			Answer: req.Answer, Expected: verdict.Expected, Correct: verdict.Correct, Score: verdict.Score,
+		},
+		Session: view,
+	})
+}
+
+// earTrainingSessionParam загружает сессию {id} текущего пользователя;
+// чужая сессия не видна (404)
+func earTrainingSessionParam(w http.ResponseWriter, r *http.Request, name string) (models.EarTrainingSession, bool) {
+	userID := r.Context().Value(auth.UserIDKey).(int64)
+	id, err := urlParamID(r, "id")
+	if err != nil {
+		http.Error(w, "Invalid session ID", http.StatusBadRequest)
+		return models.EarTrainingSession{}, false
+	}
+
+	var s models.EarTrainingSession
+	err = db.Pool.QueryRow(r.Context(), `
+		SELECT id, user_id, kind, difficulty, seed, generator_version, question_count, score, created_at, completed_at
+		FROM ear_training_sessions WHERE id = $1 AND user_id = $2
+	`, id, userID).Scan(&s.ID, &s.UserID, &s.Kind, &s.Difficulty, &s.Seed, &s.GeneratorVersion,
+		&s.QuestionCount, &s.Score, &s.CreatedAt, &s.CompletedAt)
+	if errors.Is(err, pgx.ErrNoRows) {
+		http.Error(w, "Session not found", http.StatusNotFound)
+		return s, false
+	}
+	if err != nil {
+		log.Printf("%s: %v", name, err)
+		http.Error(w, "Database error", http.StatusInternalServerError)
+		return s, false
+	}
+	return s, true
+}
+
+// earTrainingQuestionParam восстанавливает задание {index} сессии
+func earTrainingQuestionParam(w http.ResponseWriter, r *http.Request, s models.EarTrainingSession) (eartraining.Question, bool) {
+	index, err := strconv.Atoi(chi.URLParam(r, "index"))
+	if err != nil || index < 0 || index >= s.QuestionCount {
+		http.Error(w, "Question not found", http.StatusNotFound)
+		return eartraining.Question{}, false
+	}
+	if s.GeneratorVersion != eartraining.Version {
+		http.Error(w, errSessionOutdated.Error(), http.StatusGone)
+		return eartraining.Question{}, false
+	}
+	q, err := eartraining.Generate(s.Kind, s.Difficulty, s.Seed, index)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusUnprocessableEntity)
+		return q, false
+	}
+	return q, true
+}
+
+// earTrainingView собирает сессию: задания восстанавливаются генератором,
+// ответы — из базы
+func earTrainingView(ctx context.Context, s models.EarTrainingSession) (EarTrainingSessionView, error) {
+	view := EarTrainingSessionView{ID: s.ID, Kind: s.Kind, Difficulty: s.Difficulty, Seed: s.Seed,
+		Score: s.Score, CreatedAt: s.CreatedAt, CompletedAt: s.CompletedAt}
+	if s.GeneratorVersion != eartraining.Version {
+		return view, errSessionOutdated
+	}
+	questions, err := eartraining.Session(s.Kind, s.Difficulty, s.Seed, s.QuestionCount)
+	if err != nil {
+		return view, err
+	}
+
+	answers := map[int]*EarTrainingAnswerView{}
+	rows, err := db.Pool.Query(ctx, `
+		SELECT question_index, answer, expected, correct, score
+		FROM ear_training_answers WHERE session_id = $1
+	`, s.ID)
+	if err != nil {
+		return view, err
+	}
+	defer rows.Close()
+	for rows.Next() {
+		var index int
+		var a EarTrainingAnswerView
+		if err := rows.Scan(&index, &a.Answer, &a.Expected, &a.Correct, &a.Score); err != nil {
+			return view, err
+		}
+		answers[index] = &a
+	}
+	if err := rows.Err(); err != nil {
+		return view, err
+	}
+
+	view.Questions = make([]EarTrainingQuestionView, len(questions))
+	for i, q := range questions {
+		view.Questions[i] = EarTrainingQuestionView{
+			Question: q,
+			AudioURL: fmt.Sprintf("/ear-training/sessions/%d/questions/%d/audio", s.ID, i),
+			Result:   answers[i],
+		}
+		if a := answers[i]; a != nil {
+			view.Answered++
+			if a.Correct {
+				view.Correct++
+			}
+		}
+	}
+	return view, nil
+}
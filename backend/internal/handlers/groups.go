@@ -0,0 +1,325 @@
+package handlers
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/base32"
+	"encoding/json"
+	"errors"
+	"log"
+	"net/http"
+	"strings"
+	"time"
+
+	"sonara-space/backend/internal/auth"
+	"sonara-space/backend/internal/db"
+	"sonara-space/backend/internal/models"
+
+	"github.com/jackc/pgx/v5"
+)
+
+// Сколько участников может быть в группе (вместе с владельцем)
+const (
+	maxFriendsGroupMembers = 50
+	maxFamilyGroupMembers  = 6
+)
+
+// GroupView — группа учеников в ответе API
+type GroupView struct {
+	ID         int64     `json:"id"`
+	Kind       string    `json:"kind"`
+	Name       string    `json:"name"`
+	OwnerID    int64     `json:"owner_id"`
+	InviteCode string    `json:"invite_code"`
+	Members    int       `json:"members"`
+	CreatedAt  time.Time `json:"created_at"`
+}
+
+// GroupInput — тело запроса на создание группы
+type GroupInput struct {
+	Kind string `json:"kind"`
+	Name string `json:"name"`
+}
+
+// JoinGroupInput — тело запроса на вступление в группу
+type JoinGroupInput struct {
+	InviteCode string `json:"invite_code"`
+}
+
+const groupColumns = `g.id, g.kind, g.name, g.owner_id, g.invite_code,
+	(SELECT COUNT(*) FROM user_group_members m WHERE m.group_id = g.id), g.created_at`
+
+func scanGroup(row pgx.Row, g *GroupView) error {
+	return row.Scan(&g.ID, &g.Kind, &g.Name, &g.OwnerID, &g.InviteCode, &g.Members, &g.CreatedAt)
+}
+
+// newInviteCode возвращает случайный код приглашения из 8 символов
+func newInviteCode() (string, error) {
+	b := make([]byte, 5)
+	if _, err := rand.Read(b); err != nil {
+		return "", err
+	}
+	return base32.StdEncoding.EncodeToString(b), nil
+}
+
+// activeFamilySubscription возвращает действующую семейную подписку ученика
+func activeFamilySubscription(ctx context.Context, q pgx.Tx, userID int64) (int64, error) {
+	var id int64
+	err := q.QueryRow(ctx, `
+		SELECT id FROM subscriptions
+		WHERE user_id = $1 AND plan = 'family' AND status IN ('active', 'trialing')
+	`, userID).Scan(&id)
+	return id, err
+}
+
+// groupMembers возвращает участников группы, если userID в ней состоит
+func groupMembers(ctx context.Context, groupID, userID int64) ([]int64, error) {
+	rows, err := db.Pool.Query(ctx, `
+		SELECT m.user_id FROM user_group_members m
+		WHERE m.group_id = $1
+		  AND EXISTS (SELECT 1 FROM user_group_members me WHERE me.group_id = $1 AND me.user_id = $2)
+		ORDER BY m.user_id
+	`, groupID, userID)
+	if err != nil {
+		return nil, err
+	}
+	members, err := pgx.CollectRows(rows, pgx.RowTo[int64])
+	if err != nil {
+		return nil, err
+	}
+	if len(members) == 0 {
+		return nil, pgx.ErrNoRows
+	}
+	return members, nil
+}
+
+// GetMyGroupsHandler возвращает группы, в которых состоит пользователь
+func GetMyGroupsHandler(w http.ResponseWriter, r *http.Request) {
+	userID := r.Context().Value(auth.UserIDKey).(int64)
+
+	rows, err := db.Pool.Query(r.Context(), `
+		SELECT `+groupColumns+` FROM user_groups g
+		JOIN user_group_members m ON m.group_id = g.id AND m.user_id = $1
+		ORDER BY g.created_at, g.id
+	`, userID)
+	if err != nil {
+		log.Printf("GetMyGroupsHandler: %v", err)
+		http.Error(w, "Database error", http.StatusInternalServerError)
+		return
+	}
+	defer rows.Close()
+
+	list := []GroupView{}
+	for rows.Next() {
+		var g GroupView
+		if err := scanGroup(rows, &g); err != nil {
+			log.Printf("GetMyGroupsHandler: %v", err)
+			http.Error(w, "Database error", http.StatusInternalServerError)
+			return
+		}
+		list = append(list, g)
+	}
+	if err := rows.Err(); err != nil {
+		log.Printf("GetMyGroupsHandler: %v", err)
+		http.Error(w, "Database error", http.StatusInternalServerError)
+		return
+	}
+	writeJSON(w, http.StatusOK, list)
+}
+
+// CreateGroupHandler создаёт группу друзей или семейную группу; семейную —
+// только владелец действующей семейной подписки, одну на подписку
+func CreateGroupHandler(w http.ResponseWriter, r *http.Request) {
+	userID := r.Context().Value(auth.UserIDKey).(int64)
+	ctx := r.Context()
+
+	var in GroupInput
+	if err := json.NewDecoder(r.Body).Decode(&in); err != nil {
+		http.Error(w, "Invalid JSON", http.StatusBadRequest)
+		return
+	}
+	in.Name = strings.TrimSpace(in.Name)
+	if in.Kind == "" {
+		in.Kind = models.GroupFriends
+	}
+	if in.Kind != models.GroupFriends && in.Kind != models.GroupFamily {
+		http.Error(w, "invalid kind", http.StatusBadRequest)
+		return
+	}
+	if in.Name == "" || len([]rune(in.Name)) > 100 {
+		http.Error(w, "name is required (up to 100 characters)", http.StatusBadRequest)
+		return
+	}
+
+	tx, err := db.Pool.Begin(ctx)
+	if err != nil {
+		log.Printf("CreateGroupHandler: %v", err)
+		http.Error(w, "Database error", http.StatusInternalServerError)
+		return
+	}
+	defer tx.Rollback(ctx)
+
+	var subscriptionID *int64
+	if in.Kind == models.GroupFamily {
+		id, err := activeFamilySubscription(ctx, tx, userID)
+		if errors.Is(err, pgx.ErrNoRows) {
+			http.Error(w, "family subscription required", http.StatusForbidden)
+			return
+		}
+		if err != nil {
+			log.Printf("CreateGroupHandler: %v", err)
+			http.Error(w, "Database error", http.StatusInternalServerError)
+			return
+		}
+		var exists bool
+		if err := tx.QueryRow(ctx, `SELECT EXISTS(SELECT 1 FROM user_groups WHERE subscription_id = $1)`,
+			id).Scan(&exists); err != nil {
+			log.Printf("CreateGroupHandler: %v", err)
+			http.Error(w, "Database error", http.StatusInternalServerError)
+			return
+		}
+		if exists {
+			http.Error(w, "family group already exists", http.StatusConflict)
+			return
+		}
+		subscriptionID = &id
+	}
+
+	code, err := newInviteCode()
+	if err != nil {
+		log.Printf("CreateGroupHandler: %v", err)
+		http.Error(w, "Internal error", http.StatusInternalServerError)
+		return
+	}
+	var groupID int64
+	err = tx.QueryRow(ctx, `
+		INSERT INTO user_groups (kind, name, owner_id, subscription_id, invite_code)
+		VALUES ($1, $2, $3, $4, $5) RETURNING id
+	`, in.Kind, in.Name, userID, subscriptionID, code).Scan(&groupID)
+	if err == nil {
+		_, err = tx.Exec(ctx, `INSERT INTO user_group_members (group_id, user_id) VALUES ($1, $2)`, groupID, userID)
+	}
+	var g GroupView
+	if err == nil {
+		err = scanGroup(tx.QueryRow(ctx, `SELECT `+groupColumns+` FROM user_groups g WHERE g.id = $1`, groupID), &g)
+	}
+	if err == nil {
+		err = tx.Commit(ctx)
+	}
+	if err != nil {
+		log.Printf("CreateGroupHandler: %v", err)
+		http.Error(w, "Database error", http.StatusInternalServerError)
+		return
+	}
+	writeJSON(w, http.StatusCreated, g)
+}
+
+// JoinGroupHandler добавляет пользователя в группу по коду приглашения.
+// В семейную группу можно вступить, пока у владельца действует семейная подписка.
+func JoinGroupHandler(w http.ResponseWriter, r *http.Request) {
+	userID := r.Context().Value(auth.UserIDKey).(int64)
+	ctx := r.Context()
+
+	var in JoinGroupInput
+	if err := json.NewDecoder(r.Body).Decode(&in); err != nil {
+		http.Error(w, "Invalid JSON", http.StatusBadRequest)
+		return
+	}
+	code := strings.ToUpper(strings.TrimSpace(in.InviteCode))
+
+	tx, err := db.Pool.Begin(ctx)
+	if err != nil {
+		log.Printf("JoinGroupHandler: %v", err)
+		http.Error(w, "Database error", http.StatusInternalServerError)
+		return
+	}
+	defer tx.Rollback(ctx)
+
+	var g models.Group
+	err = tx.QueryRow(ctx, `
+		SELECT id, kind, owner_id FROM user_groups WHERE invite_code = $1 FOR UPDATE
+	`, code).Scan(&g.ID, &g.Kind, &g.OwnerID)
+	if errors.Is(err, pgx.ErrNoRows) {
+		http.Error(w, "Group not found", http.StatusNotFound)
+		return
+	}
+	if err != nil {
+		log.Printf("JoinGroupHandler: %v", err)
+		http.Error(w, "Database error", http.StatusInternalServerError)
+		return
+	}
+
+	var members int
+	var member bool
+	err = tx.QueryRow(ctx, `
+		SELECT COUNT(*), COUNT(*) FILTER (WHERE user_id = $2) > 0
+		FROM user_group_members WHERE group_id = $1
+	`, g.ID, userID).Scan(&members, &member)
+	if err != nil {
+		log.Printf("JoinGroupHandler: %v", err)
+		http.Error(w, "Database error", http.StatusInternalServerError)
+		return
+	}
+	if !member {
+		limit := maxFriendsGroupMembers
+		if g.Kind == models.GroupFamily {
+			limit = maxFamilyGroupMembers
+			if _, err := activeFamilySubscription(ctx, tx, g.OwnerID); errors.Is(err, pgx.ErrNoRows) {
+				http.Error(w, "family subscription is not active", http.StatusConflict)
+				return
+			} else if err != nil {
+				log.Printf("JoinGroupHandler: %v", err)
+				http.Error(w, "Database error", http.StatusInternalServerError)
+				return
+			}
+		}
+		if members >= limit {
+			http.Error(w, "group is full", http.StatusConflict)
+			return
+		}
+		if _, err := tx.Exec(ctx, `INSERT INTO user_group_members (group_id, user_id) VALUES ($1, $2)`, g.ID, userID); err != nil {
+			log.Printf("JoinGroupHandler: %v", err)
+			http.Error(w, "Database error", http.StatusInternalServerError)
+			return
+		}
+	}
+
+	var view GroupView
+	err = scanGroup(tx.QueryRow(ctx, `SELECT `+groupColumns+` FROM user_groups g WHERE g.id = $1`, g.ID), &view)
+	if err == nil {
+		err = tx.Commit(ctx)
+	}
+	if err != nil {
+		log.Printf("JoinGroupHandler: %v", err)
+		http.Error(w, "Database error", http.StatusInternalServerError)
+		return
+	}
+	writeJSON(w, http.StatusOK, view)
+}
+
+// LeaveGroupHandler выводит пользователя из группы; владелец, выходя, удаляет группу
+func LeaveGroupHandler(w http.ResponseWriter, r *http.Request) {
+	userID := r.Context().Value(auth.UserIDKey).(int64)
+	ctx := r.Context()
+
+	groupID, err := urlParamID(r, "id")
+	if err != nil {
+		http.Error(w, "Invalid group ID", http.StatusBadRequest)
+		return
+	}
+
+	tag, err := db.Pool.Exec(ctx, `DELETE FROM user_groups WHERE id = $1 AND owner_id = $2`, groupID, userID)
+	if err == nil && tag.RowsAffected() == 0 {
+		tag, err = db.Pool.Exec(ctx, `DELETE FROM user_group_members WHERE group_id = $1 AND user_id = $2`, groupID, userID)
+	}
+	if err != nil {
+		log.Printf("LeaveGroupHandler: %v", err)
+		http.Error(w, "Database error", http.StatusInternalServerError)
+		return
+	}
+	if tag.RowsAffected() == 0 {
+		http.Error(w, "Group not found", http.StatusNotFound)
+		return
+	}
+	w.WriteHeader(http.StatusNoContent)
+}
@@ -0,0 +1,155 @@
+package handlers
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+
+	"sonara-space/backend/internal/audio"
+	"sonara-space/backend/internal/content"
+	"sonara-space/backend/internal/db"
+	"sonara-space/backend/internal/i18n"
+	"sonara-space/backend/internal/instruments"
+	"sonara-space/backend/internal/models"
+	"sonara-space/backend/internal/music"
+
+	"github.com/jackc/pgx/v5"
+)
+
+// RangeView — диапазон нотами и MIDI-номерами
+type RangeView struct {
+	Low      string `json:"low"`
+	High     string `json:"high"`
+	LowMIDI  int    `json:"low_midi"`
+	HighMIDI int    `json:"high_midi"`
+}
+
+// TuningView — строй инструмента и его диапазон без каподастра
+type TuningView struct {
+	ID      string    `json:"id"`
+	Name    string    `json:"name"`
+	Strings []string  `json:"strings"`
+	Default bool      `json:"default"`
+	Range   RangeView `json:"range"`
+}
+
+// InstrumentView — инструмент справочника
+type InstrumentView struct {
+	ID      string       `json:"id"`
+	Name    string       `json:"name"`
+	Kind    string       `json:"kind"`
+	Frets   int          `json:"frets,omitempty"`
+	Capo    bool         `json:"capo"`
+	MaxCapo int          `json:"max_capo,omitempty"`
+	Tunings []TuningView `json:"tunings"`
+	Range   RangeView    `json:"range"` // в строе по умолчанию
+}
+
+func newRangeView(r instruments.Range) RangeView {
+	return RangeView{
+		Low:      music.NoteFromMIDI(r.Low, false).String(),
+		High:     music.NoteFromMIDI(r.High, false).String(),
+		LowMIDI:  r.Low,
+		HighMIDI: r.High,
+	}
+}
+
+// GetInstrumentsHandler возвращает справочник инструментов: канонические ID,
+// названия на языке запроса, строи, диапазоны и каподастр
+func GetInstrumentsHandler(w http.ResponseWriter, r *http.Request) {
+	locale := requestLocale(r)
+	chain := i18n.Chain(locale)
+
+	list := []InstrumentView{}
+	for _, inst := range instruments.All() {
+		view := InstrumentView{
+			ID:      inst.ID,
+			Name:    inst.Name(chain),
+			Kind:    inst.Kind,
+			Frets:   inst.Frets,
+			Capo:    inst.MaxCapo > 0,
+			MaxCapo: inst.MaxCapo,
+			Tunings: []TuningView{},
+		}
+		def, _ := inst.Tuning("")
+		view.Range = newRangeView(inst.Range(def, 0))
+		for i, t := range inst.Tunings {
+			view.Tunings = append(view.Tunings, TuningView{
+				ID:      t.ID,
+				Name:    t.Name(chain),
+				Strings: t.Strings,
+				Default: i == 0,
+				Range:   newRangeView(inst.Range(t, 0)),
+			})
+		}
+		list = append(list, view)
+	}
+
+	w.Header().Set("Content-Language", locale)
+	writeJSON(w, http.StatusOK, list)
+}
+
+// loadInstrumentSetup читает инструмент, строй и каподастр урока
+func loadInstrumentSetup(ctx context.Context, q content.DB, lessonID int64) (instruments.Setup, error) {
+	var s instruments.Setup
+	var tuning *string
+	err := q.QueryRow(ctx, `SELECT instrument, tuning, capo FROM lessons WHERE id = $1`, lessonID).
+		Scan(&s.Instrument, &tuning, &s.Capo)
+	if tuning != nil {
+		s.Tuning = *tuning
+	}
+	return s, err
+}
+
+// instrumentBand — полоса частот для анализа записи. Если настройка урока
+// не распознана, анализ идёт без ограничений.
+func instrumentBand(s instruments.Setup) audio.Band {
+	r, err := s.Range()
+	if err != nil {
+		return audio.Band{}
+	}
+	low, high := r.Frequencies(music.StandardA4)
+	return audio.Band{Low: low, High: high}
+}
+
+// draftNotes возвращает ноты упражнений черновика урока: ожидаемые ноты
+// упражнений типа note и шаги мелодий
+func draftNotes(ctx context.Context, lessonID int64) ([]string, error) {
+	rows, err := db.Pool.Query(ctx, `
+		SELECT e.expected FROM exercises e
+		WHERE e.lesson_id = $1 AND e.retired_at IS NULL AND e.type = 'note'
+		UNION ALL
+		SELECT step->>'symbol' FROM exercises e
+		JOIN exercise_sequences s ON s.exercise_id = e.id
+		CROSS JOIN LATERAL jsonb_array_elements(s.steps) step
+		WHERE e.lesson_id = $1 AND e.retired_at IS NULL AND s.step_type = 'note'
+	`, lessonID)
+	if err != nil {
+		return nil, err
+	}
+	return pgx.CollectRows(rows, pgx.RowTo[string])
+}
+
+// checkExerciseRange проверяет, что ноты упражнения можно сыграть на
+// инструменте урока в его строе
+func checkExerciseRange(s instruments.Setup, in *ExerciseInput) error {
+	var notes []string
+	switch {
+	case in.Type == models.ExerciseTypeNote:
+		notes = []string{in.Expected}
+	case in.Sequence != nil && in.Sequence.StepType == models.ExerciseTypeNote:
+		for _, step := range in.Sequence.Steps {
+			notes = append(notes, step.Symbol)
+		}
+	default:
+		return nil
+	}
+	r, err := s.Range()
+	if err != nil {
+		return err
+	}
+	if bad := r.Outside(notes); len(bad) > 0 {
+		return fmt.Errorf("%s is outside the %s range %s", notes[bad[0]], s, r)
+	}
+	return nil
+}
@@ -0,0 +1,192 @@
+package handlers
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"log"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"sonara-space/backend/internal/auth"
+	"sonara-space/backend/internal/db"
+	"sonara-space/backend/internal/instruments"
+	"sonara-space/backend/internal/leaderboard"
+
+	"github.com/jackc/pgx/v5"
+)
+
+// LeaderboardEntryView — место в рейтинге
+type LeaderboardEntryView struct {
+	leaderboard.Entry
+	Name string `json:"name"`
+	Me   bool   `json:"me"`
+}
+
+// LeaderboardResponse — рейтинг с местом текущего пользователя
+type LeaderboardResponse struct {
+	Metric     string                 `json:"metric"`
+	Period     string                 `json:"period"`
+	Instrument *string                `json:"instrument"`
+	GroupID    *int64                 `json:"group_id"`
+	WeekStart  *time.Time             `json:"week_start,omitempty"`
+	ResetsAt   *time.Time             `json:"resets_at,omitempty"`
+	Entries    []LeaderboardEntryView `json:"entries"`
+	// Me — место пользователя; nil, если он скрыт из рейтингов
+	Me       *leaderboard.Entry `json:"me"`
+	OptedOut bool               `json:"opted_out"`
+}
+
+// LeaderboardSettingsInput — участие в рейтингах
+type LeaderboardSettingsInput struct {
+	OptOut bool `json:"opt_out"`
+}
+
+// syncLeaderboard обновляет очки ученика в рейтингах. Ошибка не мешает
+// ответу: при следующем запросе рейтинги перестроятся.
+func syncLeaderboard(ctx context.Context, userID int64) {
+	if leaderboard.Store == nil {
+		return
+	}
+	if err := leaderboard.Store.Sync(ctx, userID); err != nil {
+		log.Printf("leaderboard sync user %d: %v", userID, err)
+	}
+}
+
+// displayNames возвращает имена учеников для рейтинга: "Анна К."
+func displayNames(ctx context.Context, ids []int64) (map[int64]string, error) {
+	rows, err := db.Pool.Query(ctx, `
+		SELECT id, COALESCE(first_name, ''), COALESCE(last_name, '') FROM users WHERE id = ANY($1)
+	`, ids)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	names := make(map[int64]string, len(ids))
+	for rows.Next() {
+		var id int64
+		var first, last string
+		if err := rows.Scan(&id, &first, &last); err != nil {
+			return nil, err
+		}
+		name := strings.TrimSpace(first)
+		if r := []rune(strings.TrimSpace(last)); len(r) > 0 {
+			name = strings.TrimSpace(name + " " + string(r[0]) + ".")
+		}
+		names[id] = name
+	}
+	return names, rows.Err()
+}
+
+// GetLeaderboardHandler возвращает рейтинг:
+// ?metric=xp|exercises&period=week|all&instrument=guitar&group=ID&limit=20
+func GetLeaderboardHandler(w http.ResponseWriter, r *http.Request) {
+	userID := r.Context().Value(auth.UserIDKey).(int64)
+	ctx := r.Context()
+	params := r.URL.Query()
+
+	q := leaderboard.Query{Metric: params.Get("metric"), Period: params.Get("period")}
+	if q.Metric == "" {
+		q.Metric = leaderboard.MetricXP
+	}
+	if q.Period == "" {
+		q.Period = leaderboard.PeriodWeek
+	}
+	if err := q.Validate(); err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	limit, err := queryLimit(r, 20, 100)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	q.Limit = limit
+	resp := LeaderboardResponse{Metric: q.Metric, Period: q.Period, Entries: []LeaderboardEntryView{}}
+
+	if v := strings.ToLower(strings.TrimSpace(params.Get("instrument"))); v != "" {
+		if !instruments.Valid(v) {
+			http.Error(w, "invalid instrument", http.StatusBadRequest)
+			return
+		}
+		q.Instrument = v
+		resp.Instrument = &v
+	}
+	if v := params.Get("group"); v != "" {
+		groupID, err := strconv.ParseInt(v, 10, 64)
+		if err != nil {
+			http.Error(w, "invalid group", http.StatusBadRequest)
+			return
+		}
+		q.Users, err = groupMembers(ctx, groupID, userID)
+		if errors.Is(err, pgx.ErrNoRows) {
+			http.Error(w, "Group not found", http.StatusNotFound)
+			return
+		}
+		if err != nil {
+			log.Printf("GetLeaderboardHandler: %v", err)
+			http.Error(w, "Database error", http.StatusInternalServerError)
+			return
+		}
+		resp.GroupID = &groupID
+	}
+	if q.Period == leaderboard.PeriodWeek {
+		start := leaderboard.WeekStart(time.Now(), leaderboard.Store.Location())
+		resets := start.AddDate(0, 0, 7)
+		resp.WeekStart, resp.ResetsAt = &start, &resets
+	}
+
+	if err := db.Pool.QueryRow(ctx, `SELECT leaderboard_opt_out FROM users WHERE id = $1`, userID).Scan(&resp.OptedOut); err != nil {
+		log.Printf("GetLeaderboardHandler: %v", err)
+		http.Error(w, "Database error", http.StatusInternalServerError)
+		return
+	}
+
+	entries, err := leaderboard.Store.Top(ctx, q)
+	if err == nil && !resp.OptedOut {
+		var me leaderboard.Entry
+		if me, err = leaderboard.Store.Position(ctx, q, userID); err == nil {
+			resp.Me = &me
+		}
+	}
+	if err != nil {
+		log.Printf("GetLeaderboardHandler: %v", err)
+		http.Error(w, "Database error", http.StatusInternalServerError)
+		return
+	}
+
+	ids := make([]int64, len(entries))
+	for i, e := range entries {
+		ids[i] = e.UserID
+	}
+	names, err := displayNames(ctx, ids)
+	if err != nil {
+		log.Printf("GetLeaderboardHandler: %v", err)
+		http.Error(w, "Database error", http.StatusInternalServerError)
+		return
+	}
+	for _, e := range entries {
+		resp.Entries = append(resp.Entries, LeaderboardEntryView{Entry: e, Name: names[e.UserID], Me: e.UserID == userID})
+	}
+	writeJSON(w, http.StatusOK, resp)
+}
+
+// UpdateLeaderboardSettingsHandler включает или выключает участие в рейтингах
+func UpdateLeaderboardSettingsHandler(w http.ResponseWriter, r *http.Request) {
+	userID := r.Context().Value(auth.UserIDKey).(int64)
+
+	var in LeaderboardSettingsInput
+	if err := json.NewDecoder(r.Body).Decode(&in); err != nil {
+		http.Error(w, "Invalid JSON", http.StatusBadRequest)
+		return
+	}
+	if _, err := db.Pool.Exec(r.Context(), `UPDATE users SET leaderboard_opt_out = $2 WHERE id = $1`, userID, in.OptOut); err != nil {
+		log.Printf("UpdateLeaderboardSettingsHandler: %v", err)
+		http.Error(w, "Database error", http.StatusInternalServerError)
+		return
+	}
+	syncLeaderboard(r.Context(), userID)
+	writeJSON(w, http.StatusOK, in)
+}
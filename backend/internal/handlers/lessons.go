@@ -1,24 +1,38 @@
 package handlers
 
 import (
+	"encoding/base64"
 	"encoding/json"
+	"errors"
+	"fmt"
 	"log"
 	"net/http"
+	"net/url"
 	"strconv"
+	"strings"
 	"time"
 
 	"sonara-space/backend/internal/auth"
 	"sonara-space/backend/internal/db"
+	"sonara-space/backend/internal/i18n"
+	"sonara-space/backend/internal/instruments"
 	"sonara-space/backend/internal/models"
+	"sonara-space/backend/internal/scoring"
+
+	"github.com/jackc/pgx/v5"
 )
 
 // LessonWithExercises представляет урок с упражнениями
 type LessonWithExercises struct {
 	models.Lesson
-	Exercises []models.Exercise `json:"exercises"`
+	Exercises []ExerciseView      `json:"exercises"`
+	Media     []MediaView         `json:"media"`
+	Version   *LessonVersionState `json:"version"`
 }
 
-// ProgressRequest представляет запрос на обновление прогресса
+// ProgressRequest представляет запрос на обновление прогресса — отметку
+// клиента без записи. Измерения клиента не принимаются: по ним нельзя
+// проверить, что упражнение действительно сыграно.
 type ProgressRequest struct {
 	ExerciseID int64  `json:"exercise_id"`
 	Status     string `json:"status"`
@@ -26,35 +40,205 @@ type ProgressRequest struct {
 
 // ProgressResponse представляет ответ с прогрессом пользователя
 type ProgressResponse struct {
-	UserID             int64   `json:"user_id"`
-	LessonID           int64   `json:"lesson_id"`
-	LessonTitle        string  `json:"lesson_title"`
-	TotalExercises     int     `json:"total_exercises"`
-	CompletedExercises int     `json:"completed_exercises"`
-	Progress           float64 `json:"progress"`
+	UserID             int64      `json:"user_id"`
+	LessonID           int64      `json:"lesson_id"`
+	LessonTitle        string     `json:"lesson_title"`
+	TotalExercises     int        `json:"total_exercises"`
+	CompletedExercises int        `json:"completed_exercises"`
+	Progress           float64    `json:"progress"`
+	LastActivity       *time.Time `json:"last_activity"`
+}
+
+// lessonColumns — колонки урока в порядке, который ожидает scanLesson
+const lessonColumns = `id, slug, title, instrument, tuning, capo, description, status, difficulty, tier, locale, tags, created_at`
+
+// scanLesson читает строку, выбранную через lessonColumns (и, возможно, доп. колонки в dest)
+func scanLesson(row pgx.Row, lesson *models.Lesson, dest ...interface{}) error {
+	return row.Scan(append([]interface{}{&lesson.ID, &lesson.Slug, &lesson.Title, &lesson.Instrument, &lesson.Tuning, &lesson.Capo, &lesson.Description,
+		&lesson.Status, &lesson.Difficulty, &lesson.Tier, &lesson.Locale, &lesson.Tags, &lesson.CreatedAt}, dest...)...)
+}
+
+// LessonProgressSummary — прогресс текущего пользователя по уроку
+type LessonProgressSummary struct {
+	TotalExercises     int        `json:"total_exercises"`
+	CompletedExercises int        `json:"completed_exercises"`
+	Percent            float64    `json:"percent"`
+	LastActivity       *time.Time `json:"last_activity,omitempty"`
+}
+
+// LessonListItem — урок в каталоге вместе с прогрессом пользователя
+type LessonListItem struct {
+	models.Lesson
+	Progress LessonProgressSummary `json:"progress"`
+}
+
+// LessonListResponse — страница каталога. next_cursor == null на последней странице.
+type LessonListResponse struct {
+	Items      []LessonListItem `json:"items"`
+	NextCursor *string          `json:"next_cursor"`
+	Limit      int              `json:"limit"`
+}
+
+// LessonCursor — позиция в каталоге: последний выданный урок.
+// Rank заполнен только при поиске, тогда уроки отсортированы по релевантности.
+type LessonCursor struct {
+	CreatedAt time.Time `json:"t"`
+	Rank      *float64  `json:"r,omitempty"`
+	ID        int64     `json:"id"`
+}
+
+// Encode упаковывает курсор в строку для next_cursor
+func (c LessonCursor) Encode() string {
+	data, _ := json.Marshal(c)
+	return base64.RawURLEncoding.EncodeToString(data)
+}
+
+// errInvalidCursor — курсор испорчен или не подходит к запросу
+var errInvalidCursor = errors.New("Invalid cursor")
+
+// DecodeLessonCursor разбирает курсор из next_cursor
+func DecodeLessonCursor(s string) (LessonCursor, error) {
+	var c LessonCursor
+	data, err := base64.RawURLEncoding.DecodeString(s)
+	if err != nil {
+		return c, errInvalidCursor
+	}
+	if err := json.Unmarshal(data, &c); err != nil || c.ID <= 0 {
+		return c, errInvalidCursor
+	}
+	return c, nil
 }
 
-// GetLessonsHandler возвращает список всех уроков
+// searchQuery объединяет запрос в русской и английской конфигурациях,
+// чтобы находились и "аккорды", и "chords"
+const searchQuery = `(websearch_to_tsquery('russian', %[1]s) || websearch_to_tsquery('english', %[1]s))`
+
+// LessonCatalogFilter строит условия, выражение релевантности и порядок
+// выдачи каталога из параметров запроса; arg добавляет параметр SQL и
+// возвращает его плейсхолдер. Ошибка — только неверный cursor.
+func LessonCatalogFilter(query url.Values, arg func(v interface{}) string) (where []string, rank, order string, err error) {
+	where = []string{"status = 'published'"}
+	for _, f := range []string{"instrument", "difficulty", "tier", "locale"} {
+		if v := strings.TrimSpace(query.Get(f)); v != "" {
+			where = append(where, f+" = "+arg(strings.ToLower(v)))
+		}
+	}
+	var tags []string
+	for _, tag := range query["tag"] {
+		if tag = strings.ToLower(strings.TrimSpace(tag)); tag != "" {
+			tags = append(tags, tag)
+		}
+	}
+	if len(tags) > 0 {
+		where = append(where, "tags @> "+arg(tags)+"::text[]")
+	}
+	var skillSlugs []string
+	for _, slug := range query["skill"] {
+		if slug = strings.TrimSpace(slug); slug != "" {
+			skillSlugs = append(skillSlugs, slug)
+		}
+	}
+	if len(skillSlugs) > 0 {
+		where = append(where, skillFilter(arg, skillSlugs))
+	}
+
+	rank = "NULL::float8"
+	order = "created_at, id"
+	search := strings.TrimSpace(query.Get("q"))
+	if search != "" {
+		tsquery := fmt.Sprintf(searchQuery, arg(search))
+		where = append(where, "search_vector @@ "+tsquery)
+		rank = "ts_rank(search_vector, " + tsquery + ")::float8"
+		order = "rank DESC, id"
+	}
+
+	if v := query.Get("cursor"); v != "" {
+		cursor, err := DecodeLessonCursor(v)
+		if err != nil || (search != "") != (cursor.Rank != nil) {
+			return nil, "", "", errInvalidCursor
+		}
+		if search != "" {
+			after := arg(*cursor.Rank)
+			where = append(where, fmt.Sprintf("(%s < %s OR (%s = %s AND id > %s))", rank, after, rank, after, arg(cursor.ID)))
+		} else {
+			where = append(where, fmt.Sprintf("(created_at, id) > (%s, %s)", arg(cursor.CreatedAt), arg(cursor.ID)))
+		}
+	}
+
+	return where, rank, order, nil
+}
+
+// GetLessonsHandler возвращает опубликованные уроки постранично.
+//
+// Параметры: ?limit=20&cursor=...; фильтры instrument, difficulty, tier, locale,
+// tag и skill (можно несколько — урок должен иметь все); q — полнотекстовый поиск по
+// названию и описанию. Без q уроки идут по дате создания, с q — по релевантности.
+// Название и описание переводятся на язык ?lang= / Accept-Language / профиля.
 func GetLessonsHandler(w http.ResponseWriter, r *http.Request) {
+	userID := r.Context().Value(auth.UserIDKey).(int64)
 	ctx := r.Context()
+	query := r.URL.Query()
 
-	query := `SELECT id, title, instrument, description, created_at FROM lessons ORDER BY created_at`
-	rows, err := db.Pool.Query(ctx, query)
+	limit, err := queryLimit(r, 20, 100)
 	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	args := []interface{}{userID}
+	arg := func(v interface{}) string {
+		args = append(args, v)
+		return "$" + strconv.Itoa(len(args))
+	}
+	locale := requestLocale(r)
+	columns := localizedLessonColumns(arg(i18n.Chain(locale)))
+
+	where, rank, order, err := LessonCatalogFilter(query, arg)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	// Берём на одну запись больше, чтобы понять, есть ли следующая страница
+	sql := `SELECT ` + columns + `, ` + rank + ` AS rank,
+			p.total_exercises, p.completed_exercises, p.last_activity
+		FROM lessons
+		CROSS JOIN LATERAL (
+			SELECT COUNT(e.exercise_id) AS total_exercises,
+				COUNT(*) FILTER (WHERE pr.completed) AS completed_exercises,
+				MAX(pr.updated_at) AS last_activity
+			FROM user_lesson_exercises($1) e
+			LEFT JOIN progress pr ON pr.exercise_id = e.exercise_id AND pr.user_id = $1
+			WHERE e.lesson_id = lessons.id
+		) p
+		WHERE ` + strings.Join(where, " AND ") + `
+		ORDER BY ` + order + `
+		LIMIT ` + arg(limit+1)
+
+	rows, err := db.Pool.Query(ctx, sql, args...)
+	if err != nil {
+		log.Printf("GetLessonsHandler: %v", err)
 		http.Error(w, "Database error", http.StatusInternalServerError)
 		return
 	}
 	defer rows.Close()
 
-	var lessons []models.Lesson
+	resp := LessonListResponse{Items: []LessonListItem{}, Limit: limit}
+	var ranks []*float64
 	for rows.Next() {
-		var lesson models.Lesson
-		err := rows.Scan(&lesson.ID, &lesson.Title, &lesson.Instrument, &lesson.Description, &lesson.CreatedAt)
+		var item LessonListItem
+		var itemRank *float64
+		err := scanLesson(rows, &item.Lesson, &itemRank, &item.Progress.TotalExercises,
+			&item.Progress.CompletedExercises, &item.Progress.LastActivity)
 		if err != nil {
 			http.Error(w, "Database scan error", http.StatusInternalServerError)
 			return
 		}
-		lessons = append(lessons, lesson)
+		if item.Progress.TotalExercises > 0 {
+			item.Progress.Percent = float64(item.Progress.CompletedExercises) / float64(item.Progress.TotalExercises) * 100
+		}
+		resp.Items = append(resp.Items, item)
+		ranks = append(ranks, itemRank)
 	}
 
 	if err = rows.Err(); err != nil {
@@ -62,12 +246,22 @@ func GetLessonsHandler(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	w.Header().Set("Content-Type", "application/json")
-	json.NewEncoder(w).Encode(lessons)
+	if len(resp.Items) > limit {
+		resp.Items = resp.Items[:limit]
+		last := resp.Items[limit-1]
+		next := LessonCursor{CreatedAt: last.CreatedAt, Rank: ranks[limit-1], ID: last.ID}.Encode()
+		resp.NextCursor = &next
+	}
+
+	w.Header().Set("Content-Language", locale)
+	writeJSON(w, http.StatusOK, resp)
 }
 
-// GetLessonHandler возвращает урок с упражнениями по ID
+// GetLessonHandler возвращает урок с упражнениями по ID. Упражнения берутся
+// из версии, которую проходит пользователь, или из текущей опубликованной.
+// С ?transpose=N песни и мелодии сдвигаются на N полутонов (-11..11).
 func GetLessonHandler(w http.ResponseWriter, r *http.Request) {
+	userID := r.Context().Value(auth.UserIDKey).(int64)
 	ctx := r.Context()
 
 	// Получаем ID из URL
@@ -77,21 +271,31 @@ func GetLessonHandler(w http.ResponseWriter, r *http.Request) {
 		http.Error(w, "Invalid lesson ID", http.StatusBadRequest)
 		return
 	}
+	semitones, err := queryTranspose(r)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
 
-	// Получаем урок
+	// Получаем урок на языке пользователя
+	locale := requestLocale(r)
+	chain := i18n.Chain(locale)
 	var lesson models.Lesson
-	lessonQuery := `SELECT id, title, instrument, description, created_at FROM lessons WHERE id = $1`
-	err = db.Pool.QueryRow(ctx, lessonQuery, lessonID).Scan(
-		&lesson.ID, &lesson.Title, &lesson.Instrument, &lesson.Description, &lesson.CreatedAt)
+	lessonQuery := `SELECT ` + localizedLessonColumns("$2") + ` FROM lessons
+		WHERE id = $1 AND status = 'published'`
+	err = scanLesson(db.Pool.QueryRow(ctx, lessonQuery, lessonID, chain), &lesson)
 	if err != nil {
 		http.Error(w, "Lesson not found", http.StatusNotFound)
 		return
 	}
 
 	// Получаем упражнения для урока
-	exercisesQuery := `SELECT id, lesson_id, title, expected, type, order_index, created_at 
-		FROM exercises WHERE lesson_id = $1 ORDER BY order_index`
-	rows, err := db.Pool.Query(ctx, exercisesQuery, lessonID)
+	exercisesQuery := `SELECT exercises.id, exercises.lesson_id, exercises.slug, ` + localizedExerciseTitle("$2") + `,
+			exercises.expected, exercises.type, ue.order_index, exercises.created_at
+		FROM user_lesson_exercises($3) ue
+		JOIN exercises ON exercises.id = ue.exercise_id
+		WHERE ue.lesson_id = $1 ORDER BY ue.order_index`
+	rows, err := db.Pool.Query(ctx, exercisesQuery, lessonID, i18n.Preferred(chain, lesson.Locale), userID)
 	if err != nil {
 		http.Error(w, "Database error", http.StatusInternalServerError)
 		return
@@ -101,7 +305,7 @@ func GetLessonHandler(w http.ResponseWriter, r *http.Request) {
 	var exercises []models.Exercise
 	for rows.Next() {
 		var exercise models.Exercise
-		err := rows.Scan(&exercise.ID, &exercise.LessonID, &exercise.Title,
+		err := rows.Scan(&exercise.ID, &exercise.LessonID, &exercise.Slug, &exercise.Title,
 			&exercise.Expected, &exercise.Type, &exercise.OrderIndex, &exercise.CreatedAt)
 		if err != nil {
 			http.Error(w, "Database scan error", http.StatusInternalServerError)
@@ -115,13 +319,63 @@ func GetLessonHandler(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
+	// Подгружаем структуру песен
+	exerciseViews, err := withSequences(ctx, exercises)
+	if err != nil {
+		log.Printf("GetLessonHandler: load sequences: %v", err)
+		http.Error(w, "Database error", http.StatusInternalServerError)
+		return
+	}
+	if r.URL.Query().Has("transpose") {
+		setup := instruments.Setup{Instrument: lesson.Instrument}
+		if lesson.Tuning != nil {
+			setup.Tuning = *lesson.Tuning
+		}
+		if err := transposeExercises(ctx, exerciseViews, semitones, setup); err != nil {
+			log.Printf("GetLessonHandler: transpose: %v", err)
+			http.Error(w, "Database error", http.StatusInternalServerError)
+			return
+		}
+	}
+
+	ids := make([]int64, 0, len(exerciseViews))
+	for _, e := range exerciseViews {
+		ids = append(ids, e.ID)
+	}
+	exerciseSkills, err := loadExerciseSkills(ctx, ids)
+	if err != nil {
+		log.Printf("GetLessonHandler: load skills: %v", err)
+		http.Error(w, "Database error", http.StatusInternalServerError)
+		return
+	}
+	for i := range exerciseViews {
+		exerciseViews[i].Skills = exerciseSkills[exerciseViews[i].ID]
+	}
+
+	media, err := loadLessonMedia(ctx, lessonID)
+	if err != nil {
+		log.Printf("GetLessonHandler: load media: %v", err)
+		http.Error(w, "Server error", http.StatusInternalServerError)
+		return
+	}
+
+	version, err := lessonVersionState(ctx, userID, lessonID)
+	if err != nil {
+		log.Printf("GetLessonHandler: load version: %v", err)
+		http.Error(w, "Database error", http.StatusInternalServerError)
+		return
+	}
+
 	// Формируем ответ
 	lessonWithExercises := LessonWithExercises{
 		Lesson:    lesson,
-		Exercises: exercises,
+		Exercises: exerciseViews,
+		Media:     media,
+		Version:   version,
 	}
 
 	w.Header().Set("Content-Type", "application/json")
+	w.Header().Set("Content-Language", locale)
 	json.NewEncoder(w).Encode(lessonWithExercises)
 }
 
@@ -150,13 +404,13 @@ func UpdateProgressHandler(w http.ResponseWriter, r *http.Request) {
 
 	log.Printf("UpdateProgressHandler: userID=%d, exerciseID=%d, status=%s", userID, req.ExerciseID, req.Status)
 
-	// Проверяем, что упражнение существует
-	var exercise models.Exercise
-	exerciseQuery := `SELECT id, lesson_id, title, expected, type, order_index, created_at 
-		FROM exercises WHERE id = $1`
-	err := db.Pool.QueryRow(ctx, exerciseQuery, req.ExerciseID).Scan(
-		&exercise.ID, &exercise.LessonID, &exercise.Title,
-		&exercise.Expected, &exercise.Type, &exercise.OrderIndex, &exercise.CreatedAt)
+	if !models.ValidStatus(req.Status) {
+		http.Error(w, "Invalid status, expected in_progress, done or failed", http.StatusBadRequest)
+		return
+	}
+
+	// Проверяем, что упражнение есть в опубликованном уроке, который видит пользователь
+	exercise, err := loadExercise(ctx, userID, req.ExerciseID)
 	if err != nil {
 		log.Printf("Exercise not found: %v", err)
 		http.Error(w, "Exercise not found", http.StatusNotFound)
@@ -164,104 +418,65 @@ func UpdateProgressHandler(w http.ResponseWriter, r *http.Request) {
 	}
 	log.Printf("Exercise found: ID=%d, Title=%s, Expected=%s, Type=%s", exercise.ID, exercise.Title, exercise.Expected, exercise.Type)
 
-	// Обновляем или создаем прогресс
-	var completed bool
-	if req.Status == "done" {
-		completed = true
+	// Отметка от клиента сохраняется как попытка в истории, а progress
+	// пересчитывается из истории в той же транзакции
+	attempt := models.Attempt{
+		UserID:     userID,
+		ExerciseID: req.ExerciseID,
+		Source:     models.AttemptSourceClient,
 	}
-
-	// Используем UPSERT для обновления или создания записи прогресса
-	upsertQuery := `
-		INSERT INTO progress (user_id, exercise_id, status, completed, attempts, best_score, completed_at, updated_at)
-		VALUES ($1, $2, $3, $4, 1, $5, $6, $7)
-		ON CONFLICT (user_id, exercise_id) 
-		DO UPDATE SET 
-			status = $3,
-			completed = $4,
-			attempts = progress.attempts + 1,
-			best_score = GREATEST(progress.best_score, $5),
-			completed_at = CASE WHEN $4 = true THEN COALESCE(progress.completed_at, $6) ELSE progress.completed_at END,
-			updated_at = $7
-	`
-
-	var completedAt *time.Time
-	now := time.Now()
-	if completed {
-		completedAt = &now
+	if req.Status == models.StatusInProgress {
+		attempt.Status = models.StatusInProgress
 	}
 
-	log.Printf("Executing SQL with params: userID=%d, exerciseID=%d, status=%s, completed=%t, best_score=100.00, completedAt=%v, now=%v",
-		userID, req.ExerciseID, req.Status, completed, completedAt, now)
+	log.Printf("Recording client attempt: userID=%d, exerciseID=%d, status=%s",
+		userID, req.ExerciseID, req.Status)
 
-	_, err = db.Pool.Exec(ctx, upsertQuery, userID, req.ExerciseID, req.Status, completed, "100.00", completedAt, now)
+	result, err := recordAttempt(ctx, &attempt, func(retries int) scoring.Result {
+		if req.Status == models.StatusInProgress {
+			return scoring.Reported(exercise.Type, false, retries)
+		}
+		return scoring.Reported(exercise.Type, req.Status == models.StatusDone, retries)
+	})
 	if err != nil {
 		log.Printf("Database error in UpdateProgressHandler: %v", err)
-		log.Printf("SQL query: %s", upsertQuery)
 		http.Error(w, "Database error", http.StatusInternalServerError)
 		return
 	}
 
-	log.Printf("SQL executed successfully")
+	log.Printf("Attempt %d recorded: score=%.2f, passed=%t", attempt.ID, result.Score, result.Passed)
 
+	w.Header().Set("Content-Type", "application/json")
 	w.WriteHeader(http.StatusOK)
-	json.NewEncoder(w).Encode(map[string]string{
+	json.NewEncoder(w).Encode(map[string]interface{}{
 		"message": "Progress updated successfully",
+		"status":  attempt.Status,
+		"scoring": result,
 	})
 }
 
-// GetUserProgressHandler возвращает прогресс пользователя по всем урокам
+// GetUserProgressHandler возвращает прогресс пользователя по всем урокам.
+// С ?include=summary ответ — объект с уроками и общим итогом (см. UserProgressResponse).
 func GetUserProgressHandler(w http.ResponseWriter, r *http.Request) {
 	userID := r.Context().Value(auth.UserIDKey).(int64)
-	ctx := r.Context()
 
-	// Получаем прогресс пользователя по всем урокам
-	progressQuery := `
-		SELECT 
-			l.id as lesson_id,
-			l.title as lesson_title,
-			COUNT(e.id) as total_exercises,
-			COUNT(CASE WHEN p.completed = true THEN 1 END) as completed_exercises
-		FROM lessons l
-		LEFT JOIN exercises e ON l.id = e.lesson_id
-		LEFT JOIN progress p ON e.id = p.exercise_id AND p.user_id = $1
-		GROUP BY l.id, l.title
-		ORDER BY l.created_at
-	`
-
-	rows, err := db.Pool.Query(ctx, progressQuery, userID)
-	if err != nil {
-		http.Error(w, "Database error", http.StatusInternalServerError)
+	include := r.URL.Query().Get("include")
+	if include != "" && include != "summary" {
+		http.Error(w, "Invalid include, expected summary", http.StatusBadRequest)
 		return
 	}
-	defer rows.Close()
 
-	var progress []ProgressResponse
-	for rows.Next() {
-		var p ProgressResponse
-		var total, completed int
-		err := rows.Scan(&p.LessonID, &p.LessonTitle, &total, &completed)
-		if err != nil {
-			http.Error(w, "Database scan error", http.StatusInternalServerError)
-			return
-		}
-
-		p.UserID = userID
-		p.TotalExercises = total
-		p.CompletedExercises = completed
-		if total > 0 {
-			p.Progress = float64(completed) / float64(total) * 100
-		} else {
-			p.Progress = 0
-		}
-
-		progress = append(progress, p)
+	views, lessons, err := userLessonProgress(r.Context(), userID)
+	if err != nil {
+		log.Printf("GetUserProgressHandler: %v", err)
+		http.Error(w, "Database error", http.StatusInternalServerError)
+		return
 	}
 
-	if err = rows.Err(); err != nil {
-		http.Error(w, "Database rows error", http.StatusInternalServerError)
+	if include == "summary" {
+		writeJSON(w, http.StatusOK, UserProgressResponse{Lessons: views, Summary: progressSummary(lessons)})
 		return
 	}
-
 	w.Header().Set("Content-Type", "application/json")
-	json.NewEncoder(w).Encode(progress)
+	json.NewEncoder(w).Encode(views)
 }
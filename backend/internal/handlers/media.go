@@ -0,0 +1,319 @@
+package handlers
+
+import (
+	"bufio"
+	"context"
+	"errors"
+	"fmt"
+	"log"
+	"mime"
+	"net/http"
+	"path"
+	"strings"
+	"time"
+
+	"sonara-space/backend/internal/db"
+	"sonara-space/backend/internal/storage"
+
+	"github.com/go-chi/chi/v5"
+	"github.com/jackc/pgx/v5"
+)
+
+// mediaURLTTL — срок действия ссылок на медиафайлы
+const mediaURLTTL = 15 * time.Minute
+
+// storageScheme — префикс uri загруженных файлов (остальные uri — внешние ссылки)
+const storageScheme = "storage://"
+
+// mediaRule — допустимые типы и размер файлов вида медиа
+type mediaRule struct {
+	maxBytes int64
+	types    []string
+}
+
+var mediaRules = map[string]mediaRule{
+	"audio": {50 << 20, []string{"audio/mpeg", "audio/mp4", "audio/aac", "audio/ogg", "audio/wav", "audio/x-wav", "audio/webm", "audio/flac"}},
+	"video": {500 << 20, []string{"video/mp4", "video/webm", "video/quicktime"}},
+	"image": {10 << 20, []string{"image/png", "image/jpeg", "image/webp", "image/gif", "image/svg+xml"}},
+	"score": {20 << 20, []string{"application/pdf", "application/vnd.recordare.musicxml+xml", "application/vnd.recordare.musicxml", "audio/midi"}},
+}
+
+// mediaExt — расширение файла по типу: по нему локальное хранилище
+// определяет Content-Type при отдаче
+var mediaExt = map[string]string{
+	"audio/mpeg": ".mp3", "audio/mp4": ".m4a", "audio/aac": ".aac", "audio/ogg": ".ogg",
+	"audio/wav": ".wav", "audio/x-wav": ".wav", "audio/webm": ".weba", "audio/flac": ".flac",
+	"video/mp4": ".mp4", "video/webm": ".webm", "video/quicktime": ".mov",
+	"image/png": ".png", "image/jpeg": ".jpg", "image/webp": ".webp", "image/gif": ".gif", "image/svg+xml": ".svg",
+	"application/pdf": ".pdf", "application/vnd.recordare.musicxml+xml": ".musicxml",
+	"application/vnd.recordare.musicxml": ".mxl", "audio/midi": ".mid",
+}
+
+// MediaView — медиафайл урока со ссылкой на скачивание
+type MediaView struct {
+	ID          int64      `json:"id"`
+	Kind        string     `json:"kind"`
+	Title       *string    `json:"title,omitempty"`
+	URL         string     `json:"url"`
+	ContentType *string    `json:"content_type,omitempty"`
+	SizeBytes   *int64     `json:"size_bytes,omitempty"`
+	ExpiresAt   *time.Time `json:"expires_at,omitempty"`
+	OrderIndex  int        `json:"order_index"`
+}
+
+// sniffMatches сверяет заявленный тип с определённым по содержимому.
+// Многие аудиоформаты не распознаются (octet-stream), а m4a и ogg
+// распознаются как видео/контейнер — это допустимо.
+func sniffMatches(declared, sniffed string) bool {
+	sniffed, _, _ = mime.ParseMediaType(sniffed)
+	major := func(t string) string { return strings.SplitN(t, "/", 2)[0] }
+	switch {
+	case sniffed == declared, sniffed == "application/octet-stream":
+		return true
+	case strings.HasSuffix(declared, "+xml") || strings.Contains(declared, "musicxml"):
+		return sniffed == "text/xml" || sniffed == "text/plain" || sniffed == "application/zip"
+	case major(declared) == "audio" || major(declared) == "video":
+		return major(sniffed) == "audio" || major(sniffed) == "video" || sniffed == "application/ogg"
+	}
+	return major(sniffed) == major(declared) && major(declared) != "application"
+}
+
+// signMedia подставляет подписанную ссылку для загруженных файлов
+func signMedia(ctx context.Context, m *MediaView, uri string) error {
+	if !strings.HasPrefix(uri, storageScheme) {
+		m.URL = uri
+		return nil
+	}
+	if storage.Store == nil {
+		return errors.New("storage is not configured")
+	}
+	expires := time.Now().Add(mediaURLTTL).Truncate(time.Second)
+	u, err := storage.Store.URL(ctx, strings.TrimPrefix(uri, storageScheme), expires)
+	if err != nil {
+		return err
+	}
+	m.URL, m.ExpiresAt = u, &expires
+	return nil
+}
+
+// loadLessonMedia возвращает медиафайлы урока с подписанными ссылками
+func loadLessonMedia(ctx context.Context, lessonID int64) ([]MediaView, error) {
+	rows, err := db.Pool.Query(ctx, `
+		SELECT id, kind, title, uri, content_type, size_bytes, order_index
+		FROM lesson_media WHERE lesson_id = $1
+		ORDER BY order_index, id
+	`, lessonID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	media := []MediaView{}
+	for rows.Next() {
+		var m MediaView
+		var uri string
+		if err := rows.Scan(&m.ID, &m.Kind, &m.Title, &uri, &m.ContentType, &m.SizeBytes, &m.OrderIndex); err != nil {
+			return nil, err
+		}
+		// Без ссылки файл бесполезен клиенту, но остальной урок показать можно
+		if err := signMedia(ctx, &m, uri); err != nil {
+			log.Printf("loadLessonMedia: %s: %v", uri, err)
+			continue
+		}
+		media = append(media, m)
+	}
+	return media, rows.Err()
+}
+
+// GetLessonMediaHandler возвращает медиафайлы опубликованного урока.
+// Ссылки на загруженные файлы действуют mediaURLTTL.
+func GetLessonMediaHandler(w http.ResponseWriter, r *http.Request) {
+	lessonID, err := urlParamID(r, "id")
+	if err != nil {
+		http.Error(w, "Invalid lesson ID", http.StatusBadRequest)
+		return
+	}
+	var exists bool
+	err = db.Pool.QueryRow(r.Context(),
+		`SELECT EXISTS (SELECT 1 FROM lessons WHERE id = $1 AND status = 'published')`, lessonID).Scan(&exists)
+	if err != nil {
+		http.Error(w, "Database error", http.StatusInternalServerError)
+		return
+	}
+	if !exists {
+		http.Error(w, "Lesson not found", http.StatusNotFound)
+		return
+	}
+
+	media, err := loadLessonMedia(r.Context(), lessonID)
+	if err != nil {
+		log.Printf("GetLessonMediaHandler: %v", err)
+		http.Error(w, "Server error", http.StatusInternalServerError)
+		return
+	}
+	writeJSON(w, http.StatusOK, media)
+}
+
+// AdminUploadMediaHandler загружает файл в тело запроса как медиа урока:
+// ?kind=audio|video|image|score&title=…, тип — из Content-Type.
+// Тип проверяется по списку для вида медиа и по содержимому файла,
+// размер — по Content-Length (без него запрос отклоняется).
+func AdminUploadMediaHandler(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+	lessonID, err := urlParamID(r, "id")
+	if err != nil {
+		http.Error(w, "Invalid lesson ID", http.StatusBadRequest)
+		return
+	}
+	kind := r.URL.Query().Get("kind")
+	rule, ok := mediaRules[kind]
+	if !ok {
+		http.Error(w, "Invalid kind, expected audio, video, image or score", http.StatusBadRequest)
+		return
+	}
+	contentType, _, _ := mime.ParseMediaType(r.Header.Get("Content-Type"))
+	allowed := false
+	for _, t := range rule.types {
+		allowed = allowed || t == contentType
+	}
+	if !allowed {
+		http.Error(w, fmt.Sprintf("Unsupported content type for %s: %s", kind, strings.Join(rule.types, ", ")),
+			http.StatusUnsupportedMediaType)
+		return
+	}
+	if r.ContentLength < 0 {
+		http.Error(w, "Content-Length is required", http.StatusLengthRequired)
+		return
+	}
+	if r.ContentLength == 0 || r.ContentLength > rule.maxBytes {
+		http.Error(w, fmt.Sprintf("File size must be between 1 byte and %d MB", rule.maxBytes>>20),
+			http.StatusRequestEntityTooLarge)
+		return
+	}
+	if storage.Store == nil {
+		http.Error(w, "Storage is not configured", http.StatusServiceUnavailable)
+		return
+	}
+
+	var exists bool
+	if err := db.Pool.QueryRow(ctx, `SELECT EXISTS (SELECT 1 FROM lessons WHERE id = $1)`, lessonID).Scan(&exists); err != nil {
+		http.Error(w, "Database error", http.StatusInternalServerError)
+		return
+	}
+	if !exists {
+		http.Error(w, "Lesson not found", http.StatusNotFound)
+		return
+	}
+
+	body := bufio.NewReaderSize(http.MaxBytesReader(w, r.Body, rule.maxBytes), 512)
+	head, _ := body.Peek(512)
+	if !sniffMatches(contentType, http.DetectContentType(head)) {
+		http.Error(w, "File content does not match Content-Type", http.StatusUnsupportedMediaType)
+		return
+	}
+
+	key := storage.NewKey(fmt.Sprintf("lessons/%d", lessonID), mediaExt[contentType])
+	if err := storage.Store.Put(ctx, key, body, r.ContentLength, contentType); err != nil {
+		log.Printf("AdminUploadMediaHandler: put %s: %v", key, err)
+		http.Error(w, "Upload failed", http.StatusBadGateway)
+		return
+	}
+
+	var title *string
+	if t := strings.TrimSpace(r.URL.Query().Get("title")); t != "" {
+		title = &t
+	}
+	m := MediaView{Kind: kind, Title: title, ContentType: &contentType, SizeBytes: &r.ContentLength}
+	err = db.Pool.QueryRow(ctx, `
+		INSERT INTO lesson_media (lesson_id, kind, uri, title, storage_key, content_type, size_bytes, order_index)
+		VALUES ($1, $2, $3, $4, $5, $6, $7,
+			COALESCE((SELECT MAX(order_index) FROM lesson_media WHERE lesson_id = $1), 0) + 1)
+		RETURNING id, order_index
+	`, lessonID, kind, storageScheme+key, title, key, contentType, r.ContentLength).Scan(&m.ID, &m.OrderIndex)
+	if err != nil {
+		log.Printf("AdminUploadMediaHandler: %v", err)
+		storage.Store.Delete(ctx, key)
+		http.Error(w, "Database error", http.StatusInternalServerError)
+		return
+	}
+
+	if err := signMedia(ctx, &m, storageScheme+key); err != nil {
+		log.Printf("AdminUploadMediaHandler: sign: %v", err)
+	}
+	writeJSON(w, http.StatusCreated, m)
+}
+
+// AdminDeleteMediaHandler удаляет медиа урока вместе с загруженным файлом
+func AdminDeleteMediaHandler(w http.ResponseWriter, r *http.Request) {
+	mediaID, err := urlParamID(r, "id")
+	if err != nil {
+		http.Error(w, "Invalid media ID", http.StatusBadRequest)
+		return
+	}
+
+	var uri string
+	err = db.Pool.QueryRow(r.Context(), `DELETE FROM lesson_media WHERE id = $1 RETURNING uri`, mediaID).Scan(&uri)
+	if errors.Is(err, pgx.ErrNoRows) {
+		http.Error(w, "Media not found", http.StatusNotFound)
+		return
+	}
+	if err != nil {
+		log.Printf("AdminDeleteMediaHandler: %v", err)
+		http.Error(w, "Database error", http.StatusInternalServerError)
+		return
+	}
+
+	// Файл, который не удалось удалить, останется в хранилище без ссылок — это не ошибка запроса
+	if strings.HasPrefix(uri, storageScheme) && storage.Store != nil {
+		if err := storage.Store.Delete(r.Context(), strings.TrimPrefix(uri, storageScheme)); err != nil {
+			log.Printf("AdminDeleteMediaHandler: delete %s: %v", uri, err)
+		}
+	}
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// MediaFileHandler отдаёт файлы локального хранилища по подписанным ссылкам.
+// http.ServeContent поддерживает Range-запросы, поэтому аудио можно
+// перематывать. Для S3 ссылки ведут прямо в хранилище.
+func MediaFileHandler(w http.ResponseWriter, r *http.Request) {
+	local, ok := storage.Store.(*storage.Local)
+	if !ok {
+		http.NotFound(w, r)
+		return
+	}
+	key := chi.URLParam(r, "*")
+	q := r.URL.Query()
+	if err := local.Verify(key, q.Get("expires"), q.Get("signature"), time.Now()); err != nil {
+		http.Error(w, "Link is invalid or expired", http.StatusForbidden)
+		return
+	}
+
+	f, err := local.Open(key)
+	if errors.Is(err, storage.ErrNotFound) || errors.Is(err, storage.ErrInvalidKey) {
+		http.NotFound(w, r)
+		return
+	}
+	if err != nil {
+		log.Printf("MediaFileHandler: %v", err)
+		http.Error(w, "Server error", http.StatusInternalServerError)
+		return
+	}
+	defer f.Close()
+	info, err := f.Stat()
+	if err != nil {
+		http.Error(w, "Server error", http.StatusInternalServerError)
+		return
+	}
+
+	for contentType, ext := range mediaExt {
+		if ext == path.Ext(key) {
+			w.Header().Set("Content-Type", contentType)
+			break
+		}
+	}
+	w.Header().Set("Cache-Control", "private, max-age=300")
+	w.Header().Set("X-Content-Type-Options", "nosniff")
+	// SVG со скриптами не должен выполняться в контексте API
+	w.Header().Set("Content-Security-Policy", "sandbox")
+	http.ServeContent(w, r, path.Base(key), info.ModTime(), f)
+}
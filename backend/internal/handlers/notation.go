@@ -0,0 +1,217 @@
+package handlers
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"log"
+	"net/http"
+	"strings"
+
+	"sonara-space/backend/internal/content"
+	"sonara-space/backend/internal/db"
+	"sonara-space/backend/internal/models"
+	"sonara-space/backend/internal/notation"
+
+	"github.com/jackc/pgx/v5"
+)
+
+const maxNotationBytes = 20 << 20
+
+// NotationPartView — партия нотного файла
+type NotationPartView struct {
+	ID         string `json:"id"`
+	Name       string `json:"name"`
+	Instrument string `json:"instrument,omitempty"`
+	Notes      int    `json:"notes"`
+	Chords     int    `json:"chords"`
+	Selected   bool   `json:"selected"`
+}
+
+// NotationImportResponse — результат импорта нотного файла
+type NotationImportResponse struct {
+	DryRun    bool               `json:"dry_run"`
+	Title     string             `json:"title"`
+	Parts     []NotationPartView `json:"parts"`
+	Exercises []ExerciseView     `json:"exercises"`
+	Warnings  []string           `json:"warnings"`
+	Errors    []string           `json:"errors,omitempty"`
+}
+
+// AdminImportNotationHandler создаёт упражнения-последовательности урока из
+// MusicXML (.musicxml, .mxl) или MIDI в теле запроса. По умолчанию берутся
+// партии инструмента урока; ?part= выбирает партию явно, ?steps=note|chord —
+// мелодию или аккорды, ?title= — название песни, ?dry_run=true — только
+// показать результат. Ноты вне диапазона инструмента урока — ошибка 422.
+func AdminImportNotationHandler(w http.ResponseWriter, r *http.Request) {
+	lessonID, err := urlParamID(r, "id")
+	if err != nil {
+		http.Error(w, "Invalid lesson ID", http.StatusBadRequest)
+		return
+	}
+	query := r.URL.Query()
+	steps := query.Get("steps")
+	if steps != "" && steps != models.ExerciseTypeNote && steps != models.ExerciseTypeChord {
+		http.Error(w, "Invalid steps, expected note or chord", http.StatusBadRequest)
+		return
+	}
+	dryRun := query.Get("dry_run") == "true"
+
+	ctx := r.Context()
+	setup, err := loadInstrumentSetup(ctx, db.Pool, lessonID)
+	if errors.Is(err, pgx.ErrNoRows) {
+		http.Error(w, "Lesson not found", http.StatusNotFound)
+		return
+	}
+	if err != nil {
+		log.Printf("AdminImportNotationHandler: %v", err)
+		http.Error(w, "Database error", http.StatusInternalServerError)
+		return
+	}
+
+	data, err := io.ReadAll(http.MaxBytesReader(w, r.Body, maxNotationBytes))
+	if err != nil {
+		http.Error(w, "File is too large", http.StatusRequestEntityTooLarge)
+		return
+	}
+	score, err := notation.Parse(data)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	resp := NotationImportResponse{
+		DryRun:    dryRun,
+		Title:     score.Title,
+		Parts:     []NotationPartView{},
+		Exercises: []ExerciseView{},
+		Warnings:  append([]string{}, score.Warnings...),
+	}
+	selected := selectParts(score, setup.Instrument, query.Get("part"))
+	for i, p := range score.Parts {
+		resp.Parts = append(resp.Parts, NotationPartView{
+			ID: p.ID, Name: p.Name, Instrument: p.Instrument,
+			Notes: len(p.Notes), Chords: len(p.Chords), Selected: selected[i],
+		})
+	}
+	if len(selected) == 0 {
+		resp.Errors = []string{fmt.Sprintf("no %s part found, choose one with ?part=", setup.Instrument)}
+		writeJSON(w, http.StatusBadRequest, resp)
+		return
+	}
+
+	var sequences []models.Sequence
+	for i := range score.Parts {
+		if !selected[i] {
+			continue
+		}
+		p := &score.Parts[i]
+		title := query.Get("title")
+		if len(selected) > 1 {
+			title = strings.TrimSpace(firstNonEmpty(title, score.Title) + " — " + p.Name)
+		}
+		seq, err := score.Sequence(p, steps, title)
+		if err == nil {
+			err = notation.CheckRange(setup, &seq)
+		}
+		if err != nil {
+			resp.Errors = append(resp.Errors, err.Error())
+			continue
+		}
+		sequences = append(sequences, seq)
+	}
+	if len(resp.Errors) > 0 {
+		writeJSON(w, http.StatusUnprocessableEntity, resp)
+		return
+	}
+
+	tx, err := db.Pool.Begin(ctx)
+	if err != nil {
+		http.Error(w, "Database error", http.StatusInternalServerError)
+		return
+	}
+	defer tx.Rollback(ctx)
+
+	for i := range sequences {
+		seq := &sequences[i]
+		expected, _ := seq.Validate()
+		exercise := models.Exercise{LessonID: lessonID, Title: seq.SongTitle, Expected: expected, Type: models.ExerciseTypeSequence}
+		if !dryRun {
+			base := content.Slugify(seq.SongTitle)
+			if base == "" {
+				base = content.ExerciseSlug(models.ExerciseTypeSequence, expected)
+			}
+			exercise.Slug, err = insertWithSlug(ctx, tx, "", base,
+				`SELECT EXISTS (SELECT 1 FROM exercises WHERE slug = $1 AND lesson_id = $2 AND retired_at IS NULL)`,
+				[]interface{}{lessonID},
+				func(tx pgx.Tx, slug string) error {
+					return tx.QueryRow(ctx, `
+						INSERT INTO exercises (lesson_id, slug, title, expected, type, order_index)
+						VALUES ($1, $2, $3, $4, $5,
+							COALESCE((SELECT MAX(order_index) FROM exercises WHERE lesson_id = $1 AND retired_at IS NULL), 0) + 1)
+						RETURNING id, order_index, created_at
+					`, lessonID, slug, exercise.Title, expected, exercise.Type).Scan(
+						&exercise.ID, &exercise.OrderIndex, &exercise.CreatedAt)
+				})
+			if err == nil {
+				err = saveSequence(ctx, tx, exercise.ID, &SequenceInput{
+					SongTitle: seq.SongTitle, StepType: seq.StepType, Tempo: seq.Tempo,
+					BeatsPerBar: seq.BeatsPerBar, BeatUnit: seq.BeatUnit, Steps: seq.Steps,
+				})
+			}
+			if err != nil {
+				log.Printf("AdminImportNotationHandler: %v", err)
+				http.Error(w, "Database error", http.StatusInternalServerError)
+				return
+			}
+		}
+		resp.Exercises = append(resp.Exercises, ExerciseView{Exercise: exercise, Sequence: NewSequenceView(seq)})
+	}
+
+	if !dryRun {
+		if err := tx.Commit(ctx); err != nil {
+			log.Printf("AdminImportNotationHandler: commit: %v", err)
+			http.Error(w, "Database error", http.StatusInternalServerError)
+			return
+		}
+	}
+	status := http.StatusCreated
+	if dryRun {
+		status = http.StatusOK
+	}
+	writeJSON(w, status, resp)
+}
+
+// selectParts выбирает партии: явно указанную, иначе партии инструмента
+// урока, иначе партии с нераспознанным инструментом
+func selectParts(score *notation.Score, instrument, partID string) map[int]bool {
+	selected := make(map[int]bool)
+	if partID != "" {
+		for i, p := range score.Parts {
+			if p.ID == partID {
+				selected[i] = true
+			}
+		}
+		return selected
+	}
+	for _, want := range []string{instrument, ""} {
+		for i, p := range score.Parts {
+			if p.Instrument == want {
+				selected[i] = true
+			}
+		}
+		if len(selected) > 0 {
+			break
+		}
+	}
+	return selected
+}
+
+func firstNonEmpty(values ...string) string {
+	for _, v := range values {
+		if v = strings.TrimSpace(v); v != "" {
+			return v
+		}
+	}
+	return ""
+}
@@ -0,0 +1,268 @@
+package handlers
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"io"
+	"log"
+	"net/http"
+	"time"
+
+	"sonara-space/backend/internal/auth"
+	"sonara-space/backend/internal/db"
+	"sonara-space/backend/internal/models"
+	"sonara-space/backend/internal/practice"
+
+	"github.com/jackc/pgx/v5"
+)
+
+// PracticeSessionView — сессия занятий в ответе API
+type PracticeSessionView struct {
+	ID              int64      `json:"id"`
+	LessonID        *int64     `json:"lesson_id"`
+	ExerciseID      *int64     `json:"exercise_id"`
+	StartedAt       time.Time  `json:"started_at"`
+	LastHeartbeatAt time.Time  `json:"last_heartbeat_at"`
+	EndedAt         *time.Time `json:"ended_at"`
+	EndReason       *string    `json:"end_reason"`
+	ActiveSeconds   int        `json:"active_seconds"`
+	// HeartbeatSeconds — через сколько секунд прислать следующий сигнал
+	HeartbeatSeconds int               `json:"heartbeat_seconds"`
+	Summary          *practice.Summary `json:"summary,omitempty"`
+}
+
+// PracticeStartInput — тело запроса на начало сессии; оба поля необязательны
+type PracticeStartInput struct {
+	LessonID   *int64 `json:"lesson_id"`
+	ExerciseID *int64 `json:"exercise_id"`
+}
+
+// PracticeHeartbeatInput — сигнал "занимаюсь"; exercise_id — текущее упражнение, если сменилось
+type PracticeHeartbeatInput struct {
+	ExerciseID *int64 `json:"exercise_id"`
+}
+
+func newPracticeSessionView(s models.PracticeSession, summary *practice.Summary) PracticeSessionView {
+	return PracticeSessionView{
+		ID:               s.ID,
+		LessonID:         s.LessonID,
+		ExerciseID:       s.ExerciseID,
+		StartedAt:        s.StartedAt,
+		LastHeartbeatAt:  s.LastHeartbeatAt,
+		EndedAt:          s.EndedAt,
+		EndReason:        s.EndReason,
+		ActiveSeconds:    s.ActiveSeconds,
+		HeartbeatSeconds: int(practice.HeartbeatInterval / time.Second),
+		Summary:          summary,
+	}
+}
+
+// exerciseLesson возвращает урок упражнения
+func exerciseLesson(ctx context.Context, q pgx.Tx, exerciseID int64) (int64, error) {
+	var lessonID int64
+	err := q.QueryRow(ctx, `SELECT lesson_id FROM exercises WHERE id = $1`, exerciseID).Scan(&lessonID)
+	return lessonID, err
+}
+
+// decodeOptionalJSON разбирает тело запроса; пустое тело — не ошибка
+func decodeOptionalJSON(r *http.Request, v interface{}) error {
+	err := json.NewDecoder(r.Body).Decode(v)
+	if errors.Is(err, io.EOF) {
+		return nil
+	}
+	return err
+}
+
+// practiceError отвечает на ошибку пакета practice
+func practiceError(w http.ResponseWriter, handler string, err error) {
+	switch {
+	case errors.Is(err, practice.ErrNotFound):
+		http.Error(w, "Session not found", http.StatusNotFound)
+	case errors.Is(err, practice.ErrEnded):
+		http.Error(w, "Session has ended", http.StatusConflict)
+	default:
+		log.Printf("%s: %v", handler, err)
+		http.Error(w, "Database error", http.StatusInternalServerError)
+	}
+}
+
+// StartPracticeSessionHandler открывает сессию занятий (открытая прежде закрывается).
+// Урок можно не указывать, если указано упражнение.
+func StartPracticeSessionHandler(w http.ResponseWriter, r *http.Request) {
+	userID := r.Context().Value(auth.UserIDKey).(int64)
+	ctx := r.Context()
+
+	var in PracticeStartInput
+	if err := decodeOptionalJSON(r, &in); err != nil {
+		http.Error(w, "Invalid JSON", http.StatusBadRequest)
+		return
+	}
+
+	tx, err := db.Pool.Begin(ctx)
+	if err != nil {
+		log.Printf("StartPracticeSessionHandler: %v", err)
+		http.Error(w, "Database error", http.StatusInternalServerError)
+		return
+	}
+	defer tx.Rollback(ctx)
+
+	if in.ExerciseID != nil {
+		lessonID, err := exerciseLesson(ctx, tx, *in.ExerciseID)
+		if errors.Is(err, pgx.ErrNoRows) {
+			http.Error(w, "Exercise not found", http.StatusBadRequest)
+			return
+		}
+		if err != nil {
+			log.Printf("StartPracticeSessionHandler: %v", err)
+			http.Error(w, "Database error", http.StatusInternalServerError)
+			return
+		}
+		if in.LessonID != nil && *in.LessonID != lessonID {
+			http.Error(w, "Exercise does not belong to the lesson", http.StatusBadRequest)
+			return
+		}
+		in.LessonID = &lessonID
+	} else if in.LessonID != nil {
+		var exists bool
+		if err := tx.QueryRow(ctx, `SELECT EXISTS(SELECT 1 FROM lessons WHERE id = $1)`, *in.LessonID).Scan(&exists); err != nil {
+			log.Printf("StartPracticeSessionHandler: %v", err)
+			http.Error(w, "Database error", http.StatusInternalServerError)
+			return
+		}
+		if !exists {
+			http.Error(w, "Lesson not found", http.StatusBadRequest)
+			return
+		}
+	}
+
+	id, err := practice.Start(ctx, tx, userID, in.LessonID, in.ExerciseID)
+	if err != nil {
+		practiceError(w, "StartPracticeSessionHandler", err)
+		return
+	}
+	s, _, err := practice.Load(ctx, tx, userID, id)
+	if err == nil {
+		err = tx.Commit(ctx)
+	}
+	if err != nil {
+		practiceError(w, "StartPracticeSessionHandler", err)
+		return
+	}
+	writeJSON(w, http.StatusCreated, newPracticeSessionView(s, nil))
+}
+
+// PracticeHeartbeatHandler принимает сигнал "занимаюсь". Сессия, по которой
+// долго не было сигналов, закрывается (409).
+func PracticeHeartbeatHandler(w http.ResponseWriter, r *http.Request) {
+	userID := r.Context().Value(auth.UserIDKey).(int64)
+	ctx := r.Context()
+
+	sessionID, err := urlParamID(r, "id")
+	if err != nil {
+		http.Error(w, "Invalid session ID", http.StatusBadRequest)
+		return
+	}
+	var in PracticeHeartbeatInput
+	if err := decodeOptionalJSON(r, &in); err != nil {
+		http.Error(w, "Invalid JSON", http.StatusBadRequest)
+		return
+	}
+
+	tx, err := db.Pool.Begin(ctx)
+	if err != nil {
+		log.Printf("PracticeHeartbeatHandler: %v", err)
+		http.Error(w, "Database error", http.StatusInternalServerError)
+		return
+	}
+	defer tx.Rollback(ctx)
+
+	if in.ExerciseID != nil {
+		if _, err := exerciseLesson(ctx, tx, *in.ExerciseID); errors.Is(err, pgx.ErrNoRows) {
+			http.Error(w, "Exercise not found", http.StatusBadRequest)
+			return
+		} else if err != nil {
+			log.Printf("PracticeHeartbeatHandler: %v", err)
+			http.Error(w, "Database error", http.StatusInternalServerError)
+			return
+		}
+	}
+
+	err = practice.Heartbeat(ctx, tx, userID, sessionID, in.ExerciseID)
+	if errors.Is(err, practice.ErrEnded) {
+		// Закрытие сессии по простою нужно сохранить
+		if cerr := tx.Commit(ctx); cerr != nil {
+			err = cerr
+		}
+	}
+	if err != nil {
+		practiceError(w, "PracticeHeartbeatHandler", err)
+		return
+	}
+	s, _, err := practice.Load(ctx, tx, userID, sessionID)
+	if err == nil {
+		err = tx.Commit(ctx)
+	}
+	if err != nil {
+		practiceError(w, "PracticeHeartbeatHandler", err)
+		return
+	}
+	writeJSON(w, http.StatusOK, newPracticeSessionView(s, nil))
+}
+
+// EndPracticeSessionHandler закрывает сессию и возвращает её итог:
+// активное время, попытки, точность и время по упражнениям
+func EndPracticeSessionHandler(w http.ResponseWriter, r *http.Request) {
+	userID := r.Context().Value(auth.UserIDKey).(int64)
+	ctx := r.Context()
+
+	sessionID, err := urlParamID(r, "id")
+	if err != nil {
+		http.Error(w, "Invalid session ID", http.StatusBadRequest)
+		return
+	}
+
+	tx, err := db.Pool.Begin(ctx)
+	if err != nil {
+		log.Printf("EndPracticeSessionHandler: %v", err)
+		http.Error(w, "Database error", http.StatusInternalServerError)
+		return
+	}
+	defer tx.Rollback(ctx)
+
+	if err := practice.End(ctx, tx, userID, sessionID); err != nil {
+		practiceError(w, "EndPracticeSessionHandler", err)
+		return
+	}
+	// Время сессии идёт в дневную цель и серию
+	if err := awardAchievements(ctx, tx, userID); err != nil {
+		practiceError(w, "EndPracticeSessionHandler", err)
+		return
+	}
+	s, summary, err := practice.Load(ctx, tx, userID, sessionID)
+	if err == nil {
+		err = tx.Commit(ctx)
+	}
+	if err != nil {
+		practiceError(w, "EndPracticeSessionHandler", err)
+		return
+	}
+	writeJSON(w, http.StatusOK, newPracticeSessionView(s, &summary))
+}
+
+// GetPracticeSessionHandler возвращает сессию с итогом на текущий момент
+func GetPracticeSessionHandler(w http.ResponseWriter, r *http.Request) {
+	userID := r.Context().Value(auth.UserIDKey).(int64)
+
+	sessionID, err := urlParamID(r, "id")
+	if err != nil {
+		http.Error(w, "Invalid session ID", http.StatusBadRequest)
+		return
+	}
+	s, summary, err := practice.Load(r.Context(), db.Pool, userID, sessionID)
+	if err != nil {
+		practiceError(w, "GetPracticeSessionHandler", err)
+		return
+	}
+	writeJSON(w, http.StatusOK, newPracticeSessionView(s, &summary))
+}
@@ -0,0 +1,212 @@
+package handlers
+
+import (
+	"context"
+	"errors"
+	"log"
+	"net/http"
+	"time"
+
+	"sonara-space/backend/internal/auth"
+	"sonara-space/backend/internal/db"
+	"sonara-space/backend/internal/i18n"
+	"sonara-space/backend/internal/models"
+	"sonara-space/backend/internal/progress"
+
+	"github.com/jackc/pgx/v5"
+)
+
+// LastAttemptView — последняя попытка упражнения
+type LastAttemptView struct {
+	At     time.Time `json:"at"`
+	Status string    `json:"status"`
+	Score  float64   `json:"score"`
+	Passed bool      `json:"passed"`
+}
+
+// ExerciseProgressView — прогресс пользователя по упражнению урока
+type ExerciseProgressView struct {
+	ExerciseID  int64            `json:"exercise_id"`
+	Title       string           `json:"title"`
+	Type        string           `json:"type"`
+	OrderIndex  int              `json:"order_index"`
+	Status      string           `json:"status"`
+	Completed   bool             `json:"completed"`
+	Attempts    int              `json:"attempts"`
+	BestScore   float64          `json:"best_score"`
+	LastAttempt *LastAttemptView `json:"last_attempt"`
+	CompletedAt *time.Time       `json:"completed_at"`
+}
+
+// LessonProgressResponse — прогресс пользователя по уроку с разбивкой по упражнениям
+type LessonProgressResponse struct {
+	LessonID     int64                  `json:"lesson_id"`
+	LessonTitle  string                 `json:"lesson_title"`
+	Progress     LessonProgressSummary  `json:"progress"`
+	Exercises    []ExerciseProgressView `json:"exercises"`
+	NextExercise *ExerciseProgressView  `json:"next_exercise"`
+}
+
+// ProgressSummaryResponse — общий прогресс пользователя
+type ProgressSummaryResponse struct {
+	TotalLessons       int        `json:"total_lessons"`
+	CompletedLessons   int        `json:"completed_lessons"`
+	TotalExercises     int        `json:"total_exercises"`
+	CompletedExercises int        `json:"completed_exercises"`
+	Progress           float64    `json:"progress"`
+	LastActivity       *time.Time `json:"last_activity"`
+}
+
+// userLessonProgress читает прогресс пользователя по опубликованным урокам:
+// для ответа API и для подсчёта итога
+func userLessonProgress(ctx context.Context, userID int64) ([]ProgressResponse, []progress.Lesson, error) {
+	rows, err := db.Pool.Query(ctx, `
+		SELECT l.id, l.title, l.instrument,
+			COUNT(e.exercise_id),
+			COUNT(*) FILTER (WHERE p.completed),
+			MAX(p.updated_at)
+		FROM lessons l
+		LEFT JOIN user_lesson_exercises($1) e ON l.id = e.lesson_id
+		LEFT JOIN progress p ON e.exercise_id = p.exercise_id AND p.user_id = $1
+		WHERE l.status = 'published'
+		GROUP BY l.id, l.title, l.instrument
+		ORDER BY l.created_at, l.id
+	`, userID)
+	if err != nil {
+		return nil, nil, err
+	}
+	defer rows.Close()
+
+	views := []ProgressResponse{}
+	var lessons []progress.Lesson
+	for rows.Next() {
+		v := ProgressResponse{UserID: userID}
+		var l progress.Lesson
+		if err := rows.Scan(&v.LessonID, &v.LessonTitle, &l.Instrument, &v.TotalExercises,
+			&v.CompletedExercises, &v.LastActivity); err != nil {
+			return nil, nil, err
+		}
+		v.Progress = progress.Percent(v.CompletedExercises, v.TotalExercises)
+		l.ID, l.Total, l.Completed, l.LastActivity = v.LessonID, v.TotalExercises, v.CompletedExercises, v.LastActivity
+		views = append(views, v)
+		lessons = append(lessons, l)
+	}
+	return views, lessons, rows.Err()
+}
+
+// UserProgressResponse — прогресс по урокам вместе с общим итогом
+// (GET /progress?include=summary)
+type UserProgressResponse struct {
+	Lessons []ProgressResponse      `json:"lessons"`
+	Summary ProgressSummaryResponse `json:"summary"`
+}
+
+// progressSummary — общий прогресс пользователя. Уроки инструментов, которыми
+// он не занимался, в итог не входят.
+func progressSummary(lessons []progress.Lesson) ProgressSummaryResponse {
+	t := progress.Sum(progress.Started(lessons))
+	return ProgressSummaryResponse{
+		TotalLessons:       t.Lessons,
+		CompletedLessons:   t.CompletedLessons,
+		TotalExercises:     t.TotalExercises,
+		CompletedExercises: t.CompletedExercises,
+		Progress:           t.Percent,
+		LastActivity:       t.LastActivity,
+	}
+}
+
+// GetLessonProgressHandler возвращает прогресс пользователя по каждому
+// упражнению урока (в версии, которую он проходит) и первое непройденное
+func GetLessonProgressHandler(w http.ResponseWriter, r *http.Request) {
+	userID := r.Context().Value(auth.UserIDKey).(int64)
+	ctx := r.Context()
+
+	lessonID, err := urlParamID(r, "id")
+	if err != nil {
+		http.Error(w, "Invalid lesson ID", http.StatusBadRequest)
+		return
+	}
+
+	locale := requestLocale(r)
+	chain := i18n.Chain(locale)
+	var lesson models.Lesson
+	err = scanLesson(db.Pool.QueryRow(ctx, `SELECT `+localizedLessonColumns("$2")+` FROM lessons
+		WHERE id = $1 AND status = 'published'`, lessonID, chain), &lesson)
+	if errors.Is(err, pgx.ErrNoRows) {
+		http.Error(w, "Lesson not found", http.StatusNotFound)
+		return
+	}
+	if err != nil {
+		log.Printf("GetLessonProgressHandler: %v", err)
+		http.Error(w, "Database error", http.StatusInternalServerError)
+		return
+	}
+
+	rows, err := db.Pool.Query(ctx, `
+		SELECT exercises.id, `+localizedExerciseTitle("$3")+`, exercises.type, ue.order_index,
+			COALESCE(p.status, $4), COALESCE(p.completed, FALSE), COALESCE(p.attempts, 0),
+			COALESCE(p.best_score, 0)::float8, p.completed_at,
+			la.created_at, la.status, la.score::float8, la.passed
+		FROM user_lesson_exercises($2) ue
+		JOIN exercises ON exercises.id = ue.exercise_id
+		LEFT JOIN progress p ON p.exercise_id = exercises.id AND p.user_id = $2
+		LEFT JOIN LATERAL (
+			SELECT a.created_at, a.status, a.score, a.passed
+			FROM exercise_attempts a
+			WHERE a.user_id = $2 AND a.exercise_id = exercises.id
+			ORDER BY a.created_at DESC, a.id DESC
+			LIMIT 1
+		) la ON TRUE
+		WHERE ue.lesson_id = $1
+		ORDER BY ue.order_index
+	`, lessonID, userID, i18n.Preferred(chain, lesson.Locale), progress.StatusNotStarted)
+	if err != nil {
+		log.Printf("GetLessonProgressHandler: %v", err)
+		http.Error(w, "Database error", http.StatusInternalServerError)
+		return
+	}
+	defer rows.Close()
+
+	resp := LessonProgressResponse{LessonID: lesson.ID, LessonTitle: lesson.Title, Exercises: []ExerciseProgressView{}}
+	for rows.Next() {
+		var e ExerciseProgressView
+		var lastAt *time.Time
+		var lastStatus *string
+		var lastScore *float64
+		var lastPassed *bool
+		err := rows.Scan(&e.ExerciseID, &e.Title, &e.Type, &e.OrderIndex,
+			&e.Status, &e.Completed, &e.Attempts, &e.BestScore, &e.CompletedAt,
+			&lastAt, &lastStatus, &lastScore, &lastPassed)
+		if err != nil {
+			log.Printf("GetLessonProgressHandler: %v", err)
+			http.Error(w, "Database error", http.StatusInternalServerError)
+			return
+		}
+		if lastAt != nil {
+			e.LastAttempt = &LastAttemptView{At: *lastAt, Status: *lastStatus, Score: *lastScore, Passed: *lastPassed}
+			resp.Progress.LastActivity = progress.Latest(resp.Progress.LastActivity, lastAt)
+		}
+		resp.Progress.TotalExercises++
+		if e.Completed {
+			resp.Progress.CompletedExercises++
+		}
+		resp.Exercises = append(resp.Exercises, e)
+	}
+	if err := rows.Err(); err != nil {
+		log.Printf("GetLessonProgressHandler: %v", err)
+		http.Error(w, "Database error", http.StatusInternalServerError)
+		return
+	}
+
+	resp.Progress.Percent = progress.Percent(resp.Progress.CompletedExercises, resp.Progress.TotalExercises)
+	completed := make([]bool, len(resp.Exercises))
+	for i, e := range resp.Exercises {
+		completed[i] = e.Completed
+	}
+	if i := progress.Next(completed); i >= 0 {
+		next := resp.Exercises[i]
+		resp.NextExercise = &next
+	}
+	w.Header().Set("Content-Language", locale)
+	writeJSON(w, http.StatusOK, resp)
+}
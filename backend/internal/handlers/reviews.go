@@ -0,0 +1,100 @@
+package handlers
+
+import (
+	"log"
+	"net/http"
+	"time"
+
+	"sonara-space/backend/internal/auth"
+	"sonara-space/backend/internal/db"
+)
+
+// ReviewView — упражнение, которое пора повторить
+type ReviewView struct {
+	ExerciseID   int64     `json:"exercise_id"`
+	Title        string    `json:"title"`
+	Type         string    `json:"type"`
+	LessonID     int64     `json:"lesson_id"`
+	LessonTitle  string    `json:"lesson_title"`
+	BestScore    float64   `json:"best_score"`
+	Ease         float64   `json:"ease"`
+	IntervalDays int       `json:"interval_days"`
+	Repetitions  int       `json:"repetitions"`
+	DueAt        time.Time `json:"due_at"`
+	OverdueDays  int       `json:"overdue_days"`
+}
+
+// DueReviewsResponse — очередь повторений на сегодня
+type DueReviewsResponse struct {
+	Reviews []ReviewView `json:"reviews"`
+	// Total — сколько всего упражнений ждёт повторения (limit на него не влияет)
+	Total int `json:"total"`
+	// NextDueAt — ближайшее повторение после сегодняшней очереди
+	NextDueAt *time.Time `json:"next_due_at"`
+}
+
+// GetDueReviewsHandler возвращает упражнения, срок повторения которых наступил,
+// начиная с самых просроченных. Учитываются только упражнения текущих версий
+// уроков ученика.
+func GetDueReviewsHandler(w http.ResponseWriter, r *http.Request) {
+	userID := r.Context().Value(auth.UserIDKey).(int64)
+	ctx := r.Context()
+
+	limit, err := queryLimit(r, 20, 100)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	rows, err := db.Pool.Query(ctx, `
+		SELECT x.id, x.title, x.type, l.id, l.title, p.best_score,
+		       p.ease, p.interval_days, p.repetitions, p.due_at,
+		       LOCALTIMESTAMP::date - p.due_at::date,
+		       COUNT(*) OVER ()
+		FROM progress p
+		JOIN user_lesson_exercises($1) e ON e.exercise_id = p.exercise_id
+		JOIN exercises x ON x.id = p.exercise_id
+		JOIN lessons l ON l.id = e.lesson_id
+		WHERE p.user_id = $1 AND p.due_at <= LOCALTIMESTAMP AND l.status = 'published'
+		ORDER BY p.due_at, l.id, e.order_index
+		LIMIT $2
+	`, userID, limit)
+	if err != nil {
+		log.Printf("GetDueReviewsHandler: %v", err)
+		http.Error(w, "Database error", http.StatusInternalServerError)
+		return
+	}
+	defer rows.Close()
+
+	resp := DueReviewsResponse{Reviews: []ReviewView{}}
+	for rows.Next() {
+		var v ReviewView
+		if err := rows.Scan(&v.ExerciseID, &v.Title, &v.Type, &v.LessonID, &v.LessonTitle, &v.BestScore,
+			&v.Ease, &v.IntervalDays, &v.Repetitions, &v.DueAt, &v.OverdueDays, &resp.Total); err != nil {
+			log.Printf("GetDueReviewsHandler: %v", err)
+			http.Error(w, "Database error", http.StatusInternalServerError)
+			return
+		}
+		resp.Reviews = append(resp.Reviews, v)
+	}
+	if err := rows.Err(); err != nil {
+		log.Printf("GetDueReviewsHandler: %v", err)
+		http.Error(w, "Database error", http.StatusInternalServerError)
+		return
+	}
+
+	err = db.Pool.QueryRow(ctx, `
+		SELECT MIN(p.due_at)
+		FROM progress p
+		JOIN user_lesson_exercises($1) e ON e.exercise_id = p.exercise_id
+		JOIN lessons l ON l.id = e.lesson_id
+		WHERE p.user_id = $1 AND p.due_at > LOCALTIMESTAMP AND l.status = 'published'
+	`, userID).Scan(&resp.NextDueAt)
+	if err != nil {
+		log.Printf("GetDueReviewsHandler: %v", err)
+		http.Error(w, "Database error", http.StatusInternalServerError)
+		return
+	}
+
+	writeJSON(w, http.StatusOK, resp)
+}
@@ -0,0 +1,204 @@
+package handlers
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"math"
+	"mime"
+	"net/http"
+
+	"sonara-space/backend/internal/audio"
+	"sonara-space/backend/internal/db"
+	"sonara-space/backend/internal/models"
+	"sonara-space/backend/internal/rhythm"
+	"sonara-space/backend/internal/scoring"
+
+	"github.com/jackc/pgx/v5"
+)
+
+// maxRhythmOnsets — ограничение числа ударов в JSON-попытке
+const maxRhythmOnsets = 1000
+
+// RhythmView — ритмический рисунок в API. Onsets — моменты ударов в мс от
+// первой доли, CountInMs — длительность отсчёта метронома перед ней.
+type RhythmView struct {
+	Tempo         int                `json:"tempo"`
+	TimeSignature string             `json:"time_signature"`
+	BeatsPerBar   int                `json:"beats_per_bar"`
+	BeatUnit      int                `json:"beat_unit"`
+	CountIn       int                `json:"count_in"`
+	CountInMs     int                `json:"count_in_ms"`
+	TotalBeats    float64            `json:"total_beats"`
+	Pattern       []models.RhythmHit `json:"pattern"`
+	Onsets        []float64          `json:"onsets_ms"`
+	PerfectMs     int                `json:"perfect_ms"`
+	GoodMs        int                `json:"good_ms"`
+	MissMs        int                `json:"miss_ms"`
+}
+
+// RhythmInput — ритмический рисунок в запросах админ-API. Нулевые окна
+// допуска заменяются значениями по умолчанию.
+type RhythmInput struct {
+	Tempo       int                `json:"tempo"`
+	BeatsPerBar int                `json:"beats_per_bar"`
+	BeatUnit    int                `json:"beat_unit"`
+	CountIn     *int               `json:"count_in"`
+	Pattern     []models.RhythmHit `json:"pattern"`
+	PerfectMs   int                `json:"perfect_ms"`
+	GoodMs      int                `json:"good_ms"`
+	MissMs      int                `json:"miss_ms"`
+}
+
+// RhythmAttemptRequest — попытка ритмического упражнения без записи:
+// моменты ударов в мс от первой доли (клиент знает, когда закончился отсчёт)
+type RhythmAttemptRequest struct {
+	Onsets []float64 `json:"onsets_ms"`
+}
+
+func newRhythmView(r *models.Rhythm) *RhythmView {
+	msPerBeat := 60000.0 / float64(r.Tempo)
+	return &RhythmView{
+		Tempo:         r.Tempo,
+		TimeSignature: fmt.Sprintf("%d/%d", r.BeatsPerBar, r.BeatUnit),
+		BeatsPerBar:   r.BeatsPerBar,
+		BeatUnit:      r.BeatUnit,
+		CountIn:       r.CountIn,
+		CountInMs:     int(float64(r.CountIn*r.BeatsPerBar)*msPerBeat + 0.5),
+		TotalBeats:    r.TotalBeats(),
+		Pattern:       r.Pattern,
+		Onsets:        r.Onsets(),
+		PerfectMs:     r.PerfectMs,
+		GoodMs:        r.GoodMs,
+		MissMs:        r.MissMs,
+	}
+}
+
+// loadRhythms загружает ритмические рисунки для указанных упражнений
+func loadRhythms(ctx context.Context, exerciseIDs []int64) (map[int64]*models.Rhythm, error) {
+	rhythms := map[int64]*models.Rhythm{}
+	if len(exerciseIDs) == 0 {
+		return rhythms, nil
+	}
+
+	rows, err := db.Pool.Query(ctx, `
+		SELECT exercise_id, tempo, beats_per_bar, beat_unit, count_in, pattern, perfect_ms, good_ms, miss_ms
+		FROM exercise_rhythms WHERE exercise_id = ANY($1)
+	`, exerciseIDs)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		var r models.Rhythm
+		if err := rows.Scan(&r.ExerciseID, &r.Tempo, &r.BeatsPerBar, &r.BeatUnit, &r.CountIn,
+			&r.Pattern, &r.PerfectMs, &r.GoodMs, &r.MissMs); err != nil {
+			return nil, err
+		}
+		rhythms[r.ExerciseID] = &r
+	}
+	return rhythms, rows.Err()
+}
+
+// model переводит запрос в модель; отсчёт по умолчанию — один такт
+func (in *RhythmInput) model() models.Rhythm {
+	r := models.Rhythm{
+		Tempo:       in.Tempo,
+		BeatsPerBar: in.BeatsPerBar,
+		BeatUnit:    in.BeatUnit,
+		CountIn:     1,
+		Pattern:     in.Pattern,
+		PerfectMs:   in.PerfectMs,
+		GoodMs:      in.GoodMs,
+		MissMs:      in.MissMs,
+	}
+	if in.CountIn != nil {
+		r.CountIn = *in.CountIn
+	}
+	return r
+}
+
+// validate проверяет рисунок и возвращает его строковое представление
+// для колонки exercises.expected ("!1 1 0.5 0.5 r1")
+func (in *RhythmInput) validate() (string, error) {
+	r := in.model()
+	expected, err := r.Validate()
+	in.PerfectMs, in.GoodMs, in.MissMs = r.PerfectMs, r.GoodMs, r.MissMs
+	return expected, err
+}
+
+// saveRhythm создаёт или заменяет ритмический рисунок упражнения
+func saveRhythm(ctx context.Context, tx pgx.Tx, exerciseID int64, in *RhythmInput) error {
+	r := in.model()
+	_, err := tx.Exec(ctx, `
+		INSERT INTO exercise_rhythms (exercise_id, tempo, beats_per_bar, beat_unit, count_in, pattern,
+			perfect_ms, good_ms, miss_ms)
+		VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9)
+		ON CONFLICT (exercise_id) DO UPDATE SET
+			tempo = $2, beats_per_bar = $3, beat_unit = $4, count_in = $5, pattern = $6,
+			perfect_ms = $7, good_ms = $8, miss_ms = $9
+	`, exerciseID, r.Tempo, r.BeatsPerBar, r.BeatUnit, r.CountIn, r.Pattern, r.PerfectMs, r.GoodMs, r.MissMs)
+	return err
+}
+
+// isJSONRequest сообщает, что тело запроса — JSON, а не запись
+func isJSONRequest(r *http.Request) bool {
+	mediaType, _, _ := mime.ParseMediaType(r.Header.Get("Content-Type"))
+	return mediaType == "application/json"
+}
+
+// readRhythmOnsets читает JSON-попытку ритмического упражнения
+func readRhythmOnsets(w http.ResponseWriter, r *http.Request) ([]float64, error) {
+	var req RhythmAttemptRequest
+	if err := json.NewDecoder(http.MaxBytesReader(w, r.Body, 64<<10)).Decode(&req); err != nil {
+		return nil, errors.New("invalid request body")
+	}
+	if len(req.Onsets) == 0 {
+		return nil, errors.New("onsets_ms is required")
+	}
+	if len(req.Onsets) > maxRhythmOnsets {
+		return nil, errors.New("too many onsets")
+	}
+	for _, t := range req.Onsets {
+		if math.IsNaN(t) || math.IsInf(t, 0) {
+			return nil, errors.New("invalid onset")
+		}
+	}
+	return req.Onsets, nil
+}
+
+// analyzeRhythm сверяет удары (мс) с рисунком упражнения. Удары из записи
+// выравниваются по первому звуку (align): момент окончания отсчёта в записи
+// неизвестен. Удары из JSON уже отсчитаны от первой доли.
+func analyzeRhythm(ctx context.Context, exerciseID int64, played []float64, align bool) (scoring.Metrics, rhythm.Result, error) {
+	rhythms, err := loadRhythms(ctx, []int64{exerciseID})
+	if err != nil {
+		return scoring.Metrics{}, rhythm.Result{}, err
+	}
+	rh, ok := rhythms[exerciseID]
+	if !ok {
+		return scoring.Metrics{}, rhythm.Result{}, errors.New("rhythm data is missing")
+	}
+
+	expected := rh.Onsets()
+	if align {
+		played = rhythm.Align(played, expected[0])
+	}
+	res := rhythm.Match(expected, played, rhythm.Windows{
+		Perfect: float64(rh.PerfectMs),
+		Good:    float64(rh.GoodMs),
+		Miss:    float64(rh.MissMs),
+	})
+	return scoring.Metrics{Steps: res.Steps()}, res, nil
+}
+
+// recordedOnsets переводит удары, найденные в записи, в миллисекунды
+func recordedOnsets(buf *audio.Buffer) []float64 {
+	var onsets []float64
+	for _, t := range audio.Onsets(buf) {
+		onsets = append(onsets, t*1000)
+	}
+	return onsets
+}
@@ -0,0 +1,181 @@
+package handlers
+
+import (
+	"context"
+	"fmt"
+
+	"sonara-space/backend/internal/db"
+	"sonara-space/backend/internal/models"
+
+	"github.com/jackc/pgx/v5"
+)
+
+// ExerciseView — упражнение в ответе API. Для песен дополнительно
+// отдаётся структура последовательности, чтобы клиенту не приходилось
+// угадывать её по названию упражнения, для ритма — рисунок с темпом.
+type ExerciseView struct {
+	models.Exercise
+	Sequence *SequenceView `json:"sequence,omitempty"`
+	Rhythm   *RhythmView   `json:"rhythm,omitempty"`
+	Skills   []string      `json:"skills,omitempty"`
+	// Transposition — тональность песни, если ученик выбрал ?transpose=
+	Transposition *TranspositionView `json:"transposition,omitempty"`
+}
+
+// SequenceView — представление песни/мелодии в API
+type SequenceView struct {
+	SongTitle     string             `json:"song_title"`
+	StepType      string             `json:"step_type"`
+	Tempo         int                `json:"tempo"`
+	TimeSignature string             `json:"time_signature"`
+	BeatsPerBar   int                `json:"beats_per_bar"`
+	BeatUnit      int                `json:"beat_unit"`
+	TotalBeats    float64            `json:"total_beats"`
+	Steps         []SequenceStepView `json:"steps"`
+}
+
+// SequenceStepView — шаг последовательности с рассчитанным положением во времени
+type SequenceStepView struct {
+	Symbol     string  `json:"symbol"`
+	Beats      float64 `json:"beats"`
+	StartBeat  float64 `json:"start_beat"`
+	Bar        int     `json:"bar"`
+	DurationMs int     `json:"duration_ms"`
+	Lyric      *string `json:"lyric,omitempty"`
+}
+
+// SequenceInput — последовательность в запросах админ-API
+type SequenceInput struct {
+	SongTitle   string                `json:"song_title"`
+	StepType    string                `json:"step_type"`
+	Tempo       int                   `json:"tempo"`
+	BeatsPerBar int                   `json:"beats_per_bar"`
+	BeatUnit    int                   `json:"beat_unit"`
+	Steps       []models.SequenceStep `json:"steps"`
+}
+
+// NewSequenceView раскладывает шаги песни по времени: начало в долях,
+// номер такта и длительность в миллисекундах при темпе песни
+func NewSequenceView(s *models.Sequence) *SequenceView {
+	view := &SequenceView{
+		SongTitle:     s.SongTitle,
+		StepType:      s.StepType,
+		Tempo:         s.Tempo,
+		TimeSignature: fmt.Sprintf("%d/%d", s.BeatsPerBar, s.BeatUnit),
+		BeatsPerBar:   s.BeatsPerBar,
+		BeatUnit:      s.BeatUnit,
+		TotalBeats:    s.TotalBeats(),
+		Steps:         make([]SequenceStepView, 0, len(s.Steps)),
+	}
+
+	msPerBeat := 60000.0 / float64(s.Tempo)
+	var position float64
+	for _, step := range s.Steps {
+		view.Steps = append(view.Steps, SequenceStepView{
+			Symbol:     step.Symbol,
+			Beats:      step.Beats,
+			StartBeat:  position,
+			Bar:        int(position)/s.BeatsPerBar + 1,
+			DurationMs: int(step.Beats*msPerBeat + 0.5),
+			Lyric:      step.Lyric,
+		})
+		position += step.Beats
+	}
+	return view
+}
+
+// loadSequences загружает последовательности для указанных упражнений
+func loadSequences(ctx context.Context, exerciseIDs []int64) (map[int64]*models.Sequence, error) {
+	sequences := map[int64]*models.Sequence{}
+	if len(exerciseIDs) == 0 {
+		return sequences, nil
+	}
+
+	rows, err := db.Pool.Query(ctx, `
+		SELECT exercise_id, song_title, step_type, tempo, beats_per_bar, beat_unit, steps
+		FROM exercise_sequences WHERE exercise_id = ANY($1)
+	`, exerciseIDs)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		var s models.Sequence
+		if err := rows.Scan(&s.ExerciseID, &s.SongTitle, &s.StepType, &s.Tempo,
+			&s.BeatsPerBar, &s.BeatUnit, &s.Steps); err != nil {
+			return nil, err
+		}
+		sequences[s.ExerciseID] = &s
+	}
+	return sequences, rows.Err()
+}
+
+// withSequences оборачивает упражнения в ExerciseView, подставляя
+// последовательности и ритмические рисунки
+func withSequences(ctx context.Context, exercises []models.Exercise) ([]ExerciseView, error) {
+	var ids, rhythmIDs []int64
+	for _, e := range exercises {
+		switch e.Type {
+		case models.ExerciseTypeSequence:
+			ids = append(ids, e.ID)
+		case models.ExerciseTypeRhythm:
+			rhythmIDs = append(rhythmIDs, e.ID)
+		}
+	}
+	sequences, err := loadSequences(ctx, ids)
+	if err != nil {
+		return nil, err
+	}
+	rhythms, err := loadRhythms(ctx, rhythmIDs)
+	if err != nil {
+		return nil, err
+	}
+
+	return ExerciseViews(exercises, sequences, rhythms), nil
+}
+
+// ExerciseViews оборачивает упражнения в ExerciseView с их
+// последовательностями и ритмическими рисунками (по ID упражнения)
+func ExerciseViews(exercises []models.Exercise, sequences map[int64]*models.Sequence, rhythms map[int64]*models.Rhythm) []ExerciseView {
+	views := make([]ExerciseView, 0, len(exercises))
+	for _, e := range exercises {
+		view := ExerciseView{Exercise: e}
+		if s, ok := sequences[e.ID]; ok {
+			view.Sequence = NewSequenceView(s)
+		}
+		if rh, ok := rhythms[e.ID]; ok {
+			view.Rhythm = newRhythmView(rh)
+		}
+		views = append(views, view)
+	}
+	return views
+}
+
+// validate проверяет последовательность и возвращает её строковое представление
+// для колонки exercises.expected ("Am C G F")
+func (in *SequenceInput) validate() (string, error) {
+	seq := models.Sequence{
+		SongTitle:   in.SongTitle,
+		StepType:    in.StepType,
+		Tempo:       in.Tempo,
+		BeatsPerBar: in.BeatsPerBar,
+		BeatUnit:    in.BeatUnit,
+		Steps:       in.Steps,
+	}
+	expected, err := seq.Validate()
+	in.SongTitle, in.Steps = seq.SongTitle, seq.Steps
+	return expected, err
+}
+
+// saveSequence создаёт или заменяет последовательность упражнения
+func saveSequence(ctx context.Context, tx pgx.Tx, exerciseID int64, in *SequenceInput) error {
+	_, err := tx.Exec(ctx, `
+		INSERT INTO exercise_sequences (exercise_id, song_title, step_type, tempo, beats_per_bar, beat_unit, steps)
+		VALUES ($1, $2, $3, $4, $5, $6, $7)
+		ON CONFLICT (exercise_id) DO UPDATE SET
+			song_title = $2, step_type = $3, tempo = $4,
+			beats_per_bar = $5, beat_unit = $6, steps = $7
+	`, exerciseID, in.SongTitle, in.StepType, in.Tempo, in.BeatsPerBar, in.BeatUnit, in.Steps)
+	return err
+}
@@ -0,0 +1,404 @@
+package handlers
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"log"
+	"net/http"
+	"strings"
+
+	"sonara-space/backend/internal/auth"
+	"sonara-space/backend/internal/content"
+	"sonara-space/backend/internal/db"
+	"sonara-space/backend/internal/instruments"
+	"sonara-space/backend/internal/skills"
+
+	"github.com/jackc/pgx/v5"
+)
+
+// SkillView — навык в ответе API
+type SkillView struct {
+	ID          int64   `json:"id"`
+	Slug        string  `json:"slug"`
+	Name        string  `json:"name"`
+	Instrument  *string `json:"instrument"`
+	Description *string `json:"description,omitempty"`
+	Exercises   int     `json:"exercises"`
+}
+
+// SkillMasteryView — навык с освоением текущего пользователя
+type SkillMasteryView struct {
+	SkillView
+	Mastery skills.Mastery `json:"mastery"`
+}
+
+// SkillInput — тело запроса на создание/изменение навыка
+type SkillInput struct {
+	Slug        string  `json:"slug"`
+	Name        string  `json:"name"`
+	Instrument  *string `json:"instrument"`
+	Description *string `json:"description"`
+}
+
+// ExerciseSkillsInput — полный список навыков упражнения (по slug)
+type ExerciseSkillsInput struct {
+	Skills []string `json:"skills"`
+}
+
+func (in *SkillInput) validate() error {
+	in.Slug = strings.TrimSpace(in.Slug)
+	in.Name = strings.TrimSpace(in.Name)
+	if in.Slug != "" && !content.ValidSlug(in.Slug) {
+		return errors.New("invalid slug")
+	}
+	if in.Name == "" {
+		return errors.New("name is required")
+	}
+	if in.Instrument != nil && !instruments.Valid(*in.Instrument) {
+		return errors.New("invalid instrument")
+	}
+	return nil
+}
+
+// skillColumns — колонки для SkillView: число упражнений считается только
+// по текущим версиям опубликованных уроков, черновики ученику не видны
+const skillColumns = `s.id, s.slug, s.name, s.instrument, s.description,
+	(SELECT COUNT(*) FROM exercise_skills es
+		JOIN lesson_version_exercises lve ON lve.exercise_id = es.exercise_id
+		JOIN lessons l ON l.published_version_id = lve.version_id
+		WHERE es.skill_id = s.id AND l.status = 'published')`
+
+func scanSkill(row pgx.Row, s *SkillView, dest ...interface{}) error {
+	return row.Scan(append([]interface{}{&s.ID, &s.Slug, &s.Name, &s.Instrument, &s.Description, &s.Exercises}, dest...)...)
+}
+
+// querySkills возвращает навыки, подходящие инструменту (и общие); пустой instrument — все
+func querySkills(ctx context.Context, instrument string) ([]SkillView, error) {
+	rows, err := db.Pool.Query(ctx, `
+		SELECT `+skillColumns+` FROM skills s
+		WHERE $1 = '' OR s.instrument IS NULL OR s.instrument = $1
+		ORDER BY s.instrument NULLS FIRST, s.id
+	`, instrument)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	list := []SkillView{}
+	for rows.Next() {
+		var s SkillView
+		if err := scanSkill(rows, &s); err != nil {
+			return nil, err
+		}
+		list = append(list, s)
+	}
+	return list, rows.Err()
+}
+
+// GetSkillsHandler возвращает таксономию навыков (?instrument=guitar|piano)
+func GetSkillsHandler(w http.ResponseWriter, r *http.Request) {
+	list, err := querySkills(r.Context(), strings.ToLower(strings.TrimSpace(r.URL.Query().Get("instrument"))))
+	if err != nil {
+		log.Printf("GetSkillsHandler: %v", err)
+		http.Error(w, "Database error", http.StatusInternalServerError)
+		return
+	}
+	writeJSON(w, http.StatusOK, list)
+}
+
+// GetMySkillsHandler возвращает освоение навыков текущим пользователем,
+// посчитанное по таблице progress (?instrument=guitar|piano)
+func GetMySkillsHandler(w http.ResponseWriter, r *http.Request) {
+	userID := r.Context().Value(auth.UserIDKey).(int64)
+	ctx := r.Context()
+
+	list, err := querySkills(ctx, strings.ToLower(strings.TrimSpace(r.URL.Query().Get("instrument"))))
+	if err != nil {
+		log.Printf("GetMySkillsHandler: %v", err)
+		http.Error(w, "Database error", http.StatusInternalServerError)
+		return
+	}
+
+	rows, err := db.Pool.Query(ctx, `
+		SELECT es.skill_id, COALESCE(p.attempts, 0), COALESCE(p.completed, FALSE), COALESCE(p.best_score, 0)::float8
+		FROM exercise_skills es
+		JOIN user_lesson_exercises($1) e ON e.exercise_id = es.exercise_id
+		JOIN lessons l ON l.id = e.lesson_id AND l.status = 'published'
+		LEFT JOIN progress p ON p.exercise_id = e.exercise_id AND p.user_id = $1
+	`, userID)
+	if err != nil {
+		log.Printf("GetMySkillsHandler: %v", err)
+		http.Error(w, "Database error", http.StatusInternalServerError)
+		return
+	}
+	defer rows.Close()
+
+	exercises := make(map[int64][]skills.Exercise)
+	for rows.Next() {
+		var skillID int64
+		var e skills.Exercise
+		if err := rows.Scan(&skillID, &e.Attempts, &e.Completed, &e.BestScore); err != nil {
+			http.Error(w, "Database scan error", http.StatusInternalServerError)
+			return
+		}
+		exercises[skillID] = append(exercises[skillID], e)
+	}
+	if err := rows.Err(); err != nil {
+		http.Error(w, "Database rows error", http.StatusInternalServerError)
+		return
+	}
+
+	resp := make([]SkillMasteryView, 0, len(list))
+	for _, s := range list {
+		resp = append(resp, SkillMasteryView{SkillView: s, Mastery: skills.Evaluate(exercises[s.ID])})
+	}
+	writeJSON(w, http.StatusOK, resp)
+}
+
+// loadExerciseSkills возвращает slug навыков для каждого упражнения
+func loadExerciseSkills(ctx context.Context, exerciseIDs []int64) (map[int64][]string, error) {
+	result := make(map[int64][]string)
+	if len(exerciseIDs) == 0 {
+		return result, nil
+	}
+	rows, err := db.Pool.Query(ctx, `
+		SELECT es.exercise_id, s.slug FROM exercise_skills es
+		JOIN skills s ON s.id = es.skill_id
+		WHERE es.exercise_id = ANY($1)
+		ORDER BY es.exercise_id, s.slug
+	`, exerciseIDs)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	for rows.Next() {
+		var id int64
+		var slug string
+		if err := rows.Scan(&id, &slug); err != nil {
+			return nil, err
+		}
+		result[id] = append(result[id], slug)
+	}
+	return result, rows.Err()
+}
+
+// AdminCreateSkillHandler добавляет навык в таксономию
+func AdminCreateSkillHandler(w http.ResponseWriter, r *http.Request) {
+	var in SkillInput
+	if err := json.NewDecoder(r.Body).Decode(&in); err != nil {
+		http.Error(w, "Invalid JSON", http.StatusBadRequest)
+		return
+	}
+	if err := in.validate(); err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	base := content.Slugify(in.Name)
+	if base == "" {
+		base = "skill"
+	}
+	var s SkillView
+	_, err := insertWithSlug(r.Context(), db.Pool, in.Slug, base,
+		`SELECT EXISTS (SELECT 1 FROM skills WHERE slug = $1)`, nil,
+		func(tx pgx.Tx, slug string) error {
+			return scanSkill(tx.QueryRow(r.Context(), `
+				WITH s AS (
+					INSERT INTO skills (slug, name, instrument, description) VALUES ($1, $2, $3, $4)
+					RETURNING id, slug, name, instrument, description
+				)
+				SELECT s.id, s.slug, s.name, s.instrument, s.description, 0 FROM s
+			`, slug, in.Name, in.Instrument, in.Description), &s)
+		})
+	if errors.Is(err, errSlugTaken) {
+		http.Error(w, err.Error(), http.StatusConflict)
+		return
+	}
+	if err != nil {
+		log.Printf("AdminCreateSkillHandler: %v", err)
+		http.Error(w, "Database error", http.StatusInternalServerError)
+		return
+	}
+	writeJSON(w, http.StatusCreated, s)
+}
+
+// AdminUpdateSkillHandler изменяет навык; slug меняется только если передан
+func AdminUpdateSkillHandler(w http.ResponseWriter, r *http.Request) {
+	skillID, err := urlParamID(r, "id")
+	if err != nil {
+		http.Error(w, "Invalid skill ID", http.StatusBadRequest)
+		return
+	}
+
+	var in SkillInput
+	if err := json.NewDecoder(r.Body).Decode(&in); err != nil {
+		http.Error(w, "Invalid JSON", http.StatusBadRequest)
+		return
+	}
+	if err := in.validate(); err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	if in.Slug != "" {
+		_, err = pickSlug(r.Context(), db.Pool, in.Slug, "",
+			`SELECT EXISTS (SELECT 1 FROM skills WHERE slug = $1 AND id <> $2)`, skillID)
+		if errors.Is(err, errSlugTaken) {
+			http.Error(w, err.Error(), http.StatusConflict)
+			return
+		}
+		if err != nil {
+			log.Printf("AdminUpdateSkillHandler: slug: %v", err)
+			http.Error(w, "Database error", http.StatusInternalServerError)
+			return
+		}
+	}
+
+	tag, err := db.Pool.Exec(r.Context(), `
+		UPDATE skills SET name = $2, instrument = $3, description = $4,
+			slug = COALESCE(NULLIF($5, ''), slug), updated_at = NOW()
+		WHERE id = $1
+	`, skillID, in.Name, in.Instrument, in.Description, in.Slug)
+	if slugConflict(err) {
+		http.Error(w, errSlugTaken.Error(), http.StatusConflict)
+		return
+	}
+	if err != nil {
+		log.Printf("AdminUpdateSkillHandler: %v", err)
+		http.Error(w, "Database error", http.StatusInternalServerError)
+		return
+	}
+	if tag.RowsAffected() == 0 {
+		http.Error(w, "Skill not found", http.StatusNotFound)
+		return
+	}
+
+	var s SkillView
+	if err := scanSkill(db.Pool.QueryRow(r.Context(), `SELECT `+skillColumns+` FROM skills s WHERE s.id = $1`, skillID), &s); err != nil {
+		log.Printf("AdminUpdateSkillHandler: %v", err)
+		http.Error(w, "Database error", http.StatusInternalServerError)
+		return
+	}
+	writeJSON(w, http.StatusOK, s)
+}
+
+// AdminDeleteSkillHandler удаляет навык; упражнения остаются
+func AdminDeleteSkillHandler(w http.ResponseWriter, r *http.Request) {
+	skillID, err := urlParamID(r, "id")
+	if err != nil {
+		http.Error(w, "Invalid skill ID", http.StatusBadRequest)
+		return
+	}
+
+	tag, err := db.Pool.Exec(r.Context(), `DELETE FROM skills WHERE id = $1`, skillID)
+	if err != nil {
+		log.Printf("AdminDeleteSkillHandler: %v", err)
+		http.Error(w, "Database error", http.StatusInternalServerError)
+		return
+	}
+	if tag.RowsAffected() == 0 {
+		http.Error(w, "Skill not found", http.StatusNotFound)
+		return
+	}
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// AdminSetExerciseSkillsHandler заменяет список навыков упражнения
+func AdminSetExerciseSkillsHandler(w http.ResponseWriter, r *http.Request) {
+	exerciseID, err := urlParamID(r, "id")
+	if err != nil {
+		http.Error(w, "Invalid exercise ID", http.StatusBadRequest)
+		return
+	}
+
+	var in ExerciseSkillsInput
+	if err := json.NewDecoder(r.Body).Decode(&in); err != nil {
+		http.Error(w, "Invalid JSON", http.StatusBadRequest)
+		return
+	}
+	slugs := make([]string, 0, len(in.Skills))
+	seen := make(map[string]bool)
+	for _, slug := range in.Skills {
+		slug = strings.TrimSpace(slug)
+		if slug == "" || seen[slug] {
+			continue
+		}
+		seen[slug] = true
+		slugs = append(slugs, slug)
+	}
+
+	ctx := r.Context()
+	tx, err := db.Pool.Begin(ctx)
+	if err != nil {
+		log.Printf("AdminSetExerciseSkillsHandler: %v", err)
+		http.Error(w, "Database error", http.StatusInternalServerError)
+		return
+	}
+	defer tx.Rollback(ctx)
+
+	var exists bool
+	if err := tx.QueryRow(ctx, `SELECT EXISTS (SELECT 1 FROM exercises WHERE id = $1)`, exerciseID).Scan(&exists); err != nil {
+		log.Printf("AdminSetExerciseSkillsHandler: %v", err)
+		http.Error(w, "Database error", http.StatusInternalServerError)
+		return
+	}
+	if !exists {
+		http.Error(w, "Exercise not found", http.StatusNotFound)
+		return
+	}
+
+	rows, err := tx.Query(ctx, `SELECT slug FROM skills WHERE slug = ANY($1)`, slugs)
+	if err != nil {
+		log.Printf("AdminSetExerciseSkillsHandler: %v", err)
+		http.Error(w, "Database error", http.StatusInternalServerError)
+		return
+	}
+	found, err := pgx.CollectRows(rows, pgx.RowTo[string])
+	if err != nil {
+		log.Printf("AdminSetExerciseSkillsHandler: %v", err)
+		http.Error(w, "Database error", http.StatusInternalServerError)
+		return
+	}
+	if len(found) != len(slugs) {
+		known := make(map[string]bool, len(found))
+		for _, slug := range found {
+			known[slug] = true
+		}
+		for _, slug := range slugs {
+			if !known[slug] {
+				http.Error(w, "Unknown skill: "+slug, http.StatusBadRequest)
+				return
+			}
+		}
+	}
+
+	if _, err := tx.Exec(ctx, `DELETE FROM exercise_skills WHERE exercise_id = $1`, exerciseID); err != nil {
+		log.Printf("AdminSetExerciseSkillsHandler: %v", err)
+		http.Error(w, "Database error", http.StatusInternalServerError)
+		return
+	}
+	_, err = tx.Exec(ctx, `
+		INSERT INTO exercise_skills (exercise_id, skill_id)
+		SELECT $1, id FROM skills WHERE slug = ANY($2)
+	`, exerciseID, slugs)
+	if err != nil {
+		log.Printf("AdminSetExerciseSkillsHandler: %v", err)
+		http.Error(w, "Database error", http.StatusInternalServerError)
+		return
+	}
+	if err := tx.Commit(ctx); err != nil {
+		log.Printf("AdminSetExerciseSkillsHandler: commit: %v", err)
+		http.Error(w, "Database error", http.StatusInternalServerError)
+		return
+	}
+
+	writeJSON(w, http.StatusOK, ExerciseSkillsInput{Skills: slugs})
+}
+
+// skillFilter — условие каталога: урок тренирует все навыки slugs
+func skillFilter(arg func(v interface{}) string, slugs []string) string {
+	return `(SELECT COUNT(DISTINCT s.slug) FROM lesson_version_exercises lve
+		JOIN exercise_skills es ON es.exercise_id = lve.exercise_id
+		JOIN skills s ON s.id = es.skill_id
+		WHERE lve.version_id = lessons.published_version_id AND s.slug = ANY(` + arg(slugs) + `)) = ` + arg(len(slugs))
+}
@@ -0,0 +1,126 @@
+package handlers
+
+import (
+	"encoding/json"
+	"log"
+	"net/http"
+	"strings"
+
+	"sonara-space/backend/internal/auth"
+	"sonara-space/backend/internal/db"
+	"sonara-space/backend/internal/stats"
+)
+
+// StatsView — серия, опыт и дневная цель в ответе API
+type StatsView struct {
+	Timezone           string       `json:"timezone"`
+	Today              string       `json:"today"` // дата в поясе ученика, YYYY-MM-DD
+	Streak             stats.Streak `json:"streak"`
+	XP                 int          `json:"xp"`
+	Level              stats.Level  `json:"level"`
+	Goal               stats.Goal   `json:"goal"`
+	CompletedExercises int          `json:"completed_exercises"`
+}
+
+// GoalInput — тело запроса на изменение дневной цели
+type GoalInput struct {
+	Kind   string `json:"kind"`
+	Target int    `json:"target"`
+}
+
+// TimezoneInput — тело запроса на изменение часового пояса
+type TimezoneInput struct {
+	Timezone string `json:"timezone"`
+}
+
+func newStatsView(st stats.Stats) StatsView {
+	return StatsView{
+		Timezone:           st.Timezone,
+		Today:              st.Today.Format("2006-01-02"),
+		Streak:             st.Streak,
+		XP:                 st.Level.XP,
+		Level:              st.Level,
+		Goal:               st.Goal,
+		CompletedExercises: st.Completed,
+	}
+}
+
+// writeStats пересчитывает сводку ученика и отдаёт её
+func writeStats(w http.ResponseWriter, r *http.Request, userID int64, handler string) {
+	ctx := r.Context()
+	tx, err := db.Pool.Begin(ctx)
+	if err != nil {
+		log.Printf("%s: %v", handler, err)
+		http.Error(w, "Database error", http.StatusInternalServerError)
+		return
+	}
+	defer tx.Rollback(ctx)
+
+	st, err := stats.Recompute(ctx, tx, userID)
+	if err == nil {
+		err = tx.Commit(ctx)
+	}
+	if err != nil {
+		log.Printf("%s: %v", handler, err)
+		http.Error(w, "Database error", http.StatusInternalServerError)
+		return
+	}
+	writeJSON(w, http.StatusOK, newStatsView(st))
+}
+
+// GetMyStatsHandler возвращает серию дней занятий, опыт, уровень и
+// выполнение дневной цели текущего пользователя
+func GetMyStatsHandler(w http.ResponseWriter, r *http.Request) {
+	userID := r.Context().Value(auth.UserIDKey).(int64)
+	writeStats(w, r, userID, "GetMyStatsHandler")
+}
+
+// UpdateMyGoalHandler меняет дневную цель: минуты записей или число упражнений
+func UpdateMyGoalHandler(w http.ResponseWriter, r *http.Request) {
+	userID := r.Context().Value(auth.UserIDKey).(int64)
+
+	var in GoalInput
+	if err := json.NewDecoder(r.Body).Decode(&in); err != nil {
+		http.Error(w, "Invalid JSON", http.StatusBadRequest)
+		return
+	}
+	in.Kind = strings.ToLower(strings.TrimSpace(in.Kind))
+	if err := stats.ValidGoal(in.Kind, in.Target); err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	_, err := db.Pool.Exec(r.Context(), `
+		UPDATE users SET daily_goal_kind = $2, daily_goal_target = $3 WHERE id = $1
+	`, userID, in.Kind, in.Target)
+	if err != nil {
+		log.Printf("UpdateMyGoalHandler: %v", err)
+		http.Error(w, "Database error", http.StatusInternalServerError)
+		return
+	}
+	writeStats(w, r, userID, "UpdateMyGoalHandler")
+}
+
+// UpdateMyTimezoneHandler меняет часовой пояс, по которому считаются дни занятий
+func UpdateMyTimezoneHandler(w http.ResponseWriter, r *http.Request) {
+	userID := r.Context().Value(auth.UserIDKey).(int64)
+
+	var in TimezoneInput
+	if err := json.NewDecoder(r.Body).Decode(&in); err != nil {
+		http.Error(w, "Invalid JSON", http.StatusBadRequest)
+		return
+	}
+	in.Timezone = strings.TrimSpace(in.Timezone)
+	if !stats.ValidTimezone(in.Timezone) {
+		http.Error(w, "invalid timezone", http.StatusBadRequest)
+		return
+	}
+
+	_, err := db.Pool.Exec(r.Context(), `UPDATE users SET timezone = $2 WHERE id = $1`, userID, in.Timezone)
+	if err != nil {
+		log.Printf("UpdateMyTimezoneHandler: %v", err)
+		http.Error(w, "Database error", http.StatusInternalServerError)
+		return
+	}
+	writeStats(w, r, userID, "UpdateMyTimezoneHandler")
+}
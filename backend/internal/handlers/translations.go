@@ -0,0 +1,245 @@
+package handlers
+
+import (
+	"bytes"
+	"fmt"
+	"log"
+	"mime"
+	"net/http"
+	"strings"
+
+	"sonara-space/backend/internal/auth"
+	"sonara-space/backend/internal/db"
+	"sonara-space/backend/internal/i18n"
+)
+
+// maxTranslationBytes — ограничение размера импортируемого файла переводов
+const maxTranslationBytes = 10 << 20
+
+// TranslationImportResponse — итог импорта файла переводов
+type TranslationImportResponse struct {
+	TargetLocale string   `json:"target_locale"`
+	Imported     int      `json:"imported"`
+	Skipped      int      `json:"skipped"`
+	Errors       []string `json:"errors"`
+}
+
+// requestLocale выбирает язык контента: ?lang=, затем Accept-Language,
+// затем users.locale текущего пользователя
+func requestLocale(r *http.Request) string {
+	lang, accept := r.URL.Query().Get("lang"), r.Header.Get("Accept-Language")
+	if l, ok := i18n.Match(lang, accept); ok {
+		return l
+	}
+
+	var userLocale *string
+	if userID, ok := r.Context().Value(auth.UserIDKey).(int64); ok {
+		err := db.Pool.QueryRow(r.Context(), `SELECT locale FROM users WHERE id = $1`, userID).Scan(&userLocale)
+		if err != nil {
+			log.Printf("requestLocale: user %d: %v", userID, err)
+		}
+	}
+	if userLocale == nil {
+		return i18n.DefaultLocale
+	}
+	return i18n.Negotiate("", "", *userLocale)
+}
+
+// localizedLessonColumns — как lessonColumns, но название и описание берутся
+// из первого перевода по цепочке chainArg (параметр text[]), который
+// предпочтительнее исходного языка урока; иначе — исходный текст
+func localizedLessonColumns(chainArg string) string {
+	field := func(name string) string {
+		return fmt.Sprintf(`COALESCE((SELECT t.%[1]s FROM lesson_translations t
+			WHERE t.lesson_id = lessons.id AND t.%[1]s IS NOT NULL
+			  AND t.locale = ANY((%[2]s::text[])[1:COALESCE(array_position(%[2]s::text[], lessons.locale::text), cardinality(%[2]s::text[]) + 1) - 1])
+			ORDER BY array_position(%[2]s::text[], t.locale::text) LIMIT 1), lessons.%[1]s)`, name, chainArg)
+	}
+	return `id, slug, ` + field("title") + `, instrument, tuning, capo, ` + field("description") +
+		`, status, difficulty, tier, locale, tags, created_at`
+}
+
+// localizedExerciseTitle — название упражнения из первого перевода по
+// списку языков localesArg (уже обрезанному до исходного языка урока)
+func localizedExerciseTitle(localesArg string) string {
+	return fmt.Sprintf(`COALESCE((SELECT t.title FROM exercise_translations t
+		WHERE t.exercise_id = exercises.id AND t.locale = ANY(%[1]s::text[])
+		ORDER BY array_position(%[1]s::text[], t.locale::text) LIMIT 1), exercises.title)`, localesArg)
+}
+
+// AdminExportTranslationsHandler выгружает строки уроков и упражнений для
+// перевода на язык ?locale=kk в формате ?format=json (по умолчанию) или xliff.
+// ?missing=true — только строки без перевода.
+func AdminExportTranslationsHandler(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+	locale := i18n.Normalize(r.URL.Query().Get("locale"))
+	if !i18n.IsSupported(locale) {
+		http.Error(w, "Unsupported locale", http.StatusBadRequest)
+		return
+	}
+	format := r.URL.Query().Get("format")
+	if format == "" {
+		format = "json"
+	}
+	if format != "json" && format != "xliff" {
+		http.Error(w, "Unsupported format, use json or xliff", http.StatusBadRequest)
+		return
+	}
+	onlyMissing := r.URL.Query().Get("missing") == "true"
+
+	// Уроки на языке перевода переводить не нужно
+	rows, err := db.Pool.Query(ctx, `
+		SELECT k.kind, k.id, k.field, k.source_locale, k.source, COALESCE(k.target, ''), k.note
+		FROM (
+			SELECT 'lesson' AS kind, l.id, 'title' AS field, l.locale::text AS source_locale,
+				l.title AS source, t.title AS target, '' AS note, l.id AS lesson_id, 0 AS pos
+			FROM lessons l
+			LEFT JOIN lesson_translations t ON t.lesson_id = l.id AND t.locale = $1
+			WHERE l.locale <> $1
+			UNION ALL
+			SELECT 'lesson', l.id, 'description', l.locale::text, l.description, t.description, '', l.id, 1
+			FROM lessons l
+			LEFT JOIN lesson_translations t ON t.lesson_id = l.id AND t.locale = $1
+			WHERE l.locale <> $1 AND l.description IS NOT NULL AND l.description <> ''
+			UNION ALL
+			SELECT 'exercise', e.id, 'title', l.locale::text, e.title, t.title,
+				'Урок: ' || l.title || ', ожидается ' || e.expected, l.id, 2 + e.order_index
+			FROM exercises e
+			JOIN lessons l ON l.id = e.lesson_id
+			LEFT JOIN exercise_translations t ON t.exercise_id = e.id AND t.locale = $1
+			WHERE l.locale <> $1 AND e.retired_at IS NULL
+		) k
+		WHERE NOT $2 OR k.target IS NULL
+		ORDER BY k.lesson_id, k.pos, k.id
+	`, locale, onlyMissing)
+	if err != nil {
+		log.Printf("AdminExportTranslationsHandler: %v", err)
+		http.Error(w, "Database error", http.StatusInternalServerError)
+		return
+	}
+	defer rows.Close()
+
+	bundle := i18n.Bundle{TargetLocale: locale, Units: []i18n.Unit{}}
+	for rows.Next() {
+		var key i18n.Key
+		var u i18n.Unit
+		if err := rows.Scan(&key.Kind, &key.ID, &key.Field, &u.SourceLocale, &u.Source, &u.Target, &u.Note); err != nil {
+			http.Error(w, "Database scan error", http.StatusInternalServerError)
+			return
+		}
+		u.ID = key.String()
+		bundle.Units = append(bundle.Units, u)
+	}
+	if err := rows.Err(); err != nil {
+		http.Error(w, "Database rows error", http.StatusInternalServerError)
+		return
+	}
+
+	var buf bytes.Buffer
+	contentType, ext := "application/json", "json"
+	if format == "xliff" {
+		contentType, ext = "application/x-xliff+xml", "xlf"
+		err = i18n.WriteXLIFF(&buf, bundle)
+	} else {
+		err = i18n.WriteJSON(&buf, bundle)
+	}
+	if err != nil {
+		log.Printf("AdminExportTranslationsHandler: encode: %v", err)
+		http.Error(w, "Server error", http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", contentType)
+	w.Header().Set("Content-Disposition", fmt.Sprintf(`attachment; filename="translations.%s.%s"`, locale, ext))
+	w.Write(buf.Bytes())
+}
+
+// AdminImportTranslationsHandler загружает переведённый файл (JSON или XLIFF,
+// по Content-Type или ?format=). Строки с пустым переводом пропускаются,
+// остальные сохраняются в одной транзакции.
+func AdminImportTranslationsHandler(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+	r.Body = http.MaxBytesReader(w, r.Body, maxTranslationBytes)
+
+	format := r.URL.Query().Get("format")
+	if format == "" {
+		mediaType, _, _ := mime.ParseMediaType(r.Header.Get("Content-Type"))
+		if strings.Contains(mediaType, "xml") || strings.Contains(mediaType, "xliff") {
+			format = "xliff"
+		}
+	}
+
+	var bundle i18n.Bundle
+	var err error
+	if format == "xliff" {
+		bundle, err = i18n.ReadXLIFF(r.Body)
+	} else {
+		bundle, err = i18n.ReadJSON(r.Body)
+	}
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	if !i18n.IsSupported(bundle.TargetLocale) {
+		http.Error(w, "Unsupported target locale", http.StatusBadRequest)
+		return
+	}
+
+	tx, err := db.Pool.Begin(ctx)
+	if err != nil {
+		http.Error(w, "Database error", http.StatusInternalServerError)
+		return
+	}
+	defer tx.Rollback(ctx)
+
+	resp := TranslationImportResponse{TargetLocale: bundle.TargetLocale, Errors: []string{}}
+	for _, u := range bundle.Units {
+		target := strings.TrimSpace(u.Target)
+		if target == "" {
+			resp.Skipped++
+			continue
+		}
+		key, err := i18n.ParseKey(u.ID)
+		if err != nil {
+			resp.Skipped++
+			resp.Errors = append(resp.Errors, err.Error())
+			continue
+		}
+
+		// Имя колонки проверено ParseKey, поэтому его можно подставить в запрос.
+		// Переводы на исходный язык урока не сохраняем — там действует исходный текст.
+		var query string
+		if key.Kind == "lesson" {
+			query = fmt.Sprintf(`
+				INSERT INTO lesson_translations (lesson_id, locale, %[1]s)
+				SELECT id, $2, $3 FROM lessons WHERE id = $1 AND locale <> $2
+				ON CONFLICT (lesson_id, locale) DO UPDATE SET %[1]s = EXCLUDED.%[1]s, updated_at = NOW()
+			`, key.Field)
+		} else {
+			query = `
+				INSERT INTO exercise_translations (exercise_id, locale, title)
+				SELECT e.id, $2, $3 FROM exercises e JOIN lessons l ON l.id = e.lesson_id
+				WHERE e.id = $1 AND l.locale <> $2
+				ON CONFLICT (exercise_id, locale) DO UPDATE SET title = EXCLUDED.title, updated_at = NOW()
+			`
+		}
+		tag, err := tx.Exec(ctx, query, key.ID, bundle.TargetLocale, target)
+		if err != nil {
+			log.Printf("AdminImportTranslationsHandler: %s: %v", u.ID, err)
+			http.Error(w, "Database error", http.StatusInternalServerError)
+			return
+		}
+		if tag.RowsAffected() == 0 {
+			resp.Skipped++
+			resp.Errors = append(resp.Errors, fmt.Sprintf("%s: not found or already in %s", u.ID, bundle.TargetLocale))
+			continue
+		}
+		resp.Imported++
+	}
+
+	if err := tx.Commit(ctx); err != nil {
+		http.Error(w, "Database error", http.StatusInternalServerError)
+		return
+	}
+	writeJSON(w, http.StatusOK, resp)
+}
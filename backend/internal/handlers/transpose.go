@@ -0,0 +1,78 @@
+package handlers
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"strconv"
+
+	"sonara-space/backend/internal/instruments"
+	"sonara-space/backend/internal/models"
+	"sonara-space/backend/internal/transpose"
+)
+
+// TranspositionView — песня в выбранной учеником тональности
+type TranspositionView struct {
+	Semitones     int             `json:"semitones"`
+	OriginalKey   string          `json:"original_key"`
+	Key           string          `json:"key"`
+	SuggestedCapo *transpose.Capo `json:"suggested_capo,omitempty"` // для аккордов на гитаре и укулеле
+}
+
+// queryTranspose разбирает ?transpose=-11..11 — сдвиг песен в полутонах
+func queryTranspose(r *http.Request) (int, error) {
+	v := r.URL.Query().Get("transpose")
+	if v == "" {
+		return 0, nil
+	}
+	n, err := strconv.Atoi(v)
+	if err != nil {
+		return 0, errors.New("invalid transpose")
+	}
+	if n < -transpose.MaxSemitones || n > transpose.MaxSemitones {
+		return 0, transpose.ErrSemitones
+	}
+	return n, nil
+}
+
+// transposeExercises переносит песни и мелодии урока на semitones полутонов:
+// подменяет шаги и expected, добавляет тональности и рекомендацию
+// каподастра для инструмента урока
+func transposeExercises(ctx context.Context, views []ExerciseView, semitones int, setup instruments.Setup) error {
+	var ids []int64
+	for _, v := range views {
+		if v.Sequence != nil {
+			ids = append(ids, v.ID)
+		}
+	}
+	sequences, err := loadSequences(ctx, ids)
+	if err != nil {
+		return err
+	}
+	inst, tuning, setupErr := setup.Resolve()
+
+	for i := range views {
+		view := &views[i]
+		seq, ok := sequences[view.ID]
+		if !ok {
+			continue
+		}
+		res, err := transpose.Sequence(seq, semitones)
+		if err != nil {
+			return err
+		}
+		view.Sequence = NewSequenceView(&res.Sequence)
+		view.Expected = res.Expected()
+		view.Transposition = &TranspositionView{
+			Semitones:   semitones,
+			OriginalKey: res.From.String(),
+			Key:         res.To.String(),
+		}
+		if setupErr == nil && inst.MaxCapo > 0 && seq.StepType == models.ExerciseTypeChord {
+			if capo, err := transpose.SuggestCapo(inst, tuning, &res.Sequence); err == nil {
+				view.Transposition.SuggestedCapo = &capo
+			}
+		}
+	}
+	return nil
+}
@@ -0,0 +1,330 @@
+package handlers
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"io"
+	"log"
+	"net/http"
+	"time"
+
+	"sonara-space/backend/internal/auth"
+	"sonara-space/backend/internal/db"
+	"sonara-space/backend/internal/models"
+	"sonara-space/backend/internal/reviews"
+	"sonara-space/backend/internal/versions"
+
+	"github.com/jackc/pgx/v5"
+)
+
+// PublishRequest — необязательное тело публикации: комментарий к версии и
+// явные переносы прогресса с удалённых упражнений на новые
+type PublishRequest struct {
+	Note    string             `json:"note"`
+	Mapping []versions.Mapping `json:"mapping"`
+}
+
+// LessonVersionView — опубликованная версия урока
+type LessonVersionView struct {
+	ID          int64     `json:"id"`
+	Version     int       `json:"version"`
+	Note        *string   `json:"note,omitempty"`
+	PublishedBy *int64    `json:"published_by,omitempty"`
+	PublishedAt time.Time `json:"published_at"`
+	Exercises   []int64   `json:"exercise_ids"`
+}
+
+// LessonVersionState — какую версию урока видит ученик
+type LessonVersionState struct {
+	Version          int  `json:"version"`
+	Latest           int  `json:"latest"`
+	Pinned           bool `json:"pinned"`
+	UpgradeAvailable bool `json:"upgrade_available"`
+}
+
+// AdminLessonView — черновик урока для редактора и история версий
+type AdminLessonView struct {
+	models.Lesson
+	Exercises        []ExerciseView      `json:"exercises"`
+	PublishedVersion *int                `json:"published_version"`
+	DraftChanged     bool                `json:"draft_changed"`
+	Versions         []LessonVersionView `json:"versions"`
+}
+
+func newLessonVersionView(v models.LessonVersion) LessonVersionView {
+	return LessonVersionView{
+		ID:          v.ID,
+		Version:     v.Version,
+		Note:        v.Note,
+		PublishedBy: v.PublishedBy,
+		PublishedAt: v.PublishedAt,
+		Exercises:   []int64{},
+	}
+}
+
+// AdminPublishLessonHandler публикует черновик урока: фиксирует упражнения
+// в новой версии. Тело запроса (PublishRequest) необязательно.
+func AdminPublishLessonHandler(w http.ResponseWriter, r *http.Request) {
+	lessonID, err := urlParamID(r, "id")
+	if err != nil {
+		http.Error(w, "Invalid lesson ID", http.StatusBadRequest)
+		return
+	}
+	var req PublishRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil && !errors.Is(err, io.EOF) {
+		http.Error(w, "Invalid JSON", http.StatusBadRequest)
+		return
+	}
+	userID := r.Context().Value(auth.UserIDKey).(int64)
+
+	ctx := r.Context()
+	tx, err := db.Pool.Begin(ctx)
+	if err != nil {
+		log.Printf("AdminPublishLessonHandler: %v", err)
+		http.Error(w, "Database error", http.StatusInternalServerError)
+		return
+	}
+	defer tx.Rollback(ctx)
+
+	v, created, err := versions.Publish(ctx, tx, lessonID, &userID, req.Note, req.Mapping)
+	switch {
+	case errors.Is(err, versions.ErrNotFound):
+		http.Error(w, "Lesson not found", http.StatusNotFound)
+		return
+	case errors.Is(err, versions.ErrEmptyDraft):
+		http.Error(w, "Lesson has no exercises", http.StatusUnprocessableEntity)
+		return
+	case errors.Is(err, versions.ErrInvalidMapping):
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	case err != nil:
+		log.Printf("AdminPublishLessonHandler: %v", err)
+		http.Error(w, "Database error", http.StatusInternalServerError)
+		return
+	}
+	if err := tx.Commit(ctx); err != nil {
+		log.Printf("AdminPublishLessonHandler: commit: %v", err)
+		http.Error(w, "Database error", http.StatusInternalServerError)
+		return
+	}
+
+	status := http.StatusOK
+	if created {
+		status = http.StatusCreated
+	}
+	writeJSON(w, status, map[string]interface{}{
+		"id":      lessonID,
+		"status":  models.LessonStatusPublished,
+		"version": newLessonVersionView(v),
+		"created": created,
+	})
+}
+
+// AdminGetLessonHandler возвращает черновик урока (включая неопубликованные
+// упражнения) и историю версий
+func AdminGetLessonHandler(w http.ResponseWriter, r *http.Request) {
+	lessonID, err := urlParamID(r, "id")
+	if err != nil {
+		http.Error(w, "Invalid lesson ID", http.StatusBadRequest)
+		return
+	}
+	ctx := r.Context()
+
+	var view AdminLessonView
+	err = scanLesson(db.Pool.QueryRow(ctx, `SELECT `+lessonColumns+` FROM lessons WHERE id = $1`, lessonID), &view.Lesson)
+	if errors.Is(err, pgx.ErrNoRows) {
+		http.Error(w, "Lesson not found", http.StatusNotFound)
+		return
+	}
+	if err != nil {
+		log.Printf("AdminGetLessonHandler: %v", err)
+		http.Error(w, "Database error", http.StatusInternalServerError)
+		return
+	}
+
+	rows, err := db.Pool.Query(ctx, `
+		SELECT id, lesson_id, slug, title, expected, type, order_index, created_at
+		FROM exercises WHERE lesson_id = $1 AND retired_at IS NULL ORDER BY order_index, id
+	`, lessonID)
+	if err != nil {
+		log.Printf("AdminGetLessonHandler: %v", err)
+		http.Error(w, "Database error", http.StatusInternalServerError)
+		return
+	}
+	exercises, err := pgx.CollectRows(rows, func(row pgx.CollectableRow) (models.Exercise, error) {
+		var e models.Exercise
+		err := row.Scan(&e.ID, &e.LessonID, &e.Slug, &e.Title, &e.Expected, &e.Type, &e.OrderIndex, &e.CreatedAt)
+		return e, err
+	})
+	if err == nil {
+		view.Exercises, err = withSequences(ctx, exercises)
+	}
+	if err == nil {
+		view.Versions, view.PublishedVersion, err = loadLessonVersions(ctx, lessonID)
+	}
+	if err != nil {
+		log.Printf("AdminGetLessonHandler: %v", err)
+		http.Error(w, "Database error", http.StatusInternalServerError)
+		return
+	}
+
+	// Черновик отличается от опубликованной версии составом или порядком
+	view.DraftChanged = true
+	for _, v := range view.Versions {
+		if view.PublishedVersion == nil || v.Version != *view.PublishedVersion || len(v.Exercises) != len(exercises) {
+			continue
+		}
+		view.DraftChanged = false
+		for i, e := range exercises {
+			if v.Exercises[i] != e.ID {
+				view.DraftChanged = true
+				break
+			}
+		}
+	}
+
+	writeJSON(w, http.StatusOK, view)
+}
+
+// loadLessonVersions возвращает версии урока (новые первыми) и номер текущей
+func loadLessonVersions(ctx context.Context, lessonID int64) ([]LessonVersionView, *int, error) {
+	rows, err := db.Pool.Query(ctx, `
+		SELECT v.id, v.lesson_id, v.version, v.note, v.published_by, v.published_at,
+			COALESCE(array_agg(lve.exercise_id ORDER BY lve.order_index) FILTER (WHERE lve.exercise_id IS NOT NULL), '{}'),
+			COALESCE(v.id = l.published_version_id, FALSE)
+		FROM lesson_versions v
+		JOIN lessons l ON l.id = v.lesson_id
+		LEFT JOIN lesson_version_exercises lve ON lve.version_id = v.id
+		WHERE v.lesson_id = $1
+		GROUP BY v.id, l.published_version_id
+		ORDER BY v.version DESC
+	`, lessonID)
+	if err != nil {
+		return nil, nil, err
+	}
+	defer rows.Close()
+
+	list := []LessonVersionView{}
+	var current *int
+	for rows.Next() {
+		var v models.LessonVersion
+		var ids []int64
+		var isCurrent bool
+		if err := rows.Scan(&v.ID, &v.LessonID, &v.Version, &v.Note, &v.PublishedBy, &v.PublishedAt, &ids, &isCurrent); err != nil {
+			return nil, nil, err
+		}
+		view := newLessonVersionView(v)
+		view.Exercises = ids
+		if isCurrent {
+			n := v.Version
+			current = &n
+		}
+		list = append(list, view)
+	}
+	return list, current, rows.Err()
+}
+
+// lessonVersionState — версия урока, которую видит пользователь, и текущая опубликованная
+func lessonVersionState(ctx context.Context, userID, lessonID int64) (*LessonVersionState, error) {
+	var st LessonVersionState
+	var pinned *int
+	err := db.Pool.QueryRow(ctx, `
+		SELECT cur.version, pv.version
+		FROM lessons l
+		JOIN lesson_versions cur ON cur.id = l.published_version_id
+		LEFT JOIN lesson_pins p ON p.lesson_id = l.id AND p.user_id = $1
+		LEFT JOIN lesson_versions pv ON pv.id = p.version_id
+		WHERE l.id = $2
+	`, userID, lessonID).Scan(&st.Latest, &pinned)
+	if errors.Is(err, pgx.ErrNoRows) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	st.Version = st.Latest
+	if pinned != nil {
+		st.Pinned = true
+		st.Version = *pinned
+	}
+	st.UpgradeAvailable = st.Version != st.Latest
+	return &st, nil
+}
+
+// UpgradeLessonHandler переводит ученика на текущую версию урока. Прогресс
+// заменённых упражнений переносится на их новые версии (история попыток
+// копируется), прогресс удалённых упражнений остаётся в истории.
+func UpgradeLessonHandler(w http.ResponseWriter, r *http.Request) {
+	userID := r.Context().Value(auth.UserIDKey).(int64)
+	lessonID, err := urlParamID(r, "id")
+	if err != nil {
+		http.Error(w, "Invalid lesson ID", http.StatusBadRequest)
+		return
+	}
+
+	ctx := r.Context()
+	tx, err := db.Pool.Begin(ctx)
+	if err != nil {
+		log.Printf("UpgradeLessonHandler: %v", err)
+		http.Error(w, "Database error", http.StatusInternalServerError)
+		return
+	}
+	defer tx.Rollback(ctx)
+
+	up, err := versions.Upgrade(ctx, tx, userID, lessonID)
+	if errors.Is(err, versions.ErrNotFound) {
+		http.Error(w, "Lesson not found", http.StatusNotFound)
+		return
+	}
+	if err != nil {
+		log.Printf("UpgradeLessonHandler: %v", err)
+		http.Error(w, "Database error", http.StatusInternalServerError)
+		return
+	}
+
+	for _, m := range up.Moved {
+		if err := carryProgress(ctx, tx, userID, m); err != nil {
+			log.Printf("UpgradeLessonHandler: carry %d -> %d: %v", m.From, m.To, err)
+			http.Error(w, "Database error", http.StatusInternalServerError)
+			return
+		}
+	}
+	// В новой версии урок может оказаться пройденным целиком
+	if err := awardAchievements(ctx, tx, userID); err != nil {
+		log.Printf("UpgradeLessonHandler: achievements: %v", err)
+		http.Error(w, "Database error", http.StatusInternalServerError)
+		return
+	}
+	if err := tx.Commit(ctx); err != nil {
+		log.Printf("UpgradeLessonHandler: commit: %v", err)
+		http.Error(w, "Database error", http.StatusInternalServerError)
+		return
+	}
+
+	writeJSON(w, http.StatusOK, up)
+}
+
+// carryProgress копирует историю попыток со старого упражнения на новое
+// (копии помечены carried и не дают повторно опыт и дни занятий),
+// пересчитывает progress нового и переносит расписание повторений.
+// История старого упражнения не трогается.
+func carryProgress(ctx context.Context, tx pgx.Tx, userID int64, m versions.Mapping) error {
+	if _, err := tx.Exec(ctx, `SELECT pg_advisory_xact_lock($1::int, $2::int)`, userID, m.To); err != nil {
+		return err
+	}
+	_, err := tx.Exec(ctx, `
+		INSERT INTO exercise_attempts (user_id, exercise_id, source, status, passed, score,
+			detected, timing_deviation_ms, duration_ms, created_at, carried)
+		SELECT user_id, $3, source, status, passed, score, detected, timing_deviation_ms, duration_ms, created_at, TRUE
+		FROM exercise_attempts WHERE user_id = $1 AND exercise_id = $2
+		ORDER BY created_at, id
+	`, userID, m.From, m.To)
+	if err != nil {
+		return err
+	}
+	if err := refreshProgress(ctx, tx, userID, m.To); err != nil {
+		return err
+	}
+	return reviews.Carry(ctx, tx, userID, m.From, m.To)
+}
@@ -0,0 +1,162 @@
+package i18n
+
+import (
+	"encoding/json"
+	"encoding/xml"
+	"errors"
+	"fmt"
+	"io"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// Unit — одна строка для перевода. ID имеет вид "lesson.5.title",
+// "lesson.5.description" или "exercise.12.title".
+type Unit struct {
+	ID           string `json:"id"`
+	SourceLocale string `json:"source_locale"`
+	Source       string `json:"source"`
+	Target       string `json:"target"`
+	Note         string `json:"note,omitempty"`
+}
+
+// Bundle — файл переводов на один язык
+type Bundle struct {
+	TargetLocale string `json:"target_locale"`
+	Units        []Unit `json:"units"`
+}
+
+// Key — разобранный ID строки
+type Key struct {
+	Kind  string // "lesson" или "exercise"
+	ID    int64
+	Field string // "title" или "description"
+}
+
+func (k Key) String() string {
+	return fmt.Sprintf("%s.%d.%s", k.Kind, k.ID, k.Field)
+}
+
+// ParseKey разбирает ID строки и проверяет, что такое поле переводится
+func ParseKey(s string) (Key, error) {
+	parts := strings.Split(s, ".")
+	if len(parts) != 3 {
+		return Key{}, fmt.Errorf("invalid unit id %q", s)
+	}
+	id, err := strconv.ParseInt(parts[1], 10, 64)
+	if err != nil || id <= 0 {
+		return Key{}, fmt.Errorf("invalid unit id %q", s)
+	}
+	k := Key{Kind: parts[0], ID: id, Field: parts[2]}
+	switch {
+	case k.Kind == "lesson" && (k.Field == "title" || k.Field == "description"):
+	case k.Kind == "exercise" && k.Field == "title":
+	default:
+		return Key{}, fmt.Errorf("unknown unit %q", s)
+	}
+	return k, nil
+}
+
+// WriteJSON записывает файл переводов в JSON
+func WriteJSON(w io.Writer, b Bundle) error {
+	enc := json.NewEncoder(w)
+	enc.SetIndent("", "  ")
+	return enc.Encode(b)
+}
+
+// ReadJSON читает файл переводов в JSON
+func ReadJSON(r io.Reader) (Bundle, error) {
+	var b Bundle
+	if err := json.NewDecoder(r).Decode(&b); err != nil {
+		return Bundle{}, fmt.Errorf("i18n: invalid JSON: %w", err)
+	}
+	b.TargetLocale = Normalize(b.TargetLocale)
+	return b, nil
+}
+
+// XLIFF 1.2: по одному <file> на исходный язык
+type xliffDoc struct {
+	XMLName xml.Name    `xml:"urn:oasis:names:tc:xliff:document:1.2 xliff"`
+	Version string      `xml:"version,attr"`
+	Files   []xliffFile `xml:"file"`
+}
+
+type xliffFile struct {
+	Original       string      `xml:"original,attr"`
+	SourceLanguage string      `xml:"source-language,attr"`
+	TargetLanguage string      `xml:"target-language,attr"`
+	Datatype       string      `xml:"datatype,attr"`
+	Units          []xliffUnit `xml:"body>trans-unit"`
+}
+
+type xliffUnit struct {
+	ID     string `xml:"id,attr"`
+	Source string `xml:"source"`
+	Target string `xml:"target"`
+	Note   string `xml:"note,omitempty"`
+}
+
+// WriteXLIFF записывает файл переводов в формате XLIFF 1.2
+func WriteXLIFF(w io.Writer, b Bundle) error {
+	bySource := make(map[string][]xliffUnit)
+	for _, u := range b.Units {
+		bySource[u.SourceLocale] = append(bySource[u.SourceLocale],
+			xliffUnit{ID: u.ID, Source: u.Source, Target: u.Target, Note: u.Note})
+	}
+	sources := make([]string, 0, len(bySource))
+	for l := range bySource {
+		sources = append(sources, l)
+	}
+	sort.Strings(sources)
+
+	doc := xliffDoc{Version: "1.2"}
+	for _, l := range sources {
+		doc.Files = append(doc.Files, xliffFile{
+			Original:       "sonara-lessons",
+			SourceLanguage: l,
+			TargetLanguage: b.TargetLocale,
+			Datatype:       "plaintext",
+			Units:          bySource[l],
+		})
+	}
+
+	if _, err := io.WriteString(w, xml.Header); err != nil {
+		return err
+	}
+	enc := xml.NewEncoder(w)
+	enc.Indent("", "  ")
+	if err := enc.Encode(doc); err != nil {
+		return err
+	}
+	_, err := io.WriteString(w, "\n")
+	return err
+}
+
+// ReadXLIFF читает файл переводов XLIFF 1.2. Все <file> должны быть
+// переведены на один и тот же язык.
+func ReadXLIFF(r io.Reader) (Bundle, error) {
+	var doc xliffDoc
+	if err := xml.NewDecoder(r).Decode(&doc); err != nil {
+		return Bundle{}, fmt.Errorf("i18n: invalid XLIFF: %w", err)
+	}
+	var b Bundle
+	for _, f := range doc.Files {
+		target := Normalize(f.TargetLanguage)
+		if b.TargetLocale == "" {
+			b.TargetLocale = target
+		} else if target != b.TargetLocale {
+			return Bundle{}, errors.New("i18n: XLIFF files have different target languages")
+		}
+		for _, u := range f.Units {
+			b.Units = append(b.Units, Unit{
+				ID:           u.ID,
+				SourceLocale: Normalize(f.SourceLanguage),
+				Source:       u.Source,
+				Target:       u.Target,
+				Note:         u.Note,
+			})
+		}
+	}
+	return b, nil
+}
@@ -0,0 +1,136 @@
+// Package i18n выбирает язык контента и описывает файлы переводов
+// (JSON и XLIFF 1.2), с которыми переводчики работают офлайн.
+package i18n
+
+import (
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// DefaultLocale — язык, на котором написан исходный контент
+const DefaultLocale = "ru"
+
+// Supported — языки, на которые переводится контент
+var Supported = []string{"ru", "kk", "en"}
+
+// fallbacks — куда откатываться, если перевода на язык нет.
+// Казахскоязычным пользователям русский понятнее английского.
+var fallbacks = map[string][]string{
+	"kk": {"ru", "en"},
+	"ru": {"en"},
+	"en": {"ru"},
+}
+
+// Normalize приводит тег языка к базовому коду: "kk-KZ" → "kk"
+func Normalize(tag string) string {
+	tag = strings.ToLower(strings.TrimSpace(tag))
+	if i := strings.IndexAny(tag, "-_"); i >= 0 {
+		tag = tag[:i]
+	}
+	return tag
+}
+
+// IsSupported проверяет, что на язык есть переводы
+func IsSupported(locale string) bool {
+	for _, l := range Supported {
+		if l == locale {
+			return true
+		}
+	}
+	return false
+}
+
+// Chain возвращает цепочку поиска перевода: сам язык, затем запасные.
+// Для неизвестных языков цепочка заканчивается языком по умолчанию и английским.
+func Chain(locale string) []string {
+	locale = Normalize(locale)
+	chain := []string{locale}
+	next, ok := fallbacks[locale]
+	if !ok {
+		next = []string{DefaultLocale, "en"}
+	}
+	for _, l := range next {
+		if l != locale {
+			chain = append(chain, l)
+		}
+	}
+	return chain
+}
+
+// ParseAcceptLanguage разбирает заголовок Accept-Language и возвращает
+// базовые коды языков по убыванию веса q
+func ParseAcceptLanguage(header string) []string {
+	type tag struct {
+		locale string
+		q      float64
+	}
+	var tags []tag
+	for _, part := range strings.Split(header, ",") {
+		fields := strings.Split(part, ";")
+		locale := Normalize(fields[0])
+		if locale == "" || locale == "*" {
+			continue
+		}
+		q := 1.0
+		for _, f := range fields[1:] {
+			f = strings.TrimSpace(f)
+			if strings.HasPrefix(f, "q=") {
+				if v, err := strconv.ParseFloat(f[2:], 64); err == nil {
+					q = v
+				}
+			}
+		}
+		if q > 0 {
+			tags = append(tags, tag{locale, q})
+		}
+	}
+	sort.SliceStable(tags, func(i, j int) bool { return tags[i].q > tags[j].q })
+
+	var out []string
+	seen := make(map[string]bool)
+	for _, t := range tags {
+		if !seen[t.locale] {
+			seen[t.locale] = true
+			out = append(out, t.locale)
+		}
+	}
+	return out
+}
+
+// Match выбирает язык по запросу: явный параметр, затем первый
+// поддерживаемый язык из Accept-Language. ok == false, если ни один не подошёл.
+func Match(explicit, acceptLanguage string) (string, bool) {
+	if l := Normalize(explicit); IsSupported(l) {
+		return l, true
+	}
+	for _, l := range ParseAcceptLanguage(acceptLanguage) {
+		if IsSupported(l) {
+			return l, true
+		}
+	}
+	return "", false
+}
+
+// Negotiate выбирает язык ответа: явный параметр запроса, затем первый
+// поддерживаемый язык из Accept-Language, затем язык профиля пользователя.
+func Negotiate(explicit, acceptLanguage, userLocale string) string {
+	if l, ok := Match(explicit, acceptLanguage); ok {
+		return l
+	}
+	if l := Normalize(userLocale); IsSupported(l) {
+		return l
+	}
+	return DefaultLocale
+}
+
+// Preferred возвращает языки цепочки, которые предпочтительнее исходного
+// языка контента: если до него дошли, исходный текст лучше любого перевода.
+func Preferred(chain []string, source string) []string {
+	for i, l := range chain {
+		if l == source {
+			return chain[:i]
+		}
+	}
+	return chain
+}
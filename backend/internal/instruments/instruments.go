@@ -0,0 +1,299 @@
+// Package instruments — справочник инструментов: канонические ID, названия,
+// строи, звучащие диапазоны и каподастр. ID и строи совпадают с таблицами
+// instruments и instrument_tunings (миграция 22), на которые ссылаются уроки,
+// курсы и навыки.
+package instruments
+
+import (
+	"errors"
+	"fmt"
+	"math"
+	"strings"
+
+	"sonara-space/backend/internal/music"
+)
+
+// ID инструментов
+const (
+	Guitar  = "guitar"
+	Bass    = "bass"
+	Ukulele = "ukulele"
+	Piano   = "piano"
+)
+
+// Семейства инструментов
+const (
+	KindStrings = "strings" // струнные с ладами: диапазон задаёт строй
+	KindKeys    = "keys"    // клавишные: диапазон фиксирован
+)
+
+var (
+	// ErrUnknownInstrument — инструмента нет в справочнике
+	ErrUnknownInstrument = errors.New("unknown instrument")
+	// ErrUnknownTuning — у инструмента нет такого строя
+	ErrUnknownTuning = errors.New("unknown tuning")
+	// ErrCapo — каподастр не поддерживается или стоит слишком высоко
+	ErrCapo = errors.New("invalid capo")
+)
+
+// Range — звучащий диапазон в MIDI-номерах
+type Range struct {
+	Low  int `json:"low"`
+	High int `json:"high"`
+}
+
+// Contains сообщает, попадает ли MIDI-нота в диапазон
+func (r Range) Contains(midi int) bool {
+	return midi >= r.Low && midi <= r.High
+}
+
+// Frequencies возвращает границы диапазона в Гц
+func (r Range) Frequencies(a4 float64) (low, high float64) {
+	return music.MIDIToFrequency(float64(r.Low), a4), music.MIDIToFrequency(float64(r.High), a4)
+}
+
+// Outside возвращает номера нот (в научной нотации), которые не лежат в
+// диапазоне. Нераспознанные обозначения пропускаются: их проверяет валидация типа.
+func (r Range) Outside(notes []string) []int {
+	var bad []int
+	for i, s := range notes {
+		n, err := music.ParseNote(s)
+		if err == nil && !r.Contains(n.MIDI()) {
+			bad = append(bad, i)
+		}
+	}
+	return bad
+}
+
+// String возвращает диапазон нотами: "E2–E6"
+func (r Range) String() string {
+	return music.NoteFromMIDI(r.Low, false).String() + "–" + music.NoteFromMIDI(r.High, false).String()
+}
+
+// Tuning — строй струнного инструмента
+type Tuning struct {
+	ID      string
+	Names   map[string]string
+	Strings []string // открытые струны от нижней (толстой) к верхней
+}
+
+// Instrument — инструмент справочника
+type Instrument struct {
+	ID      string
+	Kind    string
+	Names   map[string]string // название по языкам
+	Aliases []string          // подстроки названий, по которым инструмент узнаётся в свободном тексте
+	Tunings []Tuning          // первый строй — строй по умолчанию
+	Frets   int               // число ладов струнного инструмента
+	MaxCapo int               // самый высокий лад для каподастра, 0 — каподастр не используется
+	Keys    Range             // диапазон клавишного инструмента
+}
+
+// registry — все инструменты в порядке показа
+var registry = []Instrument{
+	{
+		ID:      Guitar,
+		Kind:    KindStrings,
+		Names:   map[string]string{"ru": "Гитара", "en": "Guitar", "kk": "Гитара"},
+		Aliases: []string{"guitar", "гитар", "gtr"},
+		Tunings: []Tuning{
+			{ID: "standard", Names: map[string]string{"ru": "Стандартный строй", "en": "Standard", "kk": "Стандартты құрылым"},
+				Strings: []string{"E2", "A2", "D3", "G3", "B3", "E4"}},
+			{ID: "drop-d", Names: map[string]string{"ru": "Drop D", "en": "Drop D", "kk": "Drop D"},
+				Strings: []string{"D2", "A2", "D3", "G3", "B3", "E4"}},
+		},
+		Frets:   24,
+		MaxCapo: 9,
+	},
+	{
+		ID:      Bass,
+		Kind:    KindStrings,
+		Names:   map[string]string{"ru": "Бас-гитара", "en": "Bass guitar", "kk": "Бас-гитара"},
+		Aliases: []string{"bass", "бас"},
+		Tunings: []Tuning{
+			{ID: "standard", Names: map[string]string{"ru": "Стандартный строй", "en": "Standard", "kk": "Стандартты құрылым"},
+				Strings: []string{"E1", "A1", "D2", "G2"}},
+		},
+		Frets: 24,
+	},
+	{
+		ID:      Ukulele,
+		Kind:    KindStrings,
+		Names:   map[string]string{"ru": "Укулеле", "en": "Ukulele", "kk": "Укулеле"},
+		Aliases: []string{"ukulele", "укулеле", "uke"},
+		Tunings: []Tuning{
+			// Реэнтрантный строй: струна G настроена выше C
+			{ID: "gcea", Names: map[string]string{"ru": "GCEA", "en": "GCEA", "kk": "GCEA"},
+				Strings: []string{"G4", "C4", "E4", "A4"}},
+		},
+		Frets:   15,
+		MaxCapo: 7,
+	},
+	{
+		ID:      Piano,
+		Kind:    KindKeys,
+		Names:   map[string]string{"ru": "Фортепиано", "en": "Piano", "kk": "Фортепиано"},
+		Aliases: []string{"piano", "пиан", "фортепиано", "рояль", "keyboard", "pno"},
+		Keys:    Range{Low: 21, High: 108}, // A0–C8, 88 клавиш
+	},
+}
+
+// All возвращает все инструменты справочника
+func All() []Instrument {
+	return append([]Instrument(nil), registry...)
+}
+
+// Lookup ищет инструмент по каноническому ID
+func Lookup(id string) (Instrument, bool) {
+	for _, inst := range registry {
+		if inst.ID == id {
+			return inst, true
+		}
+	}
+	return Instrument{}, false
+}
+
+// Valid сообщает, есть ли инструмент в справочнике
+func Valid(id string) bool {
+	_, ok := Lookup(id)
+	return ok
+}
+
+// matchOrder — порядок проверки алиасов: "бас-гитара" и "bass guitar" —
+// бас, а не гитара
+var matchOrder = []string{Bass, Ukulele, Guitar, Piano}
+
+// FromName распознаёт инструмент в свободном тексте ("Acoustic Guitar",
+// "Бас-гитара", "Piano RH"); пустая строка — не распознан
+func FromName(name string) string {
+	name = strings.ToLower(strings.TrimSpace(name))
+	if name == "" {
+		return ""
+	}
+	for _, id := range matchOrder {
+		inst, _ := Lookup(id)
+		for _, alias := range inst.Aliases {
+			if strings.Contains(name, alias) {
+				return inst.ID
+			}
+		}
+	}
+	return ""
+}
+
+// Name возвращает название по первому языку цепочки, для которого оно есть
+func (inst Instrument) Name(chain []string) string {
+	return pickName(inst.Names, chain)
+}
+
+// Name возвращает название строя по цепочке языков
+func (t Tuning) Name(chain []string) string {
+	return pickName(t.Names, chain)
+}
+
+func pickName(names map[string]string, chain []string) string {
+	for _, l := range chain {
+		if s, ok := names[l]; ok {
+			return s
+		}
+	}
+	return names["ru"]
+}
+
+// Tuning возвращает строй по ID; пустой ID — строй по умолчанию
+func (inst Instrument) Tuning(id string) (Tuning, bool) {
+	if len(inst.Tunings) == 0 {
+		return Tuning{}, id == ""
+	}
+	if id == "" {
+		return inst.Tunings[0], true
+	}
+	for _, t := range inst.Tunings {
+		if t.ID == id {
+			return t, true
+		}
+	}
+	return Tuning{}, false
+}
+
+// OpenStrings возвращает MIDI-номера открытых струн строя
+func (t Tuning) OpenStrings() []int {
+	midi := make([]int, 0, len(t.Strings))
+	for _, s := range t.Strings {
+		if n, err := music.ParseNote(s); err == nil {
+			midi = append(midi, n.MIDI())
+		}
+	}
+	return midi
+}
+
+// Range возвращает звучащий диапазон инструмента в строе t с каподастром
+// на ладу capo: от самой низкой струны (с каподастром) до последнего лада
+// самой высокой
+func (inst Instrument) Range(t Tuning, capo int) Range {
+	if inst.Kind == KindKeys {
+		return inst.Keys
+	}
+	r := Range{Low: math.MaxInt32, High: math.MinInt32}
+	for _, m := range t.OpenStrings() {
+		r.Low = min(r.Low, m+capo)
+		r.High = max(r.High, m+inst.Frets)
+	}
+	return r
+}
+
+// Setup — инструмент урока: строй (пусто — по умолчанию) и каподастр
+type Setup struct {
+	Instrument string
+	Tuning     string
+	Capo       int
+}
+
+// Resolve проверяет настройку и возвращает инструмент и строй
+func (s Setup) Resolve() (Instrument, Tuning, error) {
+	inst, ok := Lookup(s.Instrument)
+	if !ok {
+		return Instrument{}, Tuning{}, fmt.Errorf("%w %q", ErrUnknownInstrument, s.Instrument)
+	}
+	t, ok := inst.Tuning(s.Tuning)
+	if !ok {
+		return Instrument{}, Tuning{}, fmt.Errorf("%w %q for %s", ErrUnknownTuning, s.Tuning, inst.ID)
+	}
+	if s.Capo < 0 || s.Capo > inst.MaxCapo {
+		if inst.MaxCapo == 0 {
+			return Instrument{}, Tuning{}, fmt.Errorf("%w: %s is played without a capo", ErrCapo, inst.ID)
+		}
+		return Instrument{}, Tuning{}, fmt.Errorf("%w: %s capo must be between 0 and %d", ErrCapo, inst.ID, inst.MaxCapo)
+	}
+	return inst, t, nil
+}
+
+// Validate проверяет инструмент, строй и каподастр
+func (s Setup) Validate() error {
+	_, _, err := s.Resolve()
+	return err
+}
+
+// Range возвращает звучащий диапазон настройки
+func (s Setup) Range() (Range, error) {
+	inst, t, err := s.Resolve()
+	if err != nil {
+		return Range{}, err
+	}
+	return inst.Range(t, s.Capo), nil
+}
+
+// String возвращает настройку для сообщений: "guitar (drop-d, capo 2)"
+func (s Setup) String() string {
+	var extra []string
+	if s.Tuning != "" {
+		extra = append(extra, s.Tuning)
+	}
+	if s.Capo > 0 {
+		extra = append(extra, fmt.Sprintf("capo %d", s.Capo))
+	}
+	if len(extra) == 0 {
+		return s.Instrument
+	}
+	return s.Instrument + " (" + strings.Join(extra, ", ") + ")"
+}
@@ -0,0 +1,183 @@
+// Package leaderboard — рейтинги учеников по опыту и пройденным упражнениям
+// за текущую неделю и за всё время, по инструменту и внутри группы (друзья,
+// семейная подписка). Ученики, отказавшиеся от рейтингов, в них не попадают.
+//
+// Источник правды — история попыток в Postgres: очки начисляются за первое
+// прохождение упражнения, как опыт в пакете stats. Redis (sorted sets)
+// хранит готовые рейтинги; без Redis они считаются запросом к Postgres.
+// Неделя начинается в понедельник 00:00 в поясе из настроек.
+package leaderboard
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"os"
+	"sort"
+	"strconv"
+	"time"
+
+	"github.com/jackc/pgx/v5/pgxpool"
+	"github.com/redis/go-redis/v9"
+)
+
+// Что считается в рейтинге
+const (
+	MetricXP        = "xp"        // опыт
+	MetricExercises = "exercises" // пройденные упражнения
+)
+
+// За какой срок
+const (
+	PeriodWeek = "week" // с начала текущей недели
+	PeriodAll  = "all"  // за всё время
+)
+
+// ErrQuery — неизвестная метрика или период
+var ErrQuery = errors.New("invalid leaderboard query")
+
+// Query — какой рейтинг нужен
+type Query struct {
+	Metric     string
+	Period     string
+	Instrument string  // "" — все инструменты
+	Users      []int64 // nil — все ученики, иначе только эти (участники группы)
+	Limit      int
+}
+
+// Validate проверяет метрику и период
+func (q Query) Validate() error {
+	if (q.Metric != MetricXP && q.Metric != MetricExercises) || (q.Period != PeriodWeek && q.Period != PeriodAll) {
+		return ErrQuery
+	}
+	return nil
+}
+
+// Entry — место в рейтинге
+type Entry struct {
+	Rank   int   `json:"rank"`
+	UserID int64 `json:"user_id"`
+	Score  int   `json:"score"`
+}
+
+// Board — хранилище рейтингов
+type Board interface {
+	// Top возвращает первые q.Limit мест
+	Top(ctx context.Context, q Query) ([]Entry, error)
+	// Position возвращает место ученика; без очков — место после всех, у кого они есть
+	Position(ctx context.Context, q Query, userID int64) (Entry, error)
+	// Sync обновляет очки ученика после попытки или смены настроек
+	Sync(ctx context.Context, userID int64) error
+	// Rebuild пересчитывает все рейтинги
+	Rebuild(ctx context.Context) error
+	// Location — пояс, в котором начинается неделя
+	Location() *time.Location
+}
+
+// Store — рейтинги приложения, настраиваются в main через FromEnv
+var Store Board
+
+// FromEnv создаёт рейтинги по переменным окружения:
+//
+//	LEADERBOARD_BACKEND   redis (по умолчанию, если задан REDIS_HOST) или postgres
+//	LEADERBOARD_TIMEZONE  пояс начала недели (IANA), по умолчанию UTC
+//	REDIS_HOST, REDIS_PORT (6379), REDIS_PASSWORD, REDIS_DB (0)
+//
+// Если Redis недоступен, возвращаются рейтинги на Postgres вместе с ошибкой.
+func FromEnv(pool *pgxpool.Pool) (Board, error) {
+	loc, err := time.LoadLocation(envOr("LEADERBOARD_TIMEZONE", "UTC"))
+	if err != nil {
+		return nil, fmt.Errorf("leaderboard: invalid LEADERBOARD_TIMEZONE: %w", err)
+	}
+	pg := &Postgres{Pool: pool, Loc: loc}
+
+	backend := os.Getenv("LEADERBOARD_BACKEND")
+	if backend == "" && os.Getenv("REDIS_HOST") != "" {
+		backend = "redis"
+	}
+	switch backend {
+	case "", "postgres":
+		return pg, nil
+	case "redis":
+		db, err := strconv.Atoi(envOr("REDIS_DB", "0"))
+		if err != nil {
+			return pg, fmt.Errorf("leaderboard: invalid REDIS_DB: %w", err)
+		}
+		client := redis.NewClient(&redis.Options{
+			Addr:         envOr("REDIS_HOST", "localhost") + ":" + envOr("REDIS_PORT", "6379"),
+			Password:     os.Getenv("REDIS_PASSWORD"),
+			DB:           db,
+			DialTimeout:  2 * time.Second,
+			ReadTimeout:  2 * time.Second,
+			WriteTimeout: 2 * time.Second,
+		})
+		if err := client.Ping(context.Background()).Err(); err != nil {
+			client.Close()
+			return pg, fmt.Errorf("leaderboard: redis %s: %w", client.Options().Addr, err)
+		}
+		return &Redis{Client: client, PG: pg}, nil
+	}
+	return pg, fmt.Errorf("leaderboard: unknown LEADERBOARD_BACKEND %q", backend)
+}
+
+func envOr(name, fallback string) string {
+	if v := os.Getenv(name); v != "" {
+		return v
+	}
+	return fallback
+}
+
+// WeekStart возвращает начало недели (понедельник 00:00 в поясе loc), в которую попадает t
+func WeekStart(t time.Time, loc *time.Location) time.Time {
+	t = t.In(loc)
+	day := time.Date(t.Year(), t.Month(), t.Day(), 0, 0, 0, 0, loc)
+	return day.AddDate(0, 0, -((int(day.Weekday()) + 6) % 7))
+}
+
+// Ranked сортирует очки по убыванию (равные — по ID) и расставляет места:
+// равные очки делят место, следующее пропускается (1, 2, 2, 4). Нулевые
+// очки в рейтинг не попадают.
+func Ranked(scores map[int64]int) []Entry {
+	entries := make([]Entry, 0, len(scores))
+	for id, score := range scores {
+		if score > 0 {
+			entries = append(entries, Entry{UserID: id, Score: score})
+		}
+	}
+	sort.Slice(entries, func(i, j int) bool {
+		if entries[i].Score != entries[j].Score {
+			return entries[i].Score > entries[j].Score
+		}
+		return entries[i].UserID < entries[j].UserID
+	})
+	rankFrom(entries, 1)
+	return entries
+}
+
+// rankFrom расставляет места отсортированным записям: первая — first
+func rankFrom(entries []Entry, first int) {
+	for i := range entries {
+		if i > 0 && entries[i].Score == entries[i-1].Score {
+			entries[i].Rank = entries[i-1].Rank
+		} else {
+			entries[i].Rank = first + i
+		}
+	}
+}
+
+// positionIn находит ученика в полном рейтинге; без очков — место после всех
+func positionIn(entries []Entry, userID int64) Entry {
+	for _, e := range entries {
+		if e.UserID == userID {
+			return e
+		}
+	}
+	return Entry{Rank: len(entries) + 1, UserID: userID}
+}
+
+func limit(entries []Entry, n int) []Entry {
+	if n > 0 && len(entries) > n {
+		return entries[:n]
+	}
+	return entries
+}
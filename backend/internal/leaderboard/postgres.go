@@ -0,0 +1,130 @@
+package leaderboard
+
+import (
+	"context"
+	"time"
+
+	"sonara-space/backend/internal/models"
+	"sonara-space/backend/internal/stats"
+
+	"github.com/jackc/pgx/v5/pgxpool"
+)
+
+// Postgres считает рейтинги запросом к истории попыток. Каждый запрос
+// проходит по попыткам всех учеников, поэтому это запасной вариант на
+// случай, когда Redis не настроен или недоступен.
+type Postgres struct {
+	Pool *pgxpool.Pool
+	Loc  *time.Location
+}
+
+// scoreRow — очки ученика по одному инструменту
+type scoreRow struct {
+	UserID        int64
+	Instrument    string
+	XP            int
+	Exercises     int
+	WeekXP        int
+	WeekExercises int
+}
+
+func (r scoreRow) score(metric, period string) int {
+	switch {
+	case metric == MetricXP && period == PeriodAll:
+		return r.XP
+	case metric == MetricXP:
+		return r.WeekXP
+	case period == PeriodAll:
+		return r.Exercises
+	}
+	return r.WeekExercises
+}
+
+// rows считает очки учеников (users == nil — всех) по инструментам: за всё
+// время и с начала недели weekStart. Очки дают упражнения, впервые
+// пройденные самим учеником, а не перенесённой при смене версии попыткой.
+func (p *Postgres) rows(ctx context.Context, users []int64, weekStart time.Time) ([]scoreRow, error) {
+	rows, err := p.Pool.Query(ctx, `
+		WITH firsts AS (
+			SELECT DISTINCT ON (a.user_id, a.exercise_id) a.user_id, a.exercise_id, a.carried,
+			       a.created_at AT TIME ZONE current_setting('TimeZone') AS passed_at
+			FROM exercise_attempts a
+			WHERE a.passed AND ($1::bigint[] IS NULL OR a.user_id = ANY($1))
+			ORDER BY a.user_id, a.exercise_id, a.created_at, a.id
+		), scored AS (
+			SELECT f.user_id, l.instrument, f.passed_at >= $2 AS this_week,
+			       CASE l.difficulty WHEN $3 THEN $4::int WHEN $5 THEN $6::int ELSE $7::int END AS xp
+			FROM firsts f
+			JOIN exercises x ON x.id = f.exercise_id
+			JOIN lessons l ON l.id = x.lesson_id
+			JOIN users u ON u.id = f.user_id
+			WHERE NOT f.carried AND NOT u.leaderboard_opt_out
+		)
+		SELECT user_id, instrument, SUM(xp), COUNT(*),
+		       COALESCE(SUM(xp) FILTER (WHERE this_week), 0), COUNT(*) FILTER (WHERE this_week)
+		FROM scored
+		GROUP BY user_id, instrument
+	`, users, weekStart,
+		models.DifficultyIntermediate, stats.XPFor(models.DifficultyIntermediate),
+		models.DifficultyAdvanced, stats.XPFor(models.DifficultyAdvanced),
+		stats.XPFor(models.DifficultyBeginner))
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var list []scoreRow
+	for rows.Next() {
+		var r scoreRow
+		if err := rows.Scan(&r.UserID, &r.Instrument, &r.XP, &r.Exercises, &r.WeekXP, &r.WeekExercises); err != nil {
+			return nil, err
+		}
+		list = append(list, r)
+	}
+	return list, rows.Err()
+}
+
+// ranking строит полный рейтинг по запросу
+func (p *Postgres) ranking(ctx context.Context, q Query) ([]Entry, error) {
+	if q.Users != nil && len(q.Users) == 0 {
+		return []Entry{}, nil
+	}
+	rows, err := p.rows(ctx, q.Users, WeekStart(time.Now(), p.Loc))
+	if err != nil {
+		return nil, err
+	}
+	scores := make(map[int64]int)
+	for _, r := range rows {
+		if q.Instrument == "" || r.Instrument == q.Instrument {
+			scores[r.UserID] += r.score(q.Metric, q.Period)
+		}
+	}
+	return Ranked(scores), nil
+}
+
+// Top возвращает первые q.Limit мест
+func (p *Postgres) Top(ctx context.Context, q Query) ([]Entry, error) {
+	entries, err := p.ranking(ctx, q)
+	if err != nil {
+		return nil, err
+	}
+	return limit(entries, q.Limit), nil
+}
+
+// Position возвращает место ученика
+func (p *Postgres) Position(ctx context.Context, q Query, userID int64) (Entry, error) {
+	entries, err := p.ranking(ctx, q)
+	if err != nil {
+		return Entry{}, err
+	}
+	return positionIn(entries, userID), nil
+}
+
+// Sync ничего не делает: рейтинги считаются при запросе
+func (p *Postgres) Sync(ctx context.Context, userID int64) error { return nil }
+
+// Rebuild ничего не делает: рейтинги считаются при запросе
+func (p *Postgres) Rebuild(ctx context.Context) error { return nil }
+
+// Location — пояс, в котором начинается неделя
+func (p *Postgres) Location() *time.Location { return p.Loc }
@@ -0,0 +1,313 @@
+package leaderboard
+
+import (
+	"context"
+	"errors"
+	"log"
+	"strconv"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"sonara-space/backend/internal/instruments"
+
+	"github.com/redis/go-redis/v9"
+)
+
+const (
+	keyPrefix = "leaderboard:"
+	// builtKey есть в Redis, пока рейтинги построены: после перезапуска или
+	// очистки Redis они строятся заново
+	builtKey = keyPrefix + "built"
+	// zaddChunk — сколько учеников добавлять одной командой ZADD
+	zaddChunk = 500
+	// weekTTL — сколько хранить недельный рейтинг после его начала
+	weekTTL = 14 * 24 * time.Hour
+)
+
+// errReply — Redis ответил не тем типом
+var errReply = errors.New("redis: unexpected reply")
+
+// Redis хранит рейтинги в sorted sets: ключ на каждую метрику, период и
+// инструмент, очки ученика пересчитываются по Postgres при каждой попытке
+// (Sync). Недельные ключи содержат дату начала недели, поэтому новая
+// неделя начинается с пустого рейтинга. Пока Redis недоступен, запросы
+// обслуживает Postgres, а после восстановления рейтинги строятся заново.
+type Redis struct {
+	Client *redis.Client
+	PG     *Postgres
+
+	mu    sync.Mutex  // один Rebuild за раз
+	stale atomic.Bool // какие-то обновления не дошли до Redis
+}
+
+// key — ключ рейтинга; неделя задаётся датой понедельника
+func key(metric, period, instrument string, week time.Time) string {
+	p := PeriodAll
+	if period == PeriodWeek {
+		p = PeriodWeek + ":" + week.Format("2006-01-02")
+	}
+	if instrument == "" {
+		instrument = "any"
+	}
+	return keyPrefix + p + ":" + metric + ":" + instrument
+}
+
+// scopes — инструменты, по которым ведутся рейтинги ("" — все вместе)
+func scopes() []string {
+	list := []string{""}
+	for _, inst := range instruments.All() {
+		list = append(list, inst.ID)
+	}
+	return list
+}
+
+// keyScores раскладывает очки учеников по ключам рейтингов
+func keyScores(rows []scoreRow, week time.Time) map[string]map[int64]int {
+	result := make(map[string]map[int64]int)
+	for _, r := range rows {
+		for _, inst := range []string{"", r.Instrument} {
+			for _, metric := range []string{MetricXP, MetricExercises} {
+				for _, period := range []string{PeriodAll, PeriodWeek} {
+					k := key(metric, period, inst, week)
+					if result[k] == nil {
+						result[k] = make(map[int64]int)
+					}
+					result[k][r.UserID] += r.score(metric, period)
+				}
+			}
+		}
+	}
+	return result
+}
+
+// allKeys — ключи всех рейтингов недели week
+func allKeys(week time.Time) []string {
+	var keys []string
+	for _, inst := range scopes() {
+		for _, metric := range []string{MetricXP, MetricExercises} {
+			for _, period := range []string{PeriodAll, PeriodWeek} {
+				keys = append(keys, key(metric, period, inst, week))
+			}
+		}
+	}
+	return keys
+}
+
+func expireWeek(ctx context.Context, p redis.Pipeliner, week time.Time) {
+	at := week.Add(weekTTL)
+	for _, metric := range []string{MetricXP, MetricExercises} {
+		for _, inst := range scopes() {
+			p.ExpireAt(ctx, key(metric, PeriodWeek, inst, week), at)
+		}
+	}
+}
+
+// Sync записывает очки ученика во все рейтинги; отказавшийся от рейтингов
+// ученик из них удаляется
+func (r *Redis) Sync(ctx context.Context, userID int64) error {
+	week := WeekStart(time.Now(), r.PG.Loc)
+	rows, err := r.PG.rows(ctx, []int64{userID}, week)
+	if err != nil {
+		return err
+	}
+	scores := keyScores(rows, week)
+	member := strconv.FormatInt(userID, 10)
+
+	_, err = r.Client.Pipelined(ctx, func(p redis.Pipeliner) error {
+		for _, k := range allKeys(week) {
+			if score := scores[k][userID]; score > 0 {
+				p.ZAdd(ctx, k, redis.Z{Score: float64(score), Member: member})
+			} else {
+				p.ZRem(ctx, k, member)
+			}
+		}
+		expireWeek(ctx, p, week)
+		return nil
+	})
+	if err != nil {
+		r.stale.Store(true)
+		return err
+	}
+	return nil
+}
+
+// Rebuild строит все рейтинги заново по Postgres. Каждый ключ заполняется
+// во временном и подменяется целиком, так что читатели не видят пустых рейтингов.
+func (r *Redis) Rebuild(ctx context.Context) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	week := WeekStart(time.Now(), r.PG.Loc)
+	rows, err := r.PG.rows(ctx, nil, week)
+	if err != nil {
+		return err
+	}
+	scores := keyScores(rows, week)
+	r.stale.Store(false)
+
+	_, err = r.Client.Pipelined(ctx, func(p redis.Pipeliner) error {
+		for _, k := range allKeys(week) {
+			tmp := k + ":rebuild"
+			p.Del(ctx, tmp)
+			var members []redis.Z
+			added := 0
+			for id, score := range scores[k] {
+				if score <= 0 {
+					continue
+				}
+				added++
+				members = append(members, redis.Z{Score: float64(score), Member: strconv.FormatInt(id, 10)})
+				if len(members) >= zaddChunk {
+					p.ZAdd(ctx, tmp, members...)
+					members = nil
+				}
+			}
+			if len(members) > 0 {
+				p.ZAdd(ctx, tmp, members...)
+			}
+			// RENAME пустого ключа — ошибка: пустой рейтинг просто удаляем
+			if added > 0 {
+				p.Rename(ctx, tmp, k)
+			} else {
+				p.Del(ctx, k)
+			}
+		}
+		expireWeek(ctx, p, week)
+		p.Set(ctx, builtKey, "1", 0)
+		return nil
+	})
+	if err != nil {
+		r.stale.Store(true)
+		return err
+	}
+	return nil
+}
+
+// ensure строит рейтинги, если их нет в Redis или они отстали
+func (r *Redis) ensure(ctx context.Context) error {
+	if !r.stale.Load() {
+		n, err := r.Client.Exists(ctx, builtKey).Result()
+		if err != nil {
+			return err
+		}
+		if n == 1 {
+			return nil
+		}
+	}
+	return r.Rebuild(ctx)
+}
+
+// Top возвращает первые q.Limit мест
+func (r *Redis) Top(ctx context.Context, q Query) ([]Entry, error) {
+	entries, err := r.top(ctx, q)
+	if err != nil {
+		log.Printf("leaderboard: redis: %v, using postgres", err)
+		r.stale.Store(true)
+		return r.PG.Top(ctx, q)
+	}
+	return entries, nil
+}
+
+func (r *Redis) top(ctx context.Context, q Query) ([]Entry, error) {
+	if err := r.ensure(ctx); err != nil {
+		return nil, err
+	}
+	k := key(q.Metric, q.Period, q.Instrument, WeekStart(time.Now(), r.PG.Loc))
+	if q.Users != nil {
+		entries, err := r.members(ctx, k, q.Users)
+		if err != nil {
+			return nil, err
+		}
+		return limit(entries, q.Limit), nil
+	}
+
+	stop := int64(-1)
+	if q.Limit > 0 {
+		stop = int64(q.Limit - 1)
+	}
+	items, err := r.Client.ZRevRangeWithScores(ctx, k, 0, stop).Result()
+	if err != nil {
+		return nil, err
+	}
+	entries := make([]Entry, 0, len(items))
+	for _, z := range items {
+		member, _ := z.Member.(string)
+		id, err := strconv.ParseInt(member, 10, 64)
+		if err != nil {
+			return nil, errReply
+		}
+		entries = append(entries, Entry{UserID: id, Score: int(z.Score)})
+	}
+	rankFrom(entries, 1)
+	return entries, nil
+}
+
+// Position возвращает место ученика
+func (r *Redis) Position(ctx context.Context, q Query, userID int64) (Entry, error) {
+	e, err := r.position(ctx, q, userID)
+	if err != nil {
+		log.Printf("leaderboard: redis: %v, using postgres", err)
+		r.stale.Store(true)
+		return r.PG.Position(ctx, q, userID)
+	}
+	return e, nil
+}
+
+func (r *Redis) position(ctx context.Context, q Query, userID int64) (Entry, error) {
+	if err := r.ensure(ctx); err != nil {
+		return Entry{}, err
+	}
+	k := key(q.Metric, q.Period, q.Instrument, WeekStart(time.Now(), r.PG.Loc))
+	if q.Users != nil {
+		entries, err := r.members(ctx, k, q.Users)
+		if err != nil {
+			return Entry{}, err
+		}
+		return positionIn(entries, userID), nil
+	}
+
+	e := Entry{UserID: userID}
+	score, err := r.Client.ZScore(ctx, k, strconv.FormatInt(userID, 10)).Result()
+	var n int64
+	switch {
+	case errors.Is(err, redis.Nil):
+		// Ученика нет в рейтинге: он после всех
+		n, err = r.Client.ZCard(ctx, k).Result()
+	case err == nil:
+		e.Score = int(score)
+		n, err = r.Client.ZCount(ctx, k, "("+strconv.Itoa(e.Score), "+inf").Result()
+	}
+	if err != nil {
+		return Entry{}, err
+	}
+	e.Rank = int(n) + 1
+	return e, nil
+}
+
+// members строит полный рейтинг среди users по ключу k
+func (r *Redis) members(ctx context.Context, k string, users []int64) ([]Entry, error) {
+	if len(users) == 0 {
+		return []Entry{}, nil
+	}
+	members := make([]string, len(users))
+	for i, id := range users {
+		members[i] = strconv.FormatInt(id, 10)
+	}
+	// Отсутствующим в рейтинге go-redis возвращает 0 — как и ученикам без очков
+	reply, err := r.Client.ZMScore(ctx, k, members...).Result()
+	if err != nil {
+		return nil, err
+	}
+	if len(reply) != len(users) {
+		return nil, errReply
+	}
+	scores := make(map[int64]int, len(users))
+	for i, score := range reply {
+		scores[users[i]] = int(score)
+	}
+	return Ranked(scores), nil
+}
+
+// Location — пояс, в котором начинается неделя
+func (r *Redis) Location() *time.Location { return r.PG.Loc }
@@ -0,0 +1,26 @@
+package models
+
+import (
+	"encoding/json"
+	"time"
+)
+
+// Achievement — значок; условие получения (Rule) проверяет пакет achievements
+type Achievement struct {
+	ID           int64           `db:"id"`
+	Slug         string          `db:"slug"`
+	Title        string          `db:"title"`
+	Description  string          `db:"description"`
+	Icon         *string         `db:"icon"`
+	Rule         json.RawMessage `db:"rule"`
+	OrderIndex   int             `db:"order_index"`
+	BackfilledAt *time.Time      `db:"backfilled_at"`
+	CreatedAt    time.Time       `db:"created_at"`
+}
+
+// UserAchievement — значок, полученный учеником
+type UserAchievement struct {
+	UserID        int64     `db:"user_id"`
+	AchievementID int64     `db:"achievement_id"`
+	AwardedAt     time.Time `db:"awarded_at"`
+}
@@ -0,0 +1,45 @@
+package models
+
+import (
+	"encoding/json"
+	"time"
+)
+
+// Источники оценки попытки
+const (
+	AttemptSourceServer = "server"
+	AttemptSourceClient = "client"
+	AttemptSourceLegacy = "legacy"
+)
+
+// Статусы попытки и прогресса по упражнению
+const (
+	StatusInProgress = "in_progress"
+	StatusDone       = "done"
+	StatusFailed     = "failed"
+)
+
+// ValidStatus проверяет, что статус входит в перечисление
+func ValidStatus(status string) bool {
+	switch status {
+	case StatusInProgress, StatusDone, StatusFailed:
+		return true
+	}
+	return false
+}
+
+// Attempt — одна попытка выполнить упражнение
+type Attempt struct {
+	ID                int64           `db:"id"`
+	UserID            int64           `db:"user_id"`
+	ExerciseID        int64           `db:"exercise_id"`
+	Source            string          `db:"source"`
+	Status            string          `db:"status"`
+	Passed            bool            `db:"passed"`
+	Score             float64         `db:"score"`
+	Detected          json.RawMessage `db:"detected"`
+	TimingDeviationMs *int            `db:"timing_deviation_ms"`
+	DurationMs        *int            `db:"duration_ms"`
+	Carried           bool            `db:"carried"` // копия, перенесённая на новую версию упражнения
+	CreatedAt         time.Time       `db:"created_at"`
+}
@@ -0,0 +1,28 @@
+package models
+
+import "time"
+
+// Course — курс: упорядоченные модули из уроков
+type Course struct {
+	ID          int64     `db:"id"`
+	Title       string    `db:"title"`
+	Instrument  string    `db:"instrument"`
+	Description *string   `db:"description"`
+	Status      string    `db:"status"`
+	CreatedAt   time.Time `db:"created_at"`
+}
+
+// CourseModule — модуль (раздел) курса
+type CourseModule struct {
+	ID         int64  `db:"id"`
+	CourseID   int64  `db:"course_id"`
+	Title      string `db:"title"`
+	OrderIndex int    `db:"order_index"`
+}
+
+// LessonPrerequisite — урок LessonID открывается после MinPercent% урока RequiredLessonID
+type LessonPrerequisite struct {
+	LessonID         int64 `db:"lesson_id"`
+	RequiredLessonID int64 `db:"required_lesson_id"`
+	MinPercent       int   `db:"min_percent"`
+}
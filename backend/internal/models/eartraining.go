@@ -0,0 +1,29 @@
+package models
+
+import "time"
+
+// EarTrainingSession — сессия заданий на слух. Сами задания восстанавливаются
+// пакетом eartraining по Kind, Difficulty, Seed и GeneratorVersion.
+type EarTrainingSession struct {
+	ID               int64      `db:"id"`
+	UserID           int64      `db:"user_id"`
+	Kind             string     `db:"kind"`
+	Difficulty       string     `db:"difficulty"`
+	Seed             int64      `db:"seed"`
+	GeneratorVersion int        `db:"generator_version"`
+	QuestionCount    int        `db:"question_count"`
+	Score            *float64   `db:"score"`
+	CreatedAt        time.Time  `db:"created_at"`
+	CompletedAt      *time.Time `db:"completed_at"`
+}
+
+// EarTrainingAnswer — ответ на одно задание сессии
+type EarTrainingAnswer struct {
+	SessionID     int64     `db:"session_id"`
+	QuestionIndex int       `db:"question_index"`
+	Answer        string    `db:"answer"`
+	Expected      string    `db:"expected"`
+	Correct       bool      `db:"correct"`
+	Score         float64   `db:"score"`
+	CreatedAt     time.Time `db:"created_at"`
+}
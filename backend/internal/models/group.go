@@ -0,0 +1,27 @@
+package models
+
+import "time"
+
+// Виды групп учеников
+const (
+	GroupFriends = "friends"
+	GroupFamily  = "family" // участники семейной подписки владельца
+)
+
+// Group — группа учеников со своим рейтингом; вступают по коду приглашения
+type Group struct {
+	ID             int64     `db:"id"`
+	Kind           string    `db:"kind"`
+	Name           string    `db:"name"`
+	OwnerID        int64     `db:"owner_id"`
+	SubscriptionID *int64    `db:"subscription_id"`
+	InviteCode     string    `db:"invite_code"`
+	CreatedAt      time.Time `db:"created_at"`
+}
+
+// GroupMember — участник группы (владелец тоже)
+type GroupMember struct {
+	GroupID  int64     `db:"group_id"`
+	UserID   int64     `db:"user_id"`
+	JoinedAt time.Time `db:"joined_at"`
+}
@@ -2,17 +2,46 @@ package models
 
 import "time"
 
+// Статусы публикации урока
+const (
+	LessonStatusDraft     = "draft"
+	LessonStatusPublished = "published"
+	LessonStatusArchived  = "archived" // удалён, но у учеников остался прогресс
+)
+
+// Уровни сложности урока
+const (
+	DifficultyBeginner     = "beginner"
+	DifficultyIntermediate = "intermediate"
+	DifficultyAdvanced     = "advanced"
+)
+
+// Тарифы доступа к уроку
+const (
+	TierFree    = "free"
+	TierPremium = "premium"
+)
+
 type Lesson struct {
 	ID          int64     `db:"id"`
+	Slug        string    `db:"slug"`
 	Title       string    `db:"title"`
 	Instrument  string    `db:"instrument"`
+	Tuning      *string   `db:"tuning"` // nil — строй инструмента по умолчанию
+	Capo        int       `db:"capo"`
 	Description *string   `db:"description"`
+	Status      string    `db:"status"`
+	Difficulty  string    `db:"difficulty"`
+	Tier        string    `db:"tier"`
+	Locale      string    `db:"locale"`
+	Tags        []string  `db:"tags"`
 	CreatedAt   time.Time `db:"created_at"`
 }
 
 type Exercise struct {
 	ID         int64     `db:"id"`
 	LessonID   int64     `db:"lesson_id"`
+	Slug       string    `db:"slug"`
 	Title      string    `db:"title"`
 	Expected   string    `db:"expected"`
 	Type       string    `db:"type"`
@@ -24,10 +53,29 @@ type Progress struct {
 	ID          int64      `db:"id"`
 	UserID      int64      `db:"user_id"`
 	ExerciseID  int64      `db:"exercise_id"`
+	Status      string     `db:"status"`
 	Completed   bool       `db:"completed"`
 	Attempts    int        `db:"attempts"`
 	BestScore   float64    `db:"best_score"`
+	LastScore   *float64   `db:"last_score"`
 	CompletedAt *time.Time `db:"completed_at"`
 	CreatedAt   time.Time  `db:"created_at"`
 	UpdatedAt   time.Time  `db:"updated_at"`
+
+	// Расписание повторений (см. пакет reviews)
+	Ease           float64    `db:"ease"`
+	IntervalDays   int        `db:"interval_days"`
+	Repetitions    int        `db:"repetitions"`
+	DueAt          *time.Time `db:"due_at"`
+	LastReviewedAt *time.Time `db:"last_reviewed_at"`
+}
+
+// LessonVersion — опубликованная версия урока: неизменяемый состав упражнений
+type LessonVersion struct {
+	ID          int64     `db:"id"`
+	LessonID    int64     `db:"lesson_id"`
+	Version     int       `db:"version"`
+	Note        *string   `db:"note"`
+	PublishedBy *int64    `db:"published_by"`
+	PublishedAt time.Time `db:"published_at"`
 }
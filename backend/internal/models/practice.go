@@ -0,0 +1,16 @@
+package models
+
+import "time"
+
+// PracticeSession — сессия занятий; активное время считает пакет practice
+type PracticeSession struct {
+	ID              int64      `db:"id"`
+	UserID          int64      `db:"user_id"`
+	LessonID        *int64     `db:"lesson_id"`
+	ExerciseID      *int64     `db:"exercise_id"` // текущее упражнение
+	StartedAt       time.Time  `db:"started_at"`
+	LastHeartbeatAt time.Time  `db:"last_heartbeat_at"`
+	EndedAt         *time.Time `db:"ended_at"`
+	EndReason       *string    `db:"end_reason"`
+	ActiveSeconds   int        `db:"active_seconds"`
+}
@@ -0,0 +1,99 @@
+package models
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+)
+
+// Окна допуска по умолчанию для ритмических упражнений, мс
+const (
+	DefaultPerfectMs = 30  // отклонение на слух незаметно
+	DefaultGoodMs    = 80  // заметно, но в доле
+	DefaultMissMs    = 150 // дальше удар не засчитывается
+)
+
+// RhythmHit — элемент ритмического рисунка: удар или пауза длительностью
+// Beats долей. Хранится в JSONB, поэтому у полей json-теги.
+type RhythmHit struct {
+	Beats  float64 `json:"beats"`
+	Rest   bool    `json:"rest,omitempty"`
+	Accent bool    `json:"accent,omitempty"`
+}
+
+// Rhythm описывает упражнение типа "rhythm": ритмический рисунок в темпе
+// Tempo и размере BeatsPerBar/BeatUnit. Перед рисунком звучит CountIn
+// тактов метронома. PerfectMs, GoodMs и MissMs — окна допуска по отклонению удара.
+type Rhythm struct {
+	ExerciseID  int64       `db:"exercise_id"`
+	Tempo       int         `db:"tempo"`
+	BeatsPerBar int         `db:"beats_per_bar"`
+	BeatUnit    int         `db:"beat_unit"`
+	CountIn     int         `db:"count_in"`
+	Pattern     []RhythmHit `db:"pattern"`
+	PerfectMs   int         `db:"perfect_ms"`
+	GoodMs      int         `db:"good_ms"`
+	MissMs      int         `db:"miss_ms"`
+}
+
+// Onsets возвращает моменты ударов рисунка в мс от первой доли (паузы пропускаются)
+func (r *Rhythm) Onsets() []float64 {
+	msPerBeat := 60000.0 / float64(r.Tempo)
+	var onsets []float64
+	var position float64
+	for _, hit := range r.Pattern {
+		if !hit.Rest {
+			onsets = append(onsets, position*msPerBeat)
+		}
+		position += hit.Beats
+	}
+	return onsets
+}
+
+// TotalBeats возвращает длительность рисунка в долях
+func (r *Rhythm) TotalBeats() float64 {
+	var total float64
+	for _, hit := range r.Pattern {
+		total += hit.Beats
+	}
+	return total
+}
+
+// Expected возвращает рисунок строкой для колонки exercises.expected:
+// длительности ударов в долях, паузы с префиксом "r", акценты с "!" —
+// "!1 1 0.5 0.5 r1"
+func (r *Rhythm) Expected() string {
+	parts := make([]string, len(r.Pattern))
+	for i, hit := range r.Pattern {
+		s := strconv.FormatFloat(hit.Beats, 'f', -1, 64)
+		switch {
+		case hit.Rest:
+			s = "r" + s
+		case hit.Accent:
+			s = "!" + s
+		}
+		parts[i] = s
+	}
+	return strings.Join(parts, " ")
+}
+
+// ParseRhythmPattern разбирает рисунок в записи Expected
+func ParseRhythmPattern(s string) ([]RhythmHit, error) {
+	var pattern []RhythmHit
+	for _, part := range strings.Fields(s) {
+		var hit RhythmHit
+		switch {
+		case strings.HasPrefix(part, "r"):
+			hit.Rest, part = true, part[1:]
+		case strings.HasPrefix(part, "!"):
+			hit.Accent, part = true, part[1:]
+		}
+		beats, err := strconv.ParseFloat(part, 64)
+		if err != nil {
+			return nil, fmt.Errorf("invalid rhythm duration %q", part)
+		}
+		hit.Beats = beats
+		pattern = append(pattern, hit)
+	}
+	return pattern, nil
+}
@@ -0,0 +1,48 @@
+package models
+
+// Типы упражнений
+const (
+	ExerciseTypeChord    = "chord"
+	ExerciseTypeNote     = "note"
+	ExerciseTypeSequence = "sequence"
+	ExerciseTypeRhythm   = "rhythm"
+)
+
+// SequenceStep — один шаг песни/последовательности: аккорд или нота,
+// его длительность в долях и (необязательно) слог текста под ним.
+// Хранится в JSONB, поэтому у полей json-теги.
+type SequenceStep struct {
+	Symbol string  `json:"symbol"`
+	Beats  float64 `json:"beats"`
+	Lyric  *string `json:"lyric,omitempty"`
+}
+
+// Sequence описывает упражнение типа "sequence" (песня, мелодия).
+// StepType — "chord" или "note": из чего состоит последовательность.
+type Sequence struct {
+	ExerciseID  int64          `db:"exercise_id"`
+	SongTitle   string         `db:"song_title"`
+	StepType    string         `db:"step_type"`
+	Tempo       int            `db:"tempo"`
+	BeatsPerBar int            `db:"beats_per_bar"`
+	BeatUnit    int            `db:"beat_unit"`
+	Steps       []SequenceStep `db:"steps"`
+}
+
+// Symbols возвращает аккорды/ноты последовательности по порядку
+func (s *Sequence) Symbols() []string {
+	symbols := make([]string, len(s.Steps))
+	for i, step := range s.Steps {
+		symbols[i] = step.Symbol
+	}
+	return symbols
+}
+
+// TotalBeats возвращает суммарную длительность последовательности в долях
+func (s *Sequence) TotalBeats() float64 {
+	var total float64
+	for _, step := range s.Steps {
+		total += step.Beats
+	}
+	return total
+}
@@ -0,0 +1,14 @@
+package models
+
+import "time"
+
+// Skill — навык из таксономии ("открытые аккорды", "гамма до мажор").
+// Instrument == nil — навык общий для всех инструментов.
+type Skill struct {
+	ID          int64     `db:"id"`
+	Slug        string    `db:"slug"`
+	Name        string    `db:"name"`
+	Instrument  *string   `db:"instrument"`
+	Description *string   `db:"description"`
+	CreatedAt   time.Time `db:"created_at"`
+}
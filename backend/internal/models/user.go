@@ -3,11 +3,15 @@ package models
 import "time"
 
 type User struct {
-	ID           int64     `db:"id"`
-	Email        string    `db:"email"`
-	PasswordHash string    `db:"password_hash"`
-	FirstName    *string   `db:"first_name"`
-	LastName     *string   `db:"last_name"`
-	Locale       string    `db:"locale"`
-	CreatedAt    time.Time `db:"created_at"`
+	ID                int64     `db:"id"`
+	Email             string    `db:"email"`
+	PasswordHash      string    `db:"password_hash"`
+	FirstName         *string   `db:"first_name"`
+	LastName          *string   `db:"last_name"`
+	Locale            string    `db:"locale"`
+	Timezone          string    `db:"timezone"`
+	GoalKind          string    `db:"daily_goal_kind"`
+	GoalTarget        int       `db:"daily_goal_target"`
+	LeaderboardOptOut bool      `db:"leaderboard_opt_out"` // ученик скрыт из рейтингов
+	CreatedAt         time.Time `db:"created_at"`
 }
@@ -0,0 +1,117 @@
+package models
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+
+	"sonara-space/backend/internal/music"
+)
+
+// ValidateExpected проверяет, что ожидаемое значение соответствует типу
+// упражнения ("note" — нота с октавой, "chord" — обозначение аккорда)
+func ValidateExpected(exerciseType, expected string) error {
+	switch exerciseType {
+	case ExerciseTypeNote:
+		if _, err := music.ParseNote(expected); err != nil {
+			return fmt.Errorf("expected %q is not a valid note: %v", expected, err)
+		}
+	case ExerciseTypeChord:
+		if _, err := music.ParseChord(expected); err != nil {
+			return fmt.Errorf("expected %q is not a valid chord: %v", expected, err)
+		}
+	default:
+		return fmt.Errorf("invalid exercise type %q", exerciseType)
+	}
+	return nil
+}
+
+// Validate проверяет последовательность (обрезая пробелы в названии и
+// символах) и возвращает её строковое представление для колонки
+// exercises.expected ("Am C G F")
+func (s *Sequence) Validate() (string, error) {
+	s.SongTitle = strings.TrimSpace(s.SongTitle)
+	if s.SongTitle == "" {
+		return "", errors.New("sequence.song_title is required")
+	}
+	if s.StepType != ExerciseTypeChord && s.StepType != ExerciseTypeNote {
+		return "", errors.New("sequence.step_type must be chord or note")
+	}
+	if s.Tempo <= 0 || s.Tempo > 400 {
+		return "", errors.New("sequence.tempo must be between 1 and 400")
+	}
+	if s.BeatsPerBar <= 0 {
+		return "", errors.New("sequence.beats_per_bar must be positive")
+	}
+	switch s.BeatUnit {
+	case 1, 2, 4, 8, 16:
+	default:
+		return "", errors.New("sequence.beat_unit must be a power of two up to 16")
+	}
+	if len(s.Steps) == 0 {
+		return "", errors.New("sequence.steps must not be empty")
+	}
+
+	for i := range s.Steps {
+		step := &s.Steps[i]
+		step.Symbol = strings.TrimSpace(step.Symbol)
+		if step.Beats <= 0 {
+			return "", fmt.Errorf("sequence.steps[%d].beats must be positive", i)
+		}
+		if err := ValidateExpected(s.StepType, step.Symbol); err != nil {
+			return "", fmt.Errorf("sequence.steps[%d]: %w", i, err)
+		}
+	}
+	return strings.Join(s.Symbols(), " "), nil
+}
+
+// Validate проверяет рисунок, подставляет окна допуска по умолчанию и
+// возвращает строку для колонки exercises.expected
+func (r *Rhythm) Validate() (string, error) {
+	if r.Tempo < 20 || r.Tempo > 400 {
+		return "", errors.New("rhythm.tempo must be between 20 and 400")
+	}
+	if r.BeatsPerBar <= 0 {
+		return "", errors.New("rhythm.beats_per_bar must be positive")
+	}
+	switch r.BeatUnit {
+	case 1, 2, 4, 8, 16:
+	default:
+		return "", errors.New("rhythm.beat_unit must be a power of two up to 16")
+	}
+	if r.CountIn < 0 || r.CountIn > 4 {
+		return "", errors.New("rhythm.count_in must be between 0 and 4 bars")
+	}
+	if len(r.Pattern) == 0 {
+		return "", errors.New("rhythm.pattern must not be empty")
+	}
+	hits := 0
+	for i, hit := range r.Pattern {
+		if hit.Beats <= 0 {
+			return "", fmt.Errorf("rhythm.pattern[%d].beats must be positive", i)
+		}
+		if hit.Rest && hit.Accent {
+			return "", fmt.Errorf("rhythm.pattern[%d]: a rest cannot be accented", i)
+		}
+		if !hit.Rest {
+			hits++
+		}
+	}
+	if hits == 0 {
+		return "", errors.New("rhythm.pattern must contain at least one hit")
+	}
+
+	if r.PerfectMs == 0 {
+		r.PerfectMs = DefaultPerfectMs
+	}
+	if r.GoodMs == 0 {
+		r.GoodMs = DefaultGoodMs
+	}
+	if r.MissMs == 0 {
+		r.MissMs = DefaultMissMs
+	}
+	if r.PerfectMs < 0 || r.PerfectMs > r.GoodMs || r.GoodMs > r.MissMs {
+		return "", errors.New("rhythm windows must satisfy 0 <= perfect_ms <= good_ms <= miss_ms")
+	}
+	return r.Expected(), nil
+}
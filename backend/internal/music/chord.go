@@ -0,0 +1,360 @@
+package music
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+)
+
+// Interval — интервал от основного тона: ступень (1, 3, 5, 7, 9, ...) и
+// размер в полутонах. Ступень нужна для правильного написания звуков аккорда.
+type Interval struct {
+	Degree    int
+	Semitones int
+}
+
+var (
+	root        = Interval{1, 0}
+	second      = Interval{2, 2}
+	minorThird  = Interval{3, 3}
+	majorThird  = Interval{3, 4}
+	fourth      = Interval{4, 5}
+	flatFifth   = Interval{5, 6}
+	fifth       = Interval{5, 7}
+	sharpFifth  = Interval{5, 8}
+	sixth       = Interval{6, 9}
+	dimSeventh  = Interval{7, 9}
+	minSeventh  = Interval{7, 10}
+	majSeventh  = Interval{7, 11}
+	flatNinth   = Interval{9, 13}
+	ninth       = Interval{9, 14}
+	sharpNinth  = Interval{9, 15}
+	eleventh    = Interval{11, 17}
+	sharpEleven = Interval{11, 18}
+	flatThirt   = Interval{13, 20}
+	thirteenth  = Interval{13, 21}
+)
+
+// quality — тип аккорда: каноническое обозначение и состав
+type quality struct {
+	name      string
+	intervals []Interval
+}
+
+var qualities = []quality{
+	{"", []Interval{root, majorThird, fifth}},
+	{"m", []Interval{root, minorThird, fifth}},
+	{"dim", []Interval{root, minorThird, flatFifth}},
+	{"aug", []Interval{root, majorThird, sharpFifth}},
+	{"5", []Interval{root, fifth}},
+	{"sus2", []Interval{root, second, fifth}},
+	{"sus4", []Interval{root, fourth, fifth}},
+	{"6", []Interval{root, majorThird, fifth, sixth}},
+	{"m6", []Interval{root, minorThird, fifth, sixth}},
+	{"69", []Interval{root, majorThird, fifth, sixth, ninth}},
+	{"m69", []Interval{root, minorThird, fifth, sixth, ninth}},
+	{"7", []Interval{root, majorThird, fifth, minSeventh}},
+	{"maj7", []Interval{root, majorThird, fifth, majSeventh}},
+	{"m7", []Interval{root, minorThird, fifth, minSeventh}},
+	{"mMaj7", []Interval{root, minorThird, fifth, majSeventh}},
+	{"dim7", []Interval{root, minorThird, flatFifth, dimSeventh}},
+	{"m7b5", []Interval{root, minorThird, flatFifth, minSeventh}},
+	{"aug7", []Interval{root, majorThird, sharpFifth, minSeventh}},
+	{"9", []Interval{root, majorThird, fifth, minSeventh, ninth}},
+	{"maj9", []Interval{root, majorThird, fifth, majSeventh, ninth}},
+	{"m9", []Interval{root, minorThird, fifth, minSeventh, ninth}},
+	{"11", []Interval{root, majorThird, fifth, minSeventh, ninth, eleventh}},
+	{"m11", []Interval{root, minorThird, fifth, minSeventh, ninth, eleventh}},
+	{"13", []Interval{root, majorThird, fifth, minSeventh, ninth, thirteenth}},
+	{"maj13", []Interval{root, majorThird, fifth, majSeventh, ninth, thirteenth}},
+	{"m13", []Interval{root, minorThird, fifth, minSeventh, ninth, thirteenth}},
+}
+
+// qualityAliases сопоставляет встречающиеся варианты записи каноническим
+var qualityAliases = map[string]string{
+	"": "", "maj": "", "M": "", "major": "",
+	"m": "m", "min": "m", "-": "m", "minor": "m",
+	"dim": "dim", "o": "dim", "°": "dim",
+	"aug": "aug", "+": "aug",
+	"5":    "5",
+	"sus2": "sus2", "sus4": "sus4", "sus": "sus4",
+	"6": "6", "m6": "m6", "min6": "m6",
+	"69": "69", "6/9": "69", "m69": "m69",
+	"7": "7", "dom7": "7",
+	"maj7": "maj7", "M7": "maj7", "Δ": "maj7", "Δ7": "maj7", "ma7": "maj7",
+	"m7": "m7", "min7": "m7", "-7": "m7",
+	"mMaj7": "mMaj7", "mM7": "mMaj7", "minMaj7": "mMaj7",
+	"dim7": "dim7", "o7": "dim7", "°7": "dim7",
+	"m7b5": "m7b5", "ø": "m7b5", "ø7": "m7b5", "min7b5": "m7b5", "-7b5": "m7b5",
+	"aug7": "aug7", "+7": "aug7",
+	"9": "9", "maj9": "maj9", "M9": "maj9", "Δ9": "maj9", "m9": "m9", "min9": "m9", "-9": "m9",
+	"11": "11", "m11": "m11", "min11": "m11",
+	"13": "13", "maj13": "maj13", "M13": "maj13", "m13": "m13", "min13": "m13",
+}
+
+// modifiers — добавки и альтерации, которые могут идти после типа аккорда
+var modifiers = []struct {
+	name   string
+	apply  func([]Interval) []Interval
+	symbol string
+}{
+	{"add9", addInterval(ninth), "add9"},
+	{"add2", addInterval(second), "add9"},
+	{"add11", addInterval(eleventh), "add11"},
+	{"add4", addInterval(fourth), "add11"},
+	{"add13", addInterval(thirteenth), "add13"},
+	{"add6", addInterval(sixth), "6"},
+	{"sus2", replaceDegree(3, second), "sus2"},
+	{"sus4", replaceDegree(3, fourth), "sus4"},
+	{"sus", replaceDegree(3, fourth), "sus4"},
+	{"b5", replaceDegree(5, flatFifth), "b5"},
+	{"#5", replaceDegree(5, sharpFifth), "#5"},
+	{"b9", replaceDegree(9, flatNinth), "b9"},
+	{"#9", replaceDegree(9, sharpNinth), "#9"},
+	{"#11", replaceDegree(11, sharpEleven), "#11"},
+	{"b13", replaceDegree(13, flatThirt), "b13"},
+	{"no3", removeDegree(3), "no3"},
+	{"no5", removeDegree(5), "no5"},
+}
+
+var qualityByName = map[string]quality{}
+var aliasKeys []string
+
+func init() {
+	for _, q := range qualities {
+		qualityByName[q.name] = q
+	}
+	for alias := range qualityAliases {
+		aliasKeys = append(aliasKeys, alias)
+	}
+	// Ищем самый длинный подходящий префикс: "maj7" раньше "m"
+	sort.Slice(aliasKeys, func(i, j int) bool {
+		if len(aliasKeys[i]) != len(aliasKeys[j]) {
+			return len(aliasKeys[i]) > len(aliasKeys[j])
+		}
+		return aliasKeys[i] < aliasKeys[j]
+	})
+}
+
+func addInterval(iv Interval) func([]Interval) []Interval {
+	return func(ivs []Interval) []Interval {
+		for _, existing := range ivs {
+			if existing == iv {
+				return ivs
+			}
+		}
+		return append(ivs, iv)
+	}
+}
+
+func replaceDegree(degree int, iv Interval) func([]Interval) []Interval {
+	return func(ivs []Interval) []Interval {
+		out := removeDegree(degree)(ivs)
+		return append(out, iv)
+	}
+}
+
+func removeDegree(degree int) func([]Interval) []Interval {
+	return func(ivs []Interval) []Interval {
+		out := make([]Interval, 0, len(ivs))
+		for _, existing := range ivs {
+			if existing.Degree != degree {
+				out = append(out, existing)
+			}
+		}
+		return out
+	}
+}
+
+// Chord — разобранное обозначение аккорда
+type Chord struct {
+	Root      PitchName
+	Quality   string
+	Modifiers []string
+	Intervals []Interval
+	Bass      *PitchName
+}
+
+// ParseChord разбирает буквенное обозначение аккорда: трезвучия (C, Am, Bdim,
+// Caug), септаккорды (G7, Cmaj7, Am7, Bm7b5, Cdim7), sus-аккорды (Dsus4,
+// A7sus4), расширения и альтерации (C9, Dm11, G13, C7b9, Cadd9) и аккорды
+// с басом (C/G, Am/E).
+func ParseChord(s string) (Chord, error) {
+	symbol := strings.TrimSpace(s)
+	if symbol == "" {
+		return Chord{}, fmt.Errorf("music: empty chord symbol")
+	}
+
+	rootName, rest, err := parsePitchPrefix(symbol)
+	if err != nil {
+		return Chord{}, err
+	}
+	c := Chord{Root: rootName}
+
+	// Бас после последней косой черты, если после неё стоит нота (6/9 — не бас)
+	if i := strings.LastIndex(rest, "/"); i >= 0 {
+		if bass, err := ParsePitchName(rest[i+1:]); err == nil {
+			c.Bass = &bass
+			rest = rest[:i]
+		}
+	}
+
+	rest = strings.NewReplacer("(", "", ")", "", ",", "").Replace(rest)
+	// "m(maj7)" после удаления скобок превращается в "mmaj7"
+	rest = strings.Replace(rest, "mmaj7", "mMaj7", 1)
+
+	// Пустой псевдоним (мажор) стоит последним и подходит всегда
+	for _, alias := range aliasKeys {
+		if strings.HasPrefix(rest, alias) {
+			c.Quality = qualityAliases[alias]
+			rest = rest[len(alias):]
+			break
+		}
+	}
+	q := qualityByName[c.Quality]
+	c.Intervals = append([]Interval(nil), q.intervals...)
+
+	for rest != "" {
+		found := false
+		for _, m := range modifiers {
+			if strings.HasPrefix(rest, m.name) {
+				c.Intervals = m.apply(c.Intervals)
+				c.Modifiers = append(c.Modifiers, m.symbol)
+				rest = rest[len(m.name):]
+				found = true
+				break
+			}
+		}
+		if !found {
+			return Chord{}, fmt.Errorf("music: unknown chord suffix %q in %q", rest, s)
+		}
+	}
+
+	sort.Slice(c.Intervals, func(i, j int) bool {
+		return c.Intervals[i].Semitones < c.Intervals[j].Semitones
+	})
+	return c, nil
+}
+
+// PitchClasses возвращает множество звуковысотных классов аккорда (вместе с басом)
+func (c Chord) PitchClasses() PitchClassSet {
+	var set PitchClassSet
+	rootPC := c.Root.PitchClass()
+	for _, iv := range c.Intervals {
+		set = set.Add(rootPC.Transpose(iv.Semitones))
+	}
+	if c.Bass != nil {
+		set = set.Add(c.Bass.PitchClass())
+	}
+	return set
+}
+
+// Tones возвращает звуки аккорда с правильным написанием, начиная с основного
+// тона: для Am — A, C, E; для Bb7 — Bb, D, F, Ab.
+func (c Chord) Tones() []PitchName {
+	tones := make([]PitchName, 0, len(c.Intervals))
+	rootPC := c.Root.PitchClass()
+	for _, iv := range c.Intervals {
+		letter := letterAt(c.Root.Letter, iv.Degree-1)
+		tones = append(tones, spell(letter, rootPC.Transpose(iv.Semitones)))
+	}
+	return tones
+}
+
+// String возвращает каноническую запись аккорда: "Am", "Cmaj7", "G7sus4", "C/G"
+func (c Chord) String() string {
+	var b strings.Builder
+	b.WriteString(c.Root.String())
+	b.WriteString(c.Quality)
+	for _, m := range c.Modifiers {
+		b.WriteString(m)
+	}
+	if c.Bass != nil {
+		b.WriteString("/")
+		b.WriteString(c.Bass.String())
+	}
+	return b.String()
+}
+
+// Canonical возвращает аккорд с каноническим написанием основного тона и баса
+func (c Chord) Canonical() Chord {
+	out := c
+	out.Root = c.Root.Canonical()
+	if c.Bass != nil {
+		bass := c.Bass.Canonical()
+		out.Bass = &bass
+	}
+	return out
+}
+
+// Equivalent сообщает, обозначают ли два аккорда одно и то же звучание
+// с точностью до энгармонизма: C#m и Dbm, Cadd2 и Cadd9.
+func (c Chord) Equivalent(o Chord) bool {
+	if c.Root.PitchClass() != o.Root.PitchClass() || c.PitchClasses() != o.PitchClasses() {
+		return false
+	}
+	cb, ob := c.Root.PitchClass(), o.Root.PitchClass()
+	if c.Bass != nil {
+		cb = c.Bass.PitchClass()
+	}
+	if o.Bass != nil {
+		ob = o.Bass.PitchClass()
+	}
+	return cb == ob
+}
+
+// Transpose сдвигает аккорд на указанное число полутонов
+func (c Chord) Transpose(semitones int, preferFlats bool) Chord {
+	out := c
+	out.Root, _ = ParsePitchName(c.Root.PitchClass().Transpose(semitones).Name(preferFlats))
+	if c.Bass != nil {
+		bass, _ := ParsePitchName(c.Bass.PitchClass().Transpose(semitones).Name(preferFlats))
+		out.Bass = &bass
+	}
+	out.Intervals = append([]Interval(nil), c.Intervals...)
+	out.Modifiers = append([]string(nil), c.Modifiers...)
+	return out
+}
+
+// IdentifyChord называет аккорд по звучащим нотам (MIDI-номерам): C4 E4 G4 —
+// "C", E3 G3 C4 — "C/E". Сначала ищется аккорд с основным тоном в басу,
+// затем обращение. Подходят только точные совпадения состава; для двух и
+// меньше разных звуков аккорд не определяется.
+func IdentifyChord(midi []int, preferFlats bool) (Chord, bool) {
+	if len(midi) == 0 {
+		return Chord{}, false
+	}
+	var set PitchClassSet
+	lowest := midi[0]
+	for _, m := range midi {
+		set = set.Add(PitchClass(mod12(m)))
+		if m < lowest {
+			lowest = m
+		}
+	}
+	if set.Len() < 3 {
+		return Chord{}, false
+	}
+	bass := PitchClass(mod12(lowest))
+
+	roots := []PitchClass{bass}
+	for _, pc := range set.Slice() {
+		if pc != bass {
+			roots = append(roots, pc)
+		}
+	}
+	for _, root := range roots {
+		for _, q := range qualities {
+			chord, err := ParseChord(root.Name(preferFlats) + q.name)
+			if err != nil || chord.PitchClasses() != set {
+				continue
+			}
+			if root != bass {
+				b, _ := ParsePitchName(bass.Name(preferFlats))
+				chord.Bass = &b
+			}
+			return chord, true
+		}
+	}
+	return Chord{}, false
+}
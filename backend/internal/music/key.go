@@ -0,0 +1,278 @@
+package music
+
+import (
+	"fmt"
+	"strings"
+)
+
+// Key — тональность: тоника и лад (мажор или натуральный минор)
+type Key struct {
+	Tonic PitchName
+	Minor bool
+}
+
+var (
+	majorScale = [7]int{0, 2, 4, 5, 7, 9, 11}
+	minorScale = [7]int{0, 2, 3, 5, 7, 8, 10}
+)
+
+// circleOfFifths — буквы по квинтовому кругу: F — один бемоль, C — без знаков
+const circleOfFifths = "FCGDAEB"
+
+// ParseKey разбирает тональность: "G", "Em", "Bb", "F#m"
+func ParseKey(s string) (Key, error) {
+	p, rest, err := parsePitchPrefix(strings.TrimSpace(s))
+	if err != nil {
+		return Key{}, err
+	}
+	switch rest {
+	case "":
+		return Key{Tonic: p}, nil
+	case "m", "min":
+		return Key{Tonic: p, Minor: true}, nil
+	}
+	return Key{}, fmt.Errorf("music: invalid key %q", s)
+}
+
+// String возвращает тональность в записи аккорда тоники: "G", "Em"
+func (k Key) String() string {
+	if k.Minor {
+		return k.Tonic.String() + "m"
+	}
+	return k.Tonic.String()
+}
+
+// Signature возвращает число ключевых знаков: положительное — диезы,
+// отрицательное — бемоли (G — 1, Dm — -1, F# — 6)
+func (k Key) Signature() int {
+	n := strings.IndexByte(circleOfFifths, k.Tonic.Letter) - 1 + 7*k.Tonic.Accidental
+	if k.Minor {
+		n -= 3
+	}
+	return n
+}
+
+// PrefersFlats сообщает, пишутся ли хроматические звуки тональности бемолями
+func (k Key) PrefersFlats() bool {
+	return k.Signature() < 0
+}
+
+func (k Key) scale() [7]int {
+	if k.Minor {
+		return minorScale
+	}
+	return majorScale
+}
+
+// Transpose сдвигает тональность и выбирает для новой тоники запись с
+// меньшим числом знаков: Eb, а не D#. При равенстве (F# и Gb) сохраняется
+// направление знаков исходной тональности.
+func (k Key) Transpose(semitones int) Key {
+	pc := k.Tonic.PitchClass().Transpose(semitones)
+	sharp, _ := ParsePitchName(pc.Name(false))
+	flat, _ := ParsePitchName(pc.Name(true))
+	ks, kf := Key{Tonic: sharp, Minor: k.Minor}, Key{Tonic: flat, Minor: k.Minor}
+	as, af := abs(ks.Signature()), abs(kf.Signature())
+	switch {
+	case as < af:
+		return ks
+	case af < as:
+		return kf
+	case k.PrefersFlats():
+		return kf
+	}
+	return ks
+}
+
+// Spell записывает звук в тональности: ступени гаммы — буквами гаммы
+// (F# в G, Bb в F), остальные — пониженной ступенью (Bb и Eb в C).
+// Исключения — повышенные ступени, которые встречаются чаще пониженных:
+// IV ступень мажора (F# в C) и III, VI, VII ступени минора (G# в Am).
+func (k Key) Spell(pc PitchClass) PitchName {
+	tonic := k.Tonic.PitchClass()
+	degree := mod12(int(pc) - int(tonic))
+	scale := k.scale()
+
+	var p PitchName
+	for i, iv := range scale {
+		if iv == degree {
+			p = spell(letterAt(k.Tonic.Letter, i), pc)
+			break
+		}
+		if iv > degree {
+			// Хроматический звук между ступенями i-1 и i
+			raised := (!k.Minor && i == 4) || (k.Minor && (i == 3 || i == 6))
+			if raised {
+				i--
+			}
+			p = spell(letterAt(k.Tonic.Letter, i), pc)
+			break
+		}
+	}
+	if p.Letter == 0 {
+		// Между VII ступенью и октавой: пониженная тоника встречается реже повышенной VII
+		p = spell(letterAt(k.Tonic.Letter, 6), pc)
+	}
+	if p.Accidental < -1 || p.Accidental > 1 {
+		p, _ = ParsePitchName(pc.Name(k.PrefersFlats()))
+	}
+	return p
+}
+
+// SpellNote записывает MIDI-ноту в тональности
+func (k Key) SpellNote(midi int) Note {
+	p := k.Spell(PitchClass(mod12(midi)))
+	base := Note{PitchName: p}.MIDI()
+	return Note{PitchName: p, Octave: (midi - base) / 12}
+}
+
+// SpellChord переписывает основной тон и бас аккорда в тональности
+func (k Key) SpellChord(c Chord) Chord {
+	out := c
+	out.Root = k.Spell(c.Root.PitchClass())
+	if c.Bass != nil {
+		bass := k.Spell(c.Bass.PitchClass())
+		out.Bass = &bass
+	}
+	return out
+}
+
+// GuessKeyFromChords определяет тональность последовательности аккордов:
+// аккорды, которые строятся на ступенях гаммы с нужным наклонением, дают
+// очко, а тоника в начале и в конце — ещё по очку. Длительности —
+// веса аккордов (nil — все равны).
+func GuessKeyFromChords(chords []Chord, weights []float64) Key {
+	if len(chords) == 0 {
+		return Key{Tonic: PitchName{Letter: 'C'}}
+	}
+	return bestKey(func(k Key) float64 {
+		var score float64
+		for i, c := range chords {
+			w := 1.0
+			if i < len(weights) {
+				w = weights[i]
+			}
+			score += w * k.chordFit(c)
+		}
+		return score + k.tonicBonus(chords[0].Root.PitchClass(), chords[0].minor()) +
+			k.tonicBonus(chords[len(chords)-1].Root.PitchClass(), chords[len(chords)-1].minor())
+	}, chords[0].Root)
+}
+
+// GuessKeyFromNotes определяет тональность мелодии: звуки гаммы дают
+// очко с весом длительности, первая и последняя нота на тонике — бонус
+func GuessKeyFromNotes(notes []Note, weights []float64) Key {
+	if len(notes) == 0 {
+		return Key{Tonic: PitchName{Letter: 'C'}}
+	}
+	first, last := notes[0].PitchClass(), notes[len(notes)-1].PitchClass()
+	return bestKey(func(k Key) float64 {
+		var score float64
+		for i, n := range notes {
+			w := 1.0
+			if i < len(weights) {
+				w = weights[i]
+			}
+			if k.diatonic(n.PitchClass()) {
+				score += w
+			}
+		}
+		// Для мелодии лад по тонике не различить: бонус без учёта наклонения
+		if first == k.Tonic.PitchClass() {
+			score++
+		}
+		if last == k.Tonic.PitchClass() {
+			score += 2
+		}
+		return score
+	}, notes[len(notes)-1].PitchName)
+}
+
+// bestKey перебирает 24 тональности и возвращает лучшую по score. При
+// равенстве выигрывают тональность от hint и мажор.
+func bestKey(score func(Key) float64, hint PitchName) Key {
+	var best Key
+	bestScore := -1.0
+	for _, minor := range []bool{false, true} {
+		for pc := 0; pc < 12; pc++ {
+			k := Key{Tonic: PitchName{Letter: 'C'}, Minor: minor}.Transpose(pc)
+			s := score(k)
+			if k.Tonic.PitchClass() == hint.PitchClass() {
+				s += 0.25
+			}
+			if s > bestScore {
+				best, bestScore = k, s
+			}
+		}
+	}
+	if best.Tonic.PitchClass() == hint.PitchClass() && hint.Accidental != 0 {
+		// Записываем тонику так же, как в исходном тексте (Gb, а не F#)
+		best.Tonic = hint
+	}
+	return best
+}
+
+// PitchClasses возвращает звуковысотные классы гаммы тональности
+func (k Key) PitchClasses() PitchClassSet {
+	var set PitchClassSet
+	for _, iv := range k.scale() {
+		set = set.Add(k.Tonic.PitchClass().Transpose(iv))
+	}
+	return set
+}
+
+func (k Key) diatonic(pc PitchClass) bool {
+	degree := mod12(int(pc) - int(k.Tonic.PitchClass()))
+	for _, iv := range k.scale() {
+		if iv == degree {
+			return true
+		}
+	}
+	return false
+}
+
+// chordFit — насколько аккорд свойственен тональности: 1 — трезвучие
+// ступени (основной тон и терция из гаммы), 0.5 — только основной тон
+func (k Key) chordFit(c Chord) float64 {
+	root := c.Root.PitchClass()
+	if !k.diatonic(root) {
+		return 0
+	}
+	// Мажорная доминанта минора (E в Am) — с повышенной VII ступенью, но своя
+	if k.Minor && mod12(int(root)-int(k.Tonic.PitchClass())) == 7 {
+		return 1
+	}
+	for _, iv := range c.Intervals {
+		if iv.Degree == 3 && !k.diatonic(root.Transpose(iv.Semitones)) {
+			return 0.5
+		}
+	}
+	return 1
+}
+
+func (k Key) tonicBonus(root PitchClass, minor bool) float64 {
+	if root != k.Tonic.PitchClass() {
+		return 0
+	}
+	if minor == k.Minor {
+		return 1
+	}
+	return 0.25
+}
+
+// minor сообщает, малая ли терция у аккорда
+func (c Chord) minor() bool {
+	for _, iv := range c.Intervals {
+		if iv.Degree == 3 {
+			return iv.Semitones == 3
+		}
+	}
+	return false
+}
+
+func abs(n int) int {
+	if n < 0 {
+		return -n
+	}
+	return n
+}
@@ -0,0 +1,95 @@
+package music
+
+import (
+	"fmt"
+	"math"
+	"strconv"
+)
+
+// StandardA4 — стандартная частота ноты A4 (ля первой октавы), Гц
+const StandardA4 = 440.0
+
+// Note — нота в научной нотации: название и октава (C4 — до первой октавы, MIDI 60)
+type Note struct {
+	PitchName
+	Octave int
+}
+
+// ParseNote разбирает ноту в научной нотации: "C4", "F#3", "Bb2", "C#-1"
+func ParseNote(s string) (Note, error) {
+	p, rest, err := parsePitchPrefix(s)
+	if err != nil {
+		return Note{}, err
+	}
+	if rest == "" {
+		return Note{}, fmt.Errorf("music: note %q has no octave", s)
+	}
+	octave, err := strconv.Atoi(rest)
+	if err != nil || octave < -1 || octave > 9 {
+		return Note{}, fmt.Errorf("music: invalid octave in note %q", s)
+	}
+
+	n := Note{PitchName: p, Octave: octave}
+	if m := n.MIDI(); m < 0 || m > 127 {
+		return Note{}, fmt.Errorf("music: note %q is outside the MIDI range", s)
+	}
+	return n, nil
+}
+
+// NoteFromMIDI строит ноту по MIDI-номеру (60 = C4)
+func NoteFromMIDI(midi int, preferFlats bool) Note {
+	pc := PitchClass(mod12(midi))
+	p, _ := ParsePitchName(pc.Name(preferFlats))
+	// октава — деление с округлением вниз, согласованное с mod12: -1 = B-2
+	return Note{PitchName: p, Octave: (midi-mod12(midi))/12 - 1}
+}
+
+// MIDI возвращает MIDI-номер ноты. Октава относится к букве,
+// поэтому Cb4 = 59 (как B3), а B#3 = 60 (как C4).
+func (n Note) MIDI() int {
+	return (n.Octave+1)*12 + naturalPitch[n.Letter] + n.Accidental
+}
+
+// Frequency возвращает частоту ноты в равномерной темперации при заданной
+// частоте A4 (обычно StandardA4)
+func (n Note) Frequency(a4 float64) float64 {
+	return MIDIToFrequency(float64(n.MIDI()), a4)
+}
+
+// String возвращает запись ноты: "C#4"
+func (n Note) String() string {
+	return n.PitchName.String() + strconv.Itoa(n.Octave)
+}
+
+// Enharmonic сообщает, звучат ли две ноты одинаково (C#4 и Db4)
+func (n Note) Enharmonic(o Note) bool {
+	return n.MIDI() == o.MIDI()
+}
+
+// Canonical возвращает каноническое написание ноты с той же высотой
+func (n Note) Canonical() Note {
+	c := n.PitchName.Canonical()
+	midi := n.MIDI()
+	return Note{PitchName: c, Octave: (midi-naturalPitch[c.Letter]-c.Accidental)/12 - 1}
+}
+
+// Transpose сдвигает ноту на указанное число полутонов
+func (n Note) Transpose(semitones int, preferFlats bool) Note {
+	return NoteFromMIDI(n.MIDI()+semitones, preferFlats)
+}
+
+// MIDIToFrequency переводит (дробный) MIDI-номер в частоту
+func MIDIToFrequency(midi, a4 float64) float64 {
+	return a4 * math.Pow(2, (midi-69)/12)
+}
+
+// FrequencyToMIDI переводит частоту в дробный MIDI-номер
+func FrequencyToMIDI(freq, a4 float64) float64 {
+	return 69 + 12*math.Log2(freq/a4)
+}
+
+// CentsOff возвращает отклонение частоты от ноты в центах
+// (положительное — выше ноты)
+func (n Note) CentsOff(freq, a4 float64) float64 {
+	return 100 * (FrequencyToMIDI(freq, a4) - float64(n.MIDI()))
+}
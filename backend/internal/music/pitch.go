@@ -0,0 +1,200 @@
+// Package music содержит базовую теорию музыки: разбор нот в научной
+// нотации (C4, F#3, Bb2) и буквенных обозначений аккордов (Am, C7, G/B),
+// звуковысотные классы, MIDI-номера и частоты.
+package music
+
+import (
+	"fmt"
+	"strings"
+)
+
+// PitchClass — звуковысотный класс, 0 = C, 1 = C#/Db, ..., 11 = B
+type PitchClass int
+
+// naturalPitch — звуковысотный класс каждой "белой" ноты по букве
+var naturalPitch = map[byte]int{'C': 0, 'D': 2, 'E': 4, 'F': 5, 'G': 7, 'A': 9, 'B': 11}
+
+const letters = "CDEFGAB"
+
+var sharpNames = [12]string{"C", "C#", "D", "D#", "E", "F", "F#", "G", "G#", "A", "A#", "B"}
+var flatNames = [12]string{"C", "Db", "D", "Eb", "E", "F", "Gb", "G", "Ab", "A", "Bb", "B"}
+
+func mod12(n int) int {
+	return ((n % 12) + 12) % 12
+}
+
+// Name возвращает название класса диезом или бемолем
+func (pc PitchClass) Name(preferFlats bool) string {
+	if preferFlats {
+		return flatNames[mod12(int(pc))]
+	}
+	return sharpNames[mod12(int(pc))]
+}
+
+// Transpose сдвигает класс на указанное число полутонов
+func (pc PitchClass) Transpose(semitones int) PitchClass {
+	return PitchClass(mod12(int(pc) + semitones))
+}
+
+// PitchName — название ноты без октавы: буква и знак альтерации
+// (-2 дубль-бемоль, -1 бемоль, 0, 1 диез, 2 дубль-диез).
+type PitchName struct {
+	Letter     byte
+	Accidental int
+}
+
+// ParsePitchName разбирает название вида "C", "F#", "Bb", "Ebb", "Fx"
+func ParsePitchName(s string) (PitchName, error) {
+	p, rest, err := parsePitchPrefix(s)
+	if err != nil {
+		return PitchName{}, err
+	}
+	if rest != "" {
+		return PitchName{}, fmt.Errorf("music: unexpected %q after pitch name %q", rest, s)
+	}
+	return p, nil
+}
+
+// parsePitchPrefix читает букву и знаки альтерации в начале строки
+// и возвращает остаток строки.
+func parsePitchPrefix(s string) (PitchName, string, error) {
+	if s == "" {
+		return PitchName{}, "", fmt.Errorf("music: empty pitch name")
+	}
+	letter := s[0]
+	if letter >= 'a' && letter <= 'g' {
+		letter -= 'a' - 'A'
+	}
+	if _, ok := naturalPitch[letter]; !ok {
+		return PitchName{}, "", fmt.Errorf("music: invalid note letter in %q", s)
+	}
+
+	p := PitchName{Letter: letter}
+	rest := s[1:]
+loop:
+	for rest != "" {
+		switch {
+		case strings.HasPrefix(rest, "#"):
+			p.Accidental++
+			rest = rest[1:]
+		case strings.HasPrefix(rest, "♯"):
+			p.Accidental++
+			rest = rest[len("♯"):]
+		case strings.HasPrefix(rest, "x"):
+			p.Accidental += 2
+			rest = rest[1:]
+		case strings.HasPrefix(rest, "b"):
+			p.Accidental--
+			rest = rest[1:]
+		case strings.HasPrefix(rest, "♭"):
+			p.Accidental--
+			rest = rest[len("♭"):]
+		default:
+			break loop
+		}
+	}
+	if p.Accidental < -2 || p.Accidental > 2 {
+		return PitchName{}, "", fmt.Errorf("music: too many accidentals in %q", s)
+	}
+	return p, rest, nil
+}
+
+// PitchClass возвращает звуковысотный класс названия
+func (p PitchName) PitchClass() PitchClass {
+	return PitchClass(mod12(naturalPitch[p.Letter] + p.Accidental))
+}
+
+// String возвращает название в ASCII-записи: "C#", "Bb", "Fx", "Ebb"
+func (p PitchName) String() string {
+	var b strings.Builder
+	b.WriteByte(p.Letter)
+	switch {
+	case p.Accidental == 2:
+		b.WriteString("x")
+	case p.Accidental > 0:
+		b.WriteString(strings.Repeat("#", p.Accidental))
+	case p.Accidental < 0:
+		b.WriteString(strings.Repeat("b", -p.Accidental))
+	}
+	return b.String()
+}
+
+// Enharmonic сообщает, обозначают ли два названия один и тот же звук
+func (p PitchName) Enharmonic(o PitchName) bool {
+	return p.PitchClass() == o.PitchClass()
+}
+
+// Canonical возвращает каноническое написание: без дубль-знаков и без
+// E#/B#/Fb/Cb. Направление знака (диез/бемоль) по возможности сохраняется.
+func (p PitchName) Canonical() PitchName {
+	if p.Accidental == 0 {
+		return p
+	}
+	pc := p.PitchClass()
+	name := sharpNames[pc]
+	if p.Accidental < 0 {
+		name = flatNames[pc]
+	}
+	canonical, _ := ParsePitchName(name)
+	return canonical
+}
+
+// spell подбирает написание звука pc на букве letter (для правильной
+// записи ступеней аккорда: третья ступень от A — это C, а не B#).
+func spell(letter byte, pc PitchClass) PitchName {
+	acc := mod12(int(pc) - naturalPitch[letter])
+	if acc > 6 {
+		acc -= 12
+	}
+	return PitchName{Letter: letter, Accidental: acc}
+}
+
+// letterAt возвращает букву, отстоящую от letter на steps ступеней
+func letterAt(letter byte, steps int) byte {
+	i := strings.IndexByte(letters, letter)
+	return letters[((i+steps)%7+7)%7]
+}
+
+// PitchClassSet — множество звуковысотных классов (битовая маска)
+type PitchClassSet uint16
+
+// Add добавляет класс в множество
+func (s PitchClassSet) Add(pc PitchClass) PitchClassSet {
+	return s | 1<<uint(mod12(int(pc)))
+}
+
+// Has проверяет наличие класса в множестве
+func (s PitchClassSet) Has(pc PitchClass) bool {
+	return s&(1<<uint(mod12(int(pc)))) != 0
+}
+
+// Len возвращает количество классов в множестве
+func (s PitchClassSet) Len() int {
+	n := 0
+	for pc := 0; pc < 12; pc++ {
+		if s&(1<<uint(pc)) != 0 {
+			n++
+		}
+	}
+	return n
+}
+
+// Slice возвращает классы множества по возрастанию
+func (s PitchClassSet) Slice() []PitchClass {
+	var out []PitchClass
+	for pc := 0; pc < 12; pc++ {
+		if s&(1<<uint(pc)) != 0 {
+			out = append(out, PitchClass(pc))
+		}
+	}
+	return out
+}
+
+// Transpose сдвигает все классы множества
+func (s PitchClassSet) Transpose(semitones int) PitchClassSet {
+	var out PitchClassSet
+	for _, pc := range s.Slice() {
+		out = out.Add(pc.Transpose(semitones))
+	}
+	return out
+}
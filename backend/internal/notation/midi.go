@@ -0,0 +1,288 @@
+package notation
+
+import (
+	"bufio"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"io"
+	"sort"
+	"strings"
+)
+
+// maxMIDIBytes ограничивает размер одного трека
+const maxMIDIBytes = 10 << 20
+
+// drumChannel — 10-й канал General MIDI (ударные, нот у них нет)
+const drumChannel = 9
+
+var errTruncatedMIDI = errors.New("notation: truncated MIDI file")
+
+// midiKey — партия MIDI-файла: трек и канал (в формате 0 все каналы в одном треке)
+type midiKey struct {
+	track, channel int
+}
+
+type midiPart struct {
+	part Part
+	open map[int][]int // высота → индексы нот, ожидающих note off
+}
+
+// ParseMIDI читает Standard MIDI File формата 0 или 1. Каждая пара
+// трек/канал становится партией; ударные пропускаются. Время квантуется
+// до тридцатьвторых и триолей, чтобы живое исполнение давало ровные доли.
+func ParseMIDI(r io.Reader) (*Score, error) {
+	br := bufio.NewReader(r)
+	id, header, err := readChunk(br)
+	if err != nil {
+		return nil, err
+	}
+	if id != "MThd" || len(header) < 6 {
+		return nil, errors.New("notation: not a MIDI file")
+	}
+	format := binary.BigEndian.Uint16(header[0:2])
+	tracks := int(binary.BigEndian.Uint16(header[2:4]))
+	division := binary.BigEndian.Uint16(header[4:6])
+	if format > 1 {
+		return nil, fmt.Errorf("notation: MIDI format %d is not supported", format)
+	}
+	if division&0x8000 != 0 || division == 0 {
+		return nil, errors.New("notation: SMPTE time division is not supported")
+	}
+
+	s := &Score{}
+	parts := make(map[midiKey]*midiPart)
+	var order []midiKey
+	ticks := float64(division)
+
+	for t := 0; t < tracks; t++ {
+		id, data, err := readChunk(br)
+		if err != nil {
+			return nil, err
+		}
+		if id != "MTrk" {
+			t-- // неизвестные чанки пропускаются
+			continue
+		}
+		name, lyrics, err := s.readTrack(data, ticks, func(channel int) *midiPart {
+			key := midiKey{t, channel}
+			p, ok := parts[key]
+			if !ok {
+				p = &midiPart{part: Part{Program: -1}, open: make(map[int][]int)}
+				parts[key] = p
+				order = append(order, key)
+			}
+			return p
+		})
+		if err != nil {
+			return nil, err
+		}
+
+		trackParts := 0
+		for _, key := range order {
+			if key.track != t {
+				continue
+			}
+			p := &parts[key].part
+			if len(p.Notes) > 0 {
+				trackParts++
+			}
+			p.Name = name
+			for i := range p.Notes {
+				if lyric, ok := lyrics[p.Notes[i].Start]; ok && p.Notes[i].Lyric == "" {
+					p.Notes[i].Lyric = lyric
+				}
+			}
+		}
+		// Трек без нот с названием в формате 1 — обычно название песни
+		if trackParts == 0 && s.Title == "" {
+			s.Title = name
+		}
+	}
+
+	for _, key := range order {
+		p := &parts[key].part
+		if len(p.Notes) == 0 {
+			continue
+		}
+		if key.channel == drumChannel {
+			s.warn("drum tracks are skipped")
+			continue
+		}
+		p.ID = fmt.Sprintf("T%dC%d", key.track+1, key.channel+1)
+		if p.Name == "" {
+			p.Name = fmt.Sprintf("Track %d", key.track+1)
+		}
+		p.detectInstrument()
+		sort.SliceStable(p.Notes, func(i, j int) bool { return p.Notes[i].Start < p.Notes[j].Start })
+		for _, n := range p.Notes {
+			if end := n.Start + n.Duration; end > p.End {
+				p.End = end
+			}
+		}
+		p.Chords = chordsFromNotes(p.Notes)
+		s.Parts = append(s.Parts, *p)
+	}
+	if len(s.Parts) == 0 {
+		return nil, errors.New("notation: MIDI file has no notes")
+	}
+	s.defaults()
+	return s, nil
+}
+
+func readChunk(r io.Reader) (string, []byte, error) {
+	var head [8]byte
+	if _, err := io.ReadFull(r, head[:]); err != nil {
+		return "", nil, errTruncatedMIDI
+	}
+	size := binary.BigEndian.Uint32(head[4:])
+	if size > maxMIDIBytes {
+		return "", nil, errors.New("notation: MIDI chunk is too large")
+	}
+	data := make([]byte, size)
+	if _, err := io.ReadFull(r, data); err != nil {
+		return "", nil, errTruncatedMIDI
+	}
+	return string(head[:4]), data, nil
+}
+
+// readTrack разбирает события трека. Возвращает название трека и слоги
+// текста по времени (в четвертях).
+func (s *Score) readTrack(data []byte, ticks float64, part func(channel int) *midiPart) (string, map[float64]string, error) {
+	var name string
+	lyrics := make(map[float64]string)
+	var tick uint64
+	var status byte
+	i := 0
+
+	readVar := func() (uint64, error) {
+		var v uint64
+		for n := 0; n < 4; n++ {
+			if i >= len(data) {
+				return 0, errTruncatedMIDI
+			}
+			b := data[i]
+			i++
+			v = v<<7 | uint64(b&0x7f)
+			if b&0x80 == 0 {
+				return v, nil
+			}
+		}
+		return 0, errors.New("notation: invalid MIDI variable-length number")
+	}
+	need := func(n int) error {
+		if i+n > len(data) {
+			return errTruncatedMIDI
+		}
+		return nil
+	}
+
+	for i < len(data) {
+		delta, err := readVar()
+		if err != nil {
+			return "", nil, err
+		}
+		tick += delta
+		at := quantize(float64(tick) / ticks)
+
+		if err := need(1); err != nil {
+			return "", nil, err
+		}
+		b := data[i]
+		switch {
+		case b == 0xff:
+			if err := need(2); err != nil {
+				return "", nil, err
+			}
+			kind := data[i+1]
+			i += 2
+			size, err := readVar()
+			if err != nil {
+				return "", nil, err
+			}
+			if err := need(int(size)); err != nil {
+				return "", nil, err
+			}
+			body := data[i : i+int(size)]
+			i += int(size)
+			switch kind {
+			case 0x03:
+				name = strings.TrimSpace(string(body))
+			case 0x05:
+				if text := strings.TrimSpace(string(body)); text != "" {
+					lyrics[at] = text
+				}
+			case 0x51:
+				if len(body) == 3 {
+					micros := int(body[0])<<16 | int(body[1])<<8 | int(body[2])
+					if micros > 0 {
+						s.setTempo(60e6 / float64(micros))
+					}
+				}
+			case 0x58:
+				if len(body) >= 2 && body[1] < 8 {
+					s.setMeter(fmt.Sprint(body[0]), 1<<body[1])
+				}
+			case 0x2f:
+				return name, lyrics, nil
+			}
+			continue
+		case b == 0xf0 || b == 0xf7:
+			i++
+			size, err := readVar()
+			if err != nil {
+				return "", nil, err
+			}
+			if err := need(int(size)); err != nil {
+				return "", nil, err
+			}
+			i += int(size)
+			continue
+		case b&0x80 != 0:
+			status = b
+			i++
+		case status == 0:
+			return "", nil, errors.New("notation: MIDI data without status byte")
+		}
+
+		// Канальное сообщение (возможно, с running status)
+		size := 2
+		if kind := status & 0xf0; kind == 0xc0 || kind == 0xd0 {
+			size = 1
+		}
+		if err := need(size); err != nil {
+			return "", nil, err
+		}
+		args := data[i : i+size]
+		i += size
+		channel := int(status & 0x0f)
+
+		switch status & 0xf0 {
+		case 0x90:
+			if args[1] > 0 {
+				p := part(channel)
+				pitch := int(args[0])
+				p.open[pitch] = append(p.open[pitch], len(p.part.Notes))
+				p.part.Notes = append(p.part.Notes, Note{Start: at, MIDI: pitch})
+				continue
+			}
+			fallthrough
+		case 0x80:
+			p := part(channel)
+			pitch := int(args[0])
+			if queue := p.open[pitch]; len(queue) > 0 {
+				n := &p.part.Notes[queue[0]]
+				n.Duration = at - n.Start
+				if n.Duration <= 0 {
+					n.Duration = grid
+				}
+				p.open[pitch] = queue[1:]
+			}
+		case 0xc0:
+			if p := part(channel); p.part.Program < 0 {
+				p.part.Program = int(args[0])
+			}
+		}
+	}
+	return name, lyrics, nil
+}
@@ -0,0 +1,389 @@
+package notation
+
+import (
+	"archive/zip"
+	"bytes"
+	"encoding/xml"
+	"errors"
+	"fmt"
+	"io"
+	"math"
+	"path"
+	"strings"
+
+	"sonara-space/backend/internal/music"
+)
+
+// maxMXLBytes ограничивает распакованный MusicXML внутри .mxl
+const maxMXLBytes = 20 << 20
+
+type mxScore struct {
+	XMLName       xml.Name      `xml:""`
+	WorkTitle     string        `xml:"work>work-title"`
+	MovementTitle string        `xml:"movement-title"`
+	ScoreParts    []mxScorePart `xml:"part-list>score-part"`
+	Parts         []mxPart      `xml:"part"`
+}
+
+type mxScorePart struct {
+	ID             string `xml:"id,attr"`
+	Name           string `xml:"part-name"`
+	InstrumentName string `xml:"score-instrument>instrument-name"`
+	Program        int    `xml:"midi-instrument>midi-program"` // 1–128
+}
+
+type mxPart struct {
+	ID       string      `xml:"id,attr"`
+	Measures []mxMeasure `xml:"measure"`
+}
+
+type mxMeasure struct {
+	Items []mxItem `xml:",any"`
+}
+
+// mxItem — любой элемент такта (note, backup, forward, attributes, harmony,
+// direction, sound). Порядок элементов важен, поэтому они читаются одним
+// списком, а нужные поля берутся по имени элемента.
+type mxItem struct {
+	XMLName xml.Name
+
+	// attributes
+	Divisions int `xml:"divisions"`
+	Time      *struct {
+		Beats    string `xml:"beats"`
+		BeatType int    `xml:"beat-type"`
+	} `xml:"time"`
+	Transpose *struct {
+		Chromatic    int `xml:"chromatic"`
+		OctaveChange int `xml:"octave-change"`
+	} `xml:"transpose"`
+
+	// note, backup, forward
+	Chord    *struct{} `xml:"chord"`
+	Grace    *struct{} `xml:"grace"`
+	Cue      *struct{} `xml:"cue"`
+	Rest     *struct{} `xml:"rest"`
+	Pitch    *mxPitch  `xml:"pitch"`
+	Duration int       `xml:"duration"`
+	Voice    string    `xml:"voice"`
+	Ties     []struct {
+		Type string `xml:"type,attr"`
+	} `xml:"tie"`
+	Lyrics []struct {
+		Number   string `xml:"number,attr"`
+		Syllabic string `xml:"syllabic"`
+		Text     string `xml:"text"`
+	} `xml:"lyric"`
+
+	// harmony
+	Root *struct {
+		Step  string  `xml:"root-step"`
+		Alter float64 `xml:"root-alter"`
+	} `xml:"root"`
+	Kind *struct {
+		Text  string `xml:"text,attr"`
+		Value string `xml:",chardata"`
+	} `xml:"kind"`
+	Bass *struct {
+		Step  string  `xml:"bass-step"`
+		Alter float64 `xml:"bass-alter"`
+	} `xml:"bass"`
+	Degrees []struct{} `xml:"degree"`
+	Offset  int        `xml:"offset"`
+
+	// direction и sound
+	Sound *struct {
+		Tempo float64 `xml:"tempo,attr"`
+	} `xml:"sound"`
+	Metronome *struct {
+		BeatUnit  string  `xml:"beat-unit"`
+		PerMinute float64 `xml:"per-minute"`
+	} `xml:"direction-type>metronome"`
+	Tempo float64 `xml:"tempo,attr"`
+}
+
+type mxPitch struct {
+	Step   string  `xml:"step"`
+	Alter  float64 `xml:"alter"`
+	Octave int     `xml:"octave"`
+}
+
+// harmonyKinds сопоставляет значения <kind> MusicXML обозначениям аккордов
+var harmonyKinds = map[string]string{
+	"major": "", "minor": "m", "augmented": "aug", "diminished": "dim",
+	"dominant": "7", "major-seventh": "maj7", "minor-seventh": "m7",
+	"diminished-seventh": "dim7", "augmented-seventh": "aug7",
+	"half-diminished": "m7b5", "major-minor": "mMaj7",
+	"major-sixth": "6", "minor-sixth": "m6",
+	"dominant-ninth": "9", "major-ninth": "maj9", "minor-ninth": "m9",
+	"dominant-11th": "11", "minor-11th": "m11",
+	"dominant-13th": "13", "major-13th": "maj13", "minor-13th": "m13",
+	"suspended-second": "sus2", "suspended-fourth": "sus4", "power": "5",
+}
+
+// ParseMusicXML читает несжатый MusicXML (score-partwise)
+func ParseMusicXML(r io.Reader) (*Score, error) {
+	var doc mxScore
+	if err := xml.NewDecoder(r).Decode(&doc); err != nil {
+		return nil, fmt.Errorf("notation: invalid MusicXML: %w", err)
+	}
+	switch doc.XMLName.Local {
+	case "score-partwise":
+	case "score-timewise":
+		return nil, errors.New("notation: score-timewise MusicXML is not supported, export as partwise")
+	default:
+		return nil, fmt.Errorf("notation: unexpected root element <%s>", doc.XMLName.Local)
+	}
+
+	s := &Score{Title: strings.TrimSpace(doc.WorkTitle)}
+	if s.Title == "" {
+		s.Title = strings.TrimSpace(doc.MovementTitle)
+	}
+	info := make(map[string]mxScorePart)
+	for _, sp := range doc.ScoreParts {
+		info[sp.ID] = sp
+	}
+
+	for _, mp := range doc.Parts {
+		sp := info[mp.ID]
+		part := Part{ID: mp.ID, Name: strings.TrimSpace(sp.Name), Program: sp.Program - 1}
+		if part.Name == "" {
+			part.Name = strings.TrimSpace(sp.InstrumentName)
+		}
+		part.detectInstrument()
+		if part.Instrument == "" {
+			part.Instrument = InstrumentFromName(sp.InstrumentName)
+		}
+		s.readPart(&part, mp)
+		s.Parts = append(s.Parts, part)
+	}
+	if len(s.Parts) == 0 {
+		return nil, errors.New("notation: MusicXML has no parts")
+	}
+	s.defaults()
+	return s, nil
+}
+
+// ParseMXL читает сжатый MusicXML: zip с META-INF/container.xml
+func ParseMXL(data []byte) (*Score, error) {
+	zr, err := zip.NewReader(bytes.NewReader(data), int64(len(data)))
+	if err != nil {
+		return nil, fmt.Errorf("notation: invalid MXL archive: %w", err)
+	}
+	files := make(map[string]*zip.File)
+	for _, f := range zr.File {
+		files[f.Name] = f
+	}
+	open := func(f *zip.File) ([]byte, error) {
+		rc, err := f.Open()
+		if err != nil {
+			return nil, err
+		}
+		defer rc.Close()
+		b, err := io.ReadAll(io.LimitReader(rc, maxMXLBytes+1))
+		if err == nil && len(b) > maxMXLBytes {
+			err = errors.New("notation: MXL content is too large")
+		}
+		return b, err
+	}
+
+	// Основной файл указан в container.xml; если его нет — первый .xml вне META-INF
+	var root string
+	if f, ok := files["META-INF/container.xml"]; ok {
+		b, err := open(f)
+		if err != nil {
+			return nil, err
+		}
+		var container struct {
+			Rootfiles []struct {
+				FullPath string `xml:"full-path,attr"`
+			} `xml:"rootfiles>rootfile"`
+		}
+		if err := xml.Unmarshal(b, &container); err == nil && len(container.Rootfiles) > 0 {
+			root = container.Rootfiles[0].FullPath
+		}
+	}
+	if root == "" {
+		for _, f := range zr.File {
+			ext := path.Ext(f.Name)
+			if !strings.HasPrefix(f.Name, "META-INF/") && (ext == ".xml" || ext == ".musicxml") {
+				root = f.Name
+				break
+			}
+		}
+	}
+	f, ok := files[root]
+	if !ok {
+		return nil, errors.New("notation: MXL archive has no score")
+	}
+	b, err := open(f)
+	if err != nil {
+		return nil, err
+	}
+	return ParseMusicXML(bytes.NewReader(b))
+}
+
+// readPart проходит по тактам партии. В мелодию попадает первый
+// встретившийся голос; остальные голоса используются только для
+// определения аккордов, если в партии нет буквенных обозначений.
+func (s *Score) readPart(part *Part, mp mxPart) {
+	divisions := 1
+	transpose := 0
+	voice := ""
+	var pos, measureStart float64 // в четвертях
+	var all []Note
+	last := make(map[string]int) // голос → индекс последней ноты в part.Notes
+	var lastStart float64
+
+	for _, m := range mp.Measures {
+		measureEnd := measureStart
+		pos = measureStart
+		for _, it := range m.Items {
+			switch it.XMLName.Local {
+			case "attributes":
+				if it.Divisions > 0 {
+					divisions = it.Divisions
+				}
+				if it.Time != nil {
+					s.setMeter(it.Time.Beats, it.Time.BeatType)
+				}
+				if it.Transpose != nil {
+					transpose = it.Transpose.Chromatic + 12*it.Transpose.OctaveChange
+				}
+			case "sound":
+				s.setTempo(it.Tempo)
+			case "direction":
+				if it.Sound != nil {
+					s.setTempo(it.Sound.Tempo)
+				} else if it.Metronome != nil && it.Metronome.BeatUnit == "quarter" {
+					s.setTempo(it.Metronome.PerMinute)
+				}
+			case "backup":
+				pos -= float64(it.Duration) / float64(divisions)
+			case "forward":
+				pos += float64(it.Duration) / float64(divisions)
+			case "harmony":
+				if symbol, ok := s.harmonySymbol(it); ok {
+					part.Chords = append(part.Chords, ChordSymbol{
+						Start:  pos + float64(it.Offset)/float64(divisions),
+						Symbol: symbol,
+					})
+				}
+			case "note":
+				if it.Grace != nil || it.Cue != nil {
+					if it.Grace != nil {
+						s.warn("grace notes are skipped")
+					}
+					continue
+				}
+				dur := float64(it.Duration) / float64(divisions)
+				start := pos
+				if it.Chord != nil {
+					start = lastStart
+				} else {
+					pos += dur
+				}
+				lastStart = start
+				if pos > measureEnd {
+					measureEnd = pos
+				}
+				if it.Rest != nil || it.Pitch == nil {
+					continue
+				}
+
+				n := Note{Start: start, Duration: dur, MIDI: it.Pitch.midi() + transpose}
+				for _, l := range it.Lyrics {
+					if l.Number == "" || l.Number == "1" {
+						n.Lyric = strings.TrimSpace(l.Text)
+						if l.Syllabic == "begin" || l.Syllabic == "middle" {
+							n.Lyric += "-"
+						}
+						break
+					}
+				}
+				all = append(all, n)
+
+				if voice == "" {
+					voice = it.Voice
+				}
+				if it.Voice != voice {
+					s.warn("part %s: only the first voice is used for the melody", part.Name)
+					continue
+				}
+				// Продолжение залигованной ноты удлиняет предыдущую
+				if tieStop(it) {
+					if i, ok := last[it.Voice]; ok && part.Notes[i].MIDI == n.MIDI {
+						part.Notes[i].Duration += dur
+						continue
+					}
+				}
+				if it.Chord == nil {
+					last[it.Voice] = len(part.Notes)
+				}
+				part.Notes = append(part.Notes, n)
+			}
+			if pos > measureEnd {
+				measureEnd = pos
+			}
+		}
+		measureStart = measureEnd
+	}
+	part.End = measureStart
+	if len(part.Chords) == 0 {
+		part.Chords = chordsFromNotes(all)
+	}
+}
+
+func tieStop(it mxItem) bool {
+	for _, t := range it.Ties {
+		if t.Type == "stop" {
+			return true
+		}
+	}
+	return false
+}
+
+func (p *mxPitch) midi() int {
+	natural := map[string]int{"C": 0, "D": 2, "E": 4, "F": 5, "G": 7, "A": 9, "B": 11}[strings.ToUpper(p.Step)]
+	return (p.Octave+1)*12 + natural + int(math.Round(p.Alter))
+}
+
+// harmonySymbol строит обозначение аккорда из <harmony>
+func (s *Score) harmonySymbol(it mxItem) (string, bool) {
+	if it.Root == nil || it.Kind == nil {
+		return "", false
+	}
+	kind := strings.TrimSpace(it.Kind.Value)
+	if kind == "none" {
+		return "", false
+	}
+	suffix, ok := harmonyKinds[kind]
+	if !ok {
+		// Нестандартный тип — пробуем то, что написано в партитуре
+		suffix = strings.TrimSpace(it.Kind.Text)
+	}
+	if len(it.Degrees) > 0 {
+		s.warn("chord degree alterations are ignored")
+	}
+	symbol := accidental(it.Root.Step, it.Root.Alter) + suffix
+	if it.Bass != nil {
+		symbol += "/" + accidental(it.Bass.Step, it.Bass.Alter)
+	}
+	chord, err := music.ParseChord(symbol)
+	if err != nil {
+		s.warn("unknown chord %q is skipped", symbol)
+		return "", false
+	}
+	return chord.String(), true
+}
+
+func accidental(step string, alter float64) string {
+	a := int(math.Round(alter))
+	switch {
+	case a > 0:
+		return strings.ToUpper(step) + strings.Repeat("#", a)
+	case a < 0:
+		return strings.ToUpper(step) + strings.Repeat("b", -a)
+	}
+	return strings.ToUpper(step)
+}
@@ -0,0 +1,334 @@
+// Package notation читает нотные файлы (MusicXML, сжатый MusicXML .mxl и
+// Standard MIDI File) и превращает мелодию или буквенные обозначения
+// аккордов партии в последовательность для упражнения типа "sequence".
+package notation
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"math"
+	"sort"
+	"strings"
+
+	"sonara-space/backend/internal/instruments"
+	"sonara-space/backend/internal/models"
+	"sonara-space/backend/internal/music"
+)
+
+// DefaultTempo — темп, если в файле он не указан (так считают MusicXML и MIDI)
+const DefaultTempo = 120
+
+// grid — шаг квантования длительностей, в долях четверти (тридцатьвторые и триоли)
+const grid = 1.0 / 24
+
+// Note — нота партии. Время и длительность — в четвертях от начала,
+// высота — звучащая (с учётом транспонирующих инструментов).
+type Note struct {
+	Start    float64
+	Duration float64
+	MIDI     int
+	Lyric    string
+}
+
+// ChordSymbol — буквенное обозначение аккорда, действующее с момента Start
+type ChordSymbol struct {
+	Start  float64
+	Symbol string
+}
+
+// Part — партия (инструмент) нотного файла
+type Part struct {
+	ID         string
+	Name       string
+	Instrument string // ID из справочника instruments или пусто, если инструмент не распознан
+	Program    int    // General MIDI program 0–127, -1 если не указан
+	Notes      []Note
+	Chords     []ChordSymbol
+	End        float64 // длина партии в четвертях
+}
+
+// Score — разобранный нотный файл
+type Score struct {
+	Title       string
+	Tempo       float64 // ударов в минуту, доля — четверть
+	BeatsPerBar int
+	BeatUnit    int
+	Parts       []Part
+	// Warnings — что не удалось перенести: смены темпа и размера,
+	// форшлаги, несколько голосов и т.п.
+	Warnings []string
+}
+
+func (s *Score) warn(format string, args ...interface{}) {
+	msg := fmt.Sprintf(format, args...)
+	for _, w := range s.Warnings {
+		if w == msg {
+			return
+		}
+	}
+	s.Warnings = append(s.Warnings, msg)
+}
+
+// setTempo запоминает первый темп; последующие смены темпа не переносятся
+func (s *Score) setTempo(bpm float64) {
+	if bpm <= 0 {
+		return
+	}
+	bpm = math.Round(bpm*100) / 100
+	if s.Tempo == 0 {
+		s.Tempo = bpm
+	} else if s.Tempo != bpm {
+		s.warn("tempo changes are ignored, using %g bpm", s.Tempo)
+	}
+}
+
+// setMeter запоминает первый размер. beats может быть составным ("3+2").
+func (s *Score) setMeter(beats string, beatType int) {
+	n := 0
+	for _, part := range strings.Split(beats, "+") {
+		var v int
+		if _, err := fmt.Sscanf(strings.TrimSpace(part), "%d", &v); err != nil || v <= 0 {
+			return
+		}
+		n += v
+	}
+	if n <= 0 || beatType <= 0 {
+		return
+	}
+	if s.BeatsPerBar == 0 {
+		s.BeatsPerBar, s.BeatUnit = n, beatType
+	} else if s.BeatsPerBar != n || s.BeatUnit != beatType {
+		s.warn("time signature changes are ignored, using %d/%d", s.BeatsPerBar, s.BeatUnit)
+	}
+}
+
+// defaults подставляет темп и размер по умолчанию (120, 4/4)
+func (s *Score) defaults() {
+	if s.Tempo == 0 {
+		s.Tempo = DefaultTempo
+	}
+	switch s.BeatUnit {
+	case 1, 2, 4, 8, 16:
+	default:
+		if s.BeatUnit != 0 {
+			s.warn("unsupported time signature %d/%d, using 4/4", s.BeatsPerBar, s.BeatUnit)
+		}
+		s.BeatsPerBar, s.BeatUnit = 4, 4
+	}
+}
+
+// chordsFromNotes определяет аккорды по одновременно звучащим нотам: в каждый
+// момент, когда начинается нота, берутся все звучащие ноты. Если их больше
+// трёх, сначала пробуем без верхней — обычно это мелодия с проходящими
+// звуками. Записываются только смены аккорда; моменты, где аккорд не
+// распознан, пропускаются.
+func chordsFromNotes(notes []Note) []ChordSymbol {
+	onsets := make([]float64, 0, len(notes))
+	for _, n := range notes {
+		onsets = append(onsets, n.Start)
+	}
+	sort.Float64s(onsets)
+
+	var chords []ChordSymbol
+	for i, t := range onsets {
+		if i > 0 && onsets[i-1] == t {
+			continue
+		}
+		var sounding []int
+		for _, n := range notes {
+			if n.Start <= t && t < n.Start+n.Duration {
+				sounding = append(sounding, n.MIDI)
+			}
+		}
+		sort.Sort(sort.Reverse(sort.IntSlice(sounding)))
+		chord, ok := music.Chord{}, false
+		if len(sounding) > 3 {
+			chord, ok = music.IdentifyChord(sounding[1:], false)
+		}
+		if !ok {
+			if chord, ok = music.IdentifyChord(sounding, false); !ok {
+				continue
+			}
+		}
+		symbol := chord.String()
+		if k := len(chords); k > 0 && chords[k-1].Symbol == symbol {
+			continue
+		}
+		chords = append(chords, ChordSymbol{Start: t, Symbol: symbol})
+	}
+	return chords
+}
+
+// Parse определяет формат по содержимому: MIDI ("MThd"), .mxl (zip) или MusicXML
+func Parse(data []byte) (*Score, error) {
+	switch {
+	case bytes.HasPrefix(data, []byte("MThd")):
+		return ParseMIDI(bytes.NewReader(data))
+	case bytes.HasPrefix(data, []byte("PK\x03\x04")):
+		return ParseMXL(data)
+	default:
+		return ParseMusicXML(bytes.NewReader(data))
+	}
+}
+
+// InstrumentFromProgram сопоставляет инструмент General MIDI (0–127)
+// инструменту уроков: фортепиано и электропиано — piano, гитары — guitar,
+// басы — bass
+func InstrumentFromProgram(program int) string {
+	switch {
+	case program >= 0 && program <= 7:
+		return instruments.Piano
+	case program >= 24 && program <= 31:
+		return instruments.Guitar
+	case program >= 32 && program <= 39:
+		return instruments.Bass
+	}
+	return ""
+}
+
+// InstrumentFromName распознаёт инструмент по названию партии
+func InstrumentFromName(name string) string {
+	return instruments.FromName(name)
+}
+
+// detectInstrument — по program, затем по названию партии
+func (p *Part) detectInstrument() {
+	if p.Instrument = InstrumentFromProgram(p.Program); p.Instrument == "" {
+		p.Instrument = InstrumentFromName(p.Name)
+	}
+}
+
+// Melody возвращает мелодию партии: из одновременно звучащих нот берётся
+// верхняя, длительность обрезается до следующей ноты, а паузы прибавляются
+// к предыдущей ноте (в последовательности нет шагов-пауз).
+func (p *Part) Melody() []Note {
+	notes := append([]Note(nil), p.Notes...)
+	sort.SliceStable(notes, func(i, j int) bool {
+		if notes[i].Start != notes[j].Start {
+			return notes[i].Start < notes[j].Start
+		}
+		return notes[i].MIDI > notes[j].MIDI
+	})
+
+	var melody []Note
+	for _, n := range notes {
+		if k := len(melody); k > 0 && melody[k-1].Start == n.Start {
+			if melody[k-1].Lyric == "" {
+				melody[k-1].Lyric = n.Lyric
+			}
+			continue
+		}
+		melody = append(melody, n)
+	}
+	for i := range melody {
+		end := p.End
+		if i+1 < len(melody) {
+			end = melody[i+1].Start
+		}
+		if end > melody[i].Start {
+			melody[i].Duration = end - melody[i].Start
+		}
+	}
+	return melody
+}
+
+// HasMelody и HasChords сообщают, из чего можно построить последовательность
+func (p *Part) HasMelody() bool { return len(p.Notes) > 0 }
+func (p *Part) HasChords() bool { return len(p.Chords) > 0 }
+
+// Sequence строит последовательность для упражнения. stepType — "note"
+// (мелодия) или "chord" (обозначения аккордов); пустой — аккорды, если
+// они есть, иначе мелодия.
+func (s *Score) Sequence(p *Part, stepType, title string) (models.Sequence, error) {
+	if stepType == "" {
+		stepType = models.ExerciseTypeNote
+		if p.HasChords() {
+			stepType = models.ExerciseTypeChord
+		}
+	}
+	if title == "" {
+		title = s.Title
+	}
+	if title == "" {
+		title = p.Name
+	}
+
+	// Длительности в последовательности — в долях размера (в 6/8 доля — восьмая)
+	scale := float64(s.BeatUnit) / 4
+	seq := models.Sequence{
+		SongTitle:   title,
+		StepType:    stepType,
+		Tempo:       int(math.Round(s.Tempo * scale)),
+		BeatsPerBar: s.BeatsPerBar,
+		BeatUnit:    s.BeatUnit,
+	}
+	beats := func(quarters float64) float64 {
+		return math.Round(quarters*scale*1000) / 1000
+	}
+
+	switch stepType {
+	case models.ExerciseTypeNote:
+		for _, n := range p.Melody() {
+			step := models.SequenceStep{
+				Symbol: music.NoteFromMIDI(n.MIDI, false).String(),
+				Beats:  beats(n.Duration),
+			}
+			if n.Lyric != "" {
+				lyric := n.Lyric
+				step.Lyric = &lyric
+			}
+			seq.Steps = append(seq.Steps, step)
+		}
+	case models.ExerciseTypeChord:
+		for i, c := range p.Chords {
+			end := p.End
+			if i+1 < len(p.Chords) {
+				end = p.Chords[i+1].Start
+			}
+			if end <= c.Start {
+				continue
+			}
+			seq.Steps = append(seq.Steps, models.SequenceStep{Symbol: c.Symbol, Beats: beats(end - c.Start)})
+		}
+	default:
+		return seq, fmt.Errorf("notation: step type must be note or chord, got %q", stepType)
+	}
+
+	if len(seq.Steps) == 0 {
+		return seq, fmt.Errorf("notation: part %q has no %ss", p.Name, stepType)
+	}
+	if _, err := seq.Validate(); err != nil {
+		return seq, fmt.Errorf("notation: part %q: %w", p.Name, err)
+	}
+	return seq, nil
+}
+
+// ErrOutOfRange — в мелодии есть ноты, которые нельзя сыграть на инструменте
+var ErrOutOfRange = errors.New("notation: notes out of instrument range")
+
+// CheckRange проверяет, что все ноты последовательности лежат в диапазоне
+// инструмента в строе урока. Для аккордов проверка не нужна: их можно взять
+// в любой позиции.
+func CheckRange(setup instruments.Setup, seq *models.Sequence) error {
+	if seq.StepType != models.ExerciseTypeNote {
+		return nil
+	}
+	r, err := setup.Range()
+	if err != nil {
+		return err
+	}
+	var bad []string
+	for _, i := range r.Outside(seq.Symbols()) {
+		bad = append(bad, fmt.Sprintf("steps[%d] %s", i, seq.Steps[i].Symbol))
+	}
+	if len(bad) > 0 {
+		return fmt.Errorf("%w: %s range is %s: %s", ErrOutOfRange, setup, r, strings.Join(bad, ", "))
+	}
+	return nil
+}
+
+// quantize округляет время в четвертях до сетки grid
+func quantize(quarters float64) float64 {
+	return math.Round(quarters/grid) * grid
+}
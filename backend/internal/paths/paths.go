@@ -0,0 +1,78 @@
+// Package paths вычисляет состояние учебной траектории (курса): какие уроки
+// открыты с учётом пререквизитов и какой урок стоит пройти следующим.
+package paths
+
+// DefaultMinPercent — сколько процентов урока-пререквизита нужно пройти по умолчанию
+const DefaultMinPercent = 80
+
+// Prerequisite — правило "урок открывается после MinPercent% урока LessonID"
+type Prerequisite struct {
+	LessonID   int64
+	MinPercent int
+}
+
+// Lesson — урок курса в порядке прохождения (модуль, затем позиция в модуле)
+type Lesson struct {
+	ID            int64
+	Percent       float64 // доля пройденных упражнений, 0..100
+	Prerequisites []Prerequisite
+}
+
+// Requirement — проверенный пререквизит с текущим прогрессом
+type Requirement struct {
+	LessonID   int64   `json:"lesson_id"`
+	MinPercent int     `json:"min_percent"`
+	Percent    float64 `json:"percent"`
+	Met        bool    `json:"met"`
+}
+
+// State — состояние урока для пользователя
+type State struct {
+	LessonID     int64         `json:"lesson_id"`
+	Percent      float64       `json:"percent"`
+	Completed    bool          `json:"completed"`
+	Locked       bool          `json:"locked"`
+	Requirements []Requirement `json:"requirements"`
+}
+
+// Evaluate проверяет пререквизиты каждого урока. percents — прогресс по всем
+// урокам-пререквизитам (они могут быть и вне курса); урок, которого нет
+// в percents, считается не начатым.
+func Evaluate(lessons []Lesson, percents map[int64]float64) []State {
+	states := make([]State, 0, len(lessons))
+	for _, l := range lessons {
+		st := State{
+			LessonID:     l.ID,
+			Percent:      l.Percent,
+			Completed:    l.Percent >= 100,
+			Requirements: make([]Requirement, 0, len(l.Prerequisites)),
+		}
+		for _, p := range l.Prerequisites {
+			req := Requirement{LessonID: p.LessonID, MinPercent: p.MinPercent, Percent: percents[p.LessonID]}
+			req.Met = req.Percent >= float64(p.MinPercent)
+			if !req.Met {
+				st.Locked = true
+			}
+			st.Requirements = append(st.Requirements, req)
+		}
+		states = append(states, st)
+	}
+	return states
+}
+
+// Next выбирает следующий урок: сначала начатый, но не законченный открытый
+// урок, затем первый открытый и не пройденный по порядку курса.
+// ok == false, если все открытые уроки пройдены.
+func Next(states []State) (State, bool) {
+	for _, st := range states {
+		if !st.Locked && !st.Completed && st.Percent > 0 {
+			return st, true
+		}
+	}
+	for _, st := range states {
+		if !st.Locked && !st.Completed {
+			return st, true
+		}
+	}
+	return State{}, false
+}
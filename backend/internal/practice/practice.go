@@ -0,0 +1,364 @@
+// Package practice считает время занятий. Клиент открывает сессию, раз в
+// HeartbeatInterval присылает сигнал "занимаюсь" с текущим упражнением и
+// закрывает сессию. Активным считается только время между сигналами, если
+// промежуток не длиннее IdleTimeout: более долгий промежуток — ученик
+// отвлёкся, и он не засчитывается. Время промежутка относится к упражнению,
+// которое было текущим в его начале. Попытка упражнения тоже считается
+// сигналом.
+//
+// Сессия без сигналов дольше SessionTimeout закрывается при следующем
+// обращении к ней; у ученика одна открытая сессия, новая закрывает прежнюю.
+package practice
+
+import (
+	"context"
+	"errors"
+	"sort"
+	"time"
+
+	"sonara-space/backend/internal/models"
+
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgconn"
+)
+
+const (
+	// HeartbeatInterval — как часто клиент должен присылать сигнал
+	HeartbeatInterval = 30 * time.Second
+	// IdleTimeout — самый длинный промежуток между сигналами, который ещё засчитывается
+	IdleTimeout = 2 * time.Minute
+	// SessionTimeout — после стольких минут без сигналов сессия закрывается
+	SessionTimeout = 30 * time.Minute
+)
+
+// Почему закрыта сессия
+const (
+	EndUser     = "user"     // ученик закрыл сам
+	EndIdle     = "idle"     // не было сигналов дольше SessionTimeout
+	EndReplaced = "replaced" // ученик открыл новую сессию
+)
+
+var (
+	// ErrNotFound — нет такой сессии у ученика
+	ErrNotFound = errors.New("practice session not found")
+	// ErrEnded — сессия уже закрыта
+	ErrEnded = errors.New("practice session ended")
+)
+
+// DB — методы pgx, нужные пакету (подходят pgxpool.Pool и pgx.Tx).
+// Функции пакета делают несколько запросов и должны вызываться в транзакции.
+type DB interface {
+	Exec(ctx context.Context, sql string, args ...interface{}) (pgconn.CommandTag, error)
+	Query(ctx context.Context, sql string, args ...interface{}) (pgx.Rows, error)
+	QueryRow(ctx context.Context, sql string, args ...interface{}) pgx.Row
+}
+
+// Credit — сколько активного времени засчитать за промежуток между сигналами last и now
+func Credit(last, now time.Time) time.Duration {
+	gap := now.Sub(last)
+	if gap <= 0 || gap > IdleTimeout {
+		return 0
+	}
+	return gap
+}
+
+// Attempt — попытка упражнения во время сессии
+type Attempt struct {
+	ExerciseID int64
+	Title      string
+	Score      float64
+	Passed     bool
+}
+
+// ExerciseSummary — итог сессии по одному упражнению
+type ExerciseSummary struct {
+	ExerciseID    int64   `json:"exercise_id"`
+	Title         string  `json:"title"`
+	ActiveSeconds int     `json:"active_seconds"`
+	Attempts      int     `json:"attempts"`
+	Passed        int     `json:"passed"`
+	BestScore     float64 `json:"best_score"`
+}
+
+// Summary — итог сессии
+type Summary struct {
+	ActiveSeconds      int               `json:"active_seconds"`
+	ExercisesAttempted int               `json:"exercises_attempted"`
+	Attempts           int               `json:"attempts"`
+	Passed             int               `json:"passed"`
+	Accuracy           float64           `json:"accuracy"`      // доля удачных попыток, %
+	AverageScore       float64           `json:"average_score"` // средняя оценка попыток
+	Exercises          []ExerciseSummary `json:"exercises"`
+}
+
+// Summarize собирает итог сессии из активного времени по упражнениям
+// (seconds; titles — их названия) и попыток. Упражнения — по убыванию времени.
+func Summarize(activeSeconds int, seconds map[int64]int, titles map[int64]string, attempts []Attempt) Summary {
+	s := Summary{ActiveSeconds: activeSeconds, Exercises: []ExerciseSummary{}}
+	byID := make(map[int64]*ExerciseSummary)
+	get := func(id int64, title string) *ExerciseSummary {
+		e, ok := byID[id]
+		if !ok {
+			e = &ExerciseSummary{ExerciseID: id, Title: title}
+			byID[id] = e
+		}
+		return e
+	}
+	for id, sec := range seconds {
+		get(id, titles[id]).ActiveSeconds = sec
+	}
+
+	var total float64
+	for _, a := range attempts {
+		e := get(a.ExerciseID, a.Title)
+		if e.Attempts == 0 {
+			s.ExercisesAttempted++
+		}
+		e.Attempts++
+		e.BestScore = max(e.BestScore, a.Score)
+		if a.Passed {
+			e.Passed++
+			s.Passed++
+		}
+		s.Attempts++
+		total += a.Score
+	}
+	if s.Attempts > 0 {
+		s.Accuracy = round1(float64(s.Passed) / float64(s.Attempts) * 100)
+		s.AverageScore = round1(total / float64(s.Attempts))
+	}
+
+	for _, e := range byID {
+		s.Exercises = append(s.Exercises, *e)
+	}
+	sort.Slice(s.Exercises, func(i, j int) bool {
+		a, b := s.Exercises[i], s.Exercises[j]
+		if a.ActiveSeconds != b.ActiveSeconds {
+			return a.ActiveSeconds > b.ActiveSeconds
+		}
+		return a.ExerciseID < b.ExerciseID
+	})
+	return s
+}
+
+func round1(v float64) float64 {
+	return float64(int(v*10+0.5)) / 10
+}
+
+// session — строка practice_sessions, заблокированная для изменения
+type session struct {
+	id         int64
+	exerciseID *int64
+	last       time.Time
+	ended      bool
+	now        time.Time
+}
+
+func lock(ctx context.Context, db DB, userID, sessionID int64) (session, error) {
+	var s session
+	err := db.QueryRow(ctx, `
+		SELECT id, exercise_id, last_heartbeat_at, ended_at IS NOT NULL, LOCALTIMESTAMP
+		FROM practice_sessions WHERE id = $1 AND user_id = $2
+		FOR UPDATE
+	`, sessionID, userID).Scan(&s.id, &s.exerciseID, &s.last, &s.ended, &s.now)
+	if errors.Is(err, pgx.ErrNoRows) {
+		return s, ErrNotFound
+	}
+	return s, err
+}
+
+// credit засчитывает промежуток от последнего сигнала до now текущему упражнению
+func (s session) credit(ctx context.Context, db DB) (time.Duration, error) {
+	d := Credit(s.last, s.now)
+	// Округляем, а не отбрасываем доли секунды: иначе на каждом сигнале терялось бы до секунды
+	sec := int(d.Round(time.Second) / time.Second)
+	if sec == 0 {
+		return d, nil
+	}
+	if _, err := db.Exec(ctx, `UPDATE practice_sessions SET active_seconds = active_seconds + $2 WHERE id = $1`, s.id, sec); err != nil {
+		return 0, err
+	}
+	if s.exerciseID != nil {
+		_, err := db.Exec(ctx, `
+			INSERT INTO practice_session_exercises (session_id, exercise_id, active_seconds)
+			VALUES ($1, $2, $3)
+			ON CONFLICT (session_id, exercise_id)
+			DO UPDATE SET active_seconds = practice_session_exercises.active_seconds + EXCLUDED.active_seconds
+		`, s.id, *s.exerciseID, sec)
+		if err != nil {
+			return 0, err
+		}
+	}
+	return d, nil
+}
+
+// finish закрывает сессию. Если ученик давно не присылал сигналов, сессия
+// закрывается временем последнего сигнала.
+func (s session) finish(ctx context.Context, db DB, reason string) error {
+	d, err := s.credit(ctx, db)
+	if err != nil {
+		return err
+	}
+	_, err = db.Exec(ctx, `
+		UPDATE practice_sessions SET ended_at = $2, last_heartbeat_at = $2, end_reason = $3 WHERE id = $1
+	`, s.id, s.last.Add(d), reason)
+	return err
+}
+
+// Start открывает сессию ученика; открытая прежде сессия закрывается
+func Start(ctx context.Context, db DB, userID int64, lessonID, exerciseID *int64) (int64, error) {
+	var prev int64
+	err := db.QueryRow(ctx, `
+		SELECT id FROM practice_sessions WHERE user_id = $1 AND ended_at IS NULL
+	`, userID).Scan(&prev)
+	switch {
+	case err == nil:
+		s, err := lock(ctx, db, userID, prev)
+		if err != nil {
+			return 0, err
+		}
+		reason := EndReplaced
+		if s.now.Sub(s.last) > SessionTimeout {
+			reason = EndIdle
+		}
+		if err := s.finish(ctx, db, reason); err != nil {
+			return 0, err
+		}
+	case !errors.Is(err, pgx.ErrNoRows):
+		return 0, err
+	}
+
+	var id int64
+	err = db.QueryRow(ctx, `
+		INSERT INTO practice_sessions (user_id, lesson_id, exercise_id, started_at, last_heartbeat_at)
+		VALUES ($1, $2, $3, LOCALTIMESTAMP, LOCALTIMESTAMP)
+		RETURNING id
+	`, userID, lessonID, exerciseID).Scan(&id)
+	return id, err
+}
+
+// Heartbeat засчитывает время с прошлого сигнала и, если exerciseID задан,
+// делает его текущим упражнением. Сессию без сигналов дольше
+// SessionTimeout закрывает и возвращает ErrEnded.
+func Heartbeat(ctx context.Context, db DB, userID, sessionID int64, exerciseID *int64) error {
+	s, err := lock(ctx, db, userID, sessionID)
+	if err != nil {
+		return err
+	}
+	if s.ended {
+		return ErrEnded
+	}
+	if s.now.Sub(s.last) > SessionTimeout {
+		if err := s.finish(ctx, db, EndIdle); err != nil {
+			return err
+		}
+		return ErrEnded
+	}
+	if _, err := s.credit(ctx, db); err != nil {
+		return err
+	}
+	_, err = db.Exec(ctx, `
+		UPDATE practice_sessions SET last_heartbeat_at = $2, exercise_id = COALESCE($3, exercise_id) WHERE id = $1
+	`, s.id, s.now, exerciseID)
+	return err
+}
+
+// Touch — сигнал от попытки упражнения: засчитывается открытой сессии
+// ученика, если она есть
+func Touch(ctx context.Context, db DB, userID, exerciseID int64) error {
+	var id int64
+	err := db.QueryRow(ctx, `
+		SELECT id FROM practice_sessions WHERE user_id = $1 AND ended_at IS NULL
+	`, userID).Scan(&id)
+	if errors.Is(err, pgx.ErrNoRows) {
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+	if err := Heartbeat(ctx, db, userID, id, &exerciseID); err != nil && !errors.Is(err, ErrEnded) {
+		return err
+	}
+	return nil
+}
+
+// End закрывает сессию. Повторный вызов для закрытой сессии — не ошибка.
+func End(ctx context.Context, db DB, userID, sessionID int64) error {
+	s, err := lock(ctx, db, userID, sessionID)
+	if err != nil || s.ended {
+		return err
+	}
+	reason := EndUser
+	if s.now.Sub(s.last) > SessionTimeout {
+		reason = EndIdle
+	}
+	return s.finish(ctx, db, reason)
+}
+
+// Load возвращает сессию ученика с итогом. Попытки сессии — записанные
+// между её началом и концом (для открытой — до текущего момента).
+func Load(ctx context.Context, db DB, userID, sessionID int64) (models.PracticeSession, Summary, error) {
+	var ps models.PracticeSession
+	err := db.QueryRow(ctx, `
+		SELECT id, user_id, lesson_id, exercise_id, started_at, last_heartbeat_at, ended_at, end_reason, active_seconds
+		FROM practice_sessions WHERE id = $1 AND user_id = $2
+	`, sessionID, userID).Scan(&ps.ID, &ps.UserID, &ps.LessonID, &ps.ExerciseID, &ps.StartedAt,
+		&ps.LastHeartbeatAt, &ps.EndedAt, &ps.EndReason, &ps.ActiveSeconds)
+	if errors.Is(err, pgx.ErrNoRows) {
+		return ps, Summary{}, ErrNotFound
+	}
+	if err != nil {
+		return ps, Summary{}, err
+	}
+
+	seconds := make(map[int64]int)
+	titles := make(map[int64]string)
+	rows, err := db.Query(ctx, `
+		SELECT se.exercise_id, x.title, se.active_seconds
+		FROM practice_session_exercises se
+		JOIN exercises x ON x.id = se.exercise_id
+		WHERE se.session_id = $1
+	`, sessionID)
+	if err != nil {
+		return ps, Summary{}, err
+	}
+	for rows.Next() {
+		var id int64
+		var title string
+		var sec int
+		if err := rows.Scan(&id, &title, &sec); err != nil {
+			rows.Close()
+			return ps, Summary{}, err
+		}
+		seconds[id], titles[id] = sec, title
+	}
+	rows.Close()
+	if err := rows.Err(); err != nil {
+		return ps, Summary{}, err
+	}
+
+	rows, err = db.Query(ctx, `
+		SELECT a.exercise_id, x.title, a.score, a.passed
+		FROM exercise_attempts a
+		JOIN exercises x ON x.id = a.exercise_id
+		WHERE a.user_id = $1 AND NOT a.carried AND a.status <> $4
+		  AND a.created_at >= $2 AND a.created_at <= COALESCE($3, LOCALTIMESTAMP)
+		ORDER BY a.created_at, a.id
+	`, userID, ps.StartedAt, ps.EndedAt, models.StatusInProgress)
+	if err != nil {
+		return ps, Summary{}, err
+	}
+	defer rows.Close()
+	var attempts []Attempt
+	for rows.Next() {
+		var a Attempt
+		if err := rows.Scan(&a.ExerciseID, &a.Title, &a.Score, &a.Passed); err != nil {
+			return ps, Summary{}, err
+		}
+		attempts = append(attempts, a)
+	}
+	if err := rows.Err(); err != nil {
+		return ps, Summary{}, err
+	}
+	return ps, Summarize(ps.ActiveSeconds, seconds, titles, attempts), nil
+}
@@ -0,0 +1,171 @@
+// Package progress сводит прогресс ученика: сводку по упражнению из истории
+// попыток, процент пройденного, итог по урокам и следующее непройденное
+// упражнение урока. Данные читают обработчики, здесь — только правила подсчёта.
+package progress
+
+import (
+	"sort"
+	"time"
+
+	"sonara-space/backend/internal/models"
+)
+
+// StatusNotStarted — статус упражнения без единой попытки
+const StatusNotStarted = "not_started"
+
+// Lesson — прогресс по одному уроку
+type Lesson struct {
+	ID           int64
+	Instrument   string
+	Total        int // упражнений в версии урока, которую проходит ученик
+	Completed    int
+	LastActivity *time.Time // nil — ученик урок не начинал
+}
+
+// Totals — общий итог по урокам
+type Totals struct {
+	Lessons            int
+	CompletedLessons   int
+	TotalExercises     int
+	CompletedExercises int
+	Percent            float64
+	LastActivity       *time.Time
+}
+
+// Percent — доля пройденного в процентах; для пустого урока 0
+func Percent(completed, total int) float64 {
+	if total <= 0 {
+		return 0
+	}
+	return float64(completed) / float64(total) * 100
+}
+
+// Started оставляет уроки инструментов, которыми ученик занимался (начат
+// хотя бы один урок инструмента). Пока не начат ни один урок, остаются все.
+func Started(lessons []Lesson) []Lesson {
+	instruments := make(map[string]bool)
+	for _, l := range lessons {
+		if l.LastActivity != nil {
+			instruments[l.Instrument] = true
+		}
+	}
+	if len(instruments) == 0 {
+		return lessons
+	}
+	res := make([]Lesson, 0, len(lessons))
+	for _, l := range lessons {
+		if instruments[l.Instrument] {
+			res = append(res, l)
+		}
+	}
+	return res
+}
+
+// Sum складывает прогресс уроков. Урок пройден, если пройдены все его упражнения.
+func Sum(lessons []Lesson) Totals {
+	var t Totals
+	for _, l := range lessons {
+		t.Lessons++
+		if l.Total > 0 && l.Completed >= l.Total {
+			t.CompletedLessons++
+		}
+		t.TotalExercises += l.Total
+		t.CompletedExercises += l.Completed
+		t.LastActivity = Latest(t.LastActivity, l.LastActivity)
+	}
+	t.Percent = Percent(t.CompletedExercises, t.TotalExercises)
+	return t
+}
+
+// Latest — более позднее из двух времён (nil — нет времени)
+func Latest(a, b *time.Time) *time.Time {
+	if a == nil || (b != nil && b.After(*a)) {
+		return b
+	}
+	return a
+}
+
+// Next — индекс первого непройденного упражнения урока (упражнения — по
+// порядку) или -1, если пройдены все
+func Next(completed []bool) int {
+	for i, done := range completed {
+		if !done {
+			return i
+		}
+	}
+	return -1
+}
+
+// Exercise — сводка по упражнению из истории попыток (строка progress)
+type Exercise struct {
+	Status         string
+	Completed      bool
+	Attempts       int
+	BestScore      float64
+	LastScore      float64
+	ScoredByServer bool
+	CompletedAt    *time.Time
+	FirstAt        time.Time
+	LastAt         time.Time
+}
+
+// verified — попытка оценена сервером или перенесена из старого прогресса.
+// Оценку в отметке клиента сервер проверить не может: лучшую оценку она не даёт.
+func verified(a models.Attempt) bool {
+	return a.Source != models.AttemptSourceClient
+}
+
+// Retries — число неудачных проверенных попыток в истории: за них снимается
+// штраф. Отметки клиента не считаются — иначе каждое нажатие "не получилось"
+// снижало бы оценку следующей записи.
+func Retries(history []models.Attempt) int {
+	n := 0
+	for _, a := range history {
+		if verified(a) && a.Status == models.StatusFailed {
+			n++
+		}
+	}
+	return n
+}
+
+// FromAttempts сводит историю попыток упражнения; ok == false, если попыток нет.
+// Упражнение пройдено, если прошла хотя бы одна попытка: пока клиент не
+// присылает записи, его отметка "сыграл" тоже зачитывает упражнение. Иначе
+// статус — статус последней попытки.
+func FromAttempts(history []models.Attempt) (e Exercise, ok bool) {
+	if len(history) == 0 {
+		return e, false
+	}
+	sorted := append([]models.Attempt(nil), history...)
+	sort.SliceStable(sorted, func(i, j int) bool {
+		a, b := sorted[i], sorted[j]
+		if !a.CreatedAt.Equal(b.CreatedAt) {
+			return a.CreatedAt.Before(b.CreatedAt)
+		}
+		return a.ID < b.ID
+	})
+
+	for _, a := range sorted {
+		e.Attempts++
+		if a.Source == models.AttemptSourceServer {
+			e.ScoredByServer = true
+		}
+		if verified(a) {
+			e.BestScore = max(e.BestScore, a.Score)
+		}
+		if a.Passed && !e.Completed {
+			e.Completed = true
+			at := a.CreatedAt
+			e.CompletedAt = &at
+		}
+	}
+
+	first, last := sorted[0], sorted[len(sorted)-1]
+	e.FirstAt, e.LastAt = first.CreatedAt, last.CreatedAt
+	e.LastScore = last.Score
+	e.Status = last.Status
+	if e.Completed {
+		e.Status = models.StatusDone
+	}
+	return e, true
+}
@@ -0,0 +1,152 @@
+// Package reviews планирует повторение пройденных упражнений по алгоритму
+// SM-2. Расписание хранится в строке progress: лёгкость (ease), интервал в
+// днях, число успешных повторений подряд и дата следующего повторения.
+//
+// Упражнение встаёт в расписание, когда его впервые прошли. Каждая попытка
+// оценивается по шкале SM-2 (0–5, см. Quality): удачная в день повторения или
+// позже увеличивает интервал, неудачная возвращает упражнение на завтра.
+// Удачные попытки до срока расписание не меняют — повторять чаще не вредно,
+// но и "набить" интервал так нельзя.
+package reviews
+
+import (
+	"context"
+	"math"
+	"time"
+
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgconn"
+)
+
+const (
+	// DefaultEase — лёгкость нового упражнения
+	DefaultEase = 2.5
+	// MinEase — нижняя граница лёгкости: иначе трудные упражнения повторялись бы каждый день
+	MinEase = 1.3
+	// PassQuality — наименьшая оценка, которая считается успешным повторением
+	PassQuality = 3
+)
+
+// DB — методы pgx, нужные пакету (подходят pgxpool.Pool и pgx.Tx)
+type DB interface {
+	Exec(ctx context.Context, sql string, args ...interface{}) (pgconn.CommandTag, error)
+	QueryRow(ctx context.Context, sql string, args ...interface{}) pgx.Row
+}
+
+// Schedule — расписание повторения упражнения. DueAt == nil — упражнение
+// ещё не пройдено и не повторяется.
+type Schedule struct {
+	Ease         float64
+	IntervalDays int
+	Repetitions  int
+	DueAt        *time.Time
+}
+
+// Quality переводит оценку попытки (0–100) в шкалу SM-2: 5 — безупречно,
+// 4 — уверенно, 3 — пройдено с трудом, 2 и ниже — не пройдено.
+func Quality(score float64, passed bool) int {
+	switch {
+	case passed && score >= 95:
+		return 5
+	case passed && score >= 85:
+		return 4
+	case passed:
+		return PassQuality
+	case score >= 50:
+		return 2
+	case score > 0:
+		return 1
+	}
+	return 0
+}
+
+// ReportedQuality — оценка отметки клиента без записи: сервер не может
+// проверить исполнение, поэтому "сыграл" — самое слабое прохождение,
+// "не сыграл" — провал.
+func ReportedQuality(passed bool) int {
+	if passed {
+		return PassQuality
+	}
+	return 0
+}
+
+// Next возвращает расписание после попытки с оценкой quality в момент now
+// и сообщает, изменилось ли оно. Следующее повторение назначается на начало дня.
+func Next(s Schedule, quality int, now time.Time) (Schedule, bool) {
+	passed := quality >= PassQuality
+	switch {
+	case s.DueAt == nil && !passed:
+		// Ещё не пройдено — повторять нечего
+		return s, false
+	case s.DueAt != nil && passed && now.Before(*s.DueAt):
+		return s, false
+	}
+
+	if s.Ease == 0 {
+		s.Ease = DefaultEase
+	}
+	q := float64(5 - quality)
+	s.Ease = math.Max(MinEase, math.Round((s.Ease+0.1-q*(0.08+q*0.02))*100)/100)
+
+	switch {
+	case !passed:
+		s.Repetitions, s.IntervalDays = 0, 1
+	case s.Repetitions == 0:
+		s.Repetitions, s.IntervalDays = 1, 1
+	case s.Repetitions == 1:
+		s.Repetitions, s.IntervalDays = 2, 6
+	default:
+		s.Repetitions++
+		s.IntervalDays = int(math.Round(float64(s.IntervalDays) * s.Ease))
+	}
+
+	day := time.Date(now.Year(), now.Month(), now.Day(), 0, 0, 0, 0, now.Location())
+	due := day.AddDate(0, 0, s.IntervalDays)
+	s.DueAt = &due
+	return s, true
+}
+
+// Record обновляет расписание упражнения после попытки с оценкой quality
+// (Quality или ReportedQuality). Вызывается в
+// транзакции записи попытки, после пересчёта строки progress. Время берётся
+// из базы, чтобы даты не зависели от часового пояса сервера.
+func Record(ctx context.Context, db DB, userID, exerciseID int64, quality int) error {
+	var s Schedule
+	var now time.Time
+	err := db.QueryRow(ctx, `
+		SELECT ease, interval_days, repetitions, due_at, LOCALTIMESTAMP
+		FROM progress WHERE user_id = $1 AND exercise_id = $2
+		FOR UPDATE
+	`, userID, exerciseID).Scan(&s.Ease, &s.IntervalDays, &s.Repetitions, &s.DueAt, &now)
+	if err != nil {
+		return err
+	}
+
+	next, changed := Next(s, quality, now)
+	if !changed {
+		return nil
+	}
+	_, err = db.Exec(ctx, `
+		UPDATE progress SET ease = $3, interval_days = $4, repetitions = $5, due_at = $6, last_reviewed_at = $7
+		WHERE user_id = $1 AND exercise_id = $2
+	`, userID, exerciseID, next.Ease, next.IntervalDays, next.Repetitions, next.DueAt, now)
+	return err
+}
+
+// Carry переносит расписание со старого упражнения на новое при переходе
+// ученика на новую версию урока (если у нового своего расписания ещё нет).
+// Старое упражнение из очереди убирается.
+func Carry(ctx context.Context, db DB, userID, from, to int64) error {
+	_, err := db.Exec(ctx, `
+		UPDATE progress p SET ease = o.ease, interval_days = o.interval_days,
+			repetitions = o.repetitions, due_at = o.due_at, last_reviewed_at = o.last_reviewed_at
+		FROM progress o
+		WHERE p.user_id = $1 AND p.exercise_id = $3 AND p.due_at IS NULL
+		  AND o.user_id = $1 AND o.exercise_id = $2 AND o.due_at IS NOT NULL
+	`, userID, from, to)
+	if err != nil {
+		return err
+	}
+	_, err = db.Exec(ctx, `UPDATE progress SET due_at = NULL WHERE user_id = $1 AND exercise_id = $2`, userID, from)
+	return err
+}
@@ -0,0 +1,168 @@
+// Package rhythm сверяет сыгранные удары с ритмическим рисунком: каждому
+// ожидаемому удару подбирается ближайший сыгранный, по отклонению ставятся
+// вердикт (точно, хорошо, неточно, мимо), направление (рано или поздно) и балл.
+//
+// Баллы удара: в окне Perfect — 100, до окна Good — линейно до 75, до окна
+// Miss — до 40, дальше — 0 (удар пропущен). Каждый лишний удар, которому
+// нет пары в рисунке, добавляется как шаг с оценкой 0.
+package rhythm
+
+import (
+	"math"
+	"sort"
+)
+
+// Вердикты по удару
+const (
+	Perfect = "perfect"
+	Good    = "good"
+	Off     = "off"
+	Missed  = "missed"
+)
+
+// Направление отклонения
+const (
+	Early = "early"
+	Late  = "late"
+)
+
+// Windows — окна допуска по модулю отклонения, мс
+type Windows struct {
+	Perfect float64
+	Good    float64
+	Miss    float64
+}
+
+// Hit — результат по одному ожидаемому удару
+type Hit struct {
+	Index       int      `json:"index"`                  // номер удара в рисунке (паузы не считаются)
+	ExpectedMs  float64  `json:"expected_ms"`            // от первой доли
+	PlayedMs    *float64 `json:"played_ms,omitempty"`    // nil — удар пропущен
+	DeviationMs *float64 `json:"deviation_ms,omitempty"` // меньше нуля — раньше, больше — позже
+	Verdict     string   `json:"verdict"`
+	Timing      string   `json:"timing,omitempty"` // early или late, если удар вне окна Perfect
+	Score       float64  `json:"score"`
+}
+
+// Result — сверка всей попытки
+type Result struct {
+	Hits               []Hit     `json:"hits"`
+	Extra              []float64 `json:"extra_ms"`          // лишние удары, мс
+	MeanDeviationMs    float64   `json:"mean_deviation_ms"` // среднее со знаком: склонность спешить или отставать
+	MeanAbsDeviationMs float64   `json:"mean_abs_deviation_ms"`
+}
+
+// Steps возвращает баллы для scoring: по удару рисунка и ноль за каждый лишний удар
+func (r Result) Steps() []float64 {
+	steps := make([]float64, 0, len(r.Hits)+len(r.Extra))
+	for _, h := range r.Hits {
+		steps = append(steps, h.Score)
+	}
+	for range r.Extra {
+		steps = append(steps, 0)
+	}
+	return steps
+}
+
+// Match сопоставляет сыгранные удары (мс от первой доли) с ожидаемыми.
+// Удар засчитывается ожидаемому, если он ближе к нему, чем к соседним,
+// и отклоняется не больше чем на окно Miss.
+func Match(expected, played []float64, w Windows) Result {
+	played = append([]float64(nil), played...)
+	sort.Float64s(played)
+
+	res := Result{Hits: make([]Hit, len(expected)), Extra: []float64{}}
+	used := make([]bool, len(played))
+	next := 0
+	for i, e := range expected {
+		res.Hits[i] = Hit{Index: i, ExpectedMs: e, Verdict: Missed}
+
+		// Граница "своей" территории удара — середина до соседних
+		low, high := math.Inf(-1), math.Inf(1)
+		if i > 0 {
+			low = (expected[i-1] + e) / 2
+		}
+		if i+1 < len(expected) {
+			high = (e + expected[i+1]) / 2
+		}
+
+		best := -1
+		for j := next; j < len(played) && played[j] < high; j++ {
+			if played[j] < low || math.Abs(played[j]-e) > w.Miss {
+				continue
+			}
+			if best < 0 || math.Abs(played[j]-e) < math.Abs(played[best]-e) {
+				best = j
+			}
+		}
+		if best < 0 {
+			continue
+		}
+		used[best] = true
+		next = best + 1
+
+		p := played[best]
+		d := p - e
+		res.Hits[i].PlayedMs = &p
+		res.Hits[i].DeviationMs = &d
+		res.Hits[i].Verdict, res.Hits[i].Score = judge(d, w)
+		if res.Hits[i].Verdict != Perfect {
+			res.Hits[i].Timing = Late
+			if d < 0 {
+				res.Hits[i].Timing = Early
+			}
+		}
+	}
+	for j, p := range played {
+		if !used[j] {
+			res.Extra = append(res.Extra, p)
+		}
+	}
+
+	var sum, abs float64
+	n := 0
+	for _, h := range res.Hits {
+		if h.DeviationMs != nil {
+			sum += *h.DeviationMs
+			abs += math.Abs(*h.DeviationMs)
+			n++
+		}
+	}
+	if n > 0 {
+		res.MeanDeviationMs = round1(sum / float64(n))
+		res.MeanAbsDeviationMs = round1(abs / float64(n))
+	}
+	return res
+}
+
+// Align сдвигает сыгранные удары так, чтобы первый совпал с первой долей.
+// Нужен, когда отсчёт неизвестен: запись начинается не с метронома.
+func Align(played []float64, firstExpected float64) []float64 {
+	if len(played) == 0 {
+		return nil
+	}
+	first := played[0]
+	for _, p := range played {
+		first = math.Min(first, p)
+	}
+	out := make([]float64, len(played))
+	for i, p := range played {
+		out[i] = p - first + firstExpected
+	}
+	return out
+}
+
+func judge(d float64, w Windows) (string, float64) {
+	a := math.Abs(d)
+	switch {
+	case a <= w.Perfect:
+		return Perfect, 100
+	case a <= w.Good:
+		return Good, round1(100 - 25*(a-w.Perfect)/math.Max(w.Good-w.Perfect, 1))
+	}
+	return Off, round1(75 - 35*(a-w.Good)/math.Max(w.Miss-w.Good, 1))
+}
+
+func round1(v float64) float64 {
+	return math.Round(v*10) / 10
+}
@@ -0,0 +1,176 @@
+// Package scoring переводит результаты анализа попытки (точность высоты тона,
+// полнота аккорда, ритм, число повторов) в оценку 0–100 и решение "пройдено".
+//
+// Правила:
+//   - точность: нота — PitchAccuracy, аккорд — ChordAccuracy, песня и ритм —
+//     среднее по шагам (для ритма шаг — удар, см. пакет rhythm);
+//   - если измерен ритм, итог = 80% точности + 20% TimingAccuracy;
+//   - упражнение пройдено, если итог не ниже порога для его типа (Threshold);
+//   - за каждую предыдущую неудачную попытку снимается RetryPenalty баллов
+//     (не больше MaxRetryPenalty), но штраф не может опустить пройденную
+//     попытку ниже порога — повторы влияют на оценку, а не на зачёт.
+package scoring
+
+import "math"
+
+const (
+	// RetryPenalty — штраф за одну предыдущую неудачную попытку
+	RetryPenalty = 2.0
+	// MaxRetryPenalty — максимальный суммарный штраф за повторы
+	MaxRetryPenalty = 10.0
+	// timingWeight — доля ритма в итоговой оценке, если он измерен
+	timingWeight = 0.2
+	// DefaultThreshold — порог для типов упражнений без своего порога
+	DefaultThreshold = 70.0
+)
+
+// thresholds — проходной балл по типу упражнения. Для одиночной ноты порог
+// выше: её проще сыграть чисто, чем аккорд или целую песню.
+var thresholds = map[string]float64{
+	"note":     80,
+	"chord":    70,
+	"sequence": 65,
+	"rhythm":   70,
+}
+
+// Threshold возвращает проходной балл для типа упражнения
+func Threshold(exerciseType string) float64 {
+	if t, ok := thresholds[exerciseType]; ok {
+		return t
+	}
+	return DefaultThreshold
+}
+
+// Pitch — измерение высоты тона одной ноты
+type Pitch struct {
+	Correct  bool    // попали в нужный полутон
+	CentsOff float64 // отклонение от ожидаемой частоты
+}
+
+// Chord — измерение аккорда
+type Chord struct {
+	Recognized   bool    // распознан ожидаемый аккорд
+	Completeness float64 // доля прозвучавших звуков аккорда (0..1)
+}
+
+// Metrics — всё, что удалось измерить в попытке. Незаполненные составляющие
+// в оценке не участвуют.
+type Metrics struct {
+	Pitch             *Pitch
+	Chord             *Chord
+	Steps             []float64 // точность каждого шага песни (0..100)
+	TimingDeviationMs *float64  // среднее отклонение от ритма по модулю
+	Retries           int       // число предыдущих неудачных попыток
+}
+
+// Result — итог оценки попытки
+type Result struct {
+	Score     float64  `json:"score"`
+	Accuracy  float64  `json:"accuracy"`
+	Timing    *float64 `json:"timing,omitempty"`
+	Penalty   float64  `json:"retry_penalty"`
+	Threshold float64  `json:"threshold"`
+	Passed    bool     `json:"passed"`
+}
+
+// PitchAccuracy: верная нота в пределах ±10 центов — 100 баллов, дальше
+// линейно снижается до 50 баллов на границе полутона (±50 центов);
+// неверная нота — 0.
+func PitchAccuracy(p Pitch) float64 {
+	if !p.Correct {
+		return 0
+	}
+	deviation := math.Min(40, math.Max(0, math.Abs(p.CentsOff)-10))
+	return round2(100 - deviation*50/40)
+}
+
+// ChordAccuracy: распознанный аккорд даёт от 60 до 100 баллов в зависимости
+// от доли прозвучавших звуков, нераспознанный — не больше 50.
+func ChordAccuracy(c Chord) float64 {
+	completeness := clamp(c.Completeness, 0, 1)
+	if c.Recognized {
+		return round2(60 + 40*completeness)
+	}
+	return round2(50 * completeness)
+}
+
+// TimingAccuracy: отклонение до 30 мс на слух незаметно — 100 баллов,
+// дальше линейно до 0 при отклонении в 300 мс и больше.
+func TimingAccuracy(deviationMs float64) float64 {
+	deviation := math.Abs(deviationMs)
+	if deviation <= 30 {
+		return 100
+	}
+	return round2(math.Max(0, 100-(deviation-30)*100/270))
+}
+
+// Evaluate считает оценку попытки упражнения данного типа
+func Evaluate(exerciseType string, m Metrics) Result {
+	var parts []float64
+	if m.Pitch != nil {
+		parts = append(parts, PitchAccuracy(*m.Pitch))
+	}
+	if m.Chord != nil {
+		parts = append(parts, ChordAccuracy(*m.Chord))
+	}
+	if len(m.Steps) > 0 {
+		var sum float64
+		for _, s := range m.Steps {
+			sum += clamp(s, 0, 100)
+		}
+		parts = append(parts, sum/float64(len(m.Steps)))
+	}
+
+	res := Result{Threshold: Threshold(exerciseType)}
+	if len(parts) > 0 {
+		var sum float64
+		for _, p := range parts {
+			sum += p
+		}
+		res.Accuracy = round2(sum / float64(len(parts)))
+	}
+
+	performance := res.Accuracy
+	if m.TimingDeviationMs != nil {
+		timing := TimingAccuracy(*m.TimingDeviationMs)
+		res.Timing = &timing
+		performance = (1-timingWeight)*res.Accuracy + timingWeight*timing
+	}
+	return finish(res, performance, m.Retries)
+}
+
+// Reported — оценка отметки клиента без измерений ("сыграл"/"не сыграл").
+// Зачтённая попытка получает ровно проходной балл: без записи сервер не
+// может подтвердить, что она была лучше.
+func Reported(exerciseType string, passed bool, retries int) Result {
+	res := Result{Threshold: Threshold(exerciseType)}
+	var performance float64
+	if passed {
+		performance = res.Threshold
+	}
+	res.Accuracy = performance
+	return finish(res, performance, retries)
+}
+
+func finish(res Result, performance float64, retries int) Result {
+	performance = clamp(performance, 0, 100)
+	res.Passed = performance >= res.Threshold
+	if retries > 0 {
+		res.Penalty = math.Min(MaxRetryPenalty, RetryPenalty*float64(retries))
+	}
+	score := performance - res.Penalty
+	if res.Passed && score < res.Threshold {
+		score = res.Threshold
+		res.Penalty = round2(performance - res.Threshold)
+	}
+	res.Score = round2(math.Max(0, score))
+	return res
+}
+
+func clamp(v, lo, hi float64) float64 {
+	return math.Max(lo, math.Min(hi, v))
+}
+
+func round2(v float64) float64 {
+	return math.Round(v*100) / 100
+}
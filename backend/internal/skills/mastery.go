@@ -0,0 +1,85 @@
+// Package skills считает, насколько пользователь освоил навык, по его
+// прогрессу в упражнениях, которые этот навык тренируют.
+package skills
+
+// Уровни освоения навыка
+const (
+	LevelNotStarted = "not_started"
+	LevelNovice     = "novice"
+	LevelLearning   = "learning"
+	LevelProficient = "proficient"
+	LevelMastered   = "mastered"
+)
+
+// Пороги уровней по проценту освоения
+const (
+	learningFrom   = 40
+	proficientFrom = 70
+	masteredFrom   = 90
+)
+
+// Exercise — прогресс пользователя в одном упражнении навыка.
+// Упражнение без записи в progress передаётся с нулевыми значениями.
+type Exercise struct {
+	Attempts  int
+	Completed bool
+	BestScore float64 // лучшая оценка, 0..100
+}
+
+// Mastery — освоение навыка
+type Mastery struct {
+	Percent   float64 `json:"percent"`
+	Level     string  `json:"level"`
+	Exercises int     `json:"exercises"`
+	Practiced int     `json:"practiced"`
+	Completed int     `json:"completed"`
+}
+
+// Evaluate считает освоение как среднюю оценку по всем упражнениям навыка:
+// непройденные упражнения дают половину лучшей оценки, не начатые — ноль.
+// "Освоен" навык только если пройдены все его упражнения.
+func Evaluate(exercises []Exercise) Mastery {
+	m := Mastery{Exercises: len(exercises), Level: LevelNotStarted}
+	if len(exercises) == 0 {
+		return m
+	}
+
+	var sum float64
+	for _, e := range exercises {
+		score := clamp(e.BestScore)
+		if e.Completed {
+			m.Completed++
+		} else {
+			score /= 2
+		}
+		if e.Attempts > 0 || e.Completed {
+			m.Practiced++
+		}
+		sum += score
+	}
+	m.Percent = sum / float64(len(exercises))
+
+	switch {
+	case m.Practiced == 0:
+		m.Level = LevelNotStarted
+	case m.Percent >= masteredFrom && m.Completed == m.Exercises:
+		m.Level = LevelMastered
+	case m.Percent >= proficientFrom:
+		m.Level = LevelProficient
+	case m.Percent >= learningFrom:
+		m.Level = LevelLearning
+	default:
+		m.Level = LevelNovice
+	}
+	return m
+}
+
+func clamp(score float64) float64 {
+	if score < 0 {
+		return 0
+	}
+	if score > 100 {
+		return 100
+	}
+	return score
+}
@@ -0,0 +1,312 @@
+// Package stats считает мотивацию ученика: серию дней занятий (с одной
+// "заморозкой" в неделю), опыт (XP) и уровень, выполнение дневной цели.
+//
+// Всё выводится из истории попыток, поэтому пересчёт (Recompute) можно
+// повторять сколько угодно — результат тот же. Таблица user_stats — только
+// сохранённый результат последнего пересчёта для списков и рейтингов.
+package stats
+
+import (
+	"context"
+	"errors"
+	"sort"
+	"time"
+	_ "time/tzdata" // часовые пояса учеников не зависят от системной базы
+
+	"sonara-space/backend/internal/models"
+
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgconn"
+)
+
+// Виды дневной цели
+const (
+	GoalMinutes   = "minutes"   // минут записей за день
+	GoalExercises = "exercises" // пройденных за день упражнений
+)
+
+const (
+	// DefaultTimezone — часовой пояс, пока ученик не выбрал свой
+	DefaultTimezone = "UTC"
+	// FreezesPerWeek — сколько пропущенных дней за неделю (с понедельника) не прерывают серию
+	FreezesPerWeek = 1
+	// MaxGoalTarget — верхняя граница цели (минут в сутках)
+	MaxGoalTarget = 1440
+	// levelStep — опыт для перехода с первого уровня на второй; каждый следующий уровень дороже на столько же
+	levelStep = 100
+)
+
+// ErrGoal — неизвестный вид цели или цель вне 1..MaxGoalTarget
+var ErrGoal = errors.New("invalid goal")
+
+// DB — методы pgx, нужные пакету (подходят pgxpool.Pool и pgx.Tx).
+// Recompute нужно вызывать в транзакции.
+type DB interface {
+	Exec(ctx context.Context, sql string, args ...interface{}) (pgconn.CommandTag, error)
+	Query(ctx context.Context, sql string, args ...interface{}) (pgx.Rows, error)
+	QueryRow(ctx context.Context, sql string, args ...interface{}) pgx.Row
+}
+
+// ValidGoal проверяет вид и размер дневной цели
+func ValidGoal(kind string, target int) error {
+	if (kind != GoalMinutes && kind != GoalExercises) || target < 1 || target > MaxGoalTarget {
+		return ErrGoal
+	}
+	return nil
+}
+
+// ValidTimezone проверяет имя часового пояса IANA (Europe/Moscow)
+func ValidTimezone(name string) bool {
+	if name == "" || name == "Local" {
+		return false
+	}
+	_, err := time.LoadLocation(name)
+	return err == nil
+}
+
+// XPFor — опыт за первое прохождение упражнения урока данной сложности
+func XPFor(difficulty string) int {
+	switch difficulty {
+	case models.DifficultyIntermediate:
+		return 20
+	case models.DifficultyAdvanced:
+		return 40
+	}
+	return 10
+}
+
+// Level — уровень ученика по накопленному опыту
+type Level struct {
+	Level       int `json:"level"`
+	XP          int `json:"xp"`
+	LevelXP     int `json:"level_xp"`      // опыт, с которого начинается текущий уровень
+	NextLevelXP int `json:"next_level_xp"` // опыт, нужный для следующего уровня
+}
+
+// LevelFor считает уровень: для уровня n нужно levelStep·n·(n−1)/2 опыта
+// (0, 100, 300, 600, ...).
+func LevelFor(xp int) Level {
+	l := Level{Level: 1, XP: max(xp, 0)}
+	for levelStart(l.Level+1) <= l.XP {
+		l.Level++
+	}
+	l.LevelXP = levelStart(l.Level)
+	l.NextLevelXP = levelStart(l.Level + 1)
+	return l
+}
+
+func levelStart(level int) int {
+	return levelStep * level * (level - 1) / 2
+}
+
+// Streak — серия дней занятий
+type Streak struct {
+	Current        int  `json:"current"`
+	Longest        int  `json:"longest"`
+	PracticedToday bool `json:"practiced_today"`
+	FreezesLeft    int  `json:"freezes_left"` // на текущей неделе
+}
+
+// Streaks считает серию по дням занятий (даты в часовом поясе ученика,
+// полночь UTC) на дату today. Пропущенный день сжигает заморозку своей
+// недели и не прерывает серию, но и не добавляет к ней; без заморозки серия
+// обрывается. Сегодняшний день ещё не закончился, поэтому его пропуск серию
+// не прерывает.
+func Streaks(days []time.Time, today time.Time) Streak {
+	today = date(today)
+	practiced := make(map[time.Time]bool, len(days))
+	var sorted []time.Time
+	for _, d := range days {
+		d = date(d)
+		if !d.After(today) && !practiced[d] {
+			practiced[d] = true
+			sorted = append(sorted, d)
+		}
+	}
+	sort.Slice(sorted, func(i, j int) bool { return sorted[i].Before(sorted[j]) })
+
+	s := Streak{PracticedToday: practiced[today], FreezesLeft: FreezesPerWeek}
+	if len(sorted) == 0 {
+		return s
+	}
+
+	frozen := make(map[time.Time]int)
+	run := 0
+	for d := sorted[0]; !d.After(today); d = d.AddDate(0, 0, 1) {
+		switch {
+		case practiced[d]:
+			run++
+			s.Longest = max(s.Longest, run)
+		case d.Equal(today):
+		case run > 0 && frozen[weekStart(d)] < FreezesPerWeek:
+			frozen[weekStart(d)]++
+		default:
+			run = 0
+		}
+	}
+	s.Current = run
+	s.FreezesLeft = FreezesPerWeek - frozen[weekStart(today)]
+	return s
+}
+
+// Goal — выполнение дневной цели
+type Goal struct {
+	Kind      string `json:"kind"`
+	Target    int    `json:"target"`
+	Progress  int    `json:"progress"`
+	Completed bool   `json:"completed"`
+}
+
+// GoalProgress считает выполнение цели по минутам записей и числу
+// пройденных за день упражнений
+func GoalProgress(kind string, target, minutes, exercises int) Goal {
+	g := Goal{Kind: kind, Target: target, Progress: minutes}
+	if kind == GoalExercises {
+		g.Progress = exercises
+	}
+	g.Completed = g.Progress >= target
+	return g
+}
+
+// Stats — мотивационная сводка ученика
+type Stats struct {
+	Timezone string
+	Today    time.Time
+	Streak   Streak
+	Level    Level
+	Goal     Goal
+	// Completed — упражнений, за которые начислен опыт
+	Completed int
+}
+
+// Recompute пересчитывает сводку ученика по истории попыток и сохраняет её
+// в user_stats. Попытки, перенесённые при переходе на новую версию урока
+// (carried), повторяют уже учтённые и не считаются; отметки "начал"
+// (in_progress) занятием не считаются.
+func Recompute(ctx context.Context, db DB, userID int64) (Stats, error) {
+	// Параллельные пересчёты одного ученика выполняются по очереди: каждый
+	// следующий видит попытки, записанные предыдущим
+	if _, err := db.Exec(ctx, `SELECT pg_advisory_xact_lock(hashtext('user_stats'), $1::int)`, userID); err != nil {
+		return Stats{}, err
+	}
+
+	var st Stats
+	var goalKind string
+	var goalTarget int
+	err := db.QueryRow(ctx, `
+		SELECT timezone, daily_goal_kind, daily_goal_target, (NOW() AT TIME ZONE timezone)::date
+		FROM users WHERE id = $1
+	`, userID).Scan(&st.Timezone, &goalKind, &goalTarget, &st.Today)
+	if err != nil {
+		return Stats{}, err
+	}
+	st.Today = date(st.Today)
+
+	// Время в БД хранится во времени сервера: переводим в пояс ученика.
+	// День занятий — это день с попытками или с сессией не короче минуты;
+	// время дня — большее из суммы записей и активного времени сессий.
+	rows, err := db.Query(ctx, `
+		WITH a AS (
+			SELECT ((created_at AT TIME ZONE current_setting('TimeZone')) AT TIME ZONE $2)::date AS day,
+			       COALESCE(SUM(duration_ms), 0) AS duration_ms,
+			       COUNT(DISTINCT exercise_id) FILTER (WHERE passed) AS passed
+			FROM exercise_attempts
+			WHERE user_id = $1 AND NOT carried AND status <> $3
+			GROUP BY day
+		), p AS (
+			SELECT ((started_at AT TIME ZONE current_setting('TimeZone')) AT TIME ZONE $2)::date AS day,
+			       SUM(active_seconds) AS active_seconds
+			FROM practice_sessions
+			WHERE user_id = $1
+			GROUP BY day
+			HAVING SUM(active_seconds) >= 60
+		)
+		SELECT day, COALESCE(a.duration_ms, 0), COALESCE(a.passed, 0), COALESCE(p.active_seconds, 0)
+		FROM a FULL JOIN p USING (day)
+	`, userID, st.Timezone, models.StatusInProgress)
+	if err != nil {
+		return Stats{}, err
+	}
+	var days []time.Time
+	var minutes, exercises int
+	for rows.Next() {
+		var day time.Time
+		var durationMs, activeSeconds int64
+		var passed int
+		if err := rows.Scan(&day, &durationMs, &passed, &activeSeconds); err != nil {
+			rows.Close()
+			return Stats{}, err
+		}
+		day = date(day)
+		days = append(days, day)
+		if day.Equal(st.Today) {
+			minutes = int(max(durationMs/60000, activeSeconds/60))
+			exercises = passed
+		}
+	}
+	rows.Close()
+	if err := rows.Err(); err != nil {
+		return Stats{}, err
+	}
+
+	// Опыт — за каждое упражнение, впервые пройденное самим учеником
+	// (а не перенесённой попыткой), по сложности его урока
+	rows, err = db.Query(ctx, `
+		SELECT l.difficulty, COUNT(*)
+		FROM (
+			SELECT DISTINCT ON (exercise_id) exercise_id, carried
+			FROM exercise_attempts
+			WHERE user_id = $1 AND passed
+			ORDER BY exercise_id, created_at, id
+		) f
+		JOIN exercises x ON x.id = f.exercise_id
+		JOIN lessons l ON l.id = x.lesson_id
+		WHERE NOT f.carried
+		GROUP BY l.difficulty
+	`, userID)
+	if err != nil {
+		return Stats{}, err
+	}
+	xp := 0
+	for rows.Next() {
+		var difficulty string
+		var n int
+		if err := rows.Scan(&difficulty, &n); err != nil {
+			rows.Close()
+			return Stats{}, err
+		}
+		xp += XPFor(difficulty) * n
+		st.Completed += n
+	}
+	rows.Close()
+	if err := rows.Err(); err != nil {
+		return Stats{}, err
+	}
+
+	st.Streak = Streaks(days, st.Today)
+	st.Level = LevelFor(xp)
+	st.Goal = GoalProgress(goalKind, goalTarget, minutes, exercises)
+
+	_, err = db.Exec(ctx, `
+		INSERT INTO user_stats (user_id, xp, level, current_streak, longest_streak, completed_exercises, updated_at)
+		VALUES ($1, $2, $3, $4, $5, $6, NOW())
+		ON CONFLICT (user_id) DO UPDATE SET
+			xp = EXCLUDED.xp, level = EXCLUDED.level,
+			current_streak = EXCLUDED.current_streak, longest_streak = EXCLUDED.longest_streak,
+			completed_exercises = EXCLUDED.completed_exercises, updated_at = EXCLUDED.updated_at
+	`, userID, st.Level.XP, st.Level.Level, st.Streak.Current, st.Streak.Longest, st.Completed)
+	if err != nil {
+		return Stats{}, err
+	}
+	return st, nil
+}
+
+// date отбрасывает время и пояс: календарная дата как полночь UTC
+func date(t time.Time) time.Time {
+	return time.Date(t.Year(), t.Month(), t.Day(), 0, 0, 0, 0, time.UTC)
+}
+
+// weekStart — понедельник недели даты d
+func weekStart(d time.Time) time.Time {
+	return d.AddDate(0, 0, -((int(d.Weekday()) + 6) % 7))
+}
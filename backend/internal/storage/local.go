@@ -0,0 +1,130 @@
+package storage
+
+import (
+	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"io"
+	"net/url"
+	"os"
+	"path/filepath"
+	"strconv"
+	"time"
+)
+
+// ErrBadSignature — ссылка подделана или срок её действия истёк
+var ErrBadSignature = errors.New("storage: invalid or expired signature")
+
+// Local хранит файлы в каталоге на диске. Файлы отдаёт сам сервер по
+// ссылкам вида BaseURL + key + "?expires=…&signature=…" (HMAC-SHA256).
+type Local struct {
+	Dir     string
+	BaseURL string
+	secret  []byte
+}
+
+// NewLocal создаёт хранилище в каталоге dir (каталог создаётся при необходимости)
+func NewLocal(dir, baseURL string, secret []byte) (*Local, error) {
+	if len(secret) == 0 {
+		return nil, errors.New("storage: signing key is required for local storage")
+	}
+	if err := os.MkdirAll(dir, 0o755); err != nil {
+		return nil, err
+	}
+	return &Local{Dir: dir, BaseURL: baseURL, secret: secret}, nil
+}
+
+func (l *Local) path(key string) (string, error) {
+	key, err := CleanKey(key)
+	if err != nil {
+		return "", err
+	}
+	return filepath.Join(l.Dir, filepath.FromSlash(key)), nil
+}
+
+// Put записывает файл во временный файл и переименовывает, чтобы
+// недокачанная загрузка не оказалась на месте готового файла
+func (l *Local) Put(ctx context.Context, key string, r io.Reader, size int64, contentType string) error {
+	dst, err := l.path(key)
+	if err != nil {
+		return err
+	}
+	if err := os.MkdirAll(filepath.Dir(dst), 0o755); err != nil {
+		return err
+	}
+	tmp, err := os.CreateTemp(filepath.Dir(dst), ".upload-*")
+	if err != nil {
+		return err
+	}
+	defer os.Remove(tmp.Name())
+
+	n, err := io.Copy(tmp, r)
+	if cerr := tmp.Close(); err == nil {
+		err = cerr
+	}
+	if err != nil {
+		return err
+	}
+	if size >= 0 && n != size {
+		return fmt.Errorf("storage: expected %d bytes, got %d", size, n)
+	}
+	return os.Rename(tmp.Name(), dst)
+}
+
+// Delete удаляет файл
+func (l *Local) Delete(ctx context.Context, key string) error {
+	p, err := l.path(key)
+	if err != nil {
+		return err
+	}
+	if err := os.Remove(p); err != nil && !errors.Is(err, os.ErrNotExist) {
+		return err
+	}
+	return nil
+}
+
+// URL возвращает подписанную ссылку на файл
+func (l *Local) URL(ctx context.Context, key string, expires time.Time) (string, error) {
+	if _, err := CleanKey(key); err != nil {
+		return "", err
+	}
+	u := (&url.URL{Path: key}).EscapedPath()
+	q := url.Values{}
+	q.Set("expires", strconv.FormatInt(expires.Unix(), 10))
+	q.Set("signature", l.sign(key, expires.Unix()))
+	return l.BaseURL + u + "?" + q.Encode(), nil
+}
+
+func (l *Local) sign(key string, expires int64) string {
+	mac := hmac.New(sha256.New, l.secret)
+	fmt.Fprintf(mac, "%s\n%d", key, expires)
+	return hex.EncodeToString(mac.Sum(nil))
+}
+
+// Verify проверяет подпись и срок действия ссылки
+func (l *Local) Verify(key, expires, signature string, now time.Time) error {
+	exp, err := strconv.ParseInt(expires, 10, 64)
+	if err != nil || now.Unix() > exp {
+		return ErrBadSignature
+	}
+	if !hmac.Equal([]byte(l.sign(key, exp)), []byte(signature)) {
+		return ErrBadSignature
+	}
+	return nil
+}
+
+// Open открывает файл для отдачи клиенту (с поддержкой Range через http.ServeContent)
+func (l *Local) Open(key string) (*os.File, error) {
+	p, err := l.path(key)
+	if err != nil {
+		return nil, err
+	}
+	f, err := os.Open(p)
+	if errors.Is(err, os.ErrNotExist) {
+		return nil, ErrNotFound
+	}
+	return f, err
+}
@@ -0,0 +1,126 @@
+package storage
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"strings"
+	"time"
+)
+
+// maxPresign — наибольший срок действия подписанной ссылки в S3
+const maxPresign = 7 * 24 * time.Hour
+
+// S3 хранит объекты в S3-совместимом хранилище. Клиенты скачивают файлы
+// напрямую по подписанным ссылкам, поэтому Range-запросы обрабатывает S3.
+type S3 struct {
+	Endpoint *url.URL // https://s3.eu-central-1.amazonaws.com или http://localhost:9000
+	Bucket   string
+	// PathStyle — адреса вида endpoint/bucket/key (MinIO); иначе bucket.endpoint/key
+	PathStyle bool
+	Signer    Signer
+	Client    *http.Client
+}
+
+func (s *S3) objectURL(key string) (*url.URL, error) {
+	key, err := CleanKey(key)
+	if err != nil {
+		return nil, err
+	}
+	u := *s.Endpoint
+	u.RawQuery = ""
+	base := strings.TrimSuffix(u.Path, "/")
+	if s.PathStyle {
+		u.Path = base + "/" + s.Bucket + "/" + key
+	} else {
+		u.Host = s.Bucket + "." + u.Host
+		u.Path = base + "/" + key
+	}
+	// Путь в запросе кодируется так же, как в подписи
+	u.RawPath = canonicalPath(&u)
+	return &u, nil
+}
+
+func (s *S3) client() *http.Client {
+	if s.Client != nil {
+		return s.Client
+	}
+	return http.DefaultClient
+}
+
+func (s *S3) do(req *http.Request) (*http.Response, error) {
+	resp, err := s.client().Do(req)
+	if err != nil {
+		return nil, err
+	}
+	if resp.StatusCode >= 300 {
+		defer resp.Body.Close()
+		body, _ := io.ReadAll(io.LimitReader(resp.Body, 1024))
+		if resp.StatusCode == http.StatusNotFound {
+			return nil, ErrNotFound
+		}
+		return nil, fmt.Errorf("storage: S3 %s %s: %s: %s", req.Method, req.URL.Path, resp.Status, strings.TrimSpace(string(body)))
+	}
+	return resp, nil
+}
+
+// Put загружает объект одним PUT-запросом (тело не подписывается)
+func (s *S3) Put(ctx context.Context, key string, r io.Reader, size int64, contentType string) error {
+	u, err := s.objectURL(key)
+	if err != nil {
+		return err
+	}
+	req, err := http.NewRequestWithContext(ctx, http.MethodPut, u.String(), r)
+	if err != nil {
+		return err
+	}
+	req.ContentLength = size
+	if contentType != "" {
+		req.Header.Set("Content-Type", contentType)
+	}
+	s.Signer.Sign(req, UnsignedPayload, time.Now())
+	resp, err := s.do(req)
+	if err != nil {
+		return err
+	}
+	resp.Body.Close()
+	return nil
+}
+
+// Delete удаляет объект
+func (s *S3) Delete(ctx context.Context, key string) error {
+	u, err := s.objectURL(key)
+	if err != nil {
+		return err
+	}
+	req, err := http.NewRequestWithContext(ctx, http.MethodDelete, u.String(), nil)
+	if err != nil {
+		return err
+	}
+	s.Signer.Sign(req, EmptyPayload, time.Now())
+	resp, err := s.do(req)
+	if err == ErrNotFound {
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+	resp.Body.Close()
+	return nil
+}
+
+// URL возвращает подписанную GET-ссылку на объект
+func (s *S3) URL(ctx context.Context, key string, expires time.Time) (string, error) {
+	u, err := s.objectURL(key)
+	if err != nil {
+		return "", err
+	}
+	now := time.Now()
+	ttl := expires.Sub(now).Truncate(time.Second)
+	if ttl <= 0 || ttl > maxPresign {
+		return "", fmt.Errorf("storage: S3 links must expire within %s", maxPresign)
+	}
+	return s.Signer.Presign(http.MethodGet, u, ttl, now), nil
+}
@@ -0,0 +1,168 @@
+package storage
+
+import (
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"net/http"
+	"net/url"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+)
+
+const (
+	sigAlgorithm = "AWS4-HMAC-SHA256"
+	amzDate      = "20060102T150405Z"
+	// UnsignedPayload — тело запроса не входит в подпись (S3 и MinIO это допускают)
+	UnsignedPayload = "UNSIGNED-PAYLOAD"
+	// EmptyPayload — SHA-256 пустого тела
+	EmptyPayload = "e3b0c44298fc1c149afbf4c8996fb92427ae41e4649b934ca495991b7852b855"
+)
+
+// Signer подписывает запросы по AWS Signature Version 4
+type Signer struct {
+	AccessKey string
+	SecretKey string
+	Region    string
+	Service   string
+}
+
+func (s Signer) scope(t time.Time) string {
+	return t.Format("20060102") + "/" + s.Region + "/" + s.Service + "/aws4_request"
+}
+
+func (s Signer) key(t time.Time) []byte {
+	k := hmacSHA256([]byte("AWS4"+s.SecretKey), t.Format("20060102"))
+	k = hmacSHA256(k, s.Region)
+	k = hmacSHA256(k, s.Service)
+	return hmacSHA256(k, "aws4_request")
+}
+
+// Sign добавляет к запросу заголовки X-Amz-Date, X-Amz-Content-Sha256 и
+// Authorization. Подписываются host, range, content-type, content-md5 и все
+// x-amz-* заголовки.
+func (s Signer) Sign(req *http.Request, payloadHash string, t time.Time) {
+	t = t.UTC()
+	req.Header.Set("X-Amz-Date", t.Format(amzDate))
+	req.Header.Set("X-Amz-Content-Sha256", payloadHash)
+
+	headers := map[string]string{"host": requestHost(req)}
+	for name, values := range req.Header {
+		lower := strings.ToLower(name)
+		if strings.HasPrefix(lower, "x-amz-") || lower == "range" || lower == "content-type" || lower == "content-md5" {
+			headers[lower] = strings.TrimSpace(strings.Join(values, ","))
+		}
+	}
+	names := make([]string, 0, len(headers))
+	for name := range headers {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	var canonical strings.Builder
+	for _, name := range names {
+		canonical.WriteString(name + ":" + headers[name] + "\n")
+	}
+	signed := strings.Join(names, ";")
+
+	request := strings.Join([]string{
+		req.Method,
+		canonicalPath(req.URL),
+		canonicalQuery(req.URL.Query()),
+		canonical.String(),
+		signed,
+		payloadHash,
+	}, "\n")
+	signature := s.signature(request, t)
+	req.Header.Set("Authorization", fmt.Sprintf("%s Credential=%s/%s, SignedHeaders=%s, Signature=%s",
+		sigAlgorithm, s.AccessKey, s.scope(t), signed, signature))
+}
+
+// Presign возвращает ссылку с подписью в параметрах запроса, действующую
+// expires (S3 допускает не больше 7 дней). Подписывается только host.
+func (s Signer) Presign(method string, u *url.URL, expires time.Duration, t time.Time) string {
+	t = t.UTC()
+	q := u.Query()
+	q.Set("X-Amz-Algorithm", sigAlgorithm)
+	q.Set("X-Amz-Credential", s.AccessKey+"/"+s.scope(t))
+	q.Set("X-Amz-Date", t.Format(amzDate))
+	q.Set("X-Amz-Expires", strconv.Itoa(int(expires/time.Second)))
+	q.Set("X-Amz-SignedHeaders", "host")
+
+	request := strings.Join([]string{
+		method,
+		canonicalPath(u),
+		canonicalQuery(q),
+		"host:" + u.Host + "\n",
+		"host",
+		UnsignedPayload,
+	}, "\n")
+	out := *u
+	out.RawQuery = canonicalQuery(q) + "&X-Amz-Signature=" + s.signature(request, t)
+	return out.String()
+}
+
+func (s Signer) signature(canonicalRequest string, t time.Time) string {
+	hash := sha256.Sum256([]byte(canonicalRequest))
+	toSign := strings.Join([]string{sigAlgorithm, t.Format(amzDate), s.scope(t), hex.EncodeToString(hash[:])}, "\n")
+	return hex.EncodeToString(hmacSHA256(s.key(t), toSign))
+}
+
+func hmacSHA256(key []byte, data string) []byte {
+	mac := hmac.New(sha256.New, key)
+	mac.Write([]byte(data))
+	return mac.Sum(nil)
+}
+
+func requestHost(req *http.Request) string {
+	if req.Host != "" {
+		return req.Host
+	}
+	return req.URL.Host
+}
+
+// canonicalPath кодирует каждую часть пути по RFC 3986 (S3 не нормализует путь)
+func canonicalPath(u *url.URL) string {
+	p := u.Path
+	if p == "" {
+		return "/"
+	}
+	parts := strings.Split(p, "/")
+	for i, part := range parts {
+		parts[i] = uriEncode(part)
+	}
+	return strings.Join(parts, "/")
+}
+
+func canonicalQuery(q url.Values) string {
+	keys := make([]string, 0, len(q))
+	for k := range q {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	var pairs []string
+	for _, k := range keys {
+		values := append([]string(nil), q[k]...)
+		sort.Strings(values)
+		for _, v := range values {
+			pairs = append(pairs, uriEncode(k)+"="+uriEncode(v))
+		}
+	}
+	return strings.Join(pairs, "&")
+}
+
+// uriEncode кодирует всё, кроме A-Z a-z 0-9 - _ . ~
+func uriEncode(s string) string {
+	var b strings.Builder
+	for i := 0; i < len(s); i++ {
+		c := s[i]
+		if 'A' <= c && c <= 'Z' || 'a' <= c && c <= 'z' || '0' <= c && c <= '9' || c == '-' || c == '_' || c == '.' || c == '~' {
+			b.WriteByte(c)
+		} else {
+			fmt.Fprintf(&b, "%%%02X", c)
+		}
+	}
+	return b.String()
+}
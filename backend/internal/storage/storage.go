@@ -0,0 +1,110 @@
+// Package storage хранит медиафайлы уроков (аудио, видео, картинки, ноты)
+// на локальном диске или в S3-совместимом хранилище (AWS S3, MinIO) и выдаёт
+// подписанные ссылки на скачивание с ограниченным сроком действия.
+package storage
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"io"
+	"net/url"
+	"os"
+	"path"
+	"strings"
+	"time"
+)
+
+// ErrNotFound — объекта с таким ключом нет
+var ErrNotFound = errors.New("storage: object not found")
+
+// ErrInvalidKey — ключ пустой, абсолютный или выходит за пределы хранилища
+var ErrInvalidKey = errors.New("storage: invalid key")
+
+// Storage — хранилище объектов. Ключ — относительный путь вида
+// "lessons/12/3f9a….mp3".
+type Storage interface {
+	// Put сохраняет объект размера size. Существующий объект перезаписывается.
+	Put(ctx context.Context, key string, r io.Reader, size int64, contentType string) error
+	// Delete удаляет объект; удаление несуществующего объекта — не ошибка.
+	Delete(ctx context.Context, key string) error
+	// URL возвращает подписанную ссылку на скачивание, действующую до expires.
+	URL(ctx context.Context, key string, expires time.Time) (string, error)
+}
+
+// Store — хранилище приложения, настраивается в main через FromEnv
+var Store Storage
+
+// FromEnv создаёт хранилище по переменным окружения:
+//
+//	STORAGE_BACKEND      local (по умолчанию) или s3
+//	STORAGE_DIR          каталог для local, по умолчанию ./uploads
+//	STORAGE_PUBLIC_URL   адрес API для ссылок local, по умолчанию http://localhost:8080
+//	STORAGE_SIGNING_KEY  ключ подписи ссылок local (по умолчанию JWT_SECRET)
+//	S3_ENDPOINT, S3_REGION, S3_BUCKET, S3_ACCESS_KEY, S3_SECRET_KEY
+//	S3_PATH_STYLE        true (по умолчанию, как у MinIO) или false
+func FromEnv() (Storage, error) {
+	switch backend := os.Getenv("STORAGE_BACKEND"); backend {
+	case "", "local":
+		key := os.Getenv("STORAGE_SIGNING_KEY")
+		if key == "" {
+			key = os.Getenv("JWT_SECRET")
+		}
+		local, err := NewLocal(
+			envOr("STORAGE_DIR", "./uploads"),
+			envOr("STORAGE_PUBLIC_URL", "http://localhost:8080")+"/media/",
+			[]byte(key),
+		)
+		if err != nil {
+			return nil, err
+		}
+		return local, nil
+	case "s3":
+		endpoint, err := url.Parse(os.Getenv("S3_ENDPOINT"))
+		if err != nil || endpoint.Host == "" {
+			return nil, fmt.Errorf("storage: invalid S3_ENDPOINT %q", os.Getenv("S3_ENDPOINT"))
+		}
+		return &S3{
+			Endpoint:  endpoint,
+			Bucket:    os.Getenv("S3_BUCKET"),
+			PathStyle: os.Getenv("S3_PATH_STYLE") != "false",
+			Signer: Signer{
+				AccessKey: os.Getenv("S3_ACCESS_KEY"),
+				SecretKey: os.Getenv("S3_SECRET_KEY"),
+				Region:    envOr("S3_REGION", "us-east-1"),
+				Service:   "s3",
+			},
+		}, nil
+	default:
+		return nil, fmt.Errorf("storage: unknown STORAGE_BACKEND %q", backend)
+	}
+}
+
+func envOr(name, fallback string) string {
+	if v := os.Getenv(name); v != "" {
+		return v
+	}
+	return fallback
+}
+
+// NewKey создаёт случайный ключ в каталоге prefix с расширением ext (".mp3")
+func NewKey(prefix, ext string) string {
+	var b [16]byte
+	rand.Read(b[:])
+	return path.Join(prefix, hex.EncodeToString(b[:])+ext)
+}
+
+// CleanKey проверяет ключ: относительный путь без "..", "." и пустых частей
+func CleanKey(key string) (string, error) {
+	if key == "" || strings.HasPrefix(key, "/") || strings.Contains(key, "\\") || path.Clean(key) != key {
+		return "", ErrInvalidKey
+	}
+	for _, part := range strings.Split(key, "/") {
+		if part == "" || part == "." || part == ".." {
+			return "", ErrInvalidKey
+		}
+	}
+	return key, nil
+}
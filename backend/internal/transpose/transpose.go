@@ -0,0 +1,196 @@
+// Package transpose переносит песни и мелодии в другую тональность:
+// сдвигает шаги последовательности на заданное число полутонов, записывает
+// аккорды и ноты по ключевым знакам новой тональности и подбирает
+// каподастр, с которым песню можно играть открытыми аккордами.
+package transpose
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+
+	"sonara-space/backend/internal/instruments"
+	"sonara-space/backend/internal/models"
+	"sonara-space/backend/internal/music"
+	"sonara-space/backend/internal/voicings"
+)
+
+// MaxSemitones — наибольший сдвиг в любую сторону: дальше тональности повторяются
+const MaxSemitones = 11
+
+// ErrSemitones — сдвиг вне допустимых пределов
+var ErrSemitones = fmt.Errorf("transposition must be between -%d and %d semitones", MaxSemitones, MaxSemitones)
+
+// Result — последовательность в новой тональности
+type Result struct {
+	Sequence  models.Sequence
+	Semitones int
+	From      music.Key // тональность оригинала (определяется по шагам)
+	To        music.Key
+}
+
+// Key определяет тональность последовательности по её шагам
+func Key(seq *models.Sequence) (music.Key, error) {
+	weights := make([]float64, len(seq.Steps))
+	for i, step := range seq.Steps {
+		weights[i] = step.Beats
+	}
+	if seq.StepType == models.ExerciseTypeNote {
+		notes := make([]music.Note, len(seq.Steps))
+		for i, step := range seq.Steps {
+			n, err := music.ParseNote(step.Symbol)
+			if err != nil {
+				return music.Key{}, fmt.Errorf("step %d: %w", i, err)
+			}
+			notes[i] = n
+		}
+		return music.GuessKeyFromNotes(notes, weights), nil
+	}
+	chords, err := parseChords(seq.Symbols())
+	if err != nil {
+		return music.Key{}, err
+	}
+	return music.GuessKeyFromChords(chords, weights), nil
+}
+
+// Sequence сдвигает последовательность на semitones полутонов. Аккорды
+// и ноты записываются по ключевым знакам новой тональности: из G на +3 —
+// Bb и Eb, а не A# и D#. Без сдвига шаги не переписываются.
+func Sequence(seq *models.Sequence, semitones int) (Result, error) {
+	if semitones < -MaxSemitones || semitones > MaxSemitones {
+		return Result{}, ErrSemitones
+	}
+	from, err := Key(seq)
+	if err != nil {
+		return Result{}, err
+	}
+	res := Result{Sequence: *seq, Semitones: semitones, From: from, To: from}
+	res.Sequence.Steps = append([]models.SequenceStep(nil), seq.Steps...)
+	if semitones == 0 {
+		return res, nil
+	}
+
+	res.To = from.Transpose(semitones)
+	for i := range res.Sequence.Steps {
+		step := &res.Sequence.Steps[i]
+		symbol, err := Symbol(seq.StepType, step.Symbol, semitones, res.To)
+		if err != nil {
+			return Result{}, fmt.Errorf("step %d: %w", i, err)
+		}
+		step.Symbol = symbol
+	}
+	return res, nil
+}
+
+// Symbol сдвигает одну ноту или аккорд и записывает их в тональности key
+func Symbol(stepType, symbol string, semitones int, key music.Key) (string, error) {
+	if stepType == models.ExerciseTypeNote {
+		n, err := music.ParseNote(symbol)
+		if err != nil {
+			return "", err
+		}
+		midi := n.MIDI() + semitones
+		if midi < 0 || midi > 127 {
+			return "", fmt.Errorf("%s transposed by %d is outside the MIDI range", symbol, semitones)
+		}
+		return key.SpellNote(midi).String(), nil
+	}
+	c, err := music.ParseChord(symbol)
+	if err != nil {
+		return "", err
+	}
+	return key.SpellChord(c.Transpose(semitones, key.PrefersFlats())).String(), nil
+}
+
+// Expected возвращает ожидаемое значение упражнения-последовательности
+// (строку для exercises.expected) после сдвига
+func (r Result) Expected() string {
+	return strings.Join(r.Sequence.Symbols(), " ")
+}
+
+// Capo — рекомендация каподастра: на каком ладу поставить и какими
+// аппликатурами (формами) играть аккорды
+type Capo struct {
+	Fret   int      `json:"fret"`
+	Shapes []string `json:"shapes"`      // форма для каждого шага
+	Open   float64  `json:"open_shapes"` // доля длительности, сыгранная открытыми формами
+}
+
+// SuggestCapo подбирает лад каподастра, при котором больше всего аккордов
+// (с учётом длительности) играются открытыми формами без баррэ. При
+// равенстве выбирается лад ниже: без каподастра — лучше всего.
+func SuggestCapo(inst instruments.Instrument, tuning instruments.Tuning, seq *models.Sequence) (Capo, error) {
+	if inst.Kind != instruments.KindStrings || inst.MaxCapo == 0 {
+		return Capo{}, fmt.Errorf("%w: %s is played without a capo", instruments.ErrCapo, inst.ID)
+	}
+	if seq.StepType != models.ExerciseTypeChord {
+		return Capo{}, errors.New("capo is suggested only for chord sequences")
+	}
+	chords, err := parseChords(seq.Symbols())
+	if err != nil {
+		return Capo{}, err
+	}
+
+	open := tuning.OpenStrings()
+	total := seq.TotalBeats()
+	sounding := music.GuessKeyFromChords(chords, nil)
+	cache := map[string]bool{}
+	best := Capo{Open: -1}
+	for fret := 0; fret <= inst.MaxCapo; fret++ {
+		// С каподастром на ладу fret форма звучит на fret полутонов выше
+		c := Capo{Fret: fret, Shapes: make([]string, len(chords))}
+		key := sounding.Transpose(-fret)
+		var beats float64
+		for i, chord := range chords {
+			shape := key.SpellChord(chord.Transpose(-fret, key.PrefersFlats()))
+			c.Shapes[i] = shape.String()
+			ok, seen := cache[c.Shapes[i]]
+			if !seen {
+				ok = openShape(shape, open, inst.Frets)
+				cache[c.Shapes[i]] = ok
+			}
+			if ok {
+				beats += seq.Steps[i].Beats
+			}
+		}
+		if total > 0 {
+			c.Open = beats / total
+		}
+		if c.Open > best.Open {
+			best = c
+		}
+	}
+	return best, nil
+}
+
+// maxOpenFingers — открытая форма берётся не больше чем тремя пальцами
+const maxOpenFingers = 3
+
+// openShape сообщает, есть ли у аккорда открытая форма: аппликатура у
+// порожка без баррэ, которую берут не больше чем тремя пальцами
+func openShape(c music.Chord, open []int, frets int) bool {
+	for _, v := range voicings.Fretted(c, open, frets, voicings.Options{Limit: 3}) {
+		fingers := 0
+		for _, f := range v.Fingers {
+			if f > 0 {
+				fingers++
+			}
+		}
+		if v.Open && v.Barre == nil && fingers <= maxOpenFingers {
+			return true
+		}
+	}
+	return false
+}
+
+func parseChords(symbols []string) ([]music.Chord, error) {
+	chords := make([]music.Chord, len(symbols))
+	for i, s := range symbols {
+		c, err := music.ParseChord(s)
+		if err != nil {
+			return nil, fmt.Errorf("step %d: %w", i, err)
+		}
+		chords[i] = c
+	}
+	return chords, nil
+}
@@ -0,0 +1,431 @@
+// Package versions реализует версии уроков. Редактор правит черновик —
+// упражнения урока с retired_at IS NULL. Публикация фиксирует состав и
+// порядок черновика в неизменяемой версии. Ученик проходит версию, которую
+// начал, и переходит на новую только явно, с переносом прогресса.
+//
+// Упражнения опубликованных версий не меняются и не удаляются: правка создаёт
+// новую строку (Fork), удаление выводит упражнение из черновика (Remove),
+// поэтому прогресс учеников не теряется.
+package versions
+
+import (
+	"context"
+	"errors"
+	"fmt"
+
+	"sonara-space/backend/internal/models"
+
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgconn"
+)
+
+// DB — методы pgx, нужные пакету (подходят pgxpool.Pool и pgx.Tx).
+// Publish, Fork и Upgrade делают несколько запросов и должны вызываться в транзакции.
+type DB interface {
+	Exec(ctx context.Context, sql string, args ...interface{}) (pgconn.CommandTag, error)
+	Query(ctx context.Context, sql string, args ...interface{}) (pgx.Rows, error)
+	QueryRow(ctx context.Context, sql string, args ...interface{}) pgx.Row
+}
+
+var (
+	// ErrNotFound — урока или упражнения нет (или урок не опубликован)
+	ErrNotFound = errors.New("versions: not found")
+	// ErrEmptyDraft — в черновике нет упражнений
+	ErrEmptyDraft = errors.New("lesson has no exercises")
+	// ErrInvalidMapping — перенос ссылается на упражнения не из тех версий
+	ErrInvalidMapping = errors.New("invalid mapping")
+)
+
+// Mapping — перенос прогресса с упражнения From на упражнение To
+type Mapping struct {
+	From int64 `json:"from"`
+	To   int64 `json:"to"`
+}
+
+// Step — очередная версия: её упражнения и переносы из предыдущей версии
+type Step struct {
+	Exercises []int64
+	Mappings  map[int64]int64
+}
+
+// Migrate проводит упражнения start через последовательность версий.
+// moved — куда попало каждое упражнение start (сами в себя, если не менялись),
+// dropped — упражнения, для которых в какой-то версии не нашлось продолжения.
+func Migrate(start []int64, steps []Step) (moved map[int64]int64, dropped []int64) {
+	moved = make(map[int64]int64, len(start))
+	for _, id := range start {
+		moved[id] = id
+	}
+	for _, step := range steps {
+		present := make(map[int64]bool, len(step.Exercises))
+		for _, id := range step.Exercises {
+			present[id] = true
+		}
+		for _, origin := range start {
+			cur, ok := moved[origin]
+			if !ok || present[cur] {
+				continue
+			}
+			if to, ok := step.Mappings[cur]; ok && present[to] {
+				moved[origin] = to
+				continue
+			}
+			delete(moved, origin)
+			dropped = append(dropped, origin)
+		}
+	}
+	return moved, dropped
+}
+
+// AutoMappings строит переносы для новой версии: упражнение черновика,
+// заменившее (через цепочку replaces) упражнение предыдущей версии,
+// получает его прогресс. replaces — replaces_id всех упражнений урока.
+func AutoMappings(previous, draft []int64, replaces map[int64]int64) map[int64]int64 {
+	prev := make(map[int64]bool, len(previous))
+	for _, id := range previous {
+		prev[id] = true
+	}
+	kept := make(map[int64]bool, len(draft))
+	for _, id := range draft {
+		kept[id] = true
+	}
+
+	mappings := make(map[int64]int64)
+	for _, id := range draft {
+		if prev[id] {
+			continue
+		}
+		// Длина цепочки ограничена числом упражнений: защита от циклов
+		r := replaces[id]
+		for n := 0; r != 0 && !prev[r] && n < len(replaces); n++ {
+			r = replaces[r]
+		}
+		if r != 0 && prev[r] && !kept[r] {
+			if _, taken := mappings[r]; !taken {
+				mappings[r] = id
+			}
+		}
+	}
+	return mappings
+}
+
+// Published сообщает, входит ли упражнение в какую-нибудь опубликованную версию
+func Published(ctx context.Context, db DB, exerciseID int64) (bool, error) {
+	var ok bool
+	err := db.QueryRow(ctx, `SELECT EXISTS (SELECT 1 FROM lesson_version_exercises WHERE exercise_id = $1)`,
+		exerciseID).Scan(&ok)
+	return ok, err
+}
+
+// Fork заменяет упражнение черновика копией: старая строка остаётся в
+// опубликованных версиях, новая (replaces_id — старая) — в черновике.
+// Копируются песня, ритмический рисунок, переводы и навыки. Возвращает ID копии.
+func Fork(ctx context.Context, db DB, exerciseID int64) (int64, error) {
+	tag, err := db.Exec(ctx, `UPDATE exercises SET retired_at = NOW() WHERE id = $1 AND retired_at IS NULL`, exerciseID)
+	if err != nil {
+		return 0, err
+	}
+	if tag.RowsAffected() == 0 {
+		return 0, ErrNotFound
+	}
+
+	var id int64
+	err = db.QueryRow(ctx, `
+		INSERT INTO exercises (lesson_id, slug, title, expected, type, order_index, replaces_id)
+		SELECT lesson_id, slug, title, expected, type, order_index, id FROM exercises WHERE id = $1
+		RETURNING id
+	`, exerciseID).Scan(&id)
+	if err != nil {
+		return 0, err
+	}
+
+	for _, query := range []string{
+		`INSERT INTO exercise_sequences (exercise_id, song_title, step_type, tempo, beats_per_bar, beat_unit, steps)
+		 SELECT $2, song_title, step_type, tempo, beats_per_bar, beat_unit, steps FROM exercise_sequences WHERE exercise_id = $1`,
+		`INSERT INTO exercise_rhythms (exercise_id, tempo, beats_per_bar, beat_unit, count_in, pattern, perfect_ms, good_ms, miss_ms)
+		 SELECT $2, tempo, beats_per_bar, beat_unit, count_in, pattern, perfect_ms, good_ms, miss_ms FROM exercise_rhythms WHERE exercise_id = $1`,
+		`INSERT INTO exercise_translations (exercise_id, locale, title)
+		 SELECT $2, locale, title FROM exercise_translations WHERE exercise_id = $1`,
+		`INSERT INTO exercise_skills (exercise_id, skill_id)
+		 SELECT $2, skill_id FROM exercise_skills WHERE exercise_id = $1`,
+	} {
+		if _, err := db.Exec(ctx, query, exerciseID, id); err != nil {
+			return 0, fmt.Errorf("fork exercise %d: %w", exerciseID, err)
+		}
+	}
+	return id, nil
+}
+
+// Remove убирает упражнение из черновика. Упражнение без версий и без
+// прогресса удаляется совсем (deleted == true), иначе только выводится из черновика.
+func Remove(ctx context.Context, db DB, exerciseID int64) (deleted bool, err error) {
+	var keep bool
+	err = db.QueryRow(ctx, `
+		SELECT EXISTS (SELECT 1 FROM lesson_version_exercises WHERE exercise_id = e.id)
+			OR EXISTS (SELECT 1 FROM exercise_attempts WHERE exercise_id = e.id)
+			OR EXISTS (SELECT 1 FROM progress WHERE exercise_id = e.id)
+		FROM exercises e WHERE e.id = $1 AND e.retired_at IS NULL
+	`, exerciseID).Scan(&keep)
+	if errors.Is(err, pgx.ErrNoRows) {
+		return false, ErrNotFound
+	}
+	if err != nil {
+		return false, err
+	}
+	if keep {
+		_, err = db.Exec(ctx, `UPDATE exercises SET retired_at = NOW() WHERE id = $1`, exerciseID)
+		return false, err
+	}
+	_, err = db.Exec(ctx, `DELETE FROM exercises WHERE id = $1`, exerciseID)
+	return err == nil, err
+}
+
+// Publish фиксирует черновик урока в новой версии и делает её текущей.
+// Если черновик не отличается от текущей версии и explicit пуст, новая
+// версия не создаётся (created == false), урок только публикуется.
+// explicit дополняет и переопределяет переносы, найденные по replaces_id.
+func Publish(ctx context.Context, db DB, lessonID int64, publishedBy *int64, note string, explicit []Mapping) (v models.LessonVersion, created bool, err error) {
+	var currentID *int64
+	err = db.QueryRow(ctx, `SELECT published_version_id FROM lessons WHERE id = $1 FOR UPDATE`, lessonID).Scan(&currentID)
+	if errors.Is(err, pgx.ErrNoRows) {
+		return v, false, ErrNotFound
+	}
+	if err != nil {
+		return v, false, err
+	}
+
+	rows, err := db.Query(ctx, `SELECT id, COALESCE(replaces_id, 0) FROM exercises WHERE lesson_id = $1`, lessonID)
+	if err != nil {
+		return v, false, err
+	}
+	replaces := make(map[int64]int64)
+	for rows.Next() {
+		var id, replaced int64
+		if err := rows.Scan(&id, &replaced); err != nil {
+			rows.Close()
+			return v, false, err
+		}
+		replaces[id] = replaced
+	}
+	rows.Close()
+	if err := rows.Err(); err != nil {
+		return v, false, err
+	}
+	draft, err := collectIDs(ctx, db, `
+		SELECT id FROM exercises WHERE lesson_id = $1 AND retired_at IS NULL ORDER BY order_index, id
+	`, lessonID)
+	if err != nil {
+		return v, false, err
+	}
+	if len(draft) == 0 {
+		return v, false, ErrEmptyDraft
+	}
+
+	var previous []int64
+	if currentID != nil {
+		previous, err = versionExercises(ctx, db, *currentID)
+		if err != nil {
+			return v, false, err
+		}
+	}
+
+	if currentID != nil && len(explicit) == 0 && equalIDs(previous, draft) {
+		if _, err := db.Exec(ctx, `UPDATE lessons SET status = 'published', updated_at = NOW() WHERE id = $1`, lessonID); err != nil {
+			return v, false, err
+		}
+		v, err = load(ctx, db, *currentID)
+		return v, false, err
+	}
+
+	mappings := AutoMappings(previous, draft, replaces)
+	if len(explicit) > 0 {
+		inPrev, inDraft := idSet(previous), idSet(draft)
+		for _, m := range explicit {
+			if !inPrev[m.From] || inDraft[m.From] || !inDraft[m.To] {
+				return v, false, fmt.Errorf("%w: %d -> %d: from must be an exercise removed from the published version, to — an exercise of the draft",
+					ErrInvalidMapping, m.From, m.To)
+			}
+			mappings[m.From] = m.To
+		}
+	}
+
+	err = db.QueryRow(ctx, `
+		INSERT INTO lesson_versions (lesson_id, version, note, published_by)
+		SELECT $1, COALESCE(MAX(version), 0) + 1, NULLIF($2, ''), $3 FROM lesson_versions WHERE lesson_id = $1
+		RETURNING id, lesson_id, version, note, published_by, published_at
+	`, lessonID, note, publishedBy).Scan(&v.ID, &v.LessonID, &v.Version, &v.Note, &v.PublishedBy, &v.PublishedAt)
+	if err != nil {
+		return v, false, err
+	}
+	for i, id := range draft {
+		if _, err := db.Exec(ctx, `
+			INSERT INTO lesson_version_exercises (version_id, exercise_id, order_index) VALUES ($1, $2, $3)
+		`, v.ID, id, i+1); err != nil {
+			return v, false, err
+		}
+	}
+	for from, to := range mappings {
+		if _, err := db.Exec(ctx, `
+			INSERT INTO lesson_version_mappings (version_id, from_exercise_id, to_exercise_id) VALUES ($1, $2, $3)
+		`, v.ID, from, to); err != nil {
+			return v, false, err
+		}
+	}
+	_, err = db.Exec(ctx, `
+		UPDATE lessons SET published_version_id = $2, status = 'published', updated_at = NOW() WHERE id = $1
+	`, lessonID, v.ID)
+	return v, true, err
+}
+
+// Pin закрепляет за учеником версию урока при первом занятии: последнюю
+// версию, в которую входит упражнение. Уже закреплённая версия не меняется.
+func Pin(ctx context.Context, db DB, userID, exerciseID int64) error {
+	_, err := db.Exec(ctx, `
+		INSERT INTO lesson_pins (user_id, lesson_id, version_id)
+		SELECT $1, v.lesson_id, v.id
+		FROM lesson_version_exercises lve
+		JOIN lesson_versions v ON v.id = lve.version_id
+		WHERE lve.exercise_id = $2
+		ORDER BY v.version DESC
+		LIMIT 1
+		ON CONFLICT (user_id, lesson_id) DO NOTHING
+	`, userID, exerciseID)
+	return err
+}
+
+// UpgradeResult — результат перехода ученика на текущую версию урока
+type UpgradeResult struct {
+	LessonID    int64     `json:"lesson_id"`
+	FromVersion int       `json:"from_version"`
+	ToVersion   int       `json:"to_version"`
+	Moved       []Mapping `json:"moved"`
+	Dropped     []int64   `json:"dropped"`
+}
+
+// Upgrade переводит ученика на текущую опубликованную версию урока и
+// возвращает, прогресс каких упражнений нужно перенести (Moved) и какие
+// упражнения в новой версии исчезли (Dropped; их прогресс остаётся в истории).
+func Upgrade(ctx context.Context, db DB, userID, lessonID int64) (UpgradeResult, error) {
+	up := UpgradeResult{LessonID: lessonID, Moved: []Mapping{}, Dropped: []int64{}}
+
+	var currentID int64
+	err := db.QueryRow(ctx, `
+		SELECT v.id, v.version FROM lessons l JOIN lesson_versions v ON v.id = l.published_version_id
+		WHERE l.id = $1 AND l.status = 'published'
+	`, lessonID).Scan(&currentID, &up.ToVersion)
+	if errors.Is(err, pgx.ErrNoRows) {
+		return up, ErrNotFound
+	}
+	if err != nil {
+		return up, err
+	}
+
+	var pinnedID int64
+	err = db.QueryRow(ctx, `
+		SELECT v.id, v.version FROM lesson_pins p JOIN lesson_versions v ON v.id = p.version_id
+		WHERE p.user_id = $1 AND p.lesson_id = $2
+		FOR UPDATE OF p
+	`, userID, lessonID).Scan(&pinnedID, &up.FromVersion)
+	if errors.Is(err, pgx.ErrNoRows) {
+		// Ученик ещё не занимался: просто закрепляем текущую версию
+		up.FromVersion = up.ToVersion
+		_, err = db.Exec(ctx, `INSERT INTO lesson_pins (user_id, lesson_id, version_id) VALUES ($1, $2, $3)`,
+			userID, lessonID, currentID)
+		return up, err
+	}
+	if err != nil {
+		return up, err
+	}
+	if pinnedID == currentID {
+		return up, nil
+	}
+
+	start, err := versionExercises(ctx, db, pinnedID)
+	if err != nil {
+		return up, err
+	}
+	ids, err := collectIDs(ctx, db, `
+		SELECT id FROM lesson_versions WHERE lesson_id = $1 AND version > $2 AND version <= $3 ORDER BY version
+	`, lessonID, up.FromVersion, up.ToVersion)
+	if err != nil {
+		return up, err
+	}
+	steps := make([]Step, 0, len(ids))
+	for _, id := range ids {
+		step := Step{Mappings: make(map[int64]int64)}
+		if step.Exercises, err = versionExercises(ctx, db, id); err != nil {
+			return up, err
+		}
+		rows, err := db.Query(ctx, `SELECT from_exercise_id, to_exercise_id FROM lesson_version_mappings WHERE version_id = $1`, id)
+		if err != nil {
+			return up, err
+		}
+		for rows.Next() {
+			var from, to int64
+			if err := rows.Scan(&from, &to); err != nil {
+				rows.Close()
+				return up, err
+			}
+			step.Mappings[from] = to
+		}
+		rows.Close()
+		if err := rows.Err(); err != nil {
+			return up, err
+		}
+		steps = append(steps, step)
+	}
+
+	moved, dropped := Migrate(start, steps)
+	for _, origin := range start {
+		if to, ok := moved[origin]; ok && to != origin {
+			up.Moved = append(up.Moved, Mapping{From: origin, To: to})
+		}
+	}
+	up.Dropped = append(up.Dropped, dropped...)
+
+	_, err = db.Exec(ctx, `UPDATE lesson_pins SET version_id = $3, pinned_at = NOW() WHERE user_id = $1 AND lesson_id = $2`,
+		userID, lessonID, currentID)
+	return up, err
+}
+
+func load(ctx context.Context, db DB, versionID int64) (models.LessonVersion, error) {
+	var v models.LessonVersion
+	err := db.QueryRow(ctx, `
+		SELECT id, lesson_id, version, note, published_by, published_at FROM lesson_versions WHERE id = $1
+	`, versionID).Scan(&v.ID, &v.LessonID, &v.Version, &v.Note, &v.PublishedBy, &v.PublishedAt)
+	return v, err
+}
+
+func versionExercises(ctx context.Context, db DB, versionID int64) ([]int64, error) {
+	return collectIDs(ctx, db, `
+		SELECT exercise_id FROM lesson_version_exercises WHERE version_id = $1 ORDER BY order_index
+	`, versionID)
+}
+
+func collectIDs(ctx context.Context, db DB, query string, args ...interface{}) ([]int64, error) {
+	rows, err := db.Query(ctx, query, args...)
+	if err != nil {
+		return nil, err
+	}
+	return pgx.CollectRows(rows, pgx.RowTo[int64])
+}
+
+func equalIDs(a, b []int64) bool {
+	if len(a) != len(b) {
+		return false
+	}
+	for i := range a {
+		if a[i] != b[i] {
+			return false
+		}
+	}
+	return true
+}
+
+func idSet(ids []int64) map[int64]bool {
+	set := make(map[int64]bool, len(ids))
+	for _, id := range ids {
+		set[id] = true
+	}
+	return set
+}
@@ -0,0 +1,350 @@
+// Package voicings строит играбельные расположения аккордов: аппликатуры на
+// грифе струнных инструментов (с учётом строя и каподастра) и обращения для
+// фортепиано с аппликатурой обеих рук.
+package voicings
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+
+	"sonara-space/backend/internal/music"
+)
+
+// Значения по умолчанию для поиска на грифе
+const (
+	DefaultMaxStretch = 3  // пальцы охватывают четыре лада
+	DefaultMaxFret    = 12 // дальше аппликатуры повторяются октавой выше
+	DefaultLimit      = 6
+	maxFingers        = 4 // большой палец не используем
+)
+
+// Options — ограничения поиска
+type Options struct {
+	MaxStretch int // наибольшее расстояние между нажатыми ладами
+	MaxFret    int // до какого лада искать
+	Limit      int // сколько вариантов вернуть
+}
+
+func (o Options) withDefaults() Options {
+	if o.MaxStretch <= 0 {
+		o.MaxStretch = DefaultMaxStretch
+	}
+	if o.MaxFret <= 0 {
+		o.MaxFret = DefaultMaxFret
+	}
+	if o.Limit <= 0 {
+		o.Limit = DefaultLimit
+	}
+	return o
+}
+
+// Barre — баррэ указательным пальцем на ладу Fret со струны From по струну To
+// (номера струн от нижней, с нуля)
+type Barre struct {
+	Fret int `json:"fret"`
+	From int `json:"from"`
+	To   int `json:"to"`
+}
+
+// Fretting — аппликатура аккорда на грифе. Лады считаются от каподастра.
+type Fretting struct {
+	Frets    []int    `json:"frets"`     // по струнам от нижней: -1 — не звучит, 0 — открытая
+	Fingers  []int    `json:"fingers"`   // 1 — указательный … 4 — мизинец, 0 — палец не нужен
+	Notes    []string `json:"notes"`     // звучащие ноты по струнам от нижней
+	BaseFret int      `json:"base_fret"` // первый лад диаграммы
+	Barre    *Barre   `json:"barre,omitempty"`
+	Open     bool     `json:"open"` // открытая позиция: есть открытые струны, всё в первых четырёх ладах
+	Shape    string   `json:"shape"`
+
+	cost float64
+}
+
+// Fretted ищет аппликатуры аккорда для струнного инструмента. open — MIDI-номера
+// открытых струн от нижней (уже с учётом каподастра), frets — число доступных
+// ладов. Варианты отсортированы от самого удобного: открытые позиции, меньше
+// пальцев и растяжки, без баррэ.
+func Fretted(chord music.Chord, open []int, frets int, opts Options) []Fretting {
+	opts = opts.withDefaults()
+	all, must := chordTones(chord)
+	ascending := sort.IntsAreSorted(open)
+	minStrings := min(3, len(open), all.Len())
+	if !ascending {
+		// В реэнтрантном строе (укулеле) звучат все струны: бас не выделяется
+		minStrings = len(open)
+	}
+
+	maxFret := min(opts.MaxFret+opts.MaxStretch, frets)
+	seen := make(map[string]bool)
+	var found []Fretting
+
+	assign := make([]int, len(open))
+	var walk func(s, low, high int, stopped bool)
+	walk = func(s, low, high int, stopped bool) {
+		if s == len(open) {
+			v, ok := evaluate(chord, open, assign, all, must, ascending, minStrings, opts)
+			if ok && !seen[v.Shape] {
+				seen[v.Shape] = true
+				found = append(found, v)
+			}
+			return
+		}
+		// Звучащие струны идут подряд: после заглушённой звучащей струны остальные глушатся
+		sounding := false
+		for j := 0; j < s; j++ {
+			if assign[j] >= 0 {
+				sounding = true
+			}
+		}
+		assign[s] = -1
+		walk(s+1, low, high, stopped || sounding)
+		if stopped {
+			return
+		}
+		if all.Has(music.PitchClass(mod12(open[s]))) {
+			assign[s] = 0
+			walk(s+1, low, high, false)
+		}
+		for f := low; f <= high; f++ {
+			if all.Has(music.PitchClass(mod12(open[s] + f))) {
+				assign[s] = f
+				walk(s+1, low, high, false)
+			}
+		}
+		assign[s] = -1
+	}
+	for low := 1; low <= maxFret; low++ {
+		walk(0, low, min(low+opts.MaxStretch, maxFret), false)
+	}
+
+	sort.SliceStable(found, func(i, j int) bool {
+		if found[i].cost != found[j].cost {
+			return found[i].cost < found[j].cost
+		}
+		return found[i].Shape < found[j].Shape
+	})
+	if len(found) > opts.Limit {
+		found = found[:opts.Limit]
+	}
+	return found
+}
+
+// evaluate проверяет, что расположение — играбельный аккорд, и считает его стоимость
+func evaluate(chord music.Chord, open, assign []int, all, must music.PitchClassSet,
+	ascending bool, minStrings int, opts Options) (Fretting, bool) {
+	var played music.PitchClassSet
+	sounding, opens, muted := 0, 0, 0
+	lowest := -1
+	minF, maxF := 0, 0
+	var fretted []int
+	for s, f := range assign {
+		if f < 0 {
+			muted++
+			continue
+		}
+		sounding++
+		midi := open[s] + f
+		played = played.Add(music.PitchClass(mod12(midi)))
+		if lowest < 0 || midi < open[lowest]+assign[lowest] {
+			lowest = s
+		}
+		if f == 0 {
+			opens++
+			continue
+		}
+		fretted = append(fretted, s)
+		if minF == 0 || f < minF {
+			minF = f
+		}
+		maxF = max(maxF, f)
+	}
+	if sounding < minStrings || played&must != must {
+		return Fretting{}, false
+	}
+	if maxF-minF > opts.MaxStretch {
+		return Fretting{}, false
+	}
+	// Нижний звук — бас аккорда (для реэнтрантного строя укулеле не проверяем)
+	if ascending {
+		bass := chord.Root.PitchClass()
+		if chord.Bass != nil {
+			bass = chord.Bass.PitchClass()
+		}
+		if music.PitchClass(mod12(open[lowest]+assign[lowest])) != bass {
+			return Fretting{}, false
+		}
+	}
+
+	v := Fretting{
+		Frets:   append([]int(nil), assign...),
+		Fingers: make([]int, len(assign)),
+	}
+
+	firstFretted, lastFretted := len(assign), -1
+	if len(fretted) > 0 {
+		firstFretted, lastFretted = fretted[0], fretted[len(fretted)-1]
+	}
+
+	// Баррэ нужно, только если отдельных пальцев не хватает
+	rest := append([]int(nil), fretted...)
+	if len(fretted) > maxFingers {
+		b, ok := barre(assign, minF)
+		if !ok {
+			return Fretting{}, false
+		}
+		v.Barre = &b
+		rest = nil
+		for _, s := range fretted {
+			if assign[s] == minF && s >= b.From && s <= b.To {
+				v.Fingers[s] = 1
+			} else {
+				rest = append(rest, s)
+			}
+		}
+		if len(rest) > maxFingers-1 {
+			return Fretting{}, false
+		}
+	}
+	assignFingers(v.Fingers, assign, rest, minF, v.Barre != nil)
+
+	fingers := len(rest)
+	if v.Barre != nil {
+		fingers++
+	}
+	v.BaseFret = 1
+	if maxF > DefaultMaxStretch+1 {
+		v.BaseFret = minF
+	}
+	v.Open = opens > 0 && maxF <= DefaultMaxStretch+1
+	for s, f := range assign {
+		if f >= 0 {
+			v.Notes = append(v.Notes, spellNote(chord, open[s]+f))
+		}
+	}
+	v.Shape = shape(assign)
+
+	// Стоимость: позиция дальше от порожка, растяжка, число пальцев, заглушённые
+	// струны и баррэ делают аккорд труднее. Открытые струны у порожка облегчают
+	// его, а открытая струна между нажатыми в высокой позиции — мешает.
+	v.cost = float64(minF)*3 + float64(maxF-minF) + float64(fingers)*0.5 + float64(muted)*2
+	if v.Barre != nil {
+		v.cost++
+	}
+	for s, f := range assign {
+		switch {
+		case f != 0:
+		case maxF <= DefaultMaxStretch:
+			v.cost -= 0.5
+		case s > firstFretted && s < lastFretted:
+			v.cost += 1.5
+		}
+	}
+	return v, true
+}
+
+// barre проверяет, можно ли прижать указательным пальцем все струны с ладом
+// fret: между крайними такими струнами нет открытых и заглушённых
+func barre(assign []int, fret int) (Barre, bool) {
+	b := Barre{Fret: fret, From: -1}
+	for s, f := range assign {
+		if f == fret {
+			if b.From < 0 {
+				b.From = s
+			}
+			b.To = s
+		}
+	}
+	if b.From < 0 || b.From == b.To {
+		return Barre{}, false
+	}
+	for s := b.From; s <= b.To; s++ {
+		if assign[s] < fret {
+			return Barre{}, false
+		}
+	}
+	return b, true
+}
+
+// assignFingers расставляет пальцы по нажатым струнам: от нижнего лада к
+// верхнему, по возможности палец на лад, но так, чтобы пальцев хватило
+func assignFingers(fingers, assign, fretted []int, minF int, barre bool) {
+	sort.SliceStable(fretted, func(i, j int) bool {
+		if assign[fretted[i]] != assign[fretted[j]] {
+			return assign[fretted[i]] < assign[fretted[j]]
+		}
+		return fretted[i] < fretted[j]
+	})
+	prev := 0
+	if barre {
+		prev = 1
+	}
+	for k, s := range fretted {
+		finger := min(assign[s]-minF+1, maxFingers-(len(fretted)-1-k))
+		finger = max(finger, prev+1)
+		fingers[s] = finger
+		prev = finger
+	}
+}
+
+// shape — запись аппликатуры: "x32010", лады больше 9 — через точку: "8.10.10.9.8.8"
+func shape(frets []int) string {
+	wide := false
+	for _, f := range frets {
+		wide = wide || f > 9
+	}
+	parts := make([]string, len(frets))
+	for i, f := range frets {
+		if f < 0 {
+			parts[i] = "x"
+		} else {
+			parts[i] = fmt.Sprint(f)
+		}
+	}
+	if wide {
+		return strings.Join(parts, ".")
+	}
+	return strings.Join(parts, "")
+}
+
+// chordTones возвращает все звуки аккорда и обязательные: без квинты можно
+// обойтись в аккордах из четырёх и более звуков, а в 11- и 13-аккордах —
+// ещё и без промежуточных расширений
+func chordTones(c music.Chord) (all, must music.PitchClassSet) {
+	rootPC := c.Root.PitchClass()
+	top := 0
+	for _, iv := range c.Intervals {
+		top = max(top, iv.Degree)
+	}
+	for _, iv := range c.Intervals {
+		pc := rootPC.Transpose(iv.Semitones)
+		all = all.Add(pc)
+		optional := len(c.Intervals) >= 4 && iv.Degree == 5 && iv.Semitones == 7
+		optional = optional || (iv.Degree > 7 && iv.Degree < top)
+		if !optional {
+			must = must.Add(pc)
+		}
+	}
+	if c.Bass != nil {
+		all = all.Add(c.Bass.PitchClass())
+		must = must.Add(c.Bass.PitchClass())
+	}
+	return all, must
+}
+
+// spellNote записывает звучащую ноту так, как она пишется в аккорде: у Bb7 — Ab, а не G#
+func spellNote(c music.Chord, midi int) string {
+	names := c.Tones()
+	if c.Bass != nil {
+		names = append(names, *c.Bass)
+	}
+	for _, p := range names {
+		if int(p.PitchClass()) == mod12(midi) {
+			return noteAt(p, midi)
+		}
+	}
+	return music.NoteFromMIDI(midi, c.Root.Accidental < 0).String()
+}
+
+func mod12(n int) int {
+	return ((n % 12) + 12) % 12
+}
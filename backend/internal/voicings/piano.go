@@ -0,0 +1,147 @@
+package voicings
+
+import (
+	"sort"
+
+	"sonara-space/backend/internal/music"
+)
+
+// Регистры фортепианных расположений
+const (
+	rightHandFloor = 55 // G3: ниже правая рука звучит глухо
+	leftHandFloor  = 43 // G2
+	maxHandSpan    = 12 // октава — удобный охват руки
+	maxRightNotes  = 5
+)
+
+// Hand — партия одной руки: ноты снизу вверх и пальцы (1 — большой … 5 — мизинец)
+type Hand struct {
+	Notes   []string `json:"notes"`
+	MIDI    []int    `json:"midi"`
+	Fingers []int    `json:"fingers"`
+	Span    int      `json:"span"` // расстояние от нижней ноты до верхней в полутонах
+}
+
+// PianoVoicing — расположение аккорда на фортепиано: бас в левой руке,
+// обращение аккорда в правой
+type PianoVoicing struct {
+	Inversion int  `json:"inversion"` // 0 — основной вид, 1 — первое обращение, …
+	Left      Hand `json:"left"`
+	Right     Hand `json:"right"`
+}
+
+// Piano строит расположения аккорда для фортепиано: основной вид и
+// обращения в правой руке от G3 и бас аккорда в левой от G2. В правой руке
+// не больше пяти звуков — лишние (квинта, промежуточные расширения)
+// опускаются; расположения шире октавы отбрасываются.
+func Piano(chord music.Chord, opts Options) []PianoVoicing {
+	opts = opts.withDefaults()
+	tones := pianoTones(chord)
+
+	bass := chord.Root
+	if chord.Bass != nil {
+		bass = *chord.Bass
+	}
+	bassMIDI := above(bass.PitchClass(), leftHandFloor)
+	left := Hand{
+		Notes:   []string{noteAt(bass, bassMIDI)},
+		MIDI:    []int{bassMIDI},
+		Fingers: []int{5},
+	}
+
+	var out []PianoVoicing
+	for inv := range tones {
+		right := Hand{}
+		prev := rightHandFloor - 1
+		for k := range tones {
+			p := tones[(inv+k)%len(tones)]
+			midi := above(p.PitchClass(), prev+1)
+			right.Notes = append(right.Notes, noteAt(p, midi))
+			right.MIDI = append(right.MIDI, midi)
+			prev = midi
+		}
+		right.Span = right.MIDI[len(right.MIDI)-1] - right.MIDI[0]
+		if right.Span > maxHandSpan {
+			continue
+		}
+		right.Fingers = rightFingers(right.MIDI)
+		out = append(out, PianoVoicing{Inversion: inv, Left: left, Right: right})
+		if len(out) == opts.Limit {
+			break
+		}
+	}
+	return out
+}
+
+// pianoTones возвращает звуки аккорда для правой руки. Если их больше
+// пяти, сначала опускается квинта, затем расширения снизу вверх. Звуки
+// идут в тесном расположении от основного тона.
+func pianoTones(c music.Chord) []music.PitchName {
+	tones := c.Tones()
+	top := 0
+	for _, iv := range c.Intervals {
+		top = max(top, iv.Degree)
+	}
+	drop := func(keep func(music.Interval) bool) {
+		var ivs []music.Interval
+		var kept []music.PitchName
+		extra := len(tones) - maxRightNotes
+		for i, iv := range c.Intervals {
+			if extra > 0 && !keep(iv) {
+				extra--
+				continue
+			}
+			ivs = append(ivs, iv)
+			kept = append(kept, tones[i])
+		}
+		c.Intervals, tones = ivs, kept
+	}
+	drop(func(iv music.Interval) bool { return !(iv.Degree == 5 && iv.Semitones == 7) })
+	drop(func(iv music.Interval) bool { return iv.Degree <= 7 || iv.Degree == top })
+	// Тесное расположение: расширения переносятся в октаву основного тона
+	root := c.Root.PitchClass()
+	sort.SliceStable(tones, func(i, j int) bool {
+		return mod12(int(tones[i].PitchClass()-root)) < mod12(int(tones[j].PitchClass()-root))
+	})
+	return tones
+}
+
+// rightFingers подбирает аппликатуру правой руки: крайние звуки — большой
+// палец и мизинец, средние — по расстоянию между клавишами
+func rightFingers(midi []int) []int {
+	n := len(midi)
+	switch n {
+	case 1:
+		return []int{1}
+	case 2:
+		if midi[1]-midi[0] < 5 {
+			return []int{1, 3}
+		}
+		return []int{1, 5}
+	case 3:
+		// Широкий верхний интервал (кварта и больше) берётся вторым пальцем
+		if midi[2]-midi[1] >= 5 {
+			return []int{1, 2, 5}
+		}
+		return []int{1, 3, 5}
+	case 4:
+		// Тесная секунда наверху — четвёртым и пятым пальцами
+		if midi[3]-midi[2] <= 2 {
+			return []int{1, 2, 4, 5}
+		}
+		return []int{1, 2, 3, 5}
+	default:
+		return []int{1, 2, 3, 4, 5}
+	}
+}
+
+// above возвращает первую ноту класса pc не ниже MIDI-номера floor
+func above(pc music.PitchClass, floor int) int {
+	return floor + mod12(int(pc)-floor)
+}
+
+// noteAt записывает MIDI-номер нотой с названием p: Bb3, а не A#3
+func noteAt(p music.PitchName, midi int) string {
+	base := music.Note{PitchName: p}.MIDI()
+	return music.Note{PitchName: p, Octave: (midi - base) / 12}.String()
+}
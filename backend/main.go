@@ -8,9 +8,12 @@ import (
 	"time"
 
 	"sonara-space/backend/config"
+	"sonara-space/backend/internal/achievements"
 	"sonara-space/backend/internal/auth"
 	"sonara-space/backend/internal/db"
 	"sonara-space/backend/internal/handlers"
+	"sonara-space/backend/internal/leaderboard"
+	"sonara-space/backend/internal/storage"
 
 	"github.com/go-chi/chi/v5"
 	"github.com/go-chi/cors"
@@ -123,8 +126,8 @@ func main() {
 	r.Use(cors.Handler(cors.Options{
 		AllowedOrigins:   []string{"http://localhost:5173"}, // где крутится vite
 		AllowedMethods:   []string{"GET", "POST", "PUT", "DELETE", "OPTIONS"},
-		AllowedHeaders:   []string{"Accept", "Authorization", "Content-Type", "X-CSRF-Token"},
-		ExposedHeaders:   []string{"Link"},
+		AllowedHeaders:   []string{"Accept", "Authorization", "Content-Type", "X-CSRF-Token", "Range"},
+		ExposedHeaders:   []string{"Link", "Content-Range", "Accept-Ranges", "Content-Length"},
 		AllowCredentials: true,
 		MaxAge:           300,
 	}))
@@ -137,6 +140,35 @@ func main() {
 	defer pool.Close()
 	db.Pool = pool
 
+	// Рейтинги: Redis, а без него — Postgres
+	board, err := leaderboard.FromEnv(pool)
+	if board == nil {
+		log.Fatalf("leaderboard: %v", err)
+	}
+	if err != nil {
+		log.Printf("leaderboards are served from postgres: %v", err)
+	}
+	leaderboard.Store = board
+	go func() {
+		if err := board.Rebuild(context.Background()); err != nil {
+			log.Printf("leaderboard rebuild: %v", err)
+		}
+	}()
+
+	// Новые значки получают и те, кто выполнил условие раньше
+	go func() {
+		if err := achievements.Backfill(context.Background(), pool); err != nil {
+			log.Printf("achievements backfill: %v", err)
+		}
+	}()
+
+	// Хранилище медиафайлов (локальный диск или S3)
+	store, err := storage.FromEnv()
+	if err != nil {
+		log.Printf("storage is not configured, media uploads are disabled: %v", err)
+	}
+	storage.Store = store
+
 	// Health check
 	r.Get("/health", func(w http.ResponseWriter, r *http.Request) {
 		w.Write([]byte("OK"))
@@ -146,6 +178,15 @@ func main() {
 	r.Post("/auth/register", handlers.RegisterHandler)
 	r.Post("/auth/login", handlers.LoginHandler)
 
+	// Файлы локального хранилища: доступ по подписанной ссылке
+	r.Get("/media/*", handlers.MediaFileHandler)
+
+	// Справочник инструментов
+	r.Get("/instruments", handlers.GetInstrumentsHandler)
+
+	// Аппликатуры аккордов
+	r.Get("/chords/{symbol}/voicings", handlers.GetChordVoicingsHandler)
+
 	// Защищённые роуты
 	r.Group(func(protected chi.Router) {
 		protected.Use(auth.JWTMiddleware)
@@ -214,10 +255,86 @@ func main() {
 		// Уроки (временно без проверки подписки)
 		protected.Get("/lessons", handlers.GetLessonsHandler)
 		protected.Get("/lessons/{id}", handlers.GetLessonHandler)
+		protected.Get("/lessons/{id}/media", handlers.GetLessonMediaHandler)
+		protected.Get("/lessons/{id}/progress", handlers.GetLessonProgressHandler)
+		protected.Post("/lessons/{id}/upgrade", handlers.UpgradeLessonHandler)
+
+		// Курсы и рекомендации
+		protected.Get("/courses", handlers.GetCoursesHandler)
+		protected.Get("/courses/{id}", handlers.GetCourseHandler)
+		protected.Get("/courses/{id}/next-lesson", handlers.GetCourseNextLessonHandler)
+		protected.Get("/me/next-lesson", handlers.GetNextLessonHandler)
+
+		// Навыки и их освоение
+		protected.Get("/skills", handlers.GetSkillsHandler)
+		protected.Get("/me/skills", handlers.GetMySkillsHandler)
+
+		// Серии занятий, опыт и дневные цели
+		protected.Get("/me/stats", handlers.GetMyStatsHandler)
+		protected.Put("/me/goal", handlers.UpdateMyGoalHandler)
+		protected.Put("/me/timezone", handlers.UpdateMyTimezoneHandler)
+		protected.Get("/me/achievements", handlers.GetMyAchievementsHandler)
+
+		// Рейтинги и группы учеников
+		protected.Get("/leaderboards", handlers.GetLeaderboardHandler)
+		protected.Put("/me/leaderboard", handlers.UpdateLeaderboardSettingsHandler)
+		protected.Get("/groups", handlers.GetMyGroupsHandler)
+		protected.Post("/groups", handlers.CreateGroupHandler)
+		protected.Post("/groups/join", handlers.JoinGroupHandler)
+		protected.Delete("/groups/{id}/members/me", handlers.LeaveGroupHandler)
+
+		// Сессии занятий
+		protected.Post("/sessions/start", handlers.StartPracticeSessionHandler)
+		protected.Get("/sessions/{id}", handlers.GetPracticeSessionHandler)
+		protected.Post("/sessions/{id}/heartbeat", handlers.PracticeHeartbeatHandler)
+		protected.Post("/sessions/{id}/end", handlers.EndPracticeSessionHandler)
 
 		// Прогресс (временно без проверки подписки)
 		protected.Post("/progress", handlers.UpdateProgressHandler)
 		protected.Get("/progress", handlers.GetUserProgressHandler)
+		protected.Get("/reviews/due", handlers.GetDueReviewsHandler)
+
+		// Попытки: запись проверяется на сервере
+		protected.Post("/exercises/{id}/attempts", handlers.SubmitAttemptHandler)
+		protected.Get("/exercises/{id}/attempts", handlers.GetAttemptsHandler)
+
+		// Тренировка слуха: сгенерированные задания со звуком
+		protected.Post("/ear-training/sessions", handlers.CreateEarTrainingSessionHandler)
+		protected.Get("/ear-training/sessions/{id}", handlers.GetEarTrainingSessionHandler)
+		protected.Get("/ear-training/sessions/{id}/questions/{index}/audio", handlers.GetEarTrainingAudioHandler)
+		protected.Post("/ear-training/sessions/{id}/questions/{index}/answer", handlers.AnswerEarTrainingHandler)
+
+		// Управление контентом (только администраторы)
+		protected.Route("/admin", func(admin chi.Router) {
+			admin.Use(auth.RequireAdmin)
+
+			admin.Get("/lessons", handlers.AdminListLessonsHandler)
+			admin.Post("/lessons", handlers.AdminCreateLessonHandler)
+			admin.Get("/lessons/{id}", handlers.AdminGetLessonHandler)
+			admin.Put("/lessons/{id}", handlers.AdminUpdateLessonHandler)
+			admin.Delete("/lessons/{id}", handlers.AdminDeleteLessonHandler)
+			admin.Post("/lessons/{id}/publish", handlers.AdminPublishLessonHandler)
+			admin.Post("/lessons/{id}/unpublish", handlers.AdminUnpublishLessonHandler)
+
+			admin.Post("/lessons/{id}/exercises", handlers.AdminCreateExerciseHandler)
+			admin.Put("/lessons/{id}/exercises/order", handlers.AdminReorderExercisesHandler)
+			admin.Post("/lessons/{id}/notation", handlers.AdminImportNotationHandler)
+			admin.Post("/lessons/{id}/media", handlers.AdminUploadMediaHandler)
+			admin.Delete("/media/{id}", handlers.AdminDeleteMediaHandler)
+			admin.Put("/exercises/{id}", handlers.AdminUpdateExerciseHandler)
+			admin.Delete("/exercises/{id}", handlers.AdminDeleteExerciseHandler)
+			admin.Put("/exercises/{id}/skills", handlers.AdminSetExerciseSkillsHandler)
+
+			admin.Post("/skills", handlers.AdminCreateSkillHandler)
+			admin.Put("/skills/{id}", handlers.AdminUpdateSkillHandler)
+			admin.Delete("/skills/{id}", handlers.AdminDeleteSkillHandler)
+
+			admin.Get("/translations/export", handlers.AdminExportTranslationsHandler)
+			admin.Post("/translations/import", handlers.AdminImportTranslationsHandler)
+
+			admin.Get("/content/export", handlers.AdminExportContentHandler)
+			admin.Post("/content/import", handlers.AdminImportContentHandler)
+		})
 
 		// контент, доступный только подписчикам
 		protected.Group(func(sub chi.Router) {
@@ -0,0 +1,3 @@
+-- Упражнения, последовательности и прогресс удаляются каскадно
+DELETE FROM lessons
+WHERE title IN ('Первые песни на гитаре', 'Простые мелодии на пианино', 'Популярные аккорды', 'Гаммы и мелодии');
@@ -0,0 +1,11 @@
+DROP INDEX IF EXISTS idx_exercises_lesson_order;
+DROP INDEX IF EXISTS idx_lessons_status;
+
+ALTER TABLE exercises DROP COLUMN IF EXISTS updated_at;
+
+ALTER TABLE lessons DROP CONSTRAINT IF EXISTS lessons_status_chk;
+ALTER TABLE lessons
+DROP COLUMN IF EXISTS updated_at,
+DROP COLUMN IF EXISTS status;
+
+ALTER TABLE users DROP COLUMN IF EXISTS is_admin;
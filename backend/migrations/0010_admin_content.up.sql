@@ -0,0 +1,19 @@
+-- Миграция 10: управление контентом через админ-API
+
+-- Флаг администратора (редактора контента)
+ALTER TABLE users
+ADD COLUMN IF NOT EXISTS is_admin BOOLEAN NOT NULL DEFAULT FALSE;
+
+-- Черновики и публикация уроков. Уже существующие уроки считаем опубликованными.
+ALTER TABLE lessons
+ADD COLUMN IF NOT EXISTS status TEXT NOT NULL DEFAULT 'published',
+ADD COLUMN IF NOT EXISTS updated_at TIMESTAMP NOT NULL DEFAULT NOW();
+
+ALTER TABLE lessons
+ADD CONSTRAINT lessons_status_chk CHECK (status IN ('draft','published'));
+
+ALTER TABLE exercises
+ADD COLUMN IF NOT EXISTS updated_at TIMESTAMP NOT NULL DEFAULT NOW();
+
+CREATE INDEX IF NOT EXISTS idx_lessons_status ON lessons (status);
+CREATE INDEX IF NOT EXISTS idx_exercises_lesson_order ON exercises (lesson_id, order_index);
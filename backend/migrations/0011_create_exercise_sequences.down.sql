@@ -0,0 +1,43 @@
+-- Возвращаем разбивку песен "по аккорду" (строки миграции 9) вместо
+-- упражнений-последовательностей. Прогресс по песне переносится на каждую
+-- её часть; песни, созданные позже через админку, удаляются.
+CREATE TEMP TABLE song_parts (
+    lesson_title TEXT,
+    song_title   TEXT,
+    title        TEXT,
+    expected     TEXT,
+    type         TEXT,
+    order_index  INT
+);
+
+INSERT INTO song_parts VALUES
+('Первые песни на гитаре', 'Песня "Happy Birthday"', 'Песня "Happy Birthday" - аккорд Am', 'Am', 'chord', 5),
+('Первые песни на гитаре', 'Песня "Happy Birthday"', 'Песня "Happy Birthday" - аккорд C', 'C', 'chord', 6),
+('Первые песни на гитаре', 'Песня "Happy Birthday"', 'Песня "Happy Birthday" - аккорд G', 'G', 'chord', 7),
+('Первые песни на гитаре', 'Песня "Happy Birthday"', 'Песня "Happy Birthday" - аккорд F', 'F', 'chord', 8),
+('Простые мелодии на пианино', 'Мелодия "До-ре-ми"', 'Мелодия "До-ре-ми" - нота C4', 'C4', 'note', 8),
+('Простые мелодии на пианино', 'Мелодия "До-ре-ми"', 'Мелодия "До-ре-ми" - нота D4', 'D4', 'note', 9),
+('Простые мелодии на пианино', 'Мелодия "До-ре-ми"', 'Мелодия "До-ре-ми" - нота E4', 'E4', 'note', 10),
+('Гаммы и мелодии', 'Мелодия "Twinkle Twinkle"', 'Мелодия "Twinkle Twinkle" - C4', 'C4', 'note', 9),
+('Гаммы и мелодии', 'Мелодия "Twinkle Twinkle"', 'Мелодия "Twinkle Twinkle" - G4', 'G4', 'note', 10);
+
+CREATE TEMP TABLE restored_parts AS
+SELECT e.id AS sequence_id, e.lesson_id, p.title, p.expected, p.type, p.order_index
+FROM song_parts p
+JOIN lessons l ON l.title = p.lesson_title
+JOIN exercises e ON e.lesson_id = l.id AND e.type = 'sequence' AND e.title = p.song_title;
+
+INSERT INTO exercises (lesson_id, title, expected, type, order_index)
+SELECT lesson_id, title, expected, type, order_index FROM restored_parts;
+
+INSERT INTO progress (user_id, exercise_id, completed, attempts, best_score, completed_at, created_at, updated_at)
+SELECT p.user_id, e.id, p.completed, p.attempts, p.best_score, p.completed_at, p.created_at, p.updated_at
+FROM restored_parts r
+JOIN progress p ON p.exercise_id = r.sequence_id
+JOIN exercises e ON e.lesson_id = r.lesson_id AND e.title = r.title AND e.type = r.type;
+
+DELETE FROM exercises WHERE type = 'sequence';
+DROP TABLE IF EXISTS exercise_sequences;
+
+DROP TABLE restored_parts;
+DROP TABLE song_parts;
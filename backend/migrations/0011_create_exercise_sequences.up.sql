@@ -0,0 +1,89 @@
+-- Миграция 11: песни и мелодии как отдельный тип упражнения "sequence"
+
+CREATE TABLE IF NOT EXISTS exercise_sequences (
+    exercise_id     INT PRIMARY KEY REFERENCES exercises(id) ON DELETE CASCADE,
+    song_title      TEXT NOT NULL,
+    step_type       TEXT NOT NULL,
+    CONSTRAINT exercise_sequences_step_type_chk
+        CHECK (step_type IN ('chord','note')),
+    tempo           INT NOT NULL DEFAULT 90,        -- удары в минуту
+    beats_per_bar   INT NOT NULL DEFAULT 4,         -- размер: числитель
+    beat_unit       INT NOT NULL DEFAULT 4,         -- размер: знаменатель
+    CONSTRAINT exercise_sequences_meter_chk
+        CHECK (tempo > 0 AND beats_per_bar > 0 AND beat_unit IN (1,2,4,8,16)),
+    -- [{"symbol":"Am","beats":3,"lyric":"Hap-py"}, ...]
+    steps           JSONB NOT NULL
+);
+
+-- Переносим песни, которые раньше были разбиты на упражнения "по аккорду",
+-- в одно упражнение-последовательность. Прогресс по старым строкам переносится:
+-- упражнение засчитывается, если были пройдены все его части.
+CREATE TEMP TABLE song_migration (
+    lesson_title  TEXT,
+    title_prefix  TEXT,
+    new_title     TEXT,
+    song_title    TEXT,
+    step_type     TEXT,
+    tempo         INT,
+    beats_per_bar INT,
+    steps         JSONB
+);
+
+INSERT INTO song_migration VALUES
+('Первые песни на гитаре', 'Песня "Happy Birthday"', 'Песня "Happy Birthday"', 'Happy Birthday', 'chord', 90, 3,
+ '[{"symbol":"Am","beats":3,"lyric":"Hap-py birth-day"},{"symbol":"C","beats":3,"lyric":"to you"},
+   {"symbol":"G","beats":3,"lyric":"Hap-py birth-day"},{"symbol":"F","beats":3,"lyric":"to you"},
+   {"symbol":"Am","beats":3,"lyric":"Hap-py birth-day"},{"symbol":"C","beats":3,"lyric":"dear friend"},
+   {"symbol":"G","beats":3,"lyric":"Hap-py birth-day"},{"symbol":"F","beats":3,"lyric":"to you"}]'),
+('Простые мелодии на пианино', 'Мелодия "До-ре-ми"', 'Мелодия "До-ре-ми"', 'До-ре-ми', 'note', 80, 4,
+ '[{"symbol":"C4","beats":1,"lyric":"до"},{"symbol":"D4","beats":1,"lyric":"ре"},
+   {"symbol":"E4","beats":1,"lyric":"ми"},{"symbol":"F4","beats":1,"lyric":"фа"},
+   {"symbol":"G4","beats":1,"lyric":"соль"},{"symbol":"A4","beats":1,"lyric":"ля"},
+   {"symbol":"B4","beats":1,"lyric":"си"},{"symbol":"C5","beats":1,"lyric":"до"}]'),
+('Гаммы и мелодии', 'Мелодия "Twinkle Twinkle"', 'Мелодия "Twinkle Twinkle"', 'Twinkle Twinkle Little Star', 'note', 100, 4,
+ '[{"symbol":"C4","beats":1,"lyric":"Twin-"},{"symbol":"C4","beats":1,"lyric":"kle"},
+   {"symbol":"G4","beats":1,"lyric":"twin-"},{"symbol":"G4","beats":1,"lyric":"kle"},
+   {"symbol":"A4","beats":1,"lyric":"lit-"},{"symbol":"A4","beats":1,"lyric":"tle"},
+   {"symbol":"G4","beats":2,"lyric":"star"}]');
+
+CREATE TEMP TABLE song_exercises AS
+SELECT m.*, l.id AS lesson_id,
+       MIN(e.order_index) AS order_index,
+       ARRAY_AGG(e.id) AS old_ids
+FROM song_migration m
+JOIN lessons l ON l.title = m.lesson_title
+JOIN exercises e ON e.lesson_id = l.id AND e.title LIKE m.title_prefix || '%'
+GROUP BY m.lesson_title, m.title_prefix, m.new_title, m.song_title, m.step_type,
+         m.tempo, m.beats_per_bar, m.steps, l.id;
+
+ALTER TABLE song_exercises ADD COLUMN new_id INT;
+
+UPDATE song_exercises s
+SET new_id = nextval(pg_get_serial_sequence('exercises', 'id'));
+
+INSERT INTO exercises (id, lesson_id, title, expected, type, order_index)
+SELECT s.new_id, s.lesson_id, s.new_title,
+       (SELECT string_agg(step->>'symbol', ' ') FROM jsonb_array_elements(s.steps) step),
+       'sequence', s.order_index
+FROM song_exercises s;
+
+INSERT INTO exercise_sequences (exercise_id, song_title, step_type, tempo, beats_per_bar, beat_unit, steps)
+SELECT s.new_id, s.song_title, s.step_type, s.tempo, s.beats_per_bar, 4, s.steps
+FROM song_exercises s;
+
+INSERT INTO progress (user_id, exercise_id, completed, attempts, best_score, completed_at, created_at, updated_at)
+SELECT p.user_id, s.new_id,
+       BOOL_AND(p.completed) AND COUNT(*) = CARDINALITY(s.old_ids),
+       SUM(p.attempts), MAX(p.best_score),
+       CASE WHEN BOOL_AND(p.completed) AND COUNT(*) = CARDINALITY(s.old_ids) THEN MAX(p.completed_at) END,
+       MIN(p.created_at), MAX(p.updated_at)
+FROM song_exercises s
+JOIN progress p ON p.exercise_id = ANY(s.old_ids)
+GROUP BY p.user_id, s.new_id, s.old_ids;
+
+DELETE FROM exercises e
+USING song_exercises s
+WHERE e.id = ANY(s.old_ids);
+
+DROP TABLE song_exercises;
+DROP TABLE song_migration;
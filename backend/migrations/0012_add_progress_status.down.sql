@@ -0,0 +1,4 @@
+ALTER TABLE progress
+DROP COLUMN IF EXISTS scored_by_server,
+DROP COLUMN IF EXISTS last_score,
+DROP COLUMN IF EXISTS status;
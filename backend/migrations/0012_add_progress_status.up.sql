@@ -0,0 +1,9 @@
+-- Миграция 12: статус прогресса и оценки попыток, посчитанные сервером
+
+-- UpdateProgressHandler уже пишет status, но колонки в таблице не было
+ALTER TABLE progress
+ADD COLUMN IF NOT EXISTS status TEXT NOT NULL DEFAULT 'in_progress',
+ADD COLUMN IF NOT EXISTS last_score DECIMAL(5,2),
+ADD COLUMN IF NOT EXISTS scored_by_server BOOLEAN NOT NULL DEFAULT FALSE;
+
+UPDATE progress SET status = 'done' WHERE completed = TRUE;
@@ -0,0 +1,36 @@
+-- Миграция 13: история попыток; progress становится сводкой по этой таблице
+
+CREATE TABLE IF NOT EXISTS exercise_attempts (
+    id                  BIGSERIAL PRIMARY KEY,
+    user_id             INT NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+    exercise_id         INT NOT NULL REFERENCES exercises(id) ON DELETE CASCADE,
+
+    source              TEXT NOT NULL,      -- кто поставил оценку
+    CONSTRAINT exercise_attempts_source_chk
+        CHECK (source IN ('server','client','legacy')),
+
+    status              TEXT NOT NULL,
+    passed              BOOLEAN NOT NULL DEFAULT FALSE,
+    score               DECIMAL(5,2) NOT NULL DEFAULT 0.00,
+    detected            JSONB,              -- распознанные ноты/аккорды и подробности анализа
+    timing_deviation_ms INT,                -- среднее отклонение от ритма (если применимо)
+    duration_ms         INT,                -- длительность записи
+
+    created_at          TIMESTAMP NOT NULL DEFAULT NOW()
+);
+
+CREATE INDEX IF NOT EXISTS idx_exercise_attempts_user_exercise
+ON exercise_attempts (user_id, exercise_id, created_at DESC);
+
+-- Переносим существующий прогресс в историю, чтобы пересчёт сводки ничего не потерял:
+-- на каждую запись progress создаём attempts попыток, последняя несёт best_score и статус.
+INSERT INTO exercise_attempts (user_id, exercise_id, source, status, passed, score, created_at)
+SELECT p.user_id, p.exercise_id, 'legacy',
+       CASE WHEN n = GREATEST(p.attempts, 1) THEN p.status ELSE 'failed' END,
+       n = GREATEST(p.attempts, 1) AND COALESCE(p.completed, FALSE),
+       CASE WHEN n = GREATEST(p.attempts, 1) THEN COALESCE(p.best_score, 0) ELSE 0 END,
+       CASE WHEN n = GREATEST(p.attempts, 1)
+            THEN COALESCE(p.completed_at, p.updated_at, p.created_at, NOW())
+            ELSE COALESCE(p.created_at, NOW()) END
+FROM progress p
+CROSS JOIN LATERAL generate_series(1, GREATEST(p.attempts, 1)) AS n;
@@ -0,0 +1,2 @@
+ALTER TABLE exercise_attempts DROP CONSTRAINT IF EXISTS exercise_attempts_status_chk;
+ALTER TABLE progress DROP CONSTRAINT IF EXISTS progress_status_chk;
@@ -0,0 +1,18 @@
+-- Миграция 14: статусы попыток и прогресса — проверяемое перечисление
+
+-- Раньше клиент мог прислать любой статус, приводим старые значения к перечислению
+UPDATE progress
+SET status = CASE WHEN completed THEN 'done' ELSE 'in_progress' END
+WHERE status NOT IN ('in_progress','done','failed')
+   OR (completed AND status <> 'done');
+
+UPDATE exercise_attempts
+SET status = CASE WHEN passed THEN 'done' ELSE 'failed' END
+WHERE status NOT IN ('in_progress','done','failed')
+   OR (passed AND status <> 'done');
+
+ALTER TABLE progress
+ADD CONSTRAINT progress_status_chk CHECK (status IN ('in_progress','done','failed'));
+
+ALTER TABLE exercise_attempts
+ADD CONSTRAINT exercise_attempts_status_chk CHECK (status IN ('in_progress','done','failed'));
@@ -0,0 +1,14 @@
+DROP INDEX IF EXISTS idx_lessons_catalog;
+DROP INDEX IF EXISTS idx_lessons_tags;
+DROP INDEX IF EXISTS idx_lessons_search;
+
+ALTER TABLE lessons ALTER COLUMN created_at DROP NOT NULL;
+
+ALTER TABLE lessons DROP CONSTRAINT IF EXISTS lessons_tier_chk;
+ALTER TABLE lessons DROP CONSTRAINT IF EXISTS lessons_difficulty_chk;
+ALTER TABLE lessons
+DROP COLUMN IF EXISTS search_vector,
+DROP COLUMN IF EXISTS tags,
+DROP COLUMN IF EXISTS locale,
+DROP COLUMN IF EXISTS tier,
+DROP COLUMN IF EXISTS difficulty;
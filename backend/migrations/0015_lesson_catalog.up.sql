@@ -0,0 +1,44 @@
+-- Миграция 15: каталог уроков — фильтры, полнотекстовый поиск и курсорная пагинация
+
+ALTER TABLE lessons
+ADD COLUMN IF NOT EXISTS difficulty TEXT NOT NULL DEFAULT 'beginner',
+ADD COLUMN IF NOT EXISTS tier TEXT NOT NULL DEFAULT 'free',
+ADD COLUMN IF NOT EXISTS locale VARCHAR(10) NOT NULL DEFAULT 'ru',
+ADD COLUMN IF NOT EXISTS tags TEXT[] NOT NULL DEFAULT '{}';
+
+ALTER TABLE lessons
+ADD CONSTRAINT lessons_difficulty_chk CHECK (difficulty IN ('beginner','intermediate','advanced')),
+ADD CONSTRAINT lessons_tier_chk CHECK (tier IN ('free','premium'));
+
+-- Курсор строится по (created_at, id), поэтому created_at не может быть NULL
+UPDATE lessons SET created_at = NOW() WHERE created_at IS NULL;
+ALTER TABLE lessons ALTER COLUMN created_at SET NOT NULL;
+
+-- Поиск по названию (вес A) и описанию (вес B) сразу в русской и английской конфигурациях
+ALTER TABLE lessons
+ADD COLUMN IF NOT EXISTS search_vector tsvector GENERATED ALWAYS AS (
+    setweight(to_tsvector('russian', coalesce(title, '')), 'A') ||
+    setweight(to_tsvector('english', coalesce(title, '')), 'A') ||
+    setweight(to_tsvector('russian', coalesce(description, '')), 'B') ||
+    setweight(to_tsvector('english', coalesce(description, '')), 'B')
+) STORED;
+
+CREATE INDEX IF NOT EXISTS idx_lessons_search ON lessons USING GIN (search_vector);
+CREATE INDEX IF NOT EXISTS idx_lessons_tags ON lessons USING GIN (tags);
+CREATE INDEX IF NOT EXISTS idx_lessons_catalog ON lessons (status, created_at, id);
+
+-- Теги для существующих уроков по типам упражнений
+UPDATE lessons l
+SET tags = ARRAY(
+    SELECT DISTINCT CASE e.type
+        WHEN 'chord' THEN 'chords'
+        WHEN 'note' THEN 'notes'
+        WHEN 'sequence' THEN 'songs'
+    END
+    FROM exercises e
+    WHERE e.lesson_id = l.id AND e.type IN ('chord','note','sequence')
+    ORDER BY 1
+);
+
+UPDATE lessons SET difficulty = 'intermediate'
+WHERE title IN ('Популярные аккорды', 'Гаммы и мелодии');
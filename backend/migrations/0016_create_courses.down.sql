@@ -0,0 +1,4 @@
+DROP TABLE IF EXISTS lesson_prerequisites;
+DROP TABLE IF EXISTS module_lessons;
+DROP TABLE IF EXISTS course_modules;
+DROP TABLE IF EXISTS courses;
@@ -0,0 +1,106 @@
+-- Миграция 16: курсы — упорядоченные модули из уроков и пререквизиты уроков
+
+CREATE TABLE IF NOT EXISTS courses (
+    id          SERIAL PRIMARY KEY,
+    title       TEXT NOT NULL,
+    instrument  TEXT NOT NULL,
+    description TEXT,
+    status      TEXT NOT NULL DEFAULT 'published',
+    CONSTRAINT courses_status_chk CHECK (status IN ('draft','published')),
+    created_at  TIMESTAMP NOT NULL DEFAULT NOW(),
+    updated_at  TIMESTAMP NOT NULL DEFAULT NOW()
+);
+
+CREATE TABLE IF NOT EXISTS course_modules (
+    id          SERIAL PRIMARY KEY,
+    course_id   INT NOT NULL REFERENCES courses(id) ON DELETE CASCADE,
+    title       TEXT NOT NULL,
+    order_index INT NOT NULL
+);
+
+CREATE INDEX IF NOT EXISTS idx_course_modules_course ON course_modules (course_id, order_index);
+
+-- Урок может входить в несколько курсов, но в один модуль — один раз
+CREATE TABLE IF NOT EXISTS module_lessons (
+    module_id   INT NOT NULL REFERENCES course_modules(id) ON DELETE CASCADE,
+    lesson_id   INT NOT NULL REFERENCES lessons(id) ON DELETE CASCADE,
+    order_index INT NOT NULL,
+    PRIMARY KEY (module_id, lesson_id)
+);
+
+CREATE INDEX IF NOT EXISTS idx_module_lessons_lesson ON module_lessons (lesson_id);
+
+-- Урок lesson_id открывается, когда пройдено min_percent% урока required_lesson_id
+CREATE TABLE IF NOT EXISTS lesson_prerequisites (
+    lesson_id          INT NOT NULL REFERENCES lessons(id) ON DELETE CASCADE,
+    required_lesson_id INT NOT NULL REFERENCES lessons(id) ON DELETE CASCADE,
+    min_percent        INT NOT NULL DEFAULT 80,
+    PRIMARY KEY (lesson_id, required_lesson_id),
+    CONSTRAINT lesson_prerequisites_self_chk CHECK (lesson_id <> required_lesson_id),
+    CONSTRAINT lesson_prerequisites_percent_chk CHECK (min_percent BETWEEN 1 AND 100)
+);
+
+CREATE INDEX IF NOT EXISTS idx_lesson_prerequisites_required ON lesson_prerequisites (required_lesson_id);
+
+-- Курсы из существующих уроков. Уроки ищем по названию: ID в разных базах отличаются.
+CREATE TEMP TABLE course_seed (
+    course      TEXT,
+    instrument  TEXT,
+    module      TEXT,
+    module_pos  INT,
+    lesson      TEXT,
+    lesson_pos  INT
+);
+
+INSERT INTO course_seed VALUES
+('Гитара с нуля', 'guitar', 'Первые аккорды', 1, 'Основы гитары', 1),
+('Гитара с нуля', 'guitar', 'Первые аккорды', 1, 'Простые аккорды', 2),
+('Гитара с нуля', 'guitar', 'Песни', 2, 'Популярные аккорды', 1),
+('Гитара с нуля', 'guitar', 'Песни', 2, 'Первые песни на гитаре', 2),
+('Пианино с нуля', 'piano', 'Ноты и гаммы', 1, 'Основы пианино', 1),
+('Пианино с нуля', 'piano', 'Ноты и гаммы', 1, 'Гаммы на пианино', 2),
+('Пианино с нуля', 'piano', 'Мелодии', 2, 'Простые мелодии на пианино', 1),
+('Пианино с нуля', 'piano', 'Мелодии', 2, 'Гаммы и мелодии', 2);
+
+INSERT INTO courses (title, instrument, description)
+SELECT DISTINCT s.course, s.instrument,
+       CASE s.instrument WHEN 'guitar' THEN 'От первых аккордов до песен'
+                         ELSE 'От первых нот до мелодий двумя руками' END
+FROM course_seed s
+WHERE EXISTS (SELECT 1 FROM lessons l WHERE l.title = s.lesson)
+  AND NOT EXISTS (SELECT 1 FROM courses c WHERE c.title = s.course);
+
+INSERT INTO course_modules (course_id, title, order_index)
+SELECT DISTINCT c.id, s.module, s.module_pos
+FROM course_seed s
+JOIN courses c ON c.title = s.course
+WHERE NOT EXISTS (SELECT 1 FROM course_modules m WHERE m.course_id = c.id AND m.title = s.module);
+
+INSERT INTO module_lessons (module_id, lesson_id, order_index)
+SELECT m.id, l.id, s.lesson_pos
+FROM course_seed s
+JOIN courses c ON c.title = s.course
+JOIN course_modules m ON m.course_id = c.id AND m.title = s.module
+JOIN lessons l ON l.title = s.lesson
+ON CONFLICT DO NOTHING;
+
+-- Каждый следующий урок курса открывается после 80% предыдущего
+INSERT INTO lesson_prerequisites (lesson_id, required_lesson_id, min_percent)
+SELECT cur.lesson_id, prev.lesson_id, 80
+FROM (
+    SELECT c.id AS course_id, ml.lesson_id,
+           ROW_NUMBER() OVER (PARTITION BY c.id ORDER BY m.order_index, ml.order_index) AS pos
+    FROM courses c
+    JOIN course_modules m ON m.course_id = c.id
+    JOIN module_lessons ml ON ml.module_id = m.id
+) cur
+JOIN (
+    SELECT c.id AS course_id, ml.lesson_id,
+           ROW_NUMBER() OVER (PARTITION BY c.id ORDER BY m.order_index, ml.order_index) AS pos
+    FROM courses c
+    JOIN course_modules m ON m.course_id = c.id
+    JOIN module_lessons ml ON ml.module_id = m.id
+) prev ON prev.course_id = cur.course_id AND prev.pos = cur.pos - 1
+ON CONFLICT DO NOTHING;
+
+DROP TABLE course_seed;
@@ -0,0 +1,20 @@
+-- Миграция 17: переводы уроков и упражнений.
+-- Исходный текст остаётся в lessons/exercises на языке lessons.locale,
+-- здесь хранятся только переводы на другие языки.
+
+CREATE TABLE IF NOT EXISTS lesson_translations (
+    lesson_id   INT NOT NULL REFERENCES lessons(id) ON DELETE CASCADE,
+    locale      VARCHAR(10) NOT NULL,
+    title       TEXT,
+    description TEXT,
+    updated_at  TIMESTAMP NOT NULL DEFAULT NOW(),
+    PRIMARY KEY (lesson_id, locale)
+);
+
+CREATE TABLE IF NOT EXISTS exercise_translations (
+    exercise_id INT NOT NULL REFERENCES exercises(id) ON DELETE CASCADE,
+    locale      VARCHAR(10) NOT NULL,
+    title       TEXT NOT NULL,
+    updated_at  TIMESTAMP NOT NULL DEFAULT NOW(),
+    PRIMARY KEY (exercise_id, locale)
+);
@@ -0,0 +1,11 @@
+DROP TABLE IF EXISTS lesson_media;
+
+DROP INDEX IF EXISTS idx_course_modules_position;
+
+DROP INDEX IF EXISTS idx_exercises_lesson_slug;
+DROP INDEX IF EXISTS idx_lessons_slug;
+DROP INDEX IF EXISTS idx_courses_slug;
+
+ALTER TABLE exercises DROP COLUMN IF EXISTS slug;
+ALTER TABLE lessons DROP COLUMN IF EXISTS slug;
+ALTER TABLE courses DROP COLUMN IF EXISTS slug;
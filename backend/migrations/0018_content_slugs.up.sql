@@ -0,0 +1,68 @@
+-- Миграция 18: стабильные slug для импорта/экспорта контента и медиа уроков
+
+ALTER TABLE courses ADD COLUMN IF NOT EXISTS slug TEXT;
+ALTER TABLE lessons ADD COLUMN IF NOT EXISTS slug TEXT;
+ALTER TABLE exercises ADD COLUMN IF NOT EXISTS slug TEXT;
+
+UPDATE courses SET slug = CASE title
+    WHEN 'Гитара с нуля' THEN 'guitar-from-scratch'
+    WHEN 'Пианино с нуля' THEN 'piano-from-scratch'
+    ELSE 'course-' || id END
+WHERE slug IS NULL;
+
+UPDATE lessons SET slug = CASE title
+    WHEN 'Основы гитары' THEN 'guitar-basics'
+    WHEN 'Основы пианино' THEN 'piano-basics'
+    WHEN 'Простые аккорды' THEN 'simple-chords'
+    WHEN 'Гаммы на пианино' THEN 'piano-scales'
+    WHEN 'Первые песни на гитаре' THEN 'first-guitar-songs'
+    WHEN 'Простые мелодии на пианино' THEN 'simple-piano-melodies'
+    WHEN 'Популярные аккорды' THEN 'popular-chords'
+    WHEN 'Гаммы и мелодии' THEN 'scales-and-melodies'
+    ELSE 'lesson-' || id END
+WHERE slug IS NULL;
+
+-- Дубликаты названий (если уроки создавались вручную) получают суффикс с ID
+UPDATE lessons l SET slug = l.slug || '-' || l.id
+WHERE EXISTS (SELECT 1 FROM lessons o WHERE o.slug = l.slug AND o.id < l.id);
+
+-- Упражнения: slug из ожидаемого значения ("chord-am", "note-c4"), песни — "song-N"
+UPDATE exercises e
+SET slug = b.base || CASE WHEN b.n > 1 OR b.base = 'song' THEN '-' || b.n ELSE '' END
+FROM (
+    SELECT x.id, x.base,
+           ROW_NUMBER() OVER (PARTITION BY x.lesson_id, x.base ORDER BY x.order_index, x.id) AS n
+    FROM (
+        SELECT id, lesson_id, order_index,
+               CASE WHEN type = 'sequence' THEN 'song'
+                    ELSE type || '-' || COALESCE(NULLIF(
+                        trim(both '-' FROM lower(regexp_replace(expected, '[^A-Za-z0-9]+', '-', 'g'))), ''), 'x')
+               END AS base
+        FROM exercises
+    ) x
+) b
+WHERE e.id = b.id AND e.slug IS NULL;
+
+ALTER TABLE courses ALTER COLUMN slug SET NOT NULL;
+ALTER TABLE lessons ALTER COLUMN slug SET NOT NULL;
+ALTER TABLE exercises ALTER COLUMN slug SET NOT NULL;
+
+CREATE UNIQUE INDEX IF NOT EXISTS idx_courses_slug ON courses (slug);
+CREATE UNIQUE INDEX IF NOT EXISTS idx_lessons_slug ON lessons (slug);
+CREATE UNIQUE INDEX IF NOT EXISTS idx_exercises_lesson_slug ON exercises (lesson_id, slug);
+
+-- Медиа урока: ссылки на аудио, видео, ноты и картинки
+CREATE TABLE IF NOT EXISTS lesson_media (
+    id          SERIAL PRIMARY KEY,
+    lesson_id   INT NOT NULL REFERENCES lessons(id) ON DELETE CASCADE,
+    kind        TEXT NOT NULL,
+    CONSTRAINT lesson_media_kind_chk CHECK (kind IN ('audio','video','image','score','other')),
+    uri         TEXT NOT NULL,
+    title       TEXT,
+    order_index INT NOT NULL DEFAULT 0,
+    created_at  TIMESTAMP NOT NULL DEFAULT NOW(),
+    UNIQUE (lesson_id, uri)
+);
+
+-- Импорт сопоставляет модули курса по позиции
+CREATE UNIQUE INDEX IF NOT EXISTS idx_course_modules_position ON course_modules (course_id, order_index);
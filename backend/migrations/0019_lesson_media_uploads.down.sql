@@ -0,0 +1,9 @@
+DROP INDEX IF EXISTS idx_lesson_media_lesson;
+DROP INDEX IF EXISTS idx_lesson_media_storage_key;
+
+ALTER TABLE lesson_media DROP CONSTRAINT IF EXISTS lesson_media_upload_chk;
+
+ALTER TABLE lesson_media
+DROP COLUMN IF EXISTS size_bytes,
+DROP COLUMN IF EXISTS content_type,
+DROP COLUMN IF EXISTS storage_key;
@@ -0,0 +1,15 @@
+-- Миграция 19: загруженные медиафайлы уроков (аудио, видео, картинки, ноты)
+
+-- Для загруженных файлов uri = 'storage://<ключ>', для внешних ссылок — сам адрес
+ALTER TABLE lesson_media
+ADD COLUMN IF NOT EXISTS storage_key TEXT,
+ADD COLUMN IF NOT EXISTS content_type TEXT,
+ADD COLUMN IF NOT EXISTS size_bytes BIGINT;
+
+ALTER TABLE lesson_media
+ADD CONSTRAINT lesson_media_upload_chk
+    CHECK (storage_key IS NULL OR (content_type IS NOT NULL AND size_bytes >= 0));
+
+CREATE UNIQUE INDEX IF NOT EXISTS idx_lesson_media_storage_key
+    ON lesson_media (storage_key) WHERE storage_key IS NOT NULL;
+CREATE INDEX IF NOT EXISTS idx_lesson_media_lesson ON lesson_media (lesson_id, order_index);
@@ -0,0 +1,2 @@
+DROP TABLE IF EXISTS exercise_skills;
+DROP TABLE IF EXISTS skills;
@@ -0,0 +1,57 @@
+-- Миграция 20: таксономия навыков и связь упражнений с навыками
+
+CREATE TABLE IF NOT EXISTS skills (
+    id          SERIAL PRIMARY KEY,
+    slug        TEXT NOT NULL,
+    name        TEXT NOT NULL,
+    instrument  TEXT,
+    description TEXT,
+    created_at  TIMESTAMP NOT NULL DEFAULT NOW(),
+    updated_at  TIMESTAMP NOT NULL DEFAULT NOW(),
+    CONSTRAINT skills_instrument_chk CHECK (instrument IN ('guitar','piano'))
+);
+
+CREATE UNIQUE INDEX IF NOT EXISTS idx_skills_slug ON skills (slug);
+
+-- Упражнение может тренировать несколько навыков
+CREATE TABLE IF NOT EXISTS exercise_skills (
+    exercise_id INT NOT NULL REFERENCES exercises(id) ON DELETE CASCADE,
+    skill_id    INT NOT NULL REFERENCES skills(id) ON DELETE CASCADE,
+    PRIMARY KEY (exercise_id, skill_id)
+);
+
+CREATE INDEX IF NOT EXISTS idx_exercise_skills_skill ON exercise_skills (skill_id);
+
+INSERT INTO skills (slug, name, instrument, description) VALUES
+('open-chords', 'Открытые аккорды', 'guitar', 'Аккорды в первой позиции с открытыми струнами: Am, C, D, Em, G'),
+('barre-chords', 'Баррэ', 'guitar', 'Аккорды с зажатием нескольких струн одним пальцем: F, Bm'),
+('chord-changes', 'Смена аккордов', 'guitar', 'Ровная смена аккордов в ритме песни'),
+('note-reading', 'Ноты на клавиатуре', 'piano', 'Найти и сыграть ноту по названию'),
+('c-major-scale', 'Гамма до мажор', 'piano', 'Ноты гаммы до мажор первой октавы'),
+('piano-melodies', 'Мелодии', 'piano', 'Мелодия одной рукой в темпе')
+ON CONFLICT (slug) DO NOTHING;
+
+-- Навыки существующих упражнений по типу и ожидаемому значению
+INSERT INTO exercise_skills (exercise_id, skill_id)
+SELECT e.id, s.id
+FROM exercises e
+JOIN lessons l ON l.id = e.lesson_id
+JOIN skills s ON s.slug = CASE
+    WHEN l.instrument = 'guitar' AND e.type = 'chord' AND e.expected IN ('F', 'Bm', 'B', 'Bb', 'F#m', 'Cm', 'Gm')
+        THEN 'barre-chords'
+    WHEN l.instrument = 'guitar' AND e.type = 'chord' THEN 'open-chords'
+    WHEN l.instrument = 'guitar' AND e.type = 'sequence' THEN 'chord-changes'
+    WHEN l.instrument = 'piano' AND e.type = 'note' THEN 'note-reading'
+    WHEN l.instrument = 'piano' AND e.type = 'sequence' THEN 'piano-melodies'
+    END
+ON CONFLICT DO NOTHING;
+
+-- Гамма до мажор — белые клавиши первой октавы в уроках с гаммами
+INSERT INTO exercise_skills (exercise_id, skill_id)
+SELECT e.id, s.id
+FROM exercises e
+JOIN lessons l ON l.id = e.lesson_id
+JOIN skills s ON s.slug = 'c-major-scale'
+WHERE l.instrument = 'piano' AND e.type = 'note' AND e.expected ~ '^[A-G]4$'
+  AND l.slug IN ('piano-scales', 'scales-and-melodies')
+ON CONFLICT DO NOTHING;
@@ -0,0 +1,29 @@
+DROP FUNCTION IF EXISTS user_lesson_exercises(INT);
+
+ALTER TABLE exercise_attempts
+DROP CONSTRAINT IF EXISTS exercise_attempts_exercise_id_fkey,
+ADD CONSTRAINT exercise_attempts_exercise_id_fkey FOREIGN KEY (exercise_id) REFERENCES exercises(id) ON DELETE CASCADE;
+
+ALTER TABLE progress
+DROP CONSTRAINT IF EXISTS progress_exercise_id_fkey,
+ADD CONSTRAINT progress_exercise_id_fkey FOREIGN KEY (exercise_id) REFERENCES exercises(id) ON DELETE CASCADE;
+
+DROP TABLE IF EXISTS lesson_pins;
+ALTER TABLE lessons DROP COLUMN IF EXISTS published_version_id;
+DROP TABLE IF EXISTS lesson_version_mappings;
+DROP TABLE IF EXISTS lesson_version_exercises;
+DROP TABLE IF EXISTS lesson_versions;
+
+-- Выведенные из черновика упражнения остаются в истории, но снова становятся частью урока
+DROP INDEX IF EXISTS idx_exercises_lesson_slug;
+DELETE FROM exercises WHERE retired_at IS NOT NULL
+  AND NOT EXISTS (SELECT 1 FROM progress p WHERE p.exercise_id = exercises.id)
+  AND NOT EXISTS (SELECT 1 FROM exercise_attempts a WHERE a.exercise_id = exercises.id);
+UPDATE exercises SET slug = slug || '-' || id WHERE retired_at IS NOT NULL;
+CREATE UNIQUE INDEX IF NOT EXISTS idx_exercises_lesson_slug ON exercises (lesson_id, slug);
+ALTER TABLE exercises DROP COLUMN IF EXISTS retired_at, DROP COLUMN IF EXISTS replaces_id;
+
+UPDATE lessons SET status = 'draft' WHERE status = 'archived';
+ALTER TABLE lessons DROP CONSTRAINT IF EXISTS lessons_status_chk;
+ALTER TABLE lessons
+ADD CONSTRAINT lessons_status_chk CHECK (status IN ('draft','published'));
@@ -0,0 +1,103 @@
+-- Миграция 21: версии уроков — редактор правит черновик, публикация создаёт
+-- неизменяемую версию, ученик проходит ту версию, которую начал
+
+ALTER TABLE lessons DROP CONSTRAINT IF EXISTS lessons_status_chk;
+ALTER TABLE lessons
+ADD CONSTRAINT lessons_status_chk CHECK (status IN ('draft','published','archived'));
+
+-- Упражнение из опубликованной версии не меняется: правка создаёт новую строку
+-- (replaces_id — предыдущая), а старая выводится из черновика (retired_at)
+ALTER TABLE exercises
+ADD COLUMN IF NOT EXISTS replaces_id INT REFERENCES exercises(id) ON DELETE SET NULL,
+ADD COLUMN IF NOT EXISTS retired_at TIMESTAMP;
+
+-- slug уникален только среди упражнений черновика
+DROP INDEX IF EXISTS idx_exercises_lesson_slug;
+CREATE UNIQUE INDEX IF NOT EXISTS idx_exercises_lesson_slug ON exercises (lesson_id, slug) WHERE retired_at IS NULL;
+
+CREATE TABLE IF NOT EXISTS lesson_versions (
+    id           SERIAL PRIMARY KEY,
+    lesson_id    INT NOT NULL REFERENCES lessons(id) ON DELETE CASCADE,
+    version      INT NOT NULL,
+    note         TEXT,
+    published_by INT REFERENCES users(id) ON DELETE SET NULL,
+    published_at TIMESTAMP NOT NULL DEFAULT NOW(),
+    UNIQUE (lesson_id, version)
+);
+
+-- Состав и порядок упражнений версии. Упражнение версии удалить нельзя.
+CREATE TABLE IF NOT EXISTS lesson_version_exercises (
+    version_id  INT NOT NULL REFERENCES lesson_versions(id) ON DELETE CASCADE,
+    exercise_id INT NOT NULL REFERENCES exercises(id) ON DELETE RESTRICT,
+    order_index INT NOT NULL,
+    PRIMARY KEY (version_id, exercise_id)
+);
+
+CREATE INDEX IF NOT EXISTS idx_lesson_version_exercises_exercise ON lesson_version_exercises (exercise_id);
+
+-- Куда переносится прогресс с упражнения предыдущей версии при переходе на эту
+CREATE TABLE IF NOT EXISTS lesson_version_mappings (
+    version_id       INT NOT NULL REFERENCES lesson_versions(id) ON DELETE CASCADE,
+    from_exercise_id INT NOT NULL REFERENCES exercises(id) ON DELETE CASCADE,
+    to_exercise_id   INT NOT NULL REFERENCES exercises(id) ON DELETE CASCADE,
+    PRIMARY KEY (version_id, from_exercise_id)
+);
+
+ALTER TABLE lessons
+ADD COLUMN IF NOT EXISTS published_version_id INT REFERENCES lesson_versions(id) ON DELETE SET NULL;
+
+-- Версия урока, которую проходит ученик
+CREATE TABLE IF NOT EXISTS lesson_pins (
+    user_id    INT NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+    lesson_id  INT NOT NULL REFERENCES lessons(id) ON DELETE CASCADE,
+    version_id INT NOT NULL REFERENCES lesson_versions(id) ON DELETE CASCADE,
+    pinned_at  TIMESTAMP NOT NULL DEFAULT NOW(),
+    PRIMARY KEY (user_id, lesson_id)
+);
+
+-- Прогресс и история попыток больше не удаляются вместе с упражнением
+ALTER TABLE progress
+DROP CONSTRAINT IF EXISTS progress_exercise_id_fkey,
+ADD CONSTRAINT progress_exercise_id_fkey FOREIGN KEY (exercise_id) REFERENCES exercises(id) ON DELETE RESTRICT;
+
+ALTER TABLE exercise_attempts
+DROP CONSTRAINT IF EXISTS exercise_attempts_exercise_id_fkey,
+ADD CONSTRAINT exercise_attempts_exercise_id_fkey FOREIGN KEY (exercise_id) REFERENCES exercises(id) ON DELETE RESTRICT;
+
+-- Опубликованные уроки получают версию 1 из текущих упражнений
+INSERT INTO lesson_versions (lesson_id, version, note)
+SELECT l.id, 1, 'Исходная версия'
+FROM lessons l
+WHERE l.status = 'published'
+  AND NOT EXISTS (SELECT 1 FROM lesson_versions v WHERE v.lesson_id = l.id);
+
+INSERT INTO lesson_version_exercises (version_id, exercise_id, order_index)
+SELECT v.id, e.id, ROW_NUMBER() OVER (PARTITION BY v.id ORDER BY e.order_index, e.id)
+FROM lesson_versions v
+JOIN exercises e ON e.lesson_id = v.lesson_id
+WHERE v.version = 1
+ON CONFLICT DO NOTHING;
+
+UPDATE lessons l SET published_version_id = v.id
+FROM lesson_versions v
+WHERE v.lesson_id = l.id AND v.version = 1 AND l.published_version_id IS NULL;
+
+-- Ученики, которые уже занимались, закрепляются за версией 1
+INSERT INTO lesson_pins (user_id, lesson_id, version_id)
+SELECT DISTINCT p.user_id, l.id, l.published_version_id
+FROM progress p
+JOIN exercises e ON e.id = p.exercise_id
+JOIN lessons l ON l.id = e.lesson_id
+WHERE l.published_version_id IS NOT NULL
+ON CONFLICT DO NOTHING;
+
+-- Упражнения уроков в той версии, которую видит ученик: закреплённой или
+-- текущей опубликованной
+CREATE OR REPLACE FUNCTION user_lesson_exercises(p_user_id INT)
+RETURNS TABLE (lesson_id INT, version_id INT, exercise_id INT, order_index INT) AS $$
+    SELECT l.id, v.id, lve.exercise_id, lve.order_index
+    FROM lessons l
+    LEFT JOIN lesson_pins p ON p.lesson_id = l.id AND p.user_id = p_user_id
+    JOIN lesson_versions v ON v.id = COALESCE(p.version_id, l.published_version_id)
+    JOIN lesson_version_exercises lve ON lve.version_id = v.id
+$$ LANGUAGE sql STABLE;
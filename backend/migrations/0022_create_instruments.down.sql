@@ -0,0 +1,14 @@
+ALTER TABLE skills DROP CONSTRAINT IF EXISTS skills_instrument_fkey;
+UPDATE skills SET instrument = NULL WHERE instrument NOT IN ('guitar','piano');
+ALTER TABLE skills ADD CONSTRAINT skills_instrument_chk CHECK (instrument IN ('guitar','piano'));
+
+ALTER TABLE courses DROP CONSTRAINT IF EXISTS courses_instrument_fkey;
+ALTER TABLE lessons
+DROP CONSTRAINT IF EXISTS lessons_tuning_fkey,
+DROP CONSTRAINT IF EXISTS lessons_instrument_fkey,
+DROP CONSTRAINT IF EXISTS lessons_capo_chk,
+DROP COLUMN IF EXISTS capo,
+DROP COLUMN IF EXISTS tuning;
+
+DROP TABLE IF EXISTS instrument_tunings;
+DROP TABLE IF EXISTS instruments;
@@ -0,0 +1,62 @@
+-- Миграция 22: справочник инструментов и строев. Канонические данные (диапазоны,
+-- каподастр) — в пакете internal/instruments, таблицы нужны для внешних ключей
+
+CREATE TABLE IF NOT EXISTS instruments (
+    id       TEXT PRIMARY KEY,
+    name     TEXT NOT NULL,
+    kind     TEXT NOT NULL,
+    frets    INT,
+    max_capo INT NOT NULL DEFAULT 0,
+    CONSTRAINT instruments_kind_chk CHECK (kind IN ('strings','keys'))
+);
+
+-- Открытые струны строя — от нижней к верхней
+CREATE TABLE IF NOT EXISTS instrument_tunings (
+    instrument_id TEXT NOT NULL REFERENCES instruments(id) ON DELETE CASCADE,
+    id            TEXT NOT NULL,
+    name          TEXT NOT NULL,
+    strings       TEXT[] NOT NULL,
+    is_default    BOOLEAN NOT NULL DEFAULT FALSE,
+    PRIMARY KEY (instrument_id, id)
+);
+
+INSERT INTO instruments (id, name, kind, frets, max_capo) VALUES
+    ('guitar',  'Гитара',      'strings', 24,   9),
+    ('bass',    'Бас-гитара',  'strings', 24,   0),
+    ('ukulele', 'Укулеле',     'strings', 15,   7),
+    ('piano',   'Фортепиано',  'keys',    NULL, 0)
+ON CONFLICT (id) DO NOTHING;
+
+INSERT INTO instrument_tunings (instrument_id, id, name, strings, is_default) VALUES
+    ('guitar',  'standard', 'Стандартный строй', '{E2,A2,D3,G3,B3,E4}', TRUE),
+    ('guitar',  'drop-d',   'Drop D',            '{D2,A2,D3,G3,B3,E4}', FALSE),
+    ('bass',    'standard', 'Стандартный строй', '{E1,A1,D2,G2}',       TRUE),
+    ('ukulele', 'gcea',     'GCEA',              '{G4,C4,E4,A4}',       TRUE)
+ON CONFLICT (instrument_id, id) DO NOTHING;
+
+-- Строй и каподастр урока; NULL — строй инструмента по умолчанию
+ALTER TABLE lessons
+ADD COLUMN IF NOT EXISTS tuning TEXT,
+ADD COLUMN IF NOT EXISTS capo INT NOT NULL DEFAULT 0;
+
+ALTER TABLE lessons DROP CONSTRAINT IF EXISTS lessons_capo_chk;
+ALTER TABLE lessons ADD CONSTRAINT lessons_capo_chk CHECK (capo BETWEEN 0 AND 12);
+
+-- Инструмент был свободным текстом
+UPDATE lessons SET instrument = lower(trim(instrument)) WHERE instrument <> lower(trim(instrument));
+UPDATE courses SET instrument = lower(trim(instrument)) WHERE instrument <> lower(trim(instrument));
+
+ALTER TABLE lessons
+DROP CONSTRAINT IF EXISTS lessons_instrument_fkey,
+ADD CONSTRAINT lessons_instrument_fkey FOREIGN KEY (instrument) REFERENCES instruments(id),
+DROP CONSTRAINT IF EXISTS lessons_tuning_fkey,
+ADD CONSTRAINT lessons_tuning_fkey FOREIGN KEY (instrument, tuning) REFERENCES instrument_tunings(instrument_id, id);
+
+ALTER TABLE courses
+DROP CONSTRAINT IF EXISTS courses_instrument_fkey,
+ADD CONSTRAINT courses_instrument_fkey FOREIGN KEY (instrument) REFERENCES instruments(id);
+
+ALTER TABLE skills
+DROP CONSTRAINT IF EXISTS skills_instrument_chk,
+DROP CONSTRAINT IF EXISTS skills_instrument_fkey,
+ADD CONSTRAINT skills_instrument_fkey FOREIGN KEY (instrument) REFERENCES instruments(id);
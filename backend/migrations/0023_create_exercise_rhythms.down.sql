@@ -0,0 +1,2 @@
+DELETE FROM exercises WHERE type = 'rhythm';
+DROP TABLE IF EXISTS exercise_rhythms;
@@ -0,0 +1,21 @@
+-- Миграция 23: ритмические упражнения "rhythm" — рисунок ударов в заданном
+-- темпе и размере с окнами допуска по отклонению удара
+
+CREATE TABLE IF NOT EXISTS exercise_rhythms (
+    exercise_id     INT PRIMARY KEY REFERENCES exercises(id) ON DELETE CASCADE,
+    tempo           INT NOT NULL,                   -- удары в минуту
+    beats_per_bar   INT NOT NULL DEFAULT 4,
+    beat_unit       INT NOT NULL DEFAULT 4,
+    count_in        INT NOT NULL DEFAULT 1,         -- тактов метронома перед рисунком
+    CONSTRAINT exercise_rhythms_meter_chk
+        CHECK (tempo BETWEEN 20 AND 400 AND beats_per_bar > 0
+               AND beat_unit IN (1,2,4,8,16) AND count_in BETWEEN 0 AND 4),
+    -- [{"beats":1,"accent":true},{"beats":0.5},{"beats":1,"rest":true}, ...]
+    pattern         JSONB NOT NULL,
+    -- Окна допуска, мс
+    perfect_ms      INT NOT NULL DEFAULT 30,
+    good_ms         INT NOT NULL DEFAULT 80,
+    miss_ms         INT NOT NULL DEFAULT 150,
+    CONSTRAINT exercise_rhythms_windows_chk
+        CHECK (perfect_ms >= 0 AND perfect_ms <= good_ms AND good_ms <= miss_ms)
+);
@@ -0,0 +1,2 @@
+DROP TABLE IF EXISTS ear_training_answers;
+DROP TABLE IF EXISTS ear_training_sessions;
@@ -0,0 +1,35 @@
+-- Миграция 24: тренировка слуха. Задания сессии не хранятся — генератор
+-- восстанавливает их по виду, сложности, seed и версии генератора
+
+CREATE TABLE IF NOT EXISTS ear_training_sessions (
+    id                BIGSERIAL PRIMARY KEY,
+    user_id           INT NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+    kind              TEXT NOT NULL,
+    CONSTRAINT ear_training_sessions_kind_chk
+        CHECK (kind IN ('interval','chord_quality','scale_degree','dictation')),
+    difficulty        TEXT NOT NULL,
+    CONSTRAINT ear_training_sessions_difficulty_chk
+        CHECK (difficulty IN ('beginner','intermediate','advanced')),
+    seed              BIGINT NOT NULL,
+    generator_version INT NOT NULL,
+    question_count    INT NOT NULL,
+    CONSTRAINT ear_training_sessions_count_chk CHECK (question_count BETWEEN 1 AND 50),
+    score             DECIMAL(5,2),           -- средний балл, когда отвечены все задания
+    created_at        TIMESTAMP NOT NULL DEFAULT NOW(),
+    completed_at      TIMESTAMP
+);
+
+CREATE INDEX IF NOT EXISTS idx_ear_training_sessions_user
+ON ear_training_sessions (user_id, created_at DESC);
+
+-- Один ответ на задание
+CREATE TABLE IF NOT EXISTS ear_training_answers (
+    session_id     BIGINT NOT NULL REFERENCES ear_training_sessions(id) ON DELETE CASCADE,
+    question_index INT NOT NULL,
+    answer         TEXT NOT NULL,
+    expected       TEXT NOT NULL,
+    correct        BOOLEAN NOT NULL,
+    score          DECIMAL(5,2) NOT NULL,
+    created_at     TIMESTAMP NOT NULL DEFAULT NOW(),
+    PRIMARY KEY (session_id, question_index)
+);
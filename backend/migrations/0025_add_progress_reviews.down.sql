@@ -0,0 +1,8 @@
+DROP INDEX IF EXISTS idx_progress_due;
+
+ALTER TABLE progress
+DROP COLUMN IF EXISTS last_reviewed_at,
+DROP COLUMN IF EXISTS due_at,
+DROP COLUMN IF EXISTS repetitions,
+DROP COLUMN IF EXISTS interval_days,
+DROP COLUMN IF EXISTS ease;
@@ -0,0 +1,18 @@
+-- Миграция 25: расписание повторений (SM-2) в строке progress
+
+ALTER TABLE progress
+ADD COLUMN IF NOT EXISTS ease DECIMAL(4,2) NOT NULL DEFAULT 2.50,
+ADD COLUMN IF NOT EXISTS interval_days INT NOT NULL DEFAULT 0,
+ADD COLUMN IF NOT EXISTS repetitions INT NOT NULL DEFAULT 0,
+ADD COLUMN IF NOT EXISTS due_at TIMESTAMP,                -- NULL — упражнение не повторяется
+ADD COLUMN IF NOT EXISTS last_reviewed_at TIMESTAMP;
+
+-- Уже пройденные упражнения считаем выученными один раз: повторение на следующий день
+UPDATE progress
+SET repetitions = 1,
+    interval_days = 1,
+    due_at = date_trunc('day', COALESCE(completed_at, updated_at, NOW())) + INTERVAL '1 day',
+    last_reviewed_at = COALESCE(completed_at, updated_at)
+WHERE completed = TRUE AND due_at IS NULL;
+
+CREATE INDEX IF NOT EXISTS idx_progress_due ON progress(user_id, due_at) WHERE due_at IS NOT NULL;
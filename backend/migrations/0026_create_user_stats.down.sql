@@ -0,0 +1,11 @@
+DROP TABLE IF EXISTS user_stats;
+
+ALTER TABLE exercise_attempts
+DROP COLUMN IF EXISTS carried;
+
+ALTER TABLE users
+DROP CONSTRAINT IF EXISTS users_daily_goal_target_chk,
+DROP CONSTRAINT IF EXISTS users_daily_goal_kind_chk,
+DROP COLUMN IF EXISTS daily_goal_target,
+DROP COLUMN IF EXISTS daily_goal_kind,
+DROP COLUMN IF EXISTS timezone;
@@ -0,0 +1,34 @@
+-- Миграция 26: серии занятий, дневные цели и опыт
+
+ALTER TABLE users
+ADD COLUMN IF NOT EXISTS timezone TEXT NOT NULL DEFAULT 'UTC',  -- IANA, по нему считаются дни занятий
+ADD COLUMN IF NOT EXISTS daily_goal_kind TEXT NOT NULL DEFAULT 'minutes',
+ADD COLUMN IF NOT EXISTS daily_goal_target INT NOT NULL DEFAULT 10;
+
+ALTER TABLE users
+ADD CONSTRAINT users_daily_goal_kind_chk CHECK (daily_goal_kind IN ('minutes','exercises')),
+ADD CONSTRAINT users_daily_goal_target_chk CHECK (daily_goal_target BETWEEN 1 AND 1440);
+
+-- Копии попыток, перенесённые при переходе на новую версию урока: в сериях и опыте не учитываются
+ALTER TABLE exercise_attempts
+ADD COLUMN IF NOT EXISTS carried BOOLEAN NOT NULL DEFAULT FALSE;
+
+-- Уже перенесённые копии узнаём по исходной попытке того же ученика с тем же временем
+UPDATE exercise_attempts c SET carried = TRUE
+WHERE EXISTS (
+    SELECT 1 FROM exercise_attempts o
+    WHERE o.user_id = c.user_id AND o.created_at = c.created_at
+      AND o.exercise_id <> c.exercise_id AND o.id < c.id
+      AND o.status = c.status AND o.score = c.score
+);
+
+-- Результат последнего пересчёта (пакет stats); источник правды — exercise_attempts
+CREATE TABLE IF NOT EXISTS user_stats (
+    user_id             INT PRIMARY KEY REFERENCES users(id) ON DELETE CASCADE,
+    xp                  INT NOT NULL DEFAULT 0,
+    level               INT NOT NULL DEFAULT 1,
+    current_streak      INT NOT NULL DEFAULT 0,
+    longest_streak      INT NOT NULL DEFAULT 0,
+    completed_exercises INT NOT NULL DEFAULT 0,
+    updated_at          TIMESTAMP NOT NULL DEFAULT NOW()
+);
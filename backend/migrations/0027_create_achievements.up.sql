@@ -0,0 +1,39 @@
+-- Миграция 27: значки за достижения. Условия — данные (rule), их проверяет пакет achievements
+
+CREATE TABLE IF NOT EXISTS achievements (
+    id            SERIAL PRIMARY KEY,
+    slug          TEXT NOT NULL UNIQUE,
+    title         TEXT NOT NULL,
+    description   TEXT NOT NULL,
+    icon          TEXT,
+    rule          JSONB NOT NULL,        -- {"kind": "exercises", "exercise_type": "chord", "count": 1}
+    order_index   INT NOT NULL DEFAULT 0,
+    backfilled_at TIMESTAMP,             -- когда значок выдан всем, кто заслужил его раньше
+    created_at    TIMESTAMP NOT NULL DEFAULT NOW()
+);
+
+CREATE TABLE IF NOT EXISTS user_achievements (
+    user_id        INT NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+    achievement_id INT NOT NULL REFERENCES achievements(id) ON DELETE CASCADE,
+    awarded_at     TIMESTAMP NOT NULL,   -- когда выполнено условие
+    PRIMARY KEY (user_id, achievement_id)
+);
+
+INSERT INTO achievements (slug, title, description, icon, rule, order_index) VALUES
+('first-chord', 'Первый аккорд', 'Сыграйте свой первый аккорд', '🎸',
+    '{"kind": "exercises", "exercise_type": "chord", "count": 1}', 1),
+('first-note', 'Первая нота', 'Сыграйте свою первую ноту', '🎹',
+    '{"kind": "exercises", "exercise_type": "note", "count": 1}', 2),
+('ten-exercises', 'Десять упражнений', 'Пройдите десять упражнений', '🎯',
+    '{"kind": "exercises", "count": 10}', 3),
+('c-major-notes', 'Гамма до мажор', 'Сыграйте все ноты гаммы до мажор', '🎼',
+    '{"kind": "scale_notes", "scale": "C"}', 4),
+('streak-7', 'Неделя без перерыва', 'Занимайтесь 7 дней подряд', '🔥',
+    '{"kind": "streak", "days": 7}', 5),
+('streak-30', 'Месяц без перерыва', 'Занимайтесь 30 дней подряд', '🏆',
+    '{"kind": "streak", "days": 30}', 6),
+('guitar-basics', 'Основы гитары', 'Пройдите урок «Основы гитары»', '🎓',
+    '{"kind": "lesson", "lesson": "guitar-basics"}', 7),
+('xp-1000', 'Тысяча очков опыта', 'Наберите 1000 очков опыта', '⭐',
+    '{"kind": "xp", "xp": 1000}', 8)
+ON CONFLICT (slug) DO NOTHING;
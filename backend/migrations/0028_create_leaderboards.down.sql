@@ -0,0 +1,6 @@
+DROP INDEX IF EXISTS idx_exercise_attempts_passed;
+DROP TABLE IF EXISTS user_group_members;
+DROP TABLE IF EXISTS user_groups;
+
+ALTER TABLE users
+DROP COLUMN IF EXISTS leaderboard_opt_out;
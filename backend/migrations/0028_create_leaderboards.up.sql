@@ -0,0 +1,33 @@
+-- Миграция 28: рейтинги — отказ от участия, группы друзей и семейные группы
+
+ALTER TABLE users
+ADD COLUMN IF NOT EXISTS leaderboard_opt_out BOOLEAN NOT NULL DEFAULT FALSE;
+
+CREATE TABLE IF NOT EXISTS user_groups (
+    id              SERIAL PRIMARY KEY,
+    kind            TEXT NOT NULL,
+    CONSTRAINT user_groups_kind_chk CHECK (kind IN ('friends','family')),
+    name            TEXT NOT NULL,
+    owner_id        INT NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+    subscription_id BIGINT REFERENCES subscriptions(id) ON DELETE CASCADE,  -- для семейной группы
+    CONSTRAINT user_groups_family_chk CHECK ((kind = 'family') = (subscription_id IS NOT NULL)),
+    invite_code     TEXT NOT NULL UNIQUE,
+    created_at      TIMESTAMP NOT NULL DEFAULT NOW()
+);
+
+-- Одна семейная группа на подписку
+CREATE UNIQUE INDEX IF NOT EXISTS uq_user_groups_subscription ON user_groups (subscription_id)
+WHERE subscription_id IS NOT NULL;
+
+CREATE TABLE IF NOT EXISTS user_group_members (
+    group_id  INT NOT NULL REFERENCES user_groups(id) ON DELETE CASCADE,
+    user_id   INT NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+    joined_at TIMESTAMP NOT NULL DEFAULT NOW(),
+    PRIMARY KEY (group_id, user_id)
+);
+
+CREATE INDEX IF NOT EXISTS idx_user_group_members_user ON user_group_members (user_id);
+
+-- Первые прохождения упражнений для подсчёта очков
+CREATE INDEX IF NOT EXISTS idx_exercise_attempts_passed
+ON exercise_attempts (user_id, exercise_id, created_at) WHERE passed;
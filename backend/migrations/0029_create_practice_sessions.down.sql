@@ -0,0 +1,2 @@
+DROP TABLE IF EXISTS practice_session_exercises;
+DROP TABLE IF EXISTS practice_sessions;
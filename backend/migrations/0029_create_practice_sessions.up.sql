@@ -0,0 +1,28 @@
+-- Миграция 29: сессии занятий — активное время по сигналам клиента
+
+CREATE TABLE IF NOT EXISTS practice_sessions (
+    id                BIGSERIAL PRIMARY KEY,
+    user_id           INT NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+    lesson_id         INT REFERENCES lessons(id) ON DELETE SET NULL,
+    exercise_id       INT REFERENCES exercises(id) ON DELETE SET NULL,  -- текущее упражнение
+    started_at        TIMESTAMP NOT NULL DEFAULT NOW(),
+    last_heartbeat_at TIMESTAMP NOT NULL DEFAULT NOW(),
+    ended_at          TIMESTAMP,
+    end_reason        TEXT,
+    CONSTRAINT practice_sessions_end_reason_chk CHECK (end_reason IN ('user','idle','replaced')),
+    active_seconds    INT NOT NULL DEFAULT 0
+);
+
+-- У ученика одна открытая сессия
+CREATE UNIQUE INDEX IF NOT EXISTS uq_practice_sessions_open ON practice_sessions (user_id)
+WHERE ended_at IS NULL;
+
+CREATE INDEX IF NOT EXISTS idx_practice_sessions_user ON practice_sessions (user_id, started_at DESC);
+
+-- Активное время сессии по упражнениям
+CREATE TABLE IF NOT EXISTS practice_session_exercises (
+    session_id     BIGINT NOT NULL REFERENCES practice_sessions(id) ON DELETE CASCADE,
+    exercise_id    INT NOT NULL REFERENCES exercises(id) ON DELETE CASCADE,
+    active_seconds INT NOT NULL DEFAULT 0,
+    PRIMARY KEY (session_id, exercise_id)
+);
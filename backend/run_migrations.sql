@@ -171,3 +171,897 @@ INSERT INTO exercises (lesson_id, title, expected, type, order_index) VALUES
 -- Простые мелодии
 (8, 'Мелодия "Twinkle Twinkle" - C4', 'C4', 'note', 9),
 (8, 'Мелодия "Twinkle Twinkle" - G4', 'G4', 'note', 10);
+
+
+-- Миграция 10: управление контентом через админ-API
+
+-- Флаг администратора (редактора контента)
+ALTER TABLE users
+ADD COLUMN IF NOT EXISTS is_admin BOOLEAN NOT NULL DEFAULT FALSE;
+
+-- Черновики и публикация уроков. Уже существующие уроки считаем опубликованными.
+ALTER TABLE lessons
+ADD COLUMN IF NOT EXISTS status TEXT NOT NULL DEFAULT 'published',
+ADD COLUMN IF NOT EXISTS updated_at TIMESTAMP NOT NULL DEFAULT NOW();
+
+ALTER TABLE lessons
+ADD CONSTRAINT lessons_status_chk CHECK (status IN ('draft','published'));
+
+ALTER TABLE exercises
+ADD COLUMN IF NOT EXISTS updated_at TIMESTAMP NOT NULL DEFAULT NOW();
+
+CREATE INDEX IF NOT EXISTS idx_lessons_status ON lessons (status);
+CREATE INDEX IF NOT EXISTS idx_exercises_lesson_order ON exercises (lesson_id, order_index);
+
+
+-- Миграция 11: песни и мелодии как отдельный тип упражнения "sequence"
+
+CREATE TABLE IF NOT EXISTS exercise_sequences (
+    exercise_id     INT PRIMARY KEY REFERENCES exercises(id) ON DELETE CASCADE,
+    song_title      TEXT NOT NULL,
+    step_type       TEXT NOT NULL,
+    CONSTRAINT exercise_sequences_step_type_chk
+        CHECK (step_type IN ('chord','note')),
+    tempo           INT NOT NULL DEFAULT 90,        -- удары в минуту
+    beats_per_bar   INT NOT NULL DEFAULT 4,         -- размер: числитель
+    beat_unit       INT NOT NULL DEFAULT 4,         -- размер: знаменатель
+    CONSTRAINT exercise_sequences_meter_chk
+        CHECK (tempo > 0 AND beats_per_bar > 0 AND beat_unit IN (1,2,4,8,16)),
+    -- [{"symbol":"Am","beats":3,"lyric":"Hap-py"}, ...]
+    steps           JSONB NOT NULL
+);
+
+-- Переносим песни, которые раньше были разбиты на упражнения "по аккорду",
+-- в одно упражнение-последовательность. Прогресс по старым строкам переносится:
+-- упражнение засчитывается, если были пройдены все его части.
+CREATE TEMP TABLE song_migration (
+    lesson_title  TEXT,
+    title_prefix  TEXT,
+    new_title     TEXT,
+    song_title    TEXT,
+    step_type     TEXT,
+    tempo         INT,
+    beats_per_bar INT,
+    steps         JSONB
+);
+
+INSERT INTO song_migration VALUES
+('Первые песни на гитаре', 'Песня "Happy Birthday"', 'Песня "Happy Birthday"', 'Happy Birthday', 'chord', 90, 3,
+ '[{"symbol":"Am","beats":3,"lyric":"Hap-py birth-day"},{"symbol":"C","beats":3,"lyric":"to you"},
+   {"symbol":"G","beats":3,"lyric":"Hap-py birth-day"},{"symbol":"F","beats":3,"lyric":"to you"},
+   {"symbol":"Am","beats":3,"lyric":"Hap-py birth-day"},{"symbol":"C","beats":3,"lyric":"dear friend"},
+   {"symbol":"G","beats":3,"lyric":"Hap-py birth-day"},{"symbol":"F","beats":3,"lyric":"to you"}]'),
+('Простые мелодии на пианино', 'Мелодия "До-ре-ми"', 'Мелодия "До-ре-ми"', 'До-ре-ми', 'note', 80, 4,
+ '[{"symbol":"C4","beats":1,"lyric":"до"},{"symbol":"D4","beats":1,"lyric":"ре"},
+   {"symbol":"E4","beats":1,"lyric":"ми"},{"symbol":"F4","beats":1,"lyric":"фа"},
+   {"symbol":"G4","beats":1,"lyric":"соль"},{"symbol":"A4","beats":1,"lyric":"ля"},
+   {"symbol":"B4","beats":1,"lyric":"си"},{"symbol":"C5","beats":1,"lyric":"до"}]'),
+('Гаммы и мелодии', 'Мелодия "Twinkle Twinkle"', 'Мелодия "Twinkle Twinkle"', 'Twinkle Twinkle Little Star', 'note', 100, 4,
+ '[{"symbol":"C4","beats":1,"lyric":"Twin-"},{"symbol":"C4","beats":1,"lyric":"kle"},
+   {"symbol":"G4","beats":1,"lyric":"twin-"},{"symbol":"G4","beats":1,"lyric":"kle"},
+   {"symbol":"A4","beats":1,"lyric":"lit-"},{"symbol":"A4","beats":1,"lyric":"tle"},
+   {"symbol":"G4","beats":2,"lyric":"star"}]');
+
+CREATE TEMP TABLE song_exercises AS
+SELECT m.*, l.id AS lesson_id,
+       MIN(e.order_index) AS order_index,
+       ARRAY_AGG(e.id) AS old_ids
+FROM song_migration m
+JOIN lessons l ON l.title = m.lesson_title
+JOIN exercises e ON e.lesson_id = l.id AND e.title LIKE m.title_prefix || '%'
+GROUP BY m.lesson_title, m.title_prefix, m.new_title, m.song_title, m.step_type,
+         m.tempo, m.beats_per_bar, m.steps, l.id;
+
+ALTER TABLE song_exercises ADD COLUMN new_id INT;
+
+UPDATE song_exercises s
+SET new_id = nextval(pg_get_serial_sequence('exercises', 'id'));
+
+INSERT INTO exercises (id, lesson_id, title, expected, type, order_index)
+SELECT s.new_id, s.lesson_id, s.new_title,
+       (SELECT string_agg(step->>'symbol', ' ') FROM jsonb_array_elements(s.steps) step),
+       'sequence', s.order_index
+FROM song_exercises s;
+
+INSERT INTO exercise_sequences (exercise_id, song_title, step_type, tempo, beats_per_bar, beat_unit, steps)
+SELECT s.new_id, s.song_title, s.step_type, s.tempo, s.beats_per_bar, 4, s.steps
+FROM song_exercises s;
+
+INSERT INTO progress (user_id, exercise_id, completed, attempts, best_score, completed_at, created_at, updated_at)
+SELECT p.user_id, s.new_id,
+       BOOL_AND(p.completed) AND COUNT(*) = CARDINALITY(s.old_ids),
+       SUM(p.attempts), MAX(p.best_score),
+       CASE WHEN BOOL_AND(p.completed) AND COUNT(*) = CARDINALITY(s.old_ids) THEN MAX(p.completed_at) END,
+       MIN(p.created_at), MAX(p.updated_at)
+FROM song_exercises s
+JOIN progress p ON p.exercise_id = ANY(s.old_ids)
+GROUP BY p.user_id, s.new_id, s.old_ids;
+
+DELETE FROM exercises e
+USING song_exercises s
+WHERE e.id = ANY(s.old_ids);
+
+DROP TABLE song_exercises;
+DROP TABLE song_migration;
+
+
+-- Миграция 12: статус прогресса и оценки попыток, посчитанные сервером
+
+-- UpdateProgressHandler уже пишет status, но колонки в таблице не было
+ALTER TABLE progress
+ADD COLUMN IF NOT EXISTS status TEXT NOT NULL DEFAULT 'in_progress',
+ADD COLUMN IF NOT EXISTS last_score DECIMAL(5,2),
+ADD COLUMN IF NOT EXISTS scored_by_server BOOLEAN NOT NULL DEFAULT FALSE;
+
+UPDATE progress SET status = 'done' WHERE completed = TRUE;
+
+
+-- Миграция 13: история попыток; progress становится сводкой по этой таблице
+
+CREATE TABLE IF NOT EXISTS exercise_attempts (
+    id                  BIGSERIAL PRIMARY KEY,
+    user_id             INT NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+    exercise_id         INT NOT NULL REFERENCES exercises(id) ON DELETE CASCADE,
+
+    source              TEXT NOT NULL,      -- кто поставил оценку
+    CONSTRAINT exercise_attempts_source_chk
+        CHECK (source IN ('server','client','legacy')),
+
+    status              TEXT NOT NULL,
+    passed              BOOLEAN NOT NULL DEFAULT FALSE,
+    score               DECIMAL(5,2) NOT NULL DEFAULT 0.00,
+    detected            JSONB,              -- распознанные ноты/аккорды и подробности анализа
+    timing_deviation_ms INT,                -- среднее отклонение от ритма (если применимо)
+    duration_ms         INT,                -- длительность записи
+
+    created_at          TIMESTAMP NOT NULL DEFAULT NOW()
+);
+
+CREATE INDEX IF NOT EXISTS idx_exercise_attempts_user_exercise
+ON exercise_attempts (user_id, exercise_id, created_at DESC);
+
+-- Переносим существующий прогресс в историю, чтобы пересчёт сводки ничего не потерял:
+-- на каждую запись progress создаём attempts попыток, последняя несёт best_score и статус.
+INSERT INTO exercise_attempts (user_id, exercise_id, source, status, passed, score, created_at)
+SELECT p.user_id, p.exercise_id, 'legacy',
+       CASE WHEN n = GREATEST(p.attempts, 1) THEN p.status ELSE 'failed' END,
+       n = GREATEST(p.attempts, 1) AND COALESCE(p.completed, FALSE),
+       CASE WHEN n = GREATEST(p.attempts, 1) THEN COALESCE(p.best_score, 0) ELSE 0 END,
+       CASE WHEN n = GREATEST(p.attempts, 1)
+            THEN COALESCE(p.completed_at, p.updated_at, p.created_at, NOW())
+            ELSE COALESCE(p.created_at, NOW()) END
+FROM progress p
+CROSS JOIN LATERAL generate_series(1, GREATEST(p.attempts, 1)) AS n;
+
+
+-- Миграция 14: статусы попыток и прогресса — проверяемое перечисление
+
+-- Раньше клиент мог прислать любой статус, приводим старые значения к перечислению
+UPDATE progress
+SET status = CASE WHEN completed THEN 'done' ELSE 'in_progress' END
+WHERE status NOT IN ('in_progress','done','failed')
+   OR (completed AND status <> 'done');
+
+UPDATE exercise_attempts
+SET status = CASE WHEN passed THEN 'done' ELSE 'failed' END
+WHERE status NOT IN ('in_progress','done','failed')
+   OR (passed AND status <> 'done');
+
+ALTER TABLE progress
+ADD CONSTRAINT progress_status_chk CHECK (status IN ('in_progress','done','failed'));
+
+ALTER TABLE exercise_attempts
+ADD CONSTRAINT exercise_attempts_status_chk CHECK (status IN ('in_progress','done','failed'));
+
+
+-- Миграция 15: каталог уроков — фильтры, полнотекстовый поиск и курсорная пагинация
+
+ALTER TABLE lessons
+ADD COLUMN IF NOT EXISTS difficulty TEXT NOT NULL DEFAULT 'beginner',
+ADD COLUMN IF NOT EXISTS tier TEXT NOT NULL DEFAULT 'free',
+ADD COLUMN IF NOT EXISTS locale VARCHAR(10) NOT NULL DEFAULT 'ru',
+ADD COLUMN IF NOT EXISTS tags TEXT[] NOT NULL DEFAULT '{}';
+
+ALTER TABLE lessons
+ADD CONSTRAINT lessons_difficulty_chk CHECK (difficulty IN ('beginner','intermediate','advanced')),
+ADD CONSTRAINT lessons_tier_chk CHECK (tier IN ('free','premium'));
+
+-- Курсор строится по (created_at, id), поэтому created_at не может быть NULL
+UPDATE lessons SET created_at = NOW() WHERE created_at IS NULL;
+ALTER TABLE lessons ALTER COLUMN created_at SET NOT NULL;
+
+-- Поиск по названию (вес A) и описанию (вес B) сразу в русской и английской конфигурациях
+ALTER TABLE lessons
+ADD COLUMN IF NOT EXISTS search_vector tsvector GENERATED ALWAYS AS (
+    setweight(to_tsvector('russian', coalesce(title, '')), 'A') ||
+    setweight(to_tsvector('english', coalesce(title, '')), 'A') ||
+    setweight(to_tsvector('russian', coalesce(description, '')), 'B') ||
+    setweight(to_tsvector('english', coalesce(description, '')), 'B')
+) STORED;
+
+CREATE INDEX IF NOT EXISTS idx_lessons_search ON lessons USING GIN (search_vector);
+CREATE INDEX IF NOT EXISTS idx_lessons_tags ON lessons USING GIN (tags);
+CREATE INDEX IF NOT EXISTS idx_lessons_catalog ON lessons (status, created_at, id);
+
+-- Теги для существующих уроков по типам упражнений
+UPDATE lessons l
+SET tags = ARRAY(
+    SELECT DISTINCT CASE e.type
+        WHEN 'chord' THEN 'chords'
+        WHEN 'note' THEN 'notes'
+        WHEN 'sequence' THEN 'songs'
+    END
+    FROM exercises e
+    WHERE e.lesson_id = l.id AND e.type IN ('chord','note','sequence')
+    ORDER BY 1
+);
+
+UPDATE lessons SET difficulty = 'intermediate'
+WHERE title IN ('Популярные аккорды', 'Гаммы и мелодии');
+
+
+-- Миграция 16: курсы — упорядоченные модули из уроков и пререквизиты уроков
+
+CREATE TABLE IF NOT EXISTS courses (
+    id          SERIAL PRIMARY KEY,
+    title       TEXT NOT NULL,
+    instrument  TEXT NOT NULL,
+    description TEXT,
+    status      TEXT NOT NULL DEFAULT 'published',
+    CONSTRAINT courses_status_chk CHECK (status IN ('draft','published')),
+    created_at  TIMESTAMP NOT NULL DEFAULT NOW(),
+    updated_at  TIMESTAMP NOT NULL DEFAULT NOW()
+);
+
+CREATE TABLE IF NOT EXISTS course_modules (
+    id          SERIAL PRIMARY KEY,
+    course_id   INT NOT NULL REFERENCES courses(id) ON DELETE CASCADE,
+    title       TEXT NOT NULL,
+    order_index INT NOT NULL
+);
+
+CREATE INDEX IF NOT EXISTS idx_course_modules_course ON course_modules (course_id, order_index);
+
+-- Урок может входить в несколько курсов, но в один модуль — один раз
+CREATE TABLE IF NOT EXISTS module_lessons (
+    module_id   INT NOT NULL REFERENCES course_modules(id) ON DELETE CASCADE,
+    lesson_id   INT NOT NULL REFERENCES lessons(id) ON DELETE CASCADE,
+    order_index INT NOT NULL,
+    PRIMARY KEY (module_id, lesson_id)
+);
+
+CREATE INDEX IF NOT EXISTS idx_module_lessons_lesson ON module_lessons (lesson_id);
+
+-- Урок lesson_id открывается, когда пройдено min_percent% урока required_lesson_id
+CREATE TABLE IF NOT EXISTS lesson_prerequisites (
+    lesson_id          INT NOT NULL REFERENCES lessons(id) ON DELETE CASCADE,
+    required_lesson_id INT NOT NULL REFERENCES lessons(id) ON DELETE CASCADE,
+    min_percent        INT NOT NULL DEFAULT 80,
+    PRIMARY KEY (lesson_id, required_lesson_id),
+    CONSTRAINT lesson_prerequisites_self_chk CHECK (lesson_id <> required_lesson_id),
+    CONSTRAINT lesson_prerequisites_percent_chk CHECK (min_percent BETWEEN 1 AND 100)
+);
+
+CREATE INDEX IF NOT EXISTS idx_lesson_prerequisites_required ON lesson_prerequisites (required_lesson_id);
+
+-- Курсы из существующих уроков. Уроки ищем по названию: ID в разных базах отличаются.
+CREATE TEMP TABLE course_seed (
+    course      TEXT,
+    instrument  TEXT,
+    module      TEXT,
+    module_pos  INT,
+    lesson      TEXT,
+    lesson_pos  INT
+);
+
+INSERT INTO course_seed VALUES
+('Гитара с нуля', 'guitar', 'Первые аккорды', 1, 'Основы гитары', 1),
+('Гитара с нуля', 'guitar', 'Первые аккорды', 1, 'Простые аккорды', 2),
+('Гитара с нуля', 'guitar', 'Песни', 2, 'Популярные аккорды', 1),
+('Гитара с нуля', 'guitar', 'Песни', 2, 'Первые песни на гитаре', 2),
+('Пианино с нуля', 'piano', 'Ноты и гаммы', 1, 'Основы пианино', 1),
+('Пианино с нуля', 'piano', 'Ноты и гаммы', 1, 'Гаммы на пианино', 2),
+('Пианино с нуля', 'piano', 'Мелодии', 2, 'Простые мелодии на пианино', 1),
+('Пианино с нуля', 'piano', 'Мелодии', 2, 'Гаммы и мелодии', 2);
+
+INSERT INTO courses (title, instrument, description)
+SELECT DISTINCT s.course, s.instrument,
+       CASE s.instrument WHEN 'guitar' THEN 'От первых аккордов до песен'
+                         ELSE 'От первых нот до мелодий двумя руками' END
+FROM course_seed s
+WHERE EXISTS (SELECT 1 FROM lessons l WHERE l.title = s.lesson)
+  AND NOT EXISTS (SELECT 1 FROM courses c WHERE c.title = s.course);
+
+INSERT INTO course_modules (course_id, title, order_index)
+SELECT DISTINCT c.id, s.module, s.module_pos
+FROM course_seed s
+JOIN courses c ON c.title = s.course
+WHERE NOT EXISTS (SELECT 1 FROM course_modules m WHERE m.course_id = c.id AND m.title = s.module);
+
+INSERT INTO module_lessons (module_id, lesson_id, order_index)
+SELECT m.id, l.id, s.lesson_pos
+FROM course_seed s
+JOIN courses c ON c.title = s.course
+JOIN course_modules m ON m.course_id = c.id AND m.title = s.module
+JOIN lessons l ON l.title = s.lesson
+ON CONFLICT DO NOTHING;
+
+-- Каждый следующий урок курса открывается после 80% предыдущего
+INSERT INTO lesson_prerequisites (lesson_id, required_lesson_id, min_percent)
+SELECT cur.lesson_id, prev.lesson_id, 80
+FROM (
+    SELECT c.id AS course_id, ml.lesson_id,
+           ROW_NUMBER() OVER (PARTITION BY c.id ORDER BY m.order_index, ml.order_index) AS pos
+    FROM courses c
+    JOIN course_modules m ON m.course_id = c.id
+    JOIN module_lessons ml ON ml.module_id = m.id
+) cur
+JOIN (
+    SELECT c.id AS course_id, ml.lesson_id,
+           ROW_NUMBER() OVER (PARTITION BY c.id ORDER BY m.order_index, ml.order_index) AS pos
+    FROM courses c
+    JOIN course_modules m ON m.course_id = c.id
+    JOIN module_lessons ml ON ml.module_id = m.id
+) prev ON prev.course_id = cur.course_id AND prev.pos = cur.pos - 1
+ON CONFLICT DO NOTHING;
+
+DROP TABLE course_seed;
+
+
+-- Миграция 17: переводы уроков и упражнений.
+-- Исходный текст остаётся в lessons/exercises на языке lessons.locale,
+-- здесь хранятся только переводы на другие языки.
+
+CREATE TABLE IF NOT EXISTS lesson_translations (
+    lesson_id   INT NOT NULL REFERENCES lessons(id) ON DELETE CASCADE,
+    locale      VARCHAR(10) NOT NULL,
+    title       TEXT,
+    description TEXT,
+    updated_at  TIMESTAMP NOT NULL DEFAULT NOW(),
+    PRIMARY KEY (lesson_id, locale)
+);
+
+CREATE TABLE IF NOT EXISTS exercise_translations (
+    exercise_id INT NOT NULL REFERENCES exercises(id) ON DELETE CASCADE,
+    locale      VARCHAR(10) NOT NULL,
+    title       TEXT NOT NULL,
+    updated_at  TIMESTAMP NOT NULL DEFAULT NOW(),
+    PRIMARY KEY (exercise_id, locale)
+);
+
+
+-- Миграция 18: стабильные slug для импорта/экспорта контента и медиа уроков
+
+ALTER TABLE courses ADD COLUMN IF NOT EXISTS slug TEXT;
+ALTER TABLE lessons ADD COLUMN IF NOT EXISTS slug TEXT;
+ALTER TABLE exercises ADD COLUMN IF NOT EXISTS slug TEXT;
+
+UPDATE courses SET slug = CASE title
+    WHEN 'Гитара с нуля' THEN 'guitar-from-scratch'
+    WHEN 'Пианино с нуля' THEN 'piano-from-scratch'
+    ELSE 'course-' || id END
+WHERE slug IS NULL;
+
+UPDATE lessons SET slug = CASE title
+    WHEN 'Основы гитары' THEN 'guitar-basics'
+    WHEN 'Основы пианино' THEN 'piano-basics'
+    WHEN 'Простые аккорды' THEN 'simple-chords'
+    WHEN 'Гаммы на пианино' THEN 'piano-scales'
+    WHEN 'Первые песни на гитаре' THEN 'first-guitar-songs'
+    WHEN 'Простые мелодии на пианино' THEN 'simple-piano-melodies'
+    WHEN 'Популярные аккорды' THEN 'popular-chords'
+    WHEN 'Гаммы и мелодии' THEN 'scales-and-melodies'
+    ELSE 'lesson-' || id END
+WHERE slug IS NULL;
+
+-- Дубликаты названий (если уроки создавались вручную) получают суффикс с ID
+UPDATE lessons l SET slug = l.slug || '-' || l.id
+WHERE EXISTS (SELECT 1 FROM lessons o WHERE o.slug = l.slug AND o.id < l.id);
+
+-- Упражнения: slug из ожидаемого значения ("chord-am", "note-c4"), песни — "song-N"
+UPDATE exercises e
+SET slug = b.base || CASE WHEN b.n > 1 OR b.base = 'song' THEN '-' || b.n ELSE '' END
+FROM (
+    SELECT x.id, x.base,
+           ROW_NUMBER() OVER (PARTITION BY x.lesson_id, x.base ORDER BY x.order_index, x.id) AS n
+    FROM (
+        SELECT id, lesson_id, order_index,
+               CASE WHEN type = 'sequence' THEN 'song'
+                    ELSE type || '-' || COALESCE(NULLIF(
+                        trim(both '-' FROM lower(regexp_replace(expected, '[^A-Za-z0-9]+', '-', 'g'))), ''), 'x')
+               END AS base
+        FROM exercises
+    ) x
+) b
+WHERE e.id = b.id AND e.slug IS NULL;
+
+ALTER TABLE courses ALTER COLUMN slug SET NOT NULL;
+ALTER TABLE lessons ALTER COLUMN slug SET NOT NULL;
+ALTER TABLE exercises ALTER COLUMN slug SET NOT NULL;
+
+CREATE UNIQUE INDEX IF NOT EXISTS idx_courses_slug ON courses (slug);
+CREATE UNIQUE INDEX IF NOT EXISTS idx_lessons_slug ON lessons (slug);
+CREATE UNIQUE INDEX IF NOT EXISTS idx_exercises_lesson_slug ON exercises (lesson_id, slug);
+
+-- Медиа урока: ссылки на аудио, видео, ноты и картинки
+CREATE TABLE IF NOT EXISTS lesson_media (
+    id          SERIAL PRIMARY KEY,
+    lesson_id   INT NOT NULL REFERENCES lessons(id) ON DELETE CASCADE,
+    kind        TEXT NOT NULL,
+    CONSTRAINT lesson_media_kind_chk CHECK (kind IN ('audio','video','image','score','other')),
+    uri         TEXT NOT NULL,
+    title       TEXT,
+    order_index INT NOT NULL DEFAULT 0,
+    created_at  TIMESTAMP NOT NULL DEFAULT NOW(),
+    UNIQUE (lesson_id, uri)
+);
+
+-- Импорт сопоставляет модули курса по позиции
+CREATE UNIQUE INDEX IF NOT EXISTS idx_course_modules_position ON course_modules (course_id, order_index);
+
+
+-- Миграция 19: загруженные медиафайлы уроков (аудио, видео, картинки, ноты)
+
+-- Для загруженных файлов uri = 'storage://<ключ>', для внешних ссылок — сам адрес
+ALTER TABLE lesson_media
+ADD COLUMN IF NOT EXISTS storage_key TEXT,
+ADD COLUMN IF NOT EXISTS content_type TEXT,
+ADD COLUMN IF NOT EXISTS size_bytes BIGINT;
+
+ALTER TABLE lesson_media
+ADD CONSTRAINT lesson_media_upload_chk
+    CHECK (storage_key IS NULL OR (content_type IS NOT NULL AND size_bytes >= 0));
+
+CREATE UNIQUE INDEX IF NOT EXISTS idx_lesson_media_storage_key
+    ON lesson_media (storage_key) WHERE storage_key IS NOT NULL;
+CREATE INDEX IF NOT EXISTS idx_lesson_media_lesson ON lesson_media (lesson_id, order_index);
+
+
+-- Миграция 20: таксономия навыков и связь упражнений с навыками
+
+CREATE TABLE IF NOT EXISTS skills (
+    id          SERIAL PRIMARY KEY,
+    slug        TEXT NOT NULL,
+    name        TEXT NOT NULL,
+    instrument  TEXT,
+    description TEXT,
+    created_at  TIMESTAMP NOT NULL DEFAULT NOW(),
+    updated_at  TIMESTAMP NOT NULL DEFAULT NOW(),
+    CONSTRAINT skills_instrument_chk CHECK (instrument IN ('guitar','piano'))
+);
+
+CREATE UNIQUE INDEX IF NOT EXISTS idx_skills_slug ON skills (slug);
+
+-- Упражнение может тренировать несколько навыков
+CREATE TABLE IF NOT EXISTS exercise_skills (
+    exercise_id INT NOT NULL REFERENCES exercises(id) ON DELETE CASCADE,
+    skill_id    INT NOT NULL REFERENCES skills(id) ON DELETE CASCADE,
+    PRIMARY KEY (exercise_id, skill_id)
+);
+
+CREATE INDEX IF NOT EXISTS idx_exercise_skills_skill ON exercise_skills (skill_id);
+
+INSERT INTO skills (slug, name, instrument, description) VALUES
+('open-chords', 'Открытые аккорды', 'guitar', 'Аккорды в первой позиции с открытыми струнами: Am, C, D, Em, G'),
+('barre-chords', 'Баррэ', 'guitar', 'Аккорды с зажатием нескольких струн одним пальцем: F, Bm'),
+('chord-changes', 'Смена аккордов', 'guitar', 'Ровная смена аккордов в ритме песни'),
+('note-reading', 'Ноты на клавиатуре', 'piano', 'Найти и сыграть ноту по названию'),
+('c-major-scale', 'Гамма до мажор', 'piano', 'Ноты гаммы до мажор первой октавы'),
+('piano-melodies', 'Мелодии', 'piano', 'Мелодия одной рукой в темпе')
+ON CONFLICT (slug) DO NOTHING;
+
+-- Навыки существующих упражнений по типу и ожидаемому значению
+INSERT INTO exercise_skills (exercise_id, skill_id)
+SELECT e.id, s.id
+FROM exercises e
+JOIN lessons l ON l.id = e.lesson_id
+JOIN skills s ON s.slug = CASE
+    WHEN l.instrument = 'guitar' AND e.type = 'chord' AND e.expected IN ('F', 'Bm', 'B', 'Bb', 'F#m', 'Cm', 'Gm')
+        THEN 'barre-chords'
+    WHEN l.instrument = 'guitar' AND e.type = 'chord' THEN 'open-chords'
+    WHEN l.instrument = 'guitar' AND e.type = 'sequence' THEN 'chord-changes'
+    WHEN l.instrument = 'piano' AND e.type = 'note' THEN 'note-reading'
+    WHEN l.instrument = 'piano' AND e.type = 'sequence' THEN 'piano-melodies'
+    END
+ON CONFLICT DO NOTHING;
+
+-- Гамма до мажор — белые клавиши первой октавы в уроках с гаммами
+INSERT INTO exercise_skills (exercise_id, skill_id)
+SELECT e.id, s.id
+FROM exercises e
+JOIN lessons l ON l.id = e.lesson_id
+JOIN skills s ON s.slug = 'c-major-scale'
+WHERE l.instrument = 'piano' AND e.type = 'note' AND e.expected ~ '^[A-G]4$'
+  AND l.slug IN ('piano-scales', 'scales-and-melodies')
+ON CONFLICT DO NOTHING;
+
+
+-- Миграция 21: версии уроков — редактор правит черновик, публикация создаёт
+-- неизменяемую версию, ученик проходит ту версию, которую начал
+
+ALTER TABLE lessons DROP CONSTRAINT IF EXISTS lessons_status_chk;
+ALTER TABLE lessons
+ADD CONSTRAINT lessons_status_chk CHECK (status IN ('draft','published','archived'));
+
+-- Упражнение из опубликованной версии не меняется: правка создаёт новую строку
+-- (replaces_id — предыдущая), а старая выводится из черновика (retired_at)
+ALTER TABLE exercises
+ADD COLUMN IF NOT EXISTS replaces_id INT REFERENCES exercises(id) ON DELETE SET NULL,
+ADD COLUMN IF NOT EXISTS retired_at TIMESTAMP;
+
+-- slug уникален только среди упражнений черновика
+DROP INDEX IF EXISTS idx_exercises_lesson_slug;
+CREATE UNIQUE INDEX IF NOT EXISTS idx_exercises_lesson_slug ON exercises (lesson_id, slug) WHERE retired_at IS NULL;
+
+CREATE TABLE IF NOT EXISTS lesson_versions (
+    id           SERIAL PRIMARY KEY,
+    lesson_id    INT NOT NULL REFERENCES lessons(id) ON DELETE CASCADE,
+    version      INT NOT NULL,
+    note         TEXT,
+    published_by INT REFERENCES users(id) ON DELETE SET NULL,
+    published_at TIMESTAMP NOT NULL DEFAULT NOW(),
+    UNIQUE (lesson_id, version)
+);
+
+-- Состав и порядок упражнений версии. Упражнение версии удалить нельзя.
+CREATE TABLE IF NOT EXISTS lesson_version_exercises (
+    version_id  INT NOT NULL REFERENCES lesson_versions(id) ON DELETE CASCADE,
+    exercise_id INT NOT NULL REFERENCES exercises(id) ON DELETE RESTRICT,
+    order_index INT NOT NULL,
+    PRIMARY KEY (version_id, exercise_id)
+);
+
+CREATE INDEX IF NOT EXISTS idx_lesson_version_exercises_exercise ON lesson_version_exercises (exercise_id);
+
+-- Куда переносится прогресс с упражнения предыдущей версии при переходе на эту
+CREATE TABLE IF NOT EXISTS lesson_version_mappings (
+    version_id       INT NOT NULL REFERENCES lesson_versions(id) ON DELETE CASCADE,
+    from_exercise_id INT NOT NULL REFERENCES exercises(id) ON DELETE CASCADE,
+    to_exercise_id   INT NOT NULL REFERENCES exercises(id) ON DELETE CASCADE,
+    PRIMARY KEY (version_id, from_exercise_id)
+);
+
+ALTER TABLE lessons
+ADD COLUMN IF NOT EXISTS published_version_id INT REFERENCES lesson_versions(id) ON DELETE SET NULL;
+
+-- Версия урока, которую проходит ученик
+CREATE TABLE IF NOT EXISTS lesson_pins (
+    user_id    INT NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+    lesson_id  INT NOT NULL REFERENCES lessons(id) ON DELETE CASCADE,
+    version_id INT NOT NULL REFERENCES lesson_versions(id) ON DELETE CASCADE,
+    pinned_at  TIMESTAMP NOT NULL DEFAULT NOW(),
+    PRIMARY KEY (user_id, lesson_id)
+);
+
+-- Прогресс и история попыток больше не удаляются вместе с упражнением
+ALTER TABLE progress
+DROP CONSTRAINT IF EXISTS progress_exercise_id_fkey,
+ADD CONSTRAINT progress_exercise_id_fkey FOREIGN KEY (exercise_id) REFERENCES exercises(id) ON DELETE RESTRICT;
+
+ALTER TABLE exercise_attempts
+DROP CONSTRAINT IF EXISTS exercise_attempts_exercise_id_fkey,
+ADD CONSTRAINT exercise_attempts_exercise_id_fkey FOREIGN KEY (exercise_id) REFERENCES exercises(id) ON DELETE RESTRICT;
+
+-- Опубликованные уроки получают версию 1 из текущих упражнений
+INSERT INTO lesson_versions (lesson_id, version, note)
+SELECT l.id, 1, 'Исходная версия'
+FROM lessons l
+WHERE l.status = 'published'
+  AND NOT EXISTS (SELECT 1 FROM lesson_versions v WHERE v.lesson_id = l.id);
+
+INSERT INTO lesson_version_exercises (version_id, exercise_id, order_index)
+SELECT v.id, e.id, ROW_NUMBER() OVER (PARTITION BY v.id ORDER BY e.order_index, e.id)
+FROM lesson_versions v
+JOIN exercises e ON e.lesson_id = v.lesson_id
+WHERE v.version = 1
+ON CONFLICT DO NOTHING;
+
+UPDATE lessons l SET published_version_id = v.id
+FROM lesson_versions v
+WHERE v.lesson_id = l.id AND v.version = 1 AND l.published_version_id IS NULL;
+
+-- Ученики, которые уже занимались, закрепляются за версией 1
+INSERT INTO lesson_pins (user_id, lesson_id, version_id)
+SELECT DISTINCT p.user_id, l.id, l.published_version_id
+FROM progress p
+JOIN exercises e ON e.id = p.exercise_id
+JOIN lessons l ON l.id = e.lesson_id
+WHERE l.published_version_id IS NOT NULL
+ON CONFLICT DO NOTHING;
+
+-- Упражнения уроков в той версии, которую видит ученик: закреплённой или
+-- текущей опубликованной
+CREATE OR REPLACE FUNCTION user_lesson_exercises(p_user_id INT)
+RETURNS TABLE (lesson_id INT, version_id INT, exercise_id INT, order_index INT) AS $$
+    SELECT l.id, v.id, lve.exercise_id, lve.order_index
+    FROM lessons l
+    LEFT JOIN lesson_pins p ON p.lesson_id = l.id AND p.user_id = p_user_id
+    JOIN lesson_versions v ON v.id = COALESCE(p.version_id, l.published_version_id)
+    JOIN lesson_version_exercises lve ON lve.version_id = v.id
+$$ LANGUAGE sql STABLE;
+
+
+-- Миграция 22: справочник инструментов и строев. Канонические данные (диапазоны,
+-- каподастр) — в пакете internal/instruments, таблицы нужны для внешних ключей
+
+CREATE TABLE IF NOT EXISTS instruments (
+    id       TEXT PRIMARY KEY,
+    name     TEXT NOT NULL,
+    kind     TEXT NOT NULL,
+    frets    INT,
+    max_capo INT NOT NULL DEFAULT 0,
+    CONSTRAINT instruments_kind_chk CHECK (kind IN ('strings','keys'))
+);
+
+-- Открытые струны строя — от нижней к верхней
+CREATE TABLE IF NOT EXISTS instrument_tunings (
+    instrument_id TEXT NOT NULL REFERENCES instruments(id) ON DELETE CASCADE,
+    id            TEXT NOT NULL,
+    name          TEXT NOT NULL,
+    strings       TEXT[] NOT NULL,
+    is_default    BOOLEAN NOT NULL DEFAULT FALSE,
+    PRIMARY KEY (instrument_id, id)
+);
+
+INSERT INTO instruments (id, name, kind, frets, max_capo) VALUES
+    ('guitar',  'Гитара',      'strings', 24,   9),
+    ('bass',    'Бас-гитара',  'strings', 24,   0),
+    ('ukulele', 'Укулеле',     'strings', 15,   7),
+    ('piano',   'Фортепиано',  'keys',    NULL, 0)
+ON CONFLICT (id) DO NOTHING;
+
+INSERT INTO instrument_tunings (instrument_id, id, name, strings, is_default) VALUES
+    ('guitar',  'standard', 'Стандартный строй', '{E2,A2,D3,G3,B3,E4}', TRUE),
+    ('guitar',  'drop-d',   'Drop D',            '{D2,A2,D3,G3,B3,E4}', FALSE),
+    ('bass',    'standard', 'Стандартный строй', '{E1,A1,D2,G2}',       TRUE),
+    ('ukulele', 'gcea',     'GCEA',              '{G4,C4,E4,A4}',       TRUE)
+ON CONFLICT (instrument_id, id) DO NOTHING;
+
+-- Строй и каподастр урока; NULL — строй инструмента по умолчанию
+ALTER TABLE lessons
+ADD COLUMN IF NOT EXISTS tuning TEXT,
+ADD COLUMN IF NOT EXISTS capo INT NOT NULL DEFAULT 0;
+
+ALTER TABLE lessons DROP CONSTRAINT IF EXISTS lessons_capo_chk;
+ALTER TABLE lessons ADD CONSTRAINT lessons_capo_chk CHECK (capo BETWEEN 0 AND 12);
+
+-- Инструмент был свободным текстом
+UPDATE lessons SET instrument = lower(trim(instrument)) WHERE instrument <> lower(trim(instrument));
+UPDATE courses SET instrument = lower(trim(instrument)) WHERE instrument <> lower(trim(instrument));
+
+ALTER TABLE lessons
+DROP CONSTRAINT IF EXISTS lessons_instrument_fkey,
+ADD CONSTRAINT lessons_instrument_fkey FOREIGN KEY (instrument) REFERENCES instruments(id),
+DROP CONSTRAINT IF EXISTS lessons_tuning_fkey,
+ADD CONSTRAINT lessons_tuning_fkey FOREIGN KEY (instrument, tuning) REFERENCES instrument_tunings(instrument_id, id);
+
+ALTER TABLE courses
+DROP CONSTRAINT IF EXISTS courses_instrument_fkey,
+ADD CONSTRAINT courses_instrument_fkey FOREIGN KEY (instrument) REFERENCES instruments(id);
+
+ALTER TABLE skills
+DROP CONSTRAINT IF EXISTS skills_instrument_chk,
+DROP CONSTRAINT IF EXISTS skills_instrument_fkey,
+ADD CONSTRAINT skills_instrument_fkey FOREIGN KEY (instrument) REFERENCES instruments(id);
+
+
+-- Миграция 23: ритмические упражнения "rhythm" — рисунок ударов в заданном
+-- темпе и размере с окнами допуска по отклонению удара
+
+CREATE TABLE IF NOT EXISTS exercise_rhythms (
+    exercise_id     INT PRIMARY KEY REFERENCES exercises(id) ON DELETE CASCADE,
+    tempo           INT NOT NULL,                   -- удары в минуту
+    beats_per_bar   INT NOT NULL DEFAULT 4,
+    beat_unit       INT NOT NULL DEFAULT 4,
+    count_in        INT NOT NULL DEFAULT 1,         -- тактов метронома перед рисунком
+    CONSTRAINT exercise_rhythms_meter_chk
+        CHECK (tempo BETWEEN 20 AND 400 AND beats_per_bar > 0
+               AND beat_unit IN (1,2,4,8,16) AND count_in BETWEEN 0 AND 4),
+    -- [{"beats":1,"accent":true},{"beats":0.5},{"beats":1,"rest":true}, ...]
+    pattern         JSONB NOT NULL,
+    -- Окна допуска, мс
+    perfect_ms      INT NOT NULL DEFAULT 30,
+    good_ms         INT NOT NULL DEFAULT 80,
+    miss_ms         INT NOT NULL DEFAULT 150,
+    CONSTRAINT exercise_rhythms_windows_chk
+        CHECK (perfect_ms >= 0 AND perfect_ms <= good_ms AND good_ms <= miss_ms)
+);
+
+
+-- Миграция 24: тренировка слуха. Задания сессии не хранятся — генератор
+-- восстанавливает их по виду, сложности, seed и версии генератора
+
+CREATE TABLE IF NOT EXISTS ear_training_sessions (
+    id                BIGSERIAL PRIMARY KEY,
+    user_id           INT NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+    kind              TEXT NOT NULL,
+    CONSTRAINT ear_training_sessions_kind_chk
+        CHECK (kind IN ('interval','chord_quality','scale_degree','dictation')),
+    difficulty        TEXT NOT NULL,
+    CONSTRAINT ear_training_sessions_difficulty_chk
+        CHECK (difficulty IN ('beginner','intermediate','advanced')),
+    seed              BIGINT NOT NULL,
+    generator_version INT NOT NULL,
+    question_count    INT NOT NULL,
+    CONSTRAINT ear_training_sessions_count_chk CHECK (question_count BETWEEN 1 AND 50),
+    score             DECIMAL(5,2),           -- средний балл, когда отвечены все задания
+    created_at        TIMESTAMP NOT NULL DEFAULT NOW(),
+    completed_at      TIMESTAMP
+);
+
+CREATE INDEX IF NOT EXISTS idx_ear_training_sessions_user
+ON ear_training_sessions (user_id, created_at DESC);
+
+-- Один ответ на задание
+CREATE TABLE IF NOT EXISTS ear_training_answers (
+    session_id     BIGINT NOT NULL REFERENCES ear_training_sessions(id) ON DELETE CASCADE,
+    question_index INT NOT NULL,
+    answer         TEXT NOT NULL,
+    expected       TEXT NOT NULL,
+    correct        BOOLEAN NOT NULL,
+    score          DECIMAL(5,2) NOT NULL,
+    created_at     TIMESTAMP NOT NULL DEFAULT NOW(),
+    PRIMARY KEY (session_id, question_index)
+);
+
+
+-- Миграция 25: расписание повторений (SM-2) в строке progress
+
+ALTER TABLE progress
+ADD COLUMN IF NOT EXISTS ease DECIMAL(4,2) NOT NULL DEFAULT 2.50,
+ADD COLUMN IF NOT EXISTS interval_days INT NOT NULL DEFAULT 0,
+ADD COLUMN IF NOT EXISTS repetitions INT NOT NULL DEFAULT 0,
+ADD COLUMN IF NOT EXISTS due_at TIMESTAMP,                -- NULL — упражнение не повторяется
+ADD COLUMN IF NOT EXISTS last_reviewed_at TIMESTAMP;
+
+-- Уже пройденные упражнения считаем выученными один раз: повторение на следующий день
+UPDATE progress
+SET repetitions = 1,
+    interval_days = 1,
+    due_at = date_trunc('day', COALESCE(completed_at, updated_at, NOW())) + INTERVAL '1 day',
+    last_reviewed_at = COALESCE(completed_at, updated_at)
+WHERE completed = TRUE AND due_at IS NULL;
+
+CREATE INDEX IF NOT EXISTS idx_progress_due ON progress(user_id, due_at) WHERE due_at IS NOT NULL;
+
+
+-- Миграция 26: серии занятий, дневные цели и опыт
+
+ALTER TABLE users
+ADD COLUMN IF NOT EXISTS timezone TEXT NOT NULL DEFAULT 'UTC',  -- IANA, по нему считаются дни занятий
+ADD COLUMN IF NOT EXISTS daily_goal_kind TEXT NOT NULL DEFAULT 'minutes',
+ADD COLUMN IF NOT EXISTS daily_goal_target INT NOT NULL DEFAULT 10;
+
+ALTER TABLE users
+ADD CONSTRAINT users_daily_goal_kind_chk CHECK (daily_goal_kind IN ('minutes','exercises')),
+ADD CONSTRAINT users_daily_goal_target_chk CHECK (daily_goal_target BETWEEN 1 AND 1440);
+
+-- Копии попыток, перенесённые при переходе на новую версию урока: в сериях и опыте не учитываются
+ALTER TABLE exercise_attempts
+ADD COLUMN IF NOT EXISTS carried BOOLEAN NOT NULL DEFAULT FALSE;
+
+-- Уже перенесённые копии узнаём по исходной попытке того же ученика с тем же временем
+UPDATE exercise_attempts c SET carried = TRUE
+WHERE EXISTS (
+    SELECT 1 FROM exercise_attempts o
+    WHERE o.user_id = c.user_id AND o.created_at = c.created_at
+      AND o.exercise_id <> c.exercise_id AND o.id < c.id
+      AND o.status = c.status AND o.score = c.score
+);
+
+-- Результат последнего пересчёта (пакет stats); источник правды — exercise_attempts
+CREATE TABLE IF NOT EXISTS user_stats (
+    user_id             INT PRIMARY KEY REFERENCES users(id) ON DELETE CASCADE,
+    xp                  INT NOT NULL DEFAULT 0,
+    level               INT NOT NULL DEFAULT 1,
+    current_streak      INT NOT NULL DEFAULT 0,
+    longest_streak      INT NOT NULL DEFAULT 0,
+    completed_exercises INT NOT NULL DEFAULT 0,
+    updated_at          TIMESTAMP NOT NULL DEFAULT NOW()
+);
+
+
+-- Миграция 27: значки за достижения. Условия — данные (rule), их проверяет пакет achievements
+
+CREATE TABLE IF NOT EXISTS achievements (
+    id            SERIAL PRIMARY KEY,
+    slug          TEXT NOT NULL UNIQUE,
+    title         TEXT NOT NULL,
+    description   TEXT NOT NULL,
+    icon          TEXT,
+    rule          JSONB NOT NULL,        -- {"kind": "exercises", "exercise_type": "chord", "count": 1}
+    order_index   INT NOT NULL DEFAULT 0,
+    backfilled_at TIMESTAMP,             -- когда значок выдан всем, кто заслужил его раньше
+    created_at    TIMESTAMP NOT NULL DEFAULT NOW()
+);
+
+CREATE TABLE IF NOT EXISTS user_achievements (
+    user_id        INT NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+    achievement_id INT NOT NULL REFERENCES achievements(id) ON DELETE CASCADE,
+    awarded_at     TIMESTAMP NOT NULL,   -- когда выполнено условие
+    PRIMARY KEY (user_id, achievement_id)
+);
+
+INSERT INTO achievements (slug, title, description, icon, rule, order_index) VALUES
+('first-chord', 'Первый аккорд', 'Сыграйте свой первый аккорд', '🎸',
+    '{"kind": "exercises", "exercise_type": "chord", "count": 1}', 1),
+('first-note', 'Первая нота', 'Сыграйте свою первую ноту', '🎹',
+    '{"kind": "exercises", "exercise_type": "note", "count": 1}', 2),
+('ten-exercises', 'Десять упражнений', 'Пройдите десять упражнений', '🎯',
+    '{"kind": "exercises", "count": 10}', 3),
+('c-major-notes', 'Гамма до мажор', 'Сыграйте все ноты гаммы до мажор', '🎼',
+    '{"kind": "scale_notes", "scale": "C"}', 4),
+('streak-7', 'Неделя без перерыва', 'Занимайтесь 7 дней подряд', '🔥',
+    '{"kind": "streak", "days": 7}', 5),
+('streak-30', 'Месяц без перерыва', 'Занимайтесь 30 дней подряд', '🏆',
+    '{"kind": "streak", "days": 30}', 6),
+('guitar-basics', 'Основы гитары', 'Пройдите урок «Основы гитары»', '🎓',
+    '{"kind": "lesson", "lesson": "guitar-basics"}', 7),
+('xp-1000', 'Тысяча очков опыта', 'Наберите 1000 очков опыта', '⭐',
+    '{"kind": "xp", "xp": 1000}', 8)
+ON CONFLICT (slug) DO NOTHING;
+
+
+-- Миграция 28: рейтинги — отказ от участия, группы друзей и семейные группы
+
+ALTER TABLE users
+ADD COLUMN IF NOT EXISTS leaderboard_opt_out BOOLEAN NOT NULL DEFAULT FALSE;
+
+CREATE TABLE IF NOT EXISTS user_groups (
+    id              SERIAL PRIMARY KEY,
+    kind            TEXT NOT NULL,
+    CONSTRAINT user_groups_kind_chk CHECK (kind IN ('friends','family')),
+    name            TEXT NOT NULL,
+    owner_id        INT NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+    subscription_id BIGINT REFERENCES subscriptions(id) ON DELETE CASCADE,  -- для семейной группы
+    CONSTRAINT user_groups_family_chk CHECK ((kind = 'family') = (subscription_id IS NOT NULL)),
+    invite_code     TEXT NOT NULL UNIQUE,
+    created_at      TIMESTAMP NOT NULL DEFAULT NOW()
+);
+
+-- Одна семейная группа на подписку
+CREATE UNIQUE INDEX IF NOT EXISTS uq_user_groups_subscription ON user_groups (subscription_id)
+WHERE subscription_id IS NOT NULL;
+
+CREATE TABLE IF NOT EXISTS user_group_members (
+    group_id  INT NOT NULL REFERENCES user_groups(id) ON DELETE CASCADE,
+    user_id   INT NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+    joined_at TIMESTAMP NOT NULL DEFAULT NOW(),
+    PRIMARY KEY (group_id, user_id)
+);
+
+CREATE INDEX IF NOT EXISTS idx_user_group_members_user ON user_group_members (user_id);
+
+-- Первые прохождения упражнений для подсчёта очков
+CREATE INDEX IF NOT EXISTS idx_exercise_attempts_passed
+ON exercise_attempts (user_id, exercise_id, created_at) WHERE passed;
+
+
+-- Миграция 29: сессии занятий — активное время по сигналам клиента
+
+CREATE TABLE IF NOT EXISTS practice_sessions (
+    id                BIGSERIAL PRIMARY KEY,
+    user_id           INT NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+    lesson_id         INT REFERENCES lessons(id) ON DELETE SET NULL,
+    exercise_id       INT REFERENCES exercises(id) ON DELETE SET NULL,  -- текущее упражнение
+    started_at        TIMESTAMP NOT NULL DEFAULT NOW(),
+    last_heartbeat_at TIMESTAMP NOT NULL DEFAULT NOW(),
+    ended_at          TIMESTAMP,
+    end_reason        TEXT,
+    CONSTRAINT practice_sessions_end_reason_chk CHECK (end_reason IN ('user','idle','replaced')),
+    active_seconds    INT NOT NULL DEFAULT 0
+);
+
+-- У ученика одна открытая сессия
+CREATE UNIQUE INDEX IF NOT EXISTS uq_practice_sessions_open ON practice_sessions (user_id)
+WHERE ended_at IS NULL;
+
+CREATE INDEX IF NOT EXISTS idx_practice_sessions_user ON practice_sessions (user_id, started_at DESC);
+
+-- Активное время сессии по упражнениям
+CREATE TABLE IF NOT EXISTS practice_session_exercises (
+    session_id     BIGINT NOT NULL REFERENCES practice_sessions(id) ON DELETE CASCADE,
+    exercise_id    INT NOT NULL REFERENCES exercises(id) ON DELETE CASCADE,
+    active_seconds INT NOT NULL DEFAULT 0,
+    PRIMARY KEY (session_id, exercise_id)
+);
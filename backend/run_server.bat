@@ -9,4 +9,7 @@ set APP_PORT=8080
 set REDIS_HOST=localhost
 set REDIS_PORT=6379
 set JWT_SECRET=my-super-secret-jwt-key-that-is-very-long-and-secure-for-production-use
+set STORAGE_BACKEND=local
+set STORAGE_DIR=./uploads
+set STORAGE_PUBLIC_URL=http://localhost:8080
 go run main.go
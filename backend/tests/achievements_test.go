@@ -0,0 +1,100 @@
+package tests
+
+import (
+	"testing"
+	"time"
+
+	"sonara-space/backend/internal/achievements"
+	"sonara-space/backend/internal/models"
+	"sonara-space/backend/internal/music"
+	"sonara-space/backend/internal/stats"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func at(hour int) time.Time {
+	return time.Date(2024, 3, 1, hour, 0, 0, 0, time.UTC)
+}
+
+func TestAchievementRules(t *testing.T) {
+	r, err := achievements.ParseRule([]byte(`{"kind": "exercises", "exercise_type": "chord", "count": 1}`))
+	require.NoError(t, err)
+	assert.Equal(t, achievements.Rule{Kind: achievements.KindExercises, ExerciseType: "chord", Count: 1}, r)
+
+	for _, raw := range []string{
+		`{"kind": "exercises"}`,
+		`{"kind": "exercises", "exercise_type": "drums", "count": 1}`,
+		`{"kind": "scale_notes", "scale": "H"}`,
+		`{"kind": "lesson"}`,
+		`{"kind": "streak", "days": 0}`,
+		`{"kind": "level"}`,
+		`not json`,
+	} {
+		_, err := achievements.ParseRule([]byte(raw))
+		assert.ErrorIs(t, err, achievements.ErrRule, raw)
+	}
+}
+
+func TestAchievementEvaluate(t *testing.T) {
+	facts := achievements.Facts{
+		Passed: []achievements.Passed{
+			{Type: models.ExerciseTypeNote, Expected: "C4", At: at(1)},
+			{Type: models.ExerciseTypeChord, Expected: "Am", At: at(2)},
+			{Type: models.ExerciseTypeNote, Expected: "D4", At: at(3)},
+			{Type: models.ExerciseTypeNote, Expected: "E4", At: at(4)},
+			{Type: models.ExerciseTypeNote, Expected: "F#4", At: at(5)},
+			{Type: models.ExerciseTypeNote, Expected: "F4", At: at(6)},
+			{Type: models.ExerciseTypeNote, Expected: "G4", At: at(7)},
+			{Type: models.ExerciseTypeNote, Expected: "A3", At: at(8)},
+			{Type: models.ExerciseTypeNote, Expected: "C5", At: at(9)},
+			{Type: models.ExerciseTypeNote, Expected: "B4", At: at(10)},
+		},
+		Lessons: map[string]time.Time{"guitar-basics": at(12)},
+		Stats: stats.Stats{
+			Streak: stats.Streak{Current: 2, Longest: 8},
+			Level:  stats.LevelFor(250),
+		},
+		Now: at(20),
+	}
+
+	// Время выдачи — момент выполнения условия
+	ok, when := achievements.Evaluate(achievements.Rule{Kind: achievements.KindExercises, ExerciseType: "chord", Count: 1}, facts)
+	assert.True(t, ok)
+	assert.Equal(t, at(2), when)
+	ok, when = achievements.Evaluate(achievements.Rule{Kind: achievements.KindExercises, Count: 3}, facts)
+	assert.True(t, ok)
+	assert.Equal(t, at(3), when)
+	ok, _ = achievements.Evaluate(achievements.Rule{Kind: achievements.KindExercises, ExerciseType: "chord", Count: 2}, facts)
+	assert.False(t, ok)
+
+	// Гамма собрана последней недостающей нотой (октава не важна, F# не из гаммы)
+	ok, when = achievements.Evaluate(achievements.Rule{Kind: achievements.KindScaleNotes, Scale: "C"}, facts)
+	assert.True(t, ok)
+	assert.Equal(t, at(10), when)
+	ok, _ = achievements.Evaluate(achievements.Rule{Kind: achievements.KindScaleNotes, Scale: "D"}, facts)
+	assert.False(t, ok)
+
+	ok, when = achievements.Evaluate(achievements.Rule{Kind: achievements.KindLesson, Lesson: "guitar-basics"}, facts)
+	assert.True(t, ok)
+	assert.Equal(t, at(12), when)
+	ok, _ = achievements.Evaluate(achievements.Rule{Kind: achievements.KindLesson, Lesson: "piano-basics"}, facts)
+	assert.False(t, ok)
+
+	// Серия считается по самой длинной, время восстановить нельзя
+	ok, when = achievements.Evaluate(achievements.Rule{Kind: achievements.KindStreak, Days: 7}, facts)
+	assert.True(t, ok)
+	assert.Equal(t, at(20), when)
+	ok, _ = achievements.Evaluate(achievements.Rule{Kind: achievements.KindXP, XP: 1000}, facts)
+	assert.False(t, ok)
+}
+
+func TestKeyPitchClasses(t *testing.T) {
+	c, _ := music.ParseKey("C")
+	assert.Equal(t, []music.PitchClass{0, 2, 4, 5, 7, 9, 11}, c.PitchClasses().Slice())
+	am, _ := music.ParseKey("Am")
+	assert.Equal(t, c.PitchClasses(), am.PitchClasses())
+	d, _ := music.ParseKey("D")
+	assert.True(t, d.PitchClasses().Has(6))
+	assert.False(t, d.PitchClasses().Has(5))
+}
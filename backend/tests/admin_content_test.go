@@ -0,0 +1,118 @@
+package tests
+
+import (
+	"bytes"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"sonara-space/backend/internal/handlers"
+	"sonara-space/backend/internal/models"
+
+	"github.com/go-chi/chi/v5"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestLessonInputValidate(t *testing.T) {
+	tuning := "  "
+	in := handlers.LessonInput{
+		Title: "  Первые аккорды ", Instrument: " Guitar", Tuning: &tuning,
+		Locale: " KK ", Tags: []string{"Аккорды", " аккорды", "", "Песни"},
+	}
+	require.NoError(t, in.Validate())
+	assert.Equal(t, "Первые аккорды", in.Title)
+	assert.Equal(t, "guitar", in.Instrument)
+	assert.Nil(t, in.Tuning) // пустой строй — строй по умолчанию
+	assert.Equal(t, models.DifficultyBeginner, in.Difficulty)
+	assert.Equal(t, models.TierFree, in.Tier)
+	assert.Equal(t, "kk", in.Locale)
+	assert.Equal(t, []string{"аккорды", "песни"}, in.Tags)
+
+	in = handlers.LessonInput{Title: "Урок", Instrument: "guitar"}
+	require.NoError(t, in.Validate())
+	assert.Equal(t, "ru", in.Locale)
+
+	for name, bad := range map[string]handlers.LessonInput{
+		"no title":   {Title: " ", Instrument: "guitar"},
+		"instrument": {Title: "Урок", Instrument: "banjo"},
+		"slug":       {Title: "Урок", Instrument: "guitar", Slug: "Не slug"},
+		"difficulty": {Title: "Урок", Instrument: "guitar", Difficulty: "expert"},
+		"tier":       {Title: "Урок", Instrument: "guitar", Tier: "gold"},
+		"capo":       {Title: "Урок", Instrument: "piano", Capo: 2},
+		"locale":     {Title: "Урок", Instrument: "guitar", Locale: "very-long-locale"},
+	} {
+		assert.Error(t, bad.Validate(), name)
+	}
+}
+
+func TestExerciseInputValidate(t *testing.T) {
+	in := handlers.ExerciseInput{Title: " Аккорд Am ", Type: models.ExerciseTypeChord, Expected: " Am "}
+	require.NoError(t, in.Validate())
+	assert.Equal(t, "Аккорд Am", in.Title)
+	assert.Equal(t, "Am", in.Expected)
+
+	// Для песни expected строится из шагов
+	in = handlers.ExerciseInput{Title: "Песня", Type: models.ExerciseTypeSequence, Sequence: &handlers.SequenceInput{
+		SongTitle: "Песня", StepType: models.ExerciseTypeChord, Tempo: 90, BeatsPerBar: 4, BeatUnit: 4,
+		Steps: []models.SequenceStep{{Symbol: "C", Beats: 4}, {Symbol: "G", Beats: 4}},
+	}}
+	require.NoError(t, in.Validate())
+	assert.Equal(t, "C G", in.Expected)
+
+	for name, bad := range map[string]handlers.ExerciseInput{
+		"no title":          {Type: models.ExerciseTypeNote, Expected: "C4"},
+		"negative order":    {Title: "Нота", Type: models.ExerciseTypeNote, Expected: "C4", OrderIndex: -1},
+		"bad note":          {Title: "Нота", Type: models.ExerciseTypeNote, Expected: "H9"},
+		"bad type":          {Title: "Нота", Type: "drum", Expected: "C4"},
+		"missing sequence":  {Title: "Песня", Type: models.ExerciseTypeSequence},
+		"stray sequence":    {Title: "Нота", Type: models.ExerciseTypeNote, Expected: "C4", Sequence: &handlers.SequenceInput{}},
+		"missing rhythm":    {Title: "Ритм", Type: models.ExerciseTypeRhythm},
+		"stray rhythm":      {Title: "Нота", Type: models.ExerciseTypeNote, Expected: "C4", Rhythm: &handlers.RhythmInput{}},
+		"chord as note":     {Title: "Нота", Type: models.ExerciseTypeNote, Expected: "Am"},
+		"invalid slug used": {Title: "Нота", Type: models.ExerciseTypeNote, Expected: "C4", Slug: "a b"},
+	} {
+		assert.Error(t, bad.Validate(), name)
+	}
+}
+
+func TestReorderIndexes(t *testing.T) {
+	existing := map[int64]bool{10: true, 11: true, 12: true}
+
+	order, err := handlers.ReorderIndexes(existing, []int64{12, 10, 11})
+	require.NoError(t, err)
+	// Порядок — с единицы, по порядку запроса
+	assert.Equal(t, map[int64]int{12: 1, 10: 2, 11: 3}, order)
+
+	for name, ids := range map[string][]int64{
+		"missing":   {12, 10},
+		"duplicate": {12, 12, 10},
+		"foreign":   {12, 10, 99},
+		"extra":     {12, 10, 11, 13},
+	} {
+		_, err := handlers.ReorderIndexes(existing, ids)
+		assert.Error(t, err, name)
+	}
+
+	order, err = handlers.ReorderIndexes(map[int64]bool{}, nil)
+	require.NoError(t, err)
+	assert.Empty(t, order)
+}
+
+func TestAdminContentBadRequests(t *testing.T) {
+	r := chi.NewRouter()
+	r.Post("/admin/lessons", handlers.AdminCreateLessonHandler)
+	r.Put("/admin/lessons/{id}/exercises/order", handlers.AdminReorderExercisesHandler)
+
+	for _, c := range []struct{ method, path, body string }{
+		{"POST", "/admin/lessons", `{"title":`},
+		{"POST", "/admin/lessons", `{"title":"Урок","instrument":"banjo"}`},
+		{"PUT", "/admin/lessons/x/exercises/order", `{"exercise_ids":[1]}`},
+		{"PUT", "/admin/lessons/1/exercises/order", `not json`},
+	} {
+		req := httptest.NewRequest(c.method, c.path, bytes.NewBufferString(c.body))
+		w := httptest.NewRecorder()
+		r.ServeHTTP(w, req)
+		assert.Equal(t, http.StatusBadRequest, w.Code, c.method+" "+c.path+" "+c.body)
+	}
+}
@@ -0,0 +1,91 @@
+package tests
+
+import (
+	"bytes"
+	"math"
+	"testing"
+
+	"sonara-space/backend/internal/audio"
+	"sonara-space/backend/internal/music"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// tone синтезирует сумму синусоид (с парой обертонов) заданной длительности
+func tone(sampleRate int, seconds float64, freqs ...float64) *audio.Buffer {
+	n := int(seconds * float64(sampleRate))
+	samples := make([]float64, n)
+	for i := range samples {
+		t := float64(i) / float64(sampleRate)
+		for _, f := range freqs {
+			samples[i] += 0.3*math.Sin(2*math.Pi*f*t) +
+				0.1*math.Sin(2*math.Pi*2*f*t) +
+				0.05*math.Sin(2*math.Pi*3*f*t)
+		}
+		samples[i] /= float64(len(freqs))
+	}
+	return &audio.Buffer{SampleRate: sampleRate, Samples: samples}
+}
+
+func TestWAVRoundTrip(t *testing.T) {
+	src := tone(44100, 0.5, 440)
+
+	var buf bytes.Buffer
+	require.NoError(t, audio.EncodeWAV(&buf, src))
+
+	decoded, err := audio.DecodeWAV(&buf)
+	require.NoError(t, err)
+	assert.Equal(t, 44100, decoded.SampleRate)
+	require.Len(t, decoded.Samples, len(src.Samples))
+	for i := 0; i < len(src.Samples); i += 997 {
+		assert.InDelta(t, src.Samples[i], decoded.Samples[i], 1e-4)
+	}
+
+	_, err = audio.DecodeWAV(bytes.NewReader([]byte("not a wav file at all")))
+	assert.Error(t, err)
+
+	// Размер блока fmt в заголовке ~4 ГБ: отказ до выделения памяти
+	huge := []byte("RIFF\x24\x00\x00\x00WAVEfmt \xf0\xff\xff\xff")
+	_, err = audio.DecodeWAV(bytes.NewReader(huge))
+	assert.ErrorIs(t, err, audio.ErrUnsupportedFormat)
+}
+
+func TestAnalyzeNote(t *testing.T) {
+	a4, _ := music.ParseNote("A4")
+	res := audio.AnalyzeNote(tone(44100, 1, 440), a4, music.StandardA4)
+	assert.True(t, res.Match)
+	assert.Equal(t, "A4", res.DetectedNote)
+	assert.InDelta(t, 0, res.CentsOff, 5)
+
+	// Сыграна E2 вместо ожидаемой E3 — промах на октаву
+	e3, _ := music.ParseNote("E3")
+	e2, _ := music.ParseNote("E2")
+	res = audio.AnalyzeNote(tone(44100, 1, e2.Frequency(music.StandardA4)), e3, music.StandardA4)
+	assert.False(t, res.Match)
+	assert.Equal(t, "E2", res.DetectedNote)
+
+	// Тишина
+	res = audio.AnalyzeNote(&audio.Buffer{SampleRate: 44100, Samples: make([]float64, 44100)}, a4, music.StandardA4)
+	assert.False(t, res.Match)
+	assert.Zero(t, res.DetectedFrequency)
+}
+
+func TestAnalyzeChord(t *testing.T) {
+	freq := func(name string) float64 {
+		n, err := music.ParseNote(name)
+		require.NoError(t, err)
+		return n.Frequency(music.StandardA4)
+	}
+
+	am, _ := music.ParseChord("Am")
+	res := audio.AnalyzeChord(tone(44100, 1.5, freq("A3"), freq("C4"), freq("E4")), am, music.StandardA4)
+	assert.True(t, res.Match, "detected %s", res.DetectedChord)
+	assert.Equal(t, 1.0, res.Completeness)
+
+	// Вместо Am сыграли C
+	res = audio.AnalyzeChord(tone(44100, 1.5, freq("C4"), freq("E4"), freq("G4")), am, music.StandardA4)
+	assert.False(t, res.Match)
+	assert.Equal(t, "C", res.DetectedChord)
+	assert.Contains(t, res.TonesMissing, "A")
+}
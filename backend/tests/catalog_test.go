@@ -0,0 +1,112 @@
+package tests
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strconv"
+	"testing"
+	"time"
+
+	"sonara-space/backend/internal/handlers"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// catalogFilter строит фильтр каталога и собирает параметры SQL
+func catalogFilter(t *testing.T, query string) ([]string, string, string, []interface{}, error) {
+	values, err := url.ParseQuery(query)
+	require.NoError(t, err)
+	var args []interface{}
+	arg := func(v interface{}) string {
+		args = append(args, v)
+		return "$" + strconv.Itoa(len(args))
+	}
+	where, rank, order, err := handlers.LessonCatalogFilter(values, arg)
+	return where, rank, order, args, err
+}
+
+func TestLessonCursorRoundTrip(t *testing.T) {
+	at := time.Date(2024, 3, 4, 10, 30, 0, 123000000, time.UTC)
+	rank := 0.25
+
+	for _, c := range []handlers.LessonCursor{
+		{CreatedAt: at, ID: 42},
+		{CreatedAt: at, Rank: &rank, ID: 7},
+	} {
+		encoded := c.Encode()
+		assert.NotContains(t, encoded, "=") // безопасно для URL без экранирования
+		decoded, err := handlers.DecodeLessonCursor(encoded)
+		require.NoError(t, err)
+		assert.True(t, c.CreatedAt.Equal(decoded.CreatedAt))
+		assert.Equal(t, c.ID, decoded.ID)
+		assert.Equal(t, c.Rank, decoded.Rank)
+	}
+
+	for _, bad := range []string{"!!!", "bm90IGpzb24", "e30", "eyJpZCI6LTF9"} { // мусор, не JSON, {}, {"id":-1}
+		_, err := handlers.DecodeLessonCursor(bad)
+		assert.Error(t, err, bad)
+	}
+}
+
+func TestLessonCatalogFilter(t *testing.T) {
+	where, rank, order, args, err := catalogFilter(t, "")
+	require.NoError(t, err)
+	assert.Equal(t, []string{"status = 'published'"}, where)
+	assert.Equal(t, "NULL::float8", rank)
+	assert.Equal(t, "created_at, id", order)
+	assert.Empty(t, args)
+
+	// Фильтры складываются через AND, значения приводятся к нижнему регистру,
+	// пустые теги отбрасываются
+	where, _, _, args, err = catalogFilter(t, "instrument=Guitar&difficulty=beginner&tag=Songs&tag=+&tag=chords&skill=strumming&skill=barre")
+	require.NoError(t, err)
+	require.Len(t, where, 5)
+	assert.Equal(t, "instrument = $1", where[1])
+	assert.Equal(t, "difficulty = $2", where[2])
+	assert.Equal(t, "tags @> $3::text[]", where[3])
+	assert.Contains(t, where[4], "s.slug = ANY($4)")
+	assert.Equal(t, []interface{}{"guitar", "beginner", []string{"songs", "chords"}, []string{"strumming", "barre"}, 2}, args)
+
+	// Поиск сортирует по релевантности
+	where, rank, order, args, err = catalogFilter(t, "q=аккорды&tier=free")
+	require.NoError(t, err)
+	assert.Equal(t, "tier = $1", where[1])
+	assert.Contains(t, where[2], "search_vector @@")
+	assert.Contains(t, rank, "ts_rank")
+	assert.Equal(t, "rank DESC, id", order)
+	assert.Equal(t, []interface{}{"free", "аккорды"}, args)
+}
+
+func TestLessonCatalogCursor(t *testing.T) {
+	at := time.Date(2024, 3, 4, 10, 0, 0, 0, time.UTC)
+	rank := 0.5
+	plain := handlers.LessonCursor{CreatedAt: at, ID: 5}.Encode()
+	ranked := handlers.LessonCursor{CreatedAt: at, Rank: &rank, ID: 5}.Encode()
+
+	where, _, _, args, err := catalogFilter(t, "cursor="+plain)
+	require.NoError(t, err)
+	assert.Equal(t, "(created_at, id) > ($1, $2)", where[len(where)-1])
+	assert.Equal(t, int64(5), args[1])
+
+	where, _, _, args, err = catalogFilter(t, "q=гамма&cursor="+ranked)
+	require.NoError(t, err)
+	assert.Contains(t, where[len(where)-1], "id > $3")
+	assert.Equal(t, 0.5, args[1])
+
+	// Курсор поиска не подходит к обычной выдаче и наоборот
+	_, _, _, _, err = catalogFilter(t, "cursor="+ranked)
+	assert.Error(t, err)
+	_, _, _, _, err = catalogFilter(t, "q=гамма&cursor="+plain)
+	assert.Error(t, err)
+}
+
+func TestLessonCatalogMalformedCursor(t *testing.T) {
+	for _, query := range []string{"cursor=%21%21%21", "cursor=e30", "limit=0", "limit=abc"} {
+		req := asUser(httptest.NewRequest("GET", "/lessons?lang=ru&"+query, nil), 1)
+		w := httptest.NewRecorder()
+		handlers.GetLessonsHandler(w, req)
+		assert.Equal(t, http.StatusBadRequest, w.Code, query)
+	}
+}
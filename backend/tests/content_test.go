@@ -0,0 +1,136 @@
+package tests
+
+import (
+	"bytes"
+	"errors"
+	"os"
+	"strings"
+	"testing"
+
+	"sonara-space/backend/internal/content"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func loadBundle(t *testing.T) *content.Bundle {
+	t.Helper()
+	f, err := os.Open("testdata/bundle.yaml")
+	require.NoError(t, err)
+	defer f.Close()
+	b, err := content.Decode(f, true)
+	require.NoError(t, err)
+	return b
+}
+
+func TestSlugify(t *testing.T) {
+	assert.Equal(t, "osnovy-gitary", content.Slugify("Основы гитары"))
+	assert.Equal(t, "qazaq-olengderi", content.Slugify("Қазақ өлеңдері"))
+	assert.Equal(t, "happy-birthday", content.Slugify("  Happy Birthday!  "))
+	assert.Equal(t, "csharp-minor", content.Slugify("C# minor"))
+	assert.Equal(t, "obem", content.Slugify("Объём"))
+	assert.Equal(t, "chord-am", content.ExerciseSlug("chord", "Am"))
+	assert.Equal(t, "note-csharp4", content.ExerciseSlug("note", "C#4"))
+	assert.Equal(t, "song", content.ExerciseSlug("sequence", "C G C"))
+	assert.Equal(t, "rhythm", content.ExerciseSlug("rhythm", "1 1 1 1"))
+
+	assert.True(t, content.ValidSlug("guitar-basics"))
+	assert.False(t, content.ValidSlug("Guitar-basics"))
+	assert.False(t, content.ValidSlug("guitar--basics"))
+	assert.False(t, content.ValidSlug("-guitar"))
+	assert.False(t, content.ValidSlug(strings.Repeat("a", 101)))
+}
+
+func TestBundleValidateDefaults(t *testing.T) {
+	b := loadBundle(t)
+	require.NoError(t, b.Validate(nil))
+
+	basics := b.Lessons[0]
+	assert.Equal(t, "published", basics.Status)
+	assert.Equal(t, "beginner", basics.Difficulty)
+	assert.Equal(t, "free", basics.Tier)
+	assert.Equal(t, "ru", basics.Locale)
+	// Повторяющийся аккорд получает суффикс
+	assert.Equal(t, "chord-am", basics.Exercises[0].Slug)
+	assert.Equal(t, "chord-c", basics.Exercises[1].Slug)
+	assert.Equal(t, "chord-am-2", basics.Exercises[2].Slug)
+
+	songs := b.Lessons[1]
+	assert.Equal(t, 80, songs.Prerequisites[0].MinPercent)
+	// Для песни expected вычисляется из шагов
+	assert.Equal(t, "C G C", songs.Exercises[0].Expected)
+	seq, err := songs.Exercises[0].Song.Sequence()
+	require.NoError(t, err)
+	assert.Equal(t, 3, seq.BeatsPerBar)
+	assert.Equal(t, 4, seq.BeatUnit)
+	assert.Equal(t, 12.0, seq.TotalBeats())
+
+	// Ритм: expected — рисунок, отсчёт и окна допуска по умолчанию
+	strum := songs.Exercises[1]
+	assert.Equal(t, "rhythm", strum.Slug)
+	assert.Equal(t, "!1 0.5 0.5 r1 1", strum.Expected)
+	require.NotNil(t, strum.Rhythm.CountIn)
+	assert.Equal(t, 1, *strum.Rhythm.CountIn)
+	assert.Equal(t, 150, strum.Rhythm.MissMs)
+
+	require.Len(t, b.Skills, 2)
+	assert.Equal(t, []string{"open-chords"}, basics.Exercises[0].Skills)
+	assert.Equal(t, []string{"chord-changes", "open-chords"}, songs.Exercises[0].Skills)
+}
+
+func TestBundleValidateErrors(t *testing.T) {
+	b := loadBundle(t)
+	b.Lessons[0].Slug = "Guitar Basics"
+	b.Lessons[0].Exercises[1].Expected = "H7"
+	b.Lessons[1].Prerequisites[0].Lesson = "missing-lesson"
+	b.Lessons[1].Media = []content.Media{{Kind: "gif", URI: "x.gif"}}
+	b.Courses[0].Modules[0].Lessons = []string{"missing-lesson"}
+	b.Skills[1].Name = " "
+	b.Lessons[0].Exercises[0].Skills = []string{"open-chords", "open-chords"}
+	b.Lessons[0].Exercises = append(b.Lessons[0].Exercises, content.Exercise{Title: "Нижнее до", Type: "note", Expected: "C2"})
+	b.Lessons[1].Tuning = "open-g"
+	b.Lessons[1].Exercises[1].Rhythm.Pattern = "r1 r1"
+
+	err := b.Validate(nil)
+	var invalid content.ValidationError
+	require.True(t, errors.As(err, &invalid))
+	text := err.Error()
+	assert.Contains(t, text, "lessons[0] (Guitar Basics): invalid slug")
+	assert.Contains(t, text, "exercises[1] (chord-h7)")
+	assert.Contains(t, text, `prerequisite "missing-lesson" not found`)
+	assert.Contains(t, text, `invalid kind "gif"`)
+	assert.Contains(t, text, `modules[0]: lesson "missing-lesson" not found`)
+	assert.Contains(t, text, "skills[1] (chord-changes): name is required")
+	assert.Contains(t, text, `duplicate skill "open-chords"`)
+	assert.Contains(t, text, "(note-c2): C2 is outside the guitar range E2–E6")
+	assert.Contains(t, text, `unknown tuning "open-g" for guitar`)
+	assert.Contains(t, text, "rhythm.pattern must contain at least one hit")
+
+	// Уроки из базы можно упоминать, не включая их в файл
+	b = loadBundle(t)
+	b.Lessons = b.Lessons[1:]
+	b.Courses = nil
+	assert.Error(t, b.Validate(nil))
+	assert.NoError(t, b.Validate(map[string]bool{"guitar-basics": true}))
+}
+
+func TestBundleDecodeRejectsUnknownFields(t *testing.T) {
+	_, err := content.Decode(strings.NewReader("version: 1\nlessons:\n  - slug: a\n    titel: A\n"), true)
+	assert.Error(t, err)
+	_, err = content.Decode(strings.NewReader(`{"version": 1, "lesons": []}`), false)
+	assert.Error(t, err)
+}
+
+func TestBundleRoundTrip(t *testing.T) {
+	b := loadBundle(t)
+	require.NoError(t, b.Validate(nil))
+
+	for _, isYAML := range []bool{true, false} {
+		var buf bytes.Buffer
+		require.NoError(t, content.Encode(&buf, b, isYAML))
+		decoded, err := content.Decode(&buf, isYAML)
+		require.NoError(t, err)
+		require.NoError(t, decoded.Validate(nil))
+		assert.Equal(t, b, decoded)
+	}
+}
@@ -0,0 +1,115 @@
+package tests
+
+import (
+	"strings"
+	"testing"
+
+	"sonara-space/backend/internal/audio"
+	"sonara-space/backend/internal/eartraining"
+	"sonara-space/backend/internal/models"
+	"sonara-space/backend/internal/music"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+var difficulties = []string{models.DifficultyBeginner, models.DifficultyIntermediate, models.DifficultyAdvanced}
+
+func TestEarTrainingDeterministic(t *testing.T) {
+	for _, kind := range eartraining.Kinds {
+		a, err := eartraining.Session(kind, models.DifficultyAdvanced, 42, 10)
+		require.NoError(t, err)
+		b, err := eartraining.Session(kind, models.DifficultyAdvanced, 42, 10)
+		require.NoError(t, err)
+		assert.Equal(t, a, b, kind)
+
+		// Задание не зависит от длины сессии, только от номера
+		q, err := eartraining.Generate(kind, models.DifficultyAdvanced, 42, 7)
+		require.NoError(t, err)
+		assert.Equal(t, a[7], q)
+
+		other, err := eartraining.Session(kind, models.DifficultyAdvanced, 43, 10)
+		require.NoError(t, err)
+		assert.NotEqual(t, a, other, kind)
+	}
+
+	_, err := eartraining.Generate("timbre", models.DifficultyBeginner, 1, 0)
+	assert.ErrorIs(t, err, eartraining.ErrKind)
+	_, err = eartraining.Generate(eartraining.KindInterval, "expert", 1, 0)
+	assert.ErrorIs(t, err, eartraining.ErrDifficulty)
+}
+
+func TestEarTrainingQuestions(t *testing.T) {
+	for _, difficulty := range difficulties {
+		for _, kind := range eartraining.Kinds {
+			questions, err := eartraining.Session(kind, difficulty, 7, 30)
+			require.NoError(t, err)
+			for _, q := range questions {
+				require.NotEmpty(t, q.Events)
+				if kind == eartraining.KindDictation {
+					notes := strings.Fields(q.Answer)
+					assert.Len(t, notes, q.Length)
+					assert.Equal(t, q.Start, notes[0])
+					_, err := music.ParseKey(q.Key)
+					assert.NoError(t, err)
+				} else {
+					assert.Contains(t, q.Choices, q.Answer, "%s %s", difficulty, kind)
+				}
+			}
+		}
+	}
+
+	// На начальном уровне интервалы только восходящие и из короткого списка
+	questions, _ := eartraining.Session(eartraining.KindInterval, models.DifficultyBeginner, 1, 20)
+	for _, q := range questions {
+		assert.Equal(t, eartraining.Ascending, q.Direction)
+		assert.Equal(t, []string{"m3", "M3", "P4", "P5", "P8"}, q.Choices)
+	}
+}
+
+func TestEarTrainingCheck(t *testing.T) {
+	q, err := eartraining.Generate(eartraining.KindChordQuality, models.DifficultyIntermediate, 5, 0)
+	require.NoError(t, err)
+	v := eartraining.Check(q, " "+strings.ToUpper(q.Answer)+" ")
+	assert.True(t, v.Correct)
+	assert.Equal(t, 100.0, v.Score)
+	v = eartraining.Check(q, "sus4")
+	assert.False(t, v.Correct)
+	assert.Equal(t, q.Answer, v.Expected)
+
+	// Диктант засчитывается по нотам, энгармонизмы верны
+	d := eartraining.Question{Kind: eartraining.KindDictation, Answer: "C4 D4 Eb4 F4"}
+	assert.True(t, eartraining.Check(d, "C4 D4 D#4 F4").Correct)
+	v = eartraining.Check(d, "C4 D4 E4 F4")
+	assert.False(t, v.Correct)
+	assert.Equal(t, 75.0, v.Score)
+	assert.Equal(t, 50.0, eartraining.Check(d, "C4 D4").Score)
+	assert.Equal(t, 0.0, eartraining.Check(d, "").Score)
+}
+
+func TestEarTrainingRender(t *testing.T) {
+	// Восходящий интервал: вторая нота узнаётся анализатором
+	q, err := eartraining.Generate(eartraining.KindInterval, models.DifficultyBeginner, 3, 0)
+	require.NoError(t, err)
+	buf := eartraining.Render(q, 22050)
+	secondsPerBeat := 60 / float64(q.Tempo)
+	assert.InDelta(t, 3*secondsPerBeat, buf.Duration(), 0.2)
+
+	last := q.Events[len(q.Events)-1].MIDI[0]
+	note := music.NoteFromMIDI(last, false)
+	res := audio.AnalyzeNote(buf.Slice(secondsPerBeat+0.1, 2*secondsPerBeat), note, music.StandardA4)
+	assert.True(t, res.Match, "%s: %+v", note, res)
+
+	// Аккорд распознаётся
+	chord := audio.Synthesize([]audio.Tone{{MIDI: []int{57, 60, 64}, Duration: 1.5}}, 22050, music.StandardA4)
+	am, _ := music.ParseChord("Am")
+	assert.True(t, audio.AnalyzeChord(chord, am, music.StandardA4).Match)
+
+	// Ноты отделены атаками
+	melody := audio.Synthesize([]audio.Tone{
+		{MIDI: []int{60}, Start: 0, Duration: 0.5},
+		{MIDI: []int{64}, Start: 0.5, Duration: 0.5},
+		{MIDI: []int{67}, Start: 1, Duration: 0.5},
+	}, 22050, music.StandardA4)
+	assert.Len(t, audio.Onsets(melody), 3)
+}
@@ -0,0 +1,74 @@
+package tests
+
+import (
+	"bytes"
+	"testing"
+
+	"sonara-space/backend/internal/i18n"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestLocaleChain(t *testing.T) {
+	assert.Equal(t, []string{"kk", "ru", "en"}, i18n.Chain("kk-KZ"))
+	assert.Equal(t, []string{"ru", "en"}, i18n.Chain("ru"))
+	assert.Equal(t, []string{"en", "ru"}, i18n.Chain("EN"))
+	assert.Equal(t, []string{"de", "ru", "en"}, i18n.Chain("de"))
+
+	// Исходный текст урока на русском: переводы на ru и en казахскоязычному не нужны
+	assert.Equal(t, []string{"kk"}, i18n.Preferred(i18n.Chain("kk"), "ru"))
+	assert.Empty(t, i18n.Preferred(i18n.Chain("ru"), "ru"))
+	assert.Equal(t, []string{"en", "ru"}, i18n.Preferred(i18n.Chain("en"), "kk"))
+}
+
+func TestNegotiateLocale(t *testing.T) {
+	assert.Equal(t, []string{"de", "kk", "ru"}, i18n.ParseAcceptLanguage("ru;q=0.5, de-DE, kk;q=0.8, *;q=0.1"))
+
+	// Явный параметр важнее заголовка
+	assert.Equal(t, "en", i18n.Negotiate("en", "kk", "ru"))
+	// Первый поддерживаемый язык из заголовка
+	assert.Equal(t, "kk", i18n.Negotiate("", "de-DE,kk;q=0.9,ru;q=0.8", "ru"))
+	// Из заголовка ничего не подошло — язык профиля
+	assert.Equal(t, "kk", i18n.Negotiate("", "fr", "kk"))
+	assert.Equal(t, i18n.DefaultLocale, i18n.Negotiate("", "", ""))
+
+	_, ok := i18n.Match("", "fr, de")
+	assert.False(t, ok)
+}
+
+func TestTranslationKeys(t *testing.T) {
+	k, err := i18n.ParseKey("lesson.5.description")
+	require.NoError(t, err)
+	assert.Equal(t, i18n.Key{Kind: "lesson", ID: 5, Field: "description"}, k)
+	assert.Equal(t, "lesson.5.description", k.String())
+
+	for _, bad := range []string{"exercise.3.description", "lesson.x.title", "user.1.email", "lesson.1"} {
+		_, err := i18n.ParseKey(bad)
+		assert.Error(t, err, bad)
+	}
+}
+
+func TestTranslationFilesRoundTrip(t *testing.T) {
+	bundle := i18n.Bundle{TargetLocale: "kk", Units: []i18n.Unit{
+		{ID: "lesson.1.title", SourceLocale: "ru", Source: "Основы гитары", Target: "Гитара негіздері"},
+		{ID: "exercise.2.title", SourceLocale: "ru", Source: "Сыграйте аккорд Am & C", Note: "ожидается Am"},
+		{ID: "lesson.9.title", SourceLocale: "en", Source: "Songs", Target: "Әндер"},
+	}}
+
+	var xliff bytes.Buffer
+	require.NoError(t, i18n.WriteXLIFF(&xliff, bundle))
+	assert.Contains(t, xliff.String(), `source-language="en"`)
+	assert.Contains(t, xliff.String(), `Am &amp; C`)
+
+	decoded, err := i18n.ReadXLIFF(&xliff)
+	require.NoError(t, err)
+	assert.Equal(t, "kk", decoded.TargetLocale)
+	assert.ElementsMatch(t, bundle.Units, decoded.Units)
+
+	var js bytes.Buffer
+	require.NoError(t, i18n.WriteJSON(&js, bundle))
+	decoded, err = i18n.ReadJSON(&js)
+	require.NoError(t, err)
+	assert.Equal(t, bundle, decoded)
+}
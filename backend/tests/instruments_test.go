@@ -0,0 +1,111 @@
+package tests
+
+import (
+	"errors"
+	"testing"
+
+	"sonara-space/backend/internal/audio"
+	"sonara-space/backend/internal/instruments"
+	"sonara-space/backend/internal/music"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestInstrumentRegistry(t *testing.T) {
+	for _, inst := range instruments.All() {
+		if inst.Kind == instruments.KindKeys {
+			assert.Empty(t, inst.Tunings, inst.ID)
+			continue
+		}
+		require.NotEmpty(t, inst.Tunings, inst.ID)
+		for _, tuning := range inst.Tunings {
+			// Все струны строя — разбираемые ноты
+			assert.Len(t, tuning.OpenStrings(), len(tuning.Strings), "%s/%s", inst.ID, tuning.ID)
+		}
+		assert.NotEmpty(t, inst.Name([]string{"kk", "ru"}))
+	}
+
+	_, ok := instruments.Lookup("banjo")
+	assert.False(t, ok)
+	assert.Equal(t, "Guitar", mustInstrument(t, "guitar").Name([]string{"en"}))
+	assert.Equal(t, "Гитара", mustInstrument(t, "guitar").Name([]string{"de"}))
+}
+
+func TestInstrumentFromName(t *testing.T) {
+	assert.Equal(t, instruments.Guitar, instruments.FromName("Acoustic Guitar"))
+	assert.Equal(t, instruments.Bass, instruments.FromName("Electric Bass"))
+	assert.Equal(t, instruments.Bass, instruments.FromName("Бас-гитара"))
+	assert.Equal(t, instruments.Ukulele, instruments.FromName("Uke"))
+	assert.Equal(t, instruments.Piano, instruments.FromName("Фортепиано"))
+	assert.Empty(t, instruments.FromName("Voice"))
+}
+
+func TestInstrumentRanges(t *testing.T) {
+	rangeOf := func(s instruments.Setup) string {
+		r, err := s.Range()
+		require.NoError(t, err)
+		return r.String()
+	}
+	assert.Equal(t, "E2–E6", rangeOf(instruments.Setup{Instrument: "guitar"}))
+	assert.Equal(t, "D2–E6", rangeOf(instruments.Setup{Instrument: "guitar", Tuning: "drop-d"}))
+	assert.Equal(t, "F#2–E6", rangeOf(instruments.Setup{Instrument: "guitar", Capo: 2}))
+	assert.Equal(t, "E1–G4", rangeOf(instruments.Setup{Instrument: "bass"}))
+	// Реэнтрантный строй: нижняя нота — C4, а не G4
+	assert.Equal(t, "C4–C6", rangeOf(instruments.Setup{Instrument: "ukulele"}))
+	assert.Equal(t, "A0–C8", rangeOf(instruments.Setup{Instrument: "piano"}))
+
+	r, _ := instruments.Setup{Instrument: "guitar"}.Range()
+	assert.Equal(t, []int{1, 2}, r.Outside([]string{"E2", "D2", "F6", "X"}))
+}
+
+func TestInstrumentSetupErrors(t *testing.T) {
+	err := instruments.Setup{Instrument: "banjo"}.Validate()
+	assert.True(t, errors.Is(err, instruments.ErrUnknownInstrument))
+
+	err = instruments.Setup{Instrument: "guitar", Tuning: "gcea"}.Validate()
+	assert.True(t, errors.Is(err, instruments.ErrUnknownTuning))
+
+	err = instruments.Setup{Instrument: "piano", Tuning: "standard"}.Validate()
+	assert.True(t, errors.Is(err, instruments.ErrUnknownTuning))
+
+	err = instruments.Setup{Instrument: "guitar", Capo: 12}.Validate()
+	assert.True(t, errors.Is(err, instruments.ErrCapo))
+
+	err = instruments.Setup{Instrument: "bass", Capo: 1}.Validate()
+	assert.True(t, errors.Is(err, instruments.ErrCapo))
+
+	assert.NoError(t, instruments.Setup{Instrument: "ukulele", Tuning: "gcea", Capo: 3}.Validate())
+}
+
+func TestAnalyzeInInstrumentBand(t *testing.T) {
+	bandOf := func(s instruments.Setup) audio.Band {
+		r, err := s.Range()
+		require.NoError(t, err)
+		low, high := r.Frequencies(music.StandardA4)
+		return audio.Band{Low: low, High: high}
+	}
+
+	// Нижняя струна баса ниже обычной полосы анализа
+	e1, _ := music.ParseNote("E1")
+	res := audio.AnalyzeNoteInBand(tone(44100, 1, e1.Frequency(music.StandardA4)), e1, music.StandardA4,
+		bandOf(instruments.Setup{Instrument: "bass"}))
+	assert.True(t, res.Match)
+	assert.Equal(t, "E1", res.DetectedNote)
+
+	// Аккорд укулеле узнаётся в его полосе
+	c, _ := music.ParseChord("C")
+	freq := func(name string) float64 {
+		n, _ := music.ParseNote(name)
+		return n.Frequency(music.StandardA4)
+	}
+	chord := audio.AnalyzeChordInBand(tone(44100, 1.5, freq("G4"), freq("C4"), freq("E4"), freq("C5")), c,
+		music.StandardA4, bandOf(instruments.Setup{Instrument: "ukulele"}))
+	assert.True(t, chord.Match)
+}
+
+func mustInstrument(t *testing.T, id string) instruments.Instrument {
+	inst, ok := instruments.Lookup(id)
+	require.True(t, ok, id)
+	return inst
+}
@@ -0,0 +1,124 @@
+package tests
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"sonara-space/backend/internal/db"
+	"sonara-space/backend/internal/leaderboard"
+
+	"github.com/alicebob/miniredis/v2"
+	"github.com/redis/go-redis/v9"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestLeaderboardWeekStart(t *testing.T) {
+	moscow, err := time.LoadLocation("Europe/Moscow")
+	require.NoError(t, err)
+
+	// Воскресенье 22:00 UTC — в Москве уже понедельник
+	now := time.Date(2024, 3, 10, 22, 0, 0, 0, time.UTC)
+	assert.Equal(t, time.Date(2024, 3, 4, 0, 0, 0, 0, time.UTC), leaderboard.WeekStart(now, time.UTC))
+	start := leaderboard.WeekStart(now, moscow)
+	assert.Equal(t, time.Date(2024, 3, 11, 0, 0, 0, 0, moscow), start)
+	assert.Equal(t, time.Date(2024, 3, 10, 21, 0, 0, 0, time.UTC), start.UTC())
+
+	// Понедельник 00:00 — уже новая неделя
+	assert.Equal(t, start, leaderboard.WeekStart(start, moscow))
+}
+
+func TestLeaderboardRanked(t *testing.T) {
+	entries := leaderboard.Ranked(map[int64]int{1: 50, 2: 80, 3: 50, 4: 10, 5: 0})
+	assert.Equal(t, []leaderboard.Entry{
+		{Rank: 1, UserID: 2, Score: 80},
+		{Rank: 2, UserID: 1, Score: 50},
+		{Rank: 2, UserID: 3, Score: 50},
+		{Rank: 4, UserID: 4, Score: 10},
+	}, entries)
+	assert.Empty(t, leaderboard.Ranked(nil))
+
+	assert.NoError(t, leaderboard.Query{Metric: leaderboard.MetricExercises, Period: leaderboard.PeriodAll}.Validate())
+	assert.ErrorIs(t, leaderboard.Query{Metric: "coins", Period: leaderboard.PeriodAll}.Validate(), leaderboard.ErrQuery)
+	assert.ErrorIs(t, leaderboard.Query{Metric: leaderboard.MetricXP, Period: "month"}.Validate(), leaderboard.ErrQuery)
+}
+
+func TestRedisLeaderboard(t *testing.T) {
+	srv := miniredis.RunT(t)
+	client := redis.NewClient(&redis.Options{Addr: srv.Addr()})
+	defer client.Close()
+	board := &leaderboard.Redis{Client: client, PG: &leaderboard.Postgres{Loc: time.UTC}}
+	ctx := context.Background()
+
+	// Рейтинги уже построены: Postgres не нужен
+	srv.Set("leaderboard:built", "1")
+	srv.ZAdd("leaderboard:all:xp:any", 80, "2")
+	srv.ZAdd("leaderboard:all:xp:any", 50, "1")
+	srv.ZAdd("leaderboard:all:xp:any", 50, "3")
+	srv.ZAdd("leaderboard:all:xp:any", 10, "4")
+	q := leaderboard.Query{Metric: leaderboard.MetricXP, Period: leaderboard.PeriodAll}
+
+	entries, err := board.Top(ctx, q)
+	require.NoError(t, err)
+	assert.Equal(t, []leaderboard.Entry{
+		{Rank: 1, UserID: 2, Score: 80},
+		{Rank: 2, UserID: 3, Score: 50},
+		{Rank: 2, UserID: 1, Score: 50},
+		{Rank: 4, UserID: 4, Score: 10},
+	}, entries)
+
+	q.Limit = 2
+	entries, err = board.Top(ctx, q)
+	require.NoError(t, err)
+	assert.Len(t, entries, 2)
+
+	e, err := board.Position(ctx, q, 1)
+	require.NoError(t, err)
+	assert.Equal(t, leaderboard.Entry{Rank: 2, UserID: 1, Score: 50}, e)
+	// Ученика без очков нет в рейтинге — он после всех
+	e, err = board.Position(ctx, q, 9)
+	require.NoError(t, err)
+	assert.Equal(t, leaderboard.Entry{Rank: 5, UserID: 9}, e)
+
+	// Рейтинг группы строится по очкам её участников
+	q.Users, q.Limit = []int64{4, 1, 9}, 0
+	entries, err = board.Top(ctx, q)
+	require.NoError(t, err)
+	assert.Equal(t, []leaderboard.Entry{
+		{Rank: 1, UserID: 1, Score: 50},
+		{Rank: 2, UserID: 4, Score: 10},
+	}, entries)
+	e, err = board.Position(ctx, q, 9)
+	require.NoError(t, err)
+	assert.Equal(t, leaderboard.Entry{Rank: 3, UserID: 9}, e)
+
+	// Пустой рейтинг
+	entries, err = board.Top(ctx, leaderboard.Query{Metric: leaderboard.MetricExercises, Period: leaderboard.PeriodAll})
+	require.NoError(t, err)
+	assert.Empty(t, entries)
+}
+
+func TestRedisLeaderboardRebuild(t *testing.T) {
+	setupTestDB(t)
+	srv := miniredis.RunT(t)
+	client := redis.NewClient(&redis.Options{Addr: srv.Addr()})
+	defer client.Close()
+	board := &leaderboard.Redis{Client: client, PG: &leaderboard.Postgres{Pool: db.Pool, Loc: time.UTC}}
+	ctx := context.Background()
+
+	require.NoError(t, board.Rebuild(ctx))
+	assert.True(t, srv.Exists("leaderboard:built"))
+
+	// Рейтинги в Redis совпадают с посчитанными в Postgres
+	for _, metric := range []string{leaderboard.MetricXP, leaderboard.MetricExercises} {
+		for _, period := range []string{leaderboard.PeriodAll, leaderboard.PeriodWeek} {
+			q := leaderboard.Query{Metric: metric, Period: period}
+			want, err := board.PG.Top(ctx, q)
+			require.NoError(t, err)
+			got, err := board.Top(ctx, q)
+			require.NoError(t, err)
+			assert.ElementsMatch(t, want, got, "%s/%s", metric, period)
+		}
+	}
+}
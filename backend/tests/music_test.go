@@ -0,0 +1,155 @@
+package tests
+
+import (
+	"testing"
+
+	"sonara-space/backend/internal/music"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestParseNote(t *testing.T) {
+	cases := []struct {
+		in   string
+		midi int
+		name string
+	}{
+		{"C4", 60, "C4"},
+		{"A4", 69, "A4"},
+		{"F#3", 54, "F#3"},
+		{"Bb2", 46, "Bb2"},
+		{"C#-1", 1, "C#-1"},
+		{"Cb4", 59, "Cb4"},
+		{"B#3", 60, "B#3"},
+		{"E♭5", 75, "Eb5"},
+		{"Fx4", 67, "Fx4"},
+	}
+	for _, c := range cases {
+		n, err := music.ParseNote(c.in)
+		require.NoError(t, err, c.in)
+		assert.Equal(t, c.midi, n.MIDI(), c.in)
+		assert.Equal(t, c.name, n.String(), c.in)
+	}
+
+	for _, bad := range []string{"", "H4", "C", "Am", "C#", "C10", "Cbbb4", "A9"} {
+		_, err := music.ParseNote(bad)
+		assert.Error(t, err, bad)
+	}
+}
+
+func TestNoteFrequency(t *testing.T) {
+	a4, _ := music.ParseNote("A4")
+	assert.InDelta(t, 440.0, a4.Frequency(music.StandardA4), 1e-9)
+	assert.InDelta(t, 432.0, a4.Frequency(432), 1e-9)
+
+	c4, _ := music.ParseNote("C4")
+	assert.InDelta(t, 261.6256, c4.Frequency(music.StandardA4), 1e-4)
+	assert.InDelta(t, 60.0, music.FrequencyToMIDI(261.6256, music.StandardA4), 1e-4)
+	assert.InDelta(t, 0.0, c4.CentsOff(261.6256, music.StandardA4), 0.01)
+	assert.InDelta(t, 100.0, c4.CentsOff(277.1826, music.StandardA4), 0.01)
+}
+
+func TestNoteEnharmonicAndCanonical(t *testing.T) {
+	cs, _ := music.ParseNote("C#4")
+	db, _ := music.ParseNote("Db4")
+	assert.True(t, cs.Enharmonic(db))
+
+	cb, _ := music.ParseNote("Cb4")
+	assert.Equal(t, "B3", cb.Canonical().String())
+
+	bs, _ := music.ParseNote("B#3")
+	assert.Equal(t, "C4", bs.Canonical().String())
+
+	ebb, _ := music.ParseNote("Ebb4")
+	assert.Equal(t, "D4", ebb.Canonical().String())
+
+	assert.Equal(t, "Bb3", music.NoteFromMIDI(58, true).String())
+	assert.Equal(t, "A#3", music.NoteFromMIDI(58, false).String())
+
+	for _, m := range []int{-13, -12, -1, 0, 11, 12, 127} {
+		assert.Equal(t, m, music.NoteFromMIDI(m, false).MIDI(), m)
+	}
+	assert.Equal(t, "B-2", music.NoteFromMIDI(-1, false).String())
+	assert.Equal(t, "C-1", music.NoteFromMIDI(0, false).String())
+}
+
+func TestParseChord(t *testing.T) {
+	cases := []struct {
+		in        string
+		canonical string
+		tones     []string
+	}{
+		{"C", "C", []string{"C", "E", "G"}},
+		{"Am", "Am", []string{"A", "C", "E"}},
+		{"Bdim", "Bdim", []string{"B", "D", "F"}},
+		{"Caug", "Caug", []string{"C", "E", "G#"}},
+		{"G7", "G7", []string{"G", "B", "D", "F"}},
+		{"C7", "C7", []string{"C", "E", "G", "Bb"}},
+		{"CM7", "Cmaj7", []string{"C", "E", "G", "B"}},
+		{"Am7", "Am7", []string{"A", "C", "E", "G"}},
+		{"Bm7b5", "Bm7b5", []string{"B", "D", "F", "A"}},
+		{"Bbm", "Bbm", []string{"Bb", "Db", "F"}},
+		{"Dsus4", "Dsus4", []string{"D", "G", "A"}},
+		{"Asus", "Asus4", []string{"A", "D", "E"}},
+		{"G7sus4", "G7sus4", []string{"G", "C", "D", "F"}},
+		{"Cadd9", "Cadd9", []string{"C", "E", "G", "D"}},
+		{"C9", "C9", []string{"C", "E", "G", "Bb", "D"}},
+		{"C7b9", "C7b9", []string{"C", "E", "G", "Bb", "Db"}},
+		{"C6/9", "C69", []string{"C", "E", "G", "A", "D"}},
+		{"Ebmaj7", "Ebmaj7", []string{"Eb", "G", "Bb", "D"}},
+	}
+	for _, c := range cases {
+		chord, err := music.ParseChord(c.in)
+		require.NoError(t, err, c.in)
+		assert.Equal(t, c.canonical, chord.String(), c.in)
+
+		var tones []string
+		for _, tone := range chord.Tones() {
+			tones = append(tones, tone.String())
+		}
+		assert.ElementsMatch(t, c.tones, tones, c.in)
+	}
+
+	for _, bad := range []string{"", "H", "Cxyz", "Am/", "7"} {
+		_, err := music.ParseChord(bad)
+		assert.Error(t, err, bad)
+	}
+}
+
+func TestChordSlashAndPitchClasses(t *testing.T) {
+	chord, err := music.ParseChord("C/G")
+	require.NoError(t, err)
+	require.NotNil(t, chord.Bass)
+	assert.Equal(t, "G", chord.Bass.String())
+	assert.Equal(t, "C/G", chord.String())
+
+	amOverFSharp, err := music.ParseChord("Am/F#")
+	require.NoError(t, err)
+	set := amOverFSharp.PitchClasses()
+	assert.Equal(t, 4, set.Len())
+	assert.True(t, set.Has(6)) // F#
+
+	am, _ := music.ParseChord("Am")
+	assert.Equal(t, []music.PitchClass{0, 4, 9}, am.PitchClasses().Slice())
+}
+
+func TestChordEquivalence(t *testing.T) {
+	csm, _ := music.ParseChord("C#m")
+	dbm, _ := music.ParseChord("Dbm")
+	assert.True(t, csm.Equivalent(dbm))
+
+	add2, _ := music.ParseChord("Cadd2")
+	add9, _ := music.ParseChord("Cadd9")
+	assert.True(t, add2.Equivalent(add9))
+
+	c, _ := music.ParseChord("C")
+	cg, _ := music.ParseChord("C/G")
+	assert.False(t, c.Equivalent(cg))
+
+	gbm, _ := music.ParseChord("Gbm")
+	assert.Equal(t, "F#m", gbm.Transpose(0, false).String())
+
+	fbMaj, _ := music.ParseChord("Fb/Cb")
+	assert.Equal(t, "E/B", fbMaj.Canonical().String())
+}
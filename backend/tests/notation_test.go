@@ -0,0 +1,174 @@
+package tests
+
+import (
+	"archive/zip"
+	"bytes"
+	"errors"
+	"os"
+	"testing"
+
+	"sonara-space/backend/internal/instruments"
+	"sonara-space/backend/internal/models"
+	"sonara-space/backend/internal/music"
+	"sonara-space/backend/internal/notation"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func parseFixture(t *testing.T, name string) *notation.Score {
+	t.Helper()
+	data, err := os.ReadFile("testdata/" + name)
+	require.NoError(t, err)
+	score, err := notation.Parse(data)
+	require.NoError(t, err)
+	return score
+}
+
+func stepSymbols(seq models.Sequence) ([]string, []float64) {
+	var symbols []string
+	var beats []float64
+	for _, s := range seq.Steps {
+		symbols = append(symbols, s.Symbol)
+		beats = append(beats, s.Beats)
+	}
+	return symbols, beats
+}
+
+func TestIdentifyChord(t *testing.T) {
+	for _, tc := range []struct {
+		midi []int
+		want string
+	}{
+		{[]int{60, 64, 67}, "C"},
+		{[]int{57, 60, 64, 69}, "Am"},
+		{[]int{52, 55, 60}, "C/E"},
+		{[]int{55, 59, 62, 65}, "G7"},
+		{[]int{60, 64, 67, 69}, "C6"},
+		{[]int{57, 60, 64, 67}, "Am7"},
+	} {
+		chord, ok := music.IdentifyChord(tc.midi, false)
+		require.True(t, ok, tc.want)
+		assert.Equal(t, tc.want, chord.String())
+	}
+	_, ok := music.IdentifyChord([]int{60, 67}, false)
+	assert.False(t, ok)
+	_, ok = music.IdentifyChord([]int{60, 61, 62}, false)
+	assert.False(t, ok)
+}
+
+func TestParseMusicXML(t *testing.T) {
+	score := parseFixture(t, "birthday.musicxml")
+	assert.Equal(t, "Happy Birthday", score.Title)
+	assert.Equal(t, 100.0, score.Tempo)
+	assert.Equal(t, 3, score.BeatsPerBar)
+	assert.Equal(t, 4, score.BeatUnit)
+	assert.Contains(t, score.Warnings, "grace notes are skipped")
+
+	require.Len(t, score.Parts, 1)
+	part := &score.Parts[0]
+	assert.Equal(t, "guitar", part.Instrument)
+	assert.Equal(t, 24, part.Program)
+	assert.Equal(t, 9.0, part.End)
+
+	// Гитара звучит на октаву ниже записи; залигованная нота и пауза
+	// входят в длительность предыдущей ноты, из аккорда берётся верхний звук
+	melody, err := score.Sequence(part, "note", "")
+	require.NoError(t, err)
+	symbols, beats := stepSymbols(melody)
+	assert.Equal(t, []string{"G3", "G3", "A3", "G3", "C4", "B3", "G3"}, symbols)
+	assert.Equal(t, []float64{0.5, 0.5, 1, 1, 1, 4, 1}, beats)
+	assert.Equal(t, "Hap-", *melody.Steps[0].Lyric)
+	assert.Equal(t, "you", *melody.Steps[5].Lyric)
+	assert.Equal(t, "Happy Birthday", melody.SongTitle)
+	assert.Equal(t, 100, melody.Tempo)
+	assert.NoError(t, notation.CheckRange(instruments.Setup{Instrument: "guitar"}, &melody))
+
+	chords, err := score.Sequence(part, "", "Аккорды")
+	require.NoError(t, err)
+	assert.Equal(t, "chord", chords.StepType)
+	symbols, beats = stepSymbols(chords)
+	assert.Equal(t, []string{"C", "G7", "C"}, symbols)
+	assert.Equal(t, []float64{3, 3, 3}, beats)
+}
+
+func TestParseMXL(t *testing.T) {
+	data, err := os.ReadFile("testdata/birthday.musicxml")
+	require.NoError(t, err)
+
+	var buf bytes.Buffer
+	zw := zip.NewWriter(&buf)
+	w, err := zw.Create("META-INF/container.xml")
+	require.NoError(t, err)
+	w.Write([]byte(`<container><rootfiles><rootfile full-path="score/birthday.xml"/></rootfiles></container>`))
+	w, err = zw.Create("score/birthday.xml")
+	require.NoError(t, err)
+	w.Write(data)
+	require.NoError(t, zw.Close())
+
+	score, err := notation.Parse(buf.Bytes())
+	require.NoError(t, err)
+	assert.Equal(t, "Happy Birthday", score.Title)
+	require.Len(t, score.Parts, 1)
+}
+
+func TestParseMIDI(t *testing.T) {
+	score := parseFixture(t, "ode.mid")
+	assert.Equal(t, "Ode to Joy", score.Title)
+	assert.Equal(t, 120.0, score.Tempo)
+	assert.Equal(t, 4, score.BeatsPerBar)
+	assert.Equal(t, 4, score.BeatUnit)
+	assert.Contains(t, score.Warnings, "drum tracks are skipped")
+
+	require.Len(t, score.Parts, 1)
+	part := &score.Parts[0]
+	assert.Equal(t, "Piano", part.Name)
+	assert.Equal(t, "piano", part.Instrument)
+
+	// Нота, сыгранная чуть позже доли, встаёт на долю
+	melody, err := score.Sequence(part, "note", "")
+	require.NoError(t, err)
+	symbols, beats := stepSymbols(melody)
+	assert.Equal(t, []string{"E4", "E4", "F4", "G4", "G4", "F4", "E4", "D4"}, symbols)
+	assert.Equal(t, []float64{1, 1, 1, 1, 1, 1, 1, 1}, beats)
+	assert.Equal(t, "Freu-", *melody.Steps[0].Lyric)
+	assert.Equal(t, "de", *melody.Steps[1].Lyric)
+
+	// Аккорды определяются по одновременно звучащим нотам
+	chords, err := score.Sequence(part, "chord", "")
+	require.NoError(t, err)
+	symbols, beats = stepSymbols(chords)
+	assert.Equal(t, []string{"C", "G"}, symbols)
+	assert.Equal(t, []float64{4, 4}, beats)
+}
+
+func TestCheckRange(t *testing.T) {
+	seq := models.Sequence{StepType: "note", Steps: []models.SequenceStep{
+		{Symbol: "E2", Beats: 1}, {Symbol: "D2", Beats: 1}, {Symbol: "C7", Beats: 1},
+	}}
+	guitar := instruments.Setup{Instrument: "guitar"}
+	err := notation.CheckRange(guitar, &seq)
+	require.Error(t, err)
+	assert.True(t, errors.Is(err, notation.ErrOutOfRange))
+	assert.Contains(t, err.Error(), "steps[1] D2")
+	assert.Contains(t, err.Error(), "steps[2] C7")
+	assert.NotContains(t, err.Error(), "steps[0]")
+
+	// В строе Drop D нижняя струна — D2
+	err = notation.CheckRange(instruments.Setup{Instrument: "guitar", Tuning: "drop-d"}, &seq)
+	require.Error(t, err)
+	assert.NotContains(t, err.Error(), "steps[1]")
+
+	assert.NoError(t, notation.CheckRange(instruments.Setup{Instrument: "piano"}, &seq))
+	seq.StepType = "chord"
+	assert.NoError(t, notation.CheckRange(guitar, &seq))
+}
+
+func TestParseNotationErrors(t *testing.T) {
+	_, err := notation.Parse([]byte("MThd\x00\x00\x00\x06\x00\x00\x00\x01"))
+	assert.Error(t, err)
+	_, err = notation.Parse([]byte(`<score-timewise version="4.0"></score-timewise>`))
+	assert.Error(t, err)
+	_, err = notation.Parse([]byte("not a score"))
+	assert.Error(t, err)
+}
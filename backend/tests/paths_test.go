@@ -0,0 +1,60 @@
+package tests
+
+import (
+	"testing"
+
+	"sonara-space/backend/internal/paths"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestEvaluatePrerequisites(t *testing.T) {
+	lessons := []paths.Lesson{
+		{ID: 1, Percent: 75},
+		{ID: 2, Prerequisites: []paths.Prerequisite{{LessonID: 1, MinPercent: 80}}},
+		{ID: 3, Prerequisites: []paths.Prerequisite{{LessonID: 9, MinPercent: 50}}},
+	}
+	// Урок 9 вне курса, но его прогресс учитывается
+	percents := map[int64]float64{1: 75, 9: 50}
+
+	states := paths.Evaluate(lessons, percents)
+	require.Len(t, states, 3)
+	assert.False(t, states[0].Locked)
+	assert.Empty(t, states[0].Requirements)
+
+	assert.True(t, states[1].Locked, "75% < 80%")
+	require.Len(t, states[1].Requirements, 1)
+	assert.False(t, states[1].Requirements[0].Met)
+	assert.Equal(t, 75.0, states[1].Requirements[0].Percent)
+
+	assert.False(t, states[2].Locked, "50% пререквизита достаточно")
+
+	// После 80% первого урока второй открывается
+	percents[1] = 80
+	states = paths.Evaluate(lessons, percents)
+	assert.False(t, states[1].Locked)
+}
+
+func TestNextLesson(t *testing.T) {
+	states := []paths.State{
+		{LessonID: 1, Percent: 100, Completed: true},
+		{LessonID: 2},
+		{LessonID: 3, Percent: 40},
+		{LessonID: 4, Locked: true},
+	}
+	// Начатый урок важнее первого не начатого
+	next, ok := paths.Next(states)
+	require.True(t, ok)
+	assert.Equal(t, int64(3), next.LessonID)
+
+	states[2].Percent, states[2].Completed = 100, true
+	next, ok = paths.Next(states)
+	require.True(t, ok)
+	assert.Equal(t, int64(2), next.LessonID)
+
+	// Закрытые уроки не рекомендуются
+	states[1].Percent, states[1].Completed = 100, true
+	_, ok = paths.Next(states)
+	assert.False(t, ok)
+}
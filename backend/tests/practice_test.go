@@ -0,0 +1,54 @@
+package tests
+
+import (
+	"testing"
+	"time"
+
+	"sonara-space/backend/internal/practice"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestPracticeCredit(t *testing.T) {
+	last := time.Date(2024, 3, 4, 10, 0, 0, 0, time.UTC)
+
+	assert.Equal(t, 30*time.Second, practice.Credit(last, last.Add(30*time.Second)))
+	// Промежуток ровно в IdleTimeout ещё засчитывается
+	assert.Equal(t, practice.IdleTimeout, practice.Credit(last, last.Add(practice.IdleTimeout)))
+	// Ученик отвлёкся — промежуток не засчитывается целиком
+	assert.Zero(t, practice.Credit(last, last.Add(practice.IdleTimeout+time.Second)))
+	// Часы сдвинулись назад
+	assert.Zero(t, practice.Credit(last, last.Add(-time.Second)))
+	assert.Zero(t, practice.Credit(last, last))
+}
+
+func TestPracticeSummarize(t *testing.T) {
+	s := practice.Summarize(0, nil, nil, nil)
+	assert.Equal(t, practice.Summary{Exercises: []practice.ExerciseSummary{}}, s)
+
+	seconds := map[int64]int{1: 60, 2: 300, 3: 45}
+	titles := map[int64]string{1: "Гамма до мажор", 2: "Арпеджио", 3: "Интервалы"}
+	attempts := []practice.Attempt{
+		{ExerciseID: 1, Title: "Гамма до мажор", Score: 50},
+		{ExerciseID: 1, Title: "Гамма до мажор", Score: 90, Passed: true},
+		{ExerciseID: 2, Title: "Арпеджио", Score: 40},
+		{ExerciseID: 4, Title: "Ритм", Score: 85, Passed: true},
+	}
+	s = practice.Summarize(420, seconds, titles, attempts)
+
+	assert.Equal(t, 420, s.ActiveSeconds)
+	assert.Equal(t, 3, s.ExercisesAttempted)
+	assert.Equal(t, 4, s.Attempts)
+	assert.Equal(t, 2, s.Passed)
+	assert.Equal(t, 50.0, s.Accuracy)
+	assert.Equal(t, 66.3, s.AverageScore)
+
+	// По убыванию времени; упражнение без времени — в конце,
+	// упражнение без попыток тоже попадает в итог
+	assert.Equal(t, []practice.ExerciseSummary{
+		{ExerciseID: 2, Title: "Арпеджио", ActiveSeconds: 300, Attempts: 1, BestScore: 40},
+		{ExerciseID: 1, Title: "Гамма до мажор", ActiveSeconds: 60, Attempts: 2, Passed: 1, BestScore: 90},
+		{ExerciseID: 3, Title: "Интервалы", ActiveSeconds: 45},
+		{ExerciseID: 4, Title: "Ритм", Attempts: 1, Passed: 1, BestScore: 85},
+	}, s.Exercises)
+}
@@ -0,0 +1,341 @@
+package tests
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+
+	"sonara-space/backend/internal/auth"
+	"sonara-space/backend/internal/db"
+	"sonara-space/backend/internal/handlers"
+	"sonara-space/backend/internal/models"
+	"sonara-space/backend/internal/progress"
+	"sonara-space/backend/internal/versions"
+
+	"github.com/go-chi/chi/v5"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// asUser — запрос от имени пользователя, как после JWTMiddleware
+func asUser(r *http.Request, userID int64) *http.Request {
+	return r.WithContext(context.WithValue(r.Context(), auth.UserIDKey, userID))
+}
+
+func TestProgressPercent(t *testing.T) {
+	assert.Zero(t, progress.Percent(0, 0))
+	assert.Zero(t, progress.Percent(3, 0))
+	assert.Equal(t, 50.0, progress.Percent(2, 4))
+	assert.Equal(t, 100.0, progress.Percent(4, 4))
+}
+
+func TestProgressStartedInstruments(t *testing.T) {
+	at := time.Date(2024, 3, 4, 10, 0, 0, 0, time.UTC)
+	lessons := []progress.Lesson{
+		{ID: 1, Instrument: "guitar", Total: 4},
+		{ID: 2, Instrument: "piano", Total: 5},
+		{ID: 3, Instrument: "ukulele", Total: 3},
+	}
+
+	// Ничего не начато — в итог входят все уроки
+	assert.Equal(t, lessons, progress.Started(lessons))
+
+	// Начат урок гитары — остаются все уроки гитары, уроки других инструментов отбрасываются
+	lessons = append(lessons, progress.Lesson{ID: 4, Instrument: "guitar", Total: 2, Completed: 1, LastActivity: &at})
+	started := progress.Started(lessons)
+	assert.Len(t, started, 2)
+	assert.Equal(t, int64(1), started[0].ID)
+	assert.Equal(t, int64(4), started[1].ID)
+}
+
+func TestProgressSum(t *testing.T) {
+	early := time.Date(2024, 3, 4, 10, 0, 0, 0, time.UTC)
+	late := early.Add(48 * time.Hour)
+
+	assert.Equal(t, progress.Totals{}, progress.Sum(nil))
+
+	totals := progress.Sum([]progress.Lesson{
+		{ID: 1, Total: 4, Completed: 4, LastActivity: &early},
+		{ID: 2, Total: 4, Completed: 1, LastActivity: &late},
+		{ID: 3, Total: 0},
+		{ID: 4, Total: 2},
+	})
+	assert.Equal(t, 4, totals.Lessons)
+	assert.Equal(t, 1, totals.CompletedLessons) // урок без упражнений пройденным не считается
+	assert.Equal(t, 10, totals.TotalExercises)
+	assert.Equal(t, 5, totals.CompletedExercises)
+	assert.Equal(t, 50.0, totals.Percent)
+	assert.Equal(t, &late, totals.LastActivity)
+}
+
+func TestProgressNext(t *testing.T) {
+	assert.Equal(t, -1, progress.Next(nil))
+	assert.Equal(t, 0, progress.Next([]bool{false, false}))
+	// Пропущенное упражнение важнее следующих за пройденными
+	assert.Equal(t, 1, progress.Next([]bool{true, false, true, false}))
+	assert.Equal(t, -1, progress.Next([]bool{true, true}))
+}
+
+func TestLessonProgressInvalidID(t *testing.T) {
+	r := chi.NewRouter()
+	r.Get("/lessons/{id}/progress", handlers.GetLessonProgressHandler)
+
+	req := asUser(httptest.NewRequest("GET", "/lessons/abc/progress", nil), 1)
+	w := httptest.NewRecorder()
+	r.ServeHTTP(w, req)
+	assert.Equal(t, http.StatusBadRequest, w.Code)
+}
+
+// attemptAt — попытка через minutes минут после начала истории
+func attemptAt(id int64, minutes int, source, status string, passed bool, score float64) models.Attempt {
+	return models.Attempt{
+		ID: id, Source: source, Status: status, Passed: passed, Score: score,
+		CreatedAt: time.Date(2024, 3, 4, 10, minutes, 0, 0, time.UTC),
+	}
+}
+
+func TestProgressFromAttempts(t *testing.T) {
+	_, ok := progress.FromAttempts(nil)
+	assert.False(t, ok)
+
+	server := models.AttemptSourceServer
+	history := []models.Attempt{
+		// Порядок в срезе не важен: история сортируется по времени и ID
+		attemptAt(4, 30, server, models.StatusFailed, false, 40),
+		attemptAt(1, 0, server, models.StatusFailed, false, 55),
+		attemptAt(2, 10, server, models.StatusDone, true, 82),
+		attemptAt(3, 20, server, models.StatusDone, true, 91),
+	}
+	assert.Equal(t, 2, progress.Retries(history))
+
+	p, ok := progress.FromAttempts(history)
+	require.True(t, ok)
+	assert.Equal(t, models.StatusDone, p.Status) // неудача после зачёта его не отменяет
+	assert.True(t, p.Completed)
+	assert.Equal(t, 4, p.Attempts)
+	assert.Equal(t, 91.0, p.BestScore)
+	assert.Equal(t, 40.0, p.LastScore)
+	assert.True(t, p.ScoredByServer)
+	require.NotNil(t, p.CompletedAt)
+	assert.Equal(t, history[2].CreatedAt, *p.CompletedAt) // первое прохождение
+	assert.Equal(t, history[1].CreatedAt, p.FirstAt)
+	assert.Equal(t, history[0].CreatedAt, p.LastAt)
+
+	// Одинаковое время — порядок по ID
+	p, _ = progress.FromAttempts([]models.Attempt{
+		attemptAt(6, 0, server, models.StatusInProgress, false, 0),
+		attemptAt(5, 0, server, models.StatusFailed, false, 30),
+	})
+	assert.Equal(t, models.StatusInProgress, p.Status)
+	assert.Equal(t, 0.0, p.LastScore)
+}
+
+func TestProgressClientReports(t *testing.T) {
+	server, client := models.AttemptSourceServer, models.AttemptSourceClient
+
+	// "Сыграл" клиента зачитывает упражнение, но лучшую оценку не даёт
+	history := []models.Attempt{
+		attemptAt(1, 0, server, models.StatusFailed, false, 45),
+		attemptAt(2, 5, client, models.StatusDone, true, 100),
+	}
+	p, ok := progress.FromAttempts(history)
+	require.True(t, ok)
+	assert.True(t, p.Completed)
+	require.NotNil(t, p.CompletedAt)
+	assert.Equal(t, history[1].CreatedAt, *p.CompletedAt)
+	assert.Equal(t, models.StatusDone, p.Status)
+	assert.Equal(t, 45.0, p.BestScore)
+	assert.Equal(t, 100.0, p.LastScore)
+	assert.Equal(t, 2, p.Attempts)
+
+	// Только отметки клиента: оценено не сервером
+	p, _ = progress.FromAttempts([]models.Attempt{attemptAt(1, 0, client, models.StatusFailed, false, 0)})
+	assert.False(t, p.ScoredByServer)
+	assert.Equal(t, models.StatusFailed, p.Status)
+
+	// Неудачи клиента не считаются повторами для штрафа
+	assert.Zero(t, progress.Retries([]models.Attempt{
+		attemptAt(1, 0, client, models.StatusFailed, false, 0),
+		attemptAt(2, 1, client, models.StatusFailed, false, 0),
+	}))
+	assert.Equal(t, 1, progress.Retries([]models.Attempt{
+		attemptAt(1, 0, client, models.StatusFailed, false, 0),
+		attemptAt(2, 1, server, models.StatusFailed, false, 30),
+	}))
+
+	// Прогресс, перенесённый из старой таблицы, засчитывается
+	p, _ = progress.FromAttempts([]models.Attempt{attemptAt(1, 0, models.AttemptSourceLegacy, models.StatusDone, true, 80)})
+	assert.True(t, p.Completed)
+	assert.Equal(t, 80.0, p.BestScore)
+}
+
+// publishedExercise создаёт пользователя и опубликованный урок с одним
+// упражнением; после теста всё удаляется
+func publishedExercise(t *testing.T, exerciseType, expected string) (userID, exerciseID int64) {
+	ctx := context.Background()
+	suffix := strconv.FormatInt(time.Now().UnixNano(), 36)
+
+	require.NoError(t, db.Pool.QueryRow(ctx,
+		`INSERT INTO users (email, password_hash) VALUES ($1, 'x') RETURNING id`,
+		"progress-"+suffix+"@test.local").Scan(&userID))
+	var lessonID int64
+	require.NoError(t, db.Pool.QueryRow(ctx,
+		`INSERT INTO lessons (slug, title, instrument, status) VALUES ($1, 'Тест', 'guitar', 'draft') RETURNING id`,
+		"test-"+suffix).Scan(&lessonID))
+	require.NoError(t, db.Pool.QueryRow(ctx, `
+		INSERT INTO exercises (lesson_id, slug, title, expected, type, order_index)
+		VALUES ($1, 'test', 'Тест', $2, $3, 1) RETURNING id
+	`, lessonID, expected, exerciseType).Scan(&exerciseID))
+	_, _, err := versions.Publish(ctx, db.Pool, lessonID, nil, "", nil)
+	require.NoError(t, err)
+
+	t.Cleanup(func() {
+		db.Pool.Exec(ctx, `DELETE FROM lessons WHERE id = $1`, lessonID)
+		db.Pool.Exec(ctx, `DELETE FROM users WHERE id = $1`, userID)
+	})
+	return userID, exerciseID
+}
+
+func TestClientDoneCompletesExercise(t *testing.T) {
+	setupTestDB(t)
+	userID, exerciseID := publishedExercise(t, models.ExerciseTypeChord, "Am")
+
+	r := chi.NewRouter()
+	r.Post("/progress", handlers.UpdateProgressHandler)
+	report := func(status string) map[string]interface{} {
+		body := fmt.Sprintf(`{"exercise_id": %d, "status": %q}`, exerciseID, status)
+		req := asUser(httptest.NewRequest("POST", "/progress", strings.NewReader(body)), userID)
+		w := httptest.NewRecorder()
+		r.ServeHTTP(w, req)
+		require.Equal(t, http.StatusOK, w.Code, w.Body.String())
+		var resp map[string]interface{}
+		require.NoError(t, json.Unmarshal(w.Body.Bytes(), &resp))
+		return resp
+	}
+
+	assert.Equal(t, models.StatusFailed, report(models.StatusFailed)["status"])
+	// Предыдущая неудача клиента не считается повтором
+	resp := report(models.StatusFailed)
+	assert.Equal(t, 0.0, resp["scoring"].(map[string]interface{})["retry_penalty"])
+	assert.Equal(t, models.StatusDone, report(models.StatusDone)["status"])
+
+	var (
+		status    string
+		completed bool
+		attempts  int
+		bestScore float64
+		dueAt     *time.Time
+	)
+	require.NoError(t, db.Pool.QueryRow(context.Background(), `
+		SELECT status, completed, attempts, best_score::float8, due_at FROM progress
+		WHERE user_id = $1 AND exercise_id = $2
+	`, userID, exerciseID).Scan(&status, &completed, &attempts, &bestScore, &dueAt))
+	assert.Equal(t, models.StatusDone, status)
+	assert.True(t, completed)
+	assert.Equal(t, 3, attempts)
+	assert.Zero(t, bestScore)
+	// "Сыграл" ставит упражнение в расписание повторений
+	assert.NotNil(t, dueAt)
+
+	// Измерения клиента не сохраняются
+	body := fmt.Sprintf(`{"exercise_id": %d, "status": "done", "timing_deviation_ms": 12}`, exerciseID)
+	w := httptest.NewRecorder()
+	r.ServeHTTP(w, asUser(httptest.NewRequest("POST", "/progress", strings.NewReader(body)), userID))
+	require.Equal(t, http.StatusOK, w.Code)
+	var deviation *int
+	require.NoError(t, db.Pool.QueryRow(context.Background(), `
+		SELECT timing_deviation_ms FROM exercise_attempts
+		WHERE user_id = $1 AND exercise_id = $2 ORDER BY id DESC LIMIT 1
+	`, userID, exerciseID).Scan(&deviation))
+	assert.Nil(t, deviation)
+}
+
+func TestAttemptsOnlyForVisibleExercises(t *testing.T) {
+	setupTestDB(t)
+	ctx := context.Background()
+	userID, exerciseID := publishedExercise(t, models.ExerciseTypeChord, "Am")
+
+	r := chi.NewRouter()
+	r.Post("/progress", handlers.UpdateProgressHandler)
+	r.Post("/exercises/{id}/attempts", handlers.SubmitAttemptHandler)
+	submit := func(id int64) int {
+		req := asUser(httptest.NewRequest("POST", fmt.Sprintf("/exercises/%d/attempts", id), nil), userID)
+		w := httptest.NewRecorder()
+		r.ServeHTTP(w, req)
+		return w.Code
+	}
+	report := func(id int64) int {
+		body := fmt.Sprintf(`{"exercise_id": %d, "status": "done"}`, id)
+		req := asUser(httptest.NewRequest("POST", "/progress", strings.NewReader(body)), userID)
+		w := httptest.NewRecorder()
+		r.ServeHTTP(w, req)
+		return w.Code
+	}
+
+	// Опубликованное упражнение найдено: пустая запись — уже ошибка данных
+	assert.Equal(t, http.StatusBadRequest, submit(exerciseID))
+
+	// Упражнение черновика, ещё не вошедшее в версию
+	var draftID int64
+	require.NoError(t, db.Pool.QueryRow(ctx, `
+		INSERT INTO exercises (lesson_id, slug, title, expected, type, order_index)
+		SELECT lesson_id, 'draft', 'Черновик', 'C', 'chord', 2 FROM exercises WHERE id = $1
+		RETURNING id
+	`, exerciseID).Scan(&draftID))
+	assert.Equal(t, http.StatusNotFound, submit(draftID))
+	assert.Equal(t, http.StatusNotFound, report(draftID))
+
+	// Урок снят с публикации
+	_, err := db.Pool.Exec(ctx, `
+		UPDATE lessons SET status = 'archived' WHERE id = (SELECT lesson_id FROM exercises WHERE id = $1)
+	`, exerciseID)
+	require.NoError(t, err)
+	assert.Equal(t, http.StatusNotFound, submit(exerciseID))
+	assert.Equal(t, http.StatusNotFound, report(exerciseID))
+}
+
+func TestUserProgressInvalidInclude(t *testing.T) {
+	r := chi.NewRouter()
+	r.Get("/progress", handlers.GetUserProgressHandler)
+
+	req := asUser(httptest.NewRequest("GET", "/progress?include=everything", nil), 1)
+	w := httptest.NewRecorder()
+	r.ServeHTTP(w, req)
+	assert.Equal(t, http.StatusBadRequest, w.Code)
+}
+
+func TestUserProgressSummary(t *testing.T) {
+	setupTestDB(t)
+	userID, exerciseID := publishedExercise(t, models.ExerciseTypeChord, "Am")
+
+	r := chi.NewRouter()
+	r.Get("/progress", handlers.GetUserProgressHandler)
+	r.Post("/progress", handlers.UpdateProgressHandler)
+	body := fmt.Sprintf(`{"exercise_id": %d, "status": "done"}`, exerciseID)
+	w := httptest.NewRecorder()
+	r.ServeHTTP(w, asUser(httptest.NewRequest("POST", "/progress", strings.NewReader(body)), userID))
+	require.Equal(t, http.StatusOK, w.Code)
+
+	// Без параметра — прежний массив уроков
+	w = httptest.NewRecorder()
+	r.ServeHTTP(w, asUser(httptest.NewRequest("GET", "/progress", nil), userID))
+	require.Equal(t, http.StatusOK, w.Code)
+	var lessons []handlers.ProgressResponse
+	require.NoError(t, json.Unmarshal(w.Body.Bytes(), &lessons))
+	assert.NotEmpty(t, lessons)
+
+	w = httptest.NewRecorder()
+	r.ServeHTTP(w, asUser(httptest.NewRequest("GET", "/progress?include=summary", nil), userID))
+	require.Equal(t, http.StatusOK, w.Code)
+	var resp handlers.UserProgressResponse
+	require.NoError(t, json.Unmarshal(w.Body.Bytes(), &resp))
+	assert.Len(t, resp.Lessons, len(lessons))
+	assert.Equal(t, 1, resp.Summary.CompletedExercises)
+	assert.NotNil(t, resp.Summary.LastActivity)
+}
@@ -0,0 +1,75 @@
+package tests
+
+import (
+	"testing"
+	"time"
+
+	"sonara-space/backend/internal/reviews"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func day(d int) time.Time {
+	return time.Date(2024, 3, d, 18, 30, 0, 0, time.UTC)
+}
+
+func TestReviewQuality(t *testing.T) {
+	assert.Equal(t, 5, reviews.Quality(97, true))
+	assert.Equal(t, 4, reviews.Quality(90, true))
+	assert.Equal(t, 3, reviews.Quality(72, true))
+	assert.Equal(t, 2, reviews.Quality(60, false))
+	assert.Equal(t, 1, reviews.Quality(10, false))
+	assert.Equal(t, 0, reviews.Quality(0, false))
+
+	// Отметка клиента без записи — не выше прохождения с трудом
+	assert.Equal(t, reviews.PassQuality, reviews.ReportedQuality(true))
+	assert.Equal(t, 0, reviews.ReportedQuality(false))
+	s, changed := reviews.Next(reviews.Schedule{}, reviews.ReportedQuality(true), day(1))
+	assert.True(t, changed)
+	require.NotNil(t, s.DueAt)
+	assert.Equal(t, 1, s.IntervalDays)
+	assert.Less(t, s.Ease, reviews.DefaultEase)
+}
+
+func TestReviewSchedule(t *testing.T) {
+	// Неудача до первого прохождения в расписание не ставит
+	s, changed := reviews.Next(reviews.Schedule{}, 2, day(1))
+	assert.False(t, changed)
+	assert.Nil(t, s.DueAt)
+
+	// Первое прохождение — завтра, затем через 6 дней, затем интервал × ease
+	s, changed = reviews.Next(s, 4, day(1))
+	require.True(t, changed)
+	assert.Equal(t, 1, s.IntervalDays)
+	assert.Equal(t, time.Date(2024, 3, 2, 0, 0, 0, 0, time.UTC), *s.DueAt)
+	assert.Equal(t, 2.5, s.Ease)
+
+	s, _ = reviews.Next(s, 5, day(2))
+	assert.Equal(t, 6, s.IntervalDays)
+	assert.Equal(t, 2.6, s.Ease)
+	assert.Equal(t, time.Date(2024, 3, 8, 0, 0, 0, 0, time.UTC), *s.DueAt)
+
+	// Удачная попытка до срока расписание не меняет
+	early, changed := reviews.Next(s, 5, day(5))
+	assert.False(t, changed)
+	assert.Equal(t, s, early)
+
+	s, _ = reviews.Next(s, 4, day(8))
+	assert.Equal(t, 3, s.Repetitions)
+	assert.Equal(t, 16, s.IntervalDays)
+
+	// Провал возвращает упражнение на завтра и снижает лёгкость
+	lapsed, changed := reviews.Next(s, 1, day(10))
+	require.True(t, changed)
+	assert.Equal(t, 0, lapsed.Repetitions)
+	assert.Equal(t, 1, lapsed.IntervalDays)
+	assert.Equal(t, time.Date(2024, 3, 11, 0, 0, 0, 0, time.UTC), *lapsed.DueAt)
+	assert.Less(t, lapsed.Ease, s.Ease)
+
+	// Лёгкость не опускается ниже MinEase
+	for i := 0; i < 10; i++ {
+		lapsed, _ = reviews.Next(lapsed, 0, day(12+i))
+	}
+	assert.Equal(t, reviews.MinEase, lapsed.Ease)
+}
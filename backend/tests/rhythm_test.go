@@ -0,0 +1,104 @@
+package tests
+
+import (
+	"math"
+	"testing"
+
+	"sonara-space/backend/internal/audio"
+	"sonara-space/backend/internal/models"
+	"sonara-space/backend/internal/rhythm"
+	"sonara-space/backend/internal/scoring"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// plucks синтезирует затухающие удары на 220 Гц в заданные моменты (секунды)
+func plucks(sampleRate int, seconds float64, at ...float64) *audio.Buffer {
+	b := &audio.Buffer{SampleRate: sampleRate, Samples: make([]float64, int(seconds*float64(sampleRate)))}
+	for _, start := range at {
+		from := int(start * float64(sampleRate))
+		for i := from; i < len(b.Samples); i++ {
+			t := float64(i-from) / float64(sampleRate)
+			b.Samples[i] += 0.6 * math.Exp(-t*12) * math.Sin(2*math.Pi*220*t)
+		}
+	}
+	return b
+}
+
+var defaultWindows = rhythm.Windows{Perfect: models.DefaultPerfectMs, Good: models.DefaultGoodMs, Miss: models.DefaultMissMs}
+
+func TestRhythmPattern(t *testing.T) {
+	r := models.Rhythm{Tempo: 120, BeatsPerBar: 4, BeatUnit: 4,
+		Pattern: []models.RhythmHit{{Beats: 1, Accent: true}, {Beats: 0.5}, {Beats: 0.5}, {Beats: 1, Rest: true}, {Beats: 1}}}
+	expected, err := r.Validate()
+	require.NoError(t, err)
+	assert.Equal(t, "!1 0.5 0.5 r1 1", expected)
+	assert.Equal(t, []float64{0, 500, 750, 1500}, r.Onsets())
+	assert.Equal(t, models.DefaultMissMs, r.MissMs)
+
+	pattern, err := models.ParseRhythmPattern(expected)
+	require.NoError(t, err)
+	assert.Equal(t, r.Pattern, pattern)
+
+	for _, bad := range []models.Rhythm{
+		{Tempo: 0, BeatsPerBar: 4, BeatUnit: 4, Pattern: r.Pattern},
+		{Tempo: 90, BeatsPerBar: 4, BeatUnit: 3, Pattern: r.Pattern},
+		{Tempo: 90, BeatsPerBar: 4, BeatUnit: 4, Pattern: []models.RhythmHit{{Beats: 1, Rest: true}}},
+		{Tempo: 90, BeatsPerBar: 4, BeatUnit: 4, Pattern: r.Pattern, PerfectMs: 100, GoodMs: 50},
+	} {
+		_, err := bad.Validate()
+		assert.Error(t, err)
+	}
+}
+
+func TestRhythmMatch(t *testing.T) {
+	expected := []float64{0, 500, 1000, 1500}
+	res := rhythm.Match(expected, []float64{10, 460, 1120, 1900, 2000}, defaultWindows)
+
+	require.Len(t, res.Hits, 4)
+	assert.Equal(t, rhythm.Perfect, res.Hits[0].Verdict)
+	assert.Empty(t, res.Hits[0].Timing)
+	assert.Equal(t, rhythm.Good, res.Hits[1].Verdict)
+	assert.Equal(t, rhythm.Early, res.Hits[1].Timing)
+	assert.InDelta(t, -40, *res.Hits[1].DeviationMs, 0.001)
+	assert.Equal(t, rhythm.Off, res.Hits[2].Verdict)
+	assert.Equal(t, rhythm.Late, res.Hits[2].Timing)
+	// 1900 ближе к пропущенному удару 1500, чем 2000, но дальше окна промаха
+	assert.Equal(t, rhythm.Missed, res.Hits[3].Verdict)
+	assert.Nil(t, res.Hits[3].PlayedMs)
+	assert.Equal(t, []float64{1900, 2000}, res.Extra)
+
+	// Лишние удары — шаги с нулём
+	steps := res.Steps()
+	assert.Len(t, steps, 6)
+	assert.Equal(t, 100.0, steps[0])
+	assert.Equal(t, 0.0, steps[5])
+	result := scoring.Evaluate(models.ExerciseTypeRhythm, scoring.Metrics{Steps: steps})
+	assert.False(t, result.Passed)
+
+	// Ровная игра со сдвигом на 20 мс проходит
+	res = rhythm.Match(expected, []float64{20, 520, 1020, 1520}, defaultWindows)
+	assert.Equal(t, 20.0, res.MeanDeviationMs)
+	result = scoring.Evaluate(models.ExerciseTypeRhythm, scoring.Metrics{Steps: res.Steps()})
+	assert.True(t, result.Passed)
+	assert.Equal(t, 100.0, result.Score)
+}
+
+func TestRhythmAlign(t *testing.T) {
+	aligned := rhythm.Align([]float64{1300, 1800, 2310}, 0)
+	assert.Equal(t, []float64{0, 500, 1010}, aligned)
+	assert.Nil(t, rhythm.Align(nil, 0))
+}
+
+func TestDetectOnsets(t *testing.T) {
+	at := []float64{0.25, 0.75, 1.0, 1.5, 2.25}
+	onsets := audio.Onsets(plucks(22050, 3, at...))
+	require.Len(t, onsets, len(at))
+	for i, want := range at {
+		assert.InDelta(t, want, onsets[i], 0.01)
+	}
+
+	// Тишина — без ударов
+	assert.Empty(t, audio.Onsets(&audio.Buffer{SampleRate: 22050, Samples: make([]float64, 22050)}))
+}
@@ -0,0 +1,123 @@
+package tests
+
+import (
+	"testing"
+
+	"sonara-space/backend/internal/models"
+	"sonara-space/backend/internal/scoring"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func floatPtr(v float64) *float64 { return &v }
+
+func TestPitchAccuracy(t *testing.T) {
+	// До ±10 центов — идеально
+	assert.Equal(t, 100.0, scoring.PitchAccuracy(scoring.Pitch{Correct: true, CentsOff: -8}))
+	// Дальше линейно до 50 на границе полутона
+	assert.Equal(t, 75.0, scoring.PitchAccuracy(scoring.Pitch{Correct: true, CentsOff: 30}))
+	assert.Equal(t, 50.0, scoring.PitchAccuracy(scoring.Pitch{Correct: true, CentsOff: -50}))
+	// Не та нота — ноль, как бы близко она ни была
+	assert.Zero(t, scoring.PitchAccuracy(scoring.Pitch{Correct: false, CentsOff: 0}))
+}
+
+func TestChordAccuracy(t *testing.T) {
+	assert.Equal(t, 100.0, scoring.ChordAccuracy(scoring.Chord{Recognized: true, Completeness: 1}))
+	assert.InDelta(t, 86.67, scoring.ChordAccuracy(scoring.Chord{Recognized: true, Completeness: 2.0 / 3}), 0.01)
+	// Нераспознанный аккорд не дотягивает до порога, даже если часть звуков прозвучала
+	assert.InDelta(t, 33.33, scoring.ChordAccuracy(scoring.Chord{Recognized: false, Completeness: 2.0 / 3}), 0.01)
+	assert.Less(t, scoring.ChordAccuracy(scoring.Chord{Recognized: false, Completeness: 1}), scoring.Threshold(models.ExerciseTypeChord))
+}
+
+func TestTimingAccuracy(t *testing.T) {
+	assert.Equal(t, 100.0, scoring.TimingAccuracy(-25))
+	assert.Equal(t, 50.0, scoring.TimingAccuracy(165))
+	assert.Zero(t, scoring.TimingAccuracy(400))
+}
+
+func TestThresholdsByType(t *testing.T) {
+	assert.Equal(t, 80.0, scoring.Threshold(models.ExerciseTypeNote))
+	assert.Equal(t, 70.0, scoring.Threshold(models.ExerciseTypeChord))
+	assert.Equal(t, 65.0, scoring.Threshold(models.ExerciseTypeSequence))
+	assert.Equal(t, scoring.DefaultThreshold, scoring.Threshold("unknown"))
+}
+
+func TestEvaluate(t *testing.T) {
+	// Чистая нота с первой попытки
+	res := scoring.Evaluate(models.ExerciseTypeNote, scoring.Metrics{
+		Pitch: &scoring.Pitch{Correct: true, CentsOff: 3},
+	})
+	assert.True(t, res.Passed)
+	assert.Equal(t, 100.0, res.Score)
+	assert.Nil(t, res.Timing)
+
+	// Ритм занимает 20% оценки
+	res = scoring.Evaluate(models.ExerciseTypeChord, scoring.Metrics{
+		Chord:             &scoring.Chord{Recognized: true, Completeness: 1},
+		TimingDeviationMs: floatPtr(165),
+	})
+	require.NotNil(t, res.Timing)
+	assert.Equal(t, 90.0, res.Score)
+
+	// Песня — среднее по шагам
+	res = scoring.Evaluate(models.ExerciseTypeSequence, scoring.Metrics{Steps: []float64{100, 60, 50}})
+	assert.Equal(t, 70.0, res.Accuracy)
+	assert.True(t, res.Passed)
+
+	// Одна и та же нота (75 баллов) проходит как шаг песни, но не как отдельное упражнение
+	res = scoring.Evaluate(models.ExerciseTypeNote, scoring.Metrics{
+		Pitch: &scoring.Pitch{Correct: true, CentsOff: 30},
+	})
+	assert.False(t, res.Passed)
+
+	// Без измерений оценка нулевая
+	res = scoring.Evaluate(models.ExerciseTypeChord, scoring.Metrics{})
+	assert.False(t, res.Passed)
+	assert.Zero(t, res.Score)
+}
+
+func TestRetryPenalty(t *testing.T) {
+	metrics := scoring.Metrics{Chord: &scoring.Chord{Recognized: true, Completeness: 1}, Retries: 3}
+	res := scoring.Evaluate(models.ExerciseTypeChord, metrics)
+	assert.Equal(t, 6.0, res.Penalty)
+	assert.Equal(t, 94.0, res.Score)
+
+	// Штраф ограничен сверху
+	metrics.Retries = 20
+	res = scoring.Evaluate(models.ExerciseTypeChord, metrics)
+	assert.Equal(t, scoring.MaxRetryPenalty, res.Penalty)
+	assert.Equal(t, 90.0, res.Score)
+
+	// Штраф не отнимает зачёт: пройденная попытка не опускается ниже порога
+	metrics.Chord.Completeness = 0.5 // 80 баллов
+	res = scoring.Evaluate(models.ExerciseTypeChord, metrics)
+	assert.True(t, res.Passed)
+	assert.Equal(t, 70.0, res.Score)
+	assert.Equal(t, 10.0, res.Penalty)
+
+	metrics.Chord.Completeness = 0.25 // 70 баллов, ровно порог
+	res = scoring.Evaluate(models.ExerciseTypeChord, metrics)
+	assert.True(t, res.Passed)
+	assert.Equal(t, 70.0, res.Score)
+	assert.Zero(t, res.Penalty)
+}
+
+func TestReported(t *testing.T) {
+	// Отметка "сыграл" без записи — ровно проходной балл, а не 100
+	res := scoring.Reported(models.ExerciseTypeNote, true, 0)
+	assert.True(t, res.Passed)
+	assert.Equal(t, 80.0, res.Score)
+
+	res = scoring.Reported(models.ExerciseTypeNote, false, 2)
+	assert.False(t, res.Passed)
+	assert.Zero(t, res.Score)
+}
+
+func TestValidStatus(t *testing.T) {
+	for _, s := range []string{models.StatusInProgress, models.StatusDone, models.StatusFailed} {
+		assert.True(t, models.ValidStatus(s), s)
+	}
+	assert.False(t, models.ValidStatus("completed"))
+	assert.False(t, models.ValidStatus(""))
+}
@@ -0,0 +1,98 @@
+package tests
+
+import (
+	"testing"
+
+	"sonara-space/backend/internal/handlers"
+	"sonara-space/backend/internal/models"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func lyric(s string) *string { return &s }
+
+func TestSequenceValidate(t *testing.T) {
+	seq := models.Sequence{
+		SongTitle: "  Happy Birthday ", StepType: models.ExerciseTypeChord,
+		Tempo: 90, BeatsPerBar: 3, BeatUnit: 4,
+		Steps: []models.SequenceStep{{Symbol: " Am", Beats: 3}, {Symbol: "C", Beats: 3}, {Symbol: "G ", Beats: 3}},
+	}
+	expected, err := seq.Validate()
+	require.NoError(t, err)
+	assert.Equal(t, "Am C G", expected)
+	assert.Equal(t, "Happy Birthday", seq.SongTitle)
+
+	bad := seq
+	bad.Steps = []models.SequenceStep{{Symbol: "Am", Beats: 0}}
+	_, err = bad.Validate()
+	assert.Error(t, err)
+
+	bad = seq
+	bad.Steps = []models.SequenceStep{{Symbol: "C4", Beats: 1}} // нота в аккордовой песне
+	_, err = bad.Validate()
+	assert.Error(t, err)
+
+	bad = seq
+	bad.BeatUnit = 3
+	_, err = bad.Validate()
+	assert.Error(t, err)
+
+	bad = seq
+	bad.Steps = nil
+	_, err = bad.Validate()
+	assert.Error(t, err)
+}
+
+func TestNewSequenceView(t *testing.T) {
+	seq := &models.Sequence{
+		SongTitle: "Twinkle", StepType: models.ExerciseTypeNote,
+		Tempo: 120, BeatsPerBar: 3, BeatUnit: 4,
+		Steps: []models.SequenceStep{
+			{Symbol: "C4", Beats: 1, Lyric: lyric("Twin-")},
+			{Symbol: "C4", Beats: 1.5},
+			{Symbol: "G4", Beats: 0.5},
+			{Symbol: "A4", Beats: 2},
+		},
+	}
+	view := handlers.NewSequenceView(seq)
+
+	assert.Equal(t, "3/4", view.TimeSignature)
+	assert.Equal(t, 5.0, view.TotalBeats)
+	require.Len(t, view.Steps, 4)
+
+	// При 120 ударах в минуту доля — 500 мс
+	starts, bars, durations := []float64{}, []int{}, []int{}
+	for _, s := range view.Steps {
+		starts, bars, durations = append(starts, s.StartBeat), append(bars, s.Bar), append(durations, s.DurationMs)
+	}
+	assert.Equal(t, []float64{0, 1, 2.5, 3}, starts)
+	assert.Equal(t, []int{1, 1, 1, 2}, bars)
+	assert.Equal(t, []int{500, 750, 250, 1000}, durations)
+	assert.Equal(t, "Twin-", *view.Steps[0].Lyric)
+	assert.Nil(t, view.Steps[1].Lyric)
+}
+
+func TestExerciseViews(t *testing.T) {
+	exercises := []models.Exercise{
+		{ID: 1, Type: models.ExerciseTypeChord, Expected: "Am"},
+		{ID: 2, Type: models.ExerciseTypeSequence, Expected: "C G"},
+		{ID: 3, Type: models.ExerciseTypeSequence, Expected: "F"},
+	}
+	sequences := map[int64]*models.Sequence{
+		2: {ExerciseID: 2, SongTitle: "Song", StepType: models.ExerciseTypeChord, Tempo: 60, BeatsPerBar: 4, BeatUnit: 4,
+			Steps: []models.SequenceStep{{Symbol: "C", Beats: 2}, {Symbol: "G", Beats: 2}}},
+	}
+
+	views := handlers.ExerciseViews(exercises, sequences, nil)
+	require.Len(t, views, 3)
+	assert.Nil(t, views[0].Sequence)
+	require.NotNil(t, views[1].Sequence)
+	assert.Equal(t, "Song", views[1].Sequence.SongTitle)
+	assert.Equal(t, 2000, views[1].Sequence.Steps[0].DurationMs)
+	// Последовательность не найдена — упражнение отдаётся без неё
+	assert.Nil(t, views[2].Sequence)
+	assert.Nil(t, views[2].Rhythm)
+
+	assert.Empty(t, handlers.ExerciseViews(nil, nil, nil))
+}
@@ -0,0 +1,53 @@
+package tests
+
+import (
+	"testing"
+
+	"sonara-space/backend/internal/skills"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestSkillMasteryLevels(t *testing.T) {
+	m := skills.Evaluate(nil)
+	assert.Equal(t, skills.LevelNotStarted, m.Level)
+	assert.Equal(t, 0, m.Exercises)
+
+	// Упражнения без попыток — навык не начат
+	m = skills.Evaluate([]skills.Exercise{{}, {}})
+	assert.Equal(t, skills.LevelNotStarted, m.Level)
+	assert.Equal(t, 2, m.Exercises)
+
+	// Непройденное упражнение даёт половину оценки, не начатое — ноль
+	m = skills.Evaluate([]skills.Exercise{
+		{Attempts: 3, Completed: true, BestScore: 90},
+		{Attempts: 2, BestScore: 60},
+		{},
+	})
+	assert.InDelta(t, 40.0, m.Percent, 1e-9)
+	assert.Equal(t, skills.LevelLearning, m.Level)
+	assert.Equal(t, 2, m.Practiced)
+	assert.Equal(t, 1, m.Completed)
+
+	m = skills.Evaluate([]skills.Exercise{{Attempts: 1, BestScore: 30}})
+	assert.Equal(t, skills.LevelNovice, m.Level)
+}
+
+func TestSkillMasteredRequiresAllExercises(t *testing.T) {
+	all := []skills.Exercise{
+		{Attempts: 1, Completed: true, BestScore: 95},
+		{Attempts: 4, Completed: true, BestScore: 100},
+	}
+	m := skills.Evaluate(all)
+	assert.Equal(t, skills.LevelMastered, m.Level)
+	assert.InDelta(t, 97.5, m.Percent, 1e-9)
+
+	// Высокий процент без прохождения всех упражнений — только "уверенно"
+	m = skills.Evaluate(append(all, skills.Exercise{Attempts: 5, BestScore: 100}))
+	assert.InDelta(t, 81.67, m.Percent, 0.01)
+	assert.Equal(t, skills.LevelProficient, m.Level)
+
+	// Оценки вне 0..100 обрезаются
+	m = skills.Evaluate([]skills.Exercise{{Attempts: 1, Completed: true, BestScore: 150}})
+	assert.Equal(t, 100.0, m.Percent)
+}
@@ -0,0 +1,86 @@
+package tests
+
+import (
+	"testing"
+	"time"
+
+	"sonara-space/backend/internal/models"
+	"sonara-space/backend/internal/stats"
+
+	"github.com/stretchr/testify/assert"
+)
+
+// march — дата марта 2024 (4 марта — понедельник)
+func march(days ...int) []time.Time {
+	var res []time.Time
+	for _, d := range days {
+		res = append(res, time.Date(2024, 3, d, 0, 0, 0, 0, time.UTC))
+	}
+	return res
+}
+
+func TestStreaks(t *testing.T) {
+	today := march(7)[0]
+
+	s := stats.Streaks(nil, today)
+	assert.Equal(t, stats.Streak{FreezesLeft: 1}, s)
+
+	// Сегодня ещё не занимался — серия не прервана
+	s = stats.Streaks(march(4, 5, 6), today)
+	assert.Equal(t, 3, s.Current)
+	assert.False(t, s.PracticedToday)
+
+	// Повторы и время дня не важны, будущие дни не считаются
+	s = stats.Streaks(append(march(6, 7, 7, 9), time.Date(2024, 3, 5, 23, 59, 0, 0, time.UTC)), today)
+	assert.Equal(t, 3, s.Current)
+	assert.True(t, s.PracticedToday)
+
+	// Один пропуск в неделю сжигает заморозку, но не добавляет к серии
+	s = stats.Streaks(march(4, 5, 7), today)
+	assert.Equal(t, 3, s.Current)
+	assert.Equal(t, 0, s.FreezesLeft)
+
+	// Второй пропуск на той же неделе серию обрывает
+	s = stats.Streaks(march(4, 6, 8), march(8)[0])
+	assert.Equal(t, 1, s.Current)
+	assert.Equal(t, 2, s.Longest)
+
+	// На новой неделе заморозка снова есть
+	s = stats.Streaks(march(1, 2, 4, 6, 7), today)
+	assert.Equal(t, 5, s.Current)
+
+	// Два пропуска подряд в одной неделе
+	s = stats.Streaks(march(4, 5, 8), march(8)[0])
+	assert.Equal(t, 1, s.Current)
+	assert.Equal(t, 2, s.Longest)
+}
+
+func TestLevels(t *testing.T) {
+	assert.Equal(t, stats.Level{Level: 1, XP: 0, LevelXP: 0, NextLevelXP: 100}, stats.LevelFor(0))
+	assert.Equal(t, 1, stats.LevelFor(99).Level)
+	assert.Equal(t, stats.Level{Level: 2, XP: 100, LevelXP: 100, NextLevelXP: 300}, stats.LevelFor(100))
+	assert.Equal(t, 4, stats.LevelFor(999).Level)
+	assert.Equal(t, 5, stats.LevelFor(1000).Level)
+
+	assert.Less(t, stats.XPFor(models.DifficultyBeginner), stats.XPFor(models.DifficultyIntermediate))
+	assert.Less(t, stats.XPFor(models.DifficultyIntermediate), stats.XPFor(models.DifficultyAdvanced))
+}
+
+func TestGoals(t *testing.T) {
+	g := stats.GoalProgress(stats.GoalMinutes, 15, 20, 1)
+	assert.Equal(t, stats.Goal{Kind: stats.GoalMinutes, Target: 15, Progress: 20, Completed: true}, g)
+	g = stats.GoalProgress(stats.GoalExercises, 3, 20, 2)
+	assert.Equal(t, 2, g.Progress)
+	assert.False(t, g.Completed)
+
+	assert.NoError(t, stats.ValidGoal(stats.GoalExercises, 5))
+	assert.ErrorIs(t, stats.ValidGoal("hours", 1), stats.ErrGoal)
+	assert.ErrorIs(t, stats.ValidGoal(stats.GoalMinutes, 0), stats.ErrGoal)
+	assert.ErrorIs(t, stats.ValidGoal(stats.GoalMinutes, 2000), stats.ErrGoal)
+
+	assert.True(t, stats.ValidTimezone("Europe/Moscow"))
+	assert.True(t, stats.ValidTimezone("UTC"))
+	assert.False(t, stats.ValidTimezone("Mars/Olympus"))
+	assert.False(t, stats.ValidTimezone("Local"))
+	assert.False(t, stats.ValidTimezone(""))
+}
@@ -0,0 +1,128 @@
+package tests
+
+import (
+	"strings"
+	"testing"
+
+	"sonara-space/backend/internal/instruments"
+	"sonara-space/backend/internal/models"
+	"sonara-space/backend/internal/music"
+	"sonara-space/backend/internal/transpose"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func song(stepType, symbols string) *models.Sequence {
+	seq := &models.Sequence{SongTitle: "Song", StepType: stepType, Tempo: 90, BeatsPerBar: 4, BeatUnit: 4}
+	for _, s := range strings.Fields(symbols) {
+		seq.Steps = append(seq.Steps, models.SequenceStep{Symbol: s, Beats: 4})
+	}
+	return seq
+}
+
+func TestKeySignatureAndSpelling(t *testing.T) {
+	for key, want := range map[string]int{"C": 0, "G": 1, "F#": 6, "Bb": -2, "Am": 0, "Em": 1, "Dm": -1, "C#m": 4} {
+		k, err := music.ParseKey(key)
+		require.NoError(t, err)
+		assert.Equal(t, want, k.Signature(), key)
+	}
+
+	spell := func(key string, pc int) string {
+		k, err := music.ParseKey(key)
+		require.NoError(t, err)
+		return k.Spell(music.PitchClass(pc)).String()
+	}
+	assert.Equal(t, "Bb", spell("F", 10))
+	assert.Equal(t, "F#", spell("G", 6))
+	assert.Equal(t, "Bb", spell("C", 10)) // VII низкая
+	assert.Equal(t, "F#", spell("C", 6))  // IV высокая
+	assert.Equal(t, "G#", spell("Am", 8)) // VII высокая в миноре
+	assert.Equal(t, "C#", spell("Dm", 1))
+	assert.Equal(t, "Cb", spell("Eb", 11))
+
+	// Новая тональность — с меньшим числом знаков
+	g, _ := music.ParseKey("G")
+	assert.Equal(t, "Bb", g.Transpose(3).String())
+	assert.Equal(t, "E", g.Transpose(-3).String())
+	bb, _ := music.ParseKey("Bb")
+	assert.Equal(t, "Gb", bb.Transpose(-4).String()) // F#/Gb: знаки как в исходной
+	_, err := music.ParseKey("Hm")
+	assert.Error(t, err)
+}
+
+func TestGuessKey(t *testing.T) {
+	for symbols, want := range map[string]string{
+		"G C D G":       "G",
+		"C Am F G C":    "C",
+		"Am C G F Am":   "Am",
+		"Dm Gm A7 Dm":   "Dm",
+		"Bb Eb F Bb":    "Bb",
+		"F#m D A E F#m": "F#m",
+	} {
+		k, err := transpose.Key(song(models.ExerciseTypeChord, symbols))
+		require.NoError(t, err)
+		assert.Equal(t, want, k.String(), symbols)
+	}
+
+	k, err := transpose.Key(song(models.ExerciseTypeNote, "G4 A4 G4 C5 B4 G4 A4 G4 D5 C5"))
+	require.NoError(t, err)
+	assert.Equal(t, "C", k.String())
+}
+
+func TestTransposeSequence(t *testing.T) {
+	seq := song(models.ExerciseTypeChord, "G Em C D7 G/B")
+	res, err := transpose.Sequence(seq, 3)
+	require.NoError(t, err)
+	assert.Equal(t, "G", res.From.String())
+	assert.Equal(t, "Bb", res.To.String())
+	assert.Equal(t, "Bb Gm Eb F7 Bb/D", res.Expected())
+	// Исходная последовательность не меняется
+	assert.Equal(t, "G", seq.Steps[0].Symbol)
+
+	res, err = transpose.Sequence(seq, -2)
+	require.NoError(t, err)
+	assert.Equal(t, "F Dm Bb C7 F/A", res.Expected())
+
+	melody := song(models.ExerciseTypeNote, "C4 E4 G4 Bb4 C5")
+	res, err = transpose.Sequence(melody, 2)
+	require.NoError(t, err)
+	assert.Equal(t, "D4 F#4 A4 C5 D5", res.Expected())
+	_, err = transpose.Sequence(melody, -12)
+	assert.ErrorIs(t, err, transpose.ErrSemitones)
+
+	// Без сдвига запись остаётся авторской
+	res, err = transpose.Sequence(song(models.ExerciseTypeChord, "A#m F#"), 0)
+	require.NoError(t, err)
+	assert.Equal(t, "A#m F#", res.Expected())
+}
+
+func TestSuggestCapo(t *testing.T) {
+	guitar := mustInstrument(t, instruments.Guitar)
+	standard, _ := guitar.Tuning("")
+
+	// Песня в G играется открытыми аккордами без каподастра
+	capo, err := transpose.SuggestCapo(guitar, standard, song(models.ExerciseTypeChord, "G C D G"))
+	require.NoError(t, err)
+	assert.Equal(t, 0, capo.Fret)
+	assert.Equal(t, 1.0, capo.Open)
+
+	// Bb–Eb–F: каподастр на первом ладу и формы A–D–E
+	capo, err = transpose.SuggestCapo(guitar, standard, song(models.ExerciseTypeChord, "Bb Eb F Bb"))
+	require.NoError(t, err)
+	assert.Equal(t, 1, capo.Fret)
+	assert.Equal(t, []string{"A", "D", "E", "A"}, capo.Shapes)
+
+	// F#m–D–A–E: второй лад, формы Em–C–G–D
+	capo, err = transpose.SuggestCapo(guitar, standard, song(models.ExerciseTypeChord, "F#m D A E"))
+	require.NoError(t, err)
+	assert.Equal(t, 2, capo.Fret)
+	assert.Equal(t, []string{"Em", "C", "G", "D"}, capo.Shapes)
+
+	bass := mustInstrument(t, instruments.Bass)
+	bassTuning, _ := bass.Tuning("")
+	_, err = transpose.SuggestCapo(bass, bassTuning, song(models.ExerciseTypeChord, "G C D"))
+	assert.ErrorIs(t, err, instruments.ErrCapo)
+	_, err = transpose.SuggestCapo(guitar, standard, song(models.ExerciseTypeNote, "C4 D4"))
+	assert.Error(t, err)
+}
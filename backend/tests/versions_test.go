@@ -0,0 +1,49 @@
+package tests
+
+import (
+	"testing"
+
+	"sonara-space/backend/internal/versions"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestVersionAutoMappings(t *testing.T) {
+	// v1: 1, 2, 3. Упражнение 2 правили дважды (2 → 4 → 5), 3 удалили, 6 добавили
+	replaces := map[int64]int64{4: 2, 5: 4}
+	m := versions.AutoMappings([]int64{1, 2, 3}, []int64{1, 5, 6}, replaces)
+	assert.Equal(t, map[int64]int64{2: 5}, m)
+
+	// Без изменений переносы не нужны
+	assert.Empty(t, versions.AutoMappings([]int64{1, 2}, []int64{2, 1}, nil))
+
+	// Старое упражнение осталось в черновике рядом с копией — прогресс не переносится
+	assert.Empty(t, versions.AutoMappings([]int64{1, 2}, []int64{1, 2, 4}, replaces))
+
+	// Цикл в replaces не зацикливает построение
+	assert.Empty(t, versions.AutoMappings([]int64{1}, []int64{7}, map[int64]int64{7: 8, 8: 7}))
+}
+
+func TestVersionMigrate(t *testing.T) {
+	steps := []versions.Step{
+		// v2: 2 заменено на 4, 3 удалено
+		{Exercises: []int64{1, 4}, Mappings: map[int64]int64{2: 4}},
+		// v3: 4 заменено на 5, добавлено 6
+		{Exercises: []int64{1, 5, 6}, Mappings: map[int64]int64{4: 5}},
+	}
+	moved, dropped := versions.Migrate([]int64{1, 2, 3}, steps)
+	assert.Equal(t, map[int64]int64{1: 1, 2: 5}, moved)
+	assert.Equal(t, []int64{3}, dropped)
+
+	// Перенос на упражнение, которого нет в версии, не действует
+	moved, dropped = versions.Migrate([]int64{1}, []versions.Step{
+		{Exercises: []int64{2}, Mappings: map[int64]int64{1: 9}},
+	})
+	assert.Empty(t, moved)
+	assert.Equal(t, []int64{1}, dropped)
+
+	// Без новых версий всё остаётся на месте
+	moved, dropped = versions.Migrate([]int64{1, 2}, nil)
+	assert.Equal(t, map[int64]int64{1: 1, 2: 2}, moved)
+	assert.Empty(t, dropped)
+}
@@ -0,0 +1,193 @@
+package tests
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"sonara-space/backend/internal/handlers"
+	"sonara-space/backend/internal/music"
+	"sonara-space/backend/internal/voicings"
+
+	"github.com/go-chi/chi/v5"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+var standardGuitar = []int{40, 45, 50, 55, 59, 64} // E2 A2 D3 G3 B3 E4
+
+func guitarVoicings(t *testing.T, symbol string) []voicings.Fretting {
+	t.Helper()
+	c, err := music.ParseChord(symbol)
+	require.NoError(t, err)
+	list := voicings.Fretted(c, standardGuitar, 24, voicings.Options{})
+	require.NotEmpty(t, list, symbol)
+	return list
+}
+
+func TestGuitarOpenChords(t *testing.T) {
+	// Первым идёт привычная открытая аппликатура
+	cases := map[string]string{
+		"C":     "x32010",
+		"G":     "320003",
+		"Am":    "x02210",
+		"E":     "022100",
+		"D":     "xx0232",
+		"Em":    "022000",
+		"Dm":    "xx0231",
+		"A7":    "x02020",
+		"C7":    "x32310",
+		"G7":    "320001",
+		"B7":    "x21202",
+		"Cmaj7": "x32000",
+		"C/G":   "332010",
+	}
+	for symbol, want := range cases {
+		v := guitarVoicings(t, symbol)[0]
+		assert.Equal(t, want, v.Shape, symbol)
+		assert.True(t, v.Open, symbol)
+		assert.Nil(t, v.Barre, symbol)
+	}
+}
+
+func TestGuitarFingers(t *testing.T) {
+	c := guitarVoicings(t, "C")[0]
+	assert.Equal(t, []int{0, 3, 2, 0, 1, 0}, c.Fingers)
+	assert.Equal(t, []string{"C3", "E3", "G3", "C4", "E4"}, c.Notes)
+
+	g7 := guitarVoicings(t, "G7")[0]
+	assert.Equal(t, []int{3, 2, 0, 0, 0, 1}, g7.Fingers)
+
+	// Ноты пишутся как в аккорде: Ab, а не G#
+	bb7 := guitarVoicings(t, "Bb7")[0]
+	assert.Contains(t, bb7.Notes, "Ab3")
+}
+
+func TestGuitarBarreChords(t *testing.T) {
+	f := guitarVoicings(t, "F")[0]
+	assert.Equal(t, "133211", f.Shape)
+	assert.Equal(t, []int{1, 3, 4, 2, 1, 1}, f.Fingers)
+	require.NotNil(t, f.Barre)
+	assert.Equal(t, voicings.Barre{Fret: 1, From: 0, To: 5}, *f.Barre)
+
+	bm := guitarVoicings(t, "Bm")[0]
+	assert.Equal(t, "x24432", bm.Shape)
+	require.NotNil(t, bm.Barre)
+	assert.Equal(t, 2, bm.Barre.Fret)
+	assert.False(t, bm.Open)
+}
+
+func TestGuitarStretchLimit(t *testing.T) {
+	for _, symbol := range []string{"C", "F", "Bm", "G13", "Cadd9", "F#m7b5"} {
+		c, err := music.ParseChord(symbol)
+		require.NoError(t, err)
+		for _, v := range voicings.Fretted(c, standardGuitar, 24, voicings.Options{Limit: 20}) {
+			low, high := 0, 0
+			for _, f := range v.Frets {
+				if f > 0 && (low == 0 || f < low) {
+					low = f
+				}
+				high = max(high, f)
+			}
+			assert.LessOrEqual(t, high-low, voicings.DefaultMaxStretch, "%s %s", symbol, v.Shape)
+		}
+	}
+}
+
+func TestUkuleleVoicings(t *testing.T) {
+	// Реэнтрантный строй GCEA: звучат все четыре струны
+	uke := []int{67, 60, 64, 69}
+	for symbol, want := range map[string]string{"C": "0003", "Am": "2000", "F": "2010", "G": "0232"} {
+		c, err := music.ParseChord(symbol)
+		require.NoError(t, err)
+		list := voicings.Fretted(c, uke, 15, voicings.Options{})
+		require.NotEmpty(t, list, symbol)
+		assert.Equal(t, want, list[0].Shape, symbol)
+	}
+}
+
+func TestPianoInversions(t *testing.T) {
+	c, err := music.ParseChord("C")
+	require.NoError(t, err)
+	list := voicings.Piano(c, voicings.Options{})
+	require.Len(t, list, 3)
+
+	assert.Equal(t, []string{"C3"}, list[0].Left.Notes)
+	assert.Equal(t, []string{"C4", "E4", "G4"}, list[0].Right.Notes)
+	assert.Equal(t, []int{1, 3, 5}, list[0].Right.Fingers)
+	assert.Equal(t, 7, list[0].Right.Span)
+	assert.Equal(t, []string{"E4", "G4", "C5"}, list[1].Right.Notes)
+	assert.Equal(t, []int{1, 2, 5}, list[1].Right.Fingers)
+	assert.Equal(t, []string{"G3", "C4", "E4"}, list[2].Right.Notes)
+
+	// Септаккорд: четыре обращения, секунда наверху — пальцами 4 и 5
+	c7, err := music.ParseChord("Bb7")
+	require.NoError(t, err)
+	list = voicings.Piano(c7, voicings.Options{})
+	require.Len(t, list, 4)
+	assert.Equal(t, []string{"Bb3", "D4", "F4", "Ab4"}, list[0].Right.Notes)
+	assert.Equal(t, []int{1, 2, 4, 5}, list[1].Right.Fingers)
+
+	// Бас аккорда с косой чертой — в левой руке
+	ce, err := music.ParseChord("C/E")
+	require.NoError(t, err)
+	assert.Equal(t, []string{"E3"}, voicings.Piano(ce, voicings.Options{})[0].Left.Notes)
+
+	// Длинные аккорды укладываются в пять звуков и в октаву
+	g13, err := music.ParseChord("G13")
+	require.NoError(t, err)
+	for _, v := range voicings.Piano(g13, voicings.Options{}) {
+		assert.LessOrEqual(t, len(v.Right.Notes), 5)
+		assert.LessOrEqual(t, v.Right.Span, 12)
+	}
+}
+
+func TestChordVoicingsHandler(t *testing.T) {
+	r := chi.NewRouter()
+	r.Get("/chords/{symbol}/voicings", handlers.GetChordVoicingsHandler)
+
+	get := func(target string) (*httptest.ResponseRecorder, map[string]any) {
+		rec := httptest.NewRecorder()
+		r.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, target, nil))
+		var body map[string]any
+		if rec.Code == http.StatusOK {
+			require.NoError(t, json.Unmarshal(rec.Body.Bytes(), &body))
+		}
+		return rec, body
+	}
+
+	rec, body := get("/chords/C7/voicings")
+	require.Equal(t, http.StatusOK, rec.Code)
+	assert.Equal(t, "guitar", body["instrument"])
+	assert.Equal(t, "x32310", body["voicings"].([]any)[0].(map[string]any)["shape"])
+
+	// Косая черта и диез кодируются в пути
+	rec, body = get("/chords/C%2FG/voicings?limit=1")
+	require.Equal(t, http.StatusOK, rec.Code)
+	assert.Equal(t, "C/G", body["chord"])
+	assert.Len(t, body["voicings"], 1)
+	rec, body = get("/chords/F%23m/voicings")
+	require.Equal(t, http.StatusOK, rec.Code)
+	assert.Equal(t, "F#m", body["chord"])
+
+	// С каподастром лады считаются от него: G-форма на втором ладу
+	rec, body = get("/chords/A/voicings?capo=2")
+	require.Equal(t, http.StatusOK, rec.Code)
+	assert.Equal(t, []any{"F#2", "B2", "E3", "A3", "C#4", "F#4"}, body["strings"])
+	assert.Equal(t, "320003", body["voicings"].([]any)[0].(map[string]any)["shape"])
+
+	rec, body = get("/chords/Am/voicings?instrument=piano")
+	require.Equal(t, http.StatusOK, rec.Code)
+	assert.Len(t, body["voicings"], 3)
+
+	for _, target := range []string{
+		"/chords/H7/voicings",
+		"/chords/C/voicings?instrument=banjo",
+		"/chords/C/voicings?instrument=bass&capo=2",
+		"/chords/C/voicings?capo=x",
+	} {
+		rec, _ = get(target)
+		assert.Equal(t, http.StatusBadRequest, rec.Code, target)
+	}
+}
@@ -34,8 +34,18 @@ export async function api(path, { method = "GET", body, auth = true } = {}) {
 }
 
 // API функции для уроков
-export async function getLessons() {
-  return api("/lessons");
+// Каталог отдаётся постранично, собираем все страницы
+export async function getLessons(params = {}) {
+  const lessons = [];
+  let cursor = null;
+  do {
+    const query = new URLSearchParams({ ...params, limit: 100 });
+    if (cursor) query.set("cursor", cursor);
+    const page = await api(`/lessons?${query}`);
+    lessons.push(...page.items);
+    cursor = page.next_cursor;
+  } while (cursor);
+  return lessons;
 }
 
 export async function getLesson(id) {
@@ -51,4 +61,9 @@ export async function updateProgress(exerciseId, status) {
 
 export async function getUserProgress() {
   return api("/progress");
+}
+
+// Прогресс по урокам вместе с общим итогом: { lessons, summary }
+export async function getUserProgressSummary() {
+  return api("/progress?include=summary");
 }
\ No newline at end of file
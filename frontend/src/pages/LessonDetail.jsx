@@ -10,32 +10,11 @@ import audioPlayer from "../utils/audioPlayer";
 import SongDisplay from "../components/SongDisplay";
 import "../lessons-styles.css";
 
-// Функции для работы с песнями
-const getSongTitle = (exerciseTitle) => {
-  if (exerciseTitle.includes('Happy Birthday')) return 'Happy Birthday';
-  if (exerciseTitle.includes('До-ре-ми')) return 'До-ре-ми';
-  if (exerciseTitle.includes('Twinkle Twinkle')) return 'Twinkle Twinkle Little Star';
-  if (exerciseTitle.includes('Гамма C мажор')) return 'Гамма C мажор';
-  return 'Неизвестная песня';
-};
-
-const getSongSequence = (exerciseTitle, expectedValue) => {
-  // Последовательности для известных песен
-  if (exerciseTitle.includes('Happy Birthday')) {
-    return ['Am', 'C', 'G', 'F', 'Am', 'C', 'G', 'F'];
-  }
-  if (exerciseTitle.includes('До-ре-ми')) {
-    return ['C4', 'D4', 'E4', 'F4', 'G4', 'A4', 'B4', 'C5'];
-  }
-  if (exerciseTitle.includes('Twinkle Twinkle')) {
-    return ['C4', 'C4', 'G4', 'G4', 'A4', 'A4', 'G4'];
-  }
-  if (exerciseTitle.includes('Гамма C мажор')) {
-    return ['C4', 'D4', 'E4', 'F4', 'G4', 'A4', 'B4', 'C5'];
-  }
-  
-  // Если это не известная песня, возвращаем только ожидаемое значение
-  return [expectedValue];
+// Песни приходят с сервера как упражнения типа "sequence"
+// со структурой exercise.sequence (шаги, темп, размер, текст)
+const getSongSequence = (exercise) => {
+  const steps = exercise?.sequence?.steps || [];
+  return steps.map((step) => step.symbol);
 };
 
 // Компонент для отображения схемы аккорда
@@ -214,17 +193,15 @@ export default function LessonDetail() {
     const expectedValue = currentExercise.Expected || currentExercise.expected;
     
     // Проверяем, является ли это упражнением с песней
-    const isSongExercise = currentExercise.Title?.includes('Песня') || 
-                         currentExercise.Title?.includes('Мелодия') ||
-                         currentExercise.Title?.includes('Гамма');
+    const isSongExercise = exerciseType === "sequence" && currentExercise.sequence;
     
     // Возвращаем соответствующий компонент
     if (isSongExercise) {
-      const songSequence = getSongSequence(currentExercise.Title, expectedValue);
+      const songSequence = getSongSequence(currentExercise);
       return (
         <SongDisplay 
           key={`song-${lesson.ID}-${currentExercise.ID}`}
-          songTitle={getSongTitle(currentExercise.Title)}
+          songTitle={currentExercise.sequence.song_title}
           chordSequence={songSequence}
           autoPlay={true}
           instrument={instrument}
@@ -1,11 +1,12 @@
 import { useEffect, useState } from "react";
-import { getUserProgress } from "../api";
+import { getUserProgressSummary } from "../api";
 import "../lessons-styles.css";
 
 export default function Progress() {
   const [loading, setLoading] = useState(true);
   const [error, setError] = useState(null);
   const [progress, setProgress] = useState([]);
+  const [summary, setSummary] = useState(null);
 
   useEffect(() => {
     loadProgress();
@@ -14,8 +15,9 @@ export default function Progress() {
   const loadProgress = async () => {
     try {
       setLoading(true);
-      const progressData = await getUserProgress();
-      setProgress(progressData);
+      const progressData = await getUserProgressSummary();
+      setProgress(progressData.lessons);
+      setSummary(progressData.summary);
     } catch (err) {
       console.error("Error loading progress:", err);
       setError(err.message);
@@ -24,19 +26,14 @@ export default function Progress() {
     }
   };
 
-  const getTotalStats = () => {
-    const totalExercises = progress.reduce((sum, p) => sum + p.total_exercises, 0);
-    const completedExercises = progress.reduce((sum, p) => sum + p.completed_exercises, 0);
-    const totalProgress = totalExercises > 0 ? Math.round((completedExercises / totalExercises) * 100) : 0;
-    
-    return {
-      totalLessons: progress.length,
-      completedLessons: progress.filter(p => p.progress === 100).length,
-      totalExercises,
-      completedExercises,
-      totalProgress
-    };
-  };
+  // Общий итог считает сервер: уроки инструментов, которыми ученик не занимался, в него не входят
+  const getTotalStats = () => ({
+    totalLessons: summary?.total_lessons ?? 0,
+    completedLessons: summary?.completed_lessons ?? 0,
+    totalExercises: summary?.total_exercises ?? 0,
+    completedExercises: summary?.completed_exercises ?? 0,
+    totalProgress: Math.round(summary?.progress ?? 0)
+  });
 
   const getProgressColor = (progressValue) => {
     if (progressValue === 100) return "#10b981"; // green-500